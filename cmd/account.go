@@ -0,0 +1,110 @@
+/*
+Copyright © 2025 longkey1
+
+Licensed under the Apache License, Version 2.0 (the "License");
+you may not use this file except in compliance with the License.
+You may obtain a copy of the License at
+
+	http://www.apache.org/licenses/LICENSE-2.0
+
+Unless required by applicable law or agreed to in writing, software
+distributed under the License is distributed on an "AS IS" BASIS,
+WITHOUT WARRANTIES OR CONDITIONS OF ANY KIND, either express or implied.
+See the License for the specific language governing permissions and
+limitations under the License.
+*/
+package cmd
+
+import (
+	"fmt"
+	"os"
+	"strings"
+
+	"github.com/longkey1/gml/internal/gml"
+	"github.com/spf13/cobra"
+)
+
+// accountCmd represents the account command
+var accountCmd = &cobra.Command{
+	Use:   "account",
+	Short: "Manage account profiles",
+	Long: `Manage named account profiles defined under [accounts.<name>] in the config file.
+
+The account used by a command is chosen by, in order of precedence:
+the --account flag, the GML_ACCOUNT environment variable, the account
+selected with 'gml account switch', and default_account in the config.`,
+}
+
+// accountListCmd represents the account list command
+var accountListCmd = &cobra.Command{
+	Use:   "list",
+	Short: "List configured accounts",
+	Args:  cobra.NoArgs,
+	RunE:  runAccountList,
+}
+
+// accountSwitchCmd represents the account switch command
+var accountSwitchCmd = &cobra.Command{
+	Use:   "switch <name>",
+	Short: "Switch the current account",
+	Args:  cobra.ExactArgs(1),
+	RunE:  runAccountSwitch,
+}
+
+func runAccountList(cmd *cobra.Command, args []string) error {
+	cfg := getBaseConfig(cmd)
+
+	// Get flags
+	outputFormat := formatFromFlags(cmd)
+
+	current, err := selectedAccount(cmd, cfg)
+	if err != nil {
+		return err
+	}
+
+	accounts := cfg.ListAccounts(current)
+	if len(accounts) == 0 && outputFormat != gml.OutputFormatJSON {
+		fmt.Fprintln(cmd.OutOrStdout(), "No accounts configured.")
+		return nil
+	}
+
+	// Output
+	if err := gml.FormatAccountList(cmd.OutOrStdout(), accounts, outputFormat); err != nil {
+		return fmt.Errorf("unable to format output: %w", err)
+	}
+	return nil
+}
+
+func runAccountSwitch(cmd *cobra.Command, args []string) error {
+	cfg := getBaseConfig(cmd)
+	name := strings.ToLower(args[0])
+
+	if _, err := cfg.ForAccount(name); err != nil {
+		return err
+	}
+
+	path, err := currentAccountPath(cmd)
+	if err != nil {
+		return err
+	}
+	if err := gml.WriteCurrentAccount(path, name); err != nil {
+		return err
+	}
+
+	fmt.Fprintf(cmd.OutOrStdout(), "Switched to account %q.\n", name)
+	if os.Getenv("GML_ACCOUNT") != "" {
+		fmt.Fprintln(cmd.OutOrStdout(), "Note: GML_ACCOUNT is set and takes precedence.")
+	}
+	return nil
+}
+
+func init() {
+	rootCmd.AddCommand(accountCmd)
+	accountCmd.AddCommand(accountListCmd)
+	accountCmd.AddCommand(accountSwitchCmd)
+
+	setFormats(accountListCmd, gml.OutputFormatText, gml.OutputFormatJSON)
+
+	// Set custom output to enable testing
+	accountCmd.SetOut(os.Stdout)
+}
@@ -0,0 +1,116 @@
+/*
+Copyright © 2025 longkey1
+
+Licensed under the Apache License, Version 2.0 (the "License");
+you may not use this file except in compliance with the License.
+You may obtain a copy of the License at
+
+	http://www.apache.org/licenses/LICENSE-2.0
+
+Unless required by applicable law or agreed to in writing, software
+distributed under the License is distributed on an "AS IS" BASIS,
+WITHOUT WARRANTIES OR CONDITIONS OF ANY KIND, either express or implied.
+See the License for the specific language governing permissions and
+limitations under the License.
+*/
+package cmd
+
+import (
+	"bytes"
+	"encoding/json"
+	"fmt"
+	"os"
+
+	"github.com/longkey1/gml/internal/gml"
+	"github.com/spf13/cobra"
+)
+
+// apiCmd represents the api command
+var apiCmd = &cobra.Command{
+	Use:   "api <method> <path>",
+	Short: "Send a raw Gmail API request",
+	Long: `Send a request to the Gmail REST API with the current credentials and print
+the JSON response, to reach API surface gml doesn't wrap yet.
+
+The path is relative to https://gmail.googleapis.com/gmail/v1/ and may
+include a query string. The request body is JSON from --data or --input.
+Responses with an error status are printed as well, and the command exits
+with status 1. Requests that change state honor the global --dry-run.
+
+Examples:
+  gml api get users/me/profile
+  gml api get 'users/me/messages/18abc123def456?format=metadata'
+  gml api get 'users/me/messages?q=is:unread&maxResults=5' | jq '.messages'
+  gml api post users/me/labels -d '{"name": "Receipts"}'
+  gml api put users/me/settings/vacation --input vacation.json
+  gml api delete users/me/labels/Label_42 --dry-run`,
+	Args: cobra.ExactArgs(2),
+	RunE: runAPI,
+}
+
+func runAPI(cmd *cobra.Command, args []string) error {
+	ctx := cmd.Context()
+	cfg, err := GetConfig(cmd)
+	if err != nil {
+		return err
+	}
+
+	// Get flags
+	data, _ := cmd.Flags().GetString("data")
+	input, _ := cmd.Flags().GetString("input")
+	raw, _ := cmd.Flags().GetBool("raw")
+
+	var body []byte
+	switch {
+	case data != "" && input != "":
+		return fmt.Errorf("--data and --input cannot be combined")
+	case data != "":
+		body = []byte(data)
+	case input != "":
+		b, err := readInput(cmd, input)
+		if err != nil {
+			return err
+		}
+		body = b
+	}
+	if body != nil && !json.Valid(body) {
+		return fmt.Errorf("request body is not valid JSON")
+	}
+
+	// Create service
+	svc, err := gml.NewService(ctx, cfg)
+	if err != nil {
+		return fmt.Errorf("unable to create service: %w", err)
+	}
+
+	resp, err := gml.CallAPI(ctx, svc, args[0], args[1], body)
+	if err != nil {
+		return err
+	}
+
+	// Output
+	out := resp.Body
+	var indented bytes.Buffer
+	if !raw && json.Indent(&indented, resp.Body, "", "  ") == nil {
+		out = indented.Bytes()
+	}
+	if len(out) > 0 {
+		fmt.Fprintln(cmd.OutOrStdout(), string(bytes.TrimRight(out, "\n")))
+	}
+	if resp.StatusCode >= 400 {
+		return &ExitError{Code: 1, Err: fmt.Errorf("API request failed: %s", resp.Status)}
+	}
+
+	return nil
+}
+
+func init() {
+	rootCmd.AddCommand(apiCmd)
+
+	apiCmd.Flags().StringP("data", "d", "", "JSON request body")
+	apiCmd.Flags().String("input", "", "Read the JSON request body from a file (- for stdin)")
+	apiCmd.Flags().Bool("raw", false, "Print the response body as received instead of indented")
+
+	// Set custom output to enable testing
+	apiCmd.SetOut(os.Stdout)
+}
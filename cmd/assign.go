@@ -0,0 +1,122 @@
+/*
+Copyright © 2025 longkey1
+
+Licensed under the Apache License, Version 2.0 (the "License");
+you may not use this file except in compliance with the License.
+You may obtain a copy of the License at
+
+	http://www.apache.org/licenses/LICENSE-2.0
+
+Unless required by applicable law or agreed to in writing, software
+distributed under the License is distributed on an "AS IS" BASIS,
+WITHOUT WARRANTIES OR CONDITIONS OF ANY KIND, either express or implied.
+See the License for the specific language governing permissions and
+limitations under the License.
+*/
+package cmd
+
+import (
+	"fmt"
+	"os"
+
+	"github.com/longkey1/gml/internal/gml"
+	"github.com/spf13/cobra"
+)
+
+// assignCmd represents the assign command
+var assignCmd = &cobra.Command{
+	Use:   "assign",
+	Short: "Distribute matching messages across per-person labels",
+	Long: `Distribute messages matching a query across assignee labels.
+
+Strategies:
+  round-robin   Assign in turn, in the order the labels are given (default);
+                the next run continues with the label after the last one used
+  least-loaded  Assign to the label currently holding the fewest messages
+  random        Assign to a random label
+
+Requires the "modify" scope (see the scopes config option).
+
+Examples:
+  gml assign -q "label:support/unassigned" --labels alice,bob,carol
+  gml assign -q "label:support/unassigned" --labels alice,bob --strategy least-loaded \
+    --remove-label support/unassigned
+  gml assign -q "label:support/unassigned" --labels alice,bob --dry-run`,
+	RunE: runAssign,
+}
+
+func runAssign(cmd *cobra.Command, args []string) error {
+	ctx := cmd.Context()
+	cfg, err := GetConfig(cmd)
+	if err != nil {
+		return err
+	}
+
+	// Get flags
+	query, labels, err := queryFromFlags(cmd)
+	if err != nil {
+		return err
+	}
+	assignees, _ := cmd.Flags().GetStringSlice("labels")
+	removeLabels, _ := cmd.Flags().GetStringArray("remove-label")
+	strategy, _ := cmd.Flags().GetString("strategy")
+	dryRun := gml.IsDryRun(ctx)
+	outputFormat := formatFromFlags(cmd)
+
+	statePath, err := gml.StatePath(gml.AssignStateName, cfg.Account)
+	if err != nil {
+		return err
+	}
+
+	// Create service
+	svc, err := gml.NewService(ctx, cfg)
+	if err != nil {
+		return fmt.Errorf("unable to create service: %w", err)
+	}
+
+	// Assign messages
+	assignments, err := gml.AssignMessages(ctx, svc, gml.AssignOptions{
+		Query:        query,
+		LabelIDs:     labels,
+		Assignees:    assignees,
+		RemoveLabels: removeLabels,
+		Strategy:     gml.AssignStrategy(strategy),
+		DryRun:       dryRun,
+		StatePath:    statePath,
+	})
+	if err != nil {
+		return fmt.Errorf("unable to assign messages: %w", err)
+	}
+
+	if len(assignments) == 0 {
+		fmt.Fprintln(cmd.OutOrStdout(), "No messages found.")
+		return nil
+	}
+
+	// Output
+	if err := gml.FormatAssignments(cmd.OutOrStdout(), assignments, outputFormat); err != nil {
+		return fmt.Errorf("unable to format output: %w", err)
+	}
+
+	if dryRun && outputFormat != gml.OutputFormatJSON {
+		fmt.Fprintln(cmd.OutOrStdout(), "Dry run: no labels were changed.")
+	}
+
+	return nil
+}
+
+func init() {
+	rootCmd.AddCommand(assignCmd)
+
+	assignCmd.Flags().StringP("query", "q", "", "Search query (Gmail search syntax)")
+	addQueryFlags(assignCmd)
+	assignCmd.Flags().StringArrayP("label", "l", nil, "Filter by label (can be specified multiple times)")
+	assignCmd.Flags().StringSlice("labels", nil, "Comma-separated assignee labels")
+	assignCmd.Flags().StringArray("remove-label", nil, "Label to remove from assigned messages (can be specified multiple times)")
+	assignCmd.Flags().String("strategy", string(gml.AssignStrategyRoundRobin), "Assignment strategy (round-robin, least-loaded, random)")
+	setFormats(assignCmd, gml.OutputFormatText, gml.OutputFormatJSON)
+	assignCmd.MarkFlagRequired("labels")
+
+	// Set custom output to enable testing
+	assignCmd.SetOut(os.Stdout)
+}
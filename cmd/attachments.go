@@ -0,0 +1,284 @@
+/*
+Copyright © 2025 longkey1
+
+Licensed under the Apache License, Version 2.0 (the "License");
+you may not use this file except in compliance with the License.
+You may obtain a copy of the License at
+
+	http://www.apache.org/licenses/LICENSE-2.0
+
+Unless required by applicable law or agreed to in writing, software
+distributed under the License is distributed on an "AS IS" BASIS,
+WITHOUT WARRANTIES OR CONDITIONS OF ANY KIND, either express or implied.
+See the License for the specific language governing permissions and
+limitations under the License.
+*/
+package cmd
+
+import (
+	"bufio"
+	"fmt"
+	"os"
+
+	"github.com/longkey1/gml/internal/gml"
+	"github.com/spf13/cobra"
+)
+
+// attachmentsCmd represents the attachments command
+var attachmentsCmd = &cobra.Command{
+	Use:     "attachments",
+	Aliases: []string{"attachment"},
+	Short:   "Work with message attachments",
+	Long: `Work with message attachments.
+
+Examples:
+  gml attachments list -q "from:invoices@example.com"
+  gml attachments save -q "from:invoices@example.com" --dir ./invoices
+  gml attachments cat 18abc123def456 report.pdf > report.pdf`,
+}
+
+// attachmentsListCmd represents the attachments list command
+var attachmentsListCmd = &cobra.Command{
+	Use:   "list",
+	Short: "List the attachments of messages matching a query",
+	Long: `List the attachments of all messages matching a query, newest message
+first. "has:attachment" is added to the query.
+
+Examples:
+  gml attachments list -q "from:invoices@example.com"
+  gml attachments list -q "newer_than:30d" --name '*.pdf'
+  gml attachments list -l Receipts --json
+  gml attachments list -q "larger:5M" --format csv > big.csv`,
+	Args: cobra.NoArgs,
+	RunE: runAttachmentsList,
+}
+
+// attachmentsSaveCmd represents the attachments save command
+var attachmentsSaveCmd = &cobra.Command{
+	Use:   "save",
+	Short: "Download the attachments of messages matching a query",
+	Long: `Download the attachments of all messages matching a query into a directory.
+
+Files are named by --pattern, a Go template with these fields:
+  .Date       day the message was received (2025-01-31)
+  .Time       local time of day it was received (153045)
+  .Filename   attachment file name; .Name and .Ext are its parts
+  .From       sender address
+  .Subject    message subject
+  .MessageID  .ThreadID  .PartID
+Values are made safe for file names (slashes become _), while slashes in the
+pattern itself create subdirectories.
+
+Existing files are skipped, so a rerun only downloads new attachments;
+--overwrite replaces them. Attachments whose name is already taken in the
+same run get a numbered suffix ("invoice (2).pdf"), and content identical to
+a file saved or found in this run (e.g. a logo in every message) is skipped
+unless --keep-duplicates is given.
+
+Examples:
+  gml attachments save -q "from:invoices@example.com" --dir ./invoices
+  gml attachments save -q "from:invoices@" --dir ./invoices --pattern '{{.Date}}-{{.Filename}}'
+  gml attachments save -l Receipts --name '*.pdf' --pattern '{{.From}}/{{.Date}}-{{.Filename}}'
+  gml attachments save -q "newer_than:7d" --max-messages 50 --json`,
+	Args: cobra.NoArgs,
+	RunE: runAttachmentsSave,
+}
+
+// attachmentSearchFromFlags returns the search options shared by list and save
+func attachmentSearchFromFlags(cmd *cobra.Command) (gml.AttachmentSearchOptions, error) {
+	query, labels, err := queryFromFlags(cmd)
+	if err != nil {
+		return gml.AttachmentSearchOptions{}, err
+	}
+	if query, labels, err = savedSearchFromFlags(cmd, query, labels); err != nil {
+		return gml.AttachmentSearchOptions{}, err
+	}
+	if query, err = searchQueryFromFlags(cmd, query); err != nil {
+		return gml.AttachmentSearchOptions{}, err
+	}
+	name, _ := cmd.Flags().GetString("name")
+	maxMessages, _ := cmd.Flags().GetInt("max-messages")
+	return gml.AttachmentSearchOptions{
+		Query:       query,
+		LabelIDs:    labels,
+		Name:        name,
+		MaxMessages: maxMessages,
+	}, nil
+}
+
+func runAttachmentsList(cmd *cobra.Command, args []string) error {
+	ctx := cmd.Context()
+	cfg, err := GetConfig(cmd)
+	if err != nil {
+		return err
+	}
+
+	// Get flags
+	opts, err := attachmentSearchFromFlags(cmd)
+	if err != nil {
+		return err
+	}
+	csvOpts, err := csvOptionsFromFlags(cmd, gml.CSVOptions{})
+	if err != nil {
+		return err
+	}
+
+	// Create service
+	svc, err := gml.NewService(ctx, cfg)
+	if err != nil {
+		return fmt.Errorf("unable to create service: %w", err)
+	}
+
+	progress := newProgress(cmd, "Fetching messages")
+	opts.Progress = progress.Update
+	attachments, err := gml.FindAttachments(ctx, svc, opts)
+	progress.Done()
+	if err != nil {
+		return err
+	}
+
+	// Output
+	if err := gml.FormatMessageAttachments(cmd.OutOrStdout(), attachments, formatFromFlags(cmd), csvOpts); err != nil {
+		return fmt.Errorf("unable to format output: %w", err)
+	}
+	return nil
+}
+
+func runAttachmentsSave(cmd *cobra.Command, args []string) error {
+	ctx := cmd.Context()
+	cfg, err := GetConfig(cmd)
+	if err != nil {
+		return err
+	}
+
+	// Get flags
+	opts, err := attachmentSearchFromFlags(cmd)
+	if err != nil {
+		return err
+	}
+	dir, _ := cmd.Flags().GetString("dir")
+	patternStr, _ := cmd.Flags().GetString("pattern")
+	overwrite, _ := cmd.Flags().GetBool("overwrite")
+	keepDuplicates, _ := cmd.Flags().GetBool("keep-duplicates")
+
+	if opts.Query == "" && len(opts.LabelIDs) == 0 {
+		return fmt.Errorf("a query or label is required")
+	}
+	pattern, err := gml.ParseAttachmentPattern(patternStr)
+	if err != nil {
+		return err
+	}
+
+	// Create service
+	svc, err := gml.NewService(ctx, cfg)
+	if err != nil {
+		return fmt.Errorf("unable to create service: %w", err)
+	}
+
+	progress := newProgress(cmd, "Fetching messages")
+	opts.Progress = progress.Update
+	attachments, err := gml.FindAttachments(ctx, svc, opts)
+	progress.Done()
+	if err != nil {
+		return err
+	}
+
+	progress = newProgress(cmd, "Saving attachments")
+	saved, err := gml.SaveAttachments(ctx, svc, attachments, gml.SaveAttachmentsOptions{
+		Dir:            dir,
+		Pattern:        pattern,
+		Overwrite:      overwrite,
+		KeepDuplicates: keepDuplicates,
+		Progress:       progress.Update,
+	})
+	progress.Done()
+
+	// Output what was saved even when a later download failed
+	if ferr := gml.FormatSavedAttachments(cmd.OutOrStdout(), saved, formatFromFlags(cmd)); ferr != nil && err == nil {
+		err = fmt.Errorf("unable to format output: %w", ferr)
+	}
+	return err
+}
+
+// addAttachmentSearchFlags adds the message selection flags of list and save
+func addAttachmentSearchFlags(cmd *cobra.Command) {
+	cmd.Flags().StringP("query", "q", "", "Search query (Gmail search syntax)")
+	cmd.Flags().StringArrayP("label", "l", nil, "Filter by label (can be specified multiple times)")
+	addQueryFlags(cmd)
+	addSearchFlags(cmd)
+	addSavedFlag(cmd)
+	cmd.Flags().String("name", "", "Only attachments whose filename matches this glob, e.g. '*.pdf' (case-insensitive)")
+	cmd.Flags().Int("max-messages", 0, "Stop after this many messages, newest first (0 for all)")
+}
+
+// attachmentsCatCmd represents the attachments cat command
+var attachmentsCatCmd = &cobra.Command{
+	Use:   "cat <message-id> <filename>",
+	Short: "Write an attachment to stdout",
+	Long: `Write the content of one attachment to stdout, so it can be piped into
+other tools without a temporary file.
+
+The attachment is chosen by filename (exact, then case-insensitive) or by
+MIME part ID when several attachments share a name.
+
+Examples:
+  gml attachments cat 18abc123def456 backup.tar.gz | tar xz
+  gml attachments cat 18abc123def456 secret.bin.enc | openssl enc -d -aes-256-cbc -pbkdf2
+  gml attachments cat 18abc123def456 1.2 | less   # By part ID`,
+	Args: cobra.ExactArgs(2),
+	RunE: runAttachmentsCat,
+}
+
+func runAttachmentsCat(cmd *cobra.Command, args []string) error {
+	messageID, name := args[0], args[1]
+	ctx := cmd.Context()
+	cfg, err := GetConfig(cmd)
+	if err != nil {
+		return err
+	}
+
+	// Create service
+	svc, err := gml.NewService(ctx, cfg)
+	if err != nil {
+		return fmt.Errorf("unable to create service: %w", err)
+	}
+
+	attachments, err := gml.GetAttachments(ctx, svc, messageID)
+	if err != nil {
+		return err
+	}
+	attachment, err := gml.FindAttachment(attachments, name)
+	if err != nil {
+		return err
+	}
+
+	// Output
+	out := bufio.NewWriter(cmd.OutOrStdout())
+	if err := gml.WriteAttachment(ctx, svc, messageID, attachment, out); err != nil {
+		return err
+	}
+	return out.Flush()
+}
+
+func init() {
+	rootCmd.AddCommand(attachmentsCmd)
+	attachmentsCmd.AddCommand(attachmentsListCmd)
+	attachmentsCmd.AddCommand(attachmentsSaveCmd)
+	attachmentsCmd.AddCommand(attachmentsCatCmd)
+
+	addAttachmentSearchFlags(attachmentsListCmd)
+	setFormats(attachmentsListCmd, gml.OutputFormatText, gml.OutputFormatJSON, gml.OutputFormatCSV, gml.OutputFormatTSV)
+	addCSVFlags(attachmentsListCmd)
+
+	addAttachmentSearchFlags(attachmentsSaveCmd)
+	attachmentsSaveCmd.Flags().String("dir", ".", "Directory to save the attachments in")
+	attachmentsSaveCmd.Flags().String("pattern", gml.DefaultAttachmentPattern, "File name template relative to --dir (see above)")
+	attachmentsSaveCmd.Flags().Bool("overwrite", false, "Replace existing files instead of skipping them")
+	attachmentsSaveCmd.Flags().Bool("keep-duplicates", false, "Also save attachments whose content was already saved in this run")
+	setFormats(attachmentsSaveCmd, gml.OutputFormatText, gml.OutputFormatJSON)
+
+	// Set custom output to enable testing
+	attachmentsCmd.SetOut(os.Stdout)
+	attachmentsListCmd.SetOut(os.Stdout)
+	attachmentsSaveCmd.SetOut(os.Stdout)
+}
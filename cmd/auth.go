@@ -30,20 +30,41 @@ var authCmd = &cobra.Command{
 	Short: "Authenticate with Gmail API using OAuth",
 	Long: `Authenticate with Gmail API using OAuth.
 This command initiates the OAuth flow to obtain and save access tokens.
-Only applicable when auth_type is set to "oauth" in config.`,
+Only applicable when auth_type is set to "oauth" in config.
+
+On headless machines (e.g. over SSH), use --no-browser to open the
+authorization URL elsewhere and paste the redirected URL back into the
+terminal. Google's device authorization grant does not support Gmail
+scopes, so this copy-paste flow is used instead.`,
 	RunE: runAuth,
 }
 
 func runAuth(cmd *cobra.Command, args []string) error {
-	cfg := GetConfig()
+	cfg, err := GetConfig(cmd)
+	if err != nil {
+		return err
+	}
+
+	// Get flags
+	noBrowser, _ := cmd.Flags().GetBool("no-browser")
 
 	if cfg.AuthType != gml.AuthTypeOAuth {
 		return fmt.Errorf("auth command is only available for OAuth authentication (current: %s)", cfg.AuthType)
 	}
 
+	scopes, err := cfg.OAuthScopes()
+	if err != nil {
+		return err
+	}
+
+	store, err := cfg.NewTokenStore()
+	if err != nil {
+		return err
+	}
+
 	// Check if token already exists
-	if _, err := os.Stat(cfg.GoogleUserCredentials); err == nil {
-		fmt.Fprintf(cmd.OutOrStdout(), "Token file already exists: %s\n", cfg.GoogleUserCredentials)
+	if _, err := store.Load(); err == nil {
+		fmt.Fprintf(cmd.OutOrStdout(), "Token already exists: %s\n", store)
 		fmt.Fprint(cmd.OutOrStdout(), "Do you want to re-authenticate? [y/N]: ")
 		var response string
 		fmt.Scanln(&response)
@@ -55,11 +76,17 @@ func runAuth(cmd *cobra.Command, args []string) error {
 
 	// Run OAuth flow
 	auth := google.NewOAuthAuthenticator(
-		cfg.GoogleApplicationCredentials,
-		cfg.GoogleUserCredentials,
+		cfg.OAuthClientCredentials(),
+		store,
+		scopes...,
 	)
 
-	if err := auth.Authenticate(); err != nil {
+	if noBrowser {
+		err = auth.AuthenticateManual(cmd.InOrStdin(), cmd.OutOrStdout())
+	} else {
+		err = auth.Authenticate()
+	}
+	if err != nil {
 		return fmt.Errorf("authentication failed: %w", err)
 	}
 
@@ -67,7 +94,92 @@ func runAuth(cmd *cobra.Command, args []string) error {
 	return nil
 }
 
+// authStatusCmd represents the auth status command
+var authStatusCmd = &cobra.Command{
+	Use:   "status",
+	Short: "Show the current token's validity, scopes, expiry and email",
+	Long: `Show the current token's validity, scopes, expiry and email.
+
+The token is valid when Gmail accepts it. A missing, expired or revoked token
+is shown with the reason, and the command exits with status 5.`,
+	Args: cobra.NoArgs,
+	RunE: runAuthStatus,
+}
+
+// authRevokeCmd represents the auth revoke command
+var authRevokeCmd = &cobra.Command{
+	Use:   "revoke",
+	Short: "Revoke the current token and delete it locally",
+	Long: `Revoke the current token with Google's revocation endpoint and delete
+the local copy. Run 'gml auth' afterwards to obtain a new token.`,
+	Args: cobra.NoArgs,
+	RunE: runAuthRevoke,
+}
+
+func runAuthStatus(cmd *cobra.Command, args []string) error {
+	ctx := cmd.Context()
+	cfg, err := GetConfig(cmd)
+	if err != nil {
+		return err
+	}
+
+	// Get flags
+	format := formatFromFlags(cmd)
+
+	status, err := gml.GetAuthStatus(ctx, cfg)
+	if err != nil {
+		return fmt.Errorf("unable to get auth status: %w", err)
+	}
+
+	// Output
+	if err := gml.FormatAuthStatus(cmd.OutOrStdout(), status, format); err != nil {
+		return fmt.Errorf("unable to format output: %w", err)
+	}
+	if !status.Valid {
+		return &ExitError{Code: exitCodeAuthExpired}
+	}
+	return nil
+}
+
+func runAuthRevoke(cmd *cobra.Command, args []string) error {
+	ctx := cmd.Context()
+	cfg, err := GetConfig(cmd)
+	if err != nil {
+		return err
+	}
+
+	// Get flags
+	yes, _ := cmd.Flags().GetBool("yes")
+
+	if !yes {
+		fmt.Fprint(cmd.OutOrStdout(), "Revoke the current token? [y/N]: ")
+		var response string
+		fmt.Scanln(&response)
+		if response != "y" && response != "Y" {
+			fmt.Fprintln(cmd.OutOrStdout(), "Cancelled.")
+			return nil
+		}
+	}
+
+	if err := gml.RevokeAuth(ctx, cfg); err != nil {
+		return fmt.Errorf("unable to revoke token: %w", err)
+	}
+	if gml.IsDryRun(ctx) {
+		return nil
+	}
+
+	fmt.Fprintln(cmd.OutOrStdout(), "Token revoked.")
+	return nil
+}
+
 func init() {
 	rootCmd.AddCommand(authCmd)
+	authCmd.AddCommand(authStatusCmd)
+	authCmd.AddCommand(authRevokeCmd)
+
+	authCmd.Flags().Bool("no-browser", false, "Authenticate by pasting the redirect URL instead of using a local browser")
+	setFormats(authStatusCmd, gml.OutputFormatText, gml.OutputFormatJSON)
+	authRevokeCmd.Flags().BoolP("yes", "y", false, "Skip the confirmation prompt")
+
 	authCmd.SetOut(os.Stdout)
 }
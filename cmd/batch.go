@@ -0,0 +1,72 @@
+/*
+Copyright © 2025 longkey1
+
+Licensed under the Apache License, Version 2.0 (the "License");
+you may not use this file except in compliance with the License.
+You may obtain a copy of the License at
+
+	http://www.apache.org/licenses/LICENSE-2.0
+
+Unless required by applicable law or agreed to in writing, software
+distributed under the License is distributed on an "AS IS" BASIS,
+WITHOUT WARRANTIES OR CONDITIONS OF ANY KIND, either express or implied.
+See the License for the specific language governing permissions and
+limitations under the License.
+*/
+package cmd
+
+import (
+	"fmt"
+	"strings"
+
+	"github.com/longkey1/gml/internal/gml"
+	"github.com/spf13/cobra"
+)
+
+// exitCodePartialFailure is the exit status when --ignore-errors skipped failed messages
+const exitCodePartialFailure = 3
+
+// addIgnoreErrorsFlag adds --ignore-errors to a command processing many messages
+func addIgnoreErrorsFlag(cmd *cobra.Command) {
+	cmd.Flags().Bool("ignore-errors", false, fmt.Sprintf("Continue past failures on individual messages, list them at the end and exit with status %d", exitCodePartialFailure))
+}
+
+// failureLogFromFlags returns the failure log for a batch operation
+func failureLogFromFlags(cmd *cobra.Command) *gml.FailureLog {
+	ignore, _ := cmd.Flags().GetBool("ignore-errors")
+	return &gml.FailureLog{Ignore: ignore}
+}
+
+// messageIDArgs returns the message IDs given as arguments, reading them
+// whitespace-separated from stdin for "-"
+func messageIDArgs(cmd *cobra.Command, args []string) ([]string, error) {
+	var ids []string
+	for _, arg := range args {
+		if arg != "-" {
+			ids = append(ids, arg)
+			continue
+		}
+		data, err := readInput(cmd, arg)
+		if err != nil {
+			return nil, err
+		}
+		ids = append(ids, strings.Fields(string(data))...)
+	}
+	if len(ids) == 0 {
+		return nil, fmt.Errorf("no message IDs given")
+	}
+	return ids, nil
+}
+
+// reportFailures prints the messages skipped under --ignore-errors to stderr
+// and returns an error carrying exitCodePartialFailure, or nil if none failed
+func reportFailures(cmd *cobra.Command, failures *gml.FailureLog) error {
+	if failures == nil || len(failures.Items) == 0 {
+		return nil
+	}
+	fmt.Fprintf(cmd.ErrOrStderr(), "%d messages failed:\n", len(failures.Items))
+	for _, f := range failures.Items {
+		fmt.Fprintf(cmd.ErrOrStderr(), "  %s: %s\n", f.ID, f.Error)
+	}
+	return &ExitError{Code: exitCodePartialFailure}
+}
@@ -0,0 +1,106 @@
+/*
+Copyright © 2025 longkey1
+
+Licensed under the Apache License, Version 2.0 (the "License");
+you may not use this file except in compliance with the License.
+You may obtain a copy of the License at
+
+	http://www.apache.org/licenses/LICENSE-2.0
+
+Unless required by applicable law or agreed to in writing, software
+distributed under the License is distributed on an "AS IS" BASIS,
+WITHOUT WARRANTIES OR CONDITIONS OF ANY KIND, either express or implied.
+See the License for the specific language governing permissions and
+limitations under the License.
+*/
+package cmd
+
+import (
+	"context"
+	"fmt"
+	"os"
+	"time"
+
+	"github.com/longkey1/gml/internal/gml"
+	"github.com/spf13/cobra"
+)
+
+// bouncesCmd represents the bounces command
+var bouncesCmd = &cobra.Command{
+	Use:   "bounces",
+	Short: "List failed deliveries from bounce messages",
+	Long: `Search for delivery status notifications from mailer-daemon/postmaster and
+report each failed recipient with its status code and reason.
+
+The failed recipient, status and diagnostic are read from the
+message/delivery-status part (RFC 3464), falling back to the
+X-Failed-Recipients header. Delay warnings are skipped.
+
+Examples:
+  gml bounces --since 7d
+  gml bounces --since 30d -q "subject:newsletter" --format text
+  gml bounces --since 7d --format ndjson | jq -r .recipient
+  gml bounces --account all --since 1d`,
+	RunE: runBounces,
+}
+
+func runBounces(cmd *cobra.Command, args []string) error {
+	ctx := cmd.Context()
+	cfgs, err := GetAccountConfigs(cmd)
+	if err != nil {
+		return err
+	}
+
+	// Get flags
+	sinceStr, _ := cmd.Flags().GetString("since")
+	query, _ := cmd.Flags().GetString("query")
+	format := formatFromFlags(cmd)
+
+	var since time.Duration
+	if sinceStr != "" {
+		d, err := gml.ParseAge(sinceStr)
+		if err != nil {
+			return fmt.Errorf("invalid --since: %w", err)
+		}
+		since = d
+	}
+
+	// Find bounces (concurrently per account with --account all)
+	tagAccount, err := allAccountsSelected(cmd)
+	if err != nil {
+		return err
+	}
+	results := gml.ForEachAccount(ctx, cfgs, func(ctx context.Context, svc *gml.Service) ([]gml.Bounce, error) {
+		return gml.ListBounces(ctx, svc, gml.BounceOptions{
+			Since: since,
+			Query: query,
+		})
+	})
+	bounces, errs := gml.MergeAccountResults(results, func(b *gml.Bounce, account string) {
+		if tagAccount {
+			b.Account = account
+		}
+	})
+	partial, err := reportAccountErrors(cmd, errs, len(cfgs))
+	if err != nil {
+		return fmt.Errorf("unable to list bounces: %w", err)
+	}
+
+	// Output
+	if err := gml.FormatBounces(cmd.OutOrStdout(), bounces, format); err != nil {
+		return fmt.Errorf("unable to format output: %w", err)
+	}
+
+	return partial
+}
+
+func init() {
+	rootCmd.AddCommand(bouncesCmd)
+
+	bouncesCmd.Flags().String("since", "7d", "Only bounces newer than an age (e.g. 12h, 7d); empty for all")
+	bouncesCmd.Flags().StringP("query", "q", "", "Additional search query (Gmail search syntax)")
+	setFormats(bouncesCmd, gml.OutputFormatJSON, gml.OutputFormatNDJSON, gml.OutputFormatText)
+
+	// Set custom output to enable testing
+	bouncesCmd.SetOut(os.Stdout)
+}
@@ -0,0 +1,177 @@
+/*
+Copyright © 2025 longkey1
+
+Licensed under the Apache License, Version 2.0 (the "License");
+you may not use this file except in compliance with the License.
+You may obtain a copy of the License at
+
+	http://www.apache.org/licenses/LICENSE-2.0
+
+Unless required by applicable law or agreed to in writing, software
+distributed under the License is distributed on an "AS IS" BASIS,
+WITHOUT WARRANTIES OR CONDITIONS OF ANY KIND, either express or implied.
+See the License for the specific language governing permissions and
+limitations under the License.
+*/
+package cmd
+
+import (
+	"fmt"
+	"os"
+
+	"github.com/longkey1/gml/internal/gml"
+	"github.com/spf13/cobra"
+)
+
+// bulkCmd represents the bulk command
+var bulkCmd = &cobra.Command{
+	Use:   "bulk [message-id]...",
+	Short: "Add and remove labels on many messages at once",
+	Long: `Add and remove labels on message IDs, or on every message matching a query.
+Messages are changed with BatchModify in batches of 1000; progress is shown on
+stderr. Labels to add are created if they don't exist.
+
+Message IDs are read from stdin when given as -. With --thread, the change
+applies to the whole conversations of the given or matching messages.
+Requires the "modify" scope (see the scopes config option).
+
+Examples:
+  gml bulk -q "older_than:1y label:promotions" --add-label archive-old --remove-label INBOX
+  gml bulk -q "from:alerts@example.com" --remove-label UNREAD --dry-run  # Count matches only
+  gml bulk 18abc123def456 18abc123def789 --add-label Receipts
+  gml bulk -q "from:boss@example.com is:unread" --thread --remove-label UNREAD  # Read the whole threads`,
+	RunE: runBulk,
+}
+
+func runBulk(cmd *cobra.Command, args []string) error {
+	ctx := cmd.Context()
+	cfg, err := GetConfig(cmd)
+	if err != nil {
+		return err
+	}
+
+	// Get flags
+	query, labels, err := queryFromFlags(cmd)
+	if err != nil {
+		return err
+	}
+	addLabels, _ := cmd.Flags().GetStringArray("add-label")
+	removeLabels, _ := cmd.Flags().GetStringArray("remove-label")
+	dryRun := gml.IsDryRun(ctx)
+	thread, _ := cmd.Flags().GetBool("thread")
+	outputFormat := formatFromFlags(cmd)
+
+	var ids []string
+	if len(args) > 0 {
+		if query != "" || len(labels) > 0 {
+			return fmt.Errorf("message IDs cannot be combined with --query or --label")
+		}
+		if ids, err = messageIDArgs(cmd, args); err != nil {
+			return err
+		}
+	} else if query == "" && len(labels) == 0 {
+		return fmt.Errorf("message IDs, --query or --label is required")
+	}
+
+	// Create service
+	svc, err := gml.NewService(ctx, cfg)
+	if err != nil {
+		return fmt.Errorf("unable to create service: %w", err)
+	}
+
+	if thread {
+		return runBulkThreads(cmd, svc, ids, query, labels, addLabels, removeLabels, dryRun)
+	}
+
+	failures := failureLogFromFlags(cmd)
+	progress := newProgress(cmd, "Modifying messages")
+	result, err := gml.BulkModify(ctx, svc, gml.BulkModifyOptions{
+		IDs:          ids,
+		Query:        query,
+		LabelIDs:     labels,
+		AddLabels:    addLabels,
+		RemoveLabels: removeLabels,
+		DryRun:       dryRun,
+		Failures:     failures,
+		Progress:     progress.Update,
+	})
+	progress.Done()
+	if err != nil {
+		return fmt.Errorf("unable to modify messages: %w", err)
+	}
+
+	// Output
+	switch {
+	case outputFormat == gml.OutputFormatJSON:
+		if err := gml.FormatJSON(cmd.OutOrStdout(), result); err != nil {
+			return err
+		}
+	case dryRun:
+		fmt.Fprintf(cmd.OutOrStdout(), "Would modify %d messages\n", result.Matched)
+	default:
+		fmt.Fprintf(cmd.OutOrStdout(), "Modified %d of %d messages\n", result.Modified, result.Matched)
+	}
+
+	return reportFailures(cmd, failures)
+}
+
+// runBulkThreads applies the label changes of bulk --thread to the threads of
+// the message IDs, or to the threads matching the query
+func runBulkThreads(cmd *cobra.Command, svc *gml.Service, ids []string, query string, labels, addLabels, removeLabels []string, dryRun bool) error {
+	ctx := cmd.Context()
+	var threadIDs []string
+	if len(ids) > 0 {
+		var err error
+		if threadIDs, err = gml.ThreadIDsOf(ctx, svc, ids); err != nil {
+			return err
+		}
+	}
+
+	failures := failureLogFromFlags(cmd)
+	progress := newProgress(cmd, "Modifying threads")
+	result, err := gml.ModifyThreads(ctx, svc, gml.ThreadModifyOptions{
+		IDs:          threadIDs,
+		Query:        query,
+		LabelIDs:     labels,
+		Action:       gml.ThreadLabel,
+		AddLabels:    addLabels,
+		RemoveLabels: removeLabels,
+		DryRun:       dryRun,
+		Failures:     failures,
+		Progress:     progress.Update,
+	})
+	progress.Done()
+	if err != nil {
+		return fmt.Errorf("unable to modify threads: %w", err)
+	}
+
+	// Output
+	switch {
+	case formatFromFlags(cmd) == gml.OutputFormatJSON:
+		if err := gml.FormatJSON(cmd.OutOrStdout(), result); err != nil {
+			return err
+		}
+	case dryRun:
+		fmt.Fprintf(cmd.OutOrStdout(), "Would modify %d threads\n", result.Matched)
+	default:
+		fmt.Fprintf(cmd.OutOrStdout(), "Modified %d of %d threads\n", result.Modified, result.Matched)
+	}
+
+	return reportFailures(cmd, failures)
+}
+
+func init() {
+	rootCmd.AddCommand(bulkCmd)
+
+	bulkCmd.Flags().StringP("query", "q", "", "Search query (Gmail search syntax)")
+	addQueryFlags(bulkCmd)
+	bulkCmd.Flags().StringArrayP("label", "l", nil, "Filter by label (can be specified multiple times)")
+	bulkCmd.Flags().StringArray("add-label", nil, "Label name or ID to add (can be specified multiple times; created if missing)")
+	bulkCmd.Flags().StringArray("remove-label", nil, "Label name or ID to remove (can be specified multiple times)")
+	bulkCmd.Flags().Bool("thread", false, "Change the whole threads of the messages (Threads.Modify)")
+	addIgnoreErrorsFlag(bulkCmd)
+	setFormats(bulkCmd, gml.OutputFormatText, gml.OutputFormatJSON)
+
+	// Set custom output to enable testing
+	bulkCmd.SetOut(os.Stdout)
+}
@@ -0,0 +1,233 @@
+/*
+Copyright © 2025 longkey1
+
+Licensed under the Apache License, Version 2.0 (the "License");
+you may not use this file except in compliance with the License.
+You may obtain a copy of the License at
+
+	http://www.apache.org/licenses/LICENSE-2.0
+
+Unless required by applicable law or agreed to in writing, software
+distributed under the License is distributed on an "AS IS" BASIS,
+WITHOUT WARRANTIES OR CONDITIONS OF ANY KIND, either express or implied.
+See the License for the specific language governing permissions and
+limitations under the License.
+*/
+package cmd
+
+import (
+	"errors"
+	"fmt"
+	"os"
+
+	"github.com/longkey1/gml/internal/gml"
+	"github.com/pelletier/go-toml/v2"
+	"github.com/spf13/cobra"
+)
+
+// configCmd represents the config command
+var configCmd = &cobra.Command{
+	Use:   "config",
+	Short: "Create, inspect and edit the config file",
+	Long: `Create, inspect and edit the config file.
+
+Examples:
+  gml config init                 # Write a commented default config
+  gml config path
+  gml config show                 # Effective settings, secrets redacted
+  gml config edit                 # Open in $EDITOR, then validate
+  gml config validate`,
+}
+
+// configInitCmd represents the config init command
+var configInitCmd = &cobra.Command{
+	Use:   "init",
+	Short: "Write a commented default config file",
+	Long: `Write a config file listing every setting, commented out, with a short
+explanation. It is written to --config or ~/.config/gml/config.toml.
+
+Examples:
+  gml config init
+  gml config init --force         # Replace an existing file
+  gml --config ./gml.toml config init`,
+	Args: cobra.NoArgs,
+	RunE: runConfigInit,
+}
+
+// configPathCmd represents the config path command
+var configPathCmd = &cobra.Command{
+	Use:   "path",
+	Short: "Print the path of the config file",
+	Args:  cobra.NoArgs,
+	RunE:  runConfigPath,
+}
+
+// configShowCmd represents the config show command
+var configShowCmd = &cobra.Command{
+	Use:   "show",
+	Short: "Print the effective configuration",
+	Long: `Print the configuration in effect for the selected account: the config
+file with defaults applied and the account profile merged in. Empty settings
+are left out and secrets are redacted.
+
+Examples:
+  gml config show
+  gml --account work config show
+  gml config show --json`,
+	Args: cobra.NoArgs,
+	RunE: runConfigShow,
+}
+
+// configEditCmd represents the config edit command
+var configEditCmd = &cobra.Command{
+	Use:   "edit",
+	Short: "Open the config file in $EDITOR",
+	Long: `Open the config file in $VISUAL or $EDITOR (default vi), creating it from
+the default template if it doesn't exist, and validate it afterwards.
+
+Examples:
+  gml config edit
+  EDITOR="code --wait" gml config edit`,
+	Args: cobra.NoArgs,
+	RunE: runConfigEdit,
+}
+
+// configValidateCmd represents the config validate command
+var configValidateCmd = &cobra.Command{
+	Use:   "validate",
+	Short: "Check the config file for mistakes",
+	Long: `Check the config file for unknown keys, invalid values and account
+profiles that can't be used. Exits with status 1 if problems are found.
+
+Examples:
+  gml config validate
+  gml --config ./gml.toml config validate`,
+	Args: cobra.NoArgs,
+	RunE: runConfigValidate,
+}
+
+// configPath returns the config file that was read, or the one that would be
+func configPath(cmd *cobra.Command) (string, error) {
+	if path := currentInvocation(cmd).configFile; path != "" {
+		return path, nil
+	}
+	if path, _ := cmd.Flags().GetString("config"); path != "" {
+		return path, nil
+	}
+	return gml.DefaultConfigPath()
+}
+
+func runConfigInit(cmd *cobra.Command, args []string) error {
+	// Get flags
+	force, _ := cmd.Flags().GetBool("force")
+
+	path, err := configPath(cmd)
+	if err != nil {
+		return err
+	}
+	if err := gml.WriteConfigTemplate(path, force); err != nil {
+		return err
+	}
+
+	fmt.Fprintf(cmd.OutOrStdout(), "Wrote %s\n", path)
+	return nil
+}
+
+func runConfigPath(cmd *cobra.Command, args []string) error {
+	path, err := configPath(cmd)
+	if err != nil {
+		return err
+	}
+	if _, err := os.Stat(path); errors.Is(err, os.ErrNotExist) {
+		fmt.Fprintln(cmd.ErrOrStderr(), "The config file does not exist; create it with 'gml config init'.")
+	}
+
+	fmt.Fprintln(cmd.OutOrStdout(), path)
+	return nil
+}
+
+func runConfigShow(cmd *cobra.Command, args []string) error {
+	cfg, err := GetConfig(cmd)
+	if err != nil {
+		return err
+	}
+	settings := gml.ConfigSettings(cfg)
+
+	// Output
+	if formatFromFlags(cmd) == gml.OutputFormatJSON {
+		return gml.FormatJSON(cmd.OutOrStdout(), settings)
+	}
+	data, err := toml.Marshal(settings)
+	if err != nil {
+		return fmt.Errorf("unable to format config: %w", err)
+	}
+	_, err = cmd.OutOrStdout().Write(data)
+	return err
+}
+
+func runConfigEdit(cmd *cobra.Command, args []string) error {
+	path, err := configPath(cmd)
+	if err != nil {
+		return err
+	}
+	if _, err := os.Stat(path); errors.Is(err, os.ErrNotExist) {
+		if err := gml.WriteConfigTemplate(path, false); err != nil {
+			return err
+		}
+	}
+
+	if err := runEditor(cmd, path); err != nil {
+		return err
+	}
+
+	return validateConfig(cmd, path)
+}
+
+func runConfigValidate(cmd *cobra.Command, args []string) error {
+	path, err := configPath(cmd)
+	if err != nil {
+		return err
+	}
+	return validateConfig(cmd, path)
+}
+
+// validateConfig prints the problems of the config file at path, failing
+// with status 1 if there are any
+func validateConfig(cmd *cobra.Command, path string) error {
+	problems, err := gml.ValidateConfigFile(path)
+	if err != nil {
+		return err
+	}
+	if cfg, _, err := gml.ReadConfig(path); err == nil && cfg != nil {
+		problems = append(problems, commandDefaultProblems(cfg)...)
+	}
+	if len(problems) == 0 {
+		fmt.Fprintf(cmd.OutOrStdout(), "%s: OK\n", path)
+		return nil
+	}
+
+	for _, p := range problems {
+		fmt.Fprintf(cmd.OutOrStdout(), "%s: %s\n", path, p)
+	}
+	return &ExitError{Code: 1, Err: fmt.Errorf("%d problems found in %s", len(problems), path)}
+}
+
+func init() {
+	rootCmd.AddCommand(configCmd)
+	configCmd.AddCommand(configInitCmd)
+	configCmd.AddCommand(configPathCmd)
+	configCmd.AddCommand(configShowCmd)
+	configCmd.AddCommand(configEditCmd)
+	configCmd.AddCommand(configValidateCmd)
+
+	// These must work on a config file that fails to load
+	for _, c := range []*cobra.Command{configInitCmd, configPathCmd, configEditCmd, configValidateCmd} {
+		c.Annotations = map[string]string{configOptionalAnnotation: ""}
+	}
+
+	configInitCmd.Flags().Bool("force", false, "Overwrite an existing config file")
+	setFormats(configShowCmd, gml.OutputFormatText, gml.OutputFormatJSON)
+
+	// Set custom output to enable testing
+	configCmd.SetOut(os.Stdout)
+}
@@ -0,0 +1,110 @@
+/*
+Copyright © 2025 longkey1
+
+Licensed under the Apache License, Version 2.0 (the "License");
+you may not use this file except in compliance with the License.
+You may obtain a copy of the License at
+
+	http://www.apache.org/licenses/LICENSE-2.0
+
+Unless required by applicable law or agreed to in writing, software
+distributed under the License is distributed on an "AS IS" BASIS,
+WITHOUT WARRANTIES OR CONDITIONS OF ANY KIND, either express or implied.
+See the License for the specific language governing permissions and
+limitations under the License.
+*/
+package cmd
+
+import (
+	"fmt"
+	"os"
+	"slices"
+
+	"github.com/longkey1/gml/internal/gml"
+	"github.com/spf13/cobra"
+)
+
+// contactsCmd represents the contacts command
+var contactsCmd = &cobra.Command{
+	Use:   "contacts",
+	Short: "Look up contacts with the People API",
+	Long: `Look up your Google contacts and "Other contacts", the people you have
+interacted with. The same lookup resolves recipients given by name, e.g.
+'gml send --to bob', to their email address.
+
+Contact lookups need the "contacts" and/or "contacts.other" scope; add them
+to scopes in the config file and run 'gml auth' again.
+
+Examples:
+  gml contacts search bob`,
+}
+
+// contactsSearchCmd represents the contacts search command
+var contactsSearchCmd = &cobra.Command{
+	Use:   "search <name>",
+	Short: "Search contacts by name or email address",
+	Long: `Search contacts and other contacts by a prefix of a name or email address,
+and list each matching email address with the name and where it was found.
+
+Examples:
+  gml contacts search bob
+  gml contacts search "bob sm" --limit 30
+  gml contacts search alice --json | jq -r '.[0].email'`,
+	Args: cobra.ExactArgs(1),
+	RunE: runContactsSearch,
+}
+
+func runContactsSearch(cmd *cobra.Command, args []string) error {
+	ctx := cmd.Context()
+	cfg, err := GetConfig(cmd)
+	if err != nil {
+		return err
+	}
+
+	// Get flags
+	limit, _ := cmd.Flags().GetInt("limit")
+
+	// Create service
+	svc, err := gml.NewService(ctx, cfg)
+	if err != nil {
+		return fmt.Errorf("unable to create service: %w", err)
+	}
+
+	contacts, err := gml.SearchContacts(ctx, svc, args[0], limit)
+	if err != nil {
+		return err
+	}
+
+	// Output
+	if len(contacts) == 0 && formatFromFlags(cmd) == gml.OutputFormatText {
+		fmt.Fprintln(cmd.OutOrStdout(), "No contacts found.")
+		return nil
+	}
+	return gml.FormatContacts(cmd.OutOrStdout(), contacts, formatFromFlags(cmd))
+}
+
+// resolveContactNames replaces recipients given by name with the address of
+// the matching contact. The service is only created when there are names
+func resolveContactNames(cmd *cobra.Command, cfg *gml.Config, msg *gml.OutgoingMessage) error {
+	recipients := slices.Concat(msg.To, msg.Cc, msg.Bcc)
+	if !slices.ContainsFunc(recipients, gml.IsContactName) {
+		return nil
+	}
+
+	svc, err := gml.NewService(cmd.Context(), cfg)
+	if err != nil {
+		return fmt.Errorf("unable to create service: %w", err)
+	}
+	return gml.ResolveRecipients(cmd.Context(), svc, msg)
+}
+
+func init() {
+	rootCmd.AddCommand(contactsCmd)
+	contactsCmd.AddCommand(contactsSearchCmd)
+
+	setFormats(contactsSearchCmd, gml.OutputFormatText, gml.OutputFormatJSON)
+	contactsSearchCmd.Flags().IntP("limit", "n", 10, "Maximum results from each of contacts and other contacts (up to 30)")
+
+	// Set custom output to enable testing
+	contactsCmd.SetOut(os.Stdout)
+}
@@ -0,0 +1,103 @@
+/*
+Copyright © 2025 longkey1
+
+Licensed under the Apache License, Version 2.0 (the "License");
+you may not use this file except in compliance with the License.
+You may obtain a copy of the License at
+
+	http://www.apache.org/licenses/LICENSE-2.0
+
+Unless required by applicable law or agreed to in writing, software
+distributed under the License is distributed on an "AS IS" BASIS,
+WITHOUT WARRANTIES OR CONDITIONS OF ANY KIND, either express or implied.
+See the License for the specific language governing permissions and
+limitations under the License.
+*/
+package cmd
+
+import (
+	"fmt"
+	"os"
+
+	"github.com/longkey1/gml/internal/gml"
+	"github.com/spf13/cobra"
+)
+
+// countCmd represents the count command
+var countCmd = &cobra.Command{
+	Use:   "count",
+	Short: "Count messages matching a query",
+	Long: `Count the messages matching a query and labels without fetching them.
+
+By default the count is Gmail's estimate from a single request, which is
+exact for small results but approximate for large ones. --exact pages
+through all matching message IDs instead.
+
+Examples:
+  gml count -l INBOX -l UNREAD              # Unread messages in the inbox
+  gml count -q "from:billing@example.com" --newer-than 30d
+  gml count --saved receipts --exact        # Exact count of a saved search
+  gml count -l INBOX --json                 # {"count": 42, "exact": false, ...}`,
+	Args: cobra.NoArgs,
+	RunE: runCount,
+}
+
+func runCount(cmd *cobra.Command, args []string) error {
+	ctx := cmd.Context()
+	cfg, err := GetConfig(cmd)
+	if err != nil {
+		return err
+	}
+
+	// Get flags
+	exact, _ := cmd.Flags().GetBool("exact")
+	query, labels, err := queryFromFlags(cmd)
+	if err != nil {
+		return err
+	}
+	if query, labels, err = savedSearchFromFlags(cmd, query, labels); err != nil {
+		return err
+	}
+	if query, err = searchQueryFromFlags(cmd, query); err != nil {
+		return err
+	}
+
+	// Create service
+	svc, err := gml.NewService(ctx, cfg)
+	if err != nil {
+		return fmt.Errorf("unable to create service: %w", err)
+	}
+
+	opts := gml.CountOptions{Query: query, LabelIDs: labels, Exact: exact}
+	progress := newProgress(cmd, "Counting messages")
+	if exact {
+		opts.Progress = progress.Update
+	}
+	result, err := gml.CountMessages(ctx, svc, opts)
+	progress.Done()
+	if err != nil {
+		return err
+	}
+
+	// Output
+	if formatFromFlags(cmd) == gml.OutputFormatJSON {
+		return gml.FormatJSON(cmd.OutOrStdout(), result)
+	}
+	fmt.Fprintln(cmd.OutOrStdout(), result.Count)
+	return nil
+}
+
+func init() {
+	rootCmd.AddCommand(countCmd)
+
+	countCmd.Flags().StringP("query", "q", "", "Search query (Gmail search syntax)")
+	countCmd.Flags().StringArrayP("label", "l", nil, "Filter by label (can be specified multiple times)")
+	addQueryFlags(countCmd)
+	addSearchFlags(countCmd)
+	addSavedFlag(countCmd)
+	countCmd.Flags().Bool("exact", false, "Count by listing every matching message ID instead of using Gmail's estimate")
+	setFormats(countCmd, gml.OutputFormatText, gml.OutputFormatJSON)
+
+	// Set custom output to enable testing
+	countCmd.SetOut(os.Stdout)
+}
@@ -0,0 +1,52 @@
+/*
+Copyright © 2025 longkey1
+
+Licensed under the Apache License, Version 2.0 (the "License");
+you may not use this file except in compliance with the License.
+You may obtain a copy of the License at
+
+	http://www.apache.org/licenses/LICENSE-2.0
+
+Unless required by applicable law or agreed to in writing, software
+distributed under the License is distributed on an "AS IS" BASIS,
+WITHOUT WARRANTIES OR CONDITIONS OF ANY KIND, either express or implied.
+See the License for the specific language governing permissions and
+limitations under the License.
+*/
+package cmd
+
+import (
+	"fmt"
+
+	"github.com/longkey1/gml/internal/gml"
+	"github.com/spf13/cobra"
+)
+
+// addCSVFlags adds the CSV/TSV regional options to a command
+func addCSVFlags(cmd *cobra.Command) {
+	cmd.Flags().String("delimiter", "", "CSV field delimiter: a character or comma, semicolon, tab, pipe")
+	cmd.Flags().Bool("crlf", false, "End CSV lines with CRLF")
+	cmd.Flags().Bool("bom", false, "Write a UTF-8 byte order mark before CSV output (for Excel)")
+}
+
+// csvOptionsFromFlags returns the CSV options given by flags, starting from
+// base so that flags only override values that were set
+func csvOptionsFromFlags(cmd *cobra.Command, base gml.CSVOptions) (gml.CSVOptions, error) {
+	opts := base
+	flags := cmd.Flags()
+	if flagSet(cmd, "delimiter") {
+		delimiter, _ := flags.GetString("delimiter")
+		d, err := gml.ParseDelimiter(delimiter)
+		if err != nil {
+			return opts, fmt.Errorf("invalid --delimiter: %w", err)
+		}
+		opts.Delimiter = d
+	}
+	if flagSet(cmd, "crlf") {
+		opts.CRLF, _ = flags.GetBool("crlf")
+	}
+	if flagSet(cmd, "bom") {
+		opts.BOM, _ = flags.GetBool("bom")
+	}
+	return opts, nil
+}
@@ -0,0 +1,95 @@
+/*
+Copyright © 2025 longkey1
+
+Licensed under the Apache License, Version 2.0 (the "License");
+you may not use this file except in compliance with the License.
+You may obtain a copy of the License at
+
+	http://www.apache.org/licenses/LICENSE-2.0
+
+Unless required by applicable law or agreed to in writing, software
+distributed under the License is distributed on an "AS IS" BASIS,
+WITHOUT WARRANTIES OR CONDITIONS OF ANY KIND, either express or implied.
+See the License for the specific language governing permissions and
+limitations under the License.
+*/
+package cmd
+
+import (
+	"context"
+	"fmt"
+	"os"
+
+	"github.com/longkey1/gml/internal/gml"
+	"github.com/spf13/cobra"
+)
+
+// dashboardCmd represents the dashboard command
+var dashboardCmd = &cobra.Command{
+	Use:   "dashboard",
+	Short: "Show a compact mailbox overview",
+	Long: `Show a compact mailbox overview: unread counts per label, messages
+received today, the age of the oldest unread inbox message, and the number
+of drafts.
+
+Without --label, INBOX and every user label with unread messages are shown.
+
+Examples:
+  gml dashboard
+  gml dashboard -l INBOX -l Work -l Support
+  gml dashboard --account all
+  gml dashboard --format json`,
+	RunE: runDashboard,
+}
+
+func runDashboard(cmd *cobra.Command, args []string) error {
+	ctx := cmd.Context()
+	cfgs, err := GetAccountConfigs(cmd)
+	if err != nil {
+		return err
+	}
+
+	// Get flags
+	labels := labelsFromFlags(cmd)
+	format := formatFromFlags(cmd)
+
+	// Build dashboards (concurrently per account with --account all)
+	tagAccount, err := allAccountsSelected(cmd)
+	if err != nil {
+		return err
+	}
+	results := gml.ForEachAccount(ctx, cfgs, func(ctx context.Context, svc *gml.Service) ([]gml.Dashboard, error) {
+		d, err := gml.GetDashboard(ctx, svc, gml.DashboardOptions{Labels: labels})
+		if err != nil {
+			return nil, err
+		}
+		return []gml.Dashboard{*d}, nil
+	})
+	dashboards, errs := gml.MergeAccountResults(results, func(d *gml.Dashboard, account string) {
+		if tagAccount {
+			d.Account = account
+		}
+	})
+	partial, err := reportAccountErrors(cmd, errs, len(cfgs))
+	if err != nil {
+		return err
+	}
+
+	// Output
+	if err := gml.FormatDashboards(cmd.OutOrStdout(), dashboards, format); err != nil {
+		return fmt.Errorf("unable to format output: %w", err)
+	}
+
+	return partial
+}
+
+func init() {
+	rootCmd.AddCommand(dashboardCmd)
+
+	dashboardCmd.Flags().StringArrayP("label", "l", nil, "Label to show (can be specified multiple times)")
+	addLabelIDFlag(dashboardCmd)
+	setFormats(dashboardCmd, gml.OutputFormatText, gml.OutputFormatJSON)
+
+	// Set custom output to enable testing
+	dashboardCmd.SetOut(os.Stdout)
+}
@@ -0,0 +1,46 @@
+/*
+Copyright © 2025 longkey1
+
+Licensed under the Apache License, Version 2.0 (the "License");
+you may not use this file except in compliance with the License.
+You may obtain a copy of the License at
+
+	http://www.apache.org/licenses/LICENSE-2.0
+
+Unless required by applicable law or agreed to in writing, software
+distributed under the License is distributed on an "AS IS" BASIS,
+WITHOUT WARRANTIES OR CONDITIONS OF ANY KIND, either express or implied.
+See the License for the specific language governing permissions and
+limitations under the License.
+*/
+package cmd
+
+import (
+	"fmt"
+
+	"github.com/longkey1/gml/internal/gml"
+	"github.com/spf13/cobra"
+)
+
+// addDateFormatFlag adds the --date-format flag to a command
+func addDateFormatFlag(cmd *cobra.Command) {
+	cmd.Flags().String("date-format", "", "Date display: raw, relative, rfc3339, rfc1123z, datetime, date, time or a Go layout (default: date_format in config, else rfc3339)")
+}
+
+// dateFormatFromFlags returns the --date-format value, falling back to the
+// date_format config setting and then the default
+func dateFormatFromFlags(cmd *cobra.Command) (string, error) {
+	format, _ := cmd.Flags().GetString("date-format")
+	source := "--date-format"
+	if !flagSet(cmd, "date-format") {
+		format = getBaseConfig(cmd).DateFormat
+		source = "date_format"
+	}
+	if format == "" {
+		return gml.DefaultDateFormat, nil
+	}
+	if err := gml.ValidateDateFormat(format); err != nil {
+		return "", fmt.Errorf("invalid %s: %w", source, err)
+	}
+	return format, nil
+}
@@ -0,0 +1,188 @@
+/*
+Copyright © 2025 longkey1
+
+Licensed under the Apache License, Version 2.0 (the "License");
+you may not use this file except in compliance with the License.
+You may obtain a copy of the License at
+
+	http://www.apache.org/licenses/LICENSE-2.0
+
+Unless required by applicable law or agreed to in writing, software
+distributed under the License is distributed on an "AS IS" BASIS,
+WITHOUT WARRANTIES OR CONDITIONS OF ANY KIND, either express or implied.
+See the License for the specific language governing permissions and
+limitations under the License.
+*/
+package cmd
+
+import (
+	"fmt"
+	"sort"
+	"strings"
+
+	"github.com/longkey1/gml/internal/gml"
+	"github.com/spf13/cobra"
+	"github.com/spf13/pflag"
+)
+
+// commandLineOnlyFlags can't take defaults from config sections: they skip
+// confirmations, or select the config itself
+var commandLineOnlyFlags = map[string]bool{
+	"yes":     true,
+	"force":   true,
+	"dry-run": true,
+	"config":  true,
+	"account": true,
+}
+
+// exclusiveFlags lists flags whose config default is dropped when the other
+// one is given on the command line
+var exclusiveFlags = map[string]string{
+	"format": "json",
+	"json":   "format",
+}
+
+// applyConfigDefaults sets the flags of cmd that weren't given on the command
+// line from its config section ([list], [label.sync]) and returns them, with
+// the section, for the invocation. The flags are not marked as changed, so
+// config values behave like built-in defaults
+func applyConfigDefaults(cmd *cobra.Command, cfg *gml.Config) (map[string]string, error) {
+	// Commands are shared between executions: drop the config values of the
+	// previous one
+	cmd.Flags().VisitAll(func(f *pflag.Flag) {
+		if !f.Changed && f.Value.String() != f.DefValue {
+			resetFlagValue(f)
+		}
+	})
+
+	if cfg == nil {
+		return nil, nil
+	}
+	path := commandSectionPath(cmd)
+	if len(path) == 0 {
+		return nil, nil
+	}
+
+	section := "[" + strings.Join(path, ".") + "]"
+	defaults := make(map[string]string)
+	for name, value := range cfg.CommandDefaults(path) {
+		f := cmd.Flags().Lookup(name)
+		if f == nil {
+			return nil, fmt.Errorf("unknown flag --%s in config section %s", name, section)
+		}
+		if commandLineOnlyFlags[name] {
+			return nil, fmt.Errorf("--%s can't be set in config section %s, only on the command line", name, section)
+		}
+		if f.Changed {
+			continue
+		}
+		if other, ok := exclusiveFlags[name]; ok && cmd.Flags().Changed(other) {
+			continue
+		}
+		if err := setFlagValue(f, value); err != nil {
+			return nil, fmt.Errorf("invalid --%s in config section %s: %w", name, section, err)
+		}
+		defaults[name] = section
+	}
+	return defaults, nil
+}
+
+// flagSet reports whether a flag was given on the command line or set in the
+// command's config section, for settings that otherwise come from elsewhere
+func flagSet(cmd *cobra.Command, name string) bool {
+	if _, ok := currentInvocation(cmd).configDefaults[name]; ok {
+		return true
+	}
+	f := cmd.Flags().Lookup(name)
+	return f != nil && f.Changed
+}
+
+// commandSectionPath returns the config section of a command: its path below
+// the root, e.g. [label sync] for [label.sync]
+func commandSectionPath(cmd *cobra.Command) []string {
+	var path []string
+	for c := cmd; c.HasParent(); c = c.Parent() {
+		path = append([]string{c.Name()}, path...)
+	}
+	return path
+}
+
+// setFlagValue sets a flag from a decoded TOML value; arrays fill list flags
+func setFlagValue(f *pflag.Flag, value any) error {
+	list, isList := value.([]any)
+	if !isList {
+		return f.Value.Set(fmt.Sprint(value))
+	}
+
+	values := make([]string, len(list))
+	for i, v := range list {
+		values[i] = fmt.Sprint(v)
+	}
+	if sv, ok := f.Value.(pflag.SliceValue); ok {
+		return sv.Replace(values)
+	}
+	return f.Value.Set(strings.Join(values, ","))
+}
+
+// resetFlagValue sets a flag back to its default value
+func resetFlagValue(f *pflag.Flag) {
+	if sv, ok := f.Value.(pflag.SliceValue); ok {
+		var values []string
+		if def := strings.Trim(f.DefValue, "[]"); def != "" {
+			values = strings.Split(def, ",")
+		}
+		_ = sv.Replace(values)
+		return
+	}
+	_ = f.Value.Set(f.DefValue)
+}
+
+// commandDefaultProblems checks the config sections of commands: every
+// section must name a command and every key one of its flags, except those
+// that can only be given on the command line
+func commandDefaultProblems(cfg *gml.Config) []string {
+	var problems []string
+	var walk func(cmd *cobra.Command, section map[string]any, path []string)
+	walk = func(cmd *cobra.Command, section map[string]any, path []string) {
+		keys := make([]string, 0, len(section))
+		for key := range section {
+			keys = append(keys, key)
+		}
+		sort.Strings(keys)
+
+		for _, key := range keys {
+			name := strings.Join(append(path, key), ".")
+			if sub, ok := section[key].(map[string]any); ok {
+				if c := subcommand(cmd, key); c != nil {
+					walk(c, sub, append(path, key))
+				} else {
+					problems = append(problems, fmt.Sprintf("unknown section [%s]: no such command", name))
+				}
+				continue
+			}
+			if len(path) == 0 {
+				problems = append(problems, "unknown key: "+key)
+				continue
+			}
+			flag := strings.ReplaceAll(key, "_", "-")
+			switch {
+			case cmd.Flags().Lookup(flag) == nil && cmd.InheritedFlags().Lookup(flag) == nil:
+				problems = append(problems, fmt.Sprintf("%s: %s has no --%s flag", name, cmd.CommandPath(), flag))
+			case commandLineOnlyFlags[flag]:
+				problems = append(problems, fmt.Sprintf("%s: --%s can only be given on the command line", name, flag))
+			}
+		}
+	}
+	walk(rootCmd, cfg.Commands, nil)
+	return problems
+}
+
+// subcommand returns the direct subcommand of cmd with the given name
+func subcommand(cmd *cobra.Command, name string) *cobra.Command {
+	for _, c := range cmd.Commands() {
+		if c.Name() == name {
+			return c
+		}
+	}
+	return nil
+}
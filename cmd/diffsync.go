@@ -0,0 +1,122 @@
+/*
+Copyright © 2025 longkey1
+
+Licensed under the Apache License, Version 2.0 (the "License");
+you may not use this file except in compliance with the License.
+You may obtain a copy of the License at
+
+	http://www.apache.org/licenses/LICENSE-2.0
+
+Unless required by applicable law or agreed to in writing, software
+distributed under the License is distributed on an "AS IS" BASIS,
+WITHOUT WARRANTIES OR CONDITIONS OF ANY KIND, either express or implied.
+See the License for the specific language governing permissions and
+limitations under the License.
+*/
+package cmd
+
+import (
+	"fmt"
+	"os"
+	"strings"
+
+	"github.com/longkey1/gml/internal/gml"
+	"github.com/spf13/cobra"
+)
+
+// diffsyncCmd represents the diffsync command
+var diffsyncCmd = &cobra.Command{
+	Use:   "diffsync",
+	Short: "Compare messages between two accounts",
+	Long: `Compare the messages matching a query in two configured accounts by their
+Message-ID header and report messages present in one but not the other.
+
+With --copy, messages missing from the --to account are imported into it
+(requires the insert or modify scope on that account). Imported messages keep
+their original date and appear in All Mail without labels.
+
+Examples:
+  gml diffsync --from work --to personal -q "label:Keep"
+  gml diffsync --from old --to new -q "after:2024/01/01" --format json
+  gml diffsync --from old --to new -l Projects --copy`,
+	RunE: runDiffsync,
+}
+
+func runDiffsync(cmd *cobra.Command, args []string) error {
+	ctx := cmd.Context()
+	cfg := getBaseConfig(cmd)
+
+	// Get flags
+	from, _ := cmd.Flags().GetString("from")
+	to, _ := cmd.Flags().GetString("to")
+	query, labels, err := queryFromFlags(cmd)
+	if err != nil {
+		return err
+	}
+	doCopy, _ := cmd.Flags().GetBool("copy")
+	format := formatFromFlags(cmd)
+
+	if strings.EqualFold(from, to) {
+		return fmt.Errorf("--from and --to must be different accounts")
+	}
+
+	srcCfg, err := cfg.ForAccount(from)
+	if err != nil {
+		return err
+	}
+	dstCfg, err := cfg.ForAccount(to)
+	if err != nil {
+		return err
+	}
+
+	src, err := gml.NewService(ctx, srcCfg)
+	if err != nil {
+		return fmt.Errorf("unable to create service: %w", err)
+	}
+	dst, err := gml.NewService(ctx, dstCfg)
+	if err != nil {
+		return fmt.Errorf("unable to create service: %w", err)
+	}
+
+	result, err := gml.DiffAccounts(ctx, src, dst, gml.DiffSyncOptions{
+		SourceAccount: from,
+		TargetAccount: to,
+		Query:         query,
+		Labels:        labels,
+	})
+	if err != nil {
+		return err
+	}
+
+	failures := failureLogFromFlags(cmd)
+	if doCopy {
+		if _, err := gml.CopyMissing(ctx, src, dst, result.OnlyInSource, failures); err != nil {
+			return fmt.Errorf("unable to copy messages to %s: %w", to, err)
+		}
+	}
+
+	// Output
+	if err := gml.FormatDiffSync(cmd.OutOrStdout(), result, format); err != nil {
+		return fmt.Errorf("unable to format output: %w", err)
+	}
+
+	return reportFailures(cmd, failures)
+}
+
+func init() {
+	rootCmd.AddCommand(diffsyncCmd)
+
+	diffsyncCmd.Flags().String("from", "", "Source account name")
+	diffsyncCmd.Flags().String("to", "", "Target account name")
+	diffsyncCmd.Flags().StringP("query", "q", "", "Search query (Gmail search syntax), applied to both accounts")
+	addQueryFlags(diffsyncCmd)
+	diffsyncCmd.Flags().StringArrayP("label", "l", nil, "Filter by label in both accounts (can be specified multiple times)")
+	diffsyncCmd.Flags().Bool("copy", false, "Import messages missing from the target account")
+	addIgnoreErrorsFlag(diffsyncCmd)
+	setFormats(diffsyncCmd, gml.OutputFormatText, gml.OutputFormatJSON)
+	diffsyncCmd.MarkFlagRequired("from")
+	diffsyncCmd.MarkFlagRequired("to")
+
+	// Set custom output to enable testing
+	diffsyncCmd.SetOut(os.Stdout)
+}
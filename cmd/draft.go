@@ -0,0 +1,157 @@
+/*
+Copyright © 2025 longkey1
+
+Licensed under the Apache License, Version 2.0 (the "License");
+you may not use this file except in compliance with the License.
+You may obtain a copy of the License at
+
+	http://www.apache.org/licenses/LICENSE-2.0
+
+Unless required by applicable law or agreed to in writing, software
+distributed under the License is distributed on an "AS IS" BASIS,
+WITHOUT WARRANTIES OR CONDITIONS OF ANY KIND, either express or implied.
+See the License for the specific language governing permissions and
+limitations under the License.
+*/
+package cmd
+
+import (
+	"fmt"
+	"os"
+	"time"
+
+	"github.com/longkey1/gml/internal/gml"
+	"github.com/spf13/cobra"
+)
+
+// draftCmd represents the draft command
+var draftCmd = &cobra.Command{
+	Use:   "draft",
+	Short: "Work with drafts",
+	Long:  `Work with unsent drafts.`,
+}
+
+// draftListCmd represents the draft list command
+var draftListCmd = &cobra.Command{
+	Use:   "list",
+	Short: "List drafts",
+	Long: `List drafts, newest first, optionally matching a query. The id field is the
+draft ID; messageid is the ID of the draft's message, usable with gml get.
+
+Available fields: ` + gml.DraftFields.String() + `
+
+Examples:
+  gml draft list
+  gml draft list -q "to:bob" -f id,subject,date
+  gml draft list --json`,
+	Args: cobra.NoArgs,
+	RunE: runDraftList,
+}
+
+// draftCreateCmd represents the draft create command
+var draftCreateCmd = &cobra.Command{
+	Use:   "create",
+	Short: "Create a draft",
+	Long: `Save a new message as a draft, composed with the same flags as gml send
+(recipients, subject, body or --template, --attach and --inline). Recipients
+are optional.
+
+Creating drafts requires the compose (or modify) scope.
+
+Examples:
+  gml draft create --to bob@example.com -s "Proposal" --body-file proposal.txt --attach proposal.pdf
+  gml draft create --template weekly-report --var week=27
+  gml draft create -s "Notes" --body "To do: ..."`,
+	Args: cobra.NoArgs,
+	RunE: runDraftCreate,
+}
+
+func runDraftCreate(cmd *cobra.Command, args []string) error {
+	cfg, err := GetConfig(cmd)
+	if err != nil {
+		return err
+	}
+
+	msg, err := composeFromFlags(cmd, cfg)
+	if err != nil {
+		return err
+	}
+	return saveDraft(cmd, cfg, msg)
+}
+
+func runDraftList(cmd *cobra.Command, args []string) error {
+	ctx := cmd.Context()
+	cfg, err := GetConfig(cmd)
+	if err != nil {
+		return err
+	}
+
+	// Get flags
+	query, _ := cmd.Flags().GetString("query")
+	maxResults, _ := cmd.Flags().GetInt64("max-results")
+
+	fields, err := fieldsFromFlags(cmd, gml.DraftFields)
+	if err != nil {
+		return err
+	}
+	dateFormat, err := dateFormatFromFlags(cmd)
+	if err != nil {
+		return err
+	}
+	csvOpts, err := csvOptionsFromFlags(cmd, gml.CSVOptions{})
+	if err != nil {
+		return err
+	}
+
+	// Create service
+	svc, err := gml.NewService(ctx, cfg)
+	if err != nil {
+		return fmt.Errorf("unable to create service: %w", err)
+	}
+
+	progress := newProgress(cmd, "Fetching drafts")
+	drafts, err := gml.ListDrafts(ctx, svc, gml.ListDraftsOptions{
+		Query:      query,
+		MaxResults: maxResults,
+		Fields:     fields,
+		Progress:   progress.Update,
+	})
+	progress.Done()
+	if err != nil {
+		return err
+	}
+
+	now := time.Now()
+	for i := range drafts {
+		drafts[i].Date = gml.FormatMailDate(drafts[i].Date, dateFormat, now)
+	}
+
+	// Output
+	if len(drafts) == 0 && formatFromFlags(cmd) == gml.OutputFormatText {
+		fmt.Fprintln(cmd.OutOrStdout(), "No drafts found.")
+		return nil
+	}
+	return gml.FormatRecords(cmd.OutOrStdout(), drafts, gml.DraftFields, fields, formatFromFlags(cmd), gml.RecordOptions{
+		CSV:   csvOpts,
+		Table: gml.TableOptions{Width: gml.TerminalWidth(cmd.OutOrStdout())},
+	})
+}
+
+func init() {
+	rootCmd.AddCommand(draftCmd)
+	draftCmd.AddCommand(draftListCmd)
+	draftCmd.AddCommand(draftCreateCmd)
+
+	draftListCmd.Flags().StringP("query", "q", "", "Search query (Gmail search syntax)")
+	draftListCmd.Flags().Int64P("max-results", "n", 10, "Maximum number of drafts to return")
+	addFieldsFlag(draftListCmd, gml.DraftFields, "id,to,subject,date")
+	setFormats(draftListCmd, gml.OutputFormatText, gml.OutputFormatJSON, gml.OutputFormatNDJSON, gml.OutputFormatCSV, gml.OutputFormatTSV, gml.OutputFormatMarkdown)
+	addCSVFlags(draftListCmd)
+	addDateFormatFlag(draftListCmd)
+
+	addComposeFlags(draftCreateCmd)
+	setFormats(draftCreateCmd, gml.OutputFormatText, gml.OutputFormatJSON)
+
+	// Set custom output to enable testing
+	draftCmd.SetOut(os.Stdout)
+}
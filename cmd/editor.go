@@ -0,0 +1,138 @@
+/*
+Copyright © 2025 longkey1
+
+Licensed under the Apache License, Version 2.0 (the "License");
+you may not use this file except in compliance with the License.
+You may obtain a copy of the License at
+
+	http://www.apache.org/licenses/LICENSE-2.0
+
+Unless required by applicable law or agreed to in writing, software
+distributed under the License is distributed on an "AS IS" BASIS,
+WITHOUT WARRANTIES OR CONDITIONS OF ANY KIND, either express or implied.
+See the License for the specific language governing permissions and
+limitations under the License.
+*/
+package cmd
+
+import (
+	"bufio"
+	"errors"
+	"fmt"
+	"io"
+	"os"
+	"os/exec"
+	"strings"
+
+	"github.com/longkey1/gml/internal/gml"
+	"github.com/spf13/cobra"
+)
+
+// runEditor opens path in $VISUAL or $EDITOR (default vi) on the terminal
+func runEditor(cmd *cobra.Command, path string) error {
+	editor := os.Getenv("VISUAL")
+	if editor == "" {
+		editor = os.Getenv("EDITOR")
+	}
+	if editor == "" {
+		editor = "vi"
+	}
+	// Run through the shell so editors with arguments ("code --wait") work
+	c := exec.CommandContext(cmd.Context(), "sh", "-c", editor+` "$1"`, "sh", path)
+	c.Stdin, c.Stdout, c.Stderr = os.Stdin, os.Stdout, os.Stderr
+	if err := c.Run(); err != nil {
+		return fmt.Errorf("unable to run editor: %w", err)
+	}
+	return nil
+}
+
+// editText lets the user edit text in a temporary file and returns the
+// result; pattern names the file as for os.CreateTemp (e.g. "gml-*.txt")
+func editText(cmd *cobra.Command, pattern, text string) (string, error) {
+	f, err := os.CreateTemp("", pattern)
+	if err != nil {
+		return "", fmt.Errorf("unable to create temporary file: %w", err)
+	}
+	defer os.Remove(f.Name())
+
+	_, err = f.WriteString(text)
+	if cerr := f.Close(); err == nil {
+		err = cerr
+	}
+	if err != nil {
+		return "", fmt.Errorf("unable to write temporary file: %w", err)
+	}
+
+	if err := runEditor(cmd, f.Name()); err != nil {
+		return "", err
+	}
+	data, err := os.ReadFile(f.Name())
+	if err != nil {
+		return "", fmt.Errorf("unable to read edited file: %w", err)
+	}
+	return string(data), nil
+}
+
+// editOutgoing lets the user edit msg in the editor as a To/Cc/Bcc/Subject
+// header block and body, then shows a summary with the pre-send warnings and
+// asks the question (e.g. "Send this message?"), like git commit: yes returns
+// the edited message, e edits it again, anything else cancels. An empty body
+// also cancels. A nil message means canceled
+func editOutgoing(cmd *cobra.Command, msg *gml.OutgoingMessage, noChecks bool, question string) (*gml.OutgoingMessage, error) {
+	if !isInteractive(cmd) {
+		return nil, errors.New("--edit needs a terminal")
+	}
+	out := cmd.OutOrStdout()
+	in := bufio.NewReader(cmd.InOrStdin())
+
+	text := gml.FormatEditableMessage(msg)
+	for {
+		edited, err := editText(cmd, "gml-message-*.txt", text)
+		if err != nil {
+			return nil, err
+		}
+		text = edited
+
+		parsed, err := gml.ParseEditableMessage(text, msg)
+		if err == nil {
+			var cfg *gml.Config
+			if cfg, err = GetConfig(cmd); err == nil {
+				err = resolveContactNames(cmd, cfg, parsed)
+			}
+		}
+		if err == nil && strings.TrimSpace(stripQuote(parsed.Body)) == "" {
+			fmt.Fprintln(out, "The message is empty.")
+			return nil, nil
+		}
+		if err != nil {
+			fmt.Fprintf(out, "Error: %v\n", err)
+		} else {
+			fmt.Fprintln(out)
+			gml.FormatOutgoingSummary(out, parsed)
+			if !noChecks {
+				for _, w := range gml.CheckOutgoing(parsed, gml.CheckOptions{}) {
+					fmt.Fprintf(out, "Warning: %s\n", w)
+				}
+			}
+		}
+
+		if err == nil {
+			fmt.Fprintf(out, "%s [y/N/e(dit)]: ", question)
+		} else {
+			fmt.Fprint(out, "Edit again? [e/N]: ")
+		}
+		line, readErr := in.ReadString('\n')
+		if readErr != nil && !errors.Is(readErr, io.EOF) {
+			return nil, fmt.Errorf("unable to read answer: %w", readErr)
+		}
+		switch strings.ToLower(strings.TrimSpace(line)) {
+		case "y", "yes":
+			if err == nil {
+				return parsed, nil
+			}
+		case "e", "edit":
+			continue
+		}
+		return nil, nil
+	}
+}
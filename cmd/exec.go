@@ -0,0 +1,66 @@
+/*
+Copyright © 2025 longkey1
+
+Licensed under the Apache License, Version 2.0 (the "License");
+you may not use this file except in compliance with the License.
+You may obtain a copy of the License at
+
+	http://www.apache.org/licenses/LICENSE-2.0
+
+Unless required by applicable law or agreed to in writing, software
+distributed under the License is distributed on an "AS IS" BASIS,
+WITHOUT WARRANTIES OR CONDITIONS OF ANY KIND, either express or implied.
+See the License for the specific language governing permissions and
+limitations under the License.
+*/
+package cmd
+
+import (
+	"context"
+	"fmt"
+	"io"
+	"log/slog"
+
+	"github.com/longkey1/gml/internal/gml"
+	"github.com/spf13/cobra"
+)
+
+// addExecPerMessageFlag adds the --exec-per-message flag to a command
+func addExecPerMessageFlag(cmd *cobra.Command) {
+	cmd.Flags().String("exec-per-message", "", "Run a shell command for each message instead of printing it (fields in $GML_ID, $GML_FROM, $GML_SUBJECT, ...)")
+}
+
+// messageExec runs the --exec-per-message command for messages, counting
+// failures instead of stopping at the first one
+type messageExec struct {
+	command        string
+	stdout, stderr io.Writer
+	ran, failed    int
+}
+
+// messageExecFromFlags returns the --exec-per-message runner, or nil when
+// the flag is not set. Command output goes to stdout and stderr
+func messageExecFromFlags(cmd *cobra.Command, stdout io.Writer) *messageExec {
+	command, _ := cmd.Flags().GetString("exec-per-message")
+	if command == "" {
+		return nil
+	}
+	return &messageExec{command: command, stdout: stdout, stderr: cmd.ErrOrStderr()}
+}
+
+// run runs the command for a message; a failing command is logged
+func (e *messageExec) run(ctx context.Context, msg gml.MessageInfo) {
+	e.ran++
+	if err := gml.RunMessageHook(ctx, e.command, msg, e.stdout, e.stderr); err != nil {
+		e.failed++
+		slog.Warn(err.Error())
+	}
+}
+
+// err reports failed commands once all messages have run
+func (e *messageExec) err() error {
+	if e.failed == 0 {
+		return nil
+	}
+	return fmt.Errorf("command failed for %d of %d messages", e.failed, e.ran)
+}
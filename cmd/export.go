@@ -0,0 +1,198 @@
+/*
+Copyright © 2025 longkey1
+
+Licensed under the Apache License, Version 2.0 (the "License");
+you may not use this file except in compliance with the License.
+You may obtain a copy of the License at
+
+	http://www.apache.org/licenses/LICENSE-2.0
+
+Unless required by applicable law or agreed to in writing, software
+distributed under the License is distributed on an "AS IS" BASIS,
+WITHOUT WARRANTIES OR CONDITIONS OF ANY KIND, either express or implied.
+See the License for the specific language governing permissions and
+limitations under the License.
+*/
+package cmd
+
+import (
+	"fmt"
+	"os"
+	"strings"
+
+	"github.com/longkey1/gml/internal/gml"
+	"github.com/spf13/cobra"
+)
+
+// exportCmd represents the export command
+var exportCmd = &cobra.Command{
+	Use:   "export",
+	Short: "Export messages to files",
+}
+
+// exportParquetCmd represents the export parquet command
+var exportParquetCmd = &cobra.Command{
+	Use:   "parquet",
+	Short: "Export message metadata to a Parquet file",
+	Long: `Export message metadata to a Parquet file for loading into DuckDB, pandas, etc.
+
+Columns: account, id, thread_id, url, sender, recipient, subject, date,
+snippet, labels (list), body (optional, with --body)
+
+Examples:
+  gml export parquet -q "newer_than:1y" -o mail.parquet
+  gml export parquet -l INBOX --body -o inbox.parquet
+  gml export parquet -q "newer_than:1y" -o gs://my-bucket/mail.parquet`,
+	Args: cobra.NoArgs,
+	RunE: runExportParquet,
+}
+
+// exportTreeCmd represents the export tree command
+var exportTreeCmd = &cobra.Command{
+	Use:   "tree",
+	Short: "Export messages as .eml files in a label directory tree",
+	Long: `Export messages as .eml files mirroring the label hierarchy as directories.
+
+Each message is written under every label it carries (nested labels such as
+"Projects/Alpha" become nested directories). Additional copies are hard links
+unless --copy is given. Messages without labels go to _unlabeled/. Existing
+files are skipped, so re-running only downloads new messages.
+
+Examples:
+  gml export tree --dir ./mail
+  gml export tree -q "newer_than:30d" --dir ./mail --copy`,
+	Args: cobra.NoArgs,
+	RunE: runExportTree,
+}
+
+func runExportTree(cmd *cobra.Command, args []string) error {
+	ctx := cmd.Context()
+	cfg, err := GetConfig(cmd)
+	if err != nil {
+		return err
+	}
+
+	// Get flags
+	query, labels, err := queryFromFlags(cmd)
+	if err != nil {
+		return err
+	}
+	dir, _ := cmd.Flags().GetString("dir")
+	copyFiles, _ := cmd.Flags().GetBool("copy")
+
+	// Create service
+	svc, err := gml.NewService(ctx, cfg)
+	if err != nil {
+		return fmt.Errorf("unable to create service: %w", err)
+	}
+
+	failures := failureLogFromFlags(cmd)
+	progress := newProgress(cmd, "Exporting messages")
+	result, err := gml.ExportTree(ctx, svc, gml.TreeExportOptions{
+		Query:    query,
+		LabelIDs: labels,
+		Dir:      dir,
+		Copy:     copyFiles,
+		Failures: failures,
+		Progress: progress.Update,
+	})
+	progress.Done()
+	if err != nil {
+		return fmt.Errorf("unable to export messages: %w", err)
+	}
+
+	// Output
+	if formatFromFlags(cmd) == gml.OutputFormatJSON {
+		if err := gml.FormatJSON(cmd.OutOrStdout(), result); err != nil {
+			return err
+		}
+	} else {
+		fmt.Fprintf(cmd.OutOrStdout(), "Exported %d messages to %s (%d written, %d linked, %d skipped)\n",
+			result.Messages, dir, result.Written, result.Linked, result.Skipped)
+	}
+	return reportFailures(cmd, failures)
+}
+
+func runExportParquet(cmd *cobra.Command, args []string) error {
+	ctx := cmd.Context()
+	cfg, err := GetConfig(cmd)
+	if err != nil {
+		return err
+	}
+
+	// Get flags
+	query, labels, err := queryFromFlags(cmd)
+	if err != nil {
+		return err
+	}
+	withBody, _ := cmd.Flags().GetBool("body")
+	output, _ := cmd.Flags().GetString("output")
+
+	fieldList := append([]string{}, gml.MetadataFields...)
+	if withBody {
+		fieldList = append(fieldList, "body")
+	}
+	fields := gml.ParseFields(strings.Join(fieldList, ","))
+
+	// Create service
+	svc, err := gml.NewService(ctx, cfg)
+	if err != nil {
+		return fmt.Errorf("unable to create service: %w", err)
+	}
+
+	// List messages
+	progress := newProgress(cmd, "Fetching messages")
+	messages, err := gml.ListMessages(ctx, svc, gml.ListMessagesOptions{
+		Query:      query,
+		MaxResults: 500,
+		LabelIDs:   labels,
+		Fields:     fields,
+		Progress:   progress.Update,
+	})
+	progress.Done()
+	if err != nil {
+		return fmt.Errorf("unable to list messages: %w", err)
+	}
+
+	// Write to a file or object storage URL
+	out, err := gml.CreateOutput(ctx, output)
+	if err != nil {
+		return err
+	}
+	defer out.Abort()
+
+	if err := gml.ExportMessagesParquet(out, messages); err != nil {
+		return fmt.Errorf("unable to export messages: %w", err)
+	}
+	if err := out.Close(); err != nil {
+		return err
+	}
+
+	fmt.Fprintf(cmd.ErrOrStderr(), "Exported %d messages to %s\n", len(messages), output)
+	return nil
+}
+
+func init() {
+	rootCmd.AddCommand(exportCmd)
+	exportCmd.AddCommand(exportParquetCmd)
+	exportCmd.AddCommand(exportTreeCmd)
+
+	exportParquetCmd.Flags().StringP("query", "q", "", "Search query (Gmail search syntax)")
+	addQueryFlags(exportParquetCmd)
+	exportParquetCmd.Flags().StringArrayP("label", "l", nil, "Filter by label (can be specified multiple times)")
+	exportParquetCmd.Flags().Bool("body", false, "Include message bodies")
+	exportParquetCmd.Flags().StringP("output", "o", "", "Output Parquet file or s3:// / gs:// URL")
+	exportParquetCmd.MarkFlagRequired("output")
+
+	exportTreeCmd.Flags().StringP("query", "q", "", "Search query (Gmail search syntax)")
+	addQueryFlags(exportTreeCmd)
+	exportTreeCmd.Flags().StringArrayP("label", "l", nil, "Filter by label (can be specified multiple times)")
+	exportTreeCmd.Flags().String("dir", "", "Output directory")
+	exportTreeCmd.Flags().Bool("copy", false, "Copy files instead of hard-linking additional labels")
+	addIgnoreErrorsFlag(exportTreeCmd)
+	setFormats(exportTreeCmd, gml.OutputFormatText, gml.OutputFormatJSON)
+	exportTreeCmd.MarkFlagRequired("dir")
+
+	// Set custom output to enable testing
+	exportCmd.SetOut(os.Stdout)
+}
@@ -0,0 +1,35 @@
+/*
+Copyright © 2025 longkey1
+
+Licensed under the Apache License, Version 2.0 (the "License");
+you may not use this file except in compliance with the License.
+You may obtain a copy of the License at
+
+	http://www.apache.org/licenses/LICENSE-2.0
+
+Unless required by applicable law or agreed to in writing, software
+distributed under the License is distributed on an "AS IS" BASIS,
+WITHOUT WARRANTIES OR CONDITIONS OF ANY KIND, either express or implied.
+See the License for the specific language governing permissions and
+limitations under the License.
+*/
+package cmd
+
+import (
+	"github.com/longkey1/gml/internal/gml"
+	"github.com/spf13/cobra"
+)
+
+// addFieldsFlag adds -f/--fields, listing the fields of set in its help
+func addFieldsFlag(cmd *cobra.Command, set gml.FieldSet, defaults string) {
+	cmd.Flags().StringP("fields", "f", defaults, "Comma-separated list of fields ("+set.String()+")")
+	_ = cmd.RegisterFlagCompletionFunc("fields", func(c *cobra.Command, args []string, toComplete string) ([]string, cobra.ShellCompDirective) {
+		return set.Names, cobra.ShellCompDirectiveNoFileComp
+	})
+}
+
+// fieldsFromFlags parses --fields, rejecting fields not in set
+func fieldsFromFlags(cmd *cobra.Command, set gml.FieldSet) (map[string]bool, error) {
+	fieldsStr, _ := cmd.Flags().GetString("fields")
+	return set.Parse(fieldsStr)
+}
@@ -0,0 +1,345 @@
+/*
+Copyright © 2025 longkey1
+
+Licensed under the Apache License, Version 2.0 (the "License");
+you may not use this file except in compliance with the License.
+You may obtain a copy of the License at
+
+	http://www.apache.org/licenses/LICENSE-2.0
+
+Unless required by applicable law or agreed to in writing, software
+distributed under the License is distributed on an "AS IS" BASIS,
+WITHOUT WARRANTIES OR CONDITIONS OF ANY KIND, either express or implied.
+See the License for the specific language governing permissions and
+limitations under the License.
+*/
+package cmd
+
+import (
+	"fmt"
+	"io"
+	"os"
+
+	"github.com/longkey1/gml/internal/gml"
+	"github.com/spf13/cobra"
+)
+
+// filterCmd represents the filter command
+var filterCmd = &cobra.Command{
+	Use:   "filter",
+	Short: "Manage Gmail filters",
+	Long: `Manage Gmail filters (mail rules).
+
+Creating and deleting filters requires the settings.basic scope; filters that
+forward mail also require settings.sharing.
+
+Labels are referenced by name, so an exported filter set can be imported into
+another account. Missing labels are created on import.`,
+}
+
+// filterListCmd represents the filter list command
+var filterListCmd = &cobra.Command{
+	Use:   "list",
+	Short: "List filters",
+	Long: `List Gmail filters.
+
+Examples:
+  gml filter list
+  gml filter list --format json`,
+	RunE: runFilterList,
+}
+
+// filterCreateCmd represents the filter create command
+var filterCreateCmd = &cobra.Command{
+	Use:   "create",
+	Short: "Create a filter",
+	Long: `Create a Gmail filter from criteria and action flags.
+
+Examples:
+  gml filter create --from newsletter@example.com --add-label Newsletters --archive
+  gml filter create --subject "[alerts]" --add-label Alerts --mark-read
+  gml filter create --from acme.com --add-label "Client Work/Acme"   # Nested label with spaces
+  gml filter create -l "Client Work/Acme" --subject invoice --add-label Invoices
+  gml filter create --query "has:attachment larger:10M" --forward archive@example.com`,
+	RunE: runFilterCreate,
+}
+
+// filterDeleteCmd represents the filter delete command
+var filterDeleteCmd = &cobra.Command{
+	Use:   "delete <filter-id>...",
+	Short: "Delete filters",
+	Long: `Delete Gmail filters by ID (see 'gml filter list').
+
+Examples:
+  gml filter delete ANe1Bmj...`,
+	Args: cobra.MinimumNArgs(1),
+	RunE: runFilterDelete,
+}
+
+// filterExportCmd represents the filter export command
+var filterExportCmd = &cobra.Command{
+	Use:   "export",
+	Short: "Export all filters as JSON",
+	Long: `Export all filters as JSON for backup or for import into another account.
+
+Examples:
+  gml filter export > filters.json
+  gml filter export -o filters.json
+  gml filter export -o s3://my-bucket/gml/filters.json`,
+	RunE: runFilterExport,
+}
+
+// filterImportCmd represents the filter import command
+var filterImportCmd = &cobra.Command{
+	Use:   "import <file>",
+	Short: "Import filters from JSON",
+	Long: `Create filters from a JSON file written by 'gml filter export'.
+
+Filters identical to existing ones are skipped. Use "-" to read from stdin.
+
+Examples:
+  gml filter import filters.json
+  gml --account work filter export | gml --account personal filter import -`,
+	Args: cobra.ExactArgs(1),
+	RunE: runFilterImport,
+}
+
+func runFilterList(cmd *cobra.Command, args []string) error {
+	ctx := cmd.Context()
+	cfg, err := GetConfig(cmd)
+	if err != nil {
+		return err
+	}
+
+	// Get flags
+	format := formatFromFlags(cmd)
+
+	svc, err := gml.NewService(ctx, cfg)
+	if err != nil {
+		return fmt.Errorf("unable to create service: %w", err)
+	}
+
+	filters, err := gml.ListFilters(ctx, svc)
+	if err != nil {
+		return err
+	}
+
+	// Output
+	if err := gml.FormatFilters(cmd.OutOrStdout(), filters, format); err != nil {
+		return fmt.Errorf("unable to format output: %w", err)
+	}
+
+	return nil
+}
+
+func runFilterCreate(cmd *cobra.Command, args []string) error {
+	ctx := cmd.Context()
+	cfg, err := GetConfig(cmd)
+	if err != nil {
+		return err
+	}
+
+	// Get flags
+	from, _ := cmd.Flags().GetString("from")
+	to, _ := cmd.Flags().GetString("to")
+	subject, _ := cmd.Flags().GetString("subject")
+	query, _ := cmd.Flags().GetString("query")
+	negatedQuery, _ := cmd.Flags().GetString("negated-query")
+	hasAttachment, _ := cmd.Flags().GetBool("has-attachment")
+	labels, _ := cmd.Flags().GetStringArray("label")
+	addLabels, _ := cmd.Flags().GetStringArray("add-label")
+	removeLabels, _ := cmd.Flags().GetStringArray("remove-label")
+	archive, _ := cmd.Flags().GetBool("archive")
+	markRead, _ := cmd.Flags().GetBool("mark-read")
+	forward, _ := cmd.Flags().GetString("forward")
+	format := formatFromFlags(cmd)
+
+	// Filters can't match label IDs, so labels become label: search terms
+	qb := gml.NewQueryBuilder(query)
+	for _, l := range labels {
+		qb.Label(l)
+	}
+	query = qb.String()
+
+	filter := gml.Filter{
+		Criteria: gml.FilterCriteria{
+			From:          from,
+			To:            to,
+			Subject:       subject,
+			Query:         query,
+			NegatedQuery:  negatedQuery,
+			HasAttachment: hasAttachment,
+		},
+		Action: gml.FilterAction{
+			AddLabels:    addLabels,
+			RemoveLabels: removeLabels,
+			Forward:      forward,
+		},
+	}
+	if archive {
+		filter.Action.RemoveLabels = append(filter.Action.RemoveLabels, "INBOX")
+	}
+	if markRead {
+		filter.Action.RemoveLabels = append(filter.Action.RemoveLabels, "UNREAD")
+	}
+	if filter.Criteria == (gml.FilterCriteria{}) {
+		return fmt.Errorf("at least one criterion is required (--from, --to, --subject, --query, --negated-query, --has-attachment or --label)")
+	}
+	if len(filter.Action.AddLabels) == 0 && len(filter.Action.RemoveLabels) == 0 && forward == "" {
+		return fmt.Errorf("at least one action is required (--add-label, --remove-label, --archive, --mark-read or --forward)")
+	}
+
+	svc, err := gml.NewService(ctx, cfg)
+	if err != nil {
+		return fmt.Errorf("unable to create service: %w", err)
+	}
+
+	created, err := gml.CreateFilter(ctx, svc, filter)
+	if err != nil {
+		return err
+	}
+	if gml.IsDryRun(ctx) {
+		// Only the request was reported; there is no filter to show
+		return nil
+	}
+
+	// Output
+	if err := gml.FormatFilters(cmd.OutOrStdout(), []gml.Filter{*created}, format); err != nil {
+		return fmt.Errorf("unable to format output: %w", err)
+	}
+
+	return nil
+}
+
+func runFilterDelete(cmd *cobra.Command, args []string) error {
+	ctx := cmd.Context()
+	cfg, err := GetConfig(cmd)
+	if err != nil {
+		return err
+	}
+
+	svc, err := gml.NewService(ctx, cfg)
+	if err != nil {
+		return fmt.Errorf("unable to create service: %w", err)
+	}
+
+	for _, id := range args {
+		if err := gml.DeleteFilter(ctx, svc, id); err != nil {
+			return err
+		}
+		fmt.Fprintf(cmd.OutOrStdout(), "Deleted filter %s\n", id)
+	}
+
+	return nil
+}
+
+func runFilterExport(cmd *cobra.Command, args []string) error {
+	ctx := cmd.Context()
+	cfg, err := GetConfig(cmd)
+	if err != nil {
+		return err
+	}
+
+	// Get flags
+	output, _ := cmd.Flags().GetString("output")
+
+	svc, err := gml.NewService(ctx, cfg)
+	if err != nil {
+		return fmt.Errorf("unable to create service: %w", err)
+	}
+
+	filters, err := gml.ListFilters(ctx, svc)
+	if err != nil {
+		return err
+	}
+
+	// Output
+	if output == "" {
+		if err := gml.FormatFilters(cmd.OutOrStdout(), filters, gml.OutputFormatJSON); err != nil {
+			return fmt.Errorf("unable to format output: %w", err)
+		}
+		return nil
+	}
+
+	// Write to a file or object storage URL
+	out, err := gml.CreateOutput(ctx, output)
+	if err != nil {
+		return err
+	}
+	defer out.Abort()
+
+	if err := gml.FormatFilters(out, filters, gml.OutputFormatJSON); err != nil {
+		return fmt.Errorf("unable to format output: %w", err)
+	}
+	if err := out.Close(); err != nil {
+		return err
+	}
+
+	fmt.Fprintf(cmd.ErrOrStderr(), "Exported %d filters to %s\n", len(filters), output)
+	return nil
+}
+
+func runFilterImport(cmd *cobra.Command, args []string) error {
+	ctx := cmd.Context()
+	cfg, err := GetConfig(cmd)
+	if err != nil {
+		return err
+	}
+
+	var r io.Reader = cmd.InOrStdin()
+	if args[0] != "-" {
+		f, err := os.Open(args[0])
+		if err != nil {
+			return fmt.Errorf("unable to open filter file: %w", err)
+		}
+		defer f.Close()
+		r = f
+	}
+
+	svc, err := gml.NewService(ctx, cfg)
+	if err != nil {
+		return fmt.Errorf("unable to create service: %w", err)
+	}
+
+	result, err := gml.ImportFilters(ctx, svc, r)
+	if result != nil && formatFromFlags(cmd) == gml.OutputFormatJSON {
+		if ferr := gml.FormatJSON(cmd.OutOrStdout(), result); ferr != nil {
+			return ferr
+		}
+	} else if result != nil {
+		fmt.Fprintf(cmd.OutOrStdout(), "Created %d filters, skipped %d existing\n", result.Created, result.Skipped)
+	}
+	return err
+}
+
+func init() {
+	rootCmd.AddCommand(filterCmd)
+	filterCmd.AddCommand(filterListCmd)
+	filterCmd.AddCommand(filterCreateCmd)
+	filterCmd.AddCommand(filterDeleteCmd)
+	filterCmd.AddCommand(filterExportCmd)
+	filterCmd.AddCommand(filterImportCmd)
+
+	setFormats(filterListCmd, gml.OutputFormatText, gml.OutputFormatJSON)
+
+	filterCreateCmd.Flags().String("from", "", "Match sender")
+	filterCreateCmd.Flags().String("to", "", "Match recipient")
+	filterCreateCmd.Flags().String("subject", "", "Match subject")
+	filterCreateCmd.Flags().StringP("query", "q", "", "Match a Gmail search query")
+	filterCreateCmd.Flags().String("negated-query", "", "Exclude messages matching a Gmail search query")
+	filterCreateCmd.Flags().Bool("has-attachment", false, "Match messages with attachments")
+	filterCreateCmd.Flags().StringArrayP("label", "l", nil, "Match messages with a label, by name (can be specified multiple times)")
+	filterCreateCmd.Flags().StringArray("add-label", nil, "Label name or ID to add (can be specified multiple times; created if missing)")
+	filterCreateCmd.Flags().StringArray("remove-label", nil, "Label name or ID to remove (can be specified multiple times)")
+	filterCreateCmd.Flags().Bool("archive", false, "Skip the inbox")
+	filterCreateCmd.Flags().Bool("mark-read", false, "Mark as read")
+	filterCreateCmd.Flags().String("forward", "", "Forward to a verified forwarding address")
+	setFormats(filterCreateCmd, gml.OutputFormatText, gml.OutputFormatJSON)
+
+	filterExportCmd.Flags().StringP("output", "o", "", "Write to a file or s3:// / gs:// URL instead of stdout")
+	setFormats(filterExportCmd, gml.OutputFormatJSON)
+	setFormats(filterImportCmd, gml.OutputFormatText, gml.OutputFormatJSON)
+
+	// Set custom output to enable testing
+	filterCmd.SetOut(os.Stdout)
+}
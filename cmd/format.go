@@ -0,0 +1,101 @@
+/*
+Copyright © 2025 longkey1
+
+Licensed under the Apache License, Version 2.0 (the "License");
+you may not use this file except in compliance with the License.
+You may obtain a copy of the License at
+
+	http://www.apache.org/licenses/LICENSE-2.0
+
+Unless required by applicable law or agreed to in writing, software
+distributed under the License is distributed on an "AS IS" BASIS,
+WITHOUT WARRANTIES OR CONDITIONS OF ANY KIND, either express or implied.
+See the License for the specific language governing permissions and
+limitations under the License.
+*/
+package cmd
+
+import (
+	"fmt"
+	"slices"
+	"strings"
+
+	"github.com/longkey1/gml/internal/gml"
+	"github.com/spf13/cobra"
+)
+
+// formatsAnnotation lists the --format values a command supports, default first
+const formatsAnnotation = "gml_formats"
+
+// setFormats declares the --format values a command supports; the first one is
+// the default. Commands that don't declare formats only produce text output
+func setFormats(cmd *cobra.Command, formats ...gml.OutputFormat) {
+	names := make([]string, len(formats))
+	for i, f := range formats {
+		names[i] = string(f)
+	}
+	if cmd.Annotations == nil {
+		cmd.Annotations = make(map[string]string)
+	}
+	cmd.Annotations[formatsAnnotation] = strings.Join(names, ",")
+}
+
+// supportedFormats returns the --format values a command supports, default first
+func supportedFormats(cmd *cobra.Command) []string {
+	if formats, ok := cmd.Annotations[formatsAnnotation]; ok {
+		return strings.Split(formats, ",")
+	}
+	return []string{string(gml.OutputFormatText)}
+}
+
+// formatChanged reports whether --format or --json was given
+func formatChanged(cmd *cobra.Command) bool {
+	return cmd.Flags().Changed("format") || cmd.Flags().Changed("json")
+}
+
+// formatFromFlags returns the output format selected with --format or --json,
+// or the command's default. --json selects ndjson on commands that stream
+// JSON lines only
+func formatFromFlags(cmd *cobra.Command) gml.OutputFormat {
+	formats := supportedFormats(cmd)
+	if jsonOut, _ := cmd.Flags().GetBool("json"); jsonOut {
+		if !slices.Contains(formats, string(gml.OutputFormatJSON)) && slices.Contains(formats, string(gml.OutputFormatNDJSON)) {
+			return gml.OutputFormatNDJSON
+		}
+		return gml.OutputFormatJSON
+	}
+	// The flag is empty unless given or set in the command's config section
+	if format, _ := cmd.Flags().GetString("format"); format != "" {
+		return gml.OutputFormat(format)
+	}
+	return gml.OutputFormat(formats[0])
+}
+
+// checkFormatFlags rejects --format values the command doesn't support, so
+// scripts fail loudly instead of parsing unexpected text
+func checkFormatFlags(cmd *cobra.Command) error {
+	format, _ := cmd.Flags().GetString("format")
+	jsonOut, _ := cmd.Flags().GetBool("json")
+	if format == "" && !jsonOut {
+		return nil
+	}
+	if cmd.Flags().Changed("format") && cmd.Flags().Changed("json") {
+		if format != string(gml.OutputFormatJSON) {
+			return fmt.Errorf("--json and --format %s are mutually exclusive", format)
+		}
+	}
+	formats := supportedFormats(cmd)
+	if format := formatFromFlags(cmd); !slices.Contains(formats, string(format)) {
+		return fmt.Errorf("%s does not support --format %s (supported: %s)", cmd.CommandPath(), format, strings.Join(formats, ", "))
+	}
+	return nil
+}
+
+// formatUsage describes --format for a command's help
+func formatUsage(cmd *cobra.Command) string {
+	formats := supportedFormats(cmd)
+	if len(formats) == 1 {
+		return fmt.Sprintf("Output format (%s only)", formats[0])
+	}
+	return fmt.Sprintf("Output format (%s or %s; default %s)", strings.Join(formats[:len(formats)-1], ", "), formats[len(formats)-1], formats[0])
+}
@@ -0,0 +1,160 @@
+/*
+Copyright © 2025 longkey1
+
+Licensed under the Apache License, Version 2.0 (the "License");
+you may not use this file except in compliance with the License.
+You may obtain a copy of the License at
+
+	http://www.apache.org/licenses/LICENSE-2.0
+
+Unless required by applicable law or agreed to in writing, software
+distributed under the License is distributed on an "AS IS" BASIS,
+WITHOUT WARRANTIES OR CONDITIONS OF ANY KIND, either express or implied.
+See the License for the specific language governing permissions and
+limitations under the License.
+*/
+package cmd
+
+import (
+	"fmt"
+	"os"
+	"strings"
+
+	"github.com/longkey1/gml/internal/gml"
+	"github.com/spf13/cobra"
+)
+
+// forwardCmd represents the forward command
+var forwardCmd = &cobra.Command{
+	Use:   "forward <message-id>",
+	Short: "Forward a message",
+	Long: `Forward a message with its attachments. The original headers and text body
+are added below an optional note (--body or --body-file), and the subject is
+prefixed with "Fwd: ".
+
+With --edit, the forward is written in $VISUAL or $EDITOR as a
+To/Cc/Bcc/Subject header block and body, and after saving, a summary is shown
+and sending must be confirmed, like with gml send --edit.
+
+--attach and --inline add files like with gml send; --no-attachments leaves
+out the original ones. With --draft, the forward is saved as a draft instead.
+The same checks as gml send run before sending; use --no-checks to skip them.
+
+Examples:
+  gml forward 18abc123def456 --to bob@example.com
+  gml forward 18abc123def456 --to bob@example.com --body "FYI, see below."
+  gml forward 18abc123def456 --to archive@example.com --no-attachments
+  gml forward 18abc123def456 --edit                      # Recipients and note in $EDITOR
+  gml forward 18abc123def456 --to bob@example.com --attach notes.pdf --draft`,
+	Args: cobra.ExactArgs(1),
+	RunE: runForward,
+}
+
+func runForward(cmd *cobra.Command, args []string) error {
+	messageID := args[0]
+	ctx := cmd.Context()
+	cfg, err := GetConfig(cmd)
+	if err != nil {
+		return err
+	}
+
+	// Get flags
+	to, _ := cmd.Flags().GetStringArray("to")
+	cc, _ := cmd.Flags().GetStringArray("cc")
+	bcc, _ := cmd.Flags().GetStringArray("bcc")
+	body, _ := cmd.Flags().GetString("body")
+	bodyFile, _ := cmd.Flags().GetString("body-file")
+	noAttachments, _ := cmd.Flags().GetBool("no-attachments")
+	noChecks, _ := cmd.Flags().GetBool("no-checks")
+	asDraft, _ := cmd.Flags().GetBool("draft")
+	edit, _ := cmd.Flags().GetBool("edit")
+
+	if len(to)+len(cc)+len(bcc) == 0 && !asDraft && !edit {
+		return fmt.Errorf("at least one of --to, --cc or --bcc is required")
+	}
+	if bodyFile != "" {
+		data, err := readInput(cmd, bodyFile)
+		if err != nil {
+			return err
+		}
+		body = string(data)
+	}
+	attachments, err := attachmentsFromFlags(cmd)
+	if err != nil {
+		return err
+	}
+
+	// Create service
+	svc, err := gml.NewService(ctx, cfg)
+	if err != nil {
+		return fmt.Errorf("unable to create service: %w", err)
+	}
+
+	fwd, err := gml.PrepareForward(ctx, svc, messageID, gml.ForwardOptions{Note: body, NoAttachments: noAttachments})
+	if err != nil {
+		return err
+	}
+	fwd.To, fwd.Cc, fwd.Bcc = to, cc, bcc
+	if err := gml.ResolveRecipients(ctx, svc, fwd); err != nil {
+		return err
+	}
+	fwd.Attachments = append(fwd.Attachments, attachments...)
+
+	if edit {
+		question := "Send this message?"
+		if asDraft {
+			question = "Save this message as a draft?"
+		}
+		if fwd, err = editOutgoing(cmd, fwd, noChecks, question); err != nil {
+			return err
+		}
+		if fwd == nil {
+			fmt.Fprintln(cmd.OutOrStdout(), "Cancelled.")
+			return nil
+		}
+		// The warnings were shown before confirming
+		noChecks = true
+	}
+
+	if asDraft {
+		return saveDraft(cmd, cfg, fwd)
+	}
+	if err := checkOutgoing(cmd, fwd, noChecks); err != nil {
+		return err
+	}
+
+	sent, err := gml.SendMessage(ctx, svc, fwd)
+	if err != nil {
+		return err
+	}
+	if gml.IsDryRun(ctx) {
+		// Only the request was reported; there is no sent message to show
+		return nil
+	}
+
+	// Output
+	if formatFromFlags(cmd) == gml.OutputFormatJSON {
+		return gml.FormatJSON(cmd.OutOrStdout(), map[string]string{"id": sent.Id, "threadId": sent.ThreadId})
+	}
+	fmt.Fprintf(cmd.OutOrStdout(), "Forwarded %s as %s to %s\n", messageID, sent.Id, strings.Join(append(append(fwd.To, fwd.Cc...), fwd.Bcc...), ", "))
+	return nil
+}
+
+func init() {
+	rootCmd.AddCommand(forwardCmd)
+
+	forwardCmd.Flags().StringArray("to", nil, "Recipient: an address, or a name to look up in the contacts (can be specified multiple times)")
+	forwardCmd.Flags().StringArray("cc", nil, "Cc recipient: an address or a contact name (can be specified multiple times)")
+	forwardCmd.Flags().StringArray("bcc", nil, "Bcc recipient: an address or a contact name (can be specified multiple times)")
+	forwardCmd.Flags().String("body", "", "Note above the forwarded message")
+	forwardCmd.Flags().String("body-file", "", "Read the note from a file (- for stdin)")
+	forwardCmd.Flags().Bool("no-attachments", false, "Leave out the attachments of the original message")
+	addAttachFlags(forwardCmd)
+	forwardCmd.Flags().Bool("edit", false, "Write recipients and note in $EDITOR, then review and confirm the forward")
+	forwardCmd.Flags().Bool("draft", false, "Save the forward as a draft instead of sending it")
+	forwardCmd.Flags().Bool("no-checks", false, "Skip the attachment, recipient domain and recipient count checks")
+	setFormats(forwardCmd, gml.OutputFormatText, gml.OutputFormatJSON)
+
+	// Set custom output to enable testing
+	forwardCmd.SetOut(os.Stdout)
+}
@@ -17,7 +17,13 @@ package cmd
 
 import (
 	"fmt"
+	"io"
+	"net/url"
 	"os"
+	"path/filepath"
+	"strings"
+	"text/template"
+	"time"
 
 	"github.com/longkey1/gml/internal/gml"
 	"github.com/spf13/cobra"
@@ -25,24 +31,96 @@ import (
 
 // getCmd represents the get command
 var getCmd = &cobra.Command{
-	Use:   "get <message-id>",
+	Use:   "get <message-id> | -q <query>",
 	Short: "Get a Gmail message with full body",
 	Long: `Get a Gmail message by ID with full body content.
 
+With --query, the message is looked up by search instead; it is an error
+unless exactly one message matches, or pick the newest match with --latest.
+
+HTML bodies (--body-format html) reference inline images as cid: URLs, which
+don't render outside a mail client. With --embed-images they are replaced by
+data: URIs; with --output, the images are saved into a <name>_files directory
+next to the output file and linked from there.
+
 Examples:
   gml get 18abc123def456    # Get message by ID
-  gml get 18abc123def456 --format json  # Output as JSON`,
-	Args: cobra.ExactArgs(1),
+  gml get -q 'subject:"Your booking confirmation" newer_than:1d'
+  gml get -q "from:noreply@github.com" --latest  # Newest match
+  gml get 18abc123def456 --format json  # Output as JSON
+  gml get 18abc123def456 --format markdown  # Markdown document (headers + fenced body)
+  gml get 18abc123def456 --body-format markdown  # Render HTML bodies as Markdown
+  gml get 18abc123def456 --body-format html --template '{{.Body}}' -o mail.html  # Images in mail_files/
+  gml get 18abc123def456 --body-format html --template '{{.Body}}' --embed-images > mail.html
+  gml get 18abc123def456 --template '{{.Subject}}\n{{.Body}}'  # Custom output (Go template)
+  gml get 18abc123def456 --url-style thread  # Link the conversation instead of the message
+  gml get 18abc123def456 --date-format raw  # Show the Date header verbatim
+  gml get 18abc123def456 --raw-headers  # Show encoded-words (=?UTF-8?B?...?=) as received
+  gml get 18abc123def456 --headers      # All headers (Received chain, DKIM results, ...)
+  gml get 18abc123def456 --header Message-ID --header List-Unsubscribe --json
+  gml get 18abc123def456 --json | jq .auth  # SPF/DKIM/DMARC verdicts
+  gml get 18abc123def456 --no-render  # Skip the [[renderers]] configured for the sender`,
+	Args: cobra.MaximumNArgs(1),
 	RunE: runGet,
 }
 
 func runGet(cmd *cobra.Command, args []string) error {
-	messageID := args[0]
 	ctx := cmd.Context()
-	cfg := GetConfig()
+	cfg, err := GetConfig(cmd)
+	if err != nil {
+		return err
+	}
 
 	// Get flags
-	format, _ := cmd.Flags().GetString("format")
+	query, _ := cmd.Flags().GetString("query")
+	latest, _ := cmd.Flags().GetBool("latest")
+	bodyFormatStr, _ := cmd.Flags().GetString("body-format")
+	rawHeaders, _ := cmd.Flags().GetBool("raw-headers")
+	noRender, _ := cmd.Flags().GetBool("no-render")
+	embedImages, _ := cmd.Flags().GetBool("embed-images")
+	output, _ := cmd.Flags().GetString("output")
+	allHeaders, _ := cmd.Flags().GetBool("headers")
+	headers, _ := cmd.Flags().GetStringArray("header")
+
+	switch {
+	case len(args) == 1 && query != "":
+		return fmt.Errorf("a message ID cannot be combined with --query")
+	case len(args) == 0 && query == "":
+		return fmt.Errorf("a message ID or --query is required")
+	case latest && query == "":
+		return fmt.Errorf("--latest requires --query")
+	}
+
+	bodyFormat, err := gml.ParseBodyFormat(bodyFormatStr)
+	if err != nil {
+		return err
+	}
+	if embedImages && bodyFormat != gml.BodyFormatHTML {
+		return fmt.Errorf("--embed-images requires --body-format html")
+	}
+	var images gml.InlineImageOptions
+	switch {
+	case embedImages:
+		images.Embed = true
+	case output != "" && !gml.IsRemoteOutput(output):
+		images.Dir = strings.TrimSuffix(output, filepath.Ext(output)) + "_files"
+		images.Prefix = url.PathEscape(filepath.Base(images.Dir)) + "/"
+	}
+
+	dateFormat, err := dateFormatFromFlags(cmd)
+	if err != nil {
+		return err
+	}
+
+	urlStyle, err := urlStyleFromFlags(cmd)
+	if err != nil {
+		return err
+	}
+
+	outputFormat, tmpl, _, err := outputFormatFromFlags(cmd)
+	if err != nil {
+		return err
+	}
 
 	// Create service
 	svc, err := gml.NewService(ctx, cfg)
@@ -50,25 +128,81 @@ func runGet(cmd *cobra.Command, args []string) error {
 		return fmt.Errorf("unable to create service: %w", err)
 	}
 
+	var messageID string
+	if len(args) == 1 {
+		messageID = args[0]
+	} else if messageID, err = gml.FindMessageID(ctx, svc, query, latest); err != nil {
+		return err
+	}
+
 	// Get message
-	detail, err := gml.GetMessage(ctx, svc, messageID)
+	detail, err := gml.GetMessage(ctx, svc, messageID, gml.GetMessageOptions{
+		BodyFormat: bodyFormat,
+		RawHeaders: rawHeaders,
+		URLStyle:   urlStyle,
+		Images:     images,
+		AllHeaders: allHeaders,
+		Headers:    headers,
+	})
 	if err != nil {
 		return fmt.Errorf("unable to get message: %w", err)
 	}
+	if !noRender {
+		if err := gml.RenderMessageDetail(ctx, cfg.Renderers, detail); err != nil {
+			return err
+		}
+	}
+	detail.Date = gml.FormatMailDate(detail.Date, dateFormat, time.Now())
 
 	// Output
-	outputFormat := gml.OutputFormat(format)
-	if err := gml.FormatMessageDetail(cmd.OutOrStdout(), detail, outputFormat); err != nil {
-		return fmt.Errorf("unable to format output: %w", err)
+	if output == "" {
+		return writeMessageDetail(cmd.OutOrStdout(), detail, outputFormat, tmpl)
+	}
+
+	// Write to a file or object storage URL
+	out, err := gml.CreateOutput(ctx, output)
+	if err != nil {
+		return err
+	}
+	defer out.Abort()
+
+	if err := writeMessageDetail(out, detail, outputFormat, tmpl); err != nil {
+		return err
 	}
+	return out.Close()
+}
 
+// writeMessageDetail formats a message with the template, if any, or the
+// output format
+func writeMessageDetail(w io.Writer, detail *gml.MessageDetail, format gml.OutputFormat, tmpl *template.Template) error {
+	if tmpl != nil {
+		if err := gml.FormatTemplate(w, []*gml.MessageDetail{detail}, tmpl); err != nil {
+			return fmt.Errorf("unable to format output: %w", err)
+		}
+		return nil
+	}
+	if err := gml.FormatMessageDetail(w, detail, format); err != nil {
+		return fmt.Errorf("unable to format output: %w", err)
+	}
 	return nil
 }
 
 func init() {
 	rootCmd.AddCommand(getCmd)
 
-	getCmd.Flags().String("format", "text", "Output format (text or json)")
+	setFormats(getCmd, gml.OutputFormatText, gml.OutputFormatJSON, gml.OutputFormatMarkdown, gml.OutputFormatTemplate)
+	addTemplateFlags(getCmd)
+	getCmd.Flags().StringP("query", "q", "", "Get the single message matching this search query (Gmail search syntax)")
+	getCmd.Flags().Bool("latest", false, "With --query, get the newest match instead of failing when several match")
+	getCmd.Flags().String("body-format", "text", "Body format (text, html or markdown); HTML-only messages are converted for text")
+	addDateFormatFlag(getCmd)
+	addURLStyleFlag(getCmd)
+	getCmd.Flags().Bool("raw-headers", false, "Keep RFC 2047 encoded-words in from/to/subject undecoded (for debugging)")
+	getCmd.Flags().Bool("headers", false, "Show all headers of the message")
+	getCmd.Flags().StringArray("header", nil, "Show this header (can be specified multiple times)")
+	getCmd.Flags().Bool("no-render", false, "Show the body as received, ignoring configured renderers")
+	getCmd.Flags().Bool("embed-images", false, "Embed inline images of HTML bodies as data: URIs instead of cid: references")
+	getCmd.Flags().StringP("output", "o", "", "Write to a file (or s3://, gs:// URL); inline images of HTML bodies are saved next to it")
 
 	// Set custom output to enable testing
 	getCmd.SetOut(os.Stdout)
@@ -0,0 +1,144 @@
+/*
+Copyright © 2025 longkey1
+
+Licensed under the Apache License, Version 2.0 (the "License");
+you may not use this file except in compliance with the License.
+You may obtain a copy of the License at
+
+	http://www.apache.org/licenses/LICENSE-2.0
+
+Unless required by applicable law or agreed to in writing, software
+distributed under the License is distributed on an "AS IS" BASIS,
+WITHOUT WARRANTIES OR CONDITIONS OF ANY KIND, either express or implied.
+See the License for the specific language governing permissions and
+limitations under the License.
+*/
+package cmd
+
+import (
+	"fmt"
+	"os"
+	"strings"
+
+	"github.com/longkey1/gml/internal/gml"
+	"github.com/spf13/cobra"
+)
+
+// labelCmd represents the label command
+var labelCmd = &cobra.Command{
+	Use:   "label",
+	Short: "Manage labels",
+	Long: `Manage labels.
+
+Examples:
+  gml label sync labels.yaml`,
+}
+
+// labelSyncCmd represents the label sync command
+var labelSyncCmd = &cobra.Command{
+	Use:   "sync <manifest>",
+	Short: "Create and update labels to match a manifest",
+	Long: `Create and update user labels to match a YAML manifest, so a label
+taxonomy can be kept in version control and replicated across accounts.
+
+Labels are matched by name, case-insensitively. Fields left out of the
+manifest are not changed. With --prune, user labels missing from the
+manifest are deleted (messages keep their other labels).
+
+Manifest format:
+  labels:
+    - name: Projects
+      color: {text: "#ffffff", background: "#16a765"}
+      children:
+        - name: Alpha                     # Projects/Alpha
+        - name: Beta
+          labelListVisibility: showIfUnread
+    - name: Receipts/2025                 # Parents are created as needed
+      messageListVisibility: hide
+
+Colors must be from Gmail's label palette. The manifest is read from stdin
+when given as -.
+Requires the "labels" or "modify" scope (see the scopes config option).
+
+Examples:
+  gml label sync labels.yaml
+  gml label sync labels.yaml --dry-run      # Show the API requests only
+  gml label sync labels.yaml --prune
+  gml --account personal label sync labels.yaml`,
+	Args: cobra.ExactArgs(1),
+	RunE: runLabelSync,
+}
+
+func runLabelSync(cmd *cobra.Command, args []string) error {
+	ctx := cmd.Context()
+	cfg, err := GetConfig(cmd)
+	if err != nil {
+		return err
+	}
+
+	// Get flags
+	prune, _ := cmd.Flags().GetBool("prune")
+
+	data, err := readInput(cmd, args[0])
+	if err != nil {
+		return err
+	}
+	manifest, err := gml.ParseLabelManifest(data)
+	if err != nil {
+		return err
+	}
+
+	// Create service
+	svc, err := gml.NewService(ctx, cfg)
+	if err != nil {
+		return fmt.Errorf("unable to create service: %w", err)
+	}
+
+	changes, err := gml.PlanLabelSync(ctx, svc, manifest, prune)
+	if err != nil {
+		return err
+	}
+
+	failures := failureLogFromFlags(cmd)
+	applied, err := gml.ApplyLabelSync(ctx, svc, changes, failures)
+	if err != nil {
+		return err
+	}
+
+	// Output
+	if formatFromFlags(cmd) == gml.OutputFormatJSON {
+		if err := gml.FormatJSON(cmd.OutOrStdout(), changes); err != nil {
+			return err
+		}
+		return reportFailures(cmd, failures)
+	}
+	if len(changes) == 0 {
+		fmt.Fprintln(cmd.OutOrStdout(), "Labels already match the manifest")
+		return nil
+	}
+	for _, c := range changes {
+		switch c.Action {
+		case gml.LabelSyncCreate:
+			fmt.Fprintf(cmd.OutOrStdout(), "+ %s\n", c.Name)
+		case gml.LabelSyncUpdate:
+			fmt.Fprintf(cmd.OutOrStdout(), "~ %s (%s)\n", c.Name, strings.Join(c.Fields, ", "))
+		case gml.LabelSyncDelete:
+			fmt.Fprintf(cmd.OutOrStdout(), "- %s\n", c.Name)
+		}
+	}
+	fmt.Fprintf(cmd.OutOrStdout(), "Applied %d of %d changes\n", applied, len(changes))
+
+	return reportFailures(cmd, failures)
+}
+
+func init() {
+	rootCmd.AddCommand(labelCmd)
+	labelCmd.AddCommand(labelSyncCmd)
+
+	labelSyncCmd.Flags().Bool("prune", false, "Delete user labels that are not in the manifest")
+	addIgnoreErrorsFlag(labelSyncCmd)
+	setFormats(labelSyncCmd, gml.OutputFormatText, gml.OutputFormatJSON)
+
+	// Set custom output to enable testing
+	labelCmd.SetOut(os.Stdout)
+}
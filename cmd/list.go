@@ -16,11 +16,20 @@ limitations under the License.
 package cmd
 
 import (
+	"bufio"
+	"context"
+	"errors"
 	"fmt"
+	"io"
+	"maps"
 	"os"
+	"strings"
+	"time"
 
 	"github.com/longkey1/gml/internal/gml"
+	"github.com/longkey1/gml/internal/tui"
 	"github.com/spf13/cobra"
+	"golang.org/x/term"
 )
 
 const defaultFields = "id,from,subject,date,labels,snippet"
@@ -31,7 +40,7 @@ var listCmd = &cobra.Command{
 	Short: "List Gmail messages",
 	Long: `List Gmail messages with optional filters.
 
-Available fields: id, threadid, url, from, to, subject, date, labels, snippet, body
+Available fields: ` + gml.MessageFields.String() + `
 
 Common labels: INBOX, SENT, DRAFT, SPAM, TRASH, STARRED, UNREAD, IMPORTANT,
                CATEGORY_PERSONAL, CATEGORY_SOCIAL, CATEGORY_PROMOTIONS,
@@ -40,67 +49,443 @@ Common labels: INBOX, SENT, DRAFT, SPAM, TRASH, STARRED, UNREAD, IMPORTANT,
 Examples:
   gml list                              # List recent messages
   gml list -q "from:example@gmail.com"  # Search messages
-  gml list -n 20                        # Get 20 messages
+  gml list -n 20                        # Get 20 messages per page
+  gml list -l INBOX --no-prompt         # Fetch all pages without asking in a terminal
   gml list -l INBOX                     # List messages in INBOX
   gml list -l INBOX -l UNREAD           # List unread messages in INBOX
+  gml list --newer-than 7d              # Messages from the last week
+  gml list --older-than 2y -n 100       # Messages older than two years
+  gml list --saved receipts             # Run a saved search (see gml search)
   gml list -f id,from,subject,body      # Specify fields to include
-  gml list --format json                # Output as JSON`,
+  gml list -q has:attachment -f id,subject,attachments  # Attachment names and sizes
+  gml list -f id,subject,body --body-format markdown  # Render HTML bodies as Markdown
+  gml list -f id,subject,headers --json      # Every header (Message-Id, Received, ...) as a map
+  gml list -f id,from --header List-Unsubscribe --format csv  # Only selected headers
+  gml list -f id,from,subject,auth      # SPF/DKIM/DMARC verdicts
+  gml list --format json                # Output as JSON
+  gml list --format markdown            # GitHub-flavored markdown table
+  gml list --wrap-cells                 # Wrap long subjects instead of truncating
+  gml list -n 100 --pick                # Pick a message in a fuzzy finder, print its ID
+  gml list -l INBOX --pick --open       # Pick a message and show it
+  gml list -n 500 --format ndjson | jq -r .subject  # Stream one JSON object per line
+  gml list --template '{{.From}}\t{{.Subject}}'  # Custom per-message output (Go template)
+  gml list -q is:unread --exec-per-message 'notify-send "$GML_FROM" "$GML_SUBJECT"'  # Run a command per message
+  gml list --format template --template-file row.tmpl
+  gml list --date-format relative       # Show dates like "2h ago"
+  gml list --account all --sort date    # Merge accounts newest first
+  gml list --sort from --reverse        # Sort by sender, Z to A
+  gml list --min-size 5M --sort size -f id,from,subject,size  # Largest messages first
+  gml list --date-format "2006-01-02 15:04"  # Custom Go time layout (local time)
+  gml list --format csv --delimiter semicolon --bom --crlf -o mail.csv  # CSV for Excel in EU locales
+  gml list --account all                # List across all configured accounts
+  gml list -f id,from,subject,date,labels,body --format sqlite -o mail.db  # Export to SQLite
+  gml list --format json -o s3://bucket/mail.json  # Upload to object storage`,
 	RunE: runList,
 }
 
 func runList(cmd *cobra.Command, args []string) error {
 	ctx := cmd.Context()
-	cfg := GetConfig()
+	cfgs, err := GetAccountConfigs(cmd)
+	if err != nil {
+		return err
+	}
 
 	// Get flags
-	query, _ := cmd.Flags().GetString("query")
+	query, labels, err := queryFromFlags(cmd)
+	if err != nil {
+		return err
+	}
+	if query, labels, err = savedSearchFromFlags(cmd, query, labels); err != nil {
+		return err
+	}
+	if query, err = searchQueryFromFlags(cmd, query); err != nil {
+		return err
+	}
 	maxResults, _ := cmd.Flags().GetInt64("max-results")
-	labels, _ := cmd.Flags().GetStringArray("label")
-	format, _ := cmd.Flags().GetString("format")
 	fieldsStr, _ := cmd.Flags().GetString("fields")
+	output, _ := cmd.Flags().GetString("output")
+	bodyFormatStr, _ := cmd.Flags().GetString("body-format")
+	rawHeaders, _ := cmd.Flags().GetBool("raw-headers")
+	sortStr, _ := cmd.Flags().GetString("sort")
+	reverse, _ := cmd.Flags().GetBool("reverse")
+	wrapCells, _ := cmd.Flags().GetBool("wrap-cells")
+	pick, _ := cmd.Flags().GetBool("pick")
+	noPrompt, _ := cmd.Flags().GetBool("no-prompt")
+	open, _ := cmd.Flags().GetBool("open")
+	headers, _ := cmd.Flags().GetStringArray("header")
+
+	sortKey, err := gml.ParseSortKey(sortStr)
+	if err != nil {
+		return err
+	}
+
+	bodyFormat, err := gml.ParseBodyFormat(bodyFormatStr)
+	if err != nil {
+		return err
+	}
+
+	dateFormat, err := dateFormatFromFlags(cmd)
+	if err != nil {
+		return err
+	}
+
+	urlStyle, err := urlStyleFromFlags(cmd)
+	if err != nil {
+		return err
+	}
+
+	csvOpts, err := csvOptionsFromFlags(cmd, gml.CSVOptions{})
+	if err != nil {
+		return err
+	}
+
+	outputFormat, tmpl, tmplText, err := outputFormatFromFlags(cmd)
+	if err != nil {
+		return err
+	}
+	if tmpl != nil && !cmd.Flags().Changed("fields") {
+		// Fetch only what the template uses (skipping the body unless needed)
+		if f := templateFields(tmplText); f != "" {
+			fieldsStr = f
+		}
+	}
+	if outputFormat == gml.OutputFormatSQLite && output == "" {
+		return fmt.Errorf("--output is required for sqlite format")
+	}
+	if open && !pick {
+		return fmt.Errorf("--open requires --pick")
+	}
+	if pick && (output != "" || formatChanged(cmd) || tmpl != nil) {
+		return fmt.Errorf("--pick cannot be combined with --format, --template or --output")
+	}
+	exec := messageExecFromFlags(cmd, cmd.OutOrStdout())
+	if exec != nil && (pick || output != "" || formatChanged(cmd) || tmpl != nil) {
+		return fmt.Errorf("--exec-per-message cannot be combined with --pick, --format, --template or --output")
+	}
 
 	// Parse fields
-	fields := gml.ParseFields(fieldsStr)
+	fields, err := gml.MessageFields.Parse(fieldsStr)
+	if err != nil {
+		return err
+	}
+	if len(headers) > 0 {
+		fields["headers"] = true
+	}
+	if pick {
+		// The picker shows the date, sender and subject
+		fields = gml.ParseFields("id,from,subject,date")
+	}
 
-	// Create service
-	svc, err := gml.NewService(ctx, cfg)
+	// Tag rows with their account when listing across all accounts
+	all, err := allAccountsSelected(cmd)
 	if err != nil {
-		return fmt.Errorf("unable to create service: %w", err)
+		return err
+	}
+	if all {
+		if open {
+			return fmt.Errorf("--open is not supported with --account %s", allAccounts)
+		}
+		fields["account"] = true
+	}
+
+	// Fetch the sort key even when it isn't an output field
+	fetchFields := fields
+	if sortKey != "" && !fields[sortKey] {
+		fetchFields = maps.Clone(fields)
+		fetchFields[sortKey] = true
 	}
 
-	// List messages
-	messages, err := gml.ListMessages(ctx, svc, gml.ListMessagesOptions{
+	opts := gml.ListMessagesOptions{
 		Query:      query,
 		MaxResults: maxResults,
 		LabelIDs:   labels,
-		Fields:     fields,
+		Fields:     fetchFields,
+		BodyFormat: bodyFormat,
+		RawHeaders: rawHeaders,
+		Headers:    headers,
+		URLStyle:   urlStyle,
+	}
+
+	// Stream NDJSON as messages arrive unless they must be reordered first
+	if exec == nil && outputFormat == gml.OutputFormatNDJSON && sortKey == "" && !reverse {
+		return streamMessages(ctx, cmd, cfgs, output, opts, fields["account"], dateFormat)
+	}
+
+	write := func(w io.Writer, messages []gml.MessageInfo) error {
+		if tmpl != nil {
+			return gml.FormatTemplate(w, messages, tmpl)
+		}
+		if outputFormat == gml.OutputFormatText {
+			return gml.FormatMessageTable(w, messages, fields, gml.TableOptions{
+				Width: gml.TerminalWidth(w),
+				Wrap:  wrapCells,
+			})
+		}
+		return gml.FormatMessageListCSV(w, messages, fields, outputFormat, csvOpts)
+	}
+
+	// Sort on the raw headers, then render dates for display
+	prepare := func(messages []gml.MessageInfo) {
+		gml.SortMessages(messages, sortKey, reverse)
+		now := time.Now()
+		for i := range messages {
+			m := &messages[i]
+			if sortKey != "" && !fields[sortKey] {
+				clearMessageField(m, sortKey)
+			}
+			m.Date = gml.FormatMailDate(m.Date, dateFormat, now)
+		}
+	}
+
+	// Concurrent accounts would fight over a single bar
+	progress := newProgress(cmd, "Fetching messages")
+	defer progress.Done()
+	if len(cfgs) == 1 {
+		opts.Progress = progress.Update
+	}
+
+	// In a terminal, show the first page and ask before fetching the rest.
+	// Sorted output needs every message first
+	paged := 0
+	if len(cfgs) == 1 && output == "" && outputFormat == gml.OutputFormatText && tmpl == nil && exec == nil && !pick && !noPrompt &&
+		sortKey == "" && !reverse && isInteractive(cmd) {
+		answers := bufio.NewReader(cmd.InOrStdin())
+		opts.Pager = func(page []gml.MessageInfo) (gml.PageAction, error) {
+			progress.Done()
+			prepare(page)
+			if err := write(cmd.OutOrStdout(), page); err != nil {
+				return gml.PageStop, fmt.Errorf("unable to format output: %w", err)
+			}
+			paged += len(page)
+			return promptMorePages(cmd, answers, maxResults)
+		}
+	}
+
+	// List messages (concurrently per account with --account all)
+	results := gml.ForEachAccount(ctx, cfgs, func(ctx context.Context, svc *gml.Service) ([]gml.MessageInfo, error) {
+		return gml.ListMessages(ctx, svc, opts)
+	})
+	progress.Done()
+	messages, errs := gml.MergeAccountResults(results, func(m *gml.MessageInfo, account string) {
+		if fields["account"] {
+			m.Account = account
+		}
 	})
+	partial, err := reportAccountErrors(cmd, errs, len(cfgs))
 	if err != nil {
 		return fmt.Errorf("unable to list messages: %w", err)
 	}
+	prepare(messages)
 
 	if len(messages) == 0 {
+		if paged > 0 {
+			return partial
+		}
+		if pick {
+			// Keep stdout empty for $(gml list --pick)
+			fmt.Fprintln(cmd.ErrOrStderr(), "No messages found.")
+			return &ExitError{Code: 1}
+		}
 		fmt.Fprintln(cmd.OutOrStdout(), "No messages found.")
-		return nil
+		return partial
 	}
 
 	// Output
-	outputFormat := gml.OutputFormat(format)
-	if err := gml.FormatMessageList(cmd.OutOrStdout(), messages, fields, outputFormat); err != nil {
+	if pick {
+		if err := pickMessages(cmd, messages, open); err != nil {
+			return err
+		}
+		return partial
+	}
+	if exec != nil {
+		for _, m := range messages {
+			exec.run(ctx, m)
+		}
+		if err := exec.err(); err != nil {
+			return err
+		}
+		return partial
+	}
+	if outputFormat == gml.OutputFormatSQLite {
+		if err := gml.ExportMessagesSQLite(ctx, output, messages); err != nil {
+			return fmt.Errorf("unable to export messages: %w", err)
+		}
+		fmt.Fprintf(cmd.ErrOrStderr(), "Exported %d messages to %s\n", len(messages), output)
+		return partial
+	}
+
+	if output == "" {
+		if err := write(cmd.OutOrStdout(), messages); err != nil {
+			return fmt.Errorf("unable to format output: %w", err)
+		}
+		return partial
+	}
+
+	// Write to a file or object storage URL
+	out, err := gml.CreateOutput(ctx, output)
+	if err != nil {
+		return err
+	}
+	defer out.Abort()
+
+	if err := write(out, messages); err != nil {
 		return fmt.Errorf("unable to format output: %w", err)
 	}
+	if err := out.Close(); err != nil {
+		return err
+	}
+	return partial
+}
+
+// streamMessages writes each message as an NDJSON line as soon as it is fetched
+func streamMessages(ctx context.Context, cmd *cobra.Command, cfgs []*gml.Config, output string, opts gml.ListMessagesOptions, tagAccount bool, dateFormat string) error {
+	var w io.Writer = cmd.OutOrStdout()
+	var out *gml.Output
+	if output != "" {
+		var err error
+		out, err = gml.CreateOutput(ctx, output)
+		if err != nil {
+			return err
+		}
+		defer out.Abort()
+		w = out
+	}
+
+	enc := gml.NewNDJSONWriter(w)
+	results := gml.ForEachAccount(ctx, cfgs, func(ctx context.Context, svc *gml.Service) ([]gml.MessageInfo, error) {
+		o := opts
+		o.Each = func(m gml.MessageInfo) error {
+			if tagAccount {
+				m.Account = svc.Account
+			}
+			m.Date = gml.FormatMailDate(m.Date, dateFormat, time.Now())
+			return enc.Write(m)
+		}
+		return gml.ListMessages(ctx, svc, o)
+	})
+	_, errs := gml.MergeAccountResults(results, func(*gml.MessageInfo, string) {})
+	partial, err := reportAccountErrors(cmd, errs, len(cfgs))
+	if err != nil {
+		return fmt.Errorf("unable to list messages: %w", err)
+	}
+
+	if out != nil {
+		if err := out.Close(); err != nil {
+			return err
+		}
+	}
+	return partial
+}
+
+// pickMessages lets the user choose messages in a fuzzy finder and prints
+// their IDs, or shows them like gml get with open
+func pickMessages(cmd *cobra.Command, messages []gml.MessageInfo, open bool) error {
+	ids, err := tui.Pick(messages)
+	if errors.Is(err, tui.ErrPickCanceled) {
+		// Like fzf, exit 130 without output so $(gml list --pick) expands to nothing
+		return &ExitError{Code: 130}
+	}
+	if err != nil {
+		return err
+	}
+
+	if !open {
+		for _, id := range ids {
+			fmt.Fprintln(cmd.OutOrStdout(), id)
+		}
+		return nil
+	}
 
+	ctx := cmd.Context()
+	cfg, err := GetConfig(cmd)
+	if err != nil {
+		return err
+	}
+	svc, err := gml.NewService(ctx, cfg)
+	if err != nil {
+		return fmt.Errorf("unable to create service: %w", err)
+	}
+	for i, id := range ids {
+		detail, err := gml.GetMessage(ctx, svc, id, gml.GetMessageOptions{})
+		if err != nil {
+			return fmt.Errorf("unable to get message: %w", err)
+		}
+		if i > 0 {
+			fmt.Fprintln(cmd.OutOrStdout())
+		}
+		if err := gml.FormatMessageDetail(cmd.OutOrStdout(), detail, gml.OutputFormatText); err != nil {
+			return fmt.Errorf("unable to format output: %w", err)
+		}
+	}
 	return nil
 }
 
+// isInteractive reports whether both stdin and stdout are terminals, so the
+// user can be asked questions
+func isInteractive(cmd *cobra.Command) bool {
+	in, ok := cmd.InOrStdin().(*os.File)
+	return ok && term.IsTerminal(int(in.Fd())) && isTerminal(cmd.OutOrStdout())
+}
+
+// promptMorePages asks on stderr whether to fetch another page of n messages
+// and reads the answer from in, which is shared by the prompts of a listing
+// so no buffered input is lost between them
+func promptMorePages(cmd *cobra.Command, in *bufio.Reader, n int64) (gml.PageAction, error) {
+	fmt.Fprintf(cmd.ErrOrStderr(), "Fetch %d more? [y/N/all] ", n)
+	line, err := in.ReadString('\n')
+	if err != nil && !errors.Is(err, io.EOF) {
+		return gml.PageStop, fmt.Errorf("unable to read answer: %w", err)
+	}
+	switch strings.ToLower(strings.TrimSpace(line)) {
+	case "y", "yes":
+		return gml.PageNext, nil
+	case "a", "all":
+		return gml.PageAll, nil
+	}
+	return gml.PageStop, nil
+}
+
+// clearMessageField empties a field that was fetched only for sorting
+func clearMessageField(m *gml.MessageInfo, field string) {
+	switch field {
+	case gml.SortByDate:
+		m.Date = ""
+	case gml.SortByFrom:
+		m.From = ""
+	case gml.SortBySubject:
+		m.Subject = ""
+	case gml.SortBySize:
+		m.Size = 0
+	}
+}
+
 func init() {
 	rootCmd.AddCommand(listCmd)
 
 	listCmd.Flags().StringP("query", "q", "", "Search query (Gmail search syntax)")
+	addQueryFlags(listCmd)
+	addSearchFlags(listCmd)
+	addSavedFlag(listCmd)
 	listCmd.Flags().Int64P("max-results", "n", 10, "Maximum number of messages to return")
 	listCmd.Flags().StringArrayP("label", "l", nil, "Filter by label (can be specified multiple times)")
-	listCmd.Flags().String("format", "text", "Output format (text or json)")
-	listCmd.Flags().StringP("fields", "f", defaultFields, "Comma-separated list of fields (id,from,to,subject,date,labels,snippet,body)")
+	setFormats(listCmd, gml.OutputFormatText, gml.OutputFormatJSON, gml.OutputFormatNDJSON, gml.OutputFormatCSV, gml.OutputFormatTSV,
+		gml.OutputFormatMarkdown, gml.OutputFormatTemplate, gml.OutputFormatSQLite)
+	addTemplateFlags(listCmd)
+	addExecPerMessageFlag(listCmd)
+	listCmd.Flags().StringArray("header", nil, "Include this header in the headers field (can be specified multiple times; implies -f ...,headers)")
+	addCSVFlags(listCmd)
+	addDateFormatFlag(listCmd)
+	addURLStyleFlag(listCmd)
+	listCmd.Flags().String("sort", "", "Sort by date (newest first), size (largest first), from or subject after fetching (default: API order)")
+	listCmd.Flags().Bool("reverse", false, "Reverse the sort order")
+	listCmd.Flags().Bool("pick", false, "Choose messages in an interactive fuzzy finder and print their IDs (tab marks several)")
+	listCmd.Flags().Bool("open", false, "With --pick, show the chosen messages like gml get")
+	listCmd.Flags().Bool("no-prompt", false, "Fetch all pages without asking, even in a terminal")
+	listCmd.Flags().Bool("wrap-cells", false, "Wrap long table cells onto several lines instead of truncating them")
+	listCmd.Flags().StringP("output", "o", "", "Write output to a file or s3:// / gs:// URL (required for sqlite)")
+	listCmd.Flags().String("body-format", "text", "Body format (text, html or markdown); HTML-only messages are converted for text")
+	listCmd.Flags().Bool("raw-headers", false, "Keep RFC 2047 encoded-words in from/to/subject undecoded (for debugging)")
+	addFieldsFlag(listCmd, gml.MessageFields, defaultFields)
 
 	// Set custom output to enable testing
 	listCmd.SetOut(os.Stdout)
@@ -0,0 +1,277 @@
+/*
+Copyright © 2025 longkey1
+
+Licensed under the Apache License, Version 2.0 (the "License");
+you may not use this file except in compliance with the License.
+You may obtain a copy of the License at
+
+	http://www.apache.org/licenses/LICENSE-2.0
+
+Unless required by applicable law or agreed to in writing, software
+distributed under the License is distributed on an "AS IS" BASIS,
+WITHOUT WARRANTIES OR CONDITIONS OF ANY KIND, either express or implied.
+See the License for the specific language governing permissions and
+limitations under the License.
+*/
+package cmd
+
+import (
+	"fmt"
+	"os"
+	"strings"
+
+	"github.com/longkey1/gml/internal/gml"
+	"github.com/spf13/cobra"
+)
+
+// templateCmd represents the template command
+var templateCmd = &cobra.Command{
+	Use:   "template",
+	Short: "Manage message templates",
+	Long: `Manage message templates for 'gml send --template <name>'.
+
+A template is a Go template body, optionally preceded by front matter with
+the body format (text, markdown or html) and the subject and recipients,
+which are templates too:
+
+  ---
+  format: markdown
+  subject: Weekly report, week {{.week}}
+  to: {{.name}} <{{.email}}>
+  cc: team@example.com
+  ---
+  Hi {{.name}},
+
+  here is the report for week {{.week}}.
+
+Templates are added with 'gml template add', which keeps them in the
+templates directory next to the config file, or defined in the config file as
+[templates.<name>] sections (subject, body or body_file, to, cc, bcc,
+format), which can only be changed there.
+
+Examples:
+  gml template add weekly-report --file tmpl.md
+  gml template list
+  gml send --template weekly-report --var name=Bob --var email=bob@example.com --var week=27
+  gml send --template weekly-report --var name=Bob --var email=bob@example.com --var week=27 --draft
+  gml template delete weekly-report`,
+}
+
+// templateAddCmd represents the template add command
+var templateAddCmd = &cobra.Command{
+	Use:   "add <name>",
+	Short: "Add a message template",
+	Long: `Add a message template from a file. The template is checked before it is
+saved; see 'gml template' for the format.
+
+Examples:
+  gml template add weekly-report --file tmpl.md
+  gml template add weekly-report --file tmpl.md --force  # Replace an existing template
+  cat tmpl.md | gml template add weekly-report --file -`,
+	Args: cobra.ExactArgs(1),
+	RunE: runTemplateAdd,
+}
+
+// templateListCmd represents the template list command
+var templateListCmd = &cobra.Command{
+	Use:   "list",
+	Short: "List message templates",
+	Long: `List the message templates from the config file and 'gml template add'.
+
+Examples:
+  gml template list
+  gml template list --format json`,
+	Args: cobra.NoArgs,
+	RunE: runTemplateList,
+}
+
+// templateShowCmd represents the template show command
+var templateShowCmd = &cobra.Command{
+	Use:   "show <name>",
+	Short: "Print a message template",
+	Long: `Print a message template, with front matter for its subject and recipients.
+
+Examples:
+  gml template show weekly-report
+  gml template show weekly-report > tmpl.md  # Edit, then add it again with --force`,
+	Args:              cobra.ExactArgs(1),
+	RunE:              runTemplateShow,
+	ValidArgsFunction: completeMailTemplates,
+}
+
+// templateDeleteCmd represents the template delete command
+var templateDeleteCmd = &cobra.Command{
+	Use:   "delete <name>",
+	Short: "Delete a message template",
+	Long: `Delete a template added with 'gml template add'.
+
+Examples:
+  gml template delete weekly-report`,
+	Args:              cobra.ExactArgs(1),
+	RunE:              runTemplateDelete,
+	ValidArgsFunction: completeMailTemplates,
+}
+
+func runTemplateAdd(cmd *cobra.Command, args []string) error {
+	cfg := getBaseConfig(cmd)
+	store, err := templateStore(cmd)
+	if err != nil {
+		return err
+	}
+
+	// Get flags
+	file, _ := cmd.Flags().GetString("file")
+	force, _ := cmd.Flags().GetBool("force")
+
+	name, err := gml.TemplateName(args[0])
+	if err != nil {
+		return err
+	}
+	if _, ok := cfg.Templates[name]; ok {
+		return fmt.Errorf("template %s is defined in the config file; edit it with 'gml config edit'", name)
+	}
+	if _, err := store.Load(name); err == nil && !force {
+		return fmt.Errorf("template %s already exists (use --force to replace it)", name)
+	}
+
+	data, err := readInput(cmd, file)
+	if err != nil {
+		return err
+	}
+	if err := store.Save(name, string(data)); err != nil {
+		return err
+	}
+
+	// Output
+	fmt.Fprintf(cmd.OutOrStdout(), "Saved template: %s\n", name)
+	return nil
+}
+
+func runTemplateList(cmd *cobra.Command, args []string) error {
+	cfg := getBaseConfig(cmd)
+	store, err := templateStore(cmd)
+	if err != nil {
+		return err
+	}
+
+	templates, err := gml.ListMailTemplates(cfg, store)
+	if err != nil {
+		return err
+	}
+
+	// Output
+	if err := gml.FormatMailTemplates(cmd.OutOrStdout(), templates, formatFromFlags(cmd)); err != nil {
+		return fmt.Errorf("unable to format output: %w", err)
+	}
+	return nil
+}
+
+func runTemplateShow(cmd *cobra.Command, args []string) error {
+	cfg := getBaseConfig(cmd)
+
+	var text string
+	// Viper lowercases config keys
+	if tmpl, ok := cfg.Templates[strings.ToLower(args[0])]; ok {
+		body, err := tmpl.LoadBody()
+		if err != nil {
+			return err
+		}
+		tmpl.Body = body
+		text = gml.FormatMailTemplate(tmpl)
+	} else {
+		var err error
+		if text, err = loadStoredTemplate(cmd, args[0]); err != nil {
+			return err
+		}
+	}
+
+	// Output
+	fmt.Fprint(cmd.OutOrStdout(), text)
+	if !strings.HasSuffix(text, "\n") {
+		fmt.Fprintln(cmd.OutOrStdout())
+	}
+	return nil
+}
+
+func runTemplateDelete(cmd *cobra.Command, args []string) error {
+	cfg := getBaseConfig(cmd)
+
+	name, err := gml.TemplateName(args[0])
+	if err != nil {
+		return err
+	}
+	if _, ok := cfg.Templates[name]; ok {
+		return fmt.Errorf("template %s is defined in the config file; edit it with 'gml config edit'", name)
+	}
+	store, err := templateStore(cmd)
+	if err != nil {
+		return err
+	}
+	if err := store.Delete(name); err != nil {
+		return err
+	}
+
+	// Output
+	fmt.Fprintf(cmd.OutOrStdout(), "Deleted template: %s\n", name)
+	return nil
+}
+
+// templateStore returns the store of templates added with 'gml template add'
+func templateStore(cmd *cobra.Command) (*gml.TemplateStore, error) {
+	path, err := configSiblingPath(cmd, "templates")
+	if err != nil {
+		return nil, err
+	}
+	return gml.NewTemplateStore(path), nil
+}
+
+// loadStoredTemplate returns the text of a template added with 'gml template
+// add'. A name that can't be a stored template, like a path, is not found
+func loadStoredTemplate(cmd *cobra.Command, name string) (string, error) {
+	stored, err := gml.TemplateName(name)
+	if err != nil {
+		return "", fmt.Errorf("%w: %w", gml.ErrNotFound, err)
+	}
+	store, err := templateStore(cmd)
+	if err != nil {
+		return "", err
+	}
+	return store.Load(stored)
+}
+
+// completeMailTemplates completes the names of message templates
+func completeMailTemplates(cmd *cobra.Command, args []string, toComplete string) ([]string, cobra.ShellCompDirective) {
+	if len(args) > 0 {
+		return nil, cobra.ShellCompDirectiveNoFileComp
+	}
+	store, err := templateStore(cmd)
+	if err != nil {
+		return nil, cobra.ShellCompDirectiveNoFileComp
+	}
+	templates, err := gml.ListMailTemplates(getBaseConfig(cmd), store)
+	if err != nil {
+		return nil, cobra.ShellCompDirectiveNoFileComp
+	}
+	var names []string
+	for _, t := range templates {
+		names = append(names, t.Name)
+	}
+	// Template files are accepted too
+	return names, cobra.ShellCompDirectiveDefault
+}
+
+func init() {
+	rootCmd.AddCommand(templateCmd)
+	templateCmd.AddCommand(templateAddCmd)
+	templateCmd.AddCommand(templateListCmd)
+	templateCmd.AddCommand(templateShowCmd)
+	templateCmd.AddCommand(templateDeleteCmd)
+
+	templateAddCmd.Flags().String("file", "", "Template file (- for stdin)")
+	templateAddCmd.MarkFlagRequired("file")
+	templateAddCmd.Flags().Bool("force", false, "Replace an existing template")
+	setFormats(templateListCmd, gml.OutputFormatText, gml.OutputFormatJSON)
+
+	// Set custom output to enable testing
+	templateCmd.SetOut(os.Stdout)
+}
@@ -0,0 +1,198 @@
+/*
+Copyright © 2025 longkey1
+
+Licensed under the Apache License, Version 2.0 (the "License");
+you may not use this file except in compliance with the License.
+You may obtain a copy of the License at
+
+	http://www.apache.org/licenses/LICENSE-2.0
+
+Unless required by applicable law or agreed to in writing, software
+distributed under the License is distributed on an "AS IS" BASIS,
+WITHOUT WARRANTIES OR CONDITIONS OF ANY KIND, either express or implied.
+See the License for the specific language governing permissions and
+limitations under the License.
+*/
+package cmd
+
+import (
+	"context"
+	"fmt"
+	"os"
+	"slices"
+
+	"github.com/longkey1/gml/internal/gml"
+	"github.com/spf13/cobra"
+)
+
+// maintainStateName is the state file holding the maintenance checkpoint
+const maintainStateName = "maintain"
+
+// maintainCmd represents the maintain command
+var maintainCmd = &cobra.Command{
+	Use:   "maintain",
+	Short: "Run configured maintenance tasks within a time and API budget",
+	Long: `Run the [[maintenance]] tasks of the config file in order, stopping when the
+time budget (--budget) or the API request budget (--max-requests) runs out.
+The task that was cut short is recorded in a checkpoint, and the next run
+resumes with it, so a nightly job works through a large mailbox over several
+nights without exceeding quotas. A failing task is reported and skipped.
+
+Task types:
+  retention   query, labels, action      Trash (or with action = "delete",
+                                         delete permanently) matching messages
+  label_sync  manifest, prune            Apply a label manifest (see label sync)
+  backup      dir, query, labels, copy   Export new messages as .eml files
+                                         (see export tree)
+  cache       query, labels              Fetch uncached metadata for stats
+
+Example config:
+  [maintain]
+  budget = "10m"
+  max_requests = 2000
+
+  [[maintenance]]
+  type = "retention"
+  query = "category:promotions older_than:1y"
+
+  [[maintenance]]
+  type = "label_sync"
+  manifest = "~/.config/gml/labels.yaml"
+
+  [[maintenance]]
+  type = "backup"
+  dir = "~/mail-backup"
+  query = "newer_than:30d"
+
+Retention and label sync require the modify scope (delete requires full).
+The exit status is 3 when a task failed; running out of budget is not an error.
+
+Examples:
+  gml maintain --budget 10m
+  gml maintain --budget 30m --max-requests 5000
+  gml maintain --task backup --budget 1h   # Run some tasks only
+  gml maintain --status                    # Show the checkpoint
+  gml maintain --reset                     # Start over with the first task`,
+	Args: cobra.NoArgs,
+	RunE: runMaintain,
+}
+
+func runMaintain(cmd *cobra.Command, args []string) error {
+	ctx := cmd.Context()
+	cfg, err := GetConfig(cmd)
+	if err != nil {
+		return err
+	}
+
+	// Get flags
+	budget, _ := cmd.Flags().GetDuration("budget")
+	maxRequests, _ := cmd.Flags().GetInt("max-requests")
+	names, _ := cmd.Flags().GetStringArray("task")
+	status, _ := cmd.Flags().GetBool("status")
+	reset, _ := cmd.Flags().GetBool("reset")
+	dryRun := gml.IsDryRun(ctx)
+
+	tasks := cfg.Maintenance
+	if len(tasks) == 0 {
+		return fmt.Errorf("no maintenance tasks configured (add [[maintenance]] tables to the config file)")
+	}
+	if err := gml.ValidateMaintainTasks(tasks); err != nil {
+		return err
+	}
+	if len(names) > 0 {
+		var selected []gml.MaintainTask
+		for _, name := range names {
+			i := slices.IndexFunc(tasks, func(t gml.MaintainTask) bool { return gml.MaintainTaskName(t) == name })
+			if i < 0 {
+				return fmt.Errorf("maintenance task not found: %s", name)
+			}
+			selected = append(selected, tasks[i])
+		}
+		tasks = selected
+	}
+
+	statePath, err := gml.StatePath(maintainStateName, cfg.Account)
+	if err != nil {
+		return err
+	}
+	if reset {
+		if err := os.Remove(statePath); err != nil && !os.IsNotExist(err) {
+			return fmt.Errorf("unable to reset checkpoint: %w", err)
+		}
+		fmt.Fprintln(cmd.ErrOrStderr(), "Checkpoint reset")
+		return nil
+	}
+	if status {
+		state, err := gml.LoadMaintainState(statePath)
+		if err != nil {
+			return err
+		}
+		return gml.FormatMaintainState(cmd.OutOrStdout(), tasks, state, formatFromFlags(cmd))
+	}
+	// A dry run changes nothing, so it must not move the checkpoint either
+	if dryRun {
+		statePath = ""
+	}
+
+	// Create service
+	ctx = gml.WithRequestBudget(ctx, maxRequests)
+	svc, err := gml.NewService(ctx, cfg)
+	if err != nil {
+		return fmt.Errorf("unable to create service: %w", err)
+	}
+
+	if budget > 0 {
+		var cancel context.CancelFunc
+		ctx, cancel = context.WithTimeout(ctx, budget)
+		defer cancel()
+	}
+
+	var (
+		progress gml.Progress
+		current  string
+	)
+	results, err := gml.Maintain(ctx, svc, tasks, gml.MaintainOptions{
+		StatePath: statePath,
+		Progress: func(task string, done, total int) {
+			if task != current {
+				if progress != nil {
+					progress.Done()
+				}
+				current = task
+				progress = newProgress(cmd, task)
+			}
+			progress.Update(done, total)
+		},
+	})
+	if progress != nil {
+		progress.Done()
+	}
+	if err != nil {
+		return err
+	}
+
+	// Output
+	if err := gml.FormatMaintainResults(cmd.OutOrStdout(), results, formatFromFlags(cmd)); err != nil {
+		return err
+	}
+	for _, r := range results {
+		if r.Status == gml.MaintainFailed {
+			return &ExitError{Code: exitCodePartialFailure}
+		}
+	}
+	return nil
+}
+
+func init() {
+	rootCmd.AddCommand(maintainCmd)
+
+	maintainCmd.Flags().Duration("budget", 0, "Stop after this long, e.g. 10m (0 for no limit)")
+	maintainCmd.Flags().Int("max-requests", 0, "Stop after this many API requests (0 for no limit)")
+	maintainCmd.Flags().StringArray("task", nil, "Run only this task (can be specified multiple times)")
+	maintainCmd.Flags().Bool("status", false, "Show the tasks with their last run and the checkpoint")
+	maintainCmd.Flags().Bool("reset", false, "Clear the checkpoint, so the next run starts with the first task")
+	setFormats(maintainCmd, gml.OutputFormatText, gml.OutputFormatJSON)
+
+	// Set custom output to enable testing
+	maintainCmd.SetOut(os.Stdout)
+}
@@ -0,0 +1,147 @@
+/*
+Copyright © 2025 longkey1
+
+Licensed under the Apache License, Version 2.0 (the "License");
+you may not use this file except in compliance with the License.
+You may obtain a copy of the License at
+
+	http://www.apache.org/licenses/LICENSE-2.0
+
+Unless required by applicable law or agreed to in writing, software
+distributed under the License is distributed on an "AS IS" BASIS,
+WITHOUT WARRANTIES OR CONDITIONS OF ANY KIND, either express or implied.
+See the License for the specific language governing permissions and
+limitations under the License.
+*/
+package cmd
+
+import (
+	"fmt"
+	"os"
+
+	"github.com/longkey1/gml/internal/gml"
+	"github.com/spf13/cobra"
+)
+
+// markAction describes one of the star/importance commands
+type markAction struct {
+	label string
+	add   bool
+	// done and pending are the summary lines, e.g. "Starred %d messages"
+	done    string
+	pending string
+}
+
+// starCmd represents the star command
+var starCmd = newMarkCmd("star", "Star messages", markAction{
+	label: gml.LabelStarred, add: true, done: "Starred %d messages", pending: "Would star %d messages",
+})
+
+// unstarCmd represents the unstar command
+var unstarCmd = newMarkCmd("unstar", "Remove the star from messages", markAction{
+	label: gml.LabelStarred, add: false, done: "Unstarred %d messages", pending: "Would unstar %d messages",
+})
+
+// importantCmd represents the important command
+var importantCmd = newMarkCmd("important", "Mark messages as important", markAction{
+	label: gml.LabelImportant, add: true, done: "Marked %d messages important", pending: "Would mark %d messages important",
+})
+
+// unimportantCmd represents the unimportant command
+var unimportantCmd = newMarkCmd("unimportant", "Mark messages as not important", markAction{
+	label: gml.LabelImportant, add: false, done: "Marked %d messages not important", pending: "Would mark %d messages not important",
+})
+
+// newMarkCmd builds a command adding or removing a system label
+func newMarkCmd(name, short string, action markAction) *cobra.Command {
+	return &cobra.Command{
+		Use:   name + " [message-id]...",
+		Short: short,
+		Long: fmt.Sprintf(`%s by ID, or every message matching a query.
+Messages already in the requested state are left alone.
+
+Requires the "modify" scope (see the scopes config option).
+
+Examples:
+  gml %[2]s 18abc123def456 18abc123def789
+  gml %[2]s -q "from:boss@example.com is:unread"
+  gml %[2]s -q "label:Newsletters" --older-than 30d --dry-run  # Preview the changes`, short, name),
+		RunE: func(cmd *cobra.Command, args []string) error {
+			return runMark(cmd, args, action)
+		},
+	}
+}
+
+func runMark(cmd *cobra.Command, args []string, action markAction) error {
+	ctx := cmd.Context()
+	cfg, err := GetConfig(cmd)
+	if err != nil {
+		return err
+	}
+
+	// Get flags
+	query, labels, err := queryFromFlags(cmd)
+	if err != nil {
+		return err
+	}
+	dryRun := gml.IsDryRun(ctx)
+	outputFormat := formatFromFlags(cmd)
+
+	if len(args) > 0 && (query != "" || len(labels) > 0) {
+		return fmt.Errorf("message IDs cannot be combined with --query or --label")
+	}
+	if len(args) == 0 && query == "" && len(labels) == 0 {
+		return fmt.Errorf("message IDs, --query or --label is required")
+	}
+
+	// Create service
+	svc, err := gml.NewService(ctx, cfg)
+	if err != nil {
+		return fmt.Errorf("unable to create service: %w", err)
+	}
+
+	failures := failureLogFromFlags(cmd)
+	changes, err := gml.MarkMessages(ctx, svc, gml.MarkOptions{
+		IDs:      args,
+		Query:    query,
+		LabelIDs: labels,
+		Label:    action.label,
+		Add:      action.add,
+		DryRun:   dryRun,
+		Failures: failures,
+	})
+	if err != nil {
+		return fmt.Errorf("unable to modify messages: %w", err)
+	}
+
+	// Output
+	if outputFormat == gml.OutputFormatJSON || dryRun && len(changes) > 0 {
+		if err := gml.FormatMarkChanges(cmd.OutOrStdout(), changes, outputFormat); err != nil {
+			return fmt.Errorf("unable to format output: %w", err)
+		}
+	}
+	if outputFormat != gml.OutputFormatJSON {
+		summary := action.done
+		if dryRun {
+			summary = action.pending
+		}
+		fmt.Fprintf(cmd.OutOrStdout(), summary+"\n", len(changes))
+	}
+
+	return reportFailures(cmd, failures)
+}
+
+func init() {
+	for _, c := range []*cobra.Command{starCmd, unstarCmd, importantCmd, unimportantCmd} {
+		rootCmd.AddCommand(c)
+
+		c.Flags().StringP("query", "q", "", "Search query (Gmail search syntax)")
+		addQueryFlags(c)
+		c.Flags().StringArrayP("label", "l", nil, "Filter by label (can be specified multiple times)")
+		addIgnoreErrorsFlag(c)
+		setFormats(c, gml.OutputFormatText, gml.OutputFormatJSON)
+
+		// Set custom output to enable testing
+		c.SetOut(os.Stdout)
+	}
+}
@@ -0,0 +1,147 @@
+/*
+Copyright © 2025 longkey1
+
+Licensed under the Apache License, Version 2.0 (the "License");
+you may not use this file except in compliance with the License.
+You may obtain a copy of the License at
+
+	http://www.apache.org/licenses/LICENSE-2.0
+
+Unless required by applicable law or agreed to in writing, software
+distributed under the License is distributed on an "AS IS" BASIS,
+WITHOUT WARRANTIES OR CONDITIONS OF ANY KIND, either express or implied.
+See the License for the specific language governing permissions and
+limitations under the License.
+*/
+package cmd
+
+import (
+	"errors"
+	"fmt"
+	"os"
+	"path/filepath"
+	"strings"
+
+	"github.com/longkey1/gml/internal/gml"
+	"github.com/spf13/cobra"
+)
+
+// migrateCmd represents the migrate command
+var migrateCmd = &cobra.Command{
+	Use:   "migrate",
+	Short: "Copy messages from one account to another",
+	Long: `Copy messages matching a query from one configured account into another.
+
+Raw messages are streamed one at a time and imported with the same labels,
+which are created in the target account as needed. The internal date is taken
+from each message's Date header. The target account needs the insert and
+labels scopes (or modify).
+
+Migrated message IDs are recorded in a journal under $XDG_STATE_HOME/gml, so
+re-running an interrupted migration resumes where it stopped. Use --restart
+to discard the journal.
+
+Examples:
+  gml migrate --from work --to personal -q "label:Keep"
+  gml migrate --from old --to new -q "after:2020/01/01" --dry-run
+  gml migrate --from old --to new -l Projects --restart`,
+	RunE: runMigrate,
+}
+
+func runMigrate(cmd *cobra.Command, args []string) error {
+	ctx := cmd.Context()
+	cfg := getBaseConfig(cmd)
+
+	// Get flags
+	from, _ := cmd.Flags().GetString("from")
+	to, _ := cmd.Flags().GetString("to")
+	query, labels, err := queryFromFlags(cmd)
+	if err != nil {
+		return err
+	}
+	journalPath, _ := cmd.Flags().GetString("journal")
+	restart, _ := cmd.Flags().GetBool("restart")
+	dryRun := gml.IsDryRun(ctx)
+
+	if strings.EqualFold(from, to) {
+		return fmt.Errorf("--from and --to must be different accounts")
+	}
+
+	srcCfg, err := cfg.ForAccount(from)
+	if err != nil {
+		return err
+	}
+	dstCfg, err := cfg.ForAccount(to)
+	if err != nil {
+		return err
+	}
+
+	if journalPath == "" {
+		dir, err := gml.StateDir()
+		if err != nil {
+			return err
+		}
+		journalPath = filepath.Join(dir, fmt.Sprintf("migrate-%s-%s.log", srcCfg.Account, dstCfg.Account))
+	}
+	if restart && !dryRun {
+		if err := os.Remove(journalPath); err != nil && !errors.Is(err, os.ErrNotExist) {
+			return fmt.Errorf("unable to remove migration journal: %w", err)
+		}
+	}
+
+	src, err := gml.NewService(ctx, srcCfg)
+	if err != nil {
+		return fmt.Errorf("unable to create service: %w", err)
+	}
+	dst, err := gml.NewService(ctx, dstCfg)
+	if err != nil {
+		return fmt.Errorf("unable to create service: %w", err)
+	}
+
+	failures := failureLogFromFlags(cmd)
+	progress := newProgress(cmd, "Migrating messages")
+	result, err := gml.MigrateMessages(ctx, src, dst, gml.MigrateOptions{
+		Query:       query,
+		Labels:      labels,
+		JournalPath: journalPath,
+		DryRun:      dryRun,
+		Failures:    failures,
+		Progress:    progress.Update,
+	})
+	progress.Done()
+	if result != nil && formatFromFlags(cmd) == gml.OutputFormatJSON {
+		if ferr := gml.FormatJSON(cmd.OutOrStdout(), result); ferr != nil {
+			return ferr
+		}
+	} else if result != nil {
+		if dryRun {
+			fmt.Fprintf(cmd.OutOrStdout(), "Would migrate %d of %d messages (%d already migrated)\n", result.Total-result.Skipped, result.Total, result.Skipped)
+		} else {
+			fmt.Fprintf(cmd.OutOrStdout(), "Migrated %d of %d messages (%d already migrated)\n", result.Migrated, result.Total, result.Skipped)
+		}
+	}
+	if err != nil {
+		return fmt.Errorf("migration interrupted, re-run to resume: %w", err)
+	}
+
+	return reportFailures(cmd, failures)
+}
+
+func init() {
+	rootCmd.AddCommand(migrateCmd)
+
+	migrateCmd.Flags().String("from", "", "Source account name")
+	migrateCmd.Flags().String("to", "", "Target account name")
+	migrateCmd.Flags().StringP("query", "q", "", "Search query (Gmail search syntax)")
+	addQueryFlags(migrateCmd)
+	migrateCmd.Flags().StringArrayP("label", "l", nil, "Filter by label in the source account (can be specified multiple times)")
+	migrateCmd.Flags().String("journal", "", "Path of the resume journal (default: state directory)")
+	migrateCmd.Flags().Bool("restart", false, "Discard the resume journal and migrate all matching messages")
+	addIgnoreErrorsFlag(migrateCmd)
+	setFormats(migrateCmd, gml.OutputFormatText, gml.OutputFormatJSON)
+	migrateCmd.MarkFlagRequired("from")
+	migrateCmd.MarkFlagRequired("to")
+
+	// Set custom output to enable testing
+	migrateCmd.SetOut(os.Stdout)
+}
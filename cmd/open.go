@@ -0,0 +1,97 @@
+/*
+Copyright © 2025 longkey1
+
+Licensed under the Apache License, Version 2.0 (the "License");
+you may not use this file except in compliance with the License.
+You may obtain a copy of the License at
+
+	http://www.apache.org/licenses/LICENSE-2.0
+
+Unless required by applicable law or agreed to in writing, software
+distributed under the License is distributed on an "AS IS" BASIS,
+WITHOUT WARRANTIES OR CONDITIONS OF ANY KIND, either express or implied.
+See the License for the specific language governing permissions and
+limitations under the License.
+*/
+package cmd
+
+import (
+	"fmt"
+	"os"
+
+	"github.com/longkey1/gml/internal/gml"
+	"github.com/longkey1/gml/internal/google"
+	"github.com/spf13/cobra"
+)
+
+// openCmd represents the open command
+var openCmd = &cobra.Command{
+	Use:   "open <message-id|thread-id>...",
+	Short: "Open messages in the Gmail web UI",
+	Long: `Open each message or thread ID in the Gmail web UI using the default browser.
+
+Links open in the view the mail is in: the inbox, spam, trash or All Mail.
+Message IDs link to the message within its conversation; use --url-style
+thread for the conversation, or message to jump to the newest message of a thread.
+
+Examples:
+  gml open 18abc123def456              # Open the message
+  gml open 18abc123def456 --print      # Print the URL instead
+  gml open 18abc123def456 --url-style thread  # Open the whole conversation
+  gml open $(gml list --pick)          # Pick a message and open it`,
+	Args: cobra.MinimumNArgs(1),
+	RunE: runOpen,
+}
+
+func runOpen(cmd *cobra.Command, args []string) error {
+	ctx := cmd.Context()
+	cfg, err := GetConfig(cmd)
+	if err != nil {
+		return err
+	}
+
+	// Get flags
+	printOnly, _ := cmd.Flags().GetBool("print")
+	style, err := urlStyleFromFlags(cmd)
+	if err != nil {
+		return err
+	}
+
+	// Create service
+	svc, err := gml.NewService(ctx, cfg)
+	if err != nil {
+		return fmt.Errorf("unable to create service: %w", err)
+	}
+
+	email, err := gml.GetUserEmail(ctx, svc)
+	if err != nil {
+		return err
+	}
+
+	for _, id := range args {
+		url, err := gml.ResolveMailURL(ctx, svc, gml.MailLinks{Email: email, Style: style}, id)
+		if err != nil {
+			return err
+		}
+
+		// Output
+		if printOnly {
+			fmt.Fprintln(cmd.OutOrStdout(), url)
+			continue
+		}
+		if err := google.OpenBrowser(url); err != nil {
+			return fmt.Errorf("unable to open browser (use --print to get the URL): %w", err)
+		}
+	}
+	return nil
+}
+
+func init() {
+	rootCmd.AddCommand(openCmd)
+
+	openCmd.Flags().Bool("print", false, "Print the Gmail URL instead of opening a browser")
+	addURLStyleFlag(openCmd)
+
+	// Set custom output to enable testing
+	openCmd.SetOut(os.Stdout)
+}
@@ -0,0 +1,86 @@
+/*
+Copyright © 2025 longkey1
+
+Licensed under the Apache License, Version 2.0 (the "License");
+you may not use this file except in compliance with the License.
+You may obtain a copy of the License at
+
+	http://www.apache.org/licenses/LICENSE-2.0
+
+Unless required by applicable law or agreed to in writing, software
+distributed under the License is distributed on an "AS IS" BASIS,
+WITHOUT WARRANTIES OR CONDITIONS OF ANY KIND, either express or implied.
+See the License for the specific language governing permissions and
+limitations under the License.
+*/
+package cmd
+
+import (
+	"context"
+	"fmt"
+	"os"
+
+	"github.com/longkey1/gml/internal/gml"
+	"github.com/spf13/cobra"
+)
+
+// profileCmd represents the profile command
+var profileCmd = &cobra.Command{
+	Use:   "profile",
+	Short: "Show the Gmail profile of the account",
+	Long: `Show the email address, total message and thread counts and current
+history ID of the authenticated account, e.g. to check which account a token
+belongs to.
+
+Examples:
+  gml profile
+  gml profile --json | jq -r .emailAddress
+  gml profile --account all`,
+	Args: cobra.NoArgs,
+	RunE: runProfile,
+}
+
+func runProfile(cmd *cobra.Command, args []string) error {
+	ctx := cmd.Context()
+	cfgs, err := GetAccountConfigs(cmd)
+	if err != nil {
+		return err
+	}
+
+	// Get profiles (concurrently per account with --account all)
+	tagAccount, err := allAccountsSelected(cmd)
+	if err != nil {
+		return err
+	}
+	results := gml.ForEachAccount(ctx, cfgs, func(ctx context.Context, svc *gml.Service) ([]gml.Profile, error) {
+		p, err := gml.GetProfile(ctx, svc)
+		if err != nil {
+			return nil, err
+		}
+		return []gml.Profile{*p}, nil
+	})
+	profiles, errs := gml.MergeAccountResults(results, func(p *gml.Profile, account string) {
+		if tagAccount {
+			p.Account = account
+		}
+	})
+	partial, err := reportAccountErrors(cmd, errs, len(cfgs))
+	if err != nil {
+		return err
+	}
+
+	// Output
+	if err := gml.FormatProfiles(cmd.OutOrStdout(), profiles, formatFromFlags(cmd)); err != nil {
+		return fmt.Errorf("unable to format output: %w", err)
+	}
+	return partial
+}
+
+func init() {
+	rootCmd.AddCommand(profileCmd)
+
+	setFormats(profileCmd, gml.OutputFormatText, gml.OutputFormatJSON)
+
+	// Set custom output to enable testing
+	profileCmd.SetOut(os.Stdout)
+}
@@ -0,0 +1,46 @@
+/*
+Copyright © 2025 longkey1
+
+Licensed under the Apache License, Version 2.0 (the "License");
+you may not use this file except in compliance with the License.
+You may obtain a copy of the License at
+
+	http://www.apache.org/licenses/LICENSE-2.0
+
+Unless required by applicable law or agreed to in writing, software
+distributed under the License is distributed on an "AS IS" BASIS,
+WITHOUT WARRANTIES OR CONDITIONS OF ANY KIND, either express or implied.
+See the License for the specific language governing permissions and
+limitations under the License.
+*/
+package cmd
+
+import (
+	"io"
+	"os"
+
+	"github.com/longkey1/gml/internal/gml"
+	"github.com/spf13/cobra"
+	"golang.org/x/term"
+)
+
+// newProgress returns a progress reporter on stderr: a bar when stderr is a
+// terminal, log lines with --verbose, and nothing otherwise
+func newProgress(cmd *cobra.Command, title string) gml.Progress {
+	verbose, _ := cmd.Flags().GetBool("verbose")
+
+	mode := gml.ProgressNone
+	switch {
+	case isTerminal(cmd.ErrOrStderr()):
+		mode = gml.ProgressBar
+	case verbose:
+		mode = gml.ProgressLog
+	}
+	return gml.NewProgress(cmd.ErrOrStderr(), title, mode)
+}
+
+// isTerminal reports whether w is a terminal
+func isTerminal(w io.Writer) bool {
+	f, ok := w.(*os.File)
+	return ok && term.IsTerminal(int(f.Fd()))
+}
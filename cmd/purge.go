@@ -0,0 +1,134 @@
+/*
+Copyright © 2025 longkey1
+
+Licensed under the Apache License, Version 2.0 (the "License");
+you may not use this file except in compliance with the License.
+You may obtain a copy of the License at
+
+	http://www.apache.org/licenses/LICENSE-2.0
+
+Unless required by applicable law or agreed to in writing, software
+distributed under the License is distributed on an "AS IS" BASIS,
+WITHOUT WARRANTIES OR CONDITIONS OF ANY KIND, either express or implied.
+See the License for the specific language governing permissions and
+limitations under the License.
+*/
+package cmd
+
+import (
+	"fmt"
+	"os"
+
+	"github.com/longkey1/gml/internal/gml"
+	"github.com/spf13/cobra"
+	"golang.org/x/term"
+)
+
+// purgeCmd represents the purge command
+var purgeCmd = &cobra.Command{
+	Use:   "purge",
+	Short: "Permanently delete messages",
+	Long: `Permanently delete messages matching a query, or everything in the trash
+or spam, with BatchDelete. Deleted messages skip the trash and cannot be
+recovered.
+
+The number of matching messages is shown and must be confirmed; scripts
+(and non-interactive runs) must pass --yes.
+Requires the "full" scope (see the scopes config option).
+
+Examples:
+  gml purge --trash                              # Empty the trash
+  gml purge --trash --spam                       # Empty trash and spam
+  gml purge -q "from:noreply@example.com older_than:2y"
+  gml purge -q "label:Alerts" --older-than 90d --yes`,
+	Args: cobra.NoArgs,
+	RunE: runPurge,
+}
+
+func runPurge(cmd *cobra.Command, args []string) error {
+	ctx := cmd.Context()
+	cfg, err := GetConfig(cmd)
+	if err != nil {
+		return err
+	}
+
+	// Get flags
+	query, labels, err := queryFromFlags(cmd)
+	if err != nil {
+		return err
+	}
+	trash, _ := cmd.Flags().GetBool("trash")
+	spam, _ := cmd.Flags().GetBool("spam")
+	yes, _ := cmd.Flags().GetBool("yes")
+	dryRun := gml.IsDryRun(ctx)
+
+	if query == "" && len(labels) == 0 && !trash && !spam {
+		return fmt.Errorf("--query, --label, --trash or --spam is required")
+	}
+	if !yes && !dryRun && !term.IsTerminal(int(os.Stdin.Fd())) {
+		return fmt.Errorf("refusing to delete without confirmation; pass --yes when not running interactively")
+	}
+
+	// Create service
+	svc, err := gml.NewService(ctx, cfg)
+	if err != nil {
+		return fmt.Errorf("unable to create service: %w", err)
+	}
+
+	ids, err := gml.FindPurgeMessages(ctx, svc, gml.PurgeOptions{
+		Query:    query,
+		LabelIDs: labels,
+		Trash:    trash,
+		Spam:     spam,
+	})
+	if err != nil {
+		return fmt.Errorf("unable to find messages: %w", err)
+	}
+	if len(ids) == 0 {
+		fmt.Fprintln(cmd.OutOrStdout(), "No messages found.")
+		return nil
+	}
+
+	if !yes && !dryRun {
+		fmt.Fprintf(cmd.OutOrStdout(), "Permanently delete %d messages? This cannot be undone. [y/N]: ", len(ids))
+		var response string
+		fmt.Scanln(&response)
+		if response != "y" && response != "Y" {
+			fmt.Fprintln(cmd.OutOrStdout(), "Cancelled.")
+			return nil
+		}
+	}
+
+	failures := failureLogFromFlags(cmd)
+	progress := newProgress(cmd, "Deleting messages")
+	deleted, err := gml.PurgeMessages(ctx, svc, ids, failures, progress.Update)
+	progress.Done()
+	if err != nil {
+		return fmt.Errorf("deleted %d messages before failing: %w", deleted, err)
+	}
+
+	// Output
+	if dryRun {
+		// The deletions were only reported
+		fmt.Fprintf(cmd.OutOrStdout(), "Would delete %d messages\n", deleted)
+	} else {
+		fmt.Fprintf(cmd.OutOrStdout(), "Deleted %d messages\n", deleted)
+	}
+
+	return reportFailures(cmd, failures)
+}
+
+func init() {
+	rootCmd.AddCommand(purgeCmd)
+
+	purgeCmd.Flags().StringP("query", "q", "", "Search query (Gmail search syntax)")
+	addQueryFlags(purgeCmd)
+	purgeCmd.Flags().StringArrayP("label", "l", nil, "Filter by label (can be specified multiple times)")
+	purgeCmd.Flags().Bool("trash", false, "Delete every message in the trash")
+	purgeCmd.Flags().Bool("spam", false, "Delete every message in spam")
+	purgeCmd.Flags().BoolP("yes", "y", false, "Skip the confirmation prompt")
+	addIgnoreErrorsFlag(purgeCmd)
+
+	// Set custom output to enable testing
+	purgeCmd.SetOut(os.Stdout)
+}
@@ -0,0 +1,147 @@
+/*
+Copyright © 2025 longkey1
+
+Licensed under the Apache License, Version 2.0 (the "License");
+you may not use this file except in compliance with the License.
+You may obtain a copy of the License at
+
+	http://www.apache.org/licenses/LICENSE-2.0
+
+Unless required by applicable law or agreed to in writing, software
+distributed under the License is distributed on an "AS IS" BASIS,
+WITHOUT WARRANTIES OR CONDITIONS OF ANY KIND, either express or implied.
+See the License for the specific language governing permissions and
+limitations under the License.
+*/
+package cmd
+
+import (
+	"fmt"
+	"time"
+
+	"github.com/longkey1/gml/internal/gml"
+	"github.com/spf13/cobra"
+)
+
+// addQueryFlags adds the shared query modifiers to a command that already has
+// --query and --label flags
+func addQueryFlags(cmd *cobra.Command) {
+	cmd.Flags().String("older-than", "", "Only messages older than an age (e.g. 30d, 6w, 2y)")
+	cmd.Flags().String("newer-than", "", "Only messages newer than an age (e.g. 12h, 7d)")
+	cmd.Flags().Bool("query-labels", false, "Move label: and in: terms from the query to label filters")
+	addLabelIDFlag(cmd)
+}
+
+// addSearchFlags adds structured search terms that are composed into the query
+// by searchQueryFromFlags, for commands without --from/--to account flags
+func addSearchFlags(cmd *cobra.Command) {
+	cmd.Flags().String("from", "", "Only messages from this sender (from:)")
+	cmd.Flags().String("to", "", "Only messages to this recipient (to:)")
+	cmd.Flags().String("subject", "", "Only messages with these words or phrase in the subject (subject:)")
+	cmd.Flags().String("after", "", "Only messages after a date (YYYY-MM-DD, local time)")
+	cmd.Flags().String("before", "", "Only messages before a date (YYYY-MM-DD, local time)")
+	cmd.Flags().String("in", "", "Only messages in a location, e.g. inbox, sent, anywhere (in:)")
+	cmd.Flags().String("min-size", "", "Only messages larger than a size, e.g. 500K, 5M (larger:)")
+	cmd.Flags().String("max-size", "", "Only messages smaller than a size, e.g. 1M (smaller:)")
+	cmd.Flags().Bool("has-attachment", false, "Only messages with attachments (has:attachment)")
+	cmd.Flags().Bool("unread-only", false, "Only unread messages (is:unread)")
+}
+
+// searchQueryFromFlags extends query with the terms of the addSearchFlags flags
+func searchQueryFromFlags(cmd *cobra.Command, query string) (string, error) {
+	from, _ := cmd.Flags().GetString("from")
+	to, _ := cmd.Flags().GetString("to")
+	subject, _ := cmd.Flags().GetString("subject")
+	afterStr, _ := cmd.Flags().GetString("after")
+	beforeStr, _ := cmd.Flags().GetString("before")
+	in, _ := cmd.Flags().GetString("in")
+	minSizeStr, _ := cmd.Flags().GetString("min-size")
+	maxSizeStr, _ := cmd.Flags().GetString("max-size")
+	hasAttachment, _ := cmd.Flags().GetBool("has-attachment")
+	unreadOnly, _ := cmd.Flags().GetBool("unread-only")
+
+	var after, before time.Time
+	var err error
+	if afterStr != "" {
+		if after, err = gml.ParseQueryDate(afterStr); err != nil {
+			return "", fmt.Errorf("invalid --after: %w", err)
+		}
+	}
+	if beforeStr != "" {
+		if before, err = gml.ParseQueryDate(beforeStr); err != nil {
+			return "", fmt.Errorf("invalid --before: %w", err)
+		}
+	}
+	var minSize, maxSize int64
+	if minSizeStr != "" {
+		if minSize, err = gml.ParseSize(minSizeStr); err != nil {
+			return "", fmt.Errorf("invalid --min-size: %w", err)
+		}
+	}
+	if maxSizeStr != "" {
+		if maxSize, err = gml.ParseSize(maxSizeStr); err != nil {
+			return "", fmt.Errorf("invalid --max-size: %w", err)
+		}
+	}
+
+	return gml.NewQueryBuilder(query).
+		From(from).
+		To(to).
+		Subject(subject).
+		In(in).
+		After(after).
+		Before(before).
+		Larger(minSize).
+		Smaller(maxSize).
+		HasAttachment(hasAttachment).
+		Unread(unreadOnly).
+		String(), nil
+}
+
+// addLabelIDFlag adds --label-id to a command with a --label array flag
+func addLabelIDFlag(cmd *cobra.Command) {
+	cmd.Flags().StringArray("label-id", nil, "Filter by exact label ID, bypassing name matching (can be specified multiple times)")
+}
+
+// labelsFromFlags returns the --label names followed by the --label-id values,
+// marked with gml.LabelIDPrefix so they are used as IDs without name matching
+func labelsFromFlags(cmd *cobra.Command) []string {
+	labels, _ := cmd.Flags().GetStringArray("label")
+	labelIDs, _ := cmd.Flags().GetStringArray("label-id")
+	for _, id := range labelIDs {
+		labels = append(labels, gml.LabelIDPrefix+id)
+	}
+	return labels
+}
+
+// queryFromFlags returns the --query value extended with the --older-than and
+// --newer-than bounds, and the --label values plus any label terms moved out
+// of the query by --query-labels
+func queryFromFlags(cmd *cobra.Command) (string, []string, error) {
+	query, _ := cmd.Flags().GetString("query")
+	labels := labelsFromFlags(cmd)
+	olderThanStr, _ := cmd.Flags().GetString("older-than")
+	newerThanStr, _ := cmd.Flags().GetString("newer-than")
+	queryLabels, _ := cmd.Flags().GetBool("query-labels")
+
+	var olderThan, newerThan time.Duration
+	var err error
+	if olderThanStr != "" {
+		if olderThan, err = gml.ParseAge(olderThanStr); err != nil {
+			return "", nil, fmt.Errorf("invalid --older-than: %w", err)
+		}
+	}
+	if newerThanStr != "" {
+		if newerThan, err = gml.ParseAge(newerThanStr); err != nil {
+			return "", nil, fmt.Errorf("invalid --newer-than: %w", err)
+		}
+	}
+
+	if queryLabels {
+		var extracted []string
+		query, extracted = gml.ExtractLabelTerms(query)
+		labels = append(labels, extracted...)
+	}
+
+	return gml.AgeQuery(query, olderThan, newerThan, time.Now()), labels, nil
+}
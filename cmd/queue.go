@@ -0,0 +1,300 @@
+/*
+Copyright © 2025 longkey1
+
+Licensed under the Apache License, Version 2.0 (the "License");
+you may not use this file except in compliance with the License.
+You may obtain a copy of the License at
+
+	http://www.apache.org/licenses/LICENSE-2.0
+
+Unless required by applicable law or agreed to in writing, software
+distributed under the License is distributed on an "AS IS" BASIS,
+WITHOUT WARRANTIES OR CONDITIONS OF ANY KIND, either express or implied.
+See the License for the specific language governing permissions and
+limitations under the License.
+*/
+package cmd
+
+import (
+	"fmt"
+	"log/slog"
+	"os"
+	"os/signal"
+	"strings"
+	"syscall"
+	"time"
+
+	"github.com/longkey1/gml/internal/gml"
+	"github.com/spf13/cobra"
+)
+
+// queueCmd represents the queue command
+var queueCmd = &cobra.Command{
+	Use:   "queue",
+	Short: "Manage messages scheduled with send --at",
+	Long: `Manage the local send queue filled by 'gml send --at'. Queued messages are
+sent by 'gml queue run', which has to run from cron or as a daemon (see
+'gml service install queue'); nothing is sent while it doesn't run.
+
+The queue is kept per account in the state directory.
+
+Examples:
+  gml send --to bob@example.com -s "Reminder" --body "Standup in 10" --at "tomorrow 9:50am"
+  gml queue list
+  gml queue edit 1a2b3c4d                 # Edit recipients, subject and body in $EDITOR
+  gml queue edit 1a2b3c4d --at "fri 8am"  # Reschedule
+  gml queue cancel 1a2b3c4d
+  gml queue run --daemon`,
+}
+
+// queueListCmd represents the queue list command
+var queueListCmd = &cobra.Command{
+	Use:   "list",
+	Short: "List queued messages",
+	Long: `List the queued messages, soonest first, with the error of the last failed
+attempt if any. Messages that are no longer retried are marked as failed;
+edit, reschedule or cancel them.
+
+Examples:
+  gml queue list
+  gml queue list --json`,
+	Args: cobra.NoArgs,
+	RunE: runQueueList,
+}
+
+// queueRunCmd represents the queue run command
+var queueRunCmd = &cobra.Command{
+	Use:   "run",
+	Short: "Send the queued messages that are due",
+	Long: `Send the queued messages that are due and remove them from the queue. A
+message that fails stays queued with its error and is retried on the next run,
+up to 10 attempts. Messages Gmail rejects, e.g. for an invalid recipient, are
+not retried; they stay listed as failed until edited, rescheduled or canceled.
+
+By default the queue is checked once, for cron; with --daemon gml keeps
+checking every --interval until interrupted. Overlapping runs wait for each
+other, so no message is sent twice.
+
+Sending requires the send (or compose/modify) scope.
+
+Examples:
+  gml queue run
+  gml queue run --daemon --interval 30s
+  * * * * * gml queue run        # crontab entry`,
+	Args: cobra.NoArgs,
+	RunE: runQueueRun,
+}
+
+// queueCancelCmd represents the queue cancel command
+var queueCancelCmd = &cobra.Command{
+	Use:   "cancel <queue-id>...",
+	Short: "Remove messages from the queue without sending them",
+	Long: `Remove messages from the send queue without sending them.
+
+Examples:
+  gml queue cancel 1a2b3c4d`,
+	Args: cobra.MinimumNArgs(1),
+	RunE: runQueueCancel,
+}
+
+// queueEditCmd represents the queue edit command
+var queueEditCmd = &cobra.Command{
+	Use:   "edit <queue-id>",
+	Short: "Edit or reschedule a queued message",
+	Long: `Open a queued message in $VISUAL or $EDITOR (default vi) as a To/Cc/Bcc/Subject
+header block followed by the body, and save the changes to the queue. With
+--at, the message is rescheduled instead.
+
+Examples:
+  gml queue edit 1a2b3c4d
+  gml queue edit 1a2b3c4d --at "2025-07-01 08:00"
+  gml queue edit 1a2b3c4d --at 10m       # Send (almost) now`,
+	Args: cobra.ExactArgs(1),
+	RunE: runQueueEdit,
+}
+
+// sendQueuePath returns the send queue file of the current account
+func sendQueuePath(cfg *gml.Config) (string, error) {
+	return gml.StatePath(gml.SendQueueName, cfg.Account)
+}
+
+func runQueueList(cmd *cobra.Command, args []string) error {
+	cfg, err := GetConfig(cmd)
+	if err != nil {
+		return err
+	}
+
+	path, err := sendQueuePath(cfg)
+	if err != nil {
+		return err
+	}
+	queue, err := gml.LoadSendQueue(path)
+	if err != nil {
+		return err
+	}
+
+	// Output
+	if len(queue.Messages) == 0 && formatFromFlags(cmd) == gml.OutputFormatText {
+		fmt.Fprintln(cmd.OutOrStdout(), "No queued messages.")
+		return nil
+	}
+	return gml.FormatSendQueue(cmd.OutOrStdout(), queue.Messages, formatFromFlags(cmd))
+}
+
+func runQueueRun(cmd *cobra.Command, args []string) error {
+	ctx, stop := signal.NotifyContext(cmd.Context(), os.Interrupt, syscall.SIGTERM)
+	defer stop()
+	cfg, err := GetConfig(cmd)
+	if err != nil {
+		return err
+	}
+
+	// Get flags
+	daemon, _ := cmd.Flags().GetBool("daemon")
+	interval, _ := cmd.Flags().GetDuration("interval")
+	if daemon && interval < time.Second {
+		return fmt.Errorf("--interval must be at least 1s")
+	}
+	path, err := sendQueuePath(cfg)
+	if err != nil {
+		return err
+	}
+
+	// Create service
+	svc, err := gml.NewService(ctx, cfg)
+	if err != nil {
+		return fmt.Errorf("unable to create service: %w", err)
+	}
+
+	send := func() error {
+		sent, err := gml.SendDue(ctx, svc, path, time.Now())
+		for _, m := range sent {
+			fmt.Fprintf(cmd.OutOrStdout(), "Sent %s to %s: %s\n", m.ID, strings.Join(m.Message.To, ", "), m.Message.Subject)
+		}
+		return err
+	}
+	if !daemon {
+		return send()
+	}
+
+	backoff := &gml.Backoff{Interval: interval, Max: max(30*time.Minute, interval)}
+	timer := time.NewTimer(0)
+	defer timer.Stop()
+	for {
+		select {
+		case <-ctx.Done():
+			return nil
+		case <-timer.C:
+			err := send()
+			if ctx.Err() != nil {
+				return nil
+			}
+			delay := backoff.Next(err)
+			if err != nil {
+				slog.Warn("queue run failed", "err", err, "retry", delay)
+			}
+			timer.Reset(delay)
+		}
+	}
+}
+
+func runQueueCancel(cmd *cobra.Command, args []string) error {
+	ctx := cmd.Context()
+	cfg, err := GetConfig(cmd)
+	if err != nil {
+		return err
+	}
+
+	path, err := sendQueuePath(cfg)
+	if err != nil {
+		return err
+	}
+	canceled, err := gml.CancelQueued(ctx, path, args)
+	if err != nil {
+		return err
+	}
+
+	// Output
+	for _, m := range canceled {
+		fmt.Fprintf(cmd.OutOrStdout(), "Canceled %s: %s\n", m.ID, m.Message.Subject)
+	}
+	return nil
+}
+
+func runQueueEdit(cmd *cobra.Command, args []string) error {
+	ctx := cmd.Context()
+	cfg, err := GetConfig(cmd)
+	if err != nil {
+		return err
+	}
+
+	// Get flags
+	atStr, _ := cmd.Flags().GetString("at")
+	noChecks, _ := cmd.Flags().GetBool("no-checks")
+	path, err := sendQueuePath(cfg)
+	if err != nil {
+		return err
+	}
+
+	var update func(m *gml.QueuedMessage) error
+	if atStr != "" {
+		at, err := gml.ParseWhen(atStr, time.Now())
+		if err != nil {
+			return fmt.Errorf("invalid --at: %w", err)
+		}
+		update = func(m *gml.QueuedMessage) error {
+			m.At = at
+			return nil
+		}
+	} else {
+		queue, err := gml.LoadSendQueue(path)
+		if err != nil {
+			return err
+		}
+		queued, err := queue.FindQueued(args[0])
+		if err != nil {
+			return err
+		}
+		text, err := editText(cmd, "gml-queue-*.txt", gml.FormatEditableMessage(queued.Message))
+		if err != nil {
+			return err
+		}
+		msg, err := gml.ParseEditableMessage(text, queued.Message)
+		if err != nil {
+			return err
+		}
+		if err := checkOutgoing(cmd, msg, noChecks); err != nil {
+			return err
+		}
+		update = func(m *gml.QueuedMessage) error {
+			m.Message = msg
+			return nil
+		}
+	}
+
+	updated, err := gml.UpdateQueued(ctx, path, args[0], update)
+	if err != nil {
+		return err
+	}
+
+	// Output
+	fmt.Fprintf(cmd.OutOrStdout(), "Updated %s, to be sent %s\n", updated.ID, updated.At.Local().Format("Mon 2006-01-02 15:04"))
+	return nil
+}
+
+func init() {
+	rootCmd.AddCommand(queueCmd)
+	queueCmd.AddCommand(queueListCmd)
+	queueCmd.AddCommand(queueRunCmd)
+	queueCmd.AddCommand(queueCancelCmd)
+	queueCmd.AddCommand(queueEditCmd)
+
+	setFormats(queueListCmd, gml.OutputFormatText, gml.OutputFormatJSON)
+	queueRunCmd.Flags().Bool("daemon", false, "Keep running and check the queue every --interval")
+	queueRunCmd.Flags().Duration("interval", time.Minute, "How often to check the queue with --daemon")
+	queueEditCmd.Flags().String("at", "", "Reschedule the message instead of editing it (e.g. tomorrow 9am, 2h)")
+	queueEditCmd.Flags().Bool("no-checks", false, "Skip the pre-send checks on the edited message")
+
+	// Set custom output to enable testing
+	queueCmd.SetOut(os.Stdout)
+}
@@ -0,0 +1,121 @@
+/*
+Copyright © 2025 longkey1
+
+Licensed under the Apache License, Version 2.0 (the "License");
+you may not use this file except in compliance with the License.
+You may obtain a copy of the License at
+
+	http://www.apache.org/licenses/LICENSE-2.0
+
+Unless required by applicable law or agreed to in writing, software
+distributed under the License is distributed on an "AS IS" BASIS,
+WITHOUT WARRANTIES OR CONDITIONS OF ANY KIND, either express or implied.
+See the License for the specific language governing permissions and
+limitations under the License.
+*/
+package cmd
+
+import (
+	"context"
+	"fmt"
+	"log/slog"
+	"os"
+	"os/signal"
+	"path/filepath"
+	"syscall"
+	"time"
+
+	"github.com/fsnotify/fsnotify"
+	"github.com/longkey1/gml/internal/gml"
+	"github.com/spf13/cobra"
+)
+
+// reloadDebounce coalesces the bursts of events editors produce when saving
+const reloadDebounce = 250 * time.Millisecond
+
+// watchConfigChanges calls apply with the re-read configuration of the given
+// account whenever the config file changes or the process receives SIGHUP,
+// until ctx is done. Failed reloads are reported and keep the previous settings
+func watchConfigChanges(ctx context.Context, cmd *cobra.Command, account string, apply func(*gml.Config) error) {
+	hup := make(chan os.Signal, 1)
+	signal.Notify(hup, syscall.SIGHUP)
+
+	// Watch the directory rather than the file so atomic saves (write to a
+	// temp file and rename) are still noticed
+	var events <-chan fsnotify.Event
+	var errs <-chan error
+	var watcher *fsnotify.Watcher
+	path := currentInvocation(cmd).configFile
+	if path != "" {
+		w, err := fsnotify.NewWatcher()
+		if err == nil {
+			err = w.Add(filepath.Dir(path))
+		}
+		if err != nil {
+			slog.Warn("unable to watch config file, reload with SIGHUP", "err", err)
+			if w != nil {
+				w.Close()
+			}
+		} else {
+			watcher = w
+			events = w.Events
+			errs = w.Errors
+		}
+	}
+
+	go func() {
+		defer signal.Stop(hup)
+		if watcher != nil {
+			defer watcher.Close()
+		}
+
+		clean := filepath.Clean(path)
+		debounce := time.NewTimer(reloadDebounce)
+		debounce.Stop()
+
+		for {
+			select {
+			case <-ctx.Done():
+				return
+			case <-hup:
+				reloadConfig(cmd, path, account, apply)
+			case ev := <-events:
+				if filepath.Clean(ev.Name) == clean && ev.Has(fsnotify.Write|fsnotify.Create|fsnotify.Rename) {
+					debounce.Reset(reloadDebounce)
+				}
+			case err := <-errs:
+				slog.Warn("config watcher", "err", err)
+			case <-debounce.C:
+				reloadConfig(cmd, path, account, apply)
+			}
+		}
+	}()
+}
+
+// reloadConfig re-reads the config file and applies the account's configuration
+func reloadConfig(cmd *cobra.Command, path, account string, apply func(*gml.Config) error) {
+	cfg, err := readAccountConfig(path, account)
+	if err == nil {
+		err = apply(cfg)
+	}
+	if err != nil {
+		slog.Warn("config reload failed, keeping previous settings", "err", err)
+		return
+	}
+	slog.Info("config reloaded")
+}
+
+// readAccountConfig reads the config file again and selects the given account
+func readAccountConfig(path, account string) (*gml.Config, error) {
+	cfg, _, err := gml.ReadConfig(path)
+	if err != nil {
+		return nil, err
+	}
+	if cfg == nil {
+		return nil, fmt.Errorf("config file not found: %s", path)
+	}
+	if account == "" {
+		return cfg, nil
+	}
+	return cfg.ForAccount(account)
+}
@@ -0,0 +1,196 @@
+/*
+Copyright © 2025 longkey1
+
+Licensed under the Apache License, Version 2.0 (the "License");
+you may not use this file except in compliance with the License.
+You may obtain a copy of the License at
+
+	http://www.apache.org/licenses/LICENSE-2.0
+
+Unless required by applicable law or agreed to in writing, software
+distributed under the License is distributed on an "AS IS" BASIS,
+WITHOUT WARRANTIES OR CONDITIONS OF ANY KIND, either express or implied.
+See the License for the specific language governing permissions and
+limitations under the License.
+*/
+package cmd
+
+import (
+	"errors"
+	"fmt"
+	"os"
+	"strings"
+
+	"github.com/longkey1/gml/internal/gml"
+	"github.com/spf13/cobra"
+)
+
+// replyCmd represents the reply command
+var replyCmd = &cobra.Command{
+	Use:   "reply <message-id>",
+	Short: "Reply to a message in its thread",
+	Long: `Reply to a message, keeping it in the same thread. The reply goes to the
+sender (or Reply-To); with --all, the other recipients are copied as well.
+
+Without --body or --body-file, the reply is written in $VISUAL or $EDITOR
+with the original message quoted below. Saving an empty file cancels.
+
+With --edit, the editor also shows the To/Cc/Bcc/Subject header block, and
+after saving, a summary is shown and sending must be confirmed, like with
+gml send --edit.
+
+With --suggest-cmd, the thread up to the message (From, To, Date, Subject and
+body of each message, oldest first) is piped to a shell command, and what it
+prints pre-fills the editor, e.g. a draft from a language model tool. The
+replied message is also available as $GML_ID, $GML_FROM, $GML_SUBJECT, ...
+Nothing is sent until the editor is saved and closed.
+
+--attach and --inline add files like with gml send. The same checks as gml
+send run before sending; use --no-checks to skip them.
+
+Examples:
+  gml reply 18abc123def456                      # Write the reply in $EDITOR
+  gml reply 18abc123def456 --all --body "Thanks, works for me."
+  gml reply 18abc123def456 --all --edit         # Also edit recipients and subject, then confirm
+  gml reply 18abc123def456 --body "Signed copy attached." --attach signed.pdf
+  gml reply 18abc123def456 --suggest-cmd 'llm -s "Draft a short, friendly reply"'
+  gml reply 18abc123def456 --suggest-cmd './draft-reply.sh' --no-quote`,
+	Args: cobra.ExactArgs(1),
+	RunE: runReply,
+}
+
+func runReply(cmd *cobra.Command, args []string) error {
+	messageID := args[0]
+	ctx := cmd.Context()
+	cfg, err := GetConfig(cmd)
+	if err != nil {
+		return err
+	}
+
+	// Get flags
+	all, _ := cmd.Flags().GetBool("all")
+	body, _ := cmd.Flags().GetString("body")
+	bodyFile, _ := cmd.Flags().GetString("body-file")
+	suggestCmd, _ := cmd.Flags().GetString("suggest-cmd")
+	noQuote, _ := cmd.Flags().GetBool("no-quote")
+	noChecks, _ := cmd.Flags().GetBool("no-checks")
+	edit, _ := cmd.Flags().GetBool("edit")
+
+	if (body != "" || bodyFile != "") && suggestCmd != "" {
+		return fmt.Errorf("--suggest-cmd cannot be combined with --body or --body-file")
+	}
+	if bodyFile != "" {
+		data, err := readInput(cmd, bodyFile)
+		if err != nil {
+			return err
+		}
+		body = string(data)
+	}
+	attachments, err := attachmentsFromFlags(cmd)
+	if err != nil {
+		return err
+	}
+
+	// Create service
+	svc, err := gml.NewService(ctx, cfg)
+	if err != nil {
+		return fmt.Errorf("unable to create service: %w", err)
+	}
+
+	rc, err := gml.PrepareReply(ctx, svc, messageID, all)
+	if err != nil {
+		return err
+	}
+	reply := rc.Reply
+
+	if body == "" {
+		draft := ""
+		if suggestCmd != "" {
+			if draft, err = gml.SuggestReply(ctx, suggestCmd, rc, cmd.ErrOrStderr()); err != nil {
+				return err
+			}
+		}
+		if !noQuote {
+			draft += "\n" + gml.QuoteReply(rc.Message())
+		}
+		if edit {
+			// editOutgoing opens the editor below
+			body = draft
+		} else {
+			if body, err = editText(cmd, "gml-reply-*.txt", draft); err != nil {
+				return err
+			}
+			if strings.TrimSpace(stripQuote(body)) == "" {
+				return errors.New("reply is empty; not sent")
+			}
+		}
+	}
+	reply.Body = body
+	reply.Attachments = attachments
+
+	if edit {
+		if reply, err = editOutgoing(cmd, reply, noChecks, "Send this reply?"); err != nil {
+			return err
+		}
+		if reply == nil {
+			fmt.Fprintln(cmd.OutOrStdout(), "Cancelled.")
+			return nil
+		}
+		// The warnings were shown before confirming
+		noChecks = true
+	}
+
+	if err := checkOutgoing(cmd, reply, noChecks); err != nil {
+		return err
+	}
+
+	sent, err := gml.SendMessage(ctx, svc, reply)
+	if err != nil {
+		return err
+	}
+	if gml.IsDryRun(ctx) {
+		// Only the request was reported; there is no sent message to show
+		return nil
+	}
+
+	// Output
+	if formatFromFlags(cmd) == gml.OutputFormatJSON {
+		return gml.FormatJSON(cmd.OutOrStdout(), map[string]string{"id": sent.Id, "threadId": sent.ThreadId})
+	}
+	fmt.Fprintf(cmd.OutOrStdout(), "Sent reply %s to %s\n", sent.Id, strings.Join(append(reply.To, reply.Cc...), ", "))
+	return nil
+}
+
+// stripQuote drops quoted lines and the attribution line above them, leaving
+// what the user wrote
+func stripQuote(body string) string {
+	var kept []string
+	for _, line := range strings.Split(body, "\n") {
+		if strings.HasPrefix(line, ">") {
+			continue
+		}
+		kept = append(kept, line)
+	}
+	text := strings.TrimSpace(strings.Join(kept, "\n"))
+	if strings.HasPrefix(text, "On ") && strings.HasSuffix(text, " wrote:") && !strings.Contains(text, "\n") {
+		return ""
+	}
+	return text
+}
+
+func init() {
+	rootCmd.AddCommand(replyCmd)
+
+	replyCmd.Flags().BoolP("all", "a", false, "Reply to all recipients")
+	replyCmd.Flags().String("body", "", "Reply body (skips the editor)")
+	replyCmd.Flags().String("body-file", "", "Read the reply body from a file (- for stdin; skips the editor)")
+	replyCmd.Flags().String("suggest-cmd", "", "Shell command that reads the thread on stdin and prints a suggested reply for the editor")
+	replyCmd.Flags().Bool("no-quote", false, "Don't quote the original message below the reply")
+	replyCmd.Flags().Bool("edit", false, "Also edit recipients and subject in $EDITOR, then review and confirm the reply")
+	addAttachFlags(replyCmd)
+	replyCmd.Flags().Bool("no-checks", false, "Skip the attachment, recipient domain and recipient count checks")
+	setFormats(replyCmd, gml.OutputFormatText, gml.OutputFormatJSON)
+
+	// Set custom output to enable testing
+	replyCmd.SetOut(os.Stdout)
+}
@@ -0,0 +1,203 @@
+/*
+Copyright © 2025 longkey1
+
+Licensed under the Apache License, Version 2.0 (the "License");
+you may not use this file except in compliance with the License.
+You may obtain a copy of the License at
+
+	http://www.apache.org/licenses/LICENSE-2.0
+
+Unless required by applicable law or agreed to in writing, software
+distributed under the License is distributed on an "AS IS" BASIS,
+WITHOUT WARRANTIES OR CONDITIONS OF ANY KIND, either express or implied.
+See the License for the specific language governing permissions and
+limitations under the License.
+*/
+package cmd
+
+import (
+	"bytes"
+	"fmt"
+	"os"
+	"sort"
+	"strings"
+
+	"github.com/longkey1/gml/internal/gml"
+	"github.com/spf13/cobra"
+)
+
+// reportCmd represents the report command
+var reportCmd = &cobra.Command{
+	Use:   "report",
+	Short: "Run mailbox reports defined in config",
+	Long: `Run mailbox reports defined under [reports.<name>] in the config file.
+
+Example config:
+  [reports.newsletters]
+  query = "category:promotions newer_than:7d"
+  group_by = "sender"        # sender, domain, label, day, month
+  limit = 20
+  format = "text"            # text, json, csv or tsv
+  delimiter = ";"            # optional CSV delimiter; crlf and bom are also supported
+  output = "/var/reports/newsletters-{date}.txt"
+  email = ["me@example.com"] # requires the "send" scope
+  subject = "Weekly newsletter volume"`,
+}
+
+// reportListCmd represents the report list command
+var reportListCmd = &cobra.Command{
+	Use:   "list",
+	Short: "List configured reports",
+	Args:  cobra.NoArgs,
+	RunE:  runReportList,
+}
+
+// reportRunCmd represents the report run command
+var reportRunCmd = &cobra.Command{
+	Use:   "run <name>",
+	Short: "Run a configured report",
+	Long: `Run a configured report, writing it to its output path (or stdout)
+and optionally emailing it. Designed to be run from cron.
+
+Examples:
+  gml report run newsletters
+  gml report run newsletters --format json   # Override the configured format`,
+	Args: cobra.ExactArgs(1),
+	RunE: runReportRun,
+}
+
+func runReportList(cmd *cobra.Command, args []string) error {
+	cfg, err := GetConfig(cmd)
+	if err != nil {
+		return err
+	}
+
+	names := make([]string, 0, len(cfg.Reports))
+	for name := range cfg.Reports {
+		names = append(names, name)
+	}
+	sort.Strings(names)
+
+	if formatFromFlags(cmd) == gml.OutputFormatJSON {
+		type reportEntry struct {
+			Name  string `json:"name"`
+			Query string `json:"query"`
+		}
+		entries := make([]reportEntry, len(names))
+		for i, name := range names {
+			entries[i] = reportEntry{Name: name, Query: cfg.Reports[name].Query}
+		}
+		return gml.FormatJSON(cmd.OutOrStdout(), entries)
+	}
+	if len(names) == 0 {
+		fmt.Fprintln(cmd.OutOrStdout(), "No reports configured.")
+		return nil
+	}
+	for _, name := range names {
+		fmt.Fprintf(cmd.OutOrStdout(), "%s\t%s\n", name, cfg.Reports[name].Query)
+	}
+	return nil
+}
+
+func runReportRun(cmd *cobra.Command, args []string) error {
+	ctx := cmd.Context()
+	cfg, err := GetConfig(cmd)
+	if err != nil {
+		return err
+	}
+	name := strings.ToLower(args[0])
+
+	rc, ok := cfg.Reports[name]
+	if !ok {
+		return fmt.Errorf("report not found: %s", args[0])
+	}
+
+	// Flags override the report definition
+	if formatChanged(cmd) {
+		rc.Format = formatFromFlags(cmd)
+	}
+	if cmd.Flags().Changed("output") {
+		rc.Output, _ = cmd.Flags().GetString("output")
+	}
+	csvOpts, err := rc.CSVOptions()
+	if err != nil {
+		return fmt.Errorf("invalid report delimiter: %w", err)
+	}
+	if csvOpts, err = csvOptionsFromFlags(cmd, csvOpts); err != nil {
+		return err
+	}
+	noEmail, _ := cmd.Flags().GetBool("no-email")
+	if noEmail {
+		rc.Email = nil
+	}
+
+	// Create service
+	svc, err := gml.NewService(ctx, cfg)
+	if err != nil {
+		return fmt.Errorf("unable to create service: %w", err)
+	}
+
+	// Run report
+	report, err := gml.RunReport(ctx, svc, name, rc)
+	if err != nil {
+		return fmt.Errorf("unable to run report: %w", err)
+	}
+
+	var buf bytes.Buffer
+	if err := gml.FormatReportCSV(&buf, report, rc.Format, csvOpts); err != nil {
+		return fmt.Errorf("unable to format output: %w", err)
+	}
+
+	// Output
+	if rc.Output != "" {
+		path := gml.ReportOutputPath(rc.Output, report.GeneratedAt)
+		out, err := gml.CreateOutput(ctx, path)
+		if err != nil {
+			return err
+		}
+		defer out.Abort()
+		if _, err := out.Write(buf.Bytes()); err != nil {
+			return fmt.Errorf("unable to write report: %w", err)
+		}
+		if err := out.Close(); err != nil {
+			return err
+		}
+		fmt.Fprintf(cmd.ErrOrStderr(), "Wrote report to %s\n", path)
+	} else {
+		cmd.OutOrStdout().Write(buf.Bytes())
+	}
+
+	// Deliver by email
+	if len(rc.Email) > 0 {
+		subject := rc.Subject
+		if subject == "" {
+			subject = fmt.Sprintf("gml report: %s", name)
+		}
+		if _, err := gml.SendMessage(ctx, svc, &gml.OutgoingMessage{
+			To:      rc.Email,
+			Subject: subject,
+			Body:    buf.String(),
+		}); err != nil {
+			return fmt.Errorf("unable to email report: %w", err)
+		}
+		fmt.Fprintf(cmd.ErrOrStderr(), "Emailed report to %s\n", strings.Join(rc.Email, ", "))
+	}
+
+	return nil
+}
+
+func init() {
+	rootCmd.AddCommand(reportCmd)
+	reportCmd.AddCommand(reportListCmd)
+	reportCmd.AddCommand(reportRunCmd)
+
+	setFormats(reportListCmd, gml.OutputFormatText, gml.OutputFormatJSON)
+	// The default comes from the report definition
+	setFormats(reportRunCmd, gml.OutputFormatText, gml.OutputFormatJSON, gml.OutputFormatCSV, gml.OutputFormatTSV)
+	addCSVFlags(reportRunCmd)
+	reportRunCmd.Flags().StringP("output", "o", "", "Output path or s3:// / gs:// URL ({date} is expanded), overrides config")
+	reportRunCmd.Flags().Bool("no-email", false, "Skip email delivery")
+
+	// Set custom output to enable testing
+	reportCmd.SetOut(os.Stdout)
+}
@@ -16,19 +16,47 @@ limitations under the License.
 package cmd
 
 import (
+	"context"
+	"errors"
 	"fmt"
+	"log/slog"
 	"os"
 	"path/filepath"
 
 	"github.com/longkey1/gml/internal/gml"
+	"github.com/longkey1/gml/internal/telemetry"
+	"github.com/longkey1/gml/internal/version"
 	"github.com/spf13/cobra"
-	"github.com/spf13/viper"
+	"go.opentelemetry.io/otel/attribute"
+	"go.opentelemetry.io/otel/trace"
 )
 
-var (
-	cfgFile string
-	config  *gml.Config
-)
+// invocation holds the state resolved for one command execution: the global
+// flag values and the configuration they select. It travels in the command's
+// context instead of package-level variables, so commands can be executed
+// repeatedly (tests, embedding) without carrying state over. The logger set
+// up by setupLogging is the process-wide slog default, so executions must not
+// overlap
+type invocation struct {
+	// configFile is the config file that was read (empty if none was found)
+	configFile string
+	// account is the --account flag value
+	account string
+	// config is nil when no config file was found
+	config *gml.Config
+	// configDefaults maps the flags set from the command's config section to
+	// that section
+	configDefaults map[string]string
+	// span covers the command execution when tracing is enabled
+	span trace.Span
+}
+
+// invocationKey is the context key for the current invocation
+type invocationKey struct{}
+
+// configOptionalAnnotation marks commands that run even when the config file
+// can't be read, so a broken config can be inspected and fixed
+const configOptionalAnnotation = "gml_config_optional"
 
 // rootCmd represents the base command when called without any subcommands
 var rootCmd = &cobra.Command{
@@ -43,55 +71,297 @@ var rootCmd = &cobra.Command{
 // Execute adds all child commands to the root command and sets flags appropriately.
 // This is called by main.main(). It only needs to happen once to the rootCmd.
 func Execute() {
-	if err := rootCmd.Execute(); err != nil {
+	ctx, shutdown, err := telemetry.Setup(context.Background(), version.Short())
+	if err != nil {
+		fmt.Fprintf(os.Stderr, "Warning: tracing disabled: %v\n", err)
+	}
+
+	cmd, err := rootCmd.ExecuteContextC(ctx)
+	if span := currentInvocation(cmd).span; span != nil {
+		telemetry.End(span, err)
+	}
+	_ = shutdown(ctx)
+
+	if cfg := currentInvocation(cmd).config; cfg != nil && cfg.UsageStats {
+		if err := gml.RecordUsage(cmd.CommandPath()); err != nil {
+			slog.Warn("unable to record usage", "err", err)
+		}
+	}
+
+	if err != nil {
+		var exitErr *ExitError
+		if errors.As(err, &exitErr) {
+			if exitErr.Err != nil {
+				fmt.Fprintf(os.Stderr, "Error: %v\n", exitErr.Err)
+			}
+			os.Exit(exitErr.Code)
+		}
 		fmt.Fprintf(os.Stderr, "Error: %v\n", err)
-		os.Exit(1)
+		code, hint := exitStatus(err)
+		if hint != "" {
+			fmt.Fprintln(os.Stderr, hint)
+		}
+		os.Exit(code)
 	}
 }
 
-func init() {
-	cobra.OnInitialize(initConfig)
+// Exit statuses for the error kinds of the gml package
+const (
+	exitCodeNotFound      = 4
+	exitCodeAuthExpired   = 5
+	exitCodeScopeMissing  = 6
+	exitCodeQuotaExceeded = 7
+)
 
-	rootCmd.PersistentFlags().StringVar(&cfgFile, "config", "", "config file (default is $HOME/.config/gml/config.toml)")
+// exitStatus maps an error to the exit status for its kind, with a hint on
+// how to recover if there is one
+func exitStatus(err error) (int, string) {
+	switch gml.ErrorKind(err) {
+	case gml.ErrNotFound:
+		return exitCodeNotFound, ""
+	case gml.ErrAuthExpired:
+		return exitCodeAuthExpired, "Hint: the token has expired or was revoked; run 'gml auth' to sign in again"
+	case gml.ErrScopeMissing:
+		return exitCodeScopeMissing, "Hint: add the required scope to 'scopes' in the config file and run 'gml auth' again"
+	case gml.ErrQuotaExceeded:
+		return exitCodeQuotaExceeded, "Hint: Gmail API quota exceeded; try again in a minute"
+	}
+	return 1, ""
 }
 
-// initConfig reads in config file and ENV variables if set.
-func initConfig() {
-	if cfgFile != "" {
-		viper.SetConfigFile(cfgFile)
-	} else {
-		home, err := os.UserHomeDir()
-		cobra.CheckErr(err)
+// ExitError is returned by commands that need to exit with a specific status code
+// Err may be nil when the command has already reported the condition itself
+type ExitError struct {
+	Code int
+	Err  error
+}
 
-		viper.AddConfigPath(filepath.Join(home, ".config/gml"))
-		viper.SetConfigName("config")
-		viper.SetConfigType("toml")
+func (e *ExitError) Error() string {
+	if e.Err == nil {
+		return fmt.Sprintf("exit status %d", e.Code)
 	}
+	return e.Err.Error()
+}
+
+func (e *ExitError) Unwrap() error {
+	return e.Err
+}
+
+func init() {
+	rootCmd.PersistentPreRunE = loadInvocation
+
+	rootCmd.PersistentFlags().String("config", "", "config file (default is $XDG_CONFIG_HOME/gml/config.toml or ~/.config/gml/config.toml)")
+	rootCmd.PersistentFlags().String("account", "", "account profile to use (overrides GML_ACCOUNT)")
+	rootCmd.PersistentFlags().String("format", "", "Output format (supported values depend on the command)")
+	rootCmd.PersistentFlags().Bool("json", false, "Shorthand for --format json")
+	rootCmd.PersistentFlags().Bool("dry-run", false, "Print API requests that would change the mailbox as JSON lines instead of sending them")
+	rootCmd.PersistentFlags().Bool("quiet", false, "Only log errors")
+	rootCmd.PersistentFlags().Bool("verbose", false, "Log progress and informational messages on stderr")
+	rootCmd.PersistentFlags().Bool("debug", false, "Log every API call (method, URL, latency, quota cost) on stderr")
+
+	// Show the formats of the command being described in --format's help
+	defaultHelp := rootCmd.HelpFunc()
+	rootCmd.SetHelpFunc(func(c *cobra.Command, args []string) {
+		rootCmd.PersistentFlags().Lookup("format").Usage = formatUsage(c)
+		defaultHelp(c, args)
+	})
+	_ = rootCmd.RegisterFlagCompletionFunc("format", func(c *cobra.Command, args []string, toComplete string) ([]string, cobra.ShellCompDirective) {
+		return supportedFormats(c), cobra.ShellCompDirectiveNoFileComp
+	})
+}
 
-	viper.AutomaticEnv()
+// loadInvocation reads the global flags and the config file and stores them
+// in the command's context
+func loadInvocation(cmd *cobra.Command, args []string) error {
+	configFile, _ := cmd.Flags().GetString("config")
 
 	// Config file is optional for some commands (e.g., version)
-	if err := viper.ReadInConfig(); err != nil {
-		if _, ok := err.(viper.ConfigFileNotFoundError); !ok {
-			// Only fail if it's not a "file not found" error
-			cobra.CheckErr(fmt.Errorf("unable to read config file: %w", err))
+	cfg, used, err := gml.ReadConfig(configFile)
+	if _, optional := cmd.Annotations[configOptionalAnnotation]; err != nil && !optional {
+		return err
+	}
+
+	// Defaults from the command's config section apply before flags are read
+	var defaults map[string]string
+	if _, optional := cmd.Annotations[configOptionalAnnotation]; !optional {
+		if defaults, err = applyConfigDefaults(cmd, cfg); err != nil {
+			return err
 		}
-		return
+	}
+	account, _ := cmd.Flags().GetString("account")
+
+	if err := checkFormatFlags(cmd); err != nil {
+		return err
+	}
+	if err := setupLogging(cmd); err != nil {
+		return err
 	}
 
-	var err error
-	config, err = gml.LoadConfig()
+	ctx := cmd.Context()
+	if ctx == nil {
+		ctx = context.Background()
+	}
+	if dryRun, _ := cmd.Flags().GetBool("dry-run"); dryRun {
+		ctx = gml.WithDryRun(ctx, cmd.OutOrStdout())
+	}
+	ctx, span := telemetry.Start(ctx, cmd.CommandPath(), attribute.String("gml.account", account))
+	cmd.SetContext(context.WithValue(ctx, invocationKey{}, &invocation{
+		configFile:     used,
+		account:        account,
+		config:         cfg,
+		configDefaults: defaults,
+		span:           span,
+	}))
+	return nil
+}
+
+// setupLogging installs the stderr logger at the level chosen by --quiet,
+// --verbose and --debug as the slog default, which internal/gml logs to
+func setupLogging(cmd *cobra.Command) error {
+	quiet, _ := cmd.Flags().GetBool("quiet")
+	verbose, _ := cmd.Flags().GetBool("verbose")
+	debug, _ := cmd.Flags().GetBool("debug")
+	if quiet && (verbose || debug) {
+		return fmt.Errorf("--quiet cannot be combined with --verbose or --debug")
+	}
+
+	slog.SetDefault(slog.New(gml.NewLogHandler(cmd.ErrOrStderr(), gml.LogLevel(quiet, verbose, debug))))
+	return nil
+}
+
+// currentInvocation returns the invocation state of a running command
+func currentInvocation(cmd *cobra.Command) *invocation {
+	if inv, ok := cmd.Context().Value(invocationKey{}).(*invocation); ok {
+		return inv
+	}
+	return &invocation{}
+}
+
+// allAccounts is the --account value that fans a read command out to every account
+const allAccounts = gml.AllAccounts
+
+// GetConfig returns the loaded configuration for the selected account, or
+// the built-in defaults if no config file was found
+func GetConfig(cmd *cobra.Command) (*gml.Config, error) {
+	cfg := getBaseConfig(cmd)
+
+	name, err := selectedAccount(cmd, cfg)
 	if err != nil {
-		cobra.CheckErr(fmt.Errorf("unable to load config: %w", err))
+		return nil, err
 	}
+	if name == "" {
+		return cfg, nil
+	}
+	if name == allAccounts {
+		return nil, fmt.Errorf("--account %s is not supported by this command", allAccounts)
+	}
+	return cfg.ForAccount(name)
 }
 
-// GetConfig returns the loaded configuration
-// This function will panic if called before config is loaded, but that's intentional
-// as commands requiring config should only run after initConfig
-func GetConfig() *gml.Config {
-	if config == nil {
-		cobra.CheckErr(fmt.Errorf("config file not found. Please create a config file at $HOME/.config/gml/config.toml"))
+// GetAccountConfigs returns the configuration of every account when --account all
+// is selected, or the single selected configuration otherwise
+func GetAccountConfigs(cmd *cobra.Command) ([]*gml.Config, error) {
+	all, err := allAccountsSelected(cmd)
+	if err != nil {
+		return nil, err
+	}
+	if !all {
+		cfg, err := GetConfig(cmd)
+		if err != nil {
+			return nil, err
+		}
+		return []*gml.Config{cfg}, nil
+	}
+
+	cfg := getBaseConfig(cmd)
+	names := cfg.AccountNames()
+	if len(names) == 0 {
+		return nil, fmt.Errorf("no accounts configured")
+	}
+
+	var configs []*gml.Config
+	for _, name := range names {
+		acct, err := cfg.ForAccount(name)
+		if err != nil {
+			return nil, err
+		}
+		configs = append(configs, acct)
+	}
+	return configs, nil
+}
+
+// allAccountsSelected reports whether commands should fan out to every account
+func allAccountsSelected(cmd *cobra.Command) (bool, error) {
+	name, err := selectedAccount(cmd, getBaseConfig(cmd))
+	if err != nil {
+		return false, err
+	}
+	return name == allAccounts, nil
+}
+
+// reportAccountErrors prints per-account failures to stderr. It returns err
+// when every account failed; otherwise, if any failed, partial is an error
+// with exitCodePartialFailure for the command to return after showing the
+// results of the other accounts, so partial data never exits with status 0
+func reportAccountErrors(cmd *cobra.Command, errs []error, total int) (partial, err error) {
+	if len(errs) == total && total > 0 {
+		return nil, errors.Join(errs...)
+	}
+	if len(errs) == 0 {
+		return nil, nil
+	}
+	for _, err := range errs {
+		slog.Error(err.Error())
+	}
+	return &ExitError{Code: exitCodePartialFailure, Err: fmt.Errorf("%d of %d accounts failed", len(errs), total)}, nil
+}
+
+// getBaseConfig returns the loaded configuration without applying account selection
+func getBaseConfig(cmd *cobra.Command) *gml.Config {
+	cfg := currentInvocation(cmd).config
+	if cfg == nil {
+		// Without a config file, use the built-in OAuth client (read-only)
+		return gml.DefaultConfig()
+	}
+	return cfg
+}
+
+// selectedAccount returns the account name chosen by, in order of precedence,
+// the --account flag, the GML_ACCOUNT env var, 'gml account switch', and default_account
+func selectedAccount(cmd *cobra.Command, cfg *gml.Config) (string, error) {
+	if account := currentInvocation(cmd).account; account != "" {
+		return account, nil
+	}
+	if env := os.Getenv("GML_ACCOUNT"); env != "" {
+		return env, nil
+	}
+	path, err := currentAccountPath(cmd)
+	if err != nil {
+		return "", err
+	}
+	current, err := gml.ReadCurrentAccount(path)
+	if err != nil {
+		return "", err
+	}
+	if current != "" {
+		return current, nil
+	}
+	return cfg.DefaultAccount, nil
+}
+
+// currentAccountPath returns the file storing the account chosen by 'gml account switch'
+func currentAccountPath(cmd *cobra.Command) (string, error) {
+	return configSiblingPath(cmd, "current_account")
+}
+
+// configSiblingPath returns the path of a state file kept next to the config file
+func configSiblingPath(cmd *cobra.Command, name string) (string, error) {
+	if used := currentInvocation(cmd).configFile; used != "" {
+		return filepath.Join(filepath.Dir(used), name), nil
+	}
+	dir, err := gml.ConfigDir()
+	if err != nil {
+		return "", err
 	}
-	return config
+	return filepath.Join(dir, name), nil
 }
@@ -0,0 +1,157 @@
+/*
+Copyright © 2025 longkey1
+
+Licensed under the Apache License, Version 2.0 (the "License");
+you may not use this file except in compliance with the License.
+You may obtain a copy of the License at
+
+	http://www.apache.org/licenses/LICENSE-2.0
+
+Unless required by applicable law or agreed to in writing, software
+distributed under the License is distributed on an "AS IS" BASIS,
+WITHOUT WARRANTIES OR CONDITIONS OF ANY KIND, either express or implied.
+See the License for the specific language governing permissions and
+limitations under the License.
+*/
+package cmd
+
+import (
+	"fmt"
+	"os"
+	"sort"
+	"strings"
+
+	"github.com/longkey1/gml/internal/gml"
+	"github.com/spf13/cobra"
+)
+
+// runCmd represents the run command
+var runCmd = &cobra.Command{
+	Use:   "run [pipeline]",
+	Short: "Run a pipeline defined in config",
+	Long: `Run a pipeline defined under [pipelines.<name>] in the config file.
+Without arguments, the configured pipelines are listed.
+
+A pipeline is a list of steps that pass a set of messages along:
+  search   query, labels, limit          Replace the set with matching messages
+  filter   field, contains, matches,     Keep messages whose field (from, to,
+           exclude                       subject, snippet, labels) matches
+  action   add_labels, remove_labels     Change labels (missing labels are created)
+  notify   exec, email, subject, body    Run a command per message, or email a summary
+  print                                  Write messages as JSON lines
+
+String values are Go templates with .Params, .Messages, .Count and .Now
+(and .Message in notify exec). Params default to the pipeline's params table
+and can be overridden with --param.
+
+Example config:
+  [pipelines.invoices]
+  description = "Label and archive invoices"
+  params = { sender = "billing@example.com" }
+
+  [[pipelines.invoices.steps]]
+  type = "search"
+  query = "from:{{.Params.sender}} in:inbox"
+
+  [[pipelines.invoices.steps]]
+  type = "filter"
+  field = "subject"
+  contains = "invoice"
+
+  [[pipelines.invoices.steps]]
+  type = "action"
+  add_labels = ["Invoices"]
+  remove_labels = ["INBOX"]
+
+  [[pipelines.invoices.steps]]
+  type = "notify"
+  email = ["me@example.com"]
+  subject = "{{.Count}} new invoices"
+
+Action and notify steps require the modify and send scopes. With --dry-run,
+only search and filter steps run, and what the actions would do is reported.
+
+Examples:
+  gml run
+  gml run invoices
+  gml run invoices --param sender=accounts@example.com --dry-run`,
+	Args: cobra.MaximumNArgs(1),
+	RunE: runRun,
+}
+
+func runRun(cmd *cobra.Command, args []string) error {
+	ctx := cmd.Context()
+	cfg, err := GetConfig(cmd)
+	if err != nil {
+		return err
+	}
+
+	// Get flags
+	paramArgs, _ := cmd.Flags().GetStringArray("param")
+	dryRun := gml.IsDryRun(ctx)
+
+	if len(args) == 0 {
+		return listPipelines(cmd, cfg)
+	}
+
+	name := strings.ToLower(args[0])
+	pc, ok := cfg.Pipelines[name]
+	if !ok {
+		return fmt.Errorf("pipeline not found: %s", args[0])
+	}
+
+	params := make(map[string]string)
+	for _, p := range paramArgs {
+		key, value, ok := strings.Cut(p, "=")
+		if !ok || key == "" {
+			return fmt.Errorf("invalid --param %q (expected key=value)", p)
+		}
+		params[key] = value
+	}
+
+	svc, err := gml.NewService(ctx, cfg)
+	if err != nil {
+		return fmt.Errorf("unable to create service: %w", err)
+	}
+
+	failures := failureLogFromFlags(cmd)
+	if _, err := gml.RunPipeline(ctx, svc, pc, gml.PipelineOptions{
+		Params:   params,
+		DryRun:   dryRun,
+		Failures: failures,
+		Stdout:   cmd.OutOrStdout(),
+		Stderr:   cmd.ErrOrStderr(),
+	}); err != nil {
+		return fmt.Errorf("pipeline %s failed: %w", name, err)
+	}
+
+	return reportFailures(cmd, failures)
+}
+
+// listPipelines prints the configured pipelines with their descriptions
+func listPipelines(cmd *cobra.Command, cfg *gml.Config) error {
+	names := make([]string, 0, len(cfg.Pipelines))
+	for name := range cfg.Pipelines {
+		names = append(names, name)
+	}
+	sort.Strings(names)
+
+	if len(names) == 0 {
+		fmt.Fprintln(cmd.OutOrStdout(), "No pipelines configured.")
+		return nil
+	}
+	for _, name := range names {
+		fmt.Fprintf(cmd.OutOrStdout(), "%s\t%s\n", name, cfg.Pipelines[name].Description)
+	}
+	return nil
+}
+
+func init() {
+	rootCmd.AddCommand(runCmd)
+
+	runCmd.Flags().StringArrayP("param", "p", nil, "Pipeline parameter as key=value (can be specified multiple times)")
+	addIgnoreErrorsFlag(runCmd)
+
+	// Set custom output to enable testing
+	runCmd.SetOut(os.Stdout)
+}
@@ -0,0 +1,230 @@
+/*
+Copyright © 2025 longkey1
+
+Licensed under the Apache License, Version 2.0 (the "License");
+you may not use this file except in compliance with the License.
+You may obtain a copy of the License at
+
+	http://www.apache.org/licenses/LICENSE-2.0
+
+Unless required by applicable law or agreed to in writing, software
+distributed under the License is distributed on an "AS IS" BASIS,
+WITHOUT WARRANTIES OR CONDITIONS OF ANY KIND, either express or implied.
+See the License for the specific language governing permissions and
+limitations under the License.
+*/
+package cmd
+
+import (
+	"fmt"
+	"os"
+	"strings"
+
+	"github.com/longkey1/gml/internal/gml"
+	"github.com/spf13/cobra"
+)
+
+// searchCmd represents the search command
+var searchCmd = &cobra.Command{
+	Use:   "search",
+	Short: "Manage saved searches",
+	Long: `Manage named searches for 'gml list --saved <name>'.
+
+Searches are defined in the config file as [searches.<name>] sections, or
+saved with 'gml search save', which keeps them in searches.toml next to the
+config file. Searches from the config file can only be changed there.
+
+Examples:
+  gml search save receipts -q "from:amazon OR from:paypal" -l INBOX
+  gml search list
+  gml list --saved receipts
+  gml list --saved receipts --newer-than 7d  # Extra terms narrow the search
+  gml search delete receipts`,
+}
+
+// searchSaveCmd represents the search save command
+var searchSaveCmd = &cobra.Command{
+	Use:   "save <name>",
+	Short: "Save a named search",
+	Long: `Save a query and labels under a name for 'gml list --saved <name>'.
+
+Examples:
+  gml search save receipts -q "from:amazon OR from:paypal"
+  gml search save work-unread -q is:unread -l Work
+  gml search save receipts -q "from:amazon" --force  # Replace an existing search`,
+	Args: cobra.ExactArgs(1),
+	RunE: runSearchSave,
+}
+
+// searchListCmd represents the search list command
+var searchListCmd = &cobra.Command{
+	Use:   "list",
+	Short: "List saved searches",
+	Long: `List the saved searches from the config file and 'gml search save'.
+
+Examples:
+  gml search list
+  gml search list --format json`,
+	Args: cobra.NoArgs,
+	RunE: runSearchList,
+}
+
+// searchDeleteCmd represents the search delete command
+var searchDeleteCmd = &cobra.Command{
+	Use:   "delete <name>",
+	Short: "Delete a saved search",
+	Long: `Delete a search saved with 'gml search save'.
+
+Examples:
+  gml search delete receipts`,
+	Args:              cobra.ExactArgs(1),
+	RunE:              runSearchDelete,
+	ValidArgsFunction: completeSavedSearches,
+}
+
+func runSearchSave(cmd *cobra.Command, args []string) error {
+	cfg := getBaseConfig(cmd)
+	store, err := searchStore(cmd)
+	if err != nil {
+		return err
+	}
+
+	// Get flags
+	query, _ := cmd.Flags().GetString("query")
+	labels, _ := cmd.Flags().GetStringArray("label")
+	force, _ := cmd.Flags().GetBool("force")
+
+	name := gml.SearchName(args[0])
+	if name == "" {
+		return fmt.Errorf("search name is required")
+	}
+	if strings.TrimSpace(query) == "" && len(labels) == 0 {
+		return fmt.Errorf("--query or --label is required")
+	}
+
+	existing, err := gml.FindSavedSearch(cfg, store, name)
+	if err == nil {
+		if existing.Source == gml.SearchSourceConfig {
+			return fmt.Errorf("search %s is defined in the config file; edit it with 'gml config edit'", name)
+		}
+		if !force {
+			return fmt.Errorf("search %s already exists (use --force to replace it)", name)
+		}
+	}
+
+	if err := store.Save(name, gml.SavedSearch{Query: query, Labels: labels}); err != nil {
+		return err
+	}
+
+	// Output
+	fmt.Fprintf(cmd.OutOrStdout(), "Saved search: %s\n", name)
+	return nil
+}
+
+func runSearchList(cmd *cobra.Command, args []string) error {
+	cfg := getBaseConfig(cmd)
+	store, err := searchStore(cmd)
+	if err != nil {
+		return err
+	}
+
+	searches, err := gml.ListSavedSearches(cfg, store)
+	if err != nil {
+		return err
+	}
+
+	// Output
+	if err := gml.FormatSavedSearches(cmd.OutOrStdout(), searches, formatFromFlags(cmd)); err != nil {
+		return fmt.Errorf("unable to format output: %w", err)
+	}
+	return nil
+}
+
+func runSearchDelete(cmd *cobra.Command, args []string) error {
+	cfg := getBaseConfig(cmd)
+	name := gml.SearchName(args[0])
+
+	if _, ok := cfg.Searches[name]; ok {
+		return fmt.Errorf("search %s is defined in the config file; edit it with 'gml config edit'", name)
+	}
+	store, err := searchStore(cmd)
+	if err != nil {
+		return err
+	}
+	if err := store.Delete(name); err != nil {
+		return err
+	}
+
+	// Output
+	fmt.Fprintf(cmd.OutOrStdout(), "Deleted search: %s\n", name)
+	return nil
+}
+
+// searchStore returns the store of searches saved with 'gml search save'
+func searchStore(cmd *cobra.Command) (*gml.SearchStore, error) {
+	path, err := configSiblingPath(cmd, "searches.toml")
+	if err != nil {
+		return nil, err
+	}
+	return gml.NewSearchStore(path), nil
+}
+
+// addSavedFlag adds the --saved flag for running a saved search
+func addSavedFlag(cmd *cobra.Command) {
+	cmd.Flags().String("saved", "", "Run a saved search (see gml search); --query and other filters narrow it")
+	_ = cmd.RegisterFlagCompletionFunc("saved", completeSavedSearches)
+}
+
+// savedSearchFromFlags returns the query and labels of the --saved search,
+// combined with the query and labels given on the command line
+func savedSearchFromFlags(cmd *cobra.Command, query string, labels []string) (string, []string, error) {
+	name, _ := cmd.Flags().GetString("saved")
+	if name == "" {
+		return query, labels, nil
+	}
+	store, err := searchStore(cmd)
+	if err != nil {
+		return "", nil, err
+	}
+	search, err := gml.FindSavedSearch(getBaseConfig(cmd), store, name)
+	if err != nil {
+		return "", nil, err
+	}
+	return gml.CombineQuery(search.Query, query), append(search.Labels, labels...), nil
+}
+
+// completeSavedSearches completes the names of saved searches
+func completeSavedSearches(cmd *cobra.Command, args []string, toComplete string) ([]string, cobra.ShellCompDirective) {
+	if len(args) > 0 {
+		return nil, cobra.ShellCompDirectiveNoFileComp
+	}
+	store, err := searchStore(cmd)
+	if err != nil {
+		return nil, cobra.ShellCompDirectiveNoFileComp
+	}
+	searches, err := gml.ListSavedSearches(getBaseConfig(cmd), store)
+	if err != nil {
+		return nil, cobra.ShellCompDirectiveNoFileComp
+	}
+	var names []string
+	for _, s := range searches {
+		names = append(names, s.Name)
+	}
+	return names, cobra.ShellCompDirectiveNoFileComp
+}
+
+func init() {
+	rootCmd.AddCommand(searchCmd)
+	searchCmd.AddCommand(searchSaveCmd)
+	searchCmd.AddCommand(searchListCmd)
+	searchCmd.AddCommand(searchDeleteCmd)
+
+	searchSaveCmd.Flags().StringP("query", "q", "", "Search query (Gmail search syntax)")
+	searchSaveCmd.Flags().StringArrayP("label", "l", nil, "Filter by label (can be specified multiple times)")
+	searchSaveCmd.Flags().Bool("force", false, "Replace a search saved under the same name")
+
+	setFormats(searchListCmd, gml.OutputFormatText, gml.OutputFormatJSON)
+
+	// Set custom output to enable testing
+	searchCmd.SetOut(os.Stdout)
+}
@@ -0,0 +1,543 @@
+/*
+Copyright © 2025 longkey1
+
+Licensed under the Apache License, Version 2.0 (the "License");
+you may not use this file except in compliance with the License.
+You may obtain a copy of the License at
+
+	http://www.apache.org/licenses/LICENSE-2.0
+
+Unless required by applicable law or agreed to in writing, software
+distributed under the License is distributed on an "AS IS" BASIS,
+WITHOUT WARRANTIES OR CONDITIONS OF ANY KIND, either express or implied.
+See the License for the specific language governing permissions and
+limitations under the License.
+*/
+package cmd
+
+import (
+	"bytes"
+	"encoding/json"
+	"errors"
+	"fmt"
+	"log/slog"
+	"os"
+	"os/signal"
+	"path/filepath"
+	"strings"
+	"syscall"
+	"time"
+
+	"github.com/longkey1/gml/internal/gml"
+	"github.com/spf13/cobra"
+)
+
+// sendCmd represents the send command
+var sendCmd = &cobra.Command{
+	Use:   "send",
+	Short: "Send a plain-text message",
+	Long: `Send a plain-text message from the authenticated account.
+
+Before sending, gml checks for likely mistakes: a body that mentions an
+attachment when none is attached, recipient domains that look misspelled
+(gmial.com, example.con) and more than 25 recipients. If any check fails the
+message is not sent; use --no-checks to send anyway.
+
+With --merge, one message is sent per row of a CSV file. The header row names
+the variables for the --template subject and body ({{.name}}, or
+{{index . "First Name"}} for names with spaces), and the --to-column column
+(default email) holds the recipient. Sent recipients are recorded in a journal
+under $XDG_STATE_HOME/gml, so re-running after a partial failure only sends
+the remaining rows; use --restart to discard it. With --dry-run, the rendered
+--merge messages are printed instead of sent.
+
+--body-format markdown renders the body (or template) from Markdown to HTML,
+and --body-format html takes HTML; either is sent as multipart/alternative
+with a plain-text version for clients that don't show HTML.
+
+Recipients without an @ are names, looked up in the contacts and other
+contacts (see 'gml contacts'); a name matching several addresses is an error
+unless one matches it exactly.
+
+--attach adds files as attachments, with the content type taken from the
+extension or the content. --inline adds them inline instead, e.g. images
+shown in the body, referenced as cid:<file name>.
+
+With --edit, the message (prefilled from the other flags) is written in
+$VISUAL or $EDITOR as a To/Cc/Bcc/Subject header block, a blank line and the
+body. After saving, a summary and the check warnings are shown and sending
+must be confirmed, or the message edited again; an empty body cancels.
+
+With --at, the message is put in a local send queue instead and sent by
+'gml queue run' (from cron or as a daemon) once it is due; see 'gml queue'.
+With --draft, it is saved as a draft instead.
+
+--template names a template added with 'gml template add', a
+[templates.<name>] config entry or a template file. Its subject, body and
+front-matter recipients are Go templates rendered with the --var values
+({{.name}}); --subject replaces the subject template and --to/--cc/--bcc add
+recipients. With --merge, the variables come from the CSV rows instead.
+
+Sending requires the send (or compose/modify) scope.
+
+Examples:
+  gml send --to alice@example.com --subject "Hello" --body "Hi Alice"
+  gml send --edit                                       # Write the whole message in $EDITOR
+  gml send --to bob -s "Lunch?" --body "Tomorrow?"      # Resolve "bob" in the contacts
+  gml send --to bob@example.com --template weekly-report --var week=27 --edit
+  gml send --to team@example.com --cc bob@example.com -s "Notes" --body-file notes.txt
+  echo "Done" | gml send --to me@example.com -s "Job finished" --body-file -
+  gml send --to bob@example.com -s "Invoice" --body "Attached." --attach invoice.pdf
+  gml send --to team@example.com -s "Release notes" --body-file notes.md --body-format markdown
+  gml send --to team@example.com -s "Weekly report" --body-file report.txt --at "monday 8am"
+  gml send --template weekly-report --var name=Bob --var week=27
+  gml send --template weekly-report --var name=Bob --draft
+  gml send --to list@example.com -s "Announcement" --body-file msg.txt --no-checks
+  gml send --merge recipients.csv --template welcome --dry-run
+  gml send --merge recipients.csv --template welcome --rate 2s
+  gml send --merge people.csv --template invite.txt -s "Hi {{.name}}" --to-column address`,
+	RunE: runSend,
+}
+
+func runSend(cmd *cobra.Command, args []string) error {
+	ctx := cmd.Context()
+	cfg, err := GetConfig(cmd)
+	if err != nil {
+		return err
+	}
+
+	// Get flags
+	noChecks, _ := cmd.Flags().GetBool("no-checks")
+	mergePath, _ := cmd.Flags().GetString("merge")
+	atStr, _ := cmd.Flags().GetString("at")
+	asDraft, _ := cmd.Flags().GetBool("draft")
+	edit, _ := cmd.Flags().GetBool("edit")
+
+	if asDraft && atStr != "" {
+		return fmt.Errorf("--draft and --at are mutually exclusive")
+	}
+	if mergePath != "" {
+		for _, name := range []string{"to", "cc", "bcc", "body", "body-file"} {
+			if cmd.Flags().Changed(name) {
+				return fmt.Errorf("--merge takes recipients from the CSV and the body from --template")
+			}
+		}
+		if atStr != "" || asDraft || edit {
+			return fmt.Errorf("--at, --draft and --edit cannot be combined with --merge")
+		}
+		return runSendMerge(cmd, cfg, mergePath, noChecks)
+	}
+
+	msg, err := composeFromFlags(cmd, cfg)
+	if err != nil {
+		return err
+	}
+	if edit {
+		question := "Send this message?"
+		switch {
+		case asDraft:
+			question = "Save this message as a draft?"
+		case atStr != "":
+			question = "Queue this message?"
+		}
+		if msg, err = editOutgoing(cmd, msg, noChecks, question); err != nil {
+			return err
+		}
+		if msg == nil {
+			fmt.Fprintln(cmd.OutOrStdout(), "Cancelled.")
+			return nil
+		}
+		// The warnings were shown before confirming
+		noChecks = true
+	}
+
+	if asDraft {
+		return saveDraft(cmd, cfg, msg)
+	}
+	if err := checkOutgoing(cmd, msg, noChecks); err != nil {
+		return err
+	}
+	if atStr != "" {
+		return queueMessage(cmd, cfg, msg, atStr)
+	}
+
+	// Create service
+	svc, err := gml.NewService(ctx, cfg)
+	if err != nil {
+		return fmt.Errorf("unable to create service: %w", err)
+	}
+
+	sent, err := gml.SendMessage(ctx, svc, msg)
+	if err != nil {
+		return err
+	}
+	if gml.IsDryRun(ctx) {
+		// Only the request was reported; there is no sent message to show
+		return nil
+	}
+
+	// Output
+	if formatFromFlags(cmd) == gml.OutputFormatJSON {
+		return gml.FormatJSON(cmd.OutOrStdout(), map[string]string{"id": sent.Id, "threadId": sent.ThreadId})
+	}
+	fmt.Fprintf(cmd.OutOrStdout(), "Sent message %s\n", sent.Id)
+	return nil
+}
+
+// addComposeFlags adds the flags composing a new message: recipients,
+// subject, body or --template with --var, and attachments
+func addComposeFlags(cmd *cobra.Command) {
+	cmd.Flags().StringArray("to", nil, "Recipient: an address, or a name to look up in the contacts (can be specified multiple times)")
+	cmd.Flags().StringArray("cc", nil, "Cc recipient: an address or a contact name (can be specified multiple times)")
+	cmd.Flags().StringArray("bcc", nil, "Bcc recipient: an address or a contact name (can be specified multiple times)")
+	cmd.Flags().StringP("subject", "s", "", "Subject")
+	cmd.Flags().String("body", "", "Message body")
+	cmd.Flags().String("body-file", "", "Read the message body from a file (- for stdin)")
+	cmd.Flags().String("body-format", "", "Body format: text, markdown or html (markdown and html are sent with a plain-text version; default text, or the template's)")
+	cmd.Flags().String("template", "", "Compose from a template: a 'gml template' name, a [templates] config name or a template file")
+	cmd.Flags().StringArray("var", nil, "Template variable as name=value (can be specified multiple times)")
+	_ = cmd.RegisterFlagCompletionFunc("template", completeMailTemplates)
+	addAttachFlags(cmd)
+}
+
+// composeFromFlags builds the message of the compose flags, with recipients
+// given by name resolved against the contacts
+func composeFromFlags(cmd *cobra.Command, cfg *gml.Config) (*gml.OutgoingMessage, error) {
+	to, _ := cmd.Flags().GetStringArray("to")
+	cc, _ := cmd.Flags().GetStringArray("cc")
+	bcc, _ := cmd.Flags().GetStringArray("bcc")
+	subject, _ := cmd.Flags().GetString("subject")
+	body, _ := cmd.Flags().GetString("body")
+	bodyFile, _ := cmd.Flags().GetString("body-file")
+	templateName, _ := cmd.Flags().GetString("template")
+	varPairs, _ := cmd.Flags().GetStringArray("var")
+
+	if bodyFile != "" {
+		data, err := readInput(cmd, bodyFile)
+		if err != nil {
+			return nil, err
+		}
+		body = string(data)
+	}
+
+	msg := &gml.OutgoingMessage{
+		To:      to,
+		Cc:      cc,
+		Bcc:     bcc,
+		Subject: subject,
+		Body:    body,
+	}
+	if templateName != "" {
+		if body != "" || bodyFile != "" {
+			return nil, fmt.Errorf("--template cannot be combined with --body or --body-file")
+		}
+		var err error
+		if msg, err = renderMailTemplate(cmd, cfg, templateName, subject, varPairs); err != nil {
+			return nil, err
+		}
+		msg.To = append(msg.To, to...)
+		msg.Cc = append(msg.Cc, cc...)
+		msg.Bcc = append(msg.Bcc, bcc...)
+	} else if len(varPairs) > 0 {
+		return nil, fmt.Errorf("--var requires --template")
+	}
+
+	if format, _ := cmd.Flags().GetString("body-format"); format != "" {
+		var err error
+		if msg.BodyFormat, err = gml.ParseBodyFormat(format); err != nil {
+			return nil, err
+		}
+	}
+
+	attachments, err := attachmentsFromFlags(cmd)
+	if err != nil {
+		return nil, err
+	}
+	msg.Attachments = attachments
+	if err := resolveContactNames(cmd, cfg, msg); err != nil {
+		return nil, err
+	}
+	return msg, nil
+}
+
+// addAttachFlags adds --attach and --inline
+func addAttachFlags(cmd *cobra.Command) {
+	cmd.Flags().StringArray("attach", nil, "Attach a file (can be specified multiple times)")
+	cmd.Flags().StringArray("inline", nil, "Attach a file inline, e.g. an image, with its file name as content ID (can be specified multiple times)")
+}
+
+// attachmentsFromFlags reads the files of --attach and --inline
+func attachmentsFromFlags(cmd *cobra.Command) ([]gml.OutgoingAttachment, error) {
+	attach, _ := cmd.Flags().GetStringArray("attach")
+	inline, _ := cmd.Flags().GetStringArray("inline")
+
+	var attachments []gml.OutgoingAttachment
+	for i, path := range append(attach, inline...) {
+		a, err := gml.ReadAttachment(path, i >= len(attach))
+		if err != nil {
+			return nil, err
+		}
+		attachments = append(attachments, a)
+	}
+	return attachments, nil
+}
+
+// renderMailTemplate renders the --template message with the --var values,
+// with subject, if set, replacing the subject template
+func renderMailTemplate(cmd *cobra.Command, cfg *gml.Config, name, subject string, varPairs []string) (*gml.OutgoingMessage, error) {
+	tmpl, err := resolveMailTemplate(cmd, cfg, name)
+	if err != nil {
+		return nil, err
+	}
+	if subject != "" {
+		tmpl.Subject = subject
+	}
+	vars, err := gml.ParseTemplateVars(varPairs)
+	if err != nil {
+		return nil, err
+	}
+	return tmpl.Render(vars)
+}
+
+// saveDraft saves msg as a draft instead of sending it
+func saveDraft(cmd *cobra.Command, cfg *gml.Config, msg *gml.OutgoingMessage) error {
+	ctx := cmd.Context()
+
+	// Create service
+	svc, err := gml.NewService(ctx, cfg)
+	if err != nil {
+		return fmt.Errorf("unable to create service: %w", err)
+	}
+
+	draft, err := gml.CreateDraft(ctx, svc, msg)
+	if err != nil {
+		return err
+	}
+	if gml.IsDryRun(ctx) {
+		// Only the request was reported; there is no draft to show
+		return nil
+	}
+
+	// Output
+	if formatFromFlags(cmd) == gml.OutputFormatJSON {
+		return gml.FormatJSON(cmd.OutOrStdout(), map[string]string{"id": draft.Id, "messageId": draft.Message.Id})
+	}
+	fmt.Fprintf(cmd.OutOrStdout(), "Saved draft %s\n", draft.Id)
+	return nil
+}
+
+// queueMessage puts msg in the send queue for 'gml queue run' to send at the
+// --at time
+func queueMessage(cmd *cobra.Command, cfg *gml.Config, msg *gml.OutgoingMessage, atStr string) error {
+	at, err := gml.ParseWhen(atStr, time.Now())
+	if err != nil {
+		return fmt.Errorf("invalid --at: %w", err)
+	}
+	path, err := gml.StatePath(gml.SendQueueName, cfg.Account)
+	if err != nil {
+		return err
+	}
+	queued, err := gml.EnqueueMessage(cmd.Context(), path, msg, at)
+	if err != nil {
+		return err
+	}
+
+	// Output
+	if formatFromFlags(cmd) == gml.OutputFormatJSON {
+		return gml.FormatJSON(cmd.OutOrStdout(), queued)
+	}
+	fmt.Fprintf(cmd.OutOrStdout(), "Queued message %s for %s (sent by 'gml queue run')\n", queued.ID, at.Local().Format("Mon 2006-01-02 15:04"))
+	return nil
+}
+
+func runSendMerge(cmd *cobra.Command, cfg *gml.Config, mergePath string, noChecks bool) error {
+	ctx, stop := signal.NotifyContext(cmd.Context(), os.Interrupt, syscall.SIGTERM)
+	defer stop()
+
+	// Get flags
+	subject, _ := cmd.Flags().GetString("subject")
+	templateName, _ := cmd.Flags().GetString("template")
+	varPairs, _ := cmd.Flags().GetStringArray("var")
+	toColumn, _ := cmd.Flags().GetString("to-column")
+	rate, _ := cmd.Flags().GetDuration("rate")
+	journalPath, _ := cmd.Flags().GetString("journal")
+	restart, _ := cmd.Flags().GetBool("restart")
+	dryRun := gml.IsDryRun(ctx)
+
+	if templateName == "" {
+		return fmt.Errorf("--template is required with --merge")
+	}
+	if len(varPairs) > 0 {
+		return fmt.Errorf("--var cannot be combined with --merge; the variables come from the CSV")
+	}
+	tmpl, err := resolveMailTemplate(cmd, cfg, templateName)
+	if err != nil {
+		return err
+	}
+	if subject == "" {
+		subject = tmpl.Subject
+	}
+	bodyFormat := tmpl.Format
+	if format, _ := cmd.Flags().GetString("body-format"); format != "" {
+		if bodyFormat, err = gml.ParseBodyFormat(format); err != nil {
+			return err
+		}
+	}
+	attachments, err := attachmentsFromFlags(cmd)
+	if err != nil {
+		return err
+	}
+
+	data, err := readInput(cmd, mergePath)
+	if err != nil {
+		return err
+	}
+	rows, err := gml.ReadMergeCSV(bytes.NewReader(data))
+	if err != nil {
+		return err
+	}
+
+	if journalPath == "" {
+		dir, err := gml.StateDir()
+		if err != nil {
+			return err
+		}
+		account := cfg.Account
+		if account == "" {
+			account = "default"
+		}
+		base := strings.TrimSuffix(filepath.Base(mergePath), filepath.Ext(mergePath))
+		journalPath = filepath.Join(dir, fmt.Sprintf("merge-%s-%s.log", base, account))
+	}
+	if restart && !dryRun {
+		if err := os.Remove(journalPath); err != nil && !errors.Is(err, os.ErrNotExist) {
+			return fmt.Errorf("unable to remove merge journal: %w", err)
+		}
+	}
+
+	var svc *gml.Service
+	if !dryRun {
+		svc, err = gml.NewService(ctx, cfg)
+		if err != nil {
+			return fmt.Errorf("unable to create service: %w", err)
+		}
+	}
+
+	opts := gml.MergeOptions{
+		Subject:     subject,
+		Body:        tmpl.Body,
+		BodyFormat:  bodyFormat,
+		Attachments: attachments,
+		ToColumn:    toColumn,
+		Rate:        rate,
+		JournalPath: journalPath,
+		DryRun:      dryRun,
+		Status: func(s gml.MergeStatus) {
+			printMergeStatus(cmd, s)
+		},
+	}
+	if !noChecks {
+		opts.Check = func(msg *gml.OutgoingMessage) []string {
+			return gml.CheckOutgoing(msg, gml.CheckOptions{})
+		}
+	}
+
+	result, err := gml.MailMerge(ctx, svc, rows, opts)
+	if result != nil {
+		if dryRun {
+			fmt.Fprintf(cmd.ErrOrStderr(), "Would send %d of %d messages (%d already sent, %d failed)\n", result.Total-result.Skipped-result.Failed, result.Total, result.Skipped, result.Failed)
+		} else {
+			fmt.Fprintf(cmd.ErrOrStderr(), "Sent %d of %d messages (%d already sent, %d failed)\n", result.Sent, result.Total, result.Skipped, result.Failed)
+		}
+	}
+	if err != nil {
+		return fmt.Errorf("mail merge interrupted, re-run to resume: %w", err)
+	}
+	if result.Failed > 0 {
+		return &ExitError{Code: 1, Err: fmt.Errorf("%d messages failed; re-run to retry them", result.Failed)}
+	}
+	return nil
+}
+
+// resolveMailTemplate returns the template --template names: a
+// [templates.<name>] config entry, a template added with 'gml template add'
+// or a template file
+func resolveMailTemplate(cmd *cobra.Command, cfg *gml.Config, name string) (gml.MailTemplate, error) {
+	// Viper lowercases config keys
+	if tmpl, ok := cfg.Templates[strings.ToLower(name)]; ok {
+		body, err := tmpl.LoadBody()
+		if err != nil {
+			return gml.MailTemplate{}, err
+		}
+		tmpl.Body = body
+		return tmpl, nil
+	}
+
+	text, err := loadStoredTemplate(cmd, name)
+	if errors.Is(err, gml.ErrNotFound) {
+		data, readErr := os.ReadFile(name)
+		if errors.Is(readErr, os.ErrNotExist) {
+			return gml.MailTemplate{}, fmt.Errorf("template not found: %s (not in [templates] config, 'gml template list' or a file)", name)
+		}
+		text, err = string(data), readErr
+	}
+	if err != nil {
+		return gml.MailTemplate{}, fmt.Errorf("unable to read template: %w", err)
+	}
+	return gml.ParseMailTemplate(text)
+}
+
+// printMergeStatus prints one row's outcome, as a JSON line with --json; dry
+// runs print the rendered message
+func printMergeStatus(cmd *cobra.Command, s gml.MergeStatus) {
+	out := cmd.OutOrStdout()
+	if formatFromFlags(cmd) == gml.OutputFormatJSON {
+		if err := json.NewEncoder(out).Encode(s); err != nil {
+			slog.Warn("unable to write status", "err", err)
+		}
+		return
+	}
+	switch s.Status {
+	case gml.MergeStatusDryRun:
+		fmt.Fprintf(out, "--- row %d\nTo: %s\nSubject: %s\n\n%s\n", s.Row, s.To, s.Message.Subject, s.Message.Body)
+	case gml.MergeStatusFailed:
+		fmt.Fprintf(out, "row %d\t%s\t%s\t%s\n", s.Row, s.To, s.Status, s.Error)
+	default:
+		fmt.Fprintf(out, "row %d\t%s\t%s\t%s\n", s.Row, s.To, s.Status, s.MessageID)
+	}
+}
+
+// checkOutgoing runs the pre-send safety checks, printing each warning and
+// refusing to send unless --no-checks is given
+func checkOutgoing(cmd *cobra.Command, msg *gml.OutgoingMessage, noChecks bool) error {
+	if noChecks {
+		return nil
+	}
+	warnings := gml.CheckOutgoing(msg, gml.CheckOptions{})
+	if len(warnings) == 0 {
+		return nil
+	}
+	for _, w := range warnings {
+		fmt.Fprintf(cmd.ErrOrStderr(), "Warning: %s\n", w)
+	}
+	return errors.New("message not sent; fix the warnings above or use --no-checks to send anyway")
+}
+
+func init() {
+	rootCmd.AddCommand(sendCmd)
+
+	addComposeFlags(sendCmd)
+	sendCmd.Flags().Bool("edit", false, "Write the message in $EDITOR, then review and confirm it")
+	sendCmd.Flags().String("at", "", "Queue the message to be sent later by 'gml queue run' (e.g. \"2025-07-01 08:00\", tomorrow 9am, 2h)")
+	sendCmd.Flags().Bool("no-checks", false, "Skip the attachment, recipient domain and recipient count checks")
+	sendCmd.Flags().String("merge", "", "Send one message per row of a CSV file (- for stdin)")
+	sendCmd.Flags().Bool("draft", false, "Save the message as a draft instead of sending it")
+	sendCmd.Flags().String("to-column", "email", "CSV column holding the recipient for --merge")
+	sendCmd.Flags().Duration("rate", time.Second, "Minimum delay between messages for --merge")
+	sendCmd.Flags().String("journal", "", "Path of the --merge resume journal (default: state directory)")
+	sendCmd.Flags().Bool("restart", false, "Discard the --merge resume journal and send to every row")
+	setFormats(sendCmd, gml.OutputFormatText, gml.OutputFormatJSON)
+
+	// Set custom output to enable testing
+	sendCmd.SetOut(os.Stdout)
+}
@@ -0,0 +1,301 @@
+/*
+Copyright © 2025 longkey1
+
+Licensed under the Apache License, Version 2.0 (the "License");
+you may not use this file except in compliance with the License.
+You may obtain a copy of the License at
+
+	http://www.apache.org/licenses/LICENSE-2.0
+
+Unless required by applicable law or agreed to in writing, software
+distributed under the License is distributed on an "AS IS" BASIS,
+WITHOUT WARRANTIES OR CONDITIONS OF ANY KIND, either express or implied.
+See the License for the specific language governing permissions and
+limitations under the License.
+*/
+package cmd
+
+import (
+	"errors"
+	"fmt"
+	"log/slog"
+	"os"
+	"strings"
+	"time"
+
+	"github.com/longkey1/gml/internal/gml"
+	"github.com/longkey1/gml/internal/tui"
+	"github.com/spf13/cobra"
+	"golang.org/x/term"
+)
+
+// sendersCmd represents the senders command
+var sendersCmd = &cobra.Command{
+	Use:   "senders",
+	Short: "Report top senders by message count and size, and clean them up",
+	Long: `Crawl the messages matching a query, e.g. label:promotions, and list the
+top senders by message count (or total size with --sort size). The crawl
+shares the metadata cache of 'gml stats', so later runs only fetch new mail.
+
+Follow-up actions apply to the mail of the listed senders within the query:
+  --archive  remove their messages from the inbox
+  --trash    move their messages to the trash
+  --filter   also create a filter doing the same to their future mail
+
+Without --sender, actions apply to every sender in the report, after
+confirmation (scripts must pass --yes). With --pick, senders are chosen in a
+fuzzy finder and the action is asked for unless given by flag.
+Actions require the "modify" scope, filters "settings.basic".
+
+Examples:
+  gml senders -q "category:promotions"
+  gml senders -l INBOX --sort size -n 50
+  gml senders -q "category:promotions" --pick             # Choose senders and an action
+  gml senders -q "category:promotions" -n 10 --archive --filter --yes
+  gml senders --sender deals@example.com --trash --yes
+  gml senders -q "older_than:1y" --json | jq '.senders[] | select(.size > 10000000)'`,
+	Args: cobra.NoArgs,
+	RunE: runSenders,
+}
+
+func runSenders(cmd *cobra.Command, args []string) error {
+	ctx := cmd.Context()
+	cfg, err := GetConfig(cmd)
+	if err != nil {
+		return err
+	}
+
+	// Get flags
+	limit, _ := cmd.Flags().GetInt("limit")
+	sortBy, _ := cmd.Flags().GetString("sort")
+	maxMessages, _ := cmd.Flags().GetInt("max-messages")
+	concurrency, _ := cmd.Flags().GetInt("concurrency")
+	noCache, _ := cmd.Flags().GetBool("no-cache")
+	senders, _ := cmd.Flags().GetStringArray("sender")
+	archive, _ := cmd.Flags().GetBool("archive")
+	trash, _ := cmd.Flags().GetBool("trash")
+	filter, _ := cmd.Flags().GetBool("filter")
+	pick, _ := cmd.Flags().GetBool("pick")
+	yes, _ := cmd.Flags().GetBool("yes")
+
+	query, labels, err := queryFromFlags(cmd)
+	if err != nil {
+		return err
+	}
+	if query, labels, err = savedSearchFromFlags(cmd, query, labels); err != nil {
+		return err
+	}
+	if query, err = searchQueryFromFlags(cmd, query); err != nil {
+		return err
+	}
+	csvOpts, err := csvOptionsFromFlags(cmd, gml.CSVOptions{})
+	if err != nil {
+		return err
+	}
+	if sortBy != "count" && sortBy != "size" {
+		return fmt.Errorf("invalid --sort %q (count or size)", sortBy)
+	}
+
+	var action gml.SenderAction
+	switch {
+	case archive && trash:
+		return fmt.Errorf("--archive and --trash cannot be combined")
+	case archive:
+		action = gml.SenderArchive
+	case trash:
+		action = gml.SenderTrash
+	case filter && !pick:
+		return fmt.Errorf("--filter requires --archive or --trash")
+	}
+	if pick && len(senders) > 0 {
+		return fmt.Errorf("--pick and --sender cannot be combined")
+	}
+	if len(senders) > 0 && action == "" {
+		return fmt.Errorf("--sender requires --archive or --trash")
+	}
+	confirm := action != "" && !pick && !yes
+	if confirm && !term.IsTerminal(int(os.Stdin.Fd())) {
+		return fmt.Errorf("refusing to change messages without confirmation; pass --yes when not running interactively")
+	}
+
+	// Create service
+	svc, err := gml.NewService(ctx, cfg)
+	if err != nil {
+		return fmt.Errorf("unable to create service: %w", err)
+	}
+
+	if len(senders) == 0 {
+		var cache *gml.MetadataCache
+		if !noCache {
+			path, err := gml.StatePath(gml.StatsCacheName, cfg.Account)
+			if err != nil {
+				return err
+			}
+			if cache, err = gml.LoadMetadataCache(path); err != nil {
+				return err
+			}
+		}
+
+		progress := newProgress(cmd, "Fetching messages")
+		report, err := gml.TopSenders(ctx, svc, gml.SendersOptions{
+			Query:       query,
+			LabelIDs:    labels,
+			Limit:       limit,
+			SortBySize:  sortBy == "size",
+			MaxMessages: maxMessages,
+			Concurrency: concurrency,
+			Cache:       cache,
+			Progress:    progress.Update,
+		})
+		progress.Done()
+		if cache != nil {
+			// Keep what was fetched even if the crawl failed part way
+			if err := cache.Save(); err != nil {
+				slog.Warn("unable to save stats cache", "err", err)
+			}
+		}
+		if err != nil {
+			return err
+		}
+
+		if pick {
+			if senders, err = pickSenders(report); err != nil {
+				return err
+			}
+		} else {
+			// Output
+			format := formatFromFlags(cmd)
+			if action != "" {
+				// The report is what gets confirmed
+				format = gml.OutputFormatText
+			}
+			if err := gml.FormatSenders(cmd.OutOrStdout(), report, format, csvOpts); err != nil {
+				return fmt.Errorf("unable to format output: %w", err)
+			}
+			if action == "" {
+				return nil
+			}
+			for _, s := range report.Senders {
+				senders = append(senders, s.Sender)
+			}
+		}
+	}
+	if len(senders) == 0 {
+		return nil
+	}
+
+	if pick && action == "" {
+		var withFilter bool
+		if action, withFilter, err = promptSenderAction(cmd, len(senders)); err != nil || action == "" {
+			return err
+		}
+		filter = filter || withFilter
+	}
+	if confirm {
+		extra := ""
+		if filter {
+			extra = " and create filters for their future mail"
+		}
+		verb := "Archive"
+		if action == gml.SenderTrash {
+			verb = "Trash"
+		}
+		fmt.Fprintf(cmd.OutOrStdout(), "%s the messages of %d senders%s? [y/N]: ", verb, len(senders), extra)
+		var response string
+		fmt.Scanln(&response)
+		if response != "y" && response != "Y" {
+			fmt.Fprintln(cmd.OutOrStdout(), "Cancelled.")
+			return nil
+		}
+	}
+
+	failures := failureLogFromFlags(cmd)
+	progress := newProgress(cmd, "Cleaning up senders")
+	results, err := gml.CleanupSenders(ctx, svc, gml.SenderCleanupOptions{
+		Query:    query,
+		LabelIDs: labels,
+		Senders:  senders,
+		Action:   action,
+		Filter:   filter,
+		Failures: failures,
+		Progress: progress.Update,
+	})
+	progress.Done()
+	if fmtErr := gml.FormatSenderCleanup(cmd.OutOrStdout(), results, action, formatFromFlags(cmd)); fmtErr != nil && err == nil {
+		err = fmtErr
+	}
+	if err != nil {
+		return err
+	}
+	return reportFailures(cmd, failures)
+}
+
+// pickSenders lets the user choose senders of a report in the fuzzy finder
+func pickSenders(report *gml.SendersReport) ([]string, error) {
+	items := make([]gml.MessageInfo, len(report.Senders))
+	for i, s := range report.Senders {
+		from := s.Sender
+		if s.Name != "" {
+			from = s.Name + " <" + s.Sender + ">"
+		}
+		items[i] = gml.MessageInfo{
+			ID:      s.Sender,
+			From:    from,
+			Subject: fmt.Sprintf("%d messages, %s", s.Count, gml.FormatSize(s.Size)),
+			Date:    s.Latest.Format(time.RFC1123Z),
+		}
+	}
+
+	senders, err := tui.Pick(items)
+	if errors.Is(err, tui.ErrPickCanceled) {
+		return nil, &ExitError{Code: 130}
+	}
+	return senders, err
+}
+
+// promptSenderAction asks what to do with the picked senders; an empty
+// action means the user quit
+func promptSenderAction(cmd *cobra.Command, n int) (gml.SenderAction, bool, error) {
+	fmt.Fprintf(cmd.ErrOrStderr(), "%d senders: [a]rchive, [t]rash, archive and [f]ilter, trash and filte[r], [q]uit: ", n)
+	var response string
+	fmt.Scanln(&response)
+	switch strings.ToLower(strings.TrimSpace(response)) {
+	case "a":
+		return gml.SenderArchive, false, nil
+	case "t":
+		return gml.SenderTrash, false, nil
+	case "f":
+		return gml.SenderArchive, true, nil
+	case "r":
+		return gml.SenderTrash, true, nil
+	}
+	fmt.Fprintln(cmd.ErrOrStderr(), "Cancelled.")
+	return "", false, nil
+}
+
+func init() {
+	rootCmd.AddCommand(sendersCmd)
+
+	sendersCmd.Flags().StringP("query", "q", "", "Search query (Gmail search syntax)")
+	sendersCmd.Flags().StringArrayP("label", "l", nil, "Filter by label (can be specified multiple times)")
+	addQueryFlags(sendersCmd)
+	addSearchFlags(sendersCmd)
+	addSavedFlag(sendersCmd)
+	sendersCmd.Flags().IntP("limit", "n", 20, "Show the top senders only (0 for all)")
+	sendersCmd.Flags().String("sort", "count", "Rank senders by count or size")
+	sendersCmd.Flags().Int("max-messages", 0, "Stop after this many messages, newest first (0 for all)")
+	sendersCmd.Flags().Int("concurrency", 8, "Parallel metadata requests")
+	sendersCmd.Flags().Bool("no-cache", false, "Fetch every message instead of using the stats cache")
+	sendersCmd.Flags().StringArray("sender", nil, "Act on this sender instead of the report (can be specified multiple times)")
+	sendersCmd.Flags().Bool("archive", false, "Archive the messages of the senders")
+	sendersCmd.Flags().Bool("trash", false, "Move the messages of the senders to the trash")
+	sendersCmd.Flags().Bool("filter", false, "Also create a filter per sender doing the same to future mail")
+	sendersCmd.Flags().Bool("pick", false, "Choose senders in a fuzzy finder, then an action")
+	sendersCmd.Flags().BoolP("yes", "y", false, "Skip the confirmation prompt")
+	addIgnoreErrorsFlag(sendersCmd)
+	setFormats(sendersCmd, gml.OutputFormatText, gml.OutputFormatJSON, gml.OutputFormatCSV, gml.OutputFormatTSV)
+	addCSVFlags(sendersCmd)
+
+	// Set custom output to enable testing
+	sendersCmd.SetOut(os.Stdout)
+}
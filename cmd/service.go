@@ -0,0 +1,273 @@
+/*
+Copyright © 2025 longkey1
+
+Licensed under the Apache License, Version 2.0 (the "License");
+you may not use this file except in compliance with the License.
+You may obtain a copy of the License at
+
+	http://www.apache.org/licenses/LICENSE-2.0
+
+Unless required by applicable law or agreed to in writing, software
+distributed under the License is distributed on an "AS IS" BASIS,
+WITHOUT WARRANTIES OR CONDITIONS OF ANY KIND, either express or implied.
+See the License for the specific language governing permissions and
+limitations under the License.
+*/
+package cmd
+
+import (
+	"fmt"
+	"os"
+	"path/filepath"
+	"sort"
+	"strings"
+
+	"github.com/longkey1/gml/internal/gml"
+	"github.com/spf13/cobra"
+)
+
+// daemonModes maps service modes to the gml arguments they run
+var daemonModes = map[string][]string{
+	"watch":  {"watch", "--poll"},
+	"serve":  {"watch", "serve"},
+	"snooze": {"snooze", "run", "--daemon"},
+	"queue":  {"queue", "run", "--daemon"},
+}
+
+// serviceCmd represents the service command
+var serviceCmd = &cobra.Command{
+	Use:   "service",
+	Short: "Install gml daemons as user services",
+	Long: `Install long-running gml modes as user-level services: a systemd user unit
+on Linux or a launchd agent on macOS. Services start at login and restart on
+failure.
+
+Modes:
+  watch   gml watch --poll
+  serve   gml watch serve
+  snooze  gml snooze run --daemon
+  queue   gml queue run --daemon
+
+Examples:
+  gml service install watch --args '--interval 30s -l INBOX --exec ~/bin/on-mail.sh'
+  gml service install serve --args '--addr :8080 --token s3cret'
+  gml --account work service install watch   # Unit gml-watch-work for the work account
+  gml service install watch --dry-run        # Print the unit file only
+  gml service uninstall watch`,
+}
+
+// serviceInstallCmd represents the service install command
+var serviceInstallCmd = &cobra.Command{
+	Use:   "install <mode>",
+	Short: "Write and enable a user service for a daemon mode",
+	Long: `Write a systemd user unit (~/.config/systemd/user/<name>.service) or launchd
+agent (~/Library/LaunchAgents/com.github.longkey1.<name>.plist) and enable it.
+
+The service runs this gml executable with the current --config and --account,
+and inherits the current PATH so --exec hooks resolve the same commands.
+With --dry-run, the service file is printed instead of installed.
+
+Examples:
+  gml service install watch --args '-l INBOX --exec "notify-send \"\$GML_SUBJECT\""'
+  gml service install serve --args '--addr 127.0.0.1:8080' --name gml-push
+  gml service install watch --no-enable      # Write the unit without starting it`,
+	Args: cobra.ExactArgs(1),
+	RunE: runServiceInstall,
+}
+
+// serviceUninstallCmd represents the service uninstall command
+var serviceUninstallCmd = &cobra.Command{
+	Use:   "uninstall <mode>",
+	Short: "Stop, disable and remove a user service",
+	Long: `Stop, disable and remove a user service written by 'gml service install'.
+
+Examples:
+  gml service uninstall watch
+  gml --account work service uninstall watch
+  gml service uninstall serve --name gml-push`,
+	Args: cobra.ExactArgs(1),
+	RunE: runServiceUninstall,
+}
+
+func runServiceInstall(cmd *cobra.Command, args []string) error {
+	ctx := cmd.Context()
+
+	// Get flags
+	argsStr, _ := cmd.Flags().GetString("args")
+	noEnable, _ := cmd.Flags().GetBool("no-enable")
+	dryRun := gml.IsDryRun(ctx)
+
+	platform, err := gml.DaemonPlatform()
+	if err != nil {
+		return err
+	}
+
+	modeArgs, err := daemonModeArgs(args[0])
+	if err != nil {
+		return err
+	}
+	extra, err := gml.SplitArgs(argsStr)
+	if err != nil {
+		return fmt.Errorf("invalid --args: %w", err)
+	}
+
+	account, err := serviceAccount(cmd)
+	if err != nil {
+		return err
+	}
+	name, err := serviceName(cmd, args[0], account)
+	if err != nil {
+		return err
+	}
+
+	exe, err := os.Executable()
+	if err != nil {
+		return fmt.Errorf("unable to locate gml executable: %w", err)
+	}
+	if resolved, err := filepath.EvalSymlinks(exe); err == nil {
+		exe = resolved
+	}
+
+	command := []string{exe}
+	if used := currentInvocation(cmd).configFile; used != "" {
+		abs, err := filepath.Abs(used)
+		if err != nil {
+			return fmt.Errorf("unable to resolve config path: %w", err)
+		}
+		command = append(command, "--config", abs)
+	}
+	if account != "" {
+		command = append(command, "--account", account)
+	}
+	command = append(command, modeArgs...)
+	command = append(command, extra...)
+
+	svc := gml.DaemonService{
+		Name:        name,
+		Description: "gml " + strings.Join(append(modeArgs, extra...), " "),
+		Command:     command,
+		Env:         serviceEnv(),
+	}
+
+	if dryRun {
+		content, err := gml.RenderDaemon(platform, svc)
+		if err != nil {
+			return err
+		}
+		fmt.Fprint(cmd.OutOrStdout(), content)
+		return nil
+	}
+
+	path, err := gml.InstallDaemon(ctx, platform, svc, !noEnable, cmd.OutOrStdout(), cmd.ErrOrStderr())
+	if err != nil {
+		return err
+	}
+
+	fmt.Fprintf(cmd.OutOrStdout(), "Wrote %s\n", path)
+	if noEnable {
+		return nil
+	}
+	switch platform {
+	case "systemd":
+		fmt.Fprintf(cmd.OutOrStdout(), "Enabled %s.service; view logs with: journalctl --user -u %s -f\n", name, name)
+	case "launchd":
+		fmt.Fprintf(cmd.OutOrStdout(), "Loaded %s; logs are written to ~/Library/Logs/gml/%s.log\n", name, name)
+	}
+	return nil
+}
+
+func runServiceUninstall(cmd *cobra.Command, args []string) error {
+	ctx := cmd.Context()
+
+	platform, err := gml.DaemonPlatform()
+	if err != nil {
+		return err
+	}
+	if _, err := daemonModeArgs(args[0]); err != nil {
+		return err
+	}
+
+	account, err := serviceAccount(cmd)
+	if err != nil {
+		return err
+	}
+	name, err := serviceName(cmd, args[0], account)
+	if err != nil {
+		return err
+	}
+
+	path, err := gml.UninstallDaemon(ctx, platform, name, cmd.OutOrStdout(), cmd.ErrOrStderr())
+	if err != nil {
+		return err
+	}
+
+	fmt.Fprintf(cmd.OutOrStdout(), "Removed %s\n", path)
+	return nil
+}
+
+// daemonModeArgs returns the gml arguments for a service mode
+func daemonModeArgs(mode string) ([]string, error) {
+	args, ok := daemonModes[mode]
+	if !ok {
+		modes := make([]string, 0, len(daemonModes))
+		for m := range daemonModes {
+			modes = append(modes, m)
+		}
+		sort.Strings(modes)
+		return nil, fmt.Errorf("unknown service mode: %s (available: %s)", mode, strings.Join(modes, ", "))
+	}
+	return append([]string(nil), args...), nil
+}
+
+// serviceAccount returns the selected account to bake into the service
+func serviceAccount(cmd *cobra.Command) (string, error) {
+	account, err := selectedAccount(cmd, getBaseConfig(cmd))
+	if err != nil {
+		return "", err
+	}
+	if account == allAccounts {
+		return "", fmt.Errorf("--account %s is not supported by this command", allAccounts)
+	}
+	return strings.ToLower(account), nil
+}
+
+// serviceName returns --name or gml-<mode>[-<account>]
+func serviceName(cmd *cobra.Command, mode, account string) (string, error) {
+	name, _ := cmd.Flags().GetString("name")
+	if name == "" {
+		name = "gml-" + mode
+		if account != "" {
+			name += "-" + account
+		}
+	}
+	if strings.ContainsAny(name, "/ \t") {
+		return "", fmt.Errorf("invalid service name: %q", name)
+	}
+	return name, nil
+}
+
+// serviceEnv returns the environment passed to the service
+func serviceEnv() []string {
+	var env []string
+	for _, key := range []string{"PATH", "XDG_STATE_HOME"} {
+		if value := os.Getenv(key); value != "" {
+			env = append(env, key+"="+value)
+		}
+	}
+	return env
+}
+
+func init() {
+	rootCmd.AddCommand(serviceCmd)
+	serviceCmd.AddCommand(serviceInstallCmd)
+	serviceCmd.AddCommand(serviceUninstallCmd)
+
+	serviceInstallCmd.Flags().String("args", "", "Extra arguments for the daemon mode (quoted like a shell command line)")
+	serviceInstallCmd.Flags().String("name", "", "Service name (default gml-<mode>[-<account>])")
+	serviceInstallCmd.Flags().Bool("no-enable", false, "Write the service file without enabling or starting it")
+
+	serviceUninstallCmd.Flags().String("name", "", "Service name (default gml-<mode>[-<account>])")
+
+	// Set custom output to enable testing
+	serviceCmd.SetOut(os.Stdout)
+}
@@ -0,0 +1,463 @@
+/*
+Copyright © 2025 longkey1
+
+Licensed under the Apache License, Version 2.0 (the "License");
+you may not use this file except in compliance with the License.
+You may obtain a copy of the License at
+
+	http://www.apache.org/licenses/LICENSE-2.0
+
+Unless required by applicable law or agreed to in writing, software
+distributed under the License is distributed on an "AS IS" BASIS,
+WITHOUT WARRANTIES OR CONDITIONS OF ANY KIND, either express or implied.
+See the License for the specific language governing permissions and
+limitations under the License.
+*/
+package cmd
+
+import (
+	"fmt"
+	"io"
+	"os"
+
+	"github.com/longkey1/gml/internal/gml"
+	"github.com/spf13/cobra"
+)
+
+// settingsCmd represents the settings command
+var settingsCmd = &cobra.Command{
+	Use:   "settings",
+	Short: "View and update forwarding, IMAP and POP settings",
+	Long: `View and update auto-forwarding, IMAP and POP settings.
+
+Settings are printed as JSON by default so they can be diffed between
+accounts and applied with 'gml settings apply'. Updating IMAP and POP
+requires the settings.basic scope; forwarding requires settings.sharing.
+
+Examples:
+  gml settings get
+  gml --account work settings get > work.json
+  gml --account personal settings apply work.json`,
+}
+
+// settingsGetCmd represents the settings get command
+var settingsGetCmd = &cobra.Command{
+	Use:   "get",
+	Short: "Show forwarding, IMAP and POP settings",
+	Long: `Show forwarding, IMAP and POP settings.
+
+Examples:
+  gml settings get
+  gml settings get --format text`,
+	RunE: runSettingsGet,
+}
+
+// settingsApplyCmd represents the settings apply command
+var settingsApplyCmd = &cobra.Command{
+	Use:   "apply <file>",
+	Short: "Apply settings from JSON",
+	Long: `Apply settings from JSON written by 'gml settings get'.
+
+Only the sections present in the file (forwarding, imap, pop) are updated.
+Use "-" to read from stdin.
+
+Examples:
+  gml settings apply settings.json
+  gml --account work settings get | gml --account personal settings apply -`,
+	Args: cobra.ExactArgs(1),
+	RunE: runSettingsApply,
+}
+
+// settingsForwardingCmd represents the settings forwarding command
+var settingsForwardingCmd = &cobra.Command{
+	Use:   "forwarding",
+	Short: "Manage auto-forwarding",
+}
+
+// settingsForwardingGetCmd represents the settings forwarding get command
+var settingsForwardingGetCmd = &cobra.Command{
+	Use:   "get",
+	Short: "Show auto-forwarding settings",
+	RunE:  runSettingsForwardingGet,
+}
+
+// settingsForwardingSetCmd represents the settings forwarding set command
+var settingsForwardingSetCmd = &cobra.Command{
+	Use:   "set",
+	Short: "Enable auto-forwarding",
+	Long: `Enable auto-forwarding to a verified forwarding address.
+
+Dispositions: leaveInInbox, archive, trash, markRead
+
+Examples:
+  gml settings forwarding set --to archive@example.com
+  gml settings forwarding set --to archive@example.com --disposition archive`,
+	RunE: runSettingsForwardingSet,
+}
+
+// settingsForwardingDisableCmd represents the settings forwarding disable command
+var settingsForwardingDisableCmd = &cobra.Command{
+	Use:   "disable",
+	Short: "Disable auto-forwarding",
+	RunE:  runSettingsForwardingDisable,
+}
+
+// settingsImapCmd represents the settings imap command
+var settingsImapCmd = &cobra.Command{
+	Use:   "imap",
+	Short: "Manage IMAP access",
+}
+
+// settingsImapGetCmd represents the settings imap get command
+var settingsImapGetCmd = &cobra.Command{
+	Use:   "get",
+	Short: "Show IMAP settings",
+	RunE:  runSettingsImapGet,
+}
+
+// settingsImapSetCmd represents the settings imap set command
+var settingsImapSetCmd = &cobra.Command{
+	Use:   "set",
+	Short: "Update IMAP settings",
+	Long: `Update IMAP settings. Only the given flags are changed.
+
+Expunge behaviors: archive, trash, deleteForever
+
+Examples:
+  gml settings imap set --enabled
+  gml settings imap set --enabled=false
+  gml settings imap set --auto-expunge=false --expunge-behavior trash`,
+	RunE: runSettingsImapSet,
+}
+
+// settingsPopCmd represents the settings pop command
+var settingsPopCmd = &cobra.Command{
+	Use:   "pop",
+	Short: "Manage POP access",
+}
+
+// settingsPopGetCmd represents the settings pop get command
+var settingsPopGetCmd = &cobra.Command{
+	Use:   "get",
+	Short: "Show POP settings",
+	RunE:  runSettingsPopGet,
+}
+
+// settingsPopSetCmd represents the settings pop set command
+var settingsPopSetCmd = &cobra.Command{
+	Use:   "set",
+	Short: "Update POP settings",
+	Long: `Update POP settings. Only the given flags are changed.
+
+Access windows: disabled, fromNowOn, allMail
+Dispositions: leaveInInbox, archive, trash, markRead
+
+Examples:
+  gml settings pop set --access-window disabled
+  gml settings pop set --access-window fromNowOn --disposition archive`,
+	RunE: runSettingsPopSet,
+}
+
+func runSettingsGet(cmd *cobra.Command, args []string) error {
+	ctx := cmd.Context()
+	cfg, err := GetConfig(cmd)
+	if err != nil {
+		return err
+	}
+
+	// Get flags
+	format := formatFromFlags(cmd)
+
+	svc, err := gml.NewService(ctx, cfg)
+	if err != nil {
+		return fmt.Errorf("unable to create service: %w", err)
+	}
+
+	settings, err := gml.GetMailSettings(ctx, svc)
+	if err != nil {
+		return err
+	}
+
+	return formatSettings(cmd, settings, format)
+}
+
+func runSettingsApply(cmd *cobra.Command, args []string) error {
+	ctx := cmd.Context()
+	cfg, err := GetConfig(cmd)
+	if err != nil {
+		return err
+	}
+
+	// Get flags
+	format := formatFromFlags(cmd)
+
+	var r io.Reader = cmd.InOrStdin()
+	if args[0] != "-" {
+		f, err := os.Open(args[0])
+		if err != nil {
+			return fmt.Errorf("unable to open settings file: %w", err)
+		}
+		defer f.Close()
+		r = f
+	}
+	settings, err := gml.ReadMailSettings(r)
+	if err != nil {
+		return err
+	}
+
+	svc, err := gml.NewService(ctx, cfg)
+	if err != nil {
+		return fmt.Errorf("unable to create service: %w", err)
+	}
+
+	applied, err := gml.ApplyMailSettings(ctx, svc, settings)
+	if err != nil {
+		return err
+	}
+
+	return formatSettings(cmd, applied, format)
+}
+
+func runSettingsForwardingGet(cmd *cobra.Command, args []string) error {
+	ctx := cmd.Context()
+	cfg, err := GetConfig(cmd)
+	if err != nil {
+		return err
+	}
+
+	// Get flags
+	format := formatFromFlags(cmd)
+
+	svc, err := gml.NewService(ctx, cfg)
+	if err != nil {
+		return fmt.Errorf("unable to create service: %w", err)
+	}
+
+	forwarding, err := gml.GetForwarding(ctx, svc)
+	if err != nil {
+		return err
+	}
+
+	return formatSettings(cmd, &gml.MailSettings{Forwarding: forwarding}, format)
+}
+
+func runSettingsForwardingSet(cmd *cobra.Command, args []string) error {
+	ctx := cmd.Context()
+	cfg, err := GetConfig(cmd)
+	if err != nil {
+		return err
+	}
+
+	// Get flags
+	to, _ := cmd.Flags().GetString("to")
+	disposition, _ := cmd.Flags().GetString("disposition")
+	format := formatFromFlags(cmd)
+
+	svc, err := gml.NewService(ctx, cfg)
+	if err != nil {
+		return fmt.Errorf("unable to create service: %w", err)
+	}
+
+	forwarding, err := gml.SetForwarding(ctx, svc, &gml.ForwardingSettings{
+		Enabled:      true,
+		EmailAddress: to,
+		Disposition:  disposition,
+	})
+	if err != nil {
+		return err
+	}
+
+	return formatSettings(cmd, &gml.MailSettings{Forwarding: forwarding}, format)
+}
+
+func runSettingsForwardingDisable(cmd *cobra.Command, args []string) error {
+	ctx := cmd.Context()
+	cfg, err := GetConfig(cmd)
+	if err != nil {
+		return err
+	}
+
+	svc, err := gml.NewService(ctx, cfg)
+	if err != nil {
+		return fmt.Errorf("unable to create service: %w", err)
+	}
+
+	if _, err := gml.SetForwarding(ctx, svc, &gml.ForwardingSettings{Enabled: false}); err != nil {
+		return err
+	}
+
+	fmt.Fprintln(cmd.OutOrStdout(), "Auto-forwarding disabled")
+	return nil
+}
+
+func runSettingsImapGet(cmd *cobra.Command, args []string) error {
+	ctx := cmd.Context()
+	cfg, err := GetConfig(cmd)
+	if err != nil {
+		return err
+	}
+
+	// Get flags
+	format := formatFromFlags(cmd)
+
+	svc, err := gml.NewService(ctx, cfg)
+	if err != nil {
+		return fmt.Errorf("unable to create service: %w", err)
+	}
+
+	imap, err := gml.GetImap(ctx, svc)
+	if err != nil {
+		return err
+	}
+
+	return formatSettings(cmd, &gml.MailSettings{Imap: imap}, format)
+}
+
+func runSettingsImapSet(cmd *cobra.Command, args []string) error {
+	ctx := cmd.Context()
+	cfg, err := GetConfig(cmd)
+	if err != nil {
+		return err
+	}
+
+	// Get flags
+	format := formatFromFlags(cmd)
+
+	svc, err := gml.NewService(ctx, cfg)
+	if err != nil {
+		return fmt.Errorf("unable to create service: %w", err)
+	}
+
+	// Start from the current settings so unspecified flags are preserved
+	imap, err := gml.GetImap(ctx, svc)
+	if err != nil {
+		return err
+	}
+	flags := cmd.Flags()
+	if flags.Changed("enabled") {
+		imap.Enabled, _ = flags.GetBool("enabled")
+	}
+	if flags.Changed("auto-expunge") {
+		imap.AutoExpunge, _ = flags.GetBool("auto-expunge")
+	}
+	if flags.Changed("expunge-behavior") {
+		imap.ExpungeBehavior, _ = flags.GetString("expunge-behavior")
+	}
+	if flags.Changed("max-folder-size") {
+		imap.MaxFolderSize, _ = flags.GetInt64("max-folder-size")
+	}
+
+	updated, err := gml.SetImap(ctx, svc, imap)
+	if err != nil {
+		return err
+	}
+
+	return formatSettings(cmd, &gml.MailSettings{Imap: updated}, format)
+}
+
+func runSettingsPopGet(cmd *cobra.Command, args []string) error {
+	ctx := cmd.Context()
+	cfg, err := GetConfig(cmd)
+	if err != nil {
+		return err
+	}
+
+	// Get flags
+	format := formatFromFlags(cmd)
+
+	svc, err := gml.NewService(ctx, cfg)
+	if err != nil {
+		return fmt.Errorf("unable to create service: %w", err)
+	}
+
+	pop, err := gml.GetPop(ctx, svc)
+	if err != nil {
+		return err
+	}
+
+	return formatSettings(cmd, &gml.MailSettings{Pop: pop}, format)
+}
+
+func runSettingsPopSet(cmd *cobra.Command, args []string) error {
+	ctx := cmd.Context()
+	cfg, err := GetConfig(cmd)
+	if err != nil {
+		return err
+	}
+
+	// Get flags
+	format := formatFromFlags(cmd)
+
+	svc, err := gml.NewService(ctx, cfg)
+	if err != nil {
+		return fmt.Errorf("unable to create service: %w", err)
+	}
+
+	// Start from the current settings so unspecified flags are preserved
+	pop, err := gml.GetPop(ctx, svc)
+	if err != nil {
+		return err
+	}
+	flags := cmd.Flags()
+	if flags.Changed("access-window") {
+		pop.AccessWindow, _ = flags.GetString("access-window")
+	}
+	if flags.Changed("disposition") {
+		pop.Disposition, _ = flags.GetString("disposition")
+	}
+
+	updated, err := gml.SetPop(ctx, svc, pop)
+	if err != nil {
+		return err
+	}
+
+	return formatSettings(cmd, &gml.MailSettings{Pop: updated}, format)
+}
+
+// formatSettings writes settings to the command output
+func formatSettings(cmd *cobra.Command, settings *gml.MailSettings, format gml.OutputFormat) error {
+	if err := gml.FormatMailSettings(cmd.OutOrStdout(), settings, format); err != nil {
+		return fmt.Errorf("unable to format output: %w", err)
+	}
+	return nil
+}
+
+func init() {
+	rootCmd.AddCommand(settingsCmd)
+	settingsCmd.AddCommand(settingsGetCmd)
+	settingsCmd.AddCommand(settingsApplyCmd)
+	settingsCmd.AddCommand(settingsForwardingCmd)
+	settingsCmd.AddCommand(settingsImapCmd)
+	settingsCmd.AddCommand(settingsPopCmd)
+	settingsForwardingCmd.AddCommand(settingsForwardingGetCmd)
+	settingsForwardingCmd.AddCommand(settingsForwardingSetCmd)
+	settingsForwardingCmd.AddCommand(settingsForwardingDisableCmd)
+	settingsImapCmd.AddCommand(settingsImapGetCmd)
+	settingsImapCmd.AddCommand(settingsImapSetCmd)
+	settingsPopCmd.AddCommand(settingsPopGetCmd)
+	settingsPopCmd.AddCommand(settingsPopSetCmd)
+
+	for _, c := range []*cobra.Command{
+		settingsGetCmd, settingsApplyCmd,
+		settingsForwardingGetCmd, settingsForwardingSetCmd,
+		settingsImapGetCmd, settingsImapSetCmd,
+		settingsPopGetCmd, settingsPopSetCmd,
+	} {
+		setFormats(c, gml.OutputFormatJSON, gml.OutputFormatText)
+	}
+
+	settingsForwardingSetCmd.Flags().String("to", "", "Verified forwarding address")
+	settingsForwardingSetCmd.Flags().String("disposition", "leaveInInbox", "What to do with forwarded messages")
+	settingsForwardingSetCmd.MarkFlagRequired("to")
+
+	settingsImapSetCmd.Flags().Bool("enabled", false, "Enable IMAP access")
+	settingsImapSetCmd.Flags().Bool("auto-expunge", false, "Expunge messages immediately when marked deleted")
+	settingsImapSetCmd.Flags().String("expunge-behavior", "", "What to do with expunged messages")
+	settingsImapSetCmd.Flags().Int64("max-folder-size", 0, "Maximum messages per IMAP folder (0 for no limit)")
+
+	settingsPopSetCmd.Flags().String("access-window", "", "Which messages are available over POP")
+	settingsPopSetCmd.Flags().String("disposition", "", "What to do with messages after POP download")
+
+	// Set custom output to enable testing
+	settingsCmd.SetOut(os.Stdout)
+}
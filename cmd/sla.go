@@ -0,0 +1,131 @@
+/*
+Copyright © 2025 longkey1
+
+Licensed under the Apache License, Version 2.0 (the "License");
+you may not use this file except in compliance with the License.
+You may obtain a copy of the License at
+
+	http://www.apache.org/licenses/LICENSE-2.0
+
+Unless required by applicable law or agreed to in writing, software
+distributed under the License is distributed on an "AS IS" BASIS,
+WITHOUT WARRANTIES OR CONDITIONS OF ANY KIND, either express or implied.
+See the License for the specific language governing permissions and
+limitations under the License.
+*/
+package cmd
+
+import (
+	"context"
+	"fmt"
+	"os"
+
+	"github.com/longkey1/gml/internal/gml"
+	"github.com/spf13/cobra"
+)
+
+// exitCodeSLABreached is the exit status used by --exit-code when messages exceed the threshold
+const exitCodeSLABreached = 2
+
+// slaCmd represents the sla command
+var slaCmd = &cobra.Command{
+	Use:   "sla",
+	Short: "Report messages older than an age threshold",
+	Long: `Report messages matching a query that are older than an age threshold.
+
+Designed for alerting on shared inboxes: with --exit-code the command exits
+with status 2 when any message exceeds the threshold. Status 1 (or the
+status of the error kind) is reserved for errors; a message that can't be
+read fails the check instead of being left out. With --account all, status 3
+means some accounts couldn't be checked and none of the others breached.
+
+Age units: s, m, h (Go durations), d (days), w (weeks), y (365 days)
+
+Examples:
+  gml sla -q "label:support is:unread" --older-than 4h
+  gml sla -l INBOX --older-than 2d --exit-code
+  gml sla -q "is:unread" --older-than 1w --format json
+  gml sla --account all -l INBOX --older-than 1d  # Check every account`,
+	RunE: runSLA,
+}
+
+func runSLA(cmd *cobra.Command, args []string) error {
+	ctx := cmd.Context()
+	cfgs, err := GetAccountConfigs(cmd)
+	if err != nil {
+		return err
+	}
+
+	// Get flags
+	query, _ := cmd.Flags().GetString("query")
+	labels := labelsFromFlags(cmd)
+	olderThanStr, _ := cmd.Flags().GetString("older-than")
+	exitCode, _ := cmd.Flags().GetBool("exit-code")
+	outputFormat := formatFromFlags(cmd)
+
+	olderThan, err := gml.ParseAge(olderThanStr)
+	if err != nil {
+		return fmt.Errorf("invalid --older-than: %w", err)
+	}
+
+	// Find overdue messages (concurrently per account with --account all)
+	tagAccount, err := allAccountsSelected(cmd)
+	if err != nil {
+		return err
+	}
+	results := gml.ForEachAccount(ctx, cfgs, func(ctx context.Context, svc *gml.Service) ([]gml.OverdueMessage, error) {
+		return gml.FindOverdueMessages(ctx, svc, gml.SLAOptions{
+			Query:     query,
+			LabelIDs:  labels,
+			OlderThan: olderThan,
+		})
+	})
+	overdue, errs := gml.MergeAccountResults(results, func(m *gml.OverdueMessage, account string) {
+		if tagAccount {
+			m.Account = account
+		}
+	})
+	partial, err := reportAccountErrors(cmd, errs, len(cfgs))
+	if err != nil {
+		return fmt.Errorf("unable to check messages: %w", err)
+	}
+
+	if len(overdue) == 0 {
+		if outputFormat == gml.OutputFormatJSON {
+			fmt.Fprintln(cmd.OutOrStdout(), "[]")
+		} else {
+			fmt.Fprintf(cmd.OutOrStdout(), "No messages older than %s.\n", olderThanStr)
+		}
+		// An unreachable account may have overdue messages
+		return partial
+	}
+
+	// Output
+	if err := gml.FormatOverdueMessages(cmd.OutOrStdout(), overdue, outputFormat); err != nil {
+		return fmt.Errorf("unable to format output: %w", err)
+	}
+
+	if exitCode {
+		return &ExitError{
+			Code: exitCodeSLABreached,
+			Err:  fmt.Errorf("%d message(s) older than %s", len(overdue), olderThanStr),
+		}
+	}
+
+	return partial
+}
+
+func init() {
+	rootCmd.AddCommand(slaCmd)
+
+	slaCmd.Flags().StringP("query", "q", "", "Search query (Gmail search syntax)")
+	slaCmd.Flags().StringArrayP("label", "l", nil, "Filter by label (can be specified multiple times)")
+	addLabelIDFlag(slaCmd)
+	slaCmd.Flags().String("older-than", "", "Age threshold (e.g. 30m, 4h, 2d, 1w)")
+	slaCmd.Flags().Bool("exit-code", false, "Exit with status 2 when any message exceeds the threshold")
+	setFormats(slaCmd, gml.OutputFormatText, gml.OutputFormatJSON)
+	slaCmd.MarkFlagRequired("older-than")
+
+	// Set custom output to enable testing
+	slaCmd.SetOut(os.Stdout)
+}
@@ -0,0 +1,276 @@
+/*
+Copyright © 2025 longkey1
+
+Licensed under the Apache License, Version 2.0 (the "License");
+you may not use this file except in compliance with the License.
+You may obtain a copy of the License at
+
+	http://www.apache.org/licenses/LICENSE-2.0
+
+Unless required by applicable law or agreed to in writing, software
+distributed under the License is distributed on an "AS IS" BASIS,
+WITHOUT WARRANTIES OR CONDITIONS OF ANY KIND, either express or implied.
+See the License for the specific language governing permissions and
+limitations under the License.
+*/
+package cmd
+
+import (
+	"fmt"
+	"log/slog"
+	"os"
+	"os/signal"
+	"syscall"
+	"time"
+
+	"github.com/longkey1/gml/internal/gml"
+	"github.com/spf13/cobra"
+)
+
+// snoozeCmd represents the snooze command
+var snoozeCmd = &cobra.Command{
+	Use:   "snooze <message-id>... --until <time>",
+	Short: "Take messages out of the inbox until a later time",
+	Long: `Emulate Gmail's snooze, which the API doesn't offer: the messages are archived
+(INBOX removed) and recorded in a local schedule, and 'gml snooze run' puts
+them back in the inbox, marked unread, once they are due. Run it from cron or
+keep it running with --daemon (see 'gml service install snooze').
+
+--until accepts a duration (3h, 2d, in 1w), today, tonight, tomorrow or a
+weekday, optionally followed by a time of day (tomorrow 9am, fri 14:30), a
+time of day alone (9am: today, or tomorrow if it has passed) or a date
+(2025-07-01, 2025-07-01 13:00). A day without a time means 8:00.
+
+Message IDs are read from stdin when given as -. The schedule is kept per
+account in the state directory; snoozing a snoozed message moves its time.
+Requires the "modify" scope (see the scopes config option).
+
+Examples:
+  gml snooze 18abc123def456 --until "tomorrow 9am"
+  gml snooze 18abc123def456 --until 3h
+  gml list -q "from:boss@example.com" -f id --json | jq -r '.[].id' | gml snooze - --until monday
+  gml snooze list
+  gml snooze run                  # Wake due messages (e.g. from cron every 5 minutes)
+  gml snooze run --daemon         # Keep checking every minute
+  gml snooze cancel 18abc123def456`,
+	Args: cobra.MinimumNArgs(1),
+	RunE: runSnooze,
+}
+
+// snoozeListCmd represents the snooze list command
+var snoozeListCmd = &cobra.Command{
+	Use:   "list",
+	Short: "List snoozed messages",
+	Long: `List the snoozed messages of the schedule, soonest first.
+
+Examples:
+  gml snooze list
+  gml snooze list --json`,
+	Args: cobra.NoArgs,
+	RunE: runSnoozeList,
+}
+
+// snoozeRunCmd represents the snooze run command
+var snoozeRunCmd = &cobra.Command{
+	Use:   "run",
+	Short: "Return due snoozed messages to the inbox",
+	Long: `Return the snoozed messages that are due to the inbox, marked unread, and drop
+them from the schedule. Messages deleted in the meantime are dropped too.
+
+By default the schedule is checked once, for cron; with --daemon gml keeps
+checking every --interval until interrupted.
+
+Examples:
+  gml snooze run
+  gml snooze run --daemon --interval 30s
+  */5 * * * * gml snooze run     # crontab entry`,
+	Args: cobra.NoArgs,
+	RunE: runSnoozeRun,
+}
+
+// snoozeCancelCmd represents the snooze cancel command
+var snoozeCancelCmd = &cobra.Command{
+	Use:   "cancel <message-id>...",
+	Short: "Unsnooze messages now",
+	Long: `Drop messages from the snooze schedule and return them to the inbox right
+away; with --no-restore they stay archived.
+
+Examples:
+  gml snooze cancel 18abc123def456
+  gml snooze cancel 18abc123def456 --no-restore`,
+	Args: cobra.MinimumNArgs(1),
+	RunE: runSnoozeCancel,
+}
+
+func runSnooze(cmd *cobra.Command, args []string) error {
+	ctx := cmd.Context()
+	cfg, err := GetConfig(cmd)
+	if err != nil {
+		return err
+	}
+
+	// Get flags
+	untilStr, _ := cmd.Flags().GetString("until")
+	if untilStr == "" {
+		return fmt.Errorf("--until is required")
+	}
+	until, err := gml.ParseWhen(untilStr, time.Now())
+	if err != nil {
+		return err
+	}
+	ids, err := messageIDArgs(cmd, args)
+	if err != nil {
+		return err
+	}
+	path, err := gml.StatePath(gml.SnoozeStoreName, cfg.Account)
+	if err != nil {
+		return err
+	}
+
+	// Create service
+	svc, err := gml.NewService(ctx, cfg)
+	if err != nil {
+		return fmt.Errorf("unable to create service: %w", err)
+	}
+
+	failures := failureLogFromFlags(cmd)
+	snoozed, err := gml.SnoozeMessages(ctx, svc, path, ids, until, failures)
+	if err != nil {
+		return err
+	}
+
+	// Output
+	if formatFromFlags(cmd) == gml.OutputFormatJSON {
+		if err := gml.FormatSnoozed(cmd.OutOrStdout(), snoozed, gml.OutputFormatJSON); err != nil {
+			return err
+		}
+	} else {
+		fmt.Fprintf(cmd.OutOrStdout(), "Snoozed %d messages until %s\n", len(snoozed), until.Local().Format("Mon 2006-01-02 15:04"))
+	}
+	return reportFailures(cmd, failures)
+}
+
+func runSnoozeList(cmd *cobra.Command, args []string) error {
+	cfg, err := GetConfig(cmd)
+	if err != nil {
+		return err
+	}
+
+	path, err := gml.StatePath(gml.SnoozeStoreName, cfg.Account)
+	if err != nil {
+		return err
+	}
+	schedule, err := gml.LoadSnoozeSchedule(path)
+	if err != nil {
+		return err
+	}
+
+	// Output
+	if len(schedule.Messages) == 0 && formatFromFlags(cmd) == gml.OutputFormatText {
+		fmt.Fprintln(cmd.OutOrStdout(), "No snoozed messages.")
+		return nil
+	}
+	return gml.FormatSnoozed(cmd.OutOrStdout(), schedule.Messages, formatFromFlags(cmd))
+}
+
+func runSnoozeRun(cmd *cobra.Command, args []string) error {
+	ctx, stop := signal.NotifyContext(cmd.Context(), os.Interrupt, syscall.SIGTERM)
+	defer stop()
+	cfg, err := GetConfig(cmd)
+	if err != nil {
+		return err
+	}
+
+	// Get flags
+	daemon, _ := cmd.Flags().GetBool("daemon")
+	interval, _ := cmd.Flags().GetDuration("interval")
+	if daemon && interval < time.Second {
+		return fmt.Errorf("--interval must be at least 1s")
+	}
+	path, err := gml.StatePath(gml.SnoozeStoreName, cfg.Account)
+	if err != nil {
+		return err
+	}
+
+	// Create service
+	svc, err := gml.NewService(ctx, cfg)
+	if err != nil {
+		return fmt.Errorf("unable to create service: %w", err)
+	}
+
+	wake := func() error {
+		woken, err := gml.WakeSnoozed(ctx, svc, path, time.Now())
+		for _, m := range woken {
+			fmt.Fprintf(cmd.OutOrStdout(), "Woke %s: %s\n", m.ID, m.Subject)
+		}
+		return err
+	}
+	if !daemon {
+		return wake()
+	}
+
+	backoff := &gml.Backoff{Interval: interval, Max: max(30*time.Minute, interval)}
+	timer := time.NewTimer(0)
+	defer timer.Stop()
+	for {
+		select {
+		case <-ctx.Done():
+			return nil
+		case <-timer.C:
+			err := wake()
+			if ctx.Err() != nil {
+				return nil
+			}
+			delay := backoff.Next(err)
+			if err != nil {
+				slog.Warn("snooze run failed", "err", err, "retry", delay)
+			}
+			timer.Reset(delay)
+		}
+	}
+}
+
+func runSnoozeCancel(cmd *cobra.Command, args []string) error {
+	ctx := cmd.Context()
+	cfg, err := GetConfig(cmd)
+	if err != nil {
+		return err
+	}
+
+	// Get flags
+	noRestore, _ := cmd.Flags().GetBool("no-restore")
+	path, err := gml.StatePath(gml.SnoozeStoreName, cfg.Account)
+	if err != nil {
+		return err
+	}
+
+	// Create service
+	svc, err := gml.NewService(ctx, cfg)
+	if err != nil {
+		return fmt.Errorf("unable to create service: %w", err)
+	}
+
+	canceled, err := gml.CancelSnooze(ctx, svc, path, args, !noRestore)
+	for _, m := range canceled {
+		fmt.Fprintf(cmd.OutOrStdout(), "Unsnoozed %s: %s\n", m.ID, m.Subject)
+	}
+	return err
+}
+
+func init() {
+	rootCmd.AddCommand(snoozeCmd)
+	snoozeCmd.AddCommand(snoozeListCmd)
+	snoozeCmd.AddCommand(snoozeRunCmd)
+	snoozeCmd.AddCommand(snoozeCancelCmd)
+
+	snoozeCmd.Flags().String("until", "", "When the messages return to the inbox (e.g. 3h, tomorrow 9am, monday, 2025-07-01 14:00)")
+	addIgnoreErrorsFlag(snoozeCmd)
+	setFormats(snoozeCmd, gml.OutputFormatText, gml.OutputFormatJSON)
+	setFormats(snoozeListCmd, gml.OutputFormatText, gml.OutputFormatJSON)
+	snoozeRunCmd.Flags().Bool("daemon", false, "Keep running and check the schedule every --interval")
+	snoozeRunCmd.Flags().Duration("interval", time.Minute, "How often to check the schedule with --daemon")
+	snoozeCancelCmd.Flags().Bool("no-restore", false, "Leave the messages archived")
+
+	// Set custom output to enable testing
+	snoozeCmd.SetOut(os.Stdout)
+}
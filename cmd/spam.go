@@ -0,0 +1,109 @@
+/*
+Copyright © 2025 longkey1
+
+Licensed under the Apache License, Version 2.0 (the "License");
+you may not use this file except in compliance with the License.
+You may obtain a copy of the License at
+
+	http://www.apache.org/licenses/LICENSE-2.0
+
+Unless required by applicable law or agreed to in writing, software
+distributed under the License is distributed on an "AS IS" BASIS,
+WITHOUT WARRANTIES OR CONDITIONS OF ANY KIND, either express or implied.
+See the License for the specific language governing permissions and
+limitations under the License.
+*/
+package cmd
+
+import (
+	"fmt"
+	"os"
+
+	"github.com/longkey1/gml/internal/gml"
+	"github.com/spf13/cobra"
+)
+
+// spamCmd represents the spam command
+var spamCmd = &cobra.Command{
+	Use:   "spam <message-id>...",
+	Short: "Report messages as spam",
+	Long: `Move messages to spam (adds SPAM and removes INBOX).
+
+Message IDs are read from stdin when given as -, so spam triage can be
+driven by filters or external classifiers.
+Requires the "modify" scope (see the scopes config option).
+
+Examples:
+  gml spam 18abc123def456 18abc123def789
+  gml list -q "from:offers@example.com" --format template --template '{{.ID}}' | gml spam -`,
+	Args: cobra.MinimumNArgs(1),
+	RunE: func(cmd *cobra.Command, args []string) error {
+		return runSpam(cmd, args, true)
+	},
+}
+
+// notSpamCmd represents the not-spam command
+var notSpamCmd = &cobra.Command{
+	Use:   "not-spam <message-id>...",
+	Short: "Move messages out of spam and back to the inbox",
+	Long: `Move messages out of spam and back to the inbox (removes SPAM and adds INBOX).
+
+Message IDs are read from stdin when given as -.
+Requires the "modify" scope (see the scopes config option).
+
+Examples:
+  gml not-spam 18abc123def456
+  gml list -l SPAM -q "from:example.com" --format template --template '{{.ID}}' | gml not-spam -`,
+	Args: cobra.MinimumNArgs(1),
+	RunE: func(cmd *cobra.Command, args []string) error {
+		return runSpam(cmd, args, false)
+	},
+}
+
+func runSpam(cmd *cobra.Command, args []string, spam bool) error {
+	ctx := cmd.Context()
+	cfg, err := GetConfig(cmd)
+	if err != nil {
+		return err
+	}
+
+	ids, err := messageIDArgs(cmd, args)
+	if err != nil {
+		return err
+	}
+
+	// Create service
+	svc, err := gml.NewService(ctx, cfg)
+	if err != nil {
+		return fmt.Errorf("unable to create service: %w", err)
+	}
+
+	failures := failureLogFromFlags(cmd)
+	if err := gml.ReportSpam(ctx, svc, ids, spam, failures); err != nil {
+		return fmt.Errorf("unable to modify messages: %w", err)
+	}
+
+	// Output
+	moved := len(ids) - len(failures.Items)
+	done, pending := "Moved %d messages to the inbox", "Would move %d messages to the inbox"
+	if spam {
+		done, pending = "Moved %d messages to spam", "Would move %d messages to spam"
+	}
+	summary := done
+	if gml.IsDryRun(ctx) {
+		summary = pending
+	}
+	fmt.Fprintf(cmd.OutOrStdout(), summary+"\n", moved)
+
+	return reportFailures(cmd, failures)
+}
+
+func init() {
+	for _, c := range []*cobra.Command{spamCmd, notSpamCmd} {
+		rootCmd.AddCommand(c)
+		addIgnoreErrorsFlag(c)
+
+		// Set custom output to enable testing
+		c.SetOut(os.Stdout)
+	}
+}
@@ -0,0 +1,240 @@
+/*
+Copyright © 2025 longkey1
+
+Licensed under the Apache License, Version 2.0 (the "License");
+you may not use this file except in compliance with the License.
+You may obtain a copy of the License at
+
+	http://www.apache.org/licenses/LICENSE-2.0
+
+Unless required by applicable law or agreed to in writing, software
+distributed under the License is distributed on an "AS IS" BASIS,
+WITHOUT WARRANTIES OR CONDITIONS OF ANY KIND, either express or implied.
+See the License for the specific language governing permissions and
+limitations under the License.
+*/
+package cmd
+
+import (
+	"fmt"
+	"log/slog"
+	"os"
+
+	"github.com/longkey1/gml/internal/gml"
+	"github.com/spf13/cobra"
+)
+
+// statsCmd represents the stats command
+var statsCmd = &cobra.Command{
+	Use:   "stats",
+	Short: "Count messages by sender, domain, label, day or month",
+	Long: `Aggregate the messages matching a query by sender, domain, label, day or
+month, largest groups first.
+
+All matching messages are crawled, with several requests in parallel. The
+sender and date of each message are cached, so later runs over the same mail
+only fetch new messages; grouping by label always fetches, since labels change.
+
+Examples:
+  gml stats --by sender -q "newer_than:90d"   # Top senders of the last 90 days
+  gml stats --by domain -l INBOX -n 50
+  gml stats --by month -q "from:billing@example.com"
+  gml stats --by label --saved receipts
+  gml stats --by sender --max-messages 5000 --json
+  gml stats --by domain --format csv -n 0 > domains.csv`,
+	Args: cobra.NoArgs,
+	RunE: runStats,
+}
+
+// statsEngagementCmd represents the stats engagement command
+var statsEngagementCmd = &cobra.Command{
+	Use:   "engagement",
+	Short: "Report replies, read receipts and bounces per recipient of sent threads",
+	Long: `Inspect the sent threads matching a query, e.g. the messages of a mail merge
+labeled "campaign", and report for every recipient what came back:
+
+  replied       the recipient answered in the thread
+  auto-replied  only an automatic answer (out of office, ...) arrived
+  read          the recipient's mail client sent a read receipt
+  bounced       a delivery status notification reported a failure
+  no-response   nothing came back
+
+Opens are not tracked, so read receipts are the only read signal. Bounces are
+matched to the sent message by its Message-ID when the notification quotes
+it, otherwise by the recipient address. Only threads with a sent message are
+inspected ("in:sent" is added to the query).
+
+Examples:
+  gml stats engagement -q "label:campaign"
+  gml stats engagement -q "label:campaign subject:Invitation" --json
+  gml stats engagement -l campaign-2025-06 --format csv > outcomes.csv
+  gml stats engagement -q "label:campaign" --json | jq '.summary'`,
+	Args: cobra.NoArgs,
+	RunE: runStatsEngagement,
+}
+
+func runStats(cmd *cobra.Command, args []string) error {
+	ctx := cmd.Context()
+	cfg, err := GetConfig(cmd)
+	if err != nil {
+		return err
+	}
+
+	// Get flags
+	byStr, _ := cmd.Flags().GetString("by")
+	limit, _ := cmd.Flags().GetInt("limit")
+	maxMessages, _ := cmd.Flags().GetInt("max-messages")
+	concurrency, _ := cmd.Flags().GetInt("concurrency")
+	noCache, _ := cmd.Flags().GetBool("no-cache")
+
+	by, err := gml.ParseGroupBy(byStr)
+	if err != nil {
+		return err
+	}
+	query, labels, err := queryFromFlags(cmd)
+	if err != nil {
+		return err
+	}
+	if query, labels, err = savedSearchFromFlags(cmd, query, labels); err != nil {
+		return err
+	}
+	if query, err = searchQueryFromFlags(cmd, query); err != nil {
+		return err
+	}
+	csvOpts, err := csvOptionsFromFlags(cmd, gml.CSVOptions{})
+	if err != nil {
+		return err
+	}
+
+	var cache *gml.MetadataCache
+	if !noCache {
+		path, err := gml.StatePath(gml.StatsCacheName, cfg.Account)
+		if err != nil {
+			return err
+		}
+		if cache, err = gml.LoadMetadataCache(path); err != nil {
+			return err
+		}
+	}
+
+	// Create service
+	svc, err := gml.NewService(ctx, cfg)
+	if err != nil {
+		return fmt.Errorf("unable to create service: %w", err)
+	}
+
+	progress := newProgress(cmd, "Fetching messages")
+	stats, err := gml.MailboxStats(ctx, svc, gml.StatsOptions{
+		Query:       query,
+		LabelIDs:    labels,
+		By:          by,
+		Limit:       limit,
+		MaxMessages: maxMessages,
+		Concurrency: concurrency,
+		Cache:       cache,
+		Progress:    progress.Update,
+	})
+	progress.Done()
+	if cache != nil {
+		// Keep what was fetched even if the crawl failed part way
+		if err := cache.Save(); err != nil {
+			slog.Warn("unable to save stats cache", "err", err)
+		}
+	}
+	if err != nil {
+		return err
+	}
+
+	// Output
+	if err := gml.FormatStats(cmd.OutOrStdout(), stats, formatFromFlags(cmd), csvOpts); err != nil {
+		return fmt.Errorf("unable to format output: %w", err)
+	}
+	return nil
+}
+
+func runStatsEngagement(cmd *cobra.Command, args []string) error {
+	ctx := cmd.Context()
+	cfg, err := GetConfig(cmd)
+	if err != nil {
+		return err
+	}
+
+	// Get flags
+	query, labels, err := queryFromFlags(cmd)
+	if err != nil {
+		return err
+	}
+	if query, labels, err = savedSearchFromFlags(cmd, query, labels); err != nil {
+		return err
+	}
+	if query, err = searchQueryFromFlags(cmd, query); err != nil {
+		return err
+	}
+	if query == "" && len(labels) == 0 {
+		return fmt.Errorf("a query or label selecting the campaign is required")
+	}
+	csvOpts, err := csvOptionsFromFlags(cmd, gml.CSVOptions{})
+	if err != nil {
+		return err
+	}
+
+	// Create service
+	svc, err := gml.NewService(ctx, cfg)
+	if err != nil {
+		return fmt.Errorf("unable to create service: %w", err)
+	}
+
+	progress := newProgress(cmd, "Fetching threads")
+	report, err := gml.Engagement(ctx, svc, gml.EngagementOptions{
+		Query:    query,
+		LabelIDs: labels,
+		Progress: progress.Update,
+	})
+	progress.Done()
+	if err != nil {
+		return err
+	}
+
+	// Output
+	if err := gml.FormatEngagement(cmd.OutOrStdout(), report, formatFromFlags(cmd), csvOpts); err != nil {
+		return fmt.Errorf("unable to format output: %w", err)
+	}
+	return nil
+}
+
+func init() {
+	rootCmd.AddCommand(statsCmd)
+	statsCmd.AddCommand(statsEngagementCmd)
+
+	statsCmd.Flags().String("by", string(gml.GroupBySender), "Group by sender, domain, label, day or month")
+	statsCmd.Flags().StringP("query", "q", "", "Search query (Gmail search syntax)")
+	statsCmd.Flags().StringArrayP("label", "l", nil, "Filter by label (can be specified multiple times)")
+	addQueryFlags(statsCmd)
+	addSearchFlags(statsCmd)
+	addSavedFlag(statsCmd)
+	statsCmd.Flags().IntP("limit", "n", 20, "Show the largest groups only (0 for all)")
+	statsCmd.Flags().Int("max-messages", 0, "Stop after this many messages, newest first (0 for all)")
+	statsCmd.Flags().Int("concurrency", 8, "Parallel metadata requests")
+	statsCmd.Flags().Bool("no-cache", false, "Fetch every message instead of using the cached senders and dates")
+	setFormats(statsCmd, gml.OutputFormatText, gml.OutputFormatJSON, gml.OutputFormatCSV, gml.OutputFormatTSV)
+	addCSVFlags(statsCmd)
+	_ = statsCmd.RegisterFlagCompletionFunc("by", func(c *cobra.Command, args []string, toComplete string) ([]string, cobra.ShellCompDirective) {
+		var names []string
+		for _, by := range gml.GroupBys {
+			names = append(names, string(by))
+		}
+		return names, cobra.ShellCompDirectiveNoFileComp
+	})
+
+	statsEngagementCmd.Flags().StringP("query", "q", "", "Search query selecting the campaign threads (Gmail search syntax)")
+	statsEngagementCmd.Flags().StringArrayP("label", "l", nil, "Filter by label (can be specified multiple times)")
+	addQueryFlags(statsEngagementCmd)
+	addSearchFlags(statsEngagementCmd)
+	addSavedFlag(statsEngagementCmd)
+	setFormats(statsEngagementCmd, gml.OutputFormatText, gml.OutputFormatJSON, gml.OutputFormatCSV, gml.OutputFormatTSV)
+	addCSVFlags(statsEngagementCmd)
+
+	// Set custom output to enable testing
+	statsCmd.SetOut(os.Stdout)
+	statsEngagementCmd.SetOut(os.Stdout)
+}
@@ -0,0 +1,203 @@
+/*
+Copyright © 2025 longkey1
+
+Licensed under the Apache License, Version 2.0 (the "License");
+you may not use this file except in compliance with the License.
+You may obtain a copy of the License at
+
+	http://www.apache.org/licenses/LICENSE-2.0
+
+Unless required by applicable law or agreed to in writing, software
+distributed under the License is distributed on an "AS IS" BASIS,
+WITHOUT WARRANTIES OR CONDITIONS OF ANY KIND, either express or implied.
+See the License for the specific language governing permissions and
+limitations under the License.
+*/
+package cmd
+
+import (
+	"encoding/json"
+	"fmt"
+	"log/slog"
+	"os"
+	"os/signal"
+	"syscall"
+	"time"
+
+	"github.com/longkey1/gml/internal/gml"
+	"github.com/spf13/cobra"
+)
+
+// tailStateName is the state file prefix for the history ID of tail --follow
+const tailStateName = "tail"
+
+// tailCmd represents the tail command
+var tailCmd = &cobra.Command{
+	Use:   "tail",
+	Short: "Show the latest messages and optionally follow new ones",
+	Long: `Show the latest messages as JSON lines, oldest first, like tail(1).
+
+With --follow, gml keeps polling the Gmail history API every --interval and
+appends each new message as it arrives. The last processed history ID is
+stored per account and label in $XDG_STATE_HOME/gml (default
+~/.local/state/gml), so a restarted tail continues where it stopped without
+repeating the backlog. If the stored history ID is too old for Gmail, tail
+warns and continues from the current mailbox state.
+
+Quota and server errors are retried with exponential backoff up to
+--max-backoff instead of aborting, so tail can run unattended for months.
+
+With --exec-per-message, the command is run through 'sh -c' for every message
+instead of printing it, with the fields in GML_ID, GML_THREAD_ID, GML_FROM,
+GML_TO, GML_SUBJECT, GML_DATE, GML_SNIPPET, GML_LABELS and GML_URL and the
+message JSON on stdin. Failing commands are logged and skipped.
+
+With --output, lines are appended to a file that is reopened when it is
+renamed or removed, so logrotate can rotate it without copytruncate.
+
+Examples:
+  gml tail                        # Last 10 messages
+  gml tail -l SENT -n 50
+  gml tail -l SENT --follow --output sent.log  # Compliance log of sent mail
+  gml tail -f id,from,to,subject,date --follow --interval 5m
+  gml tail -l INBOX --follow --exec-per-message 'notify-send "$GML_FROM" "$GML_SUBJECT"'`,
+	Args: cobra.NoArgs,
+	RunE: runTail,
+}
+
+func runTail(cmd *cobra.Command, args []string) error {
+	ctx, stop := signal.NotifyContext(cmd.Context(), os.Interrupt, syscall.SIGTERM)
+	defer stop()
+	cfg, err := GetConfig(cmd)
+	if err != nil {
+		return err
+	}
+
+	// Get flags
+	label, _ := cmd.Flags().GetString("label")
+	count, _ := cmd.Flags().GetInt64("lines")
+	output, _ := cmd.Flags().GetString("output")
+	follow, _ := cmd.Flags().GetBool("follow")
+	interval, _ := cmd.Flags().GetDuration("interval")
+	maxBackoff, _ := cmd.Flags().GetDuration("max-backoff")
+
+	if follow && interval < time.Second {
+		return fmt.Errorf("--interval must be at least 1s")
+	}
+	fields, err := fieldsFromFlags(cmd, gml.MessageFields)
+	if err != nil {
+		return err
+	}
+
+	// Create service
+	svc, err := gml.NewService(ctx, cfg)
+	if err != nil {
+		return fmt.Errorf("unable to create service: %w", err)
+	}
+
+	out := cmd.OutOrStdout()
+	if output != "" {
+		w, err := gml.OpenAppendWriter(output)
+		if err != nil {
+			return err
+		}
+		defer w.Close()
+		out = w
+	}
+	enc := json.NewEncoder(out)
+	exec := messageExecFromFlags(cmd, out)
+	emit := func(msg gml.MessageInfo) error {
+		msg.Account = cfg.Account
+		if exec != nil {
+			// A failing command shouldn't stop a follow
+			exec.run(ctx, msg)
+			return nil
+		}
+		if err := enc.Encode(msg); err != nil {
+			return fmt.Errorf("unable to write message: %w", err)
+		}
+		return nil
+	}
+
+	statePath, err := gml.StatePath(tailStateName+"-"+gml.QueryLabelName(label), cfg.Account)
+	if err != nil {
+		return err
+	}
+	var state gml.WatchState
+	resumed, err := gml.LoadState(statePath, &state)
+	if err != nil {
+		return err
+	}
+
+	// Record the history ID before fetching the backlog so nothing arriving
+	// in between is lost
+	var notifier *gml.MessageNotifier
+	if follow {
+		if notifier, err = gml.NewMessageNotifier(ctx, svc, statePath, label, fields, emit); err != nil {
+			return err
+		}
+	}
+
+	// A resumed follow already wrote the backlog; repeating it would
+	// duplicate lines in the log
+	if !follow || !resumed {
+		messages, err := gml.LatestMessages(ctx, svc, label, count, fields)
+		if err != nil {
+			return err
+		}
+		for _, msg := range messages {
+			if err := emit(msg); err != nil {
+				return err
+			}
+		}
+	}
+	if !follow {
+		if exec != nil {
+			return exec.err()
+		}
+		return nil
+	}
+
+	backoff := &gml.Backoff{Interval: interval, Max: max(maxBackoff, interval)}
+	timer := time.NewTimer(interval)
+	defer timer.Stop()
+	for {
+		select {
+		case <-ctx.Done():
+			return nil
+		case <-timer.C:
+			_, err := notifier.Check(ctx)
+			if err != nil {
+				if ctx.Err() != nil {
+					return nil
+				}
+				if gml.IsHistoryExpired(err) {
+					slog.Warn("history expired, continuing from the current mailbox state", "err", err)
+					err = notifier.Resync(ctx)
+				}
+			}
+			delay := backoff.Next(err)
+			if err != nil {
+				slog.Warn("poll failed", "err", err, "retry", delay)
+			}
+			timer.Reset(delay)
+		}
+	}
+}
+
+func init() {
+	rootCmd.AddCommand(tailCmd)
+
+	tailCmd.Flags().StringP("label", "l", "", "Only show messages with this label")
+	tailCmd.Flags().Int64P("lines", "n", 10, "Number of latest messages to show")
+	addFieldsFlag(tailCmd, gml.MessageFields, defaultFields)
+	addExecPerMessageFlag(tailCmd)
+	tailCmd.Flags().StringP("output", "o", "", "Append to this file instead of stdout, reopening it after rotation")
+	tailCmd.Flags().BoolP("follow", "F", false, "Keep polling and append new messages as they arrive")
+	tailCmd.Flags().Duration("interval", time.Minute, "Polling interval with --follow")
+	tailCmd.Flags().Duration("max-backoff", 30*time.Minute, "Longest wait between retries after API errors")
+	setFormats(tailCmd, gml.OutputFormatNDJSON)
+
+	// Set custom output to enable testing
+	tailCmd.SetOut(os.Stdout)
+}
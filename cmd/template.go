@@ -0,0 +1,107 @@
+/*
+Copyright © 2025 longkey1
+
+Licensed under the Apache License, Version 2.0 (the "License");
+you may not use this file except in compliance with the License.
+You may obtain a copy of the License at
+
+	http://www.apache.org/licenses/LICENSE-2.0
+
+Unless required by applicable law or agreed to in writing, software
+distributed under the License is distributed on an "AS IS" BASIS,
+WITHOUT WARRANTIES OR CONDITIONS OF ANY KIND, either express or implied.
+See the License for the specific language governing permissions and
+limitations under the License.
+*/
+package cmd
+
+import (
+	"fmt"
+	"os"
+	"strings"
+	"text/template"
+
+	"github.com/longkey1/gml/internal/gml"
+	"github.com/spf13/cobra"
+)
+
+// templateEscapes expands the escapes users type in a shell-quoted --template
+var templateEscapes = strings.NewReplacer(`\t`, "\t", `\n`, "\n", `\r`, "\r")
+
+// addTemplateFlags adds --template and --template-file for --format template
+func addTemplateFlags(cmd *cobra.Command) {
+	cmd.Flags().String("template", "", `Go template per message for --format template (\t and \n are expanded)`)
+	cmd.Flags().String("template-file", "", "Read the --format template from a file")
+}
+
+// outputFormatFromFlags returns --format, switching the default to template
+// when --template or --template-file is given, and the parsed template if any
+func outputFormatFromFlags(cmd *cobra.Command) (gml.OutputFormat, *template.Template, string, error) {
+	outputFormat := formatFromFlags(cmd)
+	text, _ := cmd.Flags().GetString("template")
+	file, _ := cmd.Flags().GetString("template-file")
+
+	if text != "" && file != "" {
+		return "", nil, "", fmt.Errorf("--template and --template-file are mutually exclusive")
+	}
+	if file != "" {
+		data, err := os.ReadFile(file)
+		if err != nil {
+			return "", nil, "", fmt.Errorf("unable to read template file: %w", err)
+		}
+		text = string(data)
+	} else {
+		text = templateEscapes.Replace(text)
+	}
+
+	if text != "" && !formatChanged(cmd) {
+		outputFormat = gml.OutputFormatTemplate
+	}
+	if outputFormat != gml.OutputFormatTemplate {
+		if text != "" {
+			return "", nil, "", fmt.Errorf("--template requires --format template")
+		}
+		return outputFormat, nil, "", nil
+	}
+	if text == "" {
+		return "", nil, "", fmt.Errorf("--format template requires --template or --template-file")
+	}
+
+	tmpl, err := gml.ParseOutputTemplate(text)
+	if err != nil {
+		return "", nil, "", err
+	}
+	return outputFormat, tmpl, text, nil
+}
+
+// templateFieldNames maps MessageInfo template fields to list field names
+var templateFieldNames = map[string]string{
+	".Account":     "account",
+	".ID":          "id",
+	".ThreadID":    "threadid",
+	".Type":        "type",
+	".URL":         "url",
+	".From":        "from",
+	".To":          "to",
+	".Subject":     "subject",
+	".Date":        "date",
+	".Size":        "size",
+	".Snippet":     "snippet",
+	".Labels":      "labels",
+	".Attachments": "attachments",
+	".Headers":     "headers",
+	".Auth":        "auth",
+	".Body":        "body",
+}
+
+// templateFields returns the fields a template refers to, so only those are
+// fetched; it returns "" if none are recognized
+func templateFields(text string) string {
+	var fields []string
+	for ref, name := range templateFieldNames {
+		if strings.Contains(text, ref) {
+			fields = append(fields, name)
+		}
+	}
+	return strings.Join(fields, ",")
+}
@@ -0,0 +1,317 @@
+/*
+Copyright © 2025 longkey1
+
+Licensed under the Apache License, Version 2.0 (the "License");
+you may not use this file except in compliance with the License.
+You may obtain a copy of the License at
+
+	http://www.apache.org/licenses/LICENSE-2.0
+
+Unless required by applicable law or agreed to in writing, software
+distributed under the License is distributed on an "AS IS" BASIS,
+WITHOUT WARRANTIES OR CONDITIONS OF ANY KIND, either express or implied.
+See the License for the specific language governing permissions and
+limitations under the License.
+*/
+package cmd
+
+import (
+	"fmt"
+	"os"
+	"time"
+
+	"github.com/longkey1/gml/internal/gml"
+	"github.com/spf13/cobra"
+)
+
+// threadCmd represents the thread command
+var threadCmd = &cobra.Command{
+	Use:   "thread",
+	Short: "Work with conversations",
+	Long:  `Work with Gmail conversations (threads) instead of single messages.`,
+}
+
+// threadListCmd represents the thread list command
+var threadListCmd = &cobra.Command{
+	Use:   "list",
+	Short: "List conversations",
+	Long: `List conversations matching a query or labels, newest first. From and
+subject are those of the first message, date is that of the latest one and
+participants are everyone who wrote in the thread. Also available as 'gml threads'.
+
+Available fields: ` + gml.ThreadFields.String() + `
+
+Examples:
+  gml thread list                           # Latest 10 threads
+  gml thread list -q "from:alice" -n 50
+  gml thread list -l INBOX -f id,subject,messages,date
+  gml thread list -f id,subject,url --url-style message  # Link to the newest message
+  gml thread list --format csv > threads.csv`,
+	Args: cobra.NoArgs,
+	RunE: runThreadList,
+}
+
+// threadsCmd lists conversations like 'gml thread list', next to 'gml list'
+var threadsCmd = &cobra.Command{
+	Use:   "threads",
+	Short: "List conversations",
+	Long: `List conversations matching a query or labels, newest first, with the
+thread-level summary 'gml list' lacks: message count, participants, the
+subject of the first message and the date of the latest one. Same as
+'gml thread list'.
+
+Available fields: ` + gml.ThreadFields.String() + `
+
+Examples:
+  gml threads -l INBOX                      # Latest 10 conversations in the inbox
+  gml threads -q "is:unread" -n 50 -f id,subject,participants,messages,date
+  gml threads --saved work --format markdown
+  gml threads --from alice@example.com --after 2025-01-01 --json`,
+	Args: cobra.NoArgs,
+	RunE: runThreadList,
+}
+
+func runThreadList(cmd *cobra.Command, args []string) error {
+	ctx := cmd.Context()
+	cfg, err := GetConfig(cmd)
+	if err != nil {
+		return err
+	}
+
+	// Get flags
+	maxResults, _ := cmd.Flags().GetInt64("max-results")
+
+	query, labels, err := queryFromFlags(cmd)
+	if err != nil {
+		return err
+	}
+	if query, labels, err = savedSearchFromFlags(cmd, query, labels); err != nil {
+		return err
+	}
+	if query, err = searchQueryFromFlags(cmd, query); err != nil {
+		return err
+	}
+	fields, err := fieldsFromFlags(cmd, gml.ThreadFields)
+	if err != nil {
+		return err
+	}
+	dateFormat, err := dateFormatFromFlags(cmd)
+	if err != nil {
+		return err
+	}
+	urlStyle, err := urlStyleFromFlags(cmd)
+	if err != nil {
+		return err
+	}
+	csvOpts, err := csvOptionsFromFlags(cmd, gml.CSVOptions{})
+	if err != nil {
+		return err
+	}
+
+	// Create service
+	svc, err := gml.NewService(ctx, cfg)
+	if err != nil {
+		return fmt.Errorf("unable to create service: %w", err)
+	}
+
+	progress := newProgress(cmd, "Fetching threads")
+	threads, err := gml.ListThreads(ctx, svc, gml.ListThreadsOptions{
+		Query:      query,
+		MaxResults: maxResults,
+		LabelIDs:   labels,
+		Fields:     fields,
+		Progress:   progress.Update,
+		URLStyle:   urlStyle,
+	})
+	progress.Done()
+	if err != nil {
+		return err
+	}
+
+	now := time.Now()
+	for i := range threads {
+		threads[i].Date = gml.FormatMailDate(threads[i].Date, dateFormat, now)
+	}
+
+	// Output
+	if len(threads) == 0 && formatFromFlags(cmd) == gml.OutputFormatText {
+		fmt.Fprintln(cmd.OutOrStdout(), "No threads found.")
+		return nil
+	}
+	return gml.FormatRecords(cmd.OutOrStdout(), threads, gml.ThreadFields, fields, formatFromFlags(cmd), gml.RecordOptions{
+		CSV:   csvOpts,
+		Table: gml.TableOptions{Width: gml.TerminalWidth(cmd.OutOrStdout())},
+	})
+}
+
+// threadAction describes one of the thread action commands
+type threadAction struct {
+	action gml.ThreadAction
+	// done and pending are the summary lines, e.g. "Archived %d of %d threads"
+	done    string
+	pending string
+}
+
+// threadArchiveCmd represents the thread archive command
+var threadArchiveCmd = newThreadActionCmd("archive", "Archive whole conversations", threadAction{
+	action: gml.ThreadArchive, done: "Archived %d of %d threads", pending: "Would archive %d threads",
+})
+
+// threadTrashCmd represents the thread trash command
+var threadTrashCmd = newThreadActionCmd("trash", "Move whole conversations to the trash", threadAction{
+	action: gml.ThreadTrash, done: "Trashed %d of %d threads", pending: "Would trash %d threads",
+})
+
+// threadReadCmd represents the thread read command
+var threadReadCmd = newThreadActionCmd("read", "Mark whole conversations as read", threadAction{
+	action: gml.ThreadRead, done: "Marked %d of %d threads read", pending: "Would mark %d threads read",
+})
+
+// threadUnreadCmd represents the thread unread command
+var threadUnreadCmd = newThreadActionCmd("unread", "Mark whole conversations as unread", threadAction{
+	action: gml.ThreadUnread, done: "Marked %d of %d threads unread", pending: "Would mark %d threads unread",
+})
+
+// threadLabelCmd represents the thread label command
+var threadLabelCmd = newThreadActionCmd("label", "Add and remove labels on whole conversations", threadAction{
+	action: gml.ThreadLabel, done: "Modified %d of %d threads", pending: "Would modify %d threads",
+})
+
+// newThreadActionCmd builds a command applying an action to every message of
+// the given threads
+func newThreadActionCmd(name, short string, action threadAction) *cobra.Command {
+	example := fmt.Sprintf(`  gml thread %[1]s 18abc123def456 18abc123def789
+  gml thread %[1]s -q "from:alerts@example.com older_than:30d"
+  gml threads -q "subject:standup" -n 100 -f id --json | jq -r '.[].id' | gml thread %[1]s -
+  gml thread %[1]s -l Notifications --dry-run  # Count the threads only`, name)
+	if action.action == gml.ThreadLabel {
+		example = `  gml thread label 18abc123def456 --add-label Projects/Acme --remove-label INBOX
+  gml thread label -q "from:boss@example.com" --add-label Important-Threads`
+	}
+	return &cobra.Command{
+		Use:   name + " [thread-id]...",
+		Short: short,
+		Long: fmt.Sprintf(`%s by thread ID, or every conversation matching a query.
+Every message of a thread is changed, including replies that don't match the
+query. Thread IDs are read from stdin when given as -; use 'gml bulk --thread'
+to act on the threads of message IDs.
+
+Requires the "modify" scope (see the scopes config option).
+
+Examples:
+%s`, short, example),
+		RunE: func(cmd *cobra.Command, args []string) error {
+			return runThreadAction(cmd, args, action)
+		},
+	}
+}
+
+func runThreadAction(cmd *cobra.Command, args []string, action threadAction) error {
+	ctx := cmd.Context()
+	cfg, err := GetConfig(cmd)
+	if err != nil {
+		return err
+	}
+
+	// Get flags
+	query, labels, err := queryFromFlags(cmd)
+	if err != nil {
+		return err
+	}
+	var addLabels, removeLabels []string
+	if action.action == gml.ThreadLabel {
+		addLabels, _ = cmd.Flags().GetStringArray("add-label")
+		removeLabels, _ = cmd.Flags().GetStringArray("remove-label")
+	}
+	dryRun := gml.IsDryRun(ctx)
+
+	var ids []string
+	if len(args) > 0 {
+		if query != "" || len(labels) > 0 {
+			return fmt.Errorf("thread IDs cannot be combined with --query or --label")
+		}
+		if ids, err = messageIDArgs(cmd, args); err != nil {
+			return err
+		}
+	} else if query == "" && len(labels) == 0 {
+		return fmt.Errorf("thread IDs, --query or --label is required")
+	}
+
+	// Create service
+	svc, err := gml.NewService(ctx, cfg)
+	if err != nil {
+		return fmt.Errorf("unable to create service: %w", err)
+	}
+
+	failures := failureLogFromFlags(cmd)
+	progress := newProgress(cmd, "Modifying threads")
+	result, err := gml.ModifyThreads(ctx, svc, gml.ThreadModifyOptions{
+		IDs:          ids,
+		Query:        query,
+		LabelIDs:     labels,
+		Action:       action.action,
+		AddLabels:    addLabels,
+		RemoveLabels: removeLabels,
+		DryRun:       dryRun,
+		Failures:     failures,
+		Progress:     progress.Update,
+	})
+	progress.Done()
+	if err != nil {
+		return fmt.Errorf("unable to modify threads: %w", err)
+	}
+
+	// Output
+	switch {
+	case formatFromFlags(cmd) == gml.OutputFormatJSON:
+		if err := gml.FormatJSON(cmd.OutOrStdout(), result); err != nil {
+			return err
+		}
+	case dryRun:
+		fmt.Fprintf(cmd.OutOrStdout(), action.pending+"\n", result.Matched)
+	default:
+		fmt.Fprintf(cmd.OutOrStdout(), action.done+"\n", result.Modified, result.Matched)
+	}
+
+	return reportFailures(cmd, failures)
+}
+
+func init() {
+	rootCmd.AddCommand(threadCmd)
+	rootCmd.AddCommand(threadsCmd)
+	threadCmd.AddCommand(threadListCmd)
+
+	addThreadListFlags(threadListCmd, "id,from,subject,date,messages")
+	addThreadListFlags(threadsCmd, "id,subject,participants,messages,date")
+
+	for _, c := range []*cobra.Command{threadArchiveCmd, threadTrashCmd, threadReadCmd, threadUnreadCmd, threadLabelCmd} {
+		threadCmd.AddCommand(c)
+
+		c.Flags().StringP("query", "q", "", "Search query (Gmail search syntax)")
+		addQueryFlags(c)
+		c.Flags().StringArrayP("label", "l", nil, "Filter by label (can be specified multiple times)")
+		addIgnoreErrorsFlag(c)
+		setFormats(c, gml.OutputFormatText, gml.OutputFormatJSON)
+	}
+	threadLabelCmd.Flags().StringArray("add-label", nil, "Label name or ID to add (can be specified multiple times; created if missing)")
+	threadLabelCmd.Flags().StringArray("remove-label", nil, "Label name or ID to remove (can be specified multiple times)")
+
+	// Set custom output to enable testing
+	threadCmd.SetOut(os.Stdout)
+	threadsCmd.SetOut(os.Stdout)
+}
+
+// addThreadListFlags adds the flags shared by 'thread list' and 'threads'
+func addThreadListFlags(cmd *cobra.Command, defaultFields string) {
+	cmd.Flags().StringP("query", "q", "", "Search query (Gmail search syntax)")
+	addSavedFlag(cmd)
+	cmd.Flags().Int64P("max-results", "n", 10, "Maximum number of threads to return")
+	cmd.Flags().StringArrayP("label", "l", nil, "Filter by label (can be specified multiple times)")
+	addQueryFlags(cmd)
+	addSearchFlags(cmd)
+	addFieldsFlag(cmd, gml.ThreadFields, defaultFields)
+	setFormats(cmd, gml.OutputFormatText, gml.OutputFormatJSON, gml.OutputFormatNDJSON, gml.OutputFormatCSV, gml.OutputFormatTSV, gml.OutputFormatMarkdown)
+	addCSVFlags(cmd)
+	addDateFormatFlag(cmd)
+	addURLStyleFlag(cmd)
+}
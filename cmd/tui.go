@@ -0,0 +1,85 @@
+/*
+Copyright © 2025 longkey1
+
+Licensed under the Apache License, Version 2.0 (the "License");
+you may not use this file except in compliance with the License.
+You may obtain a copy of the License at
+
+	http://www.apache.org/licenses/LICENSE-2.0
+
+Unless required by applicable law or agreed to in writing, software
+distributed under the License is distributed on an "AS IS" BASIS,
+WITHOUT WARRANTIES OR CONDITIONS OF ANY KIND, either express or implied.
+See the License for the specific language governing permissions and
+limitations under the License.
+*/
+package cmd
+
+import (
+	"fmt"
+	"os"
+
+	"github.com/longkey1/gml/internal/gml"
+	"github.com/longkey1/gml/internal/tui"
+	"github.com/spf13/cobra"
+)
+
+// tuiCmd represents the tui command
+var tuiCmd = &cobra.Command{
+	Use:   "tui",
+	Short: "Read mail in an interactive terminal UI",
+	Long: `Read mail in an interactive terminal UI with a label sidebar, a message
+list and a preview pane.
+
+Keys:
+  j/k, arrows      Move            tab / shift+tab  Switch pane
+  enter            Open preview    h/l              Previous / next pane
+  e                Archive         # or d           Move to trash
+  u                Toggle read     s                Toggle star
+  /                Filter as you type; enter searches Gmail, esc clears
+  r                Refresh         q                Quit
+
+Examples:
+  gml tui                       # Open INBOX
+  gml tui -l Work               # Open a label
+  gml tui -q "is:unread"        # Start with a search
+  gml tui --account work`,
+	RunE: runTUI,
+}
+
+func runTUI(cmd *cobra.Command, args []string) error {
+	ctx := cmd.Context()
+	cfg, err := GetConfig(cmd)
+	if err != nil {
+		return err
+	}
+
+	// Get flags
+	label, _ := cmd.Flags().GetString("label")
+	query, _ := cmd.Flags().GetString("query")
+	pageSize, _ := cmd.Flags().GetInt64("max-results")
+
+	// Create service
+	svc, err := gml.NewService(ctx, cfg)
+	if err != nil {
+		return fmt.Errorf("unable to create service: %w", err)
+	}
+
+	return tui.Run(ctx, svc, tui.Options{
+		Label:     label,
+		Query:     query,
+		PageSize:  pageSize,
+		Renderers: cfg.Renderers,
+	})
+}
+
+func init() {
+	rootCmd.AddCommand(tuiCmd)
+
+	tuiCmd.Flags().StringP("label", "l", "INBOX", "Label to open at startup")
+	tuiCmd.Flags().StringP("query", "q", "", "Initial search query (Gmail search syntax)")
+	tuiCmd.Flags().Int64P("max-results", "n", tui.DefaultPageSize, "Number of messages loaded per label or search")
+
+	// Set custom output to enable testing
+	tuiCmd.SetOut(os.Stdout)
+}
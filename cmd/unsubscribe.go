@@ -0,0 +1,172 @@
+/*
+Copyright © 2025 longkey1
+
+Licensed under the Apache License, Version 2.0 (the "License");
+you may not use this file except in compliance with the License.
+You may obtain a copy of the License at
+
+	http://www.apache.org/licenses/LICENSE-2.0
+
+Unless required by applicable law or agreed to in writing, software
+distributed under the License is distributed on an "AS IS" BASIS,
+WITHOUT WARRANTIES OR CONDITIONS OF ANY KIND, either express or implied.
+See the License for the specific language governing permissions and
+limitations under the License.
+*/
+package cmd
+
+import (
+	"fmt"
+	"os"
+
+	"github.com/longkey1/gml/internal/gml"
+	"github.com/spf13/cobra"
+	"golang.org/x/term"
+)
+
+// unsubscribeCmd represents the unsubscribe command
+var unsubscribeCmd = &cobra.Command{
+	Use:   "unsubscribe [message-id...]",
+	Short: "Unsubscribe from mailing lists via List-Unsubscribe",
+	Long: `Unsubscribe from the mailing lists of messages, using their List-Unsubscribe
+headers (RFC 2369). Lists offering one-click unsubscribe (RFC 8058,
+List-Unsubscribe-Post) are left with an HTTP POST. For the others, the
+unsubscribe URL or address is printed to visit by hand, or with --mailto the
+unsubscribe email is sent.
+
+With --query, --label or --from instead of IDs, every list found in the
+matching messages is unsubscribed from, once per list (List-Id, or sender).
+The lists are shown and must be confirmed; scripts must pass --yes. Only the
+newest --max-messages matching messages are read.
+Sending unsubscribe emails requires the "send" scope.
+
+The exit status is 3 when some requests failed.
+
+Examples:
+  gml unsubscribe 18abc123def456
+  gml unsubscribe --from newsletter@example.com
+  gml unsubscribe -q "category:promotions newer_than:30d" --list   # Show lists only
+  gml unsubscribe -q "category:promotions" --mailto --yes
+  gml --dry-run unsubscribe --from deals@example.com                # Don't send requests`,
+	RunE: runUnsubscribe,
+}
+
+func runUnsubscribe(cmd *cobra.Command, args []string) error {
+	ctx := cmd.Context()
+	cfg, err := GetConfig(cmd)
+	if err != nil {
+		return err
+	}
+
+	// Get flags
+	query, labels, err := queryFromFlags(cmd)
+	if err != nil {
+		return err
+	}
+	if query, labels, err = savedSearchFromFlags(cmd, query, labels); err != nil {
+		return err
+	}
+	if query, err = searchQueryFromFlags(cmd, query); err != nil {
+		return err
+	}
+	listOnly, _ := cmd.Flags().GetBool("list")
+	mailto, _ := cmd.Flags().GetBool("mailto")
+	yes, _ := cmd.Flags().GetBool("yes")
+	maxMessages, _ := cmd.Flags().GetInt("max-messages")
+
+	search := query != "" || len(labels) > 0
+	switch {
+	case len(args) > 0 && search:
+		return fmt.Errorf("message IDs cannot be combined with a search")
+	case len(args) == 0 && !search:
+		return fmt.Errorf("a message ID, --query, --label or --from is required")
+	}
+	confirm := search && !listOnly && !yes
+	if confirm && !term.IsTerminal(int(os.Stdin.Fd())) {
+		return fmt.Errorf("refusing to unsubscribe without confirmation; pass --yes when not running interactively")
+	}
+
+	// Create service
+	svc, err := gml.NewService(ctx, cfg)
+	if err != nil {
+		return fmt.Errorf("unable to create service: %w", err)
+	}
+
+	failures := failureLogFromFlags(cmd)
+	progress := newProgress(cmd, "Reading messages")
+	targets, missing, err := gml.FindUnsubscribeTargets(ctx, svc, gml.UnsubscribeSearchOptions{
+		IDs:         args,
+		Query:       query,
+		LabelIDs:    labels,
+		MaxMessages: maxMessages,
+		Failures:    failures,
+		Progress:    progress.Update,
+	})
+	progress.Done()
+	if err != nil {
+		return err
+	}
+	if missing > 0 {
+		fmt.Fprintf(cmd.ErrOrStderr(), "%d messages have no List-Unsubscribe header\n", missing)
+	}
+	if len(targets) == 0 {
+		fmt.Fprintln(cmd.ErrOrStderr(), "No lists to unsubscribe from.")
+		return reportFailures(cmd, failures)
+	}
+
+	if listOnly {
+		if err := gml.FormatUnsubscribeTargets(cmd.OutOrStdout(), targets, formatFromFlags(cmd)); err != nil {
+			return err
+		}
+		return reportFailures(cmd, failures)
+	}
+	if confirm {
+		if err := gml.FormatUnsubscribeTargets(cmd.OutOrStdout(), targets, gml.OutputFormatText); err != nil {
+			return err
+		}
+		fmt.Fprintf(cmd.OutOrStdout(), "Unsubscribe from %d lists? [y/N]: ", len(targets))
+		var response string
+		fmt.Scanln(&response)
+		if response != "y" && response != "Y" {
+			fmt.Fprintln(cmd.OutOrStdout(), "Cancelled.")
+			return nil
+		}
+	}
+
+	results := make([]gml.UnsubscribeResult, len(targets))
+	failed := 0
+	for i, t := range targets {
+		results[i] = gml.Unsubscribe(ctx, svc, t, gml.UnsubscribeOptions{Mailto: mailto})
+		if results[i].Status == gml.UnsubscribeFailed {
+			failed++
+		}
+	}
+
+	// Output
+	if err := gml.FormatUnsubscribeResults(cmd.OutOrStdout(), results, formatFromFlags(cmd)); err != nil {
+		return err
+	}
+	if failed > 0 {
+		return &ExitError{Code: exitCodePartialFailure, Err: fmt.Errorf("%d of %d unsubscribe requests failed", failed, len(results))}
+	}
+	return reportFailures(cmd, failures)
+}
+
+func init() {
+	rootCmd.AddCommand(unsubscribeCmd)
+
+	unsubscribeCmd.Flags().StringP("query", "q", "", "Search query (Gmail search syntax)")
+	unsubscribeCmd.Flags().StringArrayP("label", "l", nil, "Filter by label (can be specified multiple times)")
+	addQueryFlags(unsubscribeCmd)
+	addSearchFlags(unsubscribeCmd)
+	addSavedFlag(unsubscribeCmd)
+	unsubscribeCmd.Flags().Bool("list", false, "Show the lists and their unsubscribe methods without unsubscribing")
+	unsubscribeCmd.Flags().Bool("mailto", false, "Send the unsubscribe email for lists without one-click unsubscribe")
+	unsubscribeCmd.Flags().BoolP("yes", "y", false, "Skip the confirmation prompt")
+	unsubscribeCmd.Flags().IntP("max-messages", "n", 1000, "Read at most this many matching messages, newest first (0 for all)")
+	addIgnoreErrorsFlag(unsubscribeCmd)
+	setFormats(unsubscribeCmd, gml.OutputFormatText, gml.OutputFormatJSON)
+
+	// Set custom output to enable testing
+	unsubscribeCmd.SetOut(os.Stdout)
+}
@@ -0,0 +1,45 @@
+/*
+Copyright © 2025 longkey1
+
+Licensed under the Apache License, Version 2.0 (the "License");
+you may not use this file except in compliance with the License.
+You may obtain a copy of the License at
+
+	http://www.apache.org/licenses/LICENSE-2.0
+
+Unless required by applicable law or agreed to in writing, software
+distributed under the License is distributed on an "AS IS" BASIS,
+WITHOUT WARRANTIES OR CONDITIONS OF ANY KIND, either express or implied.
+See the License for the specific language governing permissions and
+limitations under the License.
+*/
+package cmd
+
+import (
+	"fmt"
+
+	"github.com/longkey1/gml/internal/gml"
+	"github.com/spf13/cobra"
+)
+
+// addURLStyleFlag adds the --url-style flag to a command
+func addURLStyleFlag(cmd *cobra.Command) {
+	cmd.Flags().String("url-style", string(gml.URLStyleAuto), "Gmail links: auto (messages link to the message, threads to the thread), thread or message")
+	_ = cmd.RegisterFlagCompletionFunc("url-style", func(c *cobra.Command, args []string, toComplete string) ([]string, cobra.ShellCompDirective) {
+		var styles []string
+		for _, s := range gml.URLStyles {
+			styles = append(styles, string(s))
+		}
+		return styles, cobra.ShellCompDirectiveNoFileComp
+	})
+}
+
+// urlStyleFromFlags returns the --url-style value
+func urlStyleFromFlags(cmd *cobra.Command) (gml.URLStyle, error) {
+	s, _ := cmd.Flags().GetString("url-style")
+	style, err := gml.ParseURLStyle(s)
+	if err != nil {
+		return "", fmt.Errorf("invalid --url-style: %w", err)
+	}
+	return style, nil
+}
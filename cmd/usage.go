@@ -0,0 +1,91 @@
+/*
+Copyright © 2025 longkey1
+
+Licensed under the Apache License, Version 2.0 (the "License");
+you may not use this file except in compliance with the License.
+You may obtain a copy of the License at
+
+	http://www.apache.org/licenses/LICENSE-2.0
+
+Unless required by applicable law or agreed to in writing, software
+distributed under the License is distributed on an "AS IS" BASIS,
+WITHOUT WARRANTIES OR CONDITIONS OF ANY KIND, either express or implied.
+See the License for the specific language governing permissions and
+limitations under the License.
+*/
+package cmd
+
+import (
+	"fmt"
+	"os"
+
+	"github.com/longkey1/gml/internal/gml"
+	"github.com/spf13/cobra"
+)
+
+// usageCmd represents the usage command
+var usageCmd = &cobra.Command{
+	Use:   "usage",
+	Short: "Show local usage statistics",
+	Long: `Show statistics of your own gml usage: commands run, Gmail API requests
+by endpoint and the OAuth token cache hit rate. This helps to understand
+quota consumption.
+
+Collection is opt-in: set usage_stats = true in the config file. The
+statistics are kept in $XDG_STATE_HOME/gml/usage.json (default
+~/.local/state/gml) and are never sent anywhere.
+
+Examples:
+  gml usage
+  gml usage --json
+  gml usage reset                 # Start counting from zero`,
+	Args: cobra.NoArgs,
+	RunE: runUsage,
+}
+
+// usageResetCmd represents the usage reset command
+var usageResetCmd = &cobra.Command{
+	Use:   "reset",
+	Short: "Delete the local usage statistics",
+	Args:  cobra.NoArgs,
+	RunE:  runUsageReset,
+}
+
+func runUsage(cmd *cobra.Command, args []string) error {
+	cfg, err := GetConfig(cmd)
+	if err != nil {
+		return err
+	}
+
+	stats, err := gml.LoadUsage()
+	if err != nil {
+		return err
+	}
+	if !cfg.UsageStats {
+		fmt.Fprintln(cmd.ErrOrStderr(), "Usage statistics are disabled; set usage_stats = true in the config file to collect them.")
+	}
+
+	// Output
+	if formatFromFlags(cmd) == gml.OutputFormatJSON {
+		return gml.FormatJSON(cmd.OutOrStdout(), stats)
+	}
+	return gml.FormatUsage(cmd.OutOrStdout(), stats)
+}
+
+func runUsageReset(cmd *cobra.Command, args []string) error {
+	if err := gml.ResetUsage(); err != nil {
+		return err
+	}
+	fmt.Fprintln(cmd.OutOrStdout(), "Usage statistics reset")
+	return nil
+}
+
+func init() {
+	rootCmd.AddCommand(usageCmd)
+	usageCmd.AddCommand(usageResetCmd)
+
+	setFormats(usageCmd, gml.OutputFormatText, gml.OutputFormatJSON)
+
+	// Set custom output to enable testing
+	usageCmd.SetOut(os.Stdout)
+}
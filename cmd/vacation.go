@@ -0,0 +1,233 @@
+/*
+Copyright © 2025 longkey1
+
+Licensed under the Apache License, Version 2.0 (the "License");
+you may not use this file except in compliance with the License.
+You may obtain a copy of the License at
+
+	http://www.apache.org/licenses/LICENSE-2.0
+
+Unless required by applicable law or agreed to in writing, software
+distributed under the License is distributed on an "AS IS" BASIS,
+WITHOUT WARRANTIES OR CONDITIONS OF ANY KIND, either express or implied.
+See the License for the specific language governing permissions and
+limitations under the License.
+*/
+package cmd
+
+import (
+	"fmt"
+	"io"
+	"os"
+
+	"github.com/longkey1/gml/internal/gml"
+	"github.com/spf13/cobra"
+)
+
+// vacationCmd represents the vacation command
+var vacationCmd = &cobra.Command{
+	Use:   "vacation",
+	Short: "Manage the vacation responder",
+	Long: `Manage the vacation responder (out-of-office auto-reply).
+
+Reading the settings works with the readonly scope; changing them requires
+the settings.basic scope.`,
+}
+
+// vacationGetCmd represents the vacation get command
+var vacationGetCmd = &cobra.Command{
+	Use:   "get",
+	Short: "Show vacation responder settings",
+	Long: `Show the vacation responder settings.
+
+Examples:
+  gml vacation get
+  gml vacation get --format json`,
+	RunE: runVacationGet,
+}
+
+// vacationSetCmd represents the vacation set command
+var vacationSetCmd = &cobra.Command{
+	Use:   "set",
+	Short: "Turn on the vacation responder",
+	Long: `Turn on the vacation responder with the given message.
+
+Dates are YYYY-MM-DD in local time or RFC 3339 timestamps; a plain --end
+date includes that whole day.
+
+Examples:
+  gml vacation set --subject "Out of office" --body "Back on Monday."
+  gml vacation set --subject "Away" --body-file away.html --html --start 2025-08-01 --end 2025-08-15
+  gml vacation set --subject "Away" --body-file - --contacts-only < message.txt`,
+	RunE: runVacationSet,
+}
+
+// vacationOffCmd represents the vacation off command
+var vacationOffCmd = &cobra.Command{
+	Use:   "off",
+	Short: "Turn off the vacation responder",
+	Long: `Turn off the vacation responder, keeping its message for next time.
+
+Examples:
+  gml vacation off`,
+	RunE: runVacationOff,
+}
+
+func runVacationGet(cmd *cobra.Command, args []string) error {
+	ctx := cmd.Context()
+	cfg, err := GetConfig(cmd)
+	if err != nil {
+		return err
+	}
+
+	// Get flags
+	format := formatFromFlags(cmd)
+
+	svc, err := gml.NewService(ctx, cfg)
+	if err != nil {
+		return fmt.Errorf("unable to create service: %w", err)
+	}
+
+	vacation, err := gml.GetVacation(ctx, svc)
+	if err != nil {
+		return err
+	}
+
+	// Output
+	if err := gml.FormatVacation(cmd.OutOrStdout(), vacation, format); err != nil {
+		return fmt.Errorf("unable to format output: %w", err)
+	}
+
+	return nil
+}
+
+func runVacationSet(cmd *cobra.Command, args []string) error {
+	ctx := cmd.Context()
+	cfg, err := GetConfig(cmd)
+	if err != nil {
+		return err
+	}
+
+	// Get flags
+	subject, _ := cmd.Flags().GetString("subject")
+	body, _ := cmd.Flags().GetString("body")
+	bodyFile, _ := cmd.Flags().GetString("body-file")
+	html, _ := cmd.Flags().GetBool("html")
+	start, _ := cmd.Flags().GetString("start")
+	end, _ := cmd.Flags().GetString("end")
+	contactsOnly, _ := cmd.Flags().GetBool("contacts-only")
+	domainOnly, _ := cmd.Flags().GetBool("domain-only")
+	format := formatFromFlags(cmd)
+
+	if bodyFile != "" {
+		data, err := readInput(cmd, bodyFile)
+		if err != nil {
+			return err
+		}
+		body = string(data)
+	}
+	if body == "" {
+		return fmt.Errorf("--body or --body-file is required")
+	}
+
+	vacation := &gml.Vacation{
+		Enabled:            true,
+		Subject:            subject,
+		RestrictToContacts: contactsOnly,
+		RestrictToDomain:   domainOnly,
+	}
+	if html {
+		vacation.BodyHTML = body
+	} else {
+		vacation.Body = body
+	}
+	if start != "" {
+		t, err := gml.ParseVacationTime(start, false)
+		if err != nil {
+			return fmt.Errorf("invalid --start: %w", err)
+		}
+		vacation.Start = &t
+	}
+	if end != "" {
+		t, err := gml.ParseVacationTime(end, true)
+		if err != nil {
+			return fmt.Errorf("invalid --end: %w", err)
+		}
+		vacation.End = &t
+	}
+
+	svc, err := gml.NewService(ctx, cfg)
+	if err != nil {
+		return fmt.Errorf("unable to create service: %w", err)
+	}
+
+	updated, err := gml.SetVacation(ctx, svc, vacation)
+	if err != nil {
+		return err
+	}
+
+	// Output
+	if err := gml.FormatVacation(cmd.OutOrStdout(), updated, format); err != nil {
+		return fmt.Errorf("unable to format output: %w", err)
+	}
+
+	return nil
+}
+
+func runVacationOff(cmd *cobra.Command, args []string) error {
+	ctx := cmd.Context()
+	cfg, err := GetConfig(cmd)
+	if err != nil {
+		return err
+	}
+
+	svc, err := gml.NewService(ctx, cfg)
+	if err != nil {
+		return fmt.Errorf("unable to create service: %w", err)
+	}
+
+	if _, err := gml.DisableVacation(ctx, svc); err != nil {
+		return err
+	}
+
+	fmt.Fprintln(cmd.OutOrStdout(), "Vacation responder turned off")
+	return nil
+}
+
+// readInput reads a file, or stdin when path is "-"
+func readInput(cmd *cobra.Command, path string) ([]byte, error) {
+	if path == "-" {
+		data, err := io.ReadAll(cmd.InOrStdin())
+		if err != nil {
+			return nil, fmt.Errorf("unable to read stdin: %w", err)
+		}
+		return data, nil
+	}
+	data, err := os.ReadFile(path)
+	if err != nil {
+		return nil, fmt.Errorf("unable to read file: %w", err)
+	}
+	return data, nil
+}
+
+func init() {
+	rootCmd.AddCommand(vacationCmd)
+	vacationCmd.AddCommand(vacationGetCmd)
+	vacationCmd.AddCommand(vacationSetCmd)
+	vacationCmd.AddCommand(vacationOffCmd)
+
+	setFormats(vacationGetCmd, gml.OutputFormatText, gml.OutputFormatJSON)
+
+	vacationSetCmd.Flags().String("subject", "", "Auto-reply subject")
+	vacationSetCmd.Flags().String("body", "", "Auto-reply message")
+	vacationSetCmd.Flags().String("body-file", "", "Read the auto-reply message from a file (- for stdin)")
+	vacationSetCmd.Flags().Bool("html", false, "Treat the message as HTML")
+	vacationSetCmd.Flags().String("start", "", "Start date (YYYY-MM-DD or RFC 3339)")
+	vacationSetCmd.Flags().String("end", "", "End date, inclusive (YYYY-MM-DD or RFC 3339)")
+	vacationSetCmd.Flags().Bool("contacts-only", false, "Only reply to people in your contacts")
+	vacationSetCmd.Flags().Bool("domain-only", false, "Only reply to people in your domain (Workspace)")
+	setFormats(vacationSetCmd, gml.OutputFormatText, gml.OutputFormatJSON)
+
+	// Set custom output to enable testing
+	vacationCmd.SetOut(os.Stdout)
+}
@@ -18,6 +18,7 @@ package cmd
 import (
 	"fmt"
 
+	"github.com/longkey1/gml/internal/gml"
 	"github.com/longkey1/gml/internal/version"
 	"github.com/spf13/cobra"
 )
@@ -26,17 +27,22 @@ import (
 var versionCmd = &cobra.Command{
 	Use:   "version",
 	Short: "Show version information",
-	Run: func(cmd *cobra.Command, args []string) {
+	RunE: func(cmd *cobra.Command, args []string) error {
 		short, _ := cmd.Flags().GetBool("short")
-		if short {
+		switch {
+		case formatFromFlags(cmd) == gml.OutputFormatJSON:
+			return gml.FormatJSON(cmd.OutOrStdout(), version.Build())
+		case short:
 			fmt.Println(version.Short())
-		} else {
+		default:
 			fmt.Println(version.Info())
 		}
+		return nil
 	},
 }
 
 func init() {
 	rootCmd.AddCommand(versionCmd)
 	versionCmd.Flags().BoolP("short", "s", false, "Show only version number")
+	setFormats(versionCmd, gml.OutputFormatText, gml.OutputFormatJSON)
 }
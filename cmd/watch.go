@@ -0,0 +1,448 @@
+/*
+Copyright © 2025 longkey1
+
+Licensed under the Apache License, Version 2.0 (the "License");
+you may not use this file except in compliance with the License.
+You may obtain a copy of the License at
+
+	http://www.apache.org/licenses/LICENSE-2.0
+
+Unless required by applicable law or agreed to in writing, software
+distributed under the License is distributed on an "AS IS" BASIS,
+WITHOUT WARRANTIES OR CONDITIONS OF ANY KIND, either express or implied.
+See the License for the specific language governing permissions and
+limitations under the License.
+*/
+package cmd
+
+import (
+	"context"
+	"crypto/subtle"
+	"encoding/json"
+	"errors"
+	"fmt"
+	"log/slog"
+	"net/http"
+	"os"
+	"os/signal"
+	"syscall"
+	"time"
+
+	"github.com/longkey1/gml/internal/gml"
+	"github.com/spf13/cobra"
+)
+
+// watchStateName is the state file prefix for the last processed history ID
+const watchStateName = "watch"
+
+// watchCmd represents the watch command
+var watchCmd = &cobra.Command{
+	Use:   "watch",
+	Short: "Watch the mailbox for new messages",
+	Long: `Watch the mailbox for new messages.
+
+With --poll, gml polls the Gmail history API every --interval and emits each
+new message as a JSON line on stdout (or runs --exec per message). This needs
+no Google Cloud setup.
+
+For push notifications, Gmail publishes mailbox changes to a Google Cloud
+Pub/Sub topic. Grant gmail-api-push@system.gserviceaccount.com the Pub/Sub
+Publisher role on the topic, then create a push subscription pointing at
+'gml watch serve'.
+
+The last processed history ID is stored per account in
+$XDG_STATE_HOME/gml (default ~/.local/state/gml).
+
+Defaults for --label, --fields and --exec can be set in the [watch] config
+section. Running daemons reload it when the config file is saved or on
+SIGHUP; flags given on the command line keep precedence.
+
+Examples:
+  gml watch --poll
+  gml watch --poll --interval 30s -l INBOX --exec 'notify-send "$GML_FROM" "$GML_SUBJECT"'
+  gml watch start --topic projects/my-project/topics/gmail
+  gml watch serve --addr :8080 --exec 'notify-send "$GML_FROM" "$GML_SUBJECT"'
+  gml watch renew                 # Run daily; watches expire after 7 days
+  gml watch stop`,
+	RunE: runWatch,
+}
+
+// watchStartCmd represents the watch start command
+var watchStartCmd = &cobra.Command{
+	Use:   "start",
+	Short: "Register a Gmail watch on a Pub/Sub topic",
+	Long: `Register a Gmail watch that publishes mailbox changes to a Pub/Sub topic.
+
+Examples:
+  gml watch start --topic projects/my-project/topics/gmail
+  gml watch start --topic projects/my-project/topics/gmail -l INBOX`,
+	RunE: runWatchStart,
+}
+
+// watchRenewCmd represents the watch renew command
+var watchRenewCmd = &cobra.Command{
+	Use:   "renew",
+	Short: "Renew the Gmail watch before it expires",
+	Long: `Renew the Gmail watch registered with 'gml watch start'.
+
+Watches expire after 7 days; Google recommends renewing once a day.
+The topic and labels are taken from the saved state unless --topic or
+--label is given.
+
+Examples:
+  gml watch renew
+  gml watch renew --topic projects/my-project/topics/gmail`,
+	RunE: runWatchRenew,
+}
+
+// watchStopCmd represents the watch stop command
+var watchStopCmd = &cobra.Command{
+	Use:   "stop",
+	Short: "Stop Gmail push notifications",
+	Long: `Stop Gmail push notifications for the mailbox.
+
+Examples:
+  gml watch stop`,
+	RunE: runWatchStop,
+}
+
+// watchServeCmd represents the watch serve command
+var watchServeCmd = &cobra.Command{
+	Use:   "serve",
+	Short: "Receive Pub/Sub push notifications and emit new messages",
+	Long: `Run an HTTP endpoint for a Pub/Sub push subscription and emit each new
+message as a JSON line on stdout.
+
+With --exec, the command is run through 'sh -c' for every new message with
+the message JSON on stdin and fields in GML_ACCOUNT, GML_ID, GML_THREAD_ID,
+GML_URL, GML_FROM, GML_TO, GML_SUBJECT, GML_DATE, GML_SNIPPET and GML_LABELS.
+
+Set --token and append ?token=<value> to the push endpoint URL to reject
+requests that don't come from your subscription.
+
+Examples:
+  gml watch serve --addr :8080
+  gml watch serve --addr :8080 --path /gmail --token s3cret
+  gml watch serve -l INBOX --exec './on-mail.sh'
+  kill -HUP <pid>                 # Reload the [watch] config section`,
+	RunE: runWatchServe,
+}
+
+func watchStatePath(cfg *gml.Config) (string, error) {
+	return gml.StatePath(watchStateName, cfg.Account)
+}
+
+func runWatch(cmd *cobra.Command, args []string) error {
+	ctx, stop := signal.NotifyContext(cmd.Context(), os.Interrupt, syscall.SIGTERM)
+	defer stop()
+	cfg, err := GetConfig(cmd)
+	if err != nil {
+		return err
+	}
+
+	// Get flags
+	poll, _ := cmd.Flags().GetBool("poll")
+	interval, _ := cmd.Flags().GetDuration("interval")
+
+	if !poll {
+		return fmt.Errorf("specify --poll or a subcommand (start, renew, stop, serve)")
+	}
+	if interval < time.Second {
+		return fmt.Errorf("--interval must be at least 1s")
+	}
+
+	svc, err := gml.NewService(ctx, cfg)
+	if err != nil {
+		return fmt.Errorf("unable to create service: %w", err)
+	}
+
+	statePath, err := watchStatePath(cfg)
+	if err != nil {
+		return err
+	}
+
+	label, fields, execCmd := watchSettings(cmd, cfg)
+	notifier, err := gml.NewMessageNotifier(ctx, svc, statePath, label, fields, newMessageEmitter(ctx, cmd, cfg, execCmd))
+	if err != nil {
+		return err
+	}
+
+	// Pick up [watch] changes on SIGHUP or when the config file is saved
+	watchConfigChanges(ctx, cmd, cfg.Account, func(cfg *gml.Config) error {
+		label, fields, execCmd := watchSettings(cmd, cfg)
+		return notifier.Reconfigure(ctx, label, fields, newMessageEmitter(ctx, cmd, cfg, execCmd))
+	})
+
+	ticker := time.NewTicker(interval)
+	defer ticker.Stop()
+	for {
+		select {
+		case <-ctx.Done():
+			return nil
+		case <-ticker.C:
+			if _, err := notifier.Check(ctx); err != nil {
+				if ctx.Err() != nil {
+					return nil
+				}
+				// Transient API errors are retried on the next tick
+				slog.Warn(err.Error())
+			}
+		}
+	}
+}
+
+func runWatchStart(cmd *cobra.Command, args []string) error {
+	ctx := cmd.Context()
+	cfg, err := GetConfig(cmd)
+	if err != nil {
+		return err
+	}
+
+	// Get flags
+	topic, _ := cmd.Flags().GetString("topic")
+	labels := labelsFromFlags(cmd)
+
+	return startWatch(ctx, cmd, cfg, topic, labels)
+}
+
+func runWatchRenew(cmd *cobra.Command, args []string) error {
+	ctx := cmd.Context()
+	cfg, err := GetConfig(cmd)
+	if err != nil {
+		return err
+	}
+
+	// Get flags
+	topic, _ := cmd.Flags().GetString("topic")
+	labels := labelsFromFlags(cmd)
+
+	if topic == "" || len(labels) == 0 {
+		statePath, err := watchStatePath(cfg)
+		if err != nil {
+			return err
+		}
+		var state gml.WatchState
+		if _, err := gml.LoadState(statePath, &state); err != nil {
+			return err
+		}
+		if topic == "" {
+			if state.Topic == "" {
+				return fmt.Errorf("no watch registered; run 'gml watch start --topic ...' first")
+			}
+			topic = state.Topic
+		}
+		// Keep the labels of the existing watch rather than widening it to the mailbox
+		if len(labels) == 0 {
+			for _, id := range state.LabelIDs {
+				labels = append(labels, gml.LabelIDPrefix+id)
+			}
+		}
+	}
+
+	return startWatch(ctx, cmd, cfg, topic, labels)
+}
+
+// startWatch registers the watch and saves its state, keeping the history ID
+// of an existing state so messages since the last run aren't skipped
+func startWatch(ctx context.Context, cmd *cobra.Command, cfg *gml.Config, topic string, labels []string) error {
+	svc, err := gml.NewService(ctx, cfg)
+	if err != nil {
+		return fmt.Errorf("unable to create service: %w", err)
+	}
+
+	watch, err := gml.StartWatch(ctx, svc, gml.WatchOptions{
+		Topic:    topic,
+		LabelIDs: labels,
+	})
+	if err != nil {
+		return err
+	}
+	if gml.IsDryRun(ctx) {
+		// The request was only reported, so there is no watch to save
+		return nil
+	}
+
+	statePath, err := watchStatePath(cfg)
+	if err != nil {
+		return err
+	}
+	var state gml.WatchState
+	if _, err := gml.LoadState(statePath, &state); err != nil {
+		return err
+	}
+	if state.HistoryID != 0 {
+		watch.HistoryID = state.HistoryID
+	}
+	if err := gml.SaveState(statePath, watch); err != nil {
+		return err
+	}
+
+	fmt.Fprintf(cmd.OutOrStdout(), "Watching %s until %s\n", topic, watch.Expiration.Format(time.RFC3339))
+	return nil
+}
+
+func runWatchStop(cmd *cobra.Command, args []string) error {
+	ctx := cmd.Context()
+	cfg, err := GetConfig(cmd)
+	if err != nil {
+		return err
+	}
+
+	svc, err := gml.NewService(ctx, cfg)
+	if err != nil {
+		return fmt.Errorf("unable to create service: %w", err)
+	}
+
+	if err := gml.StopWatch(ctx, svc); err != nil {
+		return err
+	}
+
+	fmt.Fprintln(cmd.OutOrStdout(), "Watch stopped")
+	return nil
+}
+
+func runWatchServe(cmd *cobra.Command, args []string) error {
+	ctx, stop := signal.NotifyContext(cmd.Context(), os.Interrupt, syscall.SIGTERM)
+	defer stop()
+	cfg, err := GetConfig(cmd)
+	if err != nil {
+		return err
+	}
+
+	// Get flags
+	addr, _ := cmd.Flags().GetString("addr")
+	path, _ := cmd.Flags().GetString("path")
+	token, _ := cmd.Flags().GetString("token")
+
+	svc, err := gml.NewService(ctx, cfg)
+	if err != nil {
+		return fmt.Errorf("unable to create service: %w", err)
+	}
+
+	statePath, err := watchStatePath(cfg)
+	if err != nil {
+		return err
+	}
+
+	label, fields, execCmd := watchSettings(cmd, cfg)
+	notifier, err := gml.NewMessageNotifier(ctx, svc, statePath, label, fields, newMessageEmitter(ctx, cmd, cfg, execCmd))
+	if err != nil {
+		return err
+	}
+
+	// Pick up [watch] changes on SIGHUP or when the config file is saved
+	watchConfigChanges(ctx, cmd, cfg.Account, func(cfg *gml.Config) error {
+		label, fields, execCmd := watchSettings(cmd, cfg)
+		return notifier.Reconfigure(ctx, label, fields, newMessageEmitter(ctx, cmd, cfg, execCmd))
+	})
+
+	mux := http.NewServeMux()
+	mux.HandleFunc(path, func(w http.ResponseWriter, r *http.Request) {
+		if r.Method != http.MethodPost {
+			http.Error(w, "method not allowed", http.StatusMethodNotAllowed)
+			return
+		}
+		if token != "" && subtle.ConstantTimeCompare([]byte(r.URL.Query().Get("token")), []byte(token)) != 1 {
+			http.Error(w, "forbidden", http.StatusForbidden)
+			return
+		}
+		if _, err := gml.ParsePushRequest(r); err != nil {
+			// Acknowledge malformed messages so Pub/Sub doesn't redeliver them forever
+			slog.Warn(err.Error())
+			w.WriteHeader(http.StatusNoContent)
+			return
+		}
+		if _, err := notifier.Check(r.Context()); err != nil {
+			fmt.Fprintf(cmd.ErrOrStderr(), "Error: %v\n", err)
+			// A non-2xx status makes Pub/Sub retry the notification
+			http.Error(w, "unable to process notification", http.StatusInternalServerError)
+			return
+		}
+		w.WriteHeader(http.StatusNoContent)
+	})
+
+	server := &http.Server{Addr: addr, Handler: mux}
+	go func() {
+		<-ctx.Done()
+		shutdownCtx, cancel := context.WithTimeout(context.Background(), 5*time.Second)
+		defer cancel()
+		server.Shutdown(shutdownCtx)
+	}()
+
+	fmt.Fprintf(cmd.ErrOrStderr(), "Listening on %s%s\n", addr, path)
+	if err := server.ListenAndServe(); err != nil && !errors.Is(err, http.ErrServerClosed) {
+		return fmt.Errorf("unable to serve: %w", err)
+	}
+	return nil
+}
+
+// watchSettings returns the label, fields and exec hook for the watch daemons,
+// preferring explicitly set flags over the [watch] config section
+func watchSettings(cmd *cobra.Command, cfg *gml.Config) (string, map[string]bool, string) {
+	setting := func(name, configured string) string {
+		value, _ := cmd.Flags().GetString(name)
+		if !cmd.Flags().Changed(name) && configured != "" {
+			return configured
+		}
+		return value
+	}
+	label := setting("label", cfg.Watch.Label)
+	fields := gml.ParseFields(setting("fields", cfg.Watch.Fields))
+	execCmd := setting("exec", cfg.Watch.Exec)
+	return label, fields, execCmd
+}
+
+// newMessageEmitter returns a callback that prints each message as a JSON
+// line and runs the --exec hook, if any
+func newMessageEmitter(ctx context.Context, cmd *cobra.Command, cfg *gml.Config, execCmd string) func(gml.MessageInfo) error {
+	enc := json.NewEncoder(cmd.OutOrStdout())
+	return func(msg gml.MessageInfo) error {
+		msg.Account = cfg.Account
+		if execCmd != "" {
+			if err := gml.RunMessageHook(ctx, execCmd, msg, cmd.OutOrStdout(), cmd.ErrOrStderr()); err != nil {
+				// A failing hook shouldn't stop the watcher
+				slog.Warn(err.Error())
+			}
+			return nil
+		}
+		if err := enc.Encode(msg); err != nil {
+			return fmt.Errorf("unable to write message: %w", err)
+		}
+		return nil
+	}
+}
+
+func init() {
+	rootCmd.AddCommand(watchCmd)
+	watchCmd.AddCommand(watchStartCmd)
+	watchCmd.AddCommand(watchRenewCmd)
+	watchCmd.AddCommand(watchStopCmd)
+	watchCmd.AddCommand(watchServeCmd)
+
+	watchCmd.Flags().Bool("poll", false, "Poll the history API instead of using Pub/Sub")
+	watchCmd.Flags().Duration("interval", time.Minute, "Polling interval")
+	watchCmd.Flags().StringP("label", "l", "", "Only emit messages added with this label")
+	addFieldsFlag(watchCmd, gml.MessageFields, defaultFields)
+	watchCmd.Flags().String("exec", "", "Run a shell command for each new message instead of printing it")
+	setFormats(watchCmd, gml.OutputFormatNDJSON)
+
+	watchStartCmd.Flags().String("topic", "", "Pub/Sub topic (projects/<project>/topics/<topic>)")
+	watchStartCmd.Flags().StringArrayP("label", "l", nil, "Only notify for changes to this label (can be specified multiple times)")
+	addLabelIDFlag(watchStartCmd)
+	watchStartCmd.MarkFlagRequired("topic")
+
+	watchRenewCmd.Flags().String("topic", "", "Pub/Sub topic (defaults to the topic used by 'watch start')")
+	watchRenewCmd.Flags().StringArrayP("label", "l", nil, "Only notify for changes to this label (can be specified multiple times)")
+	addLabelIDFlag(watchRenewCmd)
+
+	watchServeCmd.Flags().String("addr", ":8080", "Address to listen on")
+	watchServeCmd.Flags().String("path", "/push", "HTTP path for the push endpoint")
+	watchServeCmd.Flags().String("token", "", "Require ?token=<value> on push requests")
+	setFormats(watchServeCmd, gml.OutputFormatNDJSON)
+	watchServeCmd.Flags().StringP("label", "l", "", "Only emit messages added with this label")
+	addFieldsFlag(watchServeCmd, gml.MessageFields, defaultFields)
+	watchServeCmd.Flags().String("exec", "", "Run a shell command for each new message instead of printing it")
+
+	// Set custom output to enable testing
+	watchCmd.SetOut(os.Stdout)
+}
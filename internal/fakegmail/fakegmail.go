@@ -0,0 +1,669 @@
+// Package fakegmail is an in-memory Gmail mailbox implementing
+// google.GmailClient, for testing the core mail functions without the API
+package fakegmail
+
+import (
+	"cmp"
+	"context"
+	"encoding/base64"
+	"encoding/json"
+	"fmt"
+	"net/http"
+	"slices"
+	"strconv"
+	"strings"
+	"sync"
+	"time"
+
+	"github.com/longkey1/gml/internal/google"
+	"google.golang.org/api/gmail/v1"
+	"google.golang.org/api/googleapi"
+)
+
+// systemLabels are the labels every mailbox starts with
+var systemLabels = []string{"INBOX", "UNREAD", "SENT", "DRAFT", "TRASH", "SPAM", "STARRED", "IMPORTANT"}
+
+// Message is a message to add to the mailbox
+type Message struct {
+	// ID and ThreadID are generated when empty; messages without a ThreadID
+	// start their own thread
+	ID       string
+	ThreadID string
+	From     string
+	To       string
+	Subject  string
+	Body     string
+	// Date defaults to the time the message is added
+	Date time.Time
+	// LabelIDs are system label IDs or IDs returned by AddLabel
+	LabelIDs    []string
+	Attachments []Attachment
+}
+
+// Attachment is a file attached to a Message
+type Attachment struct {
+	Filename string
+	MimeType string
+	Data     []byte
+}
+
+// Client is a fake mailbox. It is safe for concurrent use
+type Client struct {
+	// Match, if set, replaces the built-in search query matching
+	Match func(msg *gmail.Message, query string) bool
+	// Fail, if set, is called before each request with the name of the
+	// method and the message or label ID; an error it returns fails the
+	// request, e.g. a *googleapi.Error to simulate quota errors
+	Fail func(method, id string) error
+
+	mu          sync.Mutex
+	email       string
+	messages    map[string]*gmail.Message
+	attachments map[string]string
+	labels      []*gmail.Label
+	nextID      int
+}
+
+var _ google.GmailClient = (*Client)(nil)
+
+// New returns an empty mailbox of email with the system labels
+func New(email string) *Client {
+	c := &Client{
+		email:       email,
+		messages:    make(map[string]*gmail.Message),
+		attachments: make(map[string]string),
+	}
+	for _, id := range systemLabels {
+		c.labels = append(c.labels, &gmail.Label{Id: id, Name: id, Type: "system"})
+	}
+	return c
+}
+
+// AddLabel creates a user label and returns its ID
+func (c *Client) AddLabel(name string) string {
+	c.mu.Lock()
+	defer c.mu.Unlock()
+	if l := c.labelByName(name); l != nil {
+		return l.Id
+	}
+	return c.createLabel(&gmail.Label{Name: name}).Id
+}
+
+// AddMessage adds a message to the mailbox and returns its ID
+func (c *Client) AddMessage(m Message) string {
+	c.mu.Lock()
+	defer c.mu.Unlock()
+
+	if m.ID == "" {
+		m.ID = c.newID("msg")
+	}
+	if m.ThreadID == "" {
+		m.ThreadID = m.ID
+	}
+	if m.Date.IsZero() {
+		m.Date = time.Now()
+	}
+
+	headers := []*gmail.MessagePartHeader{
+		{Name: "From", Value: m.From},
+		{Name: "To", Value: m.To},
+		{Name: "Subject", Value: m.Subject},
+		{Name: "Date", Value: m.Date.Format(time.RFC1123Z)},
+	}
+	text := &gmail.MessagePart{
+		MimeType: "text/plain",
+		Body:     &gmail.MessagePartBody{Data: encode([]byte(m.Body)), Size: int64(len(m.Body))},
+	}
+	payload := text
+	size := int64(len(m.Body))
+	if len(m.Attachments) > 0 {
+		text.PartId = "0"
+		payload = &gmail.MessagePart{MimeType: "multipart/mixed", Body: &gmail.MessagePartBody{}, Parts: []*gmail.MessagePart{text}}
+		for i, a := range m.Attachments {
+			id := c.newID("att")
+			c.attachments[id] = encode(a.Data)
+			payload.Parts = append(payload.Parts, &gmail.MessagePart{
+				PartId:   strconv.Itoa(i + 1),
+				Filename: a.Filename,
+				MimeType: a.MimeType,
+				Body:     &gmail.MessagePartBody{AttachmentId: id, Size: int64(len(a.Data))},
+			})
+			size += int64(len(a.Data))
+		}
+	}
+	payload.Headers = headers
+
+	snippet := m.Body
+	if len(snippet) > 100 {
+		snippet = snippet[:100]
+	}
+	c.messages[m.ID] = &gmail.Message{
+		Id:           m.ID,
+		ThreadId:     m.ThreadID,
+		LabelIds:     slices.Clone(m.LabelIDs),
+		Snippet:      snippet,
+		InternalDate: m.Date.UnixMilli(),
+		SizeEstimate: size,
+		Payload:      payload,
+		Raw:          encode(rawMessage(headers, m.Body)),
+	}
+	return m.ID
+}
+
+// Message returns a copy of a message, in the full format, or nil if it
+// doesn't exist
+func (c *Client) Message(id string) *gmail.Message {
+	c.mu.Lock()
+	defer c.mu.Unlock()
+	if msg, ok := c.messages[id]; ok {
+		return clone(msg)
+	}
+	return nil
+}
+
+// GetProfile returns the email address and totals of the mailbox
+func (c *Client) GetProfile(ctx context.Context) (*gmail.Profile, error) {
+	if err := c.fail("GetProfile", ""); err != nil {
+		return nil, err
+	}
+	c.mu.Lock()
+	defer c.mu.Unlock()
+	threads := make(map[string]bool)
+	for _, msg := range c.messages {
+		threads[msg.ThreadId] = true
+	}
+	return &gmail.Profile{
+		EmailAddress:  c.email,
+		MessagesTotal: int64(len(c.messages)),
+		ThreadsTotal:  int64(len(threads)),
+	}, nil
+}
+
+// ListMessages returns one page of the messages matching opts, newest first.
+// Page tokens are offsets into the results; Fields is ignored
+func (c *Client) ListMessages(ctx context.Context, opts google.ListMessagesOptions) (*gmail.ListMessagesResponse, error) {
+	if err := c.fail("ListMessages", ""); err != nil {
+		return nil, err
+	}
+	c.mu.Lock()
+	defer c.mu.Unlock()
+
+	matched := c.matching(opts)
+	start, end, next, err := page(len(matched), opts)
+	if err != nil {
+		return nil, err
+	}
+	resp := &gmail.ListMessagesResponse{ResultSizeEstimate: int64(len(matched)), NextPageToken: next}
+	for _, msg := range matched[start:end] {
+		resp.Messages = append(resp.Messages, &gmail.Message{Id: msg.Id, ThreadId: msg.ThreadId})
+	}
+	return resp, nil
+}
+
+// GetMessage returns a message in the format of opts; Fields is ignored
+func (c *Client) GetMessage(ctx context.Context, id string, opts google.GetMessageOptions) (*gmail.Message, error) {
+	if err := c.fail("GetMessage", id); err != nil {
+		return nil, err
+	}
+	c.mu.Lock()
+	defer c.mu.Unlock()
+	stored, ok := c.messages[id]
+	if !ok {
+		return nil, notFound("Requested entity was not found.")
+	}
+	return format(stored, opts)
+}
+
+// ListThreads returns one page of the threads with a message matching opts,
+// newest first by their latest matching message. Page tokens are offsets
+// into the results; Fields is ignored
+func (c *Client) ListThreads(ctx context.Context, opts google.ListMessagesOptions) (*gmail.ListThreadsResponse, error) {
+	if err := c.fail("ListThreads", ""); err != nil {
+		return nil, err
+	}
+	c.mu.Lock()
+	defer c.mu.Unlock()
+
+	var threads []*gmail.Message
+	seen := make(map[string]bool)
+	for _, msg := range c.matching(opts) {
+		if !seen[msg.ThreadId] {
+			seen[msg.ThreadId] = true
+			threads = append(threads, msg)
+		}
+	}
+	start, end, next, err := page(len(threads), opts)
+	if err != nil {
+		return nil, err
+	}
+	resp := &gmail.ListThreadsResponse{ResultSizeEstimate: int64(len(threads)), NextPageToken: next}
+	for _, msg := range threads[start:end] {
+		resp.Threads = append(resp.Threads, &gmail.Thread{Id: msg.ThreadId, Snippet: msg.Snippet})
+	}
+	return resp, nil
+}
+
+// GetThread returns the messages of a thread, oldest first, in the format of
+// opts; Fields is ignored
+func (c *Client) GetThread(ctx context.Context, id string, opts google.GetMessageOptions) (*gmail.Thread, error) {
+	if err := c.fail("GetThread", id); err != nil {
+		return nil, err
+	}
+	c.mu.Lock()
+	defer c.mu.Unlock()
+
+	stored := c.thread(id)
+	if len(stored) == 0 {
+		return nil, notFound("Requested entity was not found.")
+	}
+	thread := &gmail.Thread{Id: id, Snippet: stored[len(stored)-1].Snippet}
+	for _, msg := range stored {
+		formatted, err := format(msg, opts)
+		if err != nil {
+			return nil, err
+		}
+		thread.Messages = append(thread.Messages, formatted)
+	}
+	return thread, nil
+}
+
+// ModifyThread adds and removes labels of every message of a thread
+func (c *Client) ModifyThread(ctx context.Context, id string, req *gmail.ModifyThreadRequest) error {
+	if err := c.fail("ModifyThread", id); err != nil {
+		return err
+	}
+	return c.modifyThread(id, req.AddLabelIds, req.RemoveLabelIds)
+}
+
+// TrashThread moves every message of a thread to the trash
+func (c *Client) TrashThread(ctx context.Context, id string) error {
+	if err := c.fail("TrashThread", id); err != nil {
+		return err
+	}
+	return c.modifyThread(id, []string{"TRASH"}, []string{"INBOX"})
+}
+
+// modifyThread changes the labels of the messages of a thread
+func (c *Client) modifyThread(id string, add, remove []string) error {
+	c.mu.Lock()
+	var ids []string
+	for _, msg := range c.thread(id) {
+		ids = append(ids, msg.Id)
+	}
+	c.mu.Unlock()
+	if len(ids) == 0 {
+		return notFound("Requested entity was not found.")
+	}
+	return c.modify(ids, add, remove)
+}
+
+// ModifyMessage adds and removes labels of a message
+func (c *Client) ModifyMessage(ctx context.Context, id string, req *gmail.ModifyMessageRequest) error {
+	if err := c.fail("ModifyMessage", id); err != nil {
+		return err
+	}
+	return c.modify([]string{id}, req.AddLabelIds, req.RemoveLabelIds)
+}
+
+// BatchModifyMessages adds and removes labels of messages; Fail is called
+// for each of them
+func (c *Client) BatchModifyMessages(ctx context.Context, req *gmail.BatchModifyMessagesRequest) error {
+	for _, id := range req.Ids {
+		if err := c.fail("BatchModifyMessages", id); err != nil {
+			return err
+		}
+	}
+	if len(req.Ids) > 1000 {
+		return apiError(http.StatusBadRequest, "Too many IDs")
+	}
+	return c.modify(req.Ids, req.AddLabelIds, req.RemoveLabelIds)
+}
+
+// TrashMessage moves a message to the trash
+func (c *Client) TrashMessage(ctx context.Context, id string) error {
+	if err := c.fail("TrashMessage", id); err != nil {
+		return err
+	}
+	return c.modify([]string{id}, []string{"TRASH"}, []string{"INBOX"})
+}
+
+// modify changes the labels of messages. Nothing changes if a message or
+// label doesn't exist
+func (c *Client) modify(ids, add, remove []string) error {
+	c.mu.Lock()
+	defer c.mu.Unlock()
+
+	for _, id := range append(slices.Clone(add), remove...) {
+		if c.label(id) == nil {
+			return apiError(http.StatusBadRequest, "Invalid label: "+id)
+		}
+	}
+	for _, id := range ids {
+		if _, ok := c.messages[id]; !ok {
+			return notFound("Requested entity was not found.")
+		}
+	}
+	for _, id := range ids {
+		msg := c.messages[id]
+		msg.LabelIds = slices.DeleteFunc(msg.LabelIds, func(l string) bool { return slices.Contains(remove, l) })
+		for _, l := range add {
+			if !slices.Contains(msg.LabelIds, l) {
+				msg.LabelIds = append(msg.LabelIds, l)
+			}
+		}
+	}
+	return nil
+}
+
+// GetAttachment returns the data of an attachment added with AddMessage
+func (c *Client) GetAttachment(ctx context.Context, messageID, id string) (*gmail.MessagePartBody, error) {
+	if err := c.fail("GetAttachment", id); err != nil {
+		return nil, err
+	}
+	c.mu.Lock()
+	defer c.mu.Unlock()
+	data, ok := c.attachments[id]
+	if _, exists := c.messages[messageID]; !ok || !exists {
+		return nil, notFound("Requested entity was not found.")
+	}
+	return &gmail.MessagePartBody{AttachmentId: id, Data: data, Size: int64(base64.URLEncoding.DecodedLen(len(data)))}, nil
+}
+
+// ListLabels returns every label with its message counts; fields are ignored
+func (c *Client) ListLabels(ctx context.Context, fields ...googleapi.Field) ([]*gmail.Label, error) {
+	if err := c.fail("ListLabels", ""); err != nil {
+		return nil, err
+	}
+	c.mu.Lock()
+	defer c.mu.Unlock()
+
+	labels := make([]*gmail.Label, len(c.labels))
+	for i, l := range c.labels {
+		labels[i] = c.withCounts(l)
+	}
+	return labels, nil
+}
+
+// GetLabel returns a label with its message counts
+func (c *Client) GetLabel(ctx context.Context, id string) (*gmail.Label, error) {
+	if err := c.fail("GetLabel", id); err != nil {
+		return nil, err
+	}
+	c.mu.Lock()
+	defer c.mu.Unlock()
+	l := c.label(id)
+	if l == nil {
+		return nil, notFound("Requested entity was not found.")
+	}
+	return c.withCounts(l), nil
+}
+
+// CreateLabel creates a user label; names are unique, ignoring case
+func (c *Client) CreateLabel(ctx context.Context, label *gmail.Label) (*gmail.Label, error) {
+	if err := c.fail("CreateLabel", label.Name); err != nil {
+		return nil, err
+	}
+	c.mu.Lock()
+	defer c.mu.Unlock()
+	if label.Name == "" {
+		return nil, apiError(http.StatusBadRequest, "Invalid label name")
+	}
+	if c.labelByName(label.Name) != nil {
+		return nil, apiError(http.StatusConflict, "Label name exists or conflicts")
+	}
+	created := *c.createLabel(label)
+	return &created, nil
+}
+
+// PatchLabel changes the name, visibility and color of a user label when
+// set in label
+func (c *Client) PatchLabel(ctx context.Context, id string, label *gmail.Label) (*gmail.Label, error) {
+	if err := c.fail("PatchLabel", id); err != nil {
+		return nil, err
+	}
+	c.mu.Lock()
+	defer c.mu.Unlock()
+	l, err := c.userLabel(id)
+	if err != nil {
+		return nil, err
+	}
+	if label.Name != "" {
+		if other := c.labelByName(label.Name); other != nil && other != l {
+			return nil, apiError(http.StatusConflict, "Label name exists or conflicts")
+		}
+		l.Name = label.Name
+	}
+	if label.LabelListVisibility != "" {
+		l.LabelListVisibility = label.LabelListVisibility
+	}
+	if label.MessageListVisibility != "" {
+		l.MessageListVisibility = label.MessageListVisibility
+	}
+	if label.Color != nil {
+		color := *label.Color
+		l.Color = &color
+	}
+	patched := *l
+	return &patched, nil
+}
+
+// DeleteLabel deletes a user label and removes it from its messages
+func (c *Client) DeleteLabel(ctx context.Context, id string) error {
+	if err := c.fail("DeleteLabel", id); err != nil {
+		return err
+	}
+	c.mu.Lock()
+	defer c.mu.Unlock()
+	if _, err := c.userLabel(id); err != nil {
+		return err
+	}
+	c.labels = slices.DeleteFunc(c.labels, func(l *gmail.Label) bool { return l.Id == id })
+	for _, msg := range c.messages {
+		msg.LabelIds = slices.DeleteFunc(msg.LabelIds, func(l string) bool { return l == id })
+	}
+	return nil
+}
+
+// createLabel adds a user label; c.mu must be held
+func (c *Client) createLabel(label *gmail.Label) *gmail.Label {
+	created := *label
+	created.Id = c.newID("Label")
+	created.Type = "user"
+	c.labels = append(c.labels, &created)
+	return &created
+}
+
+// fail runs the Fail hook
+func (c *Client) fail(method, id string) error {
+	if c.Fail == nil {
+		return nil
+	}
+	return c.Fail(method, id)
+}
+
+// newID returns a new ID with a prefix; c.mu must be held
+func (c *Client) newID(prefix string) string {
+	c.nextID++
+	return fmt.Sprintf("%s_%d", prefix, c.nextID)
+}
+
+// label returns the label with an ID; c.mu must be held
+func (c *Client) label(id string) *gmail.Label {
+	i := slices.IndexFunc(c.labels, func(l *gmail.Label) bool { return l.Id == id })
+	if i < 0 {
+		return nil
+	}
+	return c.labels[i]
+}
+
+// labelByName returns the label with a name, ignoring case; c.mu must be held
+func (c *Client) labelByName(name string) *gmail.Label {
+	i := slices.IndexFunc(c.labels, func(l *gmail.Label) bool { return strings.EqualFold(l.Name, name) })
+	if i < 0 {
+		return nil
+	}
+	return c.labels[i]
+}
+
+// userLabel returns the user label with an ID, or the API error for a
+// missing or system label; c.mu must be held
+func (c *Client) userLabel(id string) (*gmail.Label, error) {
+	l := c.label(id)
+	switch {
+	case l == nil:
+		return nil, notFound("Requested entity was not found.")
+	case l.Type == "system":
+		return nil, apiError(http.StatusBadRequest, "Invalid label: "+id)
+	}
+	return l, nil
+}
+
+// withCounts returns a copy of a label with its message and thread counts;
+// c.mu must be held
+func (c *Client) withCounts(l *gmail.Label) *gmail.Label {
+	label := *l
+	threads, unreadThreads := make(map[string]bool), make(map[string]bool)
+	for _, msg := range c.messages {
+		if !slices.Contains(msg.LabelIds, l.Id) {
+			continue
+		}
+		label.MessagesTotal++
+		threads[msg.ThreadId] = true
+		if slices.Contains(msg.LabelIds, "UNREAD") {
+			label.MessagesUnread++
+			unreadThreads[msg.ThreadId] = true
+		}
+	}
+	label.ThreadsTotal, label.ThreadsUnread = int64(len(threads)), int64(len(unreadThreads))
+	return &label
+}
+
+// matching returns the messages matching opts, newest first; c.mu must be
+// held
+func (c *Client) matching(opts google.ListMessagesOptions) []*gmail.Message {
+	var matched []*gmail.Message
+	for _, msg := range c.messages {
+		if hasLabels(msg, opts.LabelIDs) && c.match(msg, opts.Query) {
+			matched = append(matched, msg)
+		}
+	}
+	slices.SortFunc(matched, func(a, b *gmail.Message) int {
+		if n := cmp.Compare(b.InternalDate, a.InternalDate); n != 0 {
+			return n
+		}
+		return strings.Compare(b.Id, a.Id)
+	})
+	return matched
+}
+
+// thread returns the messages of a thread, oldest first; c.mu must be held
+func (c *Client) thread(id string) []*gmail.Message {
+	var messages []*gmail.Message
+	for _, msg := range c.messages {
+		if msg.ThreadId == id {
+			messages = append(messages, msg)
+		}
+	}
+	slices.SortFunc(messages, func(a, b *gmail.Message) int {
+		if n := cmp.Compare(a.InternalDate, b.InternalDate); n != 0 {
+			return n
+		}
+		return strings.Compare(a.Id, b.Id)
+	})
+	return messages
+}
+
+// page returns the bounds of the page of n results selected by opts and the
+// token of the next page. The default page size is 100, the maximum 500
+func page(n int, opts google.ListMessagesOptions) (start, end int, next string, err error) {
+	if opts.PageToken != "" {
+		if start, err = strconv.Atoi(opts.PageToken); err != nil || start < 0 || start > n {
+			return 0, 0, "", apiError(http.StatusBadRequest, "Invalid pageToken")
+		}
+	}
+	size := int(opts.MaxResults)
+	if size <= 0 {
+		size = 100
+	}
+	end = min(start+min(size, 500), n)
+	if end < n {
+		next = strconv.Itoa(end)
+	}
+	return start, end, next, nil
+}
+
+// format returns a copy of a message in the format of opts
+func format(stored *gmail.Message, opts google.GetMessageOptions) (*gmail.Message, error) {
+	msg := clone(stored)
+	switch opts.Format {
+	case "", "full":
+		msg.Raw = ""
+	case "raw":
+		msg.Payload = nil
+	case "minimal":
+		msg.Payload, msg.Raw = nil, ""
+	case "metadata":
+		msg.Raw = ""
+		headers := msg.Payload.Headers
+		if len(opts.MetadataHeaders) > 0 {
+			headers = slices.DeleteFunc(headers, func(h *gmail.MessagePartHeader) bool {
+				return !slices.ContainsFunc(opts.MetadataHeaders, func(name string) bool {
+					return strings.EqualFold(name, h.Name)
+				})
+			})
+		}
+		msg.Payload = &gmail.MessagePart{MimeType: msg.Payload.MimeType, Headers: headers}
+	default:
+		return nil, apiError(http.StatusBadRequest, fmt.Sprintf("Invalid format: %q", opts.Format))
+	}
+	return msg, nil
+}
+
+// hasLabels reports whether a message has all the labels
+func hasLabels(msg *gmail.Message, ids []string) bool {
+	for _, id := range ids {
+		if !slices.Contains(msg.LabelIds, id) {
+			return false
+		}
+	}
+	return true
+}
+
+// clone returns a deep copy of a message
+func clone(msg *gmail.Message) *gmail.Message {
+	data, err := json.Marshal(msg)
+	if err != nil {
+		panic(err)
+	}
+	var copied gmail.Message
+	if err := json.Unmarshal(data, &copied); err != nil {
+		panic(err)
+	}
+	return &copied
+}
+
+// rawMessage returns the RFC 2822 form of a plain text message
+func rawMessage(headers []*gmail.MessagePartHeader, body string) []byte {
+	var b strings.Builder
+	for _, h := range headers {
+		fmt.Fprintf(&b, "%s: %s\r\n", h.Name, h.Value)
+	}
+	b.WriteString("Content-Type: text/plain; charset=UTF-8\r\n\r\n")
+	b.WriteString(body)
+	return []byte(b.String())
+}
+
+// encode encodes data like the API, in padded base64url
+func encode(data []byte) string {
+	return base64.URLEncoding.EncodeToString(data)
+}
+
+// notFound returns the error of the API for a missing message
+func notFound(message string) error {
+	return apiError(http.StatusNotFound, message)
+}
+
+// apiError returns an error like those of the API
+func apiError(code int, message string) error {
+	return &googleapi.Error{Code: code, Message: message}
+}
@@ -0,0 +1,148 @@
+package fakegmail
+
+import (
+	"slices"
+	"strconv"
+	"strings"
+	"time"
+
+	"google.golang.org/api/gmail/v1"
+)
+
+// match reports whether a message matches a search query, with Match if set.
+// The built-in matching supports words and quoted phrases, negation with -,
+// label:, in:, is:unread/read/starred/important, from:, to:, subject:,
+// has:attachment and after:/before: with YYYY/MM/DD dates or epoch seconds;
+// other operators never match. Like Gmail, trash and spam only match when the
+// query asks for them. c.mu must be held
+func (c *Client) match(msg *gmail.Message, query string) bool {
+	if c.Match != nil {
+		return c.Match(clone(msg), query)
+	}
+
+	terms := splitQuery(query)
+	if !slices.ContainsFunc(terms, includesHidden) &&
+		(slices.Contains(msg.LabelIds, "TRASH") || slices.Contains(msg.LabelIds, "SPAM")) {
+		return false
+	}
+	for _, term := range terms {
+		negate := strings.HasPrefix(term, "-") && len(term) > 1
+		if negate {
+			term = term[1:]
+		}
+		if c.matchTerm(msg, term) == negate {
+			return false
+		}
+	}
+	return true
+}
+
+// matchTerm reports whether a message matches one query term; c.mu must be
+// held
+func (c *Client) matchTerm(msg *gmail.Message, term string) bool {
+	key, value, ok := strings.Cut(term, ":")
+	if !ok || value == "" {
+		text := strings.Join([]string{header(msg, "From"), header(msg, "To"), header(msg, "Subject"), msg.Snippet}, "\n")
+		return containsFold(text, term)
+	}
+
+	switch strings.ToLower(key) {
+	case "label":
+		return slices.ContainsFunc(msg.LabelIds, func(id string) bool {
+			l := c.label(id)
+			return id == value || l != nil && labelQueryName(l.Name) == labelQueryName(value)
+		})
+	case "in":
+		switch strings.ToLower(value) {
+		case "anywhere":
+			return true
+		case "drafts":
+			return slices.Contains(msg.LabelIds, "DRAFT")
+		}
+		return slices.Contains(msg.LabelIds, strings.ToUpper(value))
+	case "is":
+		switch strings.ToLower(value) {
+		case "read":
+			return !slices.Contains(msg.LabelIds, "UNREAD")
+		case "unread", "starred", "important":
+			return slices.Contains(msg.LabelIds, strings.ToUpper(value))
+		}
+	case "from", "to", "subject":
+		return containsFold(header(msg, key), value)
+	case "has":
+		return strings.EqualFold(value, "attachment") && msg.Payload != nil &&
+			slices.ContainsFunc(msg.Payload.Parts, func(p *gmail.MessagePart) bool { return p.Filename != "" })
+	case "after", "before":
+		t, err := time.ParseInLocation("2006/01/02", value, time.Local)
+		if err != nil {
+			secs, err := strconv.ParseInt(value, 10, 64)
+			if err != nil {
+				return false
+			}
+			t = time.Unix(secs, 0)
+		}
+		if strings.EqualFold(key, "after") {
+			return msg.InternalDate >= t.UnixMilli()
+		}
+		return msg.InternalDate < t.UnixMilli()
+	}
+	return false
+}
+
+// includesHidden reports whether a term asks for trash or spam
+func includesHidden(term string) bool {
+	switch strings.ToLower(term) {
+	case "in:trash", "in:spam", "in:anywhere", "label:trash", "label:spam":
+		return true
+	}
+	return false
+}
+
+// splitQuery splits a query into terms at spaces outside double quotes,
+// removing the quotes
+func splitQuery(query string) []string {
+	var terms []string
+	var term strings.Builder
+	quoted := false
+	for _, r := range query {
+		switch {
+		case r == '"':
+			quoted = !quoted
+		case r == ' ' && !quoted:
+			if term.Len() > 0 {
+				terms = append(terms, term.String())
+				term.Reset()
+			}
+		default:
+			term.WriteRune(r)
+		}
+	}
+	if term.Len() > 0 {
+		terms = append(terms, term.String())
+	}
+	return terms
+}
+
+// labelQueryName returns a label name as written in queries, where spaces
+// and slashes are dashes
+func labelQueryName(name string) string {
+	return strings.ToLower(strings.NewReplacer(" ", "-", "/", "-").Replace(name))
+}
+
+// header returns the value of a header of a message
+func header(msg *gmail.Message, name string) string {
+	if msg.Payload == nil {
+		return ""
+	}
+	for _, h := range msg.Payload.Headers {
+		if strings.EqualFold(h.Name, name) {
+			return h.Value
+		}
+	}
+	return ""
+}
+
+// containsFold reports whether substr is in s, ignoring case
+func containsFold(s, substr string) bool {
+	return strings.Contains(strings.ToLower(s), strings.ToLower(substr))
+}
@@ -0,0 +1,140 @@
+package fakegmail
+
+import (
+	"context"
+	"fmt"
+	"slices"
+	"testing"
+	"time"
+
+	"github.com/longkey1/gml/internal/google"
+	"google.golang.org/api/gmail/v1"
+)
+
+func TestSplitQuery(t *testing.T) {
+	tests := []struct {
+		query string
+		want  []string
+	}{
+		{query: "", want: nil},
+		{query: "  from:bob   is:unread ", want: []string{"from:bob", "is:unread"}},
+		{query: `subject:"weekly report" -label:done`, want: []string{"subject:weekly report", "-label:done"}},
+		{query: `"exact phrase"`, want: []string{"exact phrase"}},
+		{query: `to:"unterminated`, want: []string{"to:unterminated"}},
+	}
+	for _, tt := range tests {
+		t.Run(tt.query, func(t *testing.T) {
+			if got := splitQuery(tt.query); !slices.Equal(got, tt.want) {
+				t.Errorf("splitQuery(%q) = %q, want %q", tt.query, got, tt.want)
+			}
+		})
+	}
+}
+
+func TestMatch(t *testing.T) {
+	c := New("me@example.com")
+	project := c.AddLabel("My Project/Q1")
+	day := time.Date(2025, 3, 14, 9, 0, 0, 0, time.Local)
+	ids := map[string]string{
+		"report": c.AddMessage(Message{
+			From: "Bob Smith <bob@example.com>", To: "me@example.com", Subject: "Weekly report",
+			Body: "Numbers are up", Date: day, LabelIDs: []string{"INBOX", "UNREAD", project},
+		}),
+		"invoice": c.AddMessage(Message{
+			From: "billing@shop.example", To: "me@example.com", Subject: "Invoice",
+			Date: day.AddDate(0, 0, 2), LabelIDs: []string{"INBOX", "STARRED", "IMPORTANT"},
+			Attachments: []Attachment{{Filename: "invoice.pdf", MimeType: "application/pdf", Data: []byte("%PDF")}},
+		}),
+		"trash": c.AddMessage(Message{From: "bob@example.com", Subject: "Old", Date: day.AddDate(0, 0, -2), LabelIDs: []string{"TRASH"}}),
+		"spam":  c.AddMessage(Message{From: "win@prize.example", Subject: "Winner", Date: day, LabelIDs: []string{"SPAM"}}),
+	}
+
+	tests := []struct {
+		query string
+		want  []string
+	}{
+		{query: "", want: []string{"invoice", "report"}},
+		{query: "from:bob", want: []string{"report"}},
+		{query: "FROM:BOB", want: []string{"report"}},
+		{query: "to:me@example.com -from:bob", want: []string{"invoice"}},
+		{query: `subject:"weekly report"`, want: []string{"report"}},
+		{query: "numbers", want: []string{"report"}},
+		{query: "label:my-project-q1", want: []string{"report"}},
+		{query: "label:" + project, want: []string{"report"}},
+		{query: "-label:my-project-q1", want: []string{"invoice"}},
+		{query: "in:inbox is:unread", want: []string{"report"}},
+		{query: "is:read", want: []string{"invoice"}},
+		{query: "is:starred is:important", want: []string{"invoice"}},
+		{query: "has:attachment", want: []string{"invoice"}},
+		{query: "after:2025/03/15", want: []string{"invoice"}},
+		{query: "before:2025/03/15", want: []string{"report"}},
+		{query: fmt.Sprintf("before:%d", day.Add(time.Hour).Unix()), want: []string{"report"}},
+		{query: fmt.Sprintf("after:%d", day.Unix()), want: []string{"invoice", "report"}},
+		{query: "after:yesterday", want: nil},
+		{query: "in:trash", want: []string{"trash"}},
+		{query: "in:spam", want: []string{"spam"}},
+		{query: "from:bob in:anywhere", want: []string{"report", "trash"}},
+		{query: "larger:1M", want: nil},
+	}
+	for _, tt := range tests {
+		t.Run(tt.query, func(t *testing.T) {
+			resp, err := c.ListMessages(context.Background(), google.ListMessagesOptions{Query: tt.query})
+			if err != nil {
+				t.Fatal(err)
+			}
+			var got []string
+			for _, m := range resp.Messages {
+				for name, id := range ids {
+					if m.Id == id {
+						got = append(got, name)
+					}
+				}
+			}
+			slices.Sort(got)
+			if !slices.Equal(got, tt.want) {
+				t.Errorf("ListMessages(%q) = %v, want %v", tt.query, got, tt.want)
+			}
+		})
+	}
+}
+
+func TestMatchHook(t *testing.T) {
+	c := New("me@example.com")
+	c.AddMessage(Message{Subject: "a", LabelIDs: []string{"INBOX"}})
+	keep := c.AddMessage(Message{Subject: "b", LabelIDs: []string{"TRASH"}})
+
+	var queries []string
+	c.Match = func(msg *gmail.Message, query string) bool {
+		queries = append(queries, query)
+		// Changes to the copy don't reach the mailbox
+		msg.LabelIds = nil
+		return msg.Id == keep
+	}
+	resp, err := c.ListMessages(context.Background(), google.ListMessagesOptions{Query: "custom:op"})
+	if err != nil {
+		t.Fatal(err)
+	}
+	if len(resp.Messages) != 1 || resp.Messages[0].Id != keep {
+		t.Errorf("ListMessages() with Match = %+v, want only %s, trash included", resp.Messages, keep)
+	}
+	if len(queries) != 2 || queries[0] != "custom:op" {
+		t.Errorf("Match called with %q, want the query for each message", queries)
+	}
+	if got := c.Message(keep).LabelIds; !slices.Equal(got, []string{"TRASH"}) {
+		t.Errorf("labels after Match = %v, want [TRASH]", got)
+	}
+}
+
+func TestLabelQueryName(t *testing.T) {
+	tests := []struct{ name, want string }{
+		{"Work", "work"},
+		{"My Project", "my-project"},
+		{"Clients/Acme Corp", "clients-acme-corp"},
+		{"Ünïcode Label", "ünïcode-label"},
+	}
+	for _, tt := range tests {
+		if got := labelQueryName(tt.name); got != tt.want {
+			t.Errorf("labelQueryName(%q) = %q, want %q", tt.name, got, tt.want)
+		}
+	}
+}
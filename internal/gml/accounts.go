@@ -0,0 +1,124 @@
+package gml
+
+import (
+	"errors"
+	"fmt"
+	"maps"
+	"os"
+	"path/filepath"
+	"sort"
+	"strings"
+)
+
+// AllAccounts is the account name that selects every account profile (e.g.
+// --account all), so no profile may use it
+const AllAccounts = "all"
+
+// AccountConfig holds the credentials for a named account profile
+// Empty fields inherit the top-level configuration values, except the token:
+// without user_credentials the profile uses token-<name>.json, so accounts
+// never share the top-level token
+type AccountConfig struct {
+	AuthType                     AuthType     `mapstructure:"auth_type"`
+	GoogleApplicationCredentials string       `mapstructure:"application_credentials"`
+	GoogleUserCredentials        string       `mapstructure:"user_credentials"`
+	Scopes                       []string     `mapstructure:"scopes"`
+	TokenStorage                 TokenStorage `mapstructure:"token_storage"`
+	// Headers are added to the top-level headers, replacing those with the same name
+	Headers map[string]string `mapstructure:"headers"`
+}
+
+// AccountInfo represents an account profile for output
+type AccountInfo struct {
+	Name            string   `json:"name"`
+	AuthType        AuthType `json:"authType"`
+	UserCredentials string   `json:"userCredentials,omitempty"`
+	Current         bool     `json:"current"`
+}
+
+// ListAccounts returns the configured accounts, marking the current one
+func (c *Config) ListAccounts(current string) []AccountInfo {
+	accounts := make([]AccountInfo, 0, len(c.Accounts))
+	for _, name := range c.AccountNames() {
+		acct, err := c.ForAccount(name)
+		if err != nil {
+			continue
+		}
+		accounts = append(accounts, AccountInfo{
+			Name:            name,
+			AuthType:        acct.AuthType,
+			UserCredentials: acct.GoogleUserCredentials,
+			Current:         strings.EqualFold(name, current),
+		})
+	}
+	return accounts
+}
+
+// AccountNames returns the configured account names in sorted order
+func (c *Config) AccountNames() []string {
+	names := make([]string, 0, len(c.Accounts))
+	for name := range c.Accounts {
+		names = append(names, name)
+	}
+	sort.Strings(names)
+	return names
+}
+
+// ForAccount returns a copy of the configuration with the named account's
+// credentials applied
+func (c *Config) ForAccount(name string) (*Config, error) {
+	key := strings.ToLower(name)
+	acct, ok := c.Accounts[key]
+	if !ok {
+		return nil, notFoundError("account not found: %s", name)
+	}
+
+	cfg := *c
+	cfg.Account = key
+	if acct.AuthType != "" {
+		cfg.AuthType = acct.AuthType
+	}
+	if acct.GoogleApplicationCredentials != "" {
+		cfg.GoogleApplicationCredentials = acct.GoogleApplicationCredentials
+	}
+	// The top-level token belongs to the default account; an empty path
+	// selects the account's own default token file
+	cfg.GoogleUserCredentials = acct.GoogleUserCredentials
+	cfg.Tokens = nil
+	if len(acct.Scopes) > 0 {
+		cfg.Scopes = acct.Scopes
+	}
+	if acct.TokenStorage != "" {
+		cfg.TokenStorage = acct.TokenStorage
+	}
+	if len(acct.Headers) > 0 {
+		cfg.Headers = make(map[string]string, len(c.Headers)+len(acct.Headers))
+		maps.Copy(cfg.Headers, c.Headers)
+		maps.Copy(cfg.Headers, acct.Headers)
+	}
+	return &cfg, nil
+}
+
+// ReadCurrentAccount reads the account selected by 'gml account switch'
+// It returns an empty string if no account has been selected
+func ReadCurrentAccount(path string) (string, error) {
+	b, err := os.ReadFile(path)
+	if errors.Is(err, os.ErrNotExist) {
+		return "", nil
+	}
+	if err != nil {
+		return "", fmt.Errorf("unable to read current account: %w", err)
+	}
+	return strings.TrimSpace(string(b)), nil
+}
+
+// WriteCurrentAccount persists the selected account name
+func WriteCurrentAccount(path, name string) error {
+	if err := os.MkdirAll(filepath.Dir(path), 0700); err != nil {
+		return fmt.Errorf("unable to create config directory: %w", err)
+	}
+	if err := os.WriteFile(path, []byte(name+"\n"), 0600); err != nil {
+		return fmt.Errorf("unable to write current account: %w", err)
+	}
+	return nil
+}
@@ -0,0 +1,47 @@
+package gml
+
+import (
+	"bytes"
+	"context"
+	"fmt"
+	"io"
+	"net/http"
+	"slices"
+	"strings"
+)
+
+// apiMethods are the HTTP methods accepted by CallAPI
+var apiMethods = []string{http.MethodGet, http.MethodPost, http.MethodPut, http.MethodPatch, http.MethodDelete}
+
+// APIResponse is the status and raw body of a Gmail API request
+type APIResponse struct {
+	StatusCode int
+	Status     string
+	Body       []byte
+}
+
+// CallAPI sends an arbitrary request to the Gmail REST API with the service's
+// credentials, for API surface gml doesn't wrap. path is relative to gmail/v1/
+// (users/me/messages/ID?format=metadata); body may be nil
+func CallAPI(ctx context.Context, svc *Service, method, path string, body []byte) (*APIResponse, error) {
+	method = strings.ToUpper(method)
+	if !slices.Contains(apiMethods, method) {
+		return nil, fmt.Errorf("unsupported method %s (use get, post, put, patch or delete)", method)
+	}
+
+	var reader io.Reader
+	if body != nil {
+		reader = bytes.NewReader(body)
+	}
+	resp, err := svc.Gmail.Do(ctx, method, path, reader)
+	if err != nil {
+		return nil, fmt.Errorf("unable to call API: %w", apiError(err))
+	}
+	defer resp.Body.Close()
+
+	data, err := io.ReadAll(resp.Body)
+	if err != nil {
+		return nil, fmt.Errorf("unable to read API response: %w", err)
+	}
+	return &APIResponse{StatusCode: resp.StatusCode, Status: resp.Status, Body: data}, nil
+}
@@ -0,0 +1,196 @@
+package gml
+
+import (
+	"context"
+	"fmt"
+	"math/rand/v2"
+	"strings"
+
+	"google.golang.org/api/gmail/v1"
+)
+
+// AssignStrategy determines how messages are distributed across assignees
+type AssignStrategy string
+
+const (
+	AssignStrategyRoundRobin  AssignStrategy = "round-robin"
+	AssignStrategyLeastLoaded AssignStrategy = "least-loaded"
+	AssignStrategyRandom      AssignStrategy = "random"
+)
+
+// AssignStateName is the state file name of the round-robin positions
+const AssignStateName = "assign"
+
+// assignState holds where round-robin assignment continues, per assignRotationKey
+type assignState struct {
+	Next map[string]int `json:"next"`
+}
+
+// batchModifyLimit is the maximum number of IDs accepted by Users.Messages.BatchModify
+const batchModifyLimit = 1000
+
+// Assignment represents a message assigned to a label
+type Assignment struct {
+	ID    string `json:"id"`
+	Label string `json:"label"`
+}
+
+// AssignOptions contains options for distributing messages across labels
+type AssignOptions struct {
+	Query        string
+	LabelIDs     []string
+	Assignees    []string
+	RemoveLabels []string
+	Strategy     AssignStrategy
+	DryRun       bool
+	// StatePath keeps the round-robin position between runs, so each run
+	// continues with the assignee after the last one used for the same query,
+	// filter labels and assignees. Empty starts with the first assignee
+	StatePath string
+}
+
+// AssignMessages distributes messages matching the query across assignee labels
+// and applies the labels with BatchModify
+func AssignMessages(ctx context.Context, svc *Service, opts AssignOptions) ([]Assignment, error) {
+	if len(opts.Assignees) == 0 {
+		return nil, fmt.Errorf("at least one assignee label is required")
+	}
+
+	idx, err := FetchLabelIndex(ctx, svc)
+	if err != nil {
+		return nil, err
+	}
+
+	assigneeIDs, err := idx.ResolveLabelIDs(opts.Assignees)
+	if err != nil {
+		return nil, err
+	}
+	removeIDs, err := idx.ResolveLabelIDs(opts.RemoveLabels)
+	if err != nil {
+		return nil, err
+	}
+	filterIDs, err := idx.ResolveLabelIDs(opts.LabelIDs)
+	if err != nil {
+		return nil, err
+	}
+
+	ids, err := ListMessageIDs(ctx, svc, opts.Query, filterIDs)
+	if err != nil {
+		return nil, err
+	}
+	if len(ids) == 0 {
+		return nil, nil
+	}
+
+	var state assignState
+	key := assignRotationKey(opts.Query, filterIDs, assigneeIDs)
+	rotate := opts.StatePath != "" && (opts.Strategy == AssignStrategyRoundRobin || opts.Strategy == "")
+	if rotate {
+		if _, err := LoadState(opts.StatePath, &state); err != nil {
+			return nil, err
+		}
+	}
+
+	next, err := newAssigner(ctx, svc, opts.Strategy, assigneeIDs, state.Next[key])
+	if err != nil {
+		return nil, err
+	}
+
+	// Group message IDs per assignee label so each label needs few BatchModify calls
+	byLabel := make(map[string][]string)
+	var assignments []Assignment
+	for _, id := range ids {
+		labelID := next()
+		byLabel[labelID] = append(byLabel[labelID], id)
+		assignments = append(assignments, Assignment{
+			ID:    id,
+			Label: idx.MapLabelIDsToNames([]string{labelID})[0],
+		})
+	}
+
+	if opts.DryRun {
+		return assignments, nil
+	}
+
+	for _, labelID := range assigneeIDs {
+		if err := batchModify(ctx, svc, byLabel[labelID], []string{labelID}, removeIDs, nil); err != nil {
+			return nil, err
+		}
+	}
+
+	if rotate && !IsDryRun(ctx) {
+		if state.Next == nil {
+			state.Next = make(map[string]int)
+		}
+		state.Next[key] = (state.Next[key] + len(ids)) % len(assigneeIDs)
+		if err := SaveState(opts.StatePath, state); err != nil {
+			return nil, err
+		}
+	}
+
+	return assignments, nil
+}
+
+// assignRotationKey identifies a round-robin rotation in the assign state
+func assignRotationKey(query string, filterIDs, assigneeIDs []string) string {
+	return strings.Join(assigneeIDs, ",") + "|" + strings.Join(filterIDs, ",") + "|" + query
+}
+
+// newAssigner returns a function yielding the next assignee label ID for the
+// strategy; round-robin starts with labelIDs[start]
+func newAssigner(ctx context.Context, svc *Service, strategy AssignStrategy, labelIDs []string, start int) (func() string, error) {
+	switch strategy {
+	case AssignStrategyRoundRobin, "":
+		i := max(start, 0)
+		return func() string {
+			id := labelIDs[i%len(labelIDs)]
+			i++
+			return id
+		}, nil
+	case AssignStrategyRandom:
+		return func() string {
+			return labelIDs[rand.IntN(len(labelIDs))]
+		}, nil
+	case AssignStrategyLeastLoaded:
+		// Start from each label's current message count and always pick the lightest
+		loads := make([]int64, len(labelIDs))
+		for i, id := range labelIDs {
+			label, err := svc.API.GetLabel(ctx, id)
+			if err != nil {
+				return nil, fmt.Errorf("unable to get label: %w", apiError(err))
+			}
+			loads[i] = label.MessagesTotal
+		}
+		return func() string {
+			lightest := 0
+			for i := range loads {
+				if loads[i] < loads[lightest] {
+					lightest = i
+				}
+			}
+			loads[lightest]++
+			return labelIDs[lightest]
+		}, nil
+	default:
+		return nil, fmt.Errorf("unknown strategy: %s", strategy)
+	}
+}
+
+// batchModify applies label changes to messages in chunks accepted by BatchModify
+// A failed chunk is recorded for all its messages when failures are ignored
+func batchModify(ctx context.Context, svc *Service, ids, addLabelIDs, removeLabelIDs []string, failures *FailureLog) error {
+	for start := 0; start < len(ids); start += batchModifyLimit {
+		end := min(start+batchModifyLimit, len(ids))
+		req := &gmail.BatchModifyMessagesRequest{
+			Ids:            ids[start:end],
+			AddLabelIds:    addLabelIDs,
+			RemoveLabelIds: removeLabelIDs,
+		}
+		if err := svc.API.BatchModifyMessages(ctx, req); err != nil {
+			if err := failures.Record(fmt.Errorf("unable to modify messages: %w", apiError(err)), ids[start:end]...); err != nil {
+				return err
+			}
+		}
+	}
+	return nil
+}
@@ -0,0 +1,72 @@
+package gml
+
+import (
+	"bytes"
+	"context"
+	"path/filepath"
+	"slices"
+	"testing"
+
+	"github.com/longkey1/gml/internal/fakegmail"
+)
+
+func TestAssignMessagesRoundRobinResumes(t *testing.T) {
+	svc, fake := newTestService(t)
+	for _, name := range []string{"alice", "bob", "carol"} {
+		fake.AddLabel(name)
+	}
+	statePath := filepath.Join(t.TempDir(), "assign.json")
+	ctx := context.Background()
+
+	// Each run assigns the new inbox messages and takes them out of the inbox
+	assign := func(ctx context.Context, n int, assignees []string, dryRun bool) []string {
+		t.Helper()
+		addMessages(fake, n, fakegmail.Message{LabelIDs: []string{"INBOX"}})
+		assignments, err := AssignMessages(ctx, svc, AssignOptions{
+			LabelIDs:     []string{"INBOX"},
+			Assignees:    assignees,
+			RemoveLabels: []string{"INBOX"},
+			DryRun:       dryRun,
+			StatePath:    statePath,
+		})
+		if err != nil {
+			t.Fatal(err)
+		}
+		var labels []string
+		for _, a := range assignments {
+			labels = append(labels, a.Label)
+		}
+		if dryRun {
+			// The preview leaves the messages in the inbox; clear them for the next run
+			for _, a := range assignments {
+				if err := ModifyMessage(ctx, svc, a.ID, nil, []string{"INBOX"}); err != nil {
+					t.Fatal(err)
+				}
+			}
+		}
+		return labels
+	}
+
+	team := []string{"alice", "bob", "carol"}
+	tests := []struct {
+		name      string
+		ctx       context.Context
+		n         int
+		assignees []string
+		dryRun    bool
+		want      []string
+	}{
+		{name: "first run", ctx: ctx, n: 1, assignees: team, want: []string{"alice"}},
+		{name: "continues", ctx: ctx, n: 2, assignees: team, want: []string{"bob", "carol"}},
+		{name: "preview", ctx: ctx, n: 1, assignees: team, dryRun: true, want: []string{"alice"}},
+		{name: "global dry run", ctx: WithDryRun(ctx, &bytes.Buffer{}), n: 1, assignees: team, want: []string{"alice"}},
+		{name: "wraps around", ctx: ctx, n: 2, assignees: team, want: []string{"alice", "bob"}},
+		{name: "other assignees", ctx: ctx, n: 1, assignees: []string{"carol", "alice"}, want: []string{"carol"}},
+		{name: "after other assignees", ctx: ctx, n: 1, assignees: team, want: []string{"carol"}},
+	}
+	for _, tt := range tests {
+		if got := assign(tt.ctx, tt.n, tt.assignees, tt.dryRun); !slices.Equal(got, tt.want) {
+			t.Errorf("%s: assigned %v, want %v", tt.name, got, tt.want)
+		}
+	}
+}
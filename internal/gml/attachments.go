@@ -0,0 +1,165 @@
+package gml
+
+import (
+	"context"
+	"encoding/base64"
+	"fmt"
+	"io"
+	"strconv"
+	"strings"
+
+	"github.com/longkey1/gml/internal/google"
+	"google.golang.org/api/gmail/v1"
+)
+
+// AttachmentInfo describes an attachment of a message
+type AttachmentInfo struct {
+	// PartID identifies the MIME part within the message, e.g. "1.2"
+	PartID   string `json:"partId"`
+	Filename string `json:"filename"`
+	MimeType string `json:"mimeType"`
+	Size     int64  `json:"size"`
+
+	attachmentID string
+	data         string
+}
+
+// ListAttachments returns the parts of payload that carry a filename, in
+// message order
+func ListAttachments(payload *gmail.MessagePart) []AttachmentInfo {
+	var attachments []AttachmentInfo
+	var walk func(p *gmail.MessagePart)
+	walk = func(p *gmail.MessagePart) {
+		if p == nil {
+			return
+		}
+		if p.Filename != "" && p.Body != nil {
+			attachments = append(attachments, AttachmentInfo{
+				PartID:       p.PartId,
+				Filename:     p.Filename,
+				MimeType:     p.MimeType,
+				Size:         p.Body.Size,
+				attachmentID: p.Body.AttachmentId,
+				data:         p.Body.Data,
+			})
+		}
+		for _, child := range p.Parts {
+			walk(child)
+		}
+	}
+	walk(payload)
+	return attachments
+}
+
+// FormatSize renders a byte count for display, e.g. 512 B, 1.2 KB, 3.4 MB
+func FormatSize(n int64) string {
+	const unit = 1024
+	if n < unit {
+		return fmt.Sprintf("%d B", n)
+	}
+	value, suffix := float64(n)/unit, "KB"
+	for _, s := range []string{"MB", "GB", "TB"} {
+		if value < unit {
+			break
+		}
+		value, suffix = value/unit, s
+	}
+	return fmt.Sprintf("%.1f %s", value, suffix)
+}
+
+// ParseSize parses a size such as "500K", "5M", "1.5GB" or a plain byte count
+// Units are powers of 1024, like FormatSize; the B suffix is optional
+func ParseSize(s string) (int64, error) {
+	num := strings.ToUpper(strings.TrimSpace(s))
+	num = strings.TrimSuffix(num, "B")
+	mult := int64(1)
+	if n := len(num); n > 0 {
+		switch num[n-1] {
+		case 'K':
+			mult = 1 << 10
+		case 'M':
+			mult = 1 << 20
+		case 'G':
+			mult = 1 << 30
+		}
+		if mult > 1 {
+			num = strings.TrimSpace(num[:n-1])
+		}
+	}
+	value, err := strconv.ParseFloat(num, 64)
+	if err != nil || value < 0 {
+		return 0, fmt.Errorf("invalid size %q (e.g. 500K, 5M, 1G)", s)
+	}
+	return int64(value * float64(mult)), nil
+}
+
+// GetAttachments fetches a message and returns its attachments
+func GetAttachments(ctx context.Context, svc *Service, messageID string) ([]AttachmentInfo, error) {
+	msg, err := svc.API.GetMessage(ctx, messageID, google.GetMessageOptions{Format: "full"})
+	if err != nil {
+		return nil, fmt.Errorf("unable to retrieve message: %w", apiError(err))
+	}
+	return ListAttachments(msg.Payload), nil
+}
+
+// FindAttachment picks the attachment named name, matching the filename
+// exactly, then case-insensitively, then by part ID
+func FindAttachment(attachments []AttachmentInfo, name string) (AttachmentInfo, error) {
+	match := func(eq func(a AttachmentInfo) bool) []AttachmentInfo {
+		var found []AttachmentInfo
+		for _, a := range attachments {
+			if eq(a) {
+				found = append(found, a)
+			}
+		}
+		return found
+	}
+
+	for _, eq := range []func(a AttachmentInfo) bool{
+		func(a AttachmentInfo) bool { return a.Filename == name },
+		func(a AttachmentInfo) bool { return strings.EqualFold(a.Filename, name) },
+		func(a AttachmentInfo) bool { return a.PartID == name },
+	} {
+		switch found := match(eq); len(found) {
+		case 0:
+			continue
+		case 1:
+			return found[0], nil
+		default:
+			parts := make([]string, len(found))
+			for i, a := range found {
+				parts[i] = a.PartID
+			}
+			return AttachmentInfo{}, fmt.Errorf("several attachments are named %s; use a part ID instead: %s", name, strings.Join(parts, ", "))
+		}
+	}
+
+	if len(attachments) == 0 {
+		return AttachmentInfo{}, fmt.Errorf("message has no attachments")
+	}
+	names := make([]string, len(attachments))
+	for i, a := range attachments {
+		names[i] = a.Filename
+	}
+	return AttachmentInfo{}, notFoundError("attachment not found: %s (available: %s)", name, strings.Join(names, ", "))
+}
+
+// WriteAttachment writes the decoded content of an attachment of messageID
+// to w. Small attachments are inlined in the message; others are downloaded
+func WriteAttachment(ctx context.Context, svc *Service, messageID string, a AttachmentInfo, w io.Writer) error {
+	data := a.data
+	if data == "" && a.attachmentID != "" {
+		body, err := svc.API.GetAttachment(ctx, messageID, a.attachmentID)
+		if err != nil {
+			return fmt.Errorf("unable to download attachment: %w", apiError(err))
+		}
+		data = body.Data
+	}
+
+	// Gmail pads base64url inconsistently; decode without padding
+	dec := base64.NewDecoder(base64.RawURLEncoding, strings.NewReader(strings.TrimRight(data, "=")))
+	if _, err := io.Copy(w, dec); err != nil {
+		return fmt.Errorf("unable to write attachment: %w", err)
+	}
+	return nil
+}
@@ -0,0 +1,93 @@
+package gml
+
+import (
+	"context"
+	"fmt"
+	"time"
+
+	"github.com/longkey1/gml/internal/google"
+)
+
+// AuthStatus describes the current OAuth credentials
+type AuthStatus struct {
+	Account         string    `json:"account,omitempty"`
+	Email           string    `json:"email"`
+	Storage         string    `json:"storage"`
+	Scopes          []string  `json:"scopes"`
+	Expiry          time.Time `json:"expiry"`
+	Valid           bool      `json:"valid"`
+	HasRefreshToken bool      `json:"hasRefreshToken"`
+	// Error tells why the token is not valid
+	Error string `json:"error,omitempty"`
+}
+
+// GetAuthStatus reports the validity, scopes, expiry and email of the stored
+// token. The token is valid when Gmail accepts it for the profile request; a
+// missing, expired or revoked token is reported with Valid false and the
+// reason in Error rather than as an error
+func GetAuthStatus(ctx context.Context, config *Config) (*AuthStatus, error) {
+	auth, err := newOAuthAuthenticator(config)
+	if err != nil {
+		return nil, err
+	}
+	store, err := config.NewTokenStore()
+	if err != nil {
+		return nil, err
+	}
+	status := &AuthStatus{Account: config.Account, Storage: store.String()}
+
+	info, err := auth.TokenInfo(ctx)
+	if err != nil {
+		status.Error = err.Error()
+		return status, nil
+	}
+	status.Scopes = info.Scopes
+	status.Expiry = info.Expiry
+	status.HasRefreshToken = info.HasRefreshToken
+
+	svc, err := NewService(ctx, config)
+	if err != nil {
+		return nil, err
+	}
+	checkAuth(ctx, svc, status)
+	return status, nil
+}
+
+// checkAuth sets the email and validity of status from a profile request
+func checkAuth(ctx context.Context, svc *Service, status *AuthStatus) {
+	email, err := GetUserEmail(ctx, svc)
+	if err != nil {
+		status.Valid = false
+		status.Error = err.Error()
+		return
+	}
+	status.Email = email
+	status.Valid = true
+}
+
+// RevokeAuth revokes the stored token with Google and deletes it locally.
+// Under WithDryRun the revocation is only reported
+func RevokeAuth(ctx context.Context, config *Config) error {
+	auth, err := newOAuthAuthenticator(config)
+	if err != nil {
+		return err
+	}
+	return auth.Revoke(ctx)
+}
+
+// newOAuthAuthenticator builds the OAuth authenticator for the configuration
+func newOAuthAuthenticator(config *Config) (*google.OAuthAuthenticator, error) {
+	if config.AuthType != AuthTypeOAuth {
+		return nil, fmt.Errorf("only available for OAuth authentication (current: %s)", config.AuthType)
+	}
+
+	scopes, err := config.OAuthScopes()
+	if err != nil {
+		return nil, err
+	}
+	store, err := config.NewTokenStore()
+	if err != nil {
+		return nil, err
+	}
+	return google.NewOAuthAuthenticator(config.OAuthClientCredentials(), store, scopes...), nil
+}
@@ -0,0 +1,48 @@
+package gml
+
+import (
+	"context"
+	"errors"
+	"net/http"
+	"strings"
+	"testing"
+
+	"google.golang.org/api/googleapi"
+)
+
+func TestCheckAuth(t *testing.T) {
+	tests := []struct {
+		name      string
+		fail      error
+		wantValid bool
+		wantError string
+	}{
+		{name: "accepted", wantValid: true},
+		{name: "revoked", fail: &googleapi.Error{Code: http.StatusUnauthorized, Message: "Invalid Credentials"}, wantError: "Invalid Credentials"},
+		{name: "unreachable", fail: errors.New("connection refused"), wantError: "connection refused"},
+	}
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			svc, fake := newTestService(t)
+			fake.Fail = func(method, id string) error {
+				if method == "GetProfile" {
+					return tt.fail
+				}
+				return nil
+			}
+
+			// A status claiming validity must not survive a failed check
+			status := &AuthStatus{Valid: true}
+			checkAuth(context.Background(), svc, status)
+			if status.Valid != tt.wantValid {
+				t.Errorf("Valid = %v, want %v", status.Valid, tt.wantValid)
+			}
+			if tt.wantValid && (status.Email != testEmail || status.Error != "") {
+				t.Errorf("checkAuth() = %+v, want %s without an error", status, testEmail)
+			}
+			if !tt.wantValid && !strings.Contains(status.Error, tt.wantError) {
+				t.Errorf("Error = %q, want it to contain %q", status.Error, tt.wantError)
+			}
+		})
+	}
+}
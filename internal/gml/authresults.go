@@ -0,0 +1,225 @@
+package gml
+
+import (
+	"strconv"
+	"strings"
+
+	"google.golang.org/api/gmail/v1"
+)
+
+// authHeaders are the headers ParseAuthResults reads
+var authHeaders = []string{"Authentication-Results", "ARC-Authentication-Results", "ARC-Seal"}
+
+// AuthVerdict is the result of one authentication method and the domain it
+// was evaluated for
+type AuthVerdict struct {
+	// Result is pass, fail, softfail, neutral, none, temperror, permerror, ...
+	Result string `json:"result"`
+	// Domain is the envelope sender domain (spf), signing domain (dkim) or
+	// From domain (dmarc)
+	Domain string `json:"domain,omitempty"`
+}
+
+// AuthResults holds the SPF, DKIM and DMARC verdicts of a message, as
+// recorded by the receiving server in Authentication-Results (RFC 8601)
+type AuthResults struct {
+	// Server is the authserv-id of the header the verdicts were taken from
+	Server string       `json:"server,omitempty"`
+	SPF    *AuthVerdict `json:"spf,omitempty"`
+	// DKIM has a verdict per signature
+	DKIM  []AuthVerdict `json:"dkim,omitempty"`
+	DMARC *AuthVerdict  `json:"dmarc,omitempty"`
+	// ARC is the validation result of the ARC chain (RFC 8617), which
+	// vouches for the verdicts of forwarders and mailing lists
+	ARC *AuthVerdict `json:"arc,omitempty"`
+}
+
+// String summarizes the verdicts, e.g. "spf=pass dkim=pass (example.com) dmarc=pass"
+func (a *AuthResults) String() string {
+	if a == nil {
+		return ""
+	}
+	var parts []string
+	add := func(method string, v *AuthVerdict) {
+		if v == nil {
+			return
+		}
+		part := method + "=" + v.Result
+		if v.Domain != "" {
+			part += " (" + v.Domain + ")"
+		}
+		parts = append(parts, part)
+	}
+	add("spf", a.SPF)
+	for i := range a.DKIM {
+		add("dkim", &a.DKIM[i])
+	}
+	add("dmarc", a.DMARC)
+	add("arc", a.ARC)
+	return strings.Join(parts, " ")
+}
+
+// ParseAuthResults extracts the authentication verdicts of a message. Only
+// the topmost Authentication-Results header is used: it was added by the
+// receiving server (Gmail), while lower ones may have been forged by the
+// sender. Without one, the verdicts of the latest ARC hop are used. It
+// returns nil when the message carries neither
+func ParseAuthResults(payload *gmail.MessagePart) *AuthResults {
+	if payload == nil {
+		return nil
+	}
+
+	var results, arcResults, arcSeal string
+	arcInstance, sealInstance := 0, 0
+	for _, h := range payload.Headers {
+		switch strings.ToLower(h.Name) {
+		case "authentication-results":
+			if results == "" {
+				results = h.Value
+			}
+		case "arc-authentication-results":
+			// The highest instance is the latest hop
+			if i := arcInstanceOf(h.Value); i > arcInstance {
+				arcInstance, arcResults = i, h.Value
+			}
+		case "arc-seal":
+			if i := arcInstanceOf(h.Value); i > sealInstance {
+				sealInstance, arcSeal = i, h.Value
+			}
+		}
+	}
+	if results == "" {
+		// ARC-Authentication-Results starts with the instance tag
+		if _, rest, ok := strings.Cut(arcResults, ";"); ok {
+			results = rest
+		}
+	}
+	if results == "" && arcSeal == "" {
+		return nil
+	}
+
+	a := parseAuthenticationResults(results)
+	if a.ARC == nil && arcSeal != "" {
+		// cv= of the latest seal is the chain validation state it saw
+		if cv := tagValue(arcSeal, "cv"); cv != "" {
+			a.ARC = &AuthVerdict{Result: strings.ToLower(cv)}
+		}
+	}
+	return a
+}
+
+// parseAuthenticationResults parses an Authentication-Results value:
+// authserv-id followed by "method=result ptype.property=value ..." clauses
+func parseAuthenticationResults(value string) *AuthResults {
+	a := &AuthResults{}
+	clauses := strings.Split(stripHeaderComments(value), ";")
+	for i, clause := range clauses {
+		tokens := strings.Fields(clause)
+		if len(tokens) == 0 {
+			continue
+		}
+		if i == 0 {
+			a.Server = tokens[0]
+			continue
+		}
+
+		method, result, ok := strings.Cut(tokens[0], "=")
+		if !ok {
+			continue
+		}
+		// The method may carry a version, e.g. dkim/1
+		method, _, _ = strings.Cut(strings.ToLower(method), "/")
+		props := make(map[string]string)
+		for _, t := range tokens[1:] {
+			if k, v, ok := strings.Cut(t, "="); ok {
+				props[strings.ToLower(k)] = strings.Trim(v, `"`)
+			}
+		}
+
+		v := AuthVerdict{Result: strings.ToLower(result)}
+		switch method {
+		case "spf":
+			v.Domain = mailDomain(firstOf(props, "smtp.mailfrom", "smtp.helo"))
+			a.SPF = &v
+		case "dkim":
+			v.Domain = firstOf(props, "header.d", "header.i")
+			if at := strings.LastIndex(v.Domain, "@"); at >= 0 {
+				v.Domain = v.Domain[at+1:]
+			}
+			a.DKIM = append(a.DKIM, v)
+		case "dmarc":
+			v.Domain = mailDomain(props["header.from"])
+			a.DMARC = &v
+		case "arc":
+			a.ARC = &v
+		}
+	}
+	return a
+}
+
+// stripHeaderComments removes parenthesized comments, which may nest
+// (RFC 5322 CFWS), keeping quoted strings intact
+func stripHeaderComments(s string) string {
+	var b strings.Builder
+	depth := 0
+	quoted := false
+	for i := 0; i < len(s); i++ {
+		c := s[i]
+		switch {
+		case c == '\\' && (quoted || depth > 0) && i+1 < len(s):
+			if depth == 0 {
+				b.WriteByte(s[i+1])
+			}
+			i++
+		case c == '"' && depth == 0:
+			quoted = !quoted
+			b.WriteByte(c)
+		case c == '(' && !quoted:
+			depth++
+		case c == ')' && !quoted && depth > 0:
+			depth--
+			if depth == 0 {
+				b.WriteByte(' ')
+			}
+		case depth == 0:
+			b.WriteByte(c)
+		}
+	}
+	return b.String()
+}
+
+// arcInstanceOf returns the i= instance of an ARC header, 0 if missing
+func arcInstanceOf(value string) int {
+	n, _ := strconv.Atoi(tagValue(value, "i"))
+	return n
+}
+
+// tagValue returns the value of a "tag=value;" list entry (RFC 6376 tag lists)
+func tagValue(value, tag string) string {
+	for _, part := range strings.Split(value, ";") {
+		k, v, ok := strings.Cut(part, "=")
+		if ok && strings.EqualFold(strings.TrimSpace(k), tag) {
+			return strings.TrimSpace(v)
+		}
+	}
+	return ""
+}
+
+// firstOf returns the first non-empty property of names
+func firstOf(props map[string]string, names ...string) string {
+	for _, n := range names {
+		if v := props[n]; v != "" {
+			return v
+		}
+	}
+	return ""
+}
+
+// mailDomain returns the domain of an address, or the value itself when it
+// is a bare domain
+func mailDomain(s string) string {
+	if at := strings.LastIndex(s, "@"); at >= 0 {
+		return strings.ToLower(s[at+1:])
+	}
+	return strings.ToLower(s)
+}
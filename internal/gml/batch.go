@@ -0,0 +1,30 @@
+package gml
+
+import "log/slog"
+
+// ItemError is a failure on one message of a batch operation
+type ItemError struct {
+	ID    string `json:"id"`
+	Error string `json:"error"`
+}
+
+// FailureLog collects per-message failures of a batch operation. With Ignore
+// set, the operation records a failure and moves on to the next message;
+// otherwise (or with a nil log) it stops at the first failure
+type FailureLog struct {
+	Ignore bool
+	Items  []ItemError
+}
+
+// Record returns err unchanged unless failures are ignored, in which case it
+// logs err for each ID and returns nil so the caller can continue
+func (l *FailureLog) Record(err error, ids ...string) error {
+	if l == nil || !l.Ignore {
+		return err
+	}
+	for _, id := range ids {
+		slog.Info("skipping failed message", "id", id, "err", err)
+		l.Items = append(l.Items, ItemError{ID: id, Error: err.Error()})
+	}
+	return nil
+}
@@ -0,0 +1,211 @@
+package gml
+
+import (
+	"bufio"
+	"bytes"
+	"context"
+	"fmt"
+	"io"
+	"mime"
+	"mime/multipart"
+	"net/mail"
+	"net/textproto"
+	"strings"
+	"time"
+)
+
+// BounceQuery finds delivery status notifications from mail servers
+const BounceQuery = "{from:mailer-daemon from:postmaster}"
+
+// Bounce is one failed recipient reported by a delivery status notification
+type Bounce struct {
+	Account   string `json:"account,omitempty"`
+	ID        string `json:"id"`
+	Date      string `json:"date"`
+	Recipient string `json:"recipient"`
+	Action    string `json:"action,omitempty"`
+	Status    string `json:"status,omitempty"`
+	Reason    string `json:"reason,omitempty"`
+	// Subject and MessageID identify the original message when the
+	// notification includes its headers
+	Subject   string `json:"subject,omitempty"`
+	MessageID string `json:"messageId,omitempty"`
+}
+
+// BounceOptions contains options for finding bounces
+type BounceOptions struct {
+	// Since limits the search to notifications newer than this age (0 for all)
+	Since time.Duration
+	// Query narrows the search further (Gmail search syntax)
+	Query string
+	Now   time.Time
+}
+
+// ListBounces finds delivery status notifications and returns their failed recipients
+// Notifications without a recognizable failure (e.g. delay warnings) are skipped
+func ListBounces(ctx context.Context, svc *Service, opts BounceOptions) ([]Bounce, error) {
+	now := opts.Now
+	if now.IsZero() {
+		now = time.Now()
+	}
+	query := AgeQuery(BounceQuery+" "+opts.Query, 0, opts.Since, now)
+
+	ids, err := ListMessageIDs(ctx, svc, query, nil)
+	if err != nil {
+		return nil, err
+	}
+
+	var bounces []Bounce
+	for _, id := range ids {
+		raw, msg, err := GetRawMessage(ctx, svc, id)
+		if err != nil {
+			// Messages may be deleted while we iterate
+			continue
+		}
+		parsed, err := ParseBounce(raw)
+		if err != nil {
+			continue
+		}
+		for _, b := range parsed {
+			b.ID = msg.Id
+			bounces = append(bounces, b)
+		}
+	}
+	return bounces, nil
+}
+
+// ParseBounce extracts failed recipients from an RFC 3464 delivery status
+// notification, falling back to the X-Failed-Recipients header
+func ParseBounce(raw []byte) ([]Bounce, error) {
+	msg, err := mail.ReadMessage(bytes.NewReader(raw))
+	if err != nil {
+		return nil, fmt.Errorf("unable to parse message: %w", err)
+	}
+	date := msg.Header.Get("Date")
+
+	var bounces []Bounce
+	var subject, messageID string
+
+	mediaType, params, _ := mime.ParseMediaType(msg.Header.Get("Content-Type"))
+	if strings.HasPrefix(mediaType, "multipart/") && params["boundary"] != "" {
+		walkBounceParts(msg.Body, params["boundary"], func(partType string, body []byte) {
+			switch partType {
+			case "message/delivery-status", "message/global-delivery-status":
+				bounces = append(bounces, parseDeliveryStatus(body)...)
+			case "text/rfc822-headers", "message/rfc822", "message/global-headers":
+				if h, _ := textproto.NewReader(bufio.NewReader(bytes.NewReader(body))).ReadMIMEHeader(); len(h) > 0 {
+					subject = DecodeHeader(h.Get("Subject"))
+					messageID = h.Get("Message-Id")
+				}
+			}
+		})
+	}
+
+	// Gmail and some MTAs name failed recipients in a header instead
+	if len(bounces) == 0 {
+		for _, rcpt := range strings.Split(msg.Header.Get("X-Failed-Recipients"), ",") {
+			if rcpt = strings.TrimSpace(rcpt); rcpt != "" {
+				bounces = append(bounces, Bounce{Recipient: rcpt, Action: "failed"})
+			}
+		}
+	}
+	if len(bounces) == 0 {
+		return nil, fmt.Errorf("no failed recipients found")
+	}
+
+	for i := range bounces {
+		bounces[i].Date = date
+		bounces[i].Subject = subject
+		bounces[i].MessageID = messageID
+		if bounces[i].Reason == "" {
+			bounces[i].Reason = DecodeHeader(msg.Header.Get("Subject"))
+		}
+	}
+	return bounces, nil
+}
+
+// walkBounceParts calls fn for every leaf part of a multipart body, descending
+// into nested multiparts (e.g. multipart/report inside multipart/mixed)
+func walkBounceParts(r io.Reader, boundary string, fn func(mediaType string, body []byte)) {
+	mr := multipart.NewReader(r, boundary)
+	for {
+		part, err := mr.NextPart()
+		if err != nil {
+			return
+		}
+		mediaType, params, _ := mime.ParseMediaType(part.Header.Get("Content-Type"))
+		if strings.HasPrefix(mediaType, "multipart/") && params["boundary"] != "" {
+			walkBounceParts(part, params["boundary"], fn)
+			continue
+		}
+		body, err := io.ReadAll(part)
+		if err != nil {
+			return
+		}
+		fn(mediaType, body)
+	}
+}
+
+// parseDeliveryStatus parses the per-recipient field groups of a
+// message/delivery-status body, keeping only failed recipients
+func parseDeliveryStatus(body []byte) []Bounce {
+	reader := textproto.NewReader(bufio.NewReader(bytes.NewReader(normalizeNewlines(body))))
+
+	var bounces []Bounce
+	// The first group holds per-message fields; the rest are per-recipient
+	first := true
+	for {
+		h, err := reader.ReadMIMEHeader()
+		if len(h) > 0 && !first {
+			action := strings.ToLower(strings.TrimSpace(h.Get("Action")))
+			recipient := dsnAddress(firstNonEmpty(h.Get("Final-Recipient"), h.Get("Original-Recipient")))
+			if recipient != "" && (action == "" || action == "failed") {
+				bounces = append(bounces, Bounce{
+					Recipient: recipient,
+					Action:    action,
+					Status:    strings.TrimSpace(h.Get("Status")),
+					Reason:    dsnDiagnostic(h.Get("Diagnostic-Code")),
+				})
+			}
+		}
+		if len(h) > 0 {
+			first = false
+		}
+		if err != nil {
+			return bounces
+		}
+	}
+}
+
+// dsnAddress strips the address type from a recipient field ("rfc822; a@b.c")
+func dsnAddress(field string) string {
+	if _, addr, ok := strings.Cut(field, ";"); ok {
+		field = addr
+	}
+	return strings.Trim(strings.TrimSpace(field), "<>")
+}
+
+// dsnDiagnostic strips the diagnostic type from a Diagnostic-Code field
+// ("smtp; 550 5.1.1 user unknown") and collapses folded whitespace
+func dsnDiagnostic(field string) string {
+	if _, text, ok := strings.Cut(field, ";"); ok {
+		field = text
+	}
+	return strings.Join(strings.Fields(field), " ")
+}
+
+// normalizeNewlines converts CRLF line endings to LF so blank lines between
+// field groups are recognized consistently
+func normalizeNewlines(b []byte) []byte {
+	return bytes.ReplaceAll(b, []byte("\r\n"), []byte("\n"))
+}
+
+// firstNonEmpty returns the first non-empty string
+func firstNonEmpty(values ...string) string {
+	for _, v := range values {
+		if strings.TrimSpace(v) != "" {
+			return v
+		}
+	}
+	return ""
+}
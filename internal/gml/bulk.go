@@ -0,0 +1,91 @@
+package gml
+
+import (
+	"context"
+	"fmt"
+)
+
+// BulkModifyOptions selects messages and the label changes to apply to them
+type BulkModifyOptions struct {
+	// IDs are the messages to change; when empty, every message matching
+	// Query and LabelIDs is used
+	IDs      []string
+	Query    string
+	LabelIDs []string
+	// AddLabels and RemoveLabels are label names or IDs; added labels are
+	// created if they don't exist
+	AddLabels    []string
+	RemoveLabels []string
+	// DryRun resolves the messages without modifying them
+	DryRun bool
+	// Failures collects batches that couldn't be modified; nil stops at the
+	// first failure
+	Failures *FailureLog
+	// Progress, if set, is called after each batch with the number of
+	// messages processed so far and the total
+	Progress func(done, total int)
+}
+
+// BulkModifyResult summarizes a bulk label change
+type BulkModifyResult struct {
+	Matched  int `json:"matched"`
+	Modified int `json:"modified"`
+}
+
+// BulkModify applies label changes to a list of messages or to every message
+// matching a query, in BatchModify calls of up to 1000 messages
+func BulkModify(ctx context.Context, svc *Service, opts BulkModifyOptions) (*BulkModifyResult, error) {
+	if len(opts.AddLabels) == 0 && len(opts.RemoveLabels) == 0 {
+		return nil, fmt.Errorf("at least one label to add or remove is required")
+	}
+
+	idx, err := FetchLabelIndex(ctx, svc)
+	if err != nil {
+		return nil, err
+	}
+	removeIDs, err := idx.ResolveLabelIDs(opts.RemoveLabels)
+	if err != nil {
+		return nil, err
+	}
+	// A dry run doesn't create the labels to add
+	var addIDs []string
+	if !opts.DryRun {
+		if addIDs, err = idx.EnsureLabelIDs(ctx, svc, opts.AddLabels); err != nil {
+			return nil, err
+		}
+	}
+
+	ids := opts.IDs
+	if len(ids) == 0 {
+		filterIDs, err := idx.ResolveLabelIDs(opts.LabelIDs)
+		if err != nil {
+			return nil, err
+		}
+		if ids, err = ListMessageIDs(ctx, svc, opts.Query, filterIDs); err != nil {
+			return nil, err
+		}
+	}
+
+	result := &BulkModifyResult{Matched: len(ids)}
+	if opts.DryRun {
+		return result, nil
+	}
+
+	for start := 0; start < len(ids); start += batchModifyLimit {
+		end := min(start+batchModifyLimit, len(ids))
+		recorded := 0
+		if opts.Failures != nil {
+			recorded = len(opts.Failures.Items)
+		}
+		if err := batchModify(ctx, svc, ids[start:end], addIDs, removeIDs, opts.Failures); err != nil {
+			return result, err
+		}
+		if opts.Failures == nil || len(opts.Failures.Items) == recorded {
+			result.Modified += end - start
+		}
+		if opts.Progress != nil {
+			opts.Progress(end, len(ids))
+		}
+	}
+	return result, nil
+}
@@ -0,0 +1,98 @@
+package gml
+
+import (
+	"context"
+	"errors"
+	"slices"
+	"testing"
+
+	"github.com/longkey1/gml/internal/fakegmail"
+)
+
+func TestBulkModifyChunks(t *testing.T) {
+	svc, fake := newTestService(t)
+	ids := addMessages(fake, 2500, fakegmail.Message{LabelIDs: []string{"INBOX", "UNREAD"}})
+	ctx := context.Background()
+
+	var progress []int
+	result, err := BulkModify(ctx, svc, BulkModifyOptions{
+		Query:        "is:unread",
+		AddLabels:    []string{"Archive/2025"},
+		RemoveLabels: []string{"inbox"},
+		Progress:     func(done, total int) { progress = append(progress, done) },
+	})
+	if err != nil {
+		t.Fatal(err)
+	}
+	if result.Matched != 2500 || result.Modified != 2500 {
+		t.Errorf("BulkModify() = %+v, want 2500 matched and modified", result)
+	}
+	if want := []int{1000, 1000, 500}; !slices.Equal(fake.batches, want) {
+		t.Errorf("BatchModify sizes = %v, want %v", fake.batches, want)
+	}
+	if want := []int{1000, 2000, 2500}; !slices.Equal(progress, want) {
+		t.Errorf("progress = %v, want %v", progress, want)
+	}
+	for _, id := range []string{ids[0], ids[1500], ids[2499]} {
+		labels := fake.Message(id).LabelIds
+		if slices.Contains(labels, "INBOX") || len(labels) != 2 {
+			t.Errorf("labels of %s = %v, want UNREAD and the new label", id, labels)
+		}
+	}
+}
+
+func TestBulkModifyFailures(t *testing.T) {
+	svc, fake := newTestService(t)
+	ids := addMessages(fake, 2500, fakegmail.Message{LabelIDs: []string{"INBOX"}})
+	ctx := context.Background()
+	// ListMessageIDs returns the newest first, so the second batch holds
+	// ids[500:1500]
+	broken := ids[1000]
+	fake.Fail = func(method, id string) error {
+		if method == "BatchModifyMessages" && id == broken {
+			return errors.New("backend error")
+		}
+		return nil
+	}
+
+	failures := &FailureLog{Ignore: true}
+	result, err := BulkModify(ctx, svc, BulkModifyOptions{LabelIDs: []string{"INBOX"}, AddLabels: []string{"STARRED"}, Failures: failures})
+	if err != nil {
+		t.Fatal(err)
+	}
+	if result.Matched != 2500 || result.Modified != 1500 {
+		t.Errorf("BulkModify() = %+v, want 2500 matched and 1500 modified", result)
+	}
+	if len(failures.Items) != 1000 || !slices.ContainsFunc(failures.Items, func(e ItemError) bool { return e.ID == broken }) {
+		t.Errorf("BulkModify() recorded %d failures, want the 1000 messages of the batch with %s", len(failures.Items), broken)
+	}
+	if slices.Contains(fake.Message(broken).LabelIds, "STARRED") || !slices.Contains(fake.Message(ids[0]).LabelIds, "STARRED") {
+		t.Error("BulkModify() changed the failed batch or skipped the others")
+	}
+
+	// Without a log, the first failure stops the run
+	result, err = BulkModify(ctx, svc, BulkModifyOptions{IDs: ids[:1500], AddLabels: []string{"IMPORTANT"}})
+	if err == nil || result.Modified != 1000 {
+		t.Errorf("BulkModify() = %+v, %v, want an error after 1000 modified", result, err)
+	}
+}
+
+func TestBulkModifyDryRun(t *testing.T) {
+	svc, fake := newTestService(t)
+	addMessages(fake, 3, fakegmail.Message{LabelIDs: []string{"INBOX"}})
+
+	result, err := BulkModify(context.Background(), svc, BulkModifyOptions{Query: "in:inbox", AddLabels: []string{"New"}, DryRun: true})
+	if err != nil {
+		t.Fatal(err)
+	}
+	if result.Matched != 3 || result.Modified != 0 || len(fake.batches) != 0 {
+		t.Errorf("BulkModify(dry run) = %+v with %d batches, want 3 matched and nothing modified", result, len(fake.batches))
+	}
+	idx, err := FetchLabelIndex(context.Background(), svc)
+	if err != nil {
+		t.Fatal(err)
+	}
+	if _, err := idx.ResolveLabelIDs([]string{"New"}); !errors.Is(err, ErrNotFound) {
+		t.Errorf("BulkModify(dry run) created the label: %v", err)
+	}
+}
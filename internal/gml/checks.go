@@ -0,0 +1,138 @@
+package gml
+
+import (
+	"fmt"
+	"net/mail"
+	"regexp"
+	"strings"
+)
+
+// DefaultMaxRecipients is the recipient count above which sending is flagged
+const DefaultMaxRecipients = 25
+
+// CheckOptions configures the outgoing mail safety checks
+type CheckOptions struct {
+	// MaxRecipients flags messages with more To/Cc/Bcc recipients (0 for the default)
+	MaxRecipients int
+	// HasAttachments suppresses the forgotten attachment check
+	HasAttachments bool
+}
+
+// attachmentMention matches wording that usually announces an attachment
+var attachmentMention = regexp.MustCompile(`(?i)\b(attach(ed|ment|ments|ing)?|enclosed|see the file)\b|添付|pièce jointe|anbei|im anhang|adjunto`)
+
+// commonMailDomains are large providers whose near-misses are likely typos
+var commonMailDomains = []string{
+	"gmail.com", "googlemail.com", "yahoo.com", "yahoo.co.jp", "ymail.com",
+	"outlook.com", "hotmail.com", "live.com", "msn.com", "icloud.com",
+	"me.com", "aol.com", "protonmail.com", "proton.me", "gmx.com", "gmx.de",
+	"mail.com", "email.com", "hotmail.co.uk", "yahoo.co.uk",
+}
+
+// misspelledTLDs maps common top-level domain typos to the intended TLD
+var misspelledTLDs = map[string]string{
+	"con": "com", "cmo": "com", "ocm": "com", "comm": "com", "vom": "com", "xom": "com",
+	"nte": "net", "ent": "net", "ogr": "org", "rog": "org",
+}
+
+// CheckOutgoing returns warnings about likely mistakes in an outgoing message:
+// a mentioned but missing attachment, misspelled recipient domains and a
+// large recipient list
+func CheckOutgoing(msg *OutgoingMessage, opts CheckOptions) []string {
+	var warnings []string
+
+	if !opts.HasAttachments && len(msg.Attachments) == 0 && mentionsAttachment(msg.Body) {
+		warnings = append(warnings, "the message mentions an attachment but none is attached")
+	}
+
+	recipients := append(append(append([]string(nil), msg.To...), msg.Cc...), msg.Bcc...)
+	for _, r := range recipients {
+		addr := r
+		if parsed, err := mail.ParseAddress(r); err == nil {
+			addr = parsed.Address
+		}
+		at := strings.LastIndex(addr, "@")
+		if at < 0 {
+			continue
+		}
+		if suggestion := suggestDomain(strings.ToLower(addr[at+1:])); suggestion != "" {
+			warnings = append(warnings, fmt.Sprintf("recipient %s may be misspelled (did you mean %s?)", addr, addr[:at+1]+suggestion))
+		}
+	}
+
+	limit := opts.MaxRecipients
+	if limit <= 0 {
+		limit = DefaultMaxRecipients
+	}
+	if len(recipients) > limit {
+		warnings = append(warnings, fmt.Sprintf("the message has %d recipients (more than %d)", len(recipients), limit))
+	}
+
+	return warnings
+}
+
+// mentionsAttachment reports whether the body, excluding quoted lines,
+// refers to an attachment
+func mentionsAttachment(body string) bool {
+	for _, line := range strings.Split(body, "\n") {
+		if strings.HasPrefix(strings.TrimSpace(line), ">") {
+			continue
+		}
+		if attachmentMention.MatchString(line) {
+			return true
+		}
+	}
+	return false
+}
+
+// suggestDomain returns the domain a near-miss was probably meant to be,
+// or "" if the domain looks fine
+func suggestDomain(domain string) string {
+	for _, d := range commonMailDomains {
+		if domain == d {
+			return ""
+		}
+	}
+	// Short domains are too close to unrelated real ones to compare fuzzily
+	for _, d := range commonMailDomains {
+		if len(d) < 8 {
+			continue
+		}
+		if dist := editDistance(domain, d); dist == 1 || (dist == 2 && len(d) > 10) {
+			return d
+		}
+	}
+	if i := strings.LastIndex(domain, "."); i >= 0 {
+		if tld, ok := misspelledTLDs[domain[i+1:]]; ok {
+			return domain[:i+1] + tld
+		}
+	}
+	return ""
+}
+
+// editDistance returns the optimal string alignment distance between a and b
+// (insertions, deletions, substitutions and adjacent transpositions)
+func editDistance(a, b string) int {
+	ra, rb := []rune(a), []rune(b)
+	d := make([][]int, len(ra)+1)
+	for i := range d {
+		d[i] = make([]int, len(rb)+1)
+		d[i][0] = i
+	}
+	for j := range d[0] {
+		d[0][j] = j
+	}
+	for i := 1; i <= len(ra); i++ {
+		for j := 1; j <= len(rb); j++ {
+			cost := 1
+			if ra[i-1] == rb[j-1] {
+				cost = 0
+			}
+			d[i][j] = min(d[i-1][j]+1, d[i][j-1]+1, d[i-1][j-1]+cost)
+			if i > 1 && j > 1 && ra[i-1] == rb[j-2] && ra[i-2] == rb[j-1] {
+				d[i][j] = min(d[i][j], d[i-2][j-2]+1)
+			}
+		}
+	}
+	return d[len(ra)][len(rb)]
+}
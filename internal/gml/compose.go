@@ -0,0 +1,121 @@
+package gml
+
+import (
+	"fmt"
+	"io"
+	"strings"
+)
+
+// FormatEditableMessage renders a message for editing in a text editor: a
+// To/Cc/Bcc/Subject header block, a blank line and the body
+func FormatEditableMessage(msg *OutgoingMessage) string {
+	var b strings.Builder
+	fmt.Fprintf(&b, "To: %s\n", strings.Join(msg.To, ", "))
+	fmt.Fprintf(&b, "Cc: %s\n", strings.Join(msg.Cc, ", "))
+	fmt.Fprintf(&b, "Bcc: %s\n", strings.Join(msg.Bcc, ", "))
+	fmt.Fprintf(&b, "Subject: %s\n", msg.Subject)
+	b.WriteString("\n")
+	b.WriteString(msg.Body)
+	return b.String()
+}
+
+// ParseEditableMessage parses text written by FormatEditableMessage and
+// edited by the user. Threading fields, body format and attachments are kept
+// from base, which may be nil
+func ParseEditableMessage(text string, base *OutgoingMessage) (*OutgoingMessage, error) {
+	msg := &OutgoingMessage{}
+	if base != nil {
+		msg.InReplyTo, msg.References, msg.ThreadID = base.InReplyTo, base.References, base.ThreadID
+		msg.BodyFormat, msg.Attachments = base.BodyFormat, base.Attachments
+	}
+
+	text = strings.ReplaceAll(text, "\r\n", "\n")
+	header, body, ok := strings.Cut(text, "\n\n")
+	if !ok {
+		header, body = strings.TrimSuffix(text, "\n"), ""
+	}
+	for _, line := range strings.Split(header, "\n") {
+		if strings.TrimSpace(line) == "" {
+			continue
+		}
+		name, value, ok := strings.Cut(line, ":")
+		if !ok {
+			return nil, fmt.Errorf("invalid header line %q (expected Name: value, then a blank line before the body)", line)
+		}
+		value = strings.TrimSpace(value)
+		switch strings.ToLower(strings.TrimSpace(name)) {
+		case "to":
+			msg.To = SplitAddressList(value)
+		case "cc":
+			msg.Cc = SplitAddressList(value)
+		case "bcc":
+			msg.Bcc = SplitAddressList(value)
+		case "subject":
+			msg.Subject = value
+		default:
+			return nil, fmt.Errorf("unknown header %q (use To, Cc, Bcc and Subject)", strings.TrimSpace(name))
+		}
+	}
+	msg.Body = body
+	return msg, nil
+}
+
+// SplitAddressList splits a comma-separated address list, keeping commas
+// inside quoted display names and angle brackets
+func SplitAddressList(value string) []string {
+	var addrs []string
+	start, quoted, angle := 0, false, false
+	add := func(s string) {
+		if s = strings.TrimSpace(s); s != "" {
+			addrs = append(addrs, s)
+		}
+	}
+	for i := 0; i < len(value); i++ {
+		switch c := value[i]; {
+		case c == '\\' && quoted:
+			i++
+		case c == '"':
+			quoted = !quoted
+		case c == '<' && !quoted:
+			angle = true
+		case c == '>' && !quoted:
+			angle = false
+		case c == ',' && !quoted && !angle:
+			add(value[start:i])
+			start = i + 1
+		}
+	}
+	add(value[start:])
+	return addrs
+}
+
+// FormatOutgoingSummary writes the recipients, subject, body size and
+// attachments of a message for review before it is sent
+func FormatOutgoingSummary(w io.Writer, msg *OutgoingMessage) {
+	for _, h := range []struct {
+		name  string
+		addrs []string
+	}{{"To", msg.To}, {"Cc", msg.Cc}, {"Bcc", msg.Bcc}} {
+		if len(h.addrs) > 0 {
+			fmt.Fprintf(w, "%-12s %s\n", h.name+":", strings.Join(h.addrs, ", "))
+		}
+	}
+	fmt.Fprintf(w, "%-12s %s\n", "Subject:", msg.Subject)
+	lines := strings.Count(strings.TrimRight(msg.Body, "\n"), "\n") + 1
+	format := msg.BodyFormat
+	if format == "" {
+		format = BodyFormatText
+	}
+	fmt.Fprintf(w, "%-12s %d lines, %s\n", "Body:", lines, format)
+	for i, a := range msg.Attachments {
+		name := "Attachments:"
+		if i > 0 {
+			name = ""
+		}
+		inline := ""
+		if a.Inline {
+			inline = ", inline"
+		}
+		fmt.Fprintf(w, "%-12s %s (%s%s)\n", name, a.Filename, FormatSize(int64(len(a.Data))), inline)
+	}
+}
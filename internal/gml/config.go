@@ -1,9 +1,18 @@
 package gml
 
 import (
+	"errors"
 	"fmt"
+	"net/http"
+	"os"
+	"path/filepath"
+	"slices"
+	"strings"
 
+	"github.com/longkey1/gml/internal/google"
 	"github.com/spf13/viper"
+	"google.golang.org/api/gmail/v1"
+	"google.golang.org/api/people/v1"
 )
 
 // AuthType represents the authentication type
@@ -14,37 +23,353 @@ const (
 	AuthTypeServiceAccount AuthType = "service_account"
 )
 
+// TokenStorage represents where OAuth tokens are stored
+type TokenStorage string
+
+const (
+	TokenStorageFile    TokenStorage = "file"
+	TokenStorageKeyring TokenStorage = "keyring"
+)
+
 // Config holds the configuration for gml
 type Config struct {
-	AuthType                     AuthType `mapstructure:"auth_type"`
-	GoogleApplicationCredentials string   `mapstructure:"application_credentials"`
-	GoogleUserCredentials        string   `mapstructure:"user_credentials"`
+	AuthType                     AuthType     `mapstructure:"auth_type"`
+	GoogleApplicationCredentials string       `mapstructure:"application_credentials"`
+	GoogleUserCredentials        string       `mapstructure:"user_credentials"`
+	Scopes                       []string     `mapstructure:"scopes"`
+	TokenStorage                 TokenStorage `mapstructure:"token_storage"`
+
+	// Headers are added to every Gmail API request, e.g. for an enterprise API
+	// proxy; values may reference environment variables ($TOKEN or ${TOKEN})
+	Headers map[string]string `mapstructure:"headers"`
+
+	// DateFormat is the default for --date-format (raw, relative, rfc3339, ... or a Go layout)
+	DateFormat string `mapstructure:"date_format"`
+
+	// UsageStats enables local usage statistics for 'gml usage'; nothing is sent anywhere
+	UsageStats bool `mapstructure:"usage_stats"`
+
+	// DefaultAccount is used when no account is selected by flag, env or 'account switch'
+	DefaultAccount string                   `mapstructure:"default_account"`
+	Accounts       map[string]AccountConfig `mapstructure:"accounts"`
+
+	// Reports holds named report definitions for 'gml report run'
+	Reports map[string]ReportConfig `mapstructure:"reports"`
+
+	// Pipelines holds named step pipelines for 'gml run'
+	Pipelines map[string]PipelineConfig `mapstructure:"pipelines"`
+
+	// Searches holds named searches for 'gml list --saved'; 'gml search save'
+	// stores more in searches.toml next to the config file
+	Searches map[string]SavedSearch `mapstructure:"searches"`
+
+	// Templates holds named message templates for 'gml send --template'
+	Templates map[string]MailTemplate `mapstructure:"templates"`
+
+	// Maintenance holds the [[maintenance]] tasks run by 'gml maintain'
+	Maintenance []MaintainTask `mapstructure:"maintenance"`
+
+	// Renderers map senders to compact views for 'gml get' and 'gml tui'
+	Renderers []RendererConfig `mapstructure:"renderers"`
+
+	// Watch holds defaults for 'gml watch' daemons, reloaded without a restart
+	Watch WatchConfig `mapstructure:"watch"`
+
+	// Commands holds the remaining top-level keys: flag defaults in sections
+	// named after commands, e.g. [list] or [label.sync] (see CommandDefaults)
+	Commands map[string]any `mapstructure:",remain"`
+
+	// Account is the name of the selected account profile (empty for top-level credentials)
+	Account string `mapstructure:"-"`
+
+	// Tokens, if set, replaces the token store selected by token_storage,
+	// e.g. with a store of the Go API user's own
+	Tokens google.TokenStore `mapstructure:"-"`
+}
+
+// scopeAliases maps short scope names accepted in config to OAuth scopes: Gmail
+// scopes, and People API scopes for contact lookups
+var scopeAliases = map[string]string{
+	"readonly":         gmail.GmailReadonlyScope,
+	"modify":           gmail.GmailModifyScope,
+	"compose":          gmail.GmailComposeScope,
+	"send":             gmail.GmailSendScope,
+	"insert":           gmail.GmailInsertScope,
+	"labels":           gmail.GmailLabelsScope,
+	"metadata":         gmail.GmailMetadataScope,
+	"settings.basic":   gmail.GmailSettingsBasicScope,
+	"settings.sharing": gmail.GmailSettingsSharingScope,
+	"full":             gmail.MailGoogleComScope,
+	"contacts":         people.ContactsReadonlyScope,
+	"contacts.other":   people.ContactsOtherReadonlyScope,
 }
 
-// LoadConfig loads configuration from viper
-func LoadConfig() (*Config, error) {
+// ReadConfig reads the TOML config file at path, or config.toml in ConfigDir
+// when path is empty, and returns it with the path that was read
+// It uses its own viper instance, so concurrent and repeated reads are safe.
+// A missing default config file yields a nil config without error
+func ReadConfig(path string) (*Config, string, error) {
+	v := viper.New()
+	if path != "" {
+		v.SetConfigFile(path)
+	} else {
+		dir, err := ConfigDir()
+		if err != nil {
+			return nil, "", err
+		}
+		v.AddConfigPath(dir)
+		v.SetConfigName("config")
+		v.SetConfigType("toml")
+	}
+	v.AutomaticEnv()
+
+	if err := v.ReadInConfig(); err != nil {
+		var notFound viper.ConfigFileNotFoundError
+		if errors.As(err, &notFound) {
+			return nil, "", nil
+		}
+		return nil, "", fmt.Errorf("unable to read config file: %w", err)
+	}
+
+	config, err := LoadConfig(v)
+	if err != nil {
+		return nil, "", fmt.Errorf("unable to load config: %w", err)
+	}
+	return config, v.ConfigFileUsed(), nil
+}
+
+// DefaultConfig is used when there is no config file: OAuth with the built-in
+// client, read-only access and the token in the default location
+func DefaultConfig() *Config {
+	return &Config{
+		AuthType:     AuthTypeOAuth,
+		TokenStorage: TokenStorageFile,
+	}
+}
+
+// LoadConfig decodes configuration from a viper instance
+func LoadConfig(v *viper.Viper) (*Config, error) {
 	config := &Config{}
-	if err := viper.Unmarshal(config); err != nil {
+	if err := v.Unmarshal(config); err != nil {
 		return nil, fmt.Errorf("error unmarshaling config: %v", err)
 	}
+	for name := range config.Accounts {
+		if strings.EqualFold(name, AllAccounts) {
+			return nil, fmt.Errorf("accounts.%s: the account name %q is reserved for selecting every account; rename the profile", name, AllAccounts)
+		}
+	}
 
 	// Default to OAuth if not specified
 	if config.AuthType == "" {
 		config.AuthType = AuthTypeOAuth
 	}
 
+	// Default to plain file token storage if not specified
+	if config.TokenStorage == "" {
+		config.TokenStorage = TokenStorageFile
+	}
+
+	if err := config.expandPaths(); err != nil {
+		return nil, err
+	}
+
 	return config, nil
 }
 
+// ConfigDir returns the directory of the config file and OAuth client
+// credentials ($XDG_CONFIG_HOME/gml, defaulting to ~/.config/gml)
+func ConfigDir() (string, error) {
+	if dir := os.Getenv("XDG_CONFIG_HOME"); dir != "" {
+		return filepath.Join(dir, "gml"), nil
+	}
+	home, err := os.UserHomeDir()
+	if err != nil {
+		return "", fmt.Errorf("unable to determine home directory: %w", err)
+	}
+	return filepath.Join(home, ".config", "gml"), nil
+}
+
+// ExpandPath expands environment variables ($HOME, ${XDG_CONFIG_HOME}, ...)
+// and a leading ~ in a configured path
+func ExpandPath(path string) (string, error) {
+	path = os.ExpandEnv(path)
+	if path == "~" || strings.HasPrefix(path, "~/") {
+		home, err := os.UserHomeDir()
+		if err != nil {
+			return "", fmt.Errorf("unable to determine home directory: %w", err)
+		}
+		path = filepath.Join(home, path[1:])
+	}
+	return path, nil
+}
+
+// expandPaths expands the credential paths of the config and its accounts
+func (c *Config) expandPaths() error {
+	expand := func(paths ...*string) error {
+		for _, p := range paths {
+			expanded, err := ExpandPath(*p)
+			if err != nil {
+				return err
+			}
+			*p = expanded
+		}
+		return nil
+	}
+
+	if err := expand(&c.GoogleApplicationCredentials, &c.GoogleUserCredentials); err != nil {
+		return err
+	}
+	for name, acct := range c.Accounts {
+		if err := expand(&acct.GoogleApplicationCredentials, &acct.GoogleUserCredentials); err != nil {
+			return err
+		}
+		c.Accounts[name] = acct
+	}
+	return nil
+}
+
+// CommandDefaults returns the flag defaults in the config section of a
+// command, given its path below the root (["label", "sync"] for [label.sync]).
+// Keys are flag names; max_results in the config is returned as max-results
+func (c *Config) CommandDefaults(path []string) map[string]any {
+	section := c.Commands
+	for _, name := range path {
+		sub, ok := section[name].(map[string]any)
+		if !ok {
+			return nil
+		}
+		section = sub
+	}
+
+	defaults := make(map[string]any)
+	for key, value := range section {
+		// Nested tables are the sections of subcommands
+		if _, ok := value.(map[string]any); ok {
+			continue
+		}
+		defaults[strings.ReplaceAll(key, "_", "-")] = value
+	}
+	return defaults
+}
+
+// OAuthClientCredentials returns the OAuth client credentials file:
+// application_credentials, or credentials.json in ConfigDir if it exists.
+// An empty result selects the built-in client
+func (c *Config) OAuthClientCredentials() string {
+	if c.GoogleApplicationCredentials != "" {
+		return c.GoogleApplicationCredentials
+	}
+	dir, err := ConfigDir()
+	if err != nil {
+		return ""
+	}
+	path := filepath.Join(dir, "credentials.json")
+	if _, err := os.Stat(path); err != nil {
+		return ""
+	}
+	return path
+}
+
 // Validate validates the configuration
+// OAuth falls back to the built-in client and the default token path, so only
+// service accounts need credentials
 func (c *Config) Validate() error {
-	if c.GoogleApplicationCredentials == "" {
-		return fmt.Errorf("application_credentials is required")
+	if c.AuthType == AuthTypeServiceAccount && c.GoogleApplicationCredentials == "" {
+		return fmt.Errorf("application_credentials is required for service account authentication")
+	}
+	if c.AuthType == AuthTypeOAuth && c.OAuthClientCredentials() == "" && !google.HasBuiltinClient() {
+		return fmt.Errorf("application_credentials is required (this build has no built-in OAuth client)")
 	}
+	return nil
+}
 
-	if c.AuthType == AuthTypeOAuth && c.GoogleUserCredentials == "" {
-		return fmt.Errorf("user_credentials is required for OAuth authentication")
+// OAuthScopes returns the OAuth scopes to request, resolving short aliases
+// such as "readonly" or "modify". Defaults to read-only access, which is also
+// added when only scopes of other APIs, such as "contacts", are listed.
+func (c *Config) OAuthScopes() ([]string, error) {
+	if len(c.Scopes) == 0 {
+		return []string{gmail.GmailReadonlyScope}, nil
 	}
 
-	return nil
+	var scopes []string
+	for _, s := range c.Scopes {
+		s = strings.TrimSpace(s)
+		if scope, ok := scopeAliases[strings.ToLower(s)]; ok {
+			scopes = append(scopes, scope)
+			continue
+		}
+		if strings.HasPrefix(s, "https://") {
+			scopes = append(scopes, s)
+			continue
+		}
+		return nil, fmt.Errorf("unknown scope: %s", s)
+	}
+	if !slices.ContainsFunc(scopes, isGmailScope) {
+		scopes = append([]string{gmail.GmailReadonlyScope}, scopes...)
+	}
+	return scopes, nil
+}
+
+// isGmailScope reports whether an OAuth scope grants access to the Gmail API
+func isGmailScope(scope string) bool {
+	return scope == gmail.MailGoogleComScope || strings.HasPrefix(scope, "https://www.googleapis.com/auth/gmail.")
+}
+
+// HTTPHeader returns the configured headers for API requests, with
+// environment variables in their values expanded
+func (c *Config) HTTPHeader() http.Header {
+	header := make(http.Header, len(c.Headers))
+	for name, value := range c.Headers {
+		header.Set(name, os.ExpandEnv(value))
+	}
+	return header
+}
+
+// NewTokenStore returns the OAuth token store selected by token_storage, or
+// Tokens when set
+func (c *Config) NewTokenStore() (google.TokenStore, error) {
+	if c.Tokens != nil {
+		return c.Tokens, nil
+	}
+	switch c.TokenStorage {
+	case TokenStorageKeyring:
+		// Each account gets its own keyring entry
+		key := c.Account
+		if key == "" {
+			key = "default"
+		}
+		return google.NewKeyringTokenStore(key), nil
+	case TokenStorageFile, "":
+		path := c.GoogleUserCredentials
+		if path == "" {
+			var err error
+			if path, err = defaultTokenPath(c.Account); err != nil {
+				return nil, err
+			}
+		}
+		return google.NewFileTokenStore(path), nil
+	default:
+		return nil, fmt.Errorf("unknown token_storage: %s", c.TokenStorage)
+	}
+}
+
+// defaultTokenPath is where the OAuth token is stored when user_credentials
+// isn't set: token.json in StateDir, or token-<account>.json per account.
+// Tokens that earlier versions wrote to ConfigDir keep being used there
+func defaultTokenPath(account string) (string, error) {
+	name := "token.json"
+	if account != "" {
+		name = "token-" + account + ".json"
+	}
+	if dir, err := ConfigDir(); err == nil {
+		legacy := filepath.Join(dir, name)
+		if _, err := os.Stat(legacy); err == nil {
+			return legacy, nil
+		}
+	}
+	dir, err := StateDir()
+	if err != nil {
+		return "", err
+	}
+	return filepath.Join(dir, name), nil
 }
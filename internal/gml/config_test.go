@@ -0,0 +1,98 @@
+package gml
+
+import (
+	"os"
+	"path/filepath"
+	"strings"
+	"testing"
+)
+
+// writeConfig writes a config file to a temporary directory and returns its path
+func writeConfig(t *testing.T, content string) string {
+	t.Helper()
+	path := filepath.Join(t.TempDir(), "config.toml")
+	if err := os.WriteFile(path, []byte(content), 0o600); err != nil {
+		t.Fatal(err)
+	}
+	return path
+}
+
+func TestReadConfigReservedAccount(t *testing.T) {
+	tests := []struct {
+		name    string
+		content string
+		wantErr bool
+	}{
+		{name: "profiles", content: "[accounts.work]\nauth_type = \"oauth\"\n[accounts.home]\nauth_type = \"oauth\"\n"},
+		{name: "all", content: "[accounts.all]\nauth_type = \"oauth\"\n", wantErr: true},
+		{name: "all uppercase", content: "[accounts.ALL]\nauth_type = \"oauth\"\n", wantErr: true},
+	}
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			_, _, err := ReadConfig(writeConfig(t, tt.content))
+			if tt.wantErr {
+				if err == nil || !strings.Contains(err.Error(), "reserved") {
+					t.Errorf("ReadConfig() error = %v, want the name all rejected", err)
+				}
+				return
+			}
+			if err != nil {
+				t.Fatal(err)
+			}
+		})
+	}
+}
+
+func TestForAccountTokenPath(t *testing.T) {
+	t.Setenv("XDG_CONFIG_HOME", t.TempDir())
+	stateDir := t.TempDir()
+	t.Setenv("XDG_STATE_HOME", stateDir)
+	config, _, err := ReadConfig(writeConfig(t, `
+user_credentials = "/tokens/token.json"
+
+[accounts.work]
+auth_type = "oauth"
+
+[accounts.home]
+auth_type = "oauth"
+
+[accounts.own]
+user_credentials = "/tokens/own.json"
+`))
+	if err != nil {
+		t.Fatal(err)
+	}
+
+	tests := []struct {
+		account string
+		want    string
+	}{
+		{account: "work", want: filepath.Join(stateDir, "gml", "token-work.json")},
+		{account: "home", want: filepath.Join(stateDir, "gml", "token-home.json")},
+		{account: "own", want: "/tokens/own.json"},
+	}
+	for _, tt := range tests {
+		t.Run(tt.account, func(t *testing.T) {
+			cfg, err := config.ForAccount(tt.account)
+			if err != nil {
+				t.Fatal(err)
+			}
+			store, err := cfg.NewTokenStore()
+			if err != nil {
+				t.Fatal(err)
+			}
+			if got := store.String(); got != tt.want {
+				t.Errorf("token of account %s = %s, want %s", tt.account, got, tt.want)
+			}
+		})
+	}
+
+	// The top-level token stays with the default account
+	store, err := config.NewTokenStore()
+	if err != nil {
+		t.Fatal(err)
+	}
+	if got := store.String(); got != "/tokens/token.json" {
+		t.Errorf("token without an account = %s, want /tokens/token.json", got)
+	}
+}
@@ -0,0 +1,237 @@
+package gml
+
+import (
+	"errors"
+	"fmt"
+	"maps"
+	"os"
+	"path/filepath"
+	"reflect"
+	"regexp"
+	"sort"
+	"strings"
+
+	"github.com/spf13/viper"
+)
+
+// DefaultConfigPath returns the config file used when --config is not given
+func DefaultConfigPath() (string, error) {
+	dir, err := ConfigDir()
+	if err != nil {
+		return "", err
+	}
+	return filepath.Join(dir, "config.toml"), nil
+}
+
+// WriteConfigTemplate writes ConfigTemplate to path, creating its directory.
+// An existing file is only replaced with force
+func WriteConfigTemplate(path string, force bool) error {
+	if err := os.MkdirAll(filepath.Dir(path), 0700); err != nil {
+		return fmt.Errorf("unable to create config directory: %w", err)
+	}
+	flags := os.O_WRONLY | os.O_CREATE | os.O_EXCL
+	if force {
+		flags = os.O_WRONLY | os.O_CREATE | os.O_TRUNC
+	}
+	f, err := os.OpenFile(path, flags, 0600)
+	if errors.Is(err, os.ErrExist) {
+		return fmt.Errorf("config file %s already exists (use --force to overwrite)", path)
+	}
+	if err != nil {
+		return fmt.Errorf("unable to write config file: %w", err)
+	}
+	if _, err := f.WriteString(ConfigTemplate); err != nil {
+		f.Close()
+		return fmt.Errorf("unable to write config file: %w", err)
+	}
+	return f.Close()
+}
+
+// ValidateConfigFile checks the config file at path and returns every problem
+// found: unknown keys in known sections, invalid values, and per-account
+// errors. Top-level keys gml doesn't know end up in Config.Commands, which
+// only the command tree can check
+func ValidateConfigFile(path string) ([]string, error) {
+	v := viper.New()
+	v.SetConfigFile(path)
+	v.SetConfigType("toml")
+	if err := v.ReadInConfig(); err != nil {
+		return nil, fmt.Errorf("unable to read config file: %w", err)
+	}
+
+	var problems []string
+	if err := v.UnmarshalExact(&Config{}); err != nil {
+		// Unknown keys are usually typos that are otherwise silently ignored;
+		// mapstructure reports them as "'<path>' has invalid keys: a, b"
+		msg := err.Error()
+		if i := strings.LastIndex(msg, "has invalid keys: "); i >= 0 {
+			msg = "unknown keys: " + strings.TrimSpace(msg[i+len("has invalid keys: "):])
+		}
+		problems = append(problems, msg)
+	}
+	cfg, err := LoadConfig(v)
+	if err != nil {
+		return append(problems, err.Error()), nil
+	}
+
+	problems = append(problems, checkConfig(cfg, "")...)
+	for _, name := range cfg.AccountNames() {
+		acct, err := cfg.ForAccount(name)
+		if err != nil {
+			problems = append(problems, err.Error())
+			continue
+		}
+		problems = append(problems, checkConfig(acct, "accounts."+name+": ")...)
+	}
+	if cfg.DefaultAccount != "" {
+		if _, err := cfg.ForAccount(cfg.DefaultAccount); err != nil {
+			problems = append(problems, "default_account: "+err.Error())
+		}
+	}
+	if cfg.DateFormat != "" {
+		if err := ValidateDateFormat(cfg.DateFormat); err != nil {
+			problems = append(problems, "date_format: "+err.Error())
+		}
+	}
+	for name, s := range cfg.Searches {
+		if s.Query == "" && len(s.Labels) == 0 {
+			problems = append(problems, fmt.Sprintf("searches.%s: needs a query or labels", name))
+		}
+	}
+	for i, r := range cfg.Renderers {
+		if _, ok := builtinRenderers[r.Builtin]; r.Builtin != "" && !ok {
+			problems = append(problems, fmt.Sprintf("renderers[%d]: unknown builtin renderer %q", i, r.Builtin))
+		}
+		if r.From == "" || (r.Builtin == "") == (r.Command == "") {
+			problems = append(problems, fmt.Sprintf("renderers[%d]: needs from and exactly one of builtin or command", i))
+		}
+	}
+	return problems, nil
+}
+
+// checkConfig validates the credentials settings of one (account) config
+func checkConfig(cfg *Config, prefix string) []string {
+	var problems []string
+	switch cfg.AuthType {
+	case AuthTypeOAuth, AuthTypeServiceAccount:
+	default:
+		problems = append(problems, fmt.Sprintf("%sunknown auth_type: %s", prefix, cfg.AuthType))
+	}
+	switch cfg.TokenStorage {
+	case TokenStorageFile, TokenStorageKeyring:
+	default:
+		problems = append(problems, fmt.Sprintf("%sunknown token_storage: %s", prefix, cfg.TokenStorage))
+	}
+	if _, err := cfg.OAuthScopes(); err != nil {
+		problems = append(problems, prefix+err.Error())
+	}
+	if err := cfg.Validate(); err != nil {
+		problems = append(problems, prefix+err.Error())
+	}
+	for name := range cfg.HTTPHeader() {
+		if name == "Authorization" || name == "Host" {
+			problems = append(problems, fmt.Sprintf("%sheaders: %s is set by gml and can't be overridden", prefix, name))
+		}
+	}
+	return problems
+}
+
+// secretKey matches setting names whose values must not be shown
+var secretKey = regexp.MustCompile(`(?i)(secret|password|authorization|api[_-]?key|(^|[_-])token$)`)
+
+// redacted replaces secret values in 'gml config show'
+const redacted = "<redacted>"
+
+// ConfigSettings returns cfg as a map keyed like the config file, without
+// empty values and with secrets redacted, for display
+func ConfigSettings(cfg *Config) map[string]any {
+	m, _ := settingsValue(reflect.ValueOf(cfg).Elem()).(map[string]any)
+	return m
+}
+
+// settingsValue converts a config value to plain maps, slices and scalars,
+// using mapstructure names; it returns nil for empty values
+func settingsValue(v reflect.Value) any {
+	switch v.Kind() {
+	case reflect.Pointer, reflect.Interface:
+		if v.IsNil() {
+			return nil
+		}
+		return settingsValue(v.Elem())
+	case reflect.Struct:
+		m := make(map[string]any)
+		for i := 0; i < v.NumField(); i++ {
+			field := v.Type().Field(i)
+			name, opts, _ := strings.Cut(field.Tag.Get("mapstructure"), ",")
+			if opts == "remain" {
+				// Show the command sections at the top level, where they're written
+				if rest, ok := settingsValue(v.Field(i)).(map[string]any); ok {
+					maps.Copy(m, rest)
+				}
+				continue
+			}
+			if name == "" || name == "-" || !field.IsExported() {
+				continue
+			}
+			if value := settingsValue(v.Field(i)); value != nil {
+				m[name] = redact(name, value)
+			}
+		}
+		if len(m) == 0 {
+			return nil
+		}
+		return m
+	case reflect.Map:
+		if v.Len() == 0 {
+			return nil
+		}
+		m := make(map[string]any, v.Len())
+		keys := v.MapKeys()
+		sort.Slice(keys, func(i, j int) bool { return keys[i].String() < keys[j].String() })
+		for _, k := range keys {
+			if value := settingsValue(v.MapIndex(k)); value != nil {
+				m[k.String()] = redact(k.String(), value)
+			}
+		}
+		return m
+	case reflect.Slice:
+		if v.Len() == 0 {
+			return nil
+		}
+		s := make([]any, 0, v.Len())
+		for i := 0; i < v.Len(); i++ {
+			if value := settingsValue(v.Index(i)); value != nil {
+				s = append(s, value)
+			}
+		}
+		return s
+	case reflect.String:
+		if v.String() == "" {
+			return nil
+		}
+		return v.String()
+	case reflect.Bool:
+		return v.Bool()
+	case reflect.Int, reflect.Int8, reflect.Int16, reflect.Int32, reflect.Int64:
+		if v.Int() == 0 {
+			return nil
+		}
+		return v.Int()
+	case reflect.Float32, reflect.Float64:
+		if v.Float() == 0 {
+			return nil
+		}
+		return v.Float()
+	}
+	return v.Interface()
+}
+
+// redact hides the value of secret settings
+func redact(key string, value any) any {
+	if secretKey.MatchString(key) {
+		if _, ok := value.(string); ok {
+			return redacted
+		}
+	}
+	return value
+}
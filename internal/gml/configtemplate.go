@@ -0,0 +1,103 @@
+package gml
+
+// ConfigTemplate is the commented config file written by 'gml config init'
+// Every setting is commented out, so the file starts out with the defaults
+const ConfigTemplate = `# gml configuration
+# Uncomment and edit the settings you need; everything shown is optional.
+# Check the file with 'gml config validate' and the result with 'gml config show'.
+
+# Authentication: "oauth" (default) or "service_account"
+# auth_type = "oauth"
+
+# Paths may use ~ and environment variables ($HOME, ${XDG_CONFIG_HOME}).
+
+# OAuth client credentials JSON from the Google Cloud console (default
+# ~/.config/gml/credentials.json if it exists). Without it, the built-in
+# client is used, which only allows read-only access.
+# For service accounts, the service account key file.
+# application_credentials = "~/.config/gml/credentials.json"
+
+# Where the OAuth token is stored (default ~/.local/state/gml/token.json)
+# user_credentials = "~/.local/state/gml/token.json"
+
+# "file" (default, at user_credentials) or "keyring" (OS keychain)
+# token_storage = "file"
+
+# OAuth scopes to request; run 'gml auth' again after changing them.
+# Aliases: readonly (default), modify, compose, send, insert, labels,
+# metadata, settings.basic, settings.sharing, full
+# scopes = ["readonly"]
+
+# Default for --date-format: raw, relative, rfc3339 (default), rfc1123z,
+# datetime, date, time or a Go layout
+# date_format = "relative"
+
+# Record local usage statistics for 'gml usage' (never sent anywhere)
+# usage_stats = false
+
+# Named accounts; fields left out inherit the top-level values.
+# Select one with --account, GML_ACCOUNT, 'gml account switch' or:
+# default_account = "work"
+#
+# [accounts.work]
+# user_credentials = "~/.local/state/gml/work-token.json"
+# scopes = ["modify"]
+#
+# [accounts.personal]
+# token_storage = "keyring"
+
+# Defaults for 'gml watch', reloaded by running daemons
+# [watch]
+# label = "INBOX"
+# fields = "id,from,subject,url"
+# exec = "notify-send \"$GML_FROM\" \"$GML_SUBJECT\""
+
+# Compact bodies for automated senders in 'gml get' and 'gml tui'
+# [[renderers]]
+# from = "github.com"
+# builtin = "github"
+
+# Headers added to every Gmail API request, e.g. for an enterprise API proxy
+# (values may reference environment variables; accounts can add their own)
+# [headers]
+# X-Goog-Request-Reason = "mailbox audit"
+# X-Gateway-Token = "${GATEWAY_TOKEN}"
+
+# Saved searches for 'gml list --saved <name>'
+# [searches.receipts]
+# query = "from:amazon OR from:paypal"
+# labels = ["INBOX"]
+
+# Reports for 'gml report run <name>'
+# [reports.newsletters]
+# query = "category:promotions newer_than:7d"
+# group_by = "sender"
+# limit = 20
+
+# Message templates for 'gml send --template <name>' (see also 'gml template add')
+# [templates.welcome]
+# subject = "Welcome, {{.name}}"
+# body_file = "/path/to/welcome.txt"
+# to = ["{{.email}}"]
+
+# Step pipelines for 'gml run <name>'
+# [pipelines.invoices]
+# description = "Label and archive invoices"
+#
+# [[pipelines.invoices.steps]]
+# type = "search"
+# query = "from:billing@example.com in:inbox"
+#
+# [[pipelines.invoices.steps]]
+# type = "action"
+# add_labels = ["Invoices"]
+# remove_labels = ["INBOX"]
+
+# Flag defaults per command, in a section named after the command
+# (subcommands as [label.sync]); flags given on the command line win.
+# Use the flag name, with - or _ between words.
+# [list]
+# fields = "id,from,subject,date"
+# format = "json"
+# max_results = 50
+`
@@ -0,0 +1,207 @@
+package gml
+
+import (
+	"context"
+	"errors"
+	"fmt"
+	"io"
+	"log/slog"
+	"net/mail"
+	"strings"
+
+	"github.com/olekukonko/tablewriter"
+	"google.golang.org/api/people/v1"
+)
+
+// ContactSource is where a contact was found
+type ContactSource string
+
+const (
+	// ContactSourceContacts is the user's saved contacts
+	ContactSourceContacts ContactSource = "contacts"
+	// ContactSourceOther is "Other contacts", the people the user interacted
+	// with, e.g. replied to
+	ContactSourceOther ContactSource = "other"
+)
+
+// Contact is an email address of a person found by SearchContacts
+type Contact struct {
+	Name   string        `json:"name,omitempty"`
+	Email  string        `json:"email"`
+	Source ContactSource `json:"source"`
+}
+
+// Address returns the contact as a recipient, e.g. "Bob Smith <bob@example.com>"
+func (c Contact) Address() string {
+	return (&mail.Address{Name: c.Name, Address: c.Email}).String()
+}
+
+// maxContactResults is the largest page size of the People search methods
+const maxContactResults = 30
+
+// contactReadMask are the person fields SearchContacts reads
+const contactReadMask = "names,emailAddresses"
+
+// SearchContacts looks up contacts and other contacts by name or email
+// address prefix, with the People API. Each email address is a separate
+// result; addresses found in both sources are listed once, as contacts.
+// limit caps the results of each source (0 for the API default of 10).
+// A source whose scope wasn't granted is skipped unless both are missing
+func SearchContacts(ctx context.Context, svc *Service, query string, limit int) ([]Contact, error) {
+	warmUpContacts(ctx, svc)
+	return searchContacts(ctx, svc, query, limit)
+}
+
+// warmUpContacts sends the empty searches the People API asks for before
+// searching, which refresh its search cache. Failures show up in the search
+func warmUpContacts(ctx context.Context, svc *Service) {
+	if _, err := svc.People.People.SearchContacts().Query("").ReadMask(contactReadMask).Context(ctx).Do(); err != nil {
+		slog.Debug("contacts warmup failed", "err", err)
+	}
+	if _, err := svc.People.OtherContacts.Search().Query("").ReadMask(contactReadMask).Context(ctx).Do(); err != nil {
+		slog.Debug("other contacts warmup failed", "err", err)
+	}
+}
+
+// searchContacts is SearchContacts without the warmup
+func searchContacts(ctx context.Context, svc *Service, query string, limit int) ([]Contact, error) {
+	if limit > maxContactResults {
+		limit = maxContactResults
+	}
+
+	var contacts []Contact
+	seen := make(map[string]bool)
+	add := func(results []*people.SearchResult, source ContactSource) {
+		for _, r := range results {
+			if r.Person == nil {
+				continue
+			}
+			var name string
+			if len(r.Person.Names) > 0 {
+				name = r.Person.Names[0].DisplayName
+			}
+			for _, e := range r.Person.EmailAddresses {
+				key := strings.ToLower(e.Value)
+				if e.Value == "" || seen[key] {
+					continue
+				}
+				seen[key] = true
+				contacts = append(contacts, Contact{Name: name, Email: e.Value, Source: source})
+			}
+		}
+	}
+
+	var errs []error
+	call := svc.People.People.SearchContacts().Query(query).ReadMask(contactReadMask)
+	if limit > 0 {
+		call = call.PageSize(int64(limit))
+	}
+	if resp, err := call.Context(ctx).Do(); err != nil {
+		errs = append(errs, fmt.Errorf("unable to search contacts: %w", apiError(err)))
+	} else {
+		add(resp.Results, ContactSourceContacts)
+	}
+
+	other := svc.People.OtherContacts.Search().Query(query).ReadMask(contactReadMask)
+	if limit > 0 {
+		other = other.PageSize(int64(limit))
+	}
+	if resp, err := other.Context(ctx).Do(); err != nil {
+		errs = append(errs, fmt.Errorf("unable to search other contacts: %w", apiError(err)))
+	} else {
+		add(resp.Results, ContactSourceOther)
+	}
+
+	// One source is enough when the other's scope wasn't granted
+	for _, err := range errs {
+		if !errors.Is(err, ErrScopeMissing) || len(errs) == 2 {
+			return nil, errors.Join(errs...)
+		}
+		slog.Debug("skipping contact source", "err", err)
+	}
+	return contacts, nil
+}
+
+// IsContactName reports whether a recipient is a name to look up in the
+// contacts rather than an email address
+func IsContactName(recipient string) bool {
+	recipient = strings.TrimSpace(recipient)
+	return recipient != "" && !strings.Contains(recipient, "@")
+}
+
+// ResolveRecipients replaces the recipients of msg that are names, not
+// email addresses (see IsContactName), with the address of the contact
+// they match. A name matching several addresses is an error, unless one of
+// them matches it exactly: the full name, or the part of the address
+// before the @
+func ResolveRecipients(ctx context.Context, svc *Service, msg *OutgoingMessage) error {
+	warmed := false
+	for _, list := range []*[]string{&msg.To, &msg.Cc, &msg.Bcc} {
+		for i, r := range *list {
+			if !IsContactName(r) {
+				continue
+			}
+			if !warmed {
+				warmUpContacts(ctx, svc)
+				warmed = true
+			}
+			contact, err := resolveContact(ctx, svc, strings.TrimSpace(r))
+			if err != nil {
+				return err
+			}
+			slog.Debug("resolved recipient", "name", r, "address", contact.Email)
+			(*list)[i] = contact.Address()
+		}
+	}
+	return nil
+}
+
+// resolveContact returns the one contact matching a name
+func resolveContact(ctx context.Context, svc *Service, name string) (Contact, error) {
+	contacts, err := searchContacts(ctx, svc, name, maxContactResults)
+	if err != nil {
+		return Contact{}, fmt.Errorf("unable to resolve recipient %q: %w", name, err)
+	}
+	switch len(contacts) {
+	case 0:
+		return Contact{}, notFoundError("no contact matches %q", name)
+	case 1:
+		return contacts[0], nil
+	}
+
+	var exact []Contact
+	for _, c := range contacts {
+		local, _, _ := strings.Cut(c.Email, "@")
+		if strings.EqualFold(c.Name, name) || strings.EqualFold(local, name) {
+			exact = append(exact, c)
+		}
+	}
+	if len(exact) == 1 {
+		return exact[0], nil
+	}
+
+	candidates := make([]string, len(contacts))
+	for i, c := range contacts {
+		candidates[i] = c.Address()
+	}
+	return Contact{}, fmt.Errorf("recipient %q matches several contacts, use an address: %s",
+		name, strings.Join(candidates, ", "))
+}
+
+// FormatContacts outputs contacts in the specified format
+func FormatContacts(w io.Writer, contacts []Contact, format OutputFormat) error {
+	if format == OutputFormatJSON {
+		if contacts == nil {
+			contacts = []Contact{}
+		}
+		return FormatJSON(w, contacts)
+	}
+
+	table := tablewriter.NewWriter(w)
+	table.Header("NAME", "EMAIL", "SOURCE")
+	for _, c := range contacts {
+		table.Append(c.Name, c.Email, string(c.Source))
+	}
+	table.Render()
+	return nil
+}
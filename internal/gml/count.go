@@ -0,0 +1,71 @@
+package gml
+
+import (
+	"context"
+	"fmt"
+
+	"github.com/longkey1/gml/internal/google"
+	"google.golang.org/api/googleapi"
+)
+
+// CountOptions contains options for counting messages
+type CountOptions struct {
+	Query string
+	// LabelIDs are label names or IDs, resolved like 'gml list -l'
+	LabelIDs []string
+	// Exact pages through all matching IDs instead of using Gmail's estimate
+	Exact bool
+	// Progress, if set, is called with the number of IDs counted so far
+	Progress func(done, total int)
+}
+
+// CountResult is the number of messages matching a query
+type CountResult struct {
+	Count int64 `json:"count"`
+	// Exact is false when Count is Gmail's resultSizeEstimate
+	Exact  bool     `json:"exact"`
+	Query  string   `json:"query,omitempty"`
+	Labels []string `json:"labels,omitempty"`
+}
+
+// CountMessages returns the number of messages matching the query and labels.
+// By default it makes a single request and returns Gmail's resultSizeEstimate,
+// which is approximate for large results; Exact lists every matching ID
+func CountMessages(ctx context.Context, svc *Service, opts CountOptions) (*CountResult, error) {
+	var labelIDs []string
+	if len(opts.LabelIDs) > 0 {
+		idx, err := FetchLabelIndex(ctx, svc)
+		if err != nil {
+			return nil, err
+		}
+		if labelIDs, err = idx.ResolveLabelIDs(opts.LabelIDs); err != nil {
+			return nil, err
+		}
+	}
+
+	result := &CountResult{Exact: opts.Exact, Query: opts.Query, Labels: opts.LabelIDs}
+	listOpts := google.ListMessagesOptions{Query: opts.Query, LabelIDs: labelIDs, MaxResults: 1, Fields: []googleapi.Field{"resultSizeEstimate"}}
+	if opts.Exact {
+		listOpts.MaxResults, listOpts.Fields = 500, []googleapi.Field{"messages/id", "nextPageToken"}
+	}
+	for {
+		resp, err := svc.API.ListMessages(ctx, listOpts)
+		if err != nil {
+			return nil, fmt.Errorf("unable to count messages: %w", apiError(err))
+		}
+		if !opts.Exact {
+			result.Count = resp.ResultSizeEstimate
+			return result, nil
+		}
+
+		result.Count += int64(len(resp.Messages))
+		if opts.Progress != nil {
+			// The total is unknown until the last page
+			opts.Progress(int(result.Count), 0)
+		}
+		if resp.NextPageToken == "" {
+			return result, nil
+		}
+		listOpts.PageToken = resp.NextPageToken
+	}
+}
@@ -0,0 +1,68 @@
+package gml
+
+import (
+	"encoding/csv"
+	"fmt"
+	"io"
+	"strings"
+)
+
+// utf8BOM marks a file as UTF-8 for spreadsheet applications such as Excel
+const utf8BOM = "\xef\xbb\xbf"
+
+// CSVOptions controls CSV/TSV output for different spreadsheet locales
+type CSVOptions struct {
+	// Delimiter separates fields; zero means comma (tab for TSV)
+	Delimiter rune
+	// CRLF ends lines with \r\n instead of \n
+	CRLF bool
+	// BOM writes a UTF-8 byte order mark before the first row
+	BOM bool
+}
+
+// ParseDelimiter parses a delimiter flag value: a single character or one of
+// the names comma, semicolon, tab and pipe
+func ParseDelimiter(s string) (rune, error) {
+	switch strings.ToLower(s) {
+	case "":
+		return 0, nil
+	case "comma":
+		return ',', nil
+	case "semicolon":
+		return ';', nil
+	case "tab", `\t`:
+		return '\t', nil
+	case "pipe":
+		return '|', nil
+	}
+	r := []rune(s)
+	if len(r) != 1 || r[0] == '"' || r[0] == '\r' || r[0] == '\n' {
+		return 0, fmt.Errorf("invalid delimiter: %q", s)
+	}
+	return r[0], nil
+}
+
+// NewCSVWriter returns a csv.Writer configured by opts for the given format,
+// writing the byte order mark first if requested
+func NewCSVWriter(w io.Writer, format OutputFormat, opts CSVOptions) (*csv.Writer, error) {
+	if opts.BOM {
+		if _, err := io.WriteString(w, utf8BOM); err != nil {
+			return nil, fmt.Errorf("unable to write output: %w", err)
+		}
+	}
+
+	cw := csv.NewWriter(w)
+	switch {
+	case opts.Delimiter != 0:
+		cw.Comma = opts.Delimiter
+	case format == OutputFormatTSV:
+		cw.Comma = '\t'
+	}
+	cw.UseCRLF = opts.CRLF
+	return cw, nil
+}
+
+// IsDelimitedFormat reports whether format is CSV or TSV
+func IsDelimitedFormat(format OutputFormat) bool {
+	return format == OutputFormatCSV || format == OutputFormatTSV
+}
@@ -0,0 +1,308 @@
+package gml
+
+import (
+	"bytes"
+	"context"
+	"encoding/xml"
+	"errors"
+	"fmt"
+	"io"
+	"os"
+	"os/exec"
+	"path/filepath"
+	"runtime"
+	"strings"
+	"unicode"
+)
+
+// daemonLabelPrefix prefixes launchd job labels
+const daemonLabelPrefix = "com.github.longkey1."
+
+// DaemonService describes a long-running gml process to run as a user service
+type DaemonService struct {
+	// Name is the unit name, e.g. "gml-watch" or "gml-watch-work"
+	Name string
+	// Description is shown by systemctl status
+	Description string
+	// Command is the executable followed by its arguments
+	Command []string
+	// Env holds KEY=value assignments for the service environment
+	Env []string
+}
+
+// DaemonPlatform returns the user service manager for this OS (systemd or launchd)
+func DaemonPlatform() (string, error) {
+	switch runtime.GOOS {
+	case "linux":
+		return "systemd", nil
+	case "darwin":
+		return "launchd", nil
+	default:
+		return "", fmt.Errorf("service installation is not supported on %s", runtime.GOOS)
+	}
+}
+
+// DaemonFilePath returns where the unit file or plist for a service is written
+func DaemonFilePath(platform, name string) (string, error) {
+	home, err := os.UserHomeDir()
+	if err != nil {
+		return "", fmt.Errorf("unable to determine home directory: %w", err)
+	}
+	switch platform {
+	case "systemd":
+		dir := os.Getenv("XDG_CONFIG_HOME")
+		if dir == "" {
+			dir = filepath.Join(home, ".config")
+		}
+		return filepath.Join(dir, "systemd", "user", name+".service"), nil
+	case "launchd":
+		return filepath.Join(home, "Library", "LaunchAgents", daemonLabelPrefix+name+".plist"), nil
+	default:
+		return "", fmt.Errorf("unknown service platform: %s", platform)
+	}
+}
+
+// RenderDaemon returns the unit file (systemd) or property list (launchd) for a service
+func RenderDaemon(platform string, svc DaemonService) (string, error) {
+	switch platform {
+	case "systemd":
+		return systemdUnit(svc), nil
+	case "launchd":
+		return launchdPlist(svc)
+	default:
+		return "", fmt.Errorf("unknown service platform: %s", platform)
+	}
+}
+
+// InstallDaemon writes the service definition and, if enable is set, starts it
+// and enables it at login
+func InstallDaemon(ctx context.Context, platform string, svc DaemonService, enable bool, stdout, stderr io.Writer) (string, error) {
+	content, err := RenderDaemon(platform, svc)
+	if err != nil {
+		return "", err
+	}
+	path, err := DaemonFilePath(platform, svc.Name)
+	if err != nil {
+		return "", err
+	}
+
+	if err := os.MkdirAll(filepath.Dir(path), 0755); err != nil {
+		return "", fmt.Errorf("unable to create service directory: %w", err)
+	}
+	if platform == "launchd" {
+		if err := os.MkdirAll(launchdLogDir(path), 0755); err != nil {
+			return "", fmt.Errorf("unable to create log directory: %w", err)
+		}
+	}
+	if err := os.WriteFile(path, []byte(content), 0644); err != nil {
+		return "", fmt.Errorf("unable to write service file: %w", err)
+	}
+
+	if !enable {
+		return path, nil
+	}
+
+	switch platform {
+	case "systemd":
+		if err := runServiceManager(ctx, stdout, stderr, "systemctl", "--user", "daemon-reload"); err != nil {
+			return path, err
+		}
+		err = runServiceManager(ctx, stdout, stderr, "systemctl", "--user", "enable", "--now", svc.Name+".service")
+	case "launchd":
+		// Replace a previously loaded job so the new definition takes effect
+		_ = runServiceManager(ctx, io.Discard, io.Discard, "launchctl", "bootout", launchdDomain()+"/"+daemonLabelPrefix+svc.Name)
+		err = runServiceManager(ctx, stdout, stderr, "launchctl", "bootstrap", launchdDomain(), path)
+	}
+	return path, err
+}
+
+// UninstallDaemon stops and disables a service and removes its definition
+func UninstallDaemon(ctx context.Context, platform, name string, stdout, stderr io.Writer) (string, error) {
+	path, err := DaemonFilePath(platform, name)
+	if err != nil {
+		return "", err
+	}
+	if _, err := os.Stat(path); errors.Is(err, os.ErrNotExist) {
+		return "", fmt.Errorf("service not installed: %s", path)
+	}
+
+	switch platform {
+	case "systemd":
+		if err := runServiceManager(ctx, stdout, stderr, "systemctl", "--user", "disable", "--now", name+".service"); err != nil {
+			return path, err
+		}
+	case "launchd":
+		if err := runServiceManager(ctx, stdout, stderr, "launchctl", "bootout", launchdDomain()+"/"+daemonLabelPrefix+name); err != nil {
+			return path, err
+		}
+	}
+
+	if err := os.Remove(path); err != nil {
+		return path, fmt.Errorf("unable to remove service file: %w", err)
+	}
+	if platform == "systemd" {
+		return path, runServiceManager(ctx, stdout, stderr, "systemctl", "--user", "daemon-reload")
+	}
+	return path, nil
+}
+
+// runServiceManager runs systemctl or launchctl
+func runServiceManager(ctx context.Context, stdout, stderr io.Writer, name string, args ...string) error {
+	c := exec.CommandContext(ctx, name, args...)
+	c.Stdout = stdout
+	c.Stderr = stderr
+	if err := c.Run(); err != nil {
+		return fmt.Errorf("%s %s failed: %w", name, strings.Join(args, " "), err)
+	}
+	return nil
+}
+
+// systemdUnit renders a user-level systemd unit that restarts on failure
+func systemdUnit(svc DaemonService) string {
+	var b strings.Builder
+	b.WriteString("[Unit]\n")
+	fmt.Fprintf(&b, "Description=%s\n", strings.ReplaceAll(svc.Description, "%", "%%"))
+	b.WriteString("Wants=network-online.target\n")
+	b.WriteString("After=network-online.target\n")
+	b.WriteString("\n[Service]\n")
+	b.WriteString("Type=simple\n")
+	args := make([]string, len(svc.Command))
+	for i, arg := range svc.Command {
+		// ExecStart expands $VAR, Environment doesn't
+		args[i] = systemdQuote(strings.ReplaceAll(arg, "$", "$$"))
+	}
+	fmt.Fprintf(&b, "ExecStart=%s\n", strings.Join(args, " "))
+	for _, env := range svc.Env {
+		fmt.Fprintf(&b, "Environment=%s\n", systemdQuote(env))
+	}
+	b.WriteString("Restart=on-failure\n")
+	b.WriteString("RestartSec=10\n")
+	b.WriteString("\n[Install]\n")
+	b.WriteString("WantedBy=default.target\n")
+	return b.String()
+}
+
+// systemdQuote quotes a word for ExecStart or Environment, escaping the
+// specifier (%) character systemd would expand
+func systemdQuote(s string) string {
+	s = strings.ReplaceAll(s, "%", "%%")
+	if s != "" && !strings.ContainsFunc(s, func(r rune) bool {
+		return unicode.IsSpace(r) || r == '"' || r == '\'' || r == '\\' || r == ';'
+	}) {
+		return s
+	}
+	s = strings.ReplaceAll(s, `\`, `\\`)
+	s = strings.ReplaceAll(s, `"`, `\"`)
+	return `"` + s + `"`
+}
+
+// launchdPlist renders a launchd agent that runs at login and restarts on failure
+func launchdPlist(svc DaemonService) (string, error) {
+	path, err := DaemonFilePath("launchd", svc.Name)
+	if err != nil {
+		return "", err
+	}
+	logPath := filepath.Join(launchdLogDir(path), svc.Name+".log")
+
+	var b bytes.Buffer
+	b.WriteString(`<?xml version="1.0" encoding="UTF-8"?>` + "\n")
+	b.WriteString(`<!DOCTYPE plist PUBLIC "-//Apple//DTD PLIST 1.0//EN" "http://www.apple.com/DTDs/PropertyList-1.0.dtd">` + "\n")
+	b.WriteString(`<plist version="1.0">` + "\n<dict>\n")
+	plistString(&b, "Label", daemonLabelPrefix+svc.Name)
+	b.WriteString("\t<key>ProgramArguments</key>\n\t<array>\n")
+	for _, arg := range svc.Command {
+		b.WriteString("\t\t<string>")
+		xml.EscapeText(&b, []byte(arg))
+		b.WriteString("</string>\n")
+	}
+	b.WriteString("\t</array>\n")
+	if len(svc.Env) > 0 {
+		b.WriteString("\t<key>EnvironmentVariables</key>\n\t<dict>\n")
+		for _, env := range svc.Env {
+			key, value, _ := strings.Cut(env, "=")
+			b.WriteString("\t")
+			plistString(&b, key, value)
+		}
+		b.WriteString("\t</dict>\n")
+	}
+	b.WriteString("\t<key>RunAtLoad</key>\n\t<true/>\n")
+	// Restart only after a failed exit, like systemd's Restart=on-failure
+	b.WriteString("\t<key>KeepAlive</key>\n\t<dict>\n\t\t<key>SuccessfulExit</key>\n\t\t<false/>\n\t</dict>\n")
+	b.WriteString("\t<key>ThrottleInterval</key>\n\t<integer>10</integer>\n")
+	plistString(&b, "StandardOutPath", logPath)
+	plistString(&b, "StandardErrorPath", logPath)
+	b.WriteString("</dict>\n</plist>\n")
+	return b.String(), nil
+}
+
+// plistString writes a <key>/<string> pair
+func plistString(b *bytes.Buffer, key, value string) {
+	b.WriteString("\t<key>")
+	xml.EscapeText(b, []byte(key))
+	b.WriteString("</key>\n\t<string>")
+	xml.EscapeText(b, []byte(value))
+	b.WriteString("</string>\n")
+}
+
+// launchdLogDir returns ~/Library/Logs/gml for a plist under ~/Library/LaunchAgents
+func launchdLogDir(plistPath string) string {
+	return filepath.Join(filepath.Dir(filepath.Dir(plistPath)), "Logs", "gml")
+}
+
+// launchdDomain returns the launchctl domain of the current user's GUI session
+func launchdDomain() string {
+	return fmt.Sprintf("gui/%d", os.Getuid())
+}
+
+// SplitArgs splits a command line into words, honoring single quotes,
+// double quotes and backslash escapes like a POSIX shell
+func SplitArgs(s string) ([]string, error) {
+	var args []string
+	var cur strings.Builder
+	inWord := false
+	var quote rune
+	escaped := false
+
+	for _, r := range s {
+		switch {
+		case escaped:
+			cur.WriteRune(r)
+			escaped = false
+		case quote == '\'':
+			if r == '\'' {
+				quote = 0
+			} else {
+				cur.WriteRune(r)
+			}
+		case r == '\\':
+			escaped = true
+			inWord = true
+		case quote == '"':
+			if r == '"' {
+				quote = 0
+			} else {
+				cur.WriteRune(r)
+			}
+		case r == '\'' || r == '"':
+			quote = r
+			inWord = true
+		case unicode.IsSpace(r):
+			if inWord {
+				args = append(args, cur.String())
+				cur.Reset()
+				inWord = false
+			}
+		default:
+			cur.WriteRune(r)
+			inWord = true
+		}
+	}
+
+	if escaped || quote != 0 {
+		return nil, fmt.Errorf("unterminated quote or escape in: %s", s)
+	}
+	if inWord {
+		args = append(args, cur.String())
+	}
+	return args, nil
+}
@@ -0,0 +1,136 @@
+package gml
+
+import (
+	"context"
+	"fmt"
+	"sort"
+	"strings"
+	"time"
+
+	"github.com/longkey1/gml/internal/google"
+	"google.golang.org/api/gmail/v1"
+)
+
+// Dashboard is a compact overview of a mailbox
+type Dashboard struct {
+	Account         string        `json:"account,omitempty"`
+	Labels          []LabelCounts `json:"labels"`
+	ReceivedToday   int           `json:"receivedToday"`
+	OldestUnread    *time.Time    `json:"oldestUnread,omitempty"`
+	OldestUnreadAge string        `json:"oldestUnreadAge,omitempty"`
+	Drafts          int64         `json:"drafts"`
+}
+
+// LabelCounts holds message counts for a label
+type LabelCounts struct {
+	Name   string `json:"name"`
+	Unread int64  `json:"unread"`
+	Total  int64  `json:"total"`
+}
+
+// DashboardOptions contains options for building a dashboard
+type DashboardOptions struct {
+	// Labels to report; when empty, INBOX and every user label with unread messages
+	Labels []string
+	Now    time.Time
+}
+
+// GetDashboard collects unread counts, today's volume, the oldest unread inbox
+// message and the number of drafts
+func GetDashboard(ctx context.Context, svc *Service, opts DashboardOptions) (*Dashboard, error) {
+	now := opts.Now
+	if now.IsZero() {
+		now = time.Now()
+	}
+
+	labels, err := dashboardLabels(ctx, svc, opts.Labels)
+	if err != nil {
+		return nil, err
+	}
+
+	dashboard := &Dashboard{Labels: []LabelCounts{}}
+	for _, l := range labels {
+		dashboard.Labels = append(dashboard.Labels, LabelCounts{
+			Name:   l.Name,
+			Unread: l.MessagesUnread,
+			Total:  l.MessagesTotal,
+		})
+	}
+
+	midnight := time.Date(now.Year(), now.Month(), now.Day(), 0, 0, 0, 0, now.Location())
+	today, err := ListMessageIDs(ctx, svc, fmt.Sprintf("after:%d -in:sent -in:drafts", midnight.Unix()), nil)
+	if err != nil {
+		return nil, err
+	}
+	dashboard.ReceivedToday = len(today)
+
+	// Results are newest first, so the last unread ID is the oldest
+	unread, err := ListMessageIDs(ctx, svc, "", []string{"INBOX", "UNREAD"})
+	if err != nil {
+		return nil, err
+	}
+	if len(unread) > 0 {
+		msg, err := svc.API.GetMessage(ctx, unread[len(unread)-1], google.GetMessageOptions{Format: "minimal"})
+		if err != nil {
+			return nil, fmt.Errorf("unable to retrieve message: %w", apiError(err))
+		}
+		received := time.UnixMilli(msg.InternalDate)
+		dashboard.OldestUnread = &received
+		dashboard.OldestUnreadAge = FormatAge(now.Sub(received))
+	}
+
+	drafts, err := svc.API.GetLabel(ctx, "DRAFT")
+	if err != nil {
+		return nil, fmt.Errorf("unable to get label DRAFT: %w", apiError(err))
+	}
+	dashboard.Drafts = drafts.MessagesTotal
+
+	return dashboard, nil
+}
+
+// dashboardLabels fetches counts for the requested labels, or for INBOX and
+// every user label with unread messages
+func dashboardLabels(ctx context.Context, svc *Service, requested []string) ([]*gmail.Label, error) {
+	var ids []string
+	if len(requested) > 0 {
+		idx, err := FetchLabelIndex(ctx, svc)
+		if err != nil {
+			return nil, err
+		}
+		ids, err = idx.ResolveLabelIDs(requested)
+		if err != nil {
+			return nil, err
+		}
+	} else {
+		idx, err := FetchLabelIndex(ctx, svc)
+		if err != nil {
+			return nil, err
+		}
+		ids = append(ids, "INBOX")
+		for _, l := range idx.Labels() {
+			if l.Type == "user" {
+				ids = append(ids, l.ID)
+			}
+		}
+	}
+
+	var labels []*gmail.Label
+	for _, id := range ids {
+		l, err := svc.API.GetLabel(ctx, id)
+		if err != nil {
+			return nil, fmt.Errorf("unable to get label %s: %w", id, apiError(err))
+		}
+		if len(requested) == 0 && l.Type == "user" && l.MessagesUnread == 0 {
+			continue
+		}
+		labels = append(labels, l)
+	}
+
+	if len(requested) == 0 {
+		// Keep INBOX first, then user labels alphabetically
+		sort.SliceStable(labels[1:], func(i, j int) bool {
+			return strings.ToLower(labels[i+1].Name) < strings.ToLower(labels[j+1].Name)
+		})
+	}
+	return labels, nil
+}
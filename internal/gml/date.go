@@ -0,0 +1,124 @@
+package gml
+
+import (
+	"fmt"
+	"net/mail"
+	"regexp"
+	"strings"
+	"time"
+)
+
+// Named date formats accepted by --date-format and date_format
+const (
+	DateFormatRaw      = "raw"
+	DateFormatRelative = "relative"
+	DateFormatRFC3339  = "rfc3339"
+)
+
+// DefaultDateFormat is used when neither --date-format nor date_format is set
+const DefaultDateFormat = DateFormatRFC3339
+
+// dateLayouts maps named formats to Go time layouts
+var dateLayouts = map[string]string{
+	DateFormatRFC3339: time.RFC3339,
+	"rfc1123z":        time.RFC1123Z,
+	"datetime":        time.DateTime,
+	"date":            time.DateOnly,
+	"time":            time.TimeOnly,
+}
+
+// dateComment matches a trailing comment such as "(UTC)" or "(GMT+09:00)"
+var dateComment = regexp.MustCompile(`\s*\([^)]*\)\s*$`)
+
+// fallbackDateLayouts covers Date headers that net/mail rejects
+var fallbackDateLayouts = []string{
+	"Mon, _2 Jan 2006 15:04:05 -0700 MST",
+	"Mon, _2 Jan 2006 15:04:05 MST",
+	"_2 Jan 2006 15:04:05 MST",
+	time.RFC3339,
+}
+
+// ParseMailDate parses an RFC 2822 Date header, tolerating common deviations
+func ParseMailDate(value string) (time.Time, error) {
+	value = strings.TrimSpace(value)
+	if t, err := mail.ParseDate(value); err == nil {
+		return t, nil
+	}
+	trimmed := dateComment.ReplaceAllString(value, "")
+	if t, err := mail.ParseDate(trimmed); err == nil {
+		return t, nil
+	}
+	for _, layout := range fallbackDateLayouts {
+		if t, err := time.Parse(layout, trimmed); err == nil {
+			return t, nil
+		}
+	}
+	return time.Time{}, fmt.Errorf("unable to parse date: %q", value)
+}
+
+// ValidateDateFormat checks a date format: a named format or a Go time layout
+func ValidateDateFormat(format string) error {
+	if format == DateFormatRaw || format == DateFormatRelative {
+		return nil
+	}
+	if _, ok := dateLayouts[strings.ToLower(format)]; ok {
+		return nil
+	}
+	// A Go layout must reference at least one date or time element
+	sample := time.Date(2001, time.March, 4, 10, 20, 30, 0, time.UTC)
+	if sample.Format(format) == format {
+		return fmt.Errorf("unknown date format: %s (use raw, relative, rfc3339, rfc1123z, datetime, date, time or a Go layout)", format)
+	}
+	return nil
+}
+
+// FormatMailDate renders a Date header in local time using format
+// Values that can't be parsed are returned unchanged
+func FormatMailDate(value, format string, now time.Time) string {
+	if format == "" {
+		format = DefaultDateFormat
+	}
+	if format == DateFormatRaw || value == "" {
+		return value
+	}
+	t, err := ParseMailDate(value)
+	if err != nil {
+		return value
+	}
+	if format == DateFormatRelative {
+		return RelativeTime(t, now)
+	}
+	if layout, ok := dateLayouts[strings.ToLower(format)]; ok {
+		format = layout
+	}
+	return t.Local().Format(format)
+}
+
+// RelativeTime describes t relative to now, e.g. "2h ago" or "in 3d"
+// Times more than 30 days away are shown as a local date
+func RelativeTime(t, now time.Time) string {
+	d := now.Sub(t)
+	future := d < 0
+	if future {
+		d = -d
+	}
+
+	var s string
+	switch {
+	case d < time.Minute:
+		return "just now"
+	case d < time.Hour:
+		s = fmt.Sprintf("%dm", int(d/time.Minute))
+	case d < 24*time.Hour:
+		s = fmt.Sprintf("%dh", int(d/time.Hour))
+	case d < 30*24*time.Hour:
+		s = fmt.Sprintf("%dd", int(d/(24*time.Hour)))
+	default:
+		return t.Local().Format(time.DateOnly)
+	}
+
+	if future {
+		return "in " + s
+	}
+	return s + " ago"
+}
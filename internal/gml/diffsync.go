@@ -0,0 +1,167 @@
+package gml
+
+import (
+	"context"
+	"encoding/base64"
+	"fmt"
+	"sort"
+
+	"github.com/longkey1/gml/internal/google"
+	"google.golang.org/api/gmail/v1"
+)
+
+// DiffEntry describes a message found in only one of two accounts
+type DiffEntry struct {
+	Account   string `json:"account"`
+	ID        string `json:"id"`
+	MessageID string `json:"messageId"`
+	From      string `json:"from"`
+	Subject   string `json:"subject"`
+	Date      string `json:"date"`
+	Copied    bool   `json:"copied,omitempty"`
+}
+
+// DiffSyncOptions contains options for comparing two accounts
+type DiffSyncOptions struct {
+	SourceAccount string
+	TargetAccount string
+	Query         string
+	Labels        []string
+}
+
+// DiffSyncResult holds messages present in one account but not the other
+type DiffSyncResult struct {
+	Source       string      `json:"source"`
+	Target       string      `json:"target"`
+	OnlyInSource []DiffEntry `json:"onlyInSource"`
+	OnlyInTarget []DiffEntry `json:"onlyInTarget"`
+	// Skipped counts messages without a Message-ID header, which can't be matched
+	Skipped int `json:"skipped"`
+}
+
+// DiffAccounts compares the messages matching opts in two accounts by Message-ID header
+func DiffAccounts(ctx context.Context, src, dst *Service, opts DiffSyncOptions) (*DiffSyncResult, error) {
+	srcEntries, srcSkipped, err := collectMessageIDs(ctx, src, opts)
+	if err != nil {
+		return nil, fmt.Errorf("account %s: %w", opts.SourceAccount, err)
+	}
+	dstEntries, dstSkipped, err := collectMessageIDs(ctx, dst, opts)
+	if err != nil {
+		return nil, fmt.Errorf("account %s: %w", opts.TargetAccount, err)
+	}
+
+	result := &DiffSyncResult{
+		Source:       opts.SourceAccount,
+		Target:       opts.TargetAccount,
+		OnlyInSource: []DiffEntry{},
+		OnlyInTarget: []DiffEntry{},
+		Skipped:      srcSkipped + dstSkipped,
+	}
+	for id, entry := range srcEntries {
+		if _, ok := dstEntries[id]; !ok {
+			entry.Account = result.Source
+			result.OnlyInSource = append(result.OnlyInSource, entry)
+		}
+	}
+	for id, entry := range dstEntries {
+		if _, ok := srcEntries[id]; !ok {
+			entry.Account = result.Target
+			result.OnlyInTarget = append(result.OnlyInTarget, entry)
+		}
+	}
+	sortDiffEntries(result.OnlyInSource)
+	sortDiffEntries(result.OnlyInTarget)
+
+	return result, nil
+}
+
+// CopyMissing imports the raw source messages into the target account
+// Imported messages keep their original date but carry no labels, so they appear in All Mail
+// With a nil or non-ignoring failures log, copying stops at the first failure
+func CopyMissing(ctx context.Context, src, dst *Service, entries []DiffEntry, failures *FailureLog) (int, error) {
+	copied := 0
+	for i := range entries {
+		raw, _, err := GetRawMessage(ctx, src, entries[i].ID)
+		if err == nil {
+			_, err = ImportMessage(ctx, dst, raw, nil)
+		}
+		if err != nil {
+			if err := failures.Record(err, entries[i].ID); err != nil {
+				return copied, err
+			}
+			continue
+		}
+		entries[i].Copied = true
+		copied++
+	}
+	return copied, nil
+}
+
+// ImportMessage imports an RFC 822 message into the mailbox with the given label IDs,
+// using the Date header as the internal date
+func ImportMessage(ctx context.Context, svc *Service, raw []byte, labelIDs []string) (*gmail.Message, error) {
+	msg := &gmail.Message{
+		Raw:      base64.URLEncoding.EncodeToString(raw),
+		LabelIds: labelIDs,
+	}
+	imported, err := svc.Gmail.Users.Messages.Import("me", msg).
+		InternalDateSource("dateHeader").NeverMarkSpam(true).Context(ctx).Do()
+	if err != nil {
+		return nil, fmt.Errorf("unable to import message: %w", apiError(err))
+	}
+	return imported, nil
+}
+
+// collectMessageIDs returns the matching messages keyed by Message-ID header
+// and the number of messages without one
+func collectMessageIDs(ctx context.Context, svc *Service, opts DiffSyncOptions) (map[string]DiffEntry, int, error) {
+	var labelIDs []string
+	if len(opts.Labels) > 0 {
+		idx, err := FetchLabelIndex(ctx, svc)
+		if err != nil {
+			return nil, 0, err
+		}
+		labelIDs, err = idx.ResolveLabelIDs(opts.Labels)
+		if err != nil {
+			return nil, 0, err
+		}
+	}
+
+	ids, err := ListMessageIDs(ctx, svc, opts.Query, labelIDs)
+	if err != nil {
+		return nil, 0, err
+	}
+
+	entries := make(map[string]DiffEntry, len(ids))
+	skipped := 0
+	for _, id := range ids {
+		msg, err := svc.API.GetMessage(ctx, id, google.GetMessageOptions{
+			Format:          "metadata",
+			MetadataHeaders: []string{"Message-ID", "From", "Subject", "Date"},
+		})
+		if err != nil {
+			return nil, 0, fmt.Errorf("unable to retrieve message: %w", apiError(err))
+		}
+
+		messageID := headerValue(msg.Payload, "Message-ID")
+		if messageID == "" {
+			skipped++
+			continue
+		}
+		entries[messageID] = DiffEntry{
+			ID:        msg.Id,
+			MessageID: messageID,
+			From:      headerValue(msg.Payload, "From"),
+			Subject:   headerValue(msg.Payload, "Subject"),
+			Date:      headerValue(msg.Payload, "Date"),
+		}
+	}
+	return entries, skipped, nil
+}
+
+// sortDiffEntries orders entries by Message-ID for stable output
+func sortDiffEntries(entries []DiffEntry) {
+	sort.Slice(entries, func(i, j int) bool {
+		return entries[i].MessageID < entries[j].MessageID
+	})
+}
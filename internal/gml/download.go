@@ -0,0 +1,385 @@
+package gml
+
+import (
+	"bytes"
+	"context"
+	"crypto/sha256"
+	"fmt"
+	"io"
+	"os"
+	"path"
+	"path/filepath"
+	"strings"
+	"text/template"
+	"time"
+
+	"github.com/longkey1/gml/internal/google"
+	"github.com/olekukonko/tablewriter"
+)
+
+// DefaultAttachmentPattern names saved attachments after their filename
+const DefaultAttachmentPattern = "{{.Filename}}"
+
+// MessageAttachment is an attachment together with the message carrying it
+type MessageAttachment struct {
+	MessageID string    `json:"messageId"`
+	ThreadID  string    `json:"threadId"`
+	From      string    `json:"from"`
+	Subject   string    `json:"subject"`
+	Date      time.Time `json:"date"`
+	AttachmentInfo
+}
+
+// AttachmentSearchOptions contains options for finding attachments by query
+type AttachmentSearchOptions struct {
+	Query    string
+	LabelIDs []string
+	// Name keeps attachments whose filename matches this glob, case-insensitively
+	Name string
+	// MaxMessages stops after this many messages, newest first (0 for all)
+	MaxMessages int
+	// Progress, if set, is called as messages are fetched
+	Progress func(done, total int)
+}
+
+// FindAttachments returns the attachments of all messages matching the query,
+// newest message first ("has:attachment" is added to the query)
+func FindAttachments(ctx context.Context, svc *Service, opts AttachmentSearchOptions) ([]MessageAttachment, error) {
+	name := strings.ToLower(opts.Name)
+	if _, err := path.Match(name, ""); err != nil {
+		return nil, fmt.Errorf("invalid name pattern %s: %w", opts.Name, err)
+	}
+
+	var labelIDs []string
+	if len(opts.LabelIDs) > 0 {
+		idx, err := FetchLabelIndex(ctx, svc)
+		if err != nil {
+			return nil, err
+		}
+		if labelIDs, err = idx.ResolveLabelIDs(opts.LabelIDs); err != nil {
+			return nil, err
+		}
+	}
+
+	ids, err := ListMessageIDs(ctx, svc, strings.TrimSpace(opts.Query+" has:attachment"), labelIDs)
+	if err != nil {
+		return nil, err
+	}
+	if opts.MaxMessages > 0 && len(ids) > opts.MaxMessages {
+		ids = ids[:opts.MaxMessages]
+	}
+
+	var found []MessageAttachment
+	for i, id := range ids {
+		if opts.Progress != nil {
+			opts.Progress(i, len(ids))
+		}
+		msg, err := svc.API.GetMessage(ctx, id, google.GetMessageOptions{Format: "full"})
+		if err != nil {
+			return nil, fmt.Errorf("unable to retrieve message %s: %w", id, apiError(err))
+		}
+		for _, a := range ListAttachments(msg.Payload) {
+			if name != "" {
+				if ok, _ := path.Match(name, strings.ToLower(a.Filename)); !ok {
+					continue
+				}
+			}
+			found = append(found, MessageAttachment{
+				MessageID:      msg.Id,
+				ThreadID:       msg.ThreadId,
+				From:           headerValue(msg.Payload, "From"),
+				Subject:        headerValue(msg.Payload, "Subject"),
+				Date:           time.UnixMilli(msg.InternalDate),
+				AttachmentInfo: a,
+			})
+		}
+	}
+	if opts.Progress != nil {
+		opts.Progress(len(ids), len(ids))
+	}
+	return found, nil
+}
+
+// SaveStatus is what happened to an attachment when saving
+type SaveStatus string
+
+const (
+	// SaveStatusSaved means the attachment was written
+	SaveStatusSaved SaveStatus = "saved"
+	// SaveStatusExists means the file was already there and was kept
+	SaveStatusExists SaveStatus = "exists"
+	// SaveStatusDuplicate means identical content was already saved in this run
+	SaveStatusDuplicate SaveStatus = "duplicate"
+)
+
+// SavedAttachment is the outcome of saving one attachment
+type SavedAttachment struct {
+	MessageAttachment
+	Path   string     `json:"path,omitempty"`
+	Status SaveStatus `json:"status"`
+	// DuplicateOf is the path holding the same content, for duplicates
+	DuplicateOf string `json:"duplicateOf,omitempty"`
+}
+
+// SaveAttachmentsOptions contains options for saving attachments to files
+type SaveAttachmentsOptions struct {
+	Dir string
+	// Pattern renders the path of each file relative to Dir (see
+	// AttachmentPathData); it may contain slashes to create subdirectories
+	Pattern *template.Template
+	// Overwrite replaces existing files instead of skipping them
+	Overwrite bool
+	// KeepDuplicates saves attachments whose content was already saved in
+	// this run, e.g. a logo attached to every message
+	KeepDuplicates bool
+	// Progress, if set, is called as attachments are saved
+	Progress func(done, total int)
+}
+
+// AttachmentPathData is the data of the file name pattern. Every value is
+// made safe for file names: path separators and reserved characters become _
+type AttachmentPathData struct {
+	// Date is the day the message was received, e.g. 2025-01-31
+	Date string
+	// Time is the local time of day it was received, e.g. 153045
+	Time      string
+	Filename  string
+	Name      string
+	Ext       string
+	From      string
+	Subject   string
+	MessageID string
+	ThreadID  string
+	PartID    string
+}
+
+// ParseAttachmentPattern parses a file name pattern for SaveAttachments
+func ParseAttachmentPattern(text string) (*template.Template, error) {
+	tmpl, err := template.New("pattern").Funcs(templateFuncs).Option("missingkey=error").Parse(text)
+	if err != nil {
+		return nil, fmt.Errorf("invalid pattern: %w", err)
+	}
+	return tmpl, nil
+}
+
+// SaveAttachments downloads attachments into files named by the pattern.
+// Existing files are skipped unless Overwrite is set, attachments rendering
+// to the same path in one run get a numbered suffix, and content already
+// saved in this run is skipped unless KeepDuplicates is set
+func SaveAttachments(ctx context.Context, svc *Service, attachments []MessageAttachment, opts SaveAttachmentsOptions) ([]SavedAttachment, error) {
+	used := map[string]bool{}
+	hashes := map[[sha256.Size]byte]string{}
+
+	results := make([]SavedAttachment, 0, len(attachments))
+	for i, a := range attachments {
+		if opts.Progress != nil {
+			opts.Progress(i, len(attachments))
+		}
+		rel, err := attachmentPath(opts.Pattern, a)
+		if err != nil {
+			return results, err
+		}
+		target := uniquePath(filepath.Join(opts.Dir, rel), used)
+		result := SavedAttachment{MessageAttachment: a, Path: target}
+
+		if _, err := os.Stat(target); err == nil && !opts.Overwrite {
+			// Remember the content so a rerun still skips its duplicates
+			if sum, err := hashFile(target); err == nil {
+				if _, seen := hashes[sum]; !seen {
+					hashes[sum] = target
+				}
+			}
+			result.Status = SaveStatusExists
+			results = append(results, result)
+			continue
+		}
+
+		hash, err := saveAttachmentFile(ctx, svc, a, target, func(sum [sha256.Size]byte) bool {
+			_, seen := hashes[sum]
+			return !seen || opts.KeepDuplicates
+		})
+		if err != nil {
+			return results, err
+		}
+		if first, seen := hashes[hash]; seen && !opts.KeepDuplicates {
+			// Nothing was written, so a later attachment may take the path
+			delete(used, target)
+			result.Status = SaveStatusDuplicate
+			result.Path = ""
+			result.DuplicateOf = first
+		} else {
+			result.Status = SaveStatusSaved
+			if !seen {
+				hashes[hash] = target
+			}
+		}
+		results = append(results, result)
+	}
+	if opts.Progress != nil {
+		opts.Progress(len(attachments), len(attachments))
+	}
+	return results, nil
+}
+
+// saveAttachmentFile downloads an attachment to a temporary file next to
+// target and renames it into place when keep accepts its content hash
+func saveAttachmentFile(ctx context.Context, svc *Service, a MessageAttachment, target string, keep func([sha256.Size]byte) bool) ([sha256.Size]byte, error) {
+	var sum [sha256.Size]byte
+	if err := os.MkdirAll(filepath.Dir(target), 0755); err != nil {
+		return sum, fmt.Errorf("unable to create directory: %w", err)
+	}
+	tmp, err := os.CreateTemp(filepath.Dir(target), ".gml-*")
+	if err != nil {
+		return sum, fmt.Errorf("unable to create file: %w", err)
+	}
+	defer os.Remove(tmp.Name())
+
+	h := sha256.New()
+	err = WriteAttachment(ctx, svc, a.MessageID, a.AttachmentInfo, io.MultiWriter(tmp, h))
+	if cerr := tmp.Close(); err == nil && cerr != nil {
+		err = fmt.Errorf("unable to write attachment: %w", cerr)
+	}
+	if err != nil {
+		return sum, fmt.Errorf("%s (message %s): %w", a.Filename, a.MessageID, err)
+	}
+	copy(sum[:], h.Sum(nil))
+
+	if keep(sum) {
+		if err := os.Rename(tmp.Name(), target); err != nil {
+			return sum, fmt.Errorf("unable to save attachment: %w", err)
+		}
+	}
+	return sum, nil
+}
+
+// hashFile returns the SHA-256 of a file's content
+func hashFile(name string) ([sha256.Size]byte, error) {
+	var sum [sha256.Size]byte
+	f, err := os.Open(name)
+	if err != nil {
+		return sum, err
+	}
+	defer f.Close()
+	h := sha256.New()
+	if _, err := io.Copy(h, f); err != nil {
+		return sum, err
+	}
+	copy(sum[:], h.Sum(nil))
+	return sum, nil
+}
+
+// attachmentPath renders the pattern for an attachment; the result must stay
+// inside the target directory
+func attachmentPath(pattern *template.Template, a MessageAttachment) (string, error) {
+	ext := filepath.Ext(a.Filename)
+	local := a.Date.Local()
+	data := AttachmentPathData{
+		Date:      local.Format("2006-01-02"),
+		Time:      local.Format("150405"),
+		Filename:  safeFileName(a.Filename),
+		Name:      safeFileName(strings.TrimSuffix(a.Filename, ext)),
+		Ext:       safeFileName(ext),
+		From:      safeFileName(senderAddress(a.From)),
+		Subject:   safeFileName(a.Subject),
+		MessageID: a.MessageID,
+		ThreadID:  a.ThreadID,
+		PartID:    a.PartID,
+	}
+
+	var buf bytes.Buffer
+	if err := pattern.Execute(&buf, data); err != nil {
+		return "", fmt.Errorf("unable to render pattern: %w", err)
+	}
+	rel := filepath.FromSlash(strings.TrimSpace(buf.String()))
+	if rel == "" || !filepath.IsLocal(rel) {
+		return "", fmt.Errorf("pattern renders %q for %s, which is not a path inside the directory", buf.String(), a.Filename)
+	}
+	return rel, nil
+}
+
+// safeFileName replaces path separators, characters reserved on Windows and
+// control characters with _, so a value can't leave its directory
+func safeFileName(s string) string {
+	s = strings.Map(func(r rune) rune {
+		if r < 0x20 || r == 0x7f || strings.ContainsRune(`/\:*?"<>|`, r) {
+			return '_'
+		}
+		return r
+	}, s)
+	s = strings.Trim(s, " .")
+	if s == "" {
+		return "_"
+	}
+	return s
+}
+
+// uniquePath returns p, or p with a " (n)" suffix before the extension when
+// an earlier attachment of this run already took it, and marks it used
+func uniquePath(p string, used map[string]bool) string {
+	candidate := p
+	ext := filepath.Ext(p)
+	for n := 2; used[candidate]; n++ {
+		candidate = fmt.Sprintf("%s (%d)%s", strings.TrimSuffix(p, ext), n, ext)
+	}
+	used[candidate] = true
+	return candidate
+}
+
+// FormatMessageAttachments outputs found attachments in the specified format
+func FormatMessageAttachments(w io.Writer, attachments []MessageAttachment, format OutputFormat, csvOpts CSVOptions) error {
+	switch format {
+	case OutputFormatJSON:
+		if attachments == nil {
+			attachments = []MessageAttachment{}
+		}
+		return FormatJSON(w, attachments)
+	case OutputFormatCSV, OutputFormatTSV:
+		cw, err := NewCSVWriter(w, format, csvOpts)
+		if err != nil {
+			return err
+		}
+		cw.Write([]string{"message_id", "date", "from", "subject", "part_id", "filename", "mime_type", "size"})
+		for _, a := range attachments {
+			cw.Write([]string{a.MessageID, a.Date.Format(time.RFC3339), a.From, a.Subject, a.PartID, a.Filename, a.MimeType, fmt.Sprint(a.Size)})
+		}
+		cw.Flush()
+		return cw.Error()
+	default:
+		var total int64
+		table := tablewriter.NewWriter(w)
+		table.Header("MESSAGE", "DATE", "FROM", "FILENAME", "SIZE")
+		for _, a := range attachments {
+			table.Append(a.MessageID, a.Date.Local().Format("2006-01-02"), a.From, a.Filename, FormatSize(a.Size))
+			total += a.Size
+		}
+		table.Render()
+		fmt.Fprintf(w, "%d attachments, %s\n", len(attachments), FormatSize(total))
+		return nil
+	}
+}
+
+// FormatSavedAttachments outputs the outcome of SaveAttachments
+func FormatSavedAttachments(w io.Writer, saved []SavedAttachment, format OutputFormat) error {
+	if format == OutputFormatJSON {
+		if saved == nil {
+			saved = []SavedAttachment{}
+		}
+		return FormatJSON(w, saved)
+	}
+
+	counts := map[SaveStatus]int{}
+	for _, s := range saved {
+		counts[s.Status]++
+		switch s.Status {
+		case SaveStatusSaved:
+			fmt.Fprintf(w, "saved      %s (%s)\n", s.Path, FormatSize(s.Size))
+		case SaveStatusExists:
+			fmt.Fprintf(w, "exists     %s\n", s.Path)
+		case SaveStatusDuplicate:
+			fmt.Fprintf(w, "duplicate  %s (same as %s)\n", s.Filename, s.DuplicateOf)
+		}
+	}
+	fmt.Fprintf(w, "Saved %d, skipped %d existing and %d duplicate attachments\n",
+		counts[SaveStatusSaved], counts[SaveStatusExists], counts[SaveStatusDuplicate])
+	return nil
+}
@@ -0,0 +1,162 @@
+package gml
+
+import (
+	"bytes"
+	"context"
+	"encoding/base64"
+	"fmt"
+
+	"google.golang.org/api/gmail/v1"
+	"google.golang.org/api/googleapi"
+)
+
+// DraftInfo represents a draft for output
+type DraftInfo struct {
+	ID        string `json:"id,omitempty"`
+	MessageID string `json:"messageId,omitempty"`
+	ThreadID  string `json:"threadId,omitempty"`
+	To        string `json:"to,omitempty"`
+	Subject   string `json:"subject,omitempty"`
+	Date      string `json:"date,omitempty"`
+	Snippet   string `json:"snippet,omitempty"`
+}
+
+// DraftFields are the fields of draft listings
+var DraftFields = FieldSet{
+	Names: []string{"id", "messageid", "threadid", "to", "subject", "date", "snippet"},
+}
+
+// FieldValue returns a field of the draft for table and CSV output
+func (d DraftInfo) FieldValue(name string) any {
+	switch name {
+	case "id":
+		return d.ID
+	case "messageid":
+		return d.MessageID
+	case "threadid":
+		return d.ThreadID
+	case "to":
+		return d.To
+	case "subject":
+		return d.Subject
+	case "date":
+		return d.Date
+	case "snippet":
+		return d.Snippet
+	}
+	return nil
+}
+
+// ListDraftsOptions contains options for listing drafts
+type ListDraftsOptions struct {
+	Query      string
+	MaxResults int64
+	Fields     map[string]bool
+	// Progress, if set, is called as draft details are fetched
+	Progress func(done, total int)
+}
+
+// ListDrafts returns up to MaxResults drafts matching the query, newest first.
+// Draft details are only fetched for fields beyond the IDs
+func ListDrafts(ctx context.Context, svc *Service, opts ListDraftsOptions) ([]DraftInfo, error) {
+	// List drafts with pagination up to MaxResults
+	var listed []*gmail.Draft
+	pageToken := ""
+	for int64(len(listed)) < opts.MaxResults {
+		call := svc.Gmail.Users.Drafts.List("me").MaxResults(min(opts.MaxResults-int64(len(listed)), 500)).Context(ctx)
+		if opts.Query != "" {
+			call = call.Q(opts.Query)
+		}
+		if pageToken != "" {
+			call = call.PageToken(pageToken)
+		}
+
+		result, err := call.Do()
+		if err != nil {
+			return nil, fmt.Errorf("unable to list drafts: %w", apiError(err))
+		}
+		listed = append(listed, result.Drafts...)
+
+		if result.NextPageToken == "" {
+			break
+		}
+		pageToken = result.NextPageToken
+	}
+
+	// drafts.list only returns the draft, message and thread IDs
+	needsDetails := opts.Fields["to"] || opts.Fields["subject"] || opts.Fields["date"] || opts.Fields["snippet"]
+
+	drafts := make([]DraftInfo, 0, len(listed))
+	for i, d := range listed {
+		if opts.Progress != nil {
+			opts.Progress(i, len(listed))
+		}
+		msg := d.Message
+		if needsDetails {
+			draft, err := svc.Gmail.Users.Drafts.Get("me", d.Id).Format("metadata").Context(ctx).Do()
+			if err != nil {
+				return nil, fmt.Errorf("unable to get draft %s: %w", d.Id, apiError(err))
+			}
+			msg = draft.Message
+		}
+		drafts = append(drafts, buildDraftInfo(d.Id, msg, opts.Fields))
+	}
+	if opts.Progress != nil {
+		opts.Progress(len(listed), len(listed))
+	}
+	return drafts, nil
+}
+
+// buildDraftInfo constructs a DraftInfo with the requested fields
+func buildDraftInfo(id string, msg *gmail.Message, fields map[string]bool) DraftInfo {
+	info := DraftInfo{}
+	if fields["id"] {
+		info.ID = id
+	}
+	if msg == nil {
+		return info
+	}
+	if fields["messageid"] {
+		info.MessageID = msg.Id
+	}
+	if fields["threadid"] {
+		info.ThreadID = msg.ThreadId
+	}
+	if fields["to"] {
+		info.To = headerValue(msg.Payload, "To")
+	}
+	if fields["subject"] {
+		info.Subject = headerValue(msg.Payload, "Subject")
+	}
+	if fields["date"] {
+		info.Date = headerValue(msg.Payload, "Date")
+	}
+	if fields["snippet"] {
+		info.Snippet = msg.Snippet
+	}
+	return info
+}
+
+// CreateDraft saves the message as a draft of the authenticated user; unlike
+// a sent message, it may have no recipients. Large messages are uploaded as
+// media, like in SendMessage
+func CreateDraft(ctx context.Context, svc *Service, msg *OutgoingMessage) (*gmail.Draft, error) {
+	raw, err := msg.build()
+	if err != nil {
+		return nil, err
+	}
+
+	message := &gmail.Message{ThreadId: msg.ThreadID}
+	if len(raw) <= maxRawSize {
+		message.Raw = base64.URLEncoding.EncodeToString(raw)
+	}
+	call := svc.Gmail.Users.Drafts.Create("me", &gmail.Draft{Message: message})
+	if len(raw) > maxRawSize {
+		call = call.Media(bytes.NewReader(raw), googleapi.ContentType("message/rfc822"))
+	}
+	draft, err := call.Context(ctx).Do()
+	if err != nil {
+		return nil, fmt.Errorf("unable to create draft: %w", apiError(err))
+	}
+	return draft, nil
+}
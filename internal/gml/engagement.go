@@ -0,0 +1,343 @@
+package gml
+
+import (
+	"context"
+	"fmt"
+	"io"
+	"mime"
+	"net/mail"
+	"slices"
+	"strings"
+	"time"
+
+	"github.com/longkey1/gml/internal/google"
+	"github.com/olekukonko/tablewriter"
+	"google.golang.org/api/gmail/v1"
+	"google.golang.org/api/googleapi"
+)
+
+// EngagementOutcome is what came back from one recipient of a sent message
+type EngagementOutcome string
+
+const (
+	// EngagementReplied means the recipient answered in the thread
+	EngagementReplied EngagementOutcome = "replied"
+	// EngagementAutoReplied means only an automatic answer (e.g. out of office) arrived
+	EngagementAutoReplied EngagementOutcome = "auto-replied"
+	// EngagementRead means the recipient's client sent a read receipt (RFC 8098)
+	EngagementRead EngagementOutcome = "read"
+	// EngagementBounced means a delivery status notification reported a failure
+	EngagementBounced EngagementOutcome = "bounced"
+	// EngagementNoResponse means nothing came back
+	EngagementNoResponse EngagementOutcome = "no-response"
+)
+
+// EngagementOutcomes lists the outcomes in summary order, strongest signal first
+var EngagementOutcomes = []EngagementOutcome{
+	EngagementReplied, EngagementAutoReplied, EngagementRead, EngagementBounced, EngagementNoResponse,
+}
+
+// RecipientEngagement is the outcome for one recipient of one sent thread
+type RecipientEngagement struct {
+	Recipient string            `json:"recipient"`
+	Outcome   EngagementOutcome `json:"outcome"`
+	ThreadID  string            `json:"threadId"`
+	Subject   string            `json:"subject"`
+	Sent      time.Time         `json:"sent"`
+	// Responded is when the reply, receipt or bounce arrived
+	Responded time.Time `json:"responded,omitzero"`
+	// Detail is the bounce reason, when bounced
+	Detail string `json:"detail,omitempty"`
+}
+
+// EngagementCount is the number of recipients with an outcome
+type EngagementCount struct {
+	Outcome EngagementOutcome `json:"outcome"`
+	Count   int               `json:"count"`
+}
+
+// EngagementReport summarizes the outcomes of sent campaign threads
+type EngagementReport struct {
+	Query      string                `json:"query"`
+	Threads    int                   `json:"threads"`
+	Summary    []EngagementCount     `json:"summary"`
+	Recipients []RecipientEngagement `json:"recipients"`
+}
+
+// EngagementOptions contains options for an engagement report
+type EngagementOptions struct {
+	Query    string
+	LabelIDs []string
+	// Progress, if set, is called as threads are fetched
+	Progress func(done, total int)
+}
+
+// engagementHeaders are the headers needed to classify the messages of a thread
+var engagementHeaders = []string{
+	"From", "To", "Cc", "Bcc", "Subject", "Message-ID", "Content-Type", "Auto-Submitted", "X-Autoreply",
+}
+
+// Engagement inspects the threads matching the query that contain a sent
+// message and reports, per recipient, whether they replied, sent a read
+// receipt, answered automatically, bounced or stayed silent
+//
+// Opens cannot be observed without tracking pixels, so read receipts are the
+// only read signal. Bounces are matched to the sent message by its Message-ID
+// when the notification quotes it, otherwise by recipient address
+func Engagement(ctx context.Context, svc *Service, opts EngagementOptions) (*EngagementReport, error) {
+	var labelIDs []string
+	if len(opts.LabelIDs) > 0 {
+		idx, err := FetchLabelIndex(ctx, svc)
+		if err != nil {
+			return nil, err
+		}
+		if labelIDs, err = idx.ResolveLabelIDs(opts.LabelIDs); err != nil {
+			return nil, err
+		}
+	}
+
+	threadIDs, err := listThreadIDs(ctx, svc, strings.TrimSpace(opts.Query+" in:sent"), labelIDs)
+	if err != nil {
+		return nil, err
+	}
+
+	var (
+		rows []RecipientEngagement
+		// sentIDs maps the Message-ID of sent messages to their rows
+		sentIDs = map[string][]int{}
+		first   time.Time
+	)
+	for i, id := range threadIDs {
+		if opts.Progress != nil {
+			opts.Progress(i, len(threadIDs))
+		}
+		thread, err := svc.API.GetThread(ctx, id, google.GetMessageOptions{Format: "metadata", MetadataHeaders: engagementHeaders})
+		if err != nil {
+			return nil, fmt.Errorf("unable to get thread %s: %w", id, apiError(err))
+		}
+
+		start := len(rows)
+		rows = append(rows, threadEngagement(thread, sentIDs, len(rows))...)
+		for _, r := range rows[start:] {
+			if first.IsZero() || r.Sent.Before(first) {
+				first = r.Sent
+			}
+		}
+	}
+	if opts.Progress != nil {
+		opts.Progress(len(threadIDs), len(threadIDs))
+	}
+
+	if len(rows) > 0 {
+		bounces, err := ListBounces(ctx, svc, BounceOptions{Query: fmt.Sprintf("after:%d", first.Unix())})
+		if err != nil {
+			return nil, err
+		}
+		applyBounces(rows, sentIDs, bounces)
+	}
+
+	return &EngagementReport{
+		Query:      opts.Query,
+		Threads:    len(threadIDs),
+		Summary:    summarizeEngagement(rows),
+		Recipients: rows,
+	}, nil
+}
+
+// listThreadIDs returns the IDs of all threads matching the query and labels
+func listThreadIDs(ctx context.Context, svc *Service, query string, labelIDs []string) ([]string, error) {
+	var ids []string
+	opts := google.ListMessagesOptions{
+		Query:      query,
+		LabelIDs:   labelIDs,
+		MaxResults: 500,
+		Fields:     []googleapi.Field{"nextPageToken", "threads/id"},
+	}
+	for {
+		result, err := svc.API.ListThreads(ctx, opts)
+		if err != nil {
+			return nil, fmt.Errorf("unable to list threads: %w", apiError(err))
+		}
+		for _, t := range result.Threads {
+			ids = append(ids, t.Id)
+		}
+
+		if result.NextPageToken == "" {
+			break
+		}
+		opts.PageToken = result.NextPageToken
+	}
+	return ids, nil
+}
+
+// threadEngagement returns a row for every recipient of the sent messages in
+// a thread, classified by the messages that came back from them. Row indexes,
+// offset by base, are recorded in sentIDs under the sent Message-ID
+func threadEngagement(thread *gmail.Thread, sentIDs map[string][]int, base int) []RecipientEngagement {
+	var rows []RecipientEngagement
+	byRecipient := map[string]int{}
+	for _, msg := range thread.Messages {
+		if !slices.Contains(msg.LabelIds, "SENT") {
+			continue
+		}
+		sent := time.UnixMilli(msg.InternalDate)
+		messageID := headerValue(msg.Payload, "Message-ID")
+		for _, name := range []string{"To", "Cc", "Bcc"} {
+			for _, addr := range headerAddresses(headerValue(msg.Payload, name)) {
+				i, ok := byRecipient[addr]
+				if !ok {
+					i = len(rows)
+					byRecipient[addr] = i
+					rows = append(rows, RecipientEngagement{
+						Recipient: addr,
+						Outcome:   EngagementNoResponse,
+						ThreadID:  thread.Id,
+						Subject:   headerValue(msg.Payload, "Subject"),
+						Sent:      sent,
+					})
+				}
+				if messageID != "" {
+					sentIDs[messageID] = append(sentIDs[messageID], base+i)
+				}
+			}
+		}
+	}
+
+	for _, msg := range thread.Messages {
+		if slices.Contains(msg.LabelIds, "SENT") {
+			continue
+		}
+		i, ok := byRecipient[senderAddress(headerValue(msg.Payload, "From"))]
+		if !ok {
+			continue
+		}
+		outcome := responseOutcome(msg.Payload)
+		if engagementRank(outcome) < engagementRank(rows[i].Outcome) {
+			rows[i].Outcome = outcome
+			rows[i].Responded = time.UnixMilli(msg.InternalDate)
+		}
+	}
+	return rows
+}
+
+// responseOutcome classifies a message from a recipient as a read receipt,
+// an automatic answer or a reply
+func responseOutcome(payload *gmail.MessagePart) EngagementOutcome {
+	mediaType, params, _ := mime.ParseMediaType(headerValue(payload, "Content-Type"))
+	if mediaType == "multipart/report" && strings.EqualFold(params["report-type"], "disposition-notification") {
+		return EngagementRead
+	}
+	if auto := strings.ToLower(headerValue(payload, "Auto-Submitted")); auto != "" && auto != "no" {
+		return EngagementAutoReplied
+	}
+	if headerValue(payload, "X-Autoreply") != "" {
+		return EngagementAutoReplied
+	}
+	return EngagementReplied
+}
+
+// applyBounces marks the rows failed by a bounce: by the quoted Message-ID of
+// the sent message when present, otherwise by any row sent to the address
+// before the notification
+func applyBounces(rows []RecipientEngagement, sentIDs map[string][]int, bounces []Bounce) {
+	for _, b := range bounces {
+		recipient := strings.ToLower(b.Recipient)
+		received, _ := mail.ParseDate(b.Date)
+
+		var candidates []int
+		if b.MessageID != "" {
+			candidates = sentIDs[b.MessageID]
+		} else {
+			for i, r := range rows {
+				if received.IsZero() || !received.Before(r.Sent) {
+					candidates = append(candidates, i)
+				}
+			}
+		}
+		for _, i := range candidates {
+			if rows[i].Recipient != recipient || engagementRank(EngagementBounced) >= engagementRank(rows[i].Outcome) {
+				continue
+			}
+			rows[i].Outcome = EngagementBounced
+			rows[i].Responded = received
+			rows[i].Detail = strings.TrimSpace(strings.Join([]string{b.Status, b.Reason}, " "))
+		}
+	}
+}
+
+// engagementRank orders outcomes by strength, lower is stronger
+func engagementRank(o EngagementOutcome) int {
+	return slices.Index(EngagementOutcomes, o)
+}
+
+// summarizeEngagement counts the rows per outcome, in EngagementOutcomes order
+func summarizeEngagement(rows []RecipientEngagement) []EngagementCount {
+	counts := make([]EngagementCount, len(EngagementOutcomes))
+	for i, o := range EngagementOutcomes {
+		counts[i].Outcome = o
+	}
+	for _, r := range rows {
+		counts[engagementRank(r.Outcome)].Count++
+	}
+	return counts
+}
+
+// headerAddresses returns the lowercased addresses of an address list header
+func headerAddresses(value string) []string {
+	if strings.TrimSpace(value) == "" {
+		return nil
+	}
+	list, err := mail.ParseAddressList(value)
+	if err != nil {
+		return []string{senderAddress(value)}
+	}
+	addrs := make([]string, len(list))
+	for i, a := range list {
+		addrs[i] = strings.ToLower(a.Address)
+	}
+	return addrs
+}
+
+// FormatEngagement outputs an engagement report in the specified format
+func FormatEngagement(w io.Writer, report *EngagementReport, format OutputFormat, csvOpts CSVOptions) error {
+	switch format {
+	case OutputFormatJSON:
+		if report.Recipients == nil {
+			report.Recipients = []RecipientEngagement{}
+		}
+		return FormatJSON(w, report)
+	case OutputFormatCSV, OutputFormatTSV:
+		cw, err := NewCSVWriter(w, format, csvOpts)
+		if err != nil {
+			return err
+		}
+		cw.Write([]string{"recipient", "outcome", "thread_id", "subject", "sent", "responded", "detail"})
+		for _, r := range report.Recipients {
+			cw.Write([]string{r.Recipient, string(r.Outcome), r.ThreadID, r.Subject, formatEngagementTime(r.Sent), formatEngagementTime(r.Responded), r.Detail})
+		}
+		cw.Flush()
+		return cw.Error()
+	default:
+		table := tablewriter.NewWriter(w)
+		table.Header("RECIPIENT", "OUTCOME", "SENT", "RESPONDED", "SUBJECT")
+		for _, r := range report.Recipients {
+			table.Append(r.Recipient, string(r.Outcome), formatEngagementTime(r.Sent), formatEngagementTime(r.Responded), r.Subject)
+		}
+		table.Render()
+
+		total := len(report.Recipients)
+		fmt.Fprintf(w, "Threads: %d, recipients: %d\n", report.Threads, total)
+		for _, c := range report.Summary {
+			fmt.Fprintf(w, "  %-13s %5d  %5.1f%%\n", c.Outcome, c.Count, 100*float64(c.Count)/float64(max(total, 1)))
+		}
+		return nil
+	}
+}
+
+// formatEngagementTime formats a sent or response time, empty when unset
+func formatEngagementTime(t time.Time) string {
+	if t.IsZero() {
+		return ""
+	}
+	return t.Local().Format("2006-01-02 15:04")
+}
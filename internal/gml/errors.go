@@ -0,0 +1,99 @@
+package gml
+
+import (
+	"errors"
+	"fmt"
+	"net/http"
+
+	"golang.org/x/oauth2"
+	"google.golang.org/api/googleapi"
+)
+
+// Kinds of errors returned by this package; test for them with errors.Is.
+// The underlying *googleapi.Error or *oauth2.RetrieveError stays reachable
+// with errors.As
+var (
+	// ErrNotFound means a message, thread, label or other named item doesn't exist
+	ErrNotFound = errors.New("not found")
+	// ErrAuthExpired means the stored token was revoked or expired and the
+	// account has to sign in again
+	ErrAuthExpired = errors.New("authorization expired")
+	// ErrQuotaExceeded means a Gmail quota or rate limit was hit; retry later
+	ErrQuotaExceeded = errors.New("quota exceeded")
+	// ErrScopeMissing means the token lacks the OAuth scope the request needs
+	ErrScopeMissing = errors.New("missing OAuth scope")
+)
+
+// Error is an error classified by kind: errors.Is matches Kind, and
+// errors.As reaches the wrapped error
+type Error struct {
+	Kind error
+	Err  error
+}
+
+func (e *Error) Error() string {
+	return e.Err.Error()
+}
+
+func (e *Error) Unwrap() []error {
+	return []error{e.Kind, e.Err}
+}
+
+// ErrorKind returns the kind of err (one of the Err* sentinels), also for
+// API errors that weren't classified yet, or nil
+func ErrorKind(err error) error {
+	for _, kind := range []error{ErrNotFound, ErrAuthExpired, ErrQuotaExceeded, ErrScopeMissing} {
+		if errors.Is(err, kind) {
+			return kind
+		}
+	}
+	return apiErrorKind(err)
+}
+
+// apiError classifies an error returned by a Gmail API call; errors of no
+// known kind are returned as is
+func apiError(err error) error {
+	if kind := apiErrorKind(err); kind != nil {
+		return &Error{Kind: kind, Err: err}
+	}
+	return err
+}
+
+// apiErrorKind maps Gmail API and token refresh errors to their kind
+func apiErrorKind(err error) error {
+	var retrieveErr *oauth2.RetrieveError
+	if errors.As(err, &retrieveErr) {
+		if retrieveErr.ErrorCode == "invalid_grant" {
+			return ErrAuthExpired
+		}
+		return nil
+	}
+
+	var apiErr *googleapi.Error
+	if !errors.As(err, &apiErr) {
+		return nil
+	}
+	switch apiErr.Code {
+	case http.StatusNotFound:
+		return ErrNotFound
+	case http.StatusUnauthorized:
+		return ErrAuthExpired
+	case http.StatusTooManyRequests:
+		return ErrQuotaExceeded
+	case http.StatusForbidden:
+		for _, e := range apiErr.Errors {
+			switch e.Reason {
+			case "rateLimitExceeded", "userRateLimitExceeded", "quotaExceeded", "dailyLimitExceeded":
+				return ErrQuotaExceeded
+			case "insufficientPermissions":
+				return ErrScopeMissing
+			}
+		}
+	}
+	return nil
+}
+
+// notFoundError returns an error of kind ErrNotFound with the given message
+func notFoundError(format string, args ...any) error {
+	return &Error{Kind: ErrNotFound, Err: fmt.Errorf(format, args...)}
+}
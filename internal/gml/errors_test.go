@@ -0,0 +1,89 @@
+package gml
+
+import (
+	"context"
+	"errors"
+	"net/http"
+	"testing"
+
+	"github.com/longkey1/gml/internal/fakegmail"
+	"golang.org/x/oauth2"
+	"google.golang.org/api/googleapi"
+)
+
+func TestAPIErrorKinds(t *testing.T) {
+	forbidden := func(reason string) error {
+		return &googleapi.Error{Code: http.StatusForbidden, Errors: []googleapi.ErrorItem{{Reason: reason}}}
+	}
+	tests := []struct {
+		name string
+		err  error
+		want error
+	}{
+		{name: "not found", err: &googleapi.Error{Code: http.StatusNotFound}, want: ErrNotFound},
+		{name: "unauthorized", err: &googleapi.Error{Code: http.StatusUnauthorized}, want: ErrAuthExpired},
+		{name: "too many requests", err: &googleapi.Error{Code: http.StatusTooManyRequests}, want: ErrQuotaExceeded},
+		{name: "rate limit", err: forbidden("rateLimitExceeded"), want: ErrQuotaExceeded},
+		{name: "user rate limit", err: forbidden("userRateLimitExceeded"), want: ErrQuotaExceeded},
+		{name: "quota", err: forbidden("quotaExceeded"), want: ErrQuotaExceeded},
+		{name: "daily limit", err: forbidden("dailyLimitExceeded"), want: ErrQuotaExceeded},
+		{name: "insufficient permissions", err: forbidden("insufficientPermissions"), want: ErrScopeMissing},
+		{name: "other forbidden", err: forbidden("domainPolicy"), want: nil},
+		{name: "server error", err: &googleapi.Error{Code: http.StatusInternalServerError}, want: nil},
+		{name: "expired token", err: &oauth2.RetrieveError{ErrorCode: "invalid_grant"}, want: ErrAuthExpired},
+		{name: "other token error", err: &oauth2.RetrieveError{ErrorCode: "invalid_client"}, want: nil},
+		{name: "plain error", err: errors.New("connection reset"), want: nil},
+	}
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			svc, fake := newTestService(t)
+			id := fake.AddMessage(fakegmail.Message{Subject: "hello", Date: testDate})
+			fake.Fail = func(method, failID string) error {
+				if method == "GetMessage" && failID == id {
+					return tt.err
+				}
+				return nil
+			}
+
+			_, err := GetMessage(context.Background(), svc, id, GetMessageOptions{})
+			if err == nil {
+				t.Fatal("GetMessage() succeeded, want an error")
+			}
+			if got := ErrorKind(err); got != tt.want {
+				t.Errorf("ErrorKind() = %v, want %v", got, tt.want)
+			}
+			for _, kind := range []error{ErrNotFound, ErrAuthExpired, ErrQuotaExceeded, ErrScopeMissing} {
+				if got := errors.Is(err, kind); got != (kind == tt.want) {
+					t.Errorf("errors.Is(err, %v) = %v", kind, got)
+				}
+			}
+			if !errors.Is(err, tt.err) {
+				t.Errorf("error %v doesn't wrap %v", err, tt.err)
+			}
+			var apiErr *googleapi.Error
+			if _, ok := tt.err.(*googleapi.Error); ok && !errors.As(err, &apiErr) {
+				t.Errorf("errors.As(%v, *googleapi.Error) = false", err)
+			}
+		})
+	}
+}
+
+func TestFakeErrorKinds(t *testing.T) {
+	svc, fake := newTestService(t)
+	id := fake.AddMessage(fakegmail.Message{Subject: "hello", Date: testDate})
+	ctx := context.Background()
+
+	// The fake's own errors classify like Gmail's
+	err := ModifyMessage(ctx, svc, id, []string{"Label_404"}, nil)
+	if err == nil || ErrorKind(err) != nil {
+		t.Errorf("ModifyMessage() with an invalid label: error = %v, kind %v, want an unclassified error", err, ErrorKind(err))
+	}
+	var apiErr *googleapi.Error
+	if !errors.As(err, &apiErr) || apiErr.Code != http.StatusBadRequest {
+		t.Errorf("ModifyMessage() with an invalid label: error = %v, want a 400", err)
+	}
+
+	if _, err := GetAttachments(ctx, svc, "missing"); !errors.Is(err, ErrNotFound) {
+		t.Errorf("GetAttachments() of a missing message: error = %v, want ErrNotFound", err)
+	}
+}
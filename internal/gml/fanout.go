@@ -0,0 +1,63 @@
+package gml
+
+import (
+	"context"
+	"fmt"
+	"sync"
+
+	"github.com/longkey1/gml/internal/telemetry"
+	"go.opentelemetry.io/otel/attribute"
+)
+
+// AccountResult holds the outcome of running an operation against one account
+type AccountResult[T any] struct {
+	Account string
+	Value   T
+	Err     error
+}
+
+// ForEachAccount runs fn concurrently against a service for each configuration
+// Results are returned in the same order as configs
+func ForEachAccount[T any](ctx context.Context, configs []*Config, fn func(ctx context.Context, svc *Service) (T, error)) []AccountResult[T] {
+	results := make([]AccountResult[T], len(configs))
+
+	var wg sync.WaitGroup
+	for i, cfg := range configs {
+		wg.Add(1)
+		go func() {
+			defer wg.Done()
+			results[i].Account = cfg.Account
+
+			ctx, span := telemetry.Start(ctx, "account", attribute.String("gml.account", cfg.Account))
+			defer func() { telemetry.End(span, results[i].Err) }()
+
+			svc, err := NewService(ctx, cfg)
+			if err != nil {
+				results[i].Err = fmt.Errorf("unable to create service: %w", err)
+				return
+			}
+			results[i].Value, results[i].Err = fn(ctx, svc)
+		}()
+	}
+	wg.Wait()
+
+	return results
+}
+
+// MergeAccountResults flattens per-account slices, tagging each item with its account name
+// Failed accounts are skipped and their errors returned alongside the merged items
+func MergeAccountResults[T any](results []AccountResult[[]T], tag func(item *T, account string)) ([]T, []error) {
+	var merged []T
+	var errs []error
+	for _, r := range results {
+		if r.Err != nil {
+			errs = append(errs, fmt.Errorf("account %s: %w", r.Account, r.Err))
+			continue
+		}
+		for i := range r.Value {
+			tag(&r.Value[i], r.Account)
+		}
+		merged = append(merged, r.Value...)
+	}
+	return merged, errs
+}
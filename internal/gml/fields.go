@@ -0,0 +1,228 @@
+package gml
+
+import (
+	"encoding/json"
+	"fmt"
+	"io"
+	"slices"
+	"strings"
+
+	"github.com/mattn/go-runewidth"
+	"github.com/olekukonko/tablewriter"
+)
+
+// FieldSet describes the fields a listing command can output, so list,
+// thread list and draft list share -f parsing, validation and column order
+type FieldSet struct {
+	// Names lists every field in output order
+	Names []string
+	// Long lists fields too long for a table cell (bodies), printed per row
+	// after table and markdown output
+	Long []string
+}
+
+// MessageFields are the fields of message listings (MessageInfo)
+var MessageFields = FieldSet{
+	Names: []string{"account", "id", "threadid", "type", "url", "from", "to", "subject", "date", "size", "labels", "attachments", "snippet", "auth", "headers", "body"},
+	Long:  []string{"headers", "body"},
+}
+
+// String returns the field names, comma-separated, for flag help
+func (s FieldSet) String() string {
+	return strings.Join(s.Names, ",")
+}
+
+// Parse parses a comma-separated field list, rejecting names not in the set
+func (s FieldSet) Parse(list string) (map[string]bool, error) {
+	fields := ParseFields(list)
+	delete(fields, "")
+	for f := range fields {
+		if !slices.Contains(s.Names, f) {
+			return nil, fmt.Errorf("unknown field %q (available: %s)", f, s)
+		}
+	}
+	if len(fields) == 0 {
+		return nil, fmt.Errorf("no fields selected (available: %s)", s)
+	}
+	return fields, nil
+}
+
+// Columns returns the selected fields in output order, with or without the
+// long fields
+func (s FieldSet) Columns(fields map[string]bool, long bool) []string {
+	var columns []string
+	for _, f := range s.Names {
+		if fields[f] && (long || !slices.Contains(s.Long, f)) {
+			columns = append(columns, f)
+		}
+	}
+	return columns
+}
+
+// Record is one row of a field-based listing
+type Record interface {
+	// FieldValue returns the value of a field: a string, a []string joined
+	// per format, or anything fmt can print
+	FieldValue(name string) any
+}
+
+// RecordOptions controls FormatRecords
+type RecordOptions struct {
+	// CSV applies to CSV and TSV output
+	CSV CSVOptions
+	// Table applies to text output
+	Table TableOptions
+}
+
+// FormatRecords outputs rows with the selected fields: JSON and NDJSON encode
+// the rows themselves, markdown and text draw a table followed by the long
+// fields, and CSV/TSV write every field untruncated
+func FormatRecords[R Record](w io.Writer, rows []R, set FieldSet, fields map[string]bool, format OutputFormat, opts RecordOptions) error {
+	switch {
+	case format == OutputFormatJSON:
+		data, err := json.MarshalIndent(rows, "", "  ")
+		if err != nil {
+			return fmt.Errorf("unable to marshal JSON: %w", err)
+		}
+		fmt.Fprintln(w, string(data))
+		return nil
+	case format == OutputFormatNDJSON:
+		enc := NewNDJSONWriter(w)
+		for _, r := range rows {
+			if err := enc.Write(r); err != nil {
+				return err
+			}
+		}
+		return nil
+	case format == OutputFormatMarkdown:
+		formatRecordsMarkdown(w, rows, set, fields)
+		return nil
+	case IsDelimitedFormat(format):
+		return formatRecordsCSV(w, rows, set, fields, format, opts.CSV)
+	}
+	formatRecordsTable(w, rows, set, fields, opts.Table)
+	return nil
+}
+
+// fieldText renders a field value, joining lists with sep
+func fieldText(v any, sep string) string {
+	switch v := v.(type) {
+	case string:
+		return v
+	case []string:
+		return strings.Join(v, sep)
+	case nil:
+		return ""
+	}
+	return fmt.Sprint(v)
+}
+
+// recordText returns a field of a row for headings
+func recordText(r Record, name string) string {
+	return fieldText(r.FieldValue(name), ", ")
+}
+
+// formatRecordsTable outputs rows as a table laid out to opts.Width
+func formatRecordsTable[R Record](w io.Writer, rows []R, set FieldSet, fields map[string]bool, opts TableOptions) {
+	columns := set.Columns(fields, false)
+
+	cells := make([][]string, len(rows))
+	natural := make([]int, len(columns))
+	for i, c := range columns {
+		natural[i] = len(c)
+	}
+	for r, rec := range rows {
+		row := make([]string, len(columns))
+		for i, c := range columns {
+			row[i] = fieldText(rec.FieldValue(c), ", ")
+			natural[i] = max(natural[i], runewidth.StringWidth(row[i]))
+		}
+		cells[r] = row
+	}
+	widths := tableColumnWidths(columns, natural, opts)
+
+	headers := make([]any, len(columns))
+	for i, c := range columns {
+		headers[i] = strings.ToUpper(c)
+	}
+	table := tablewriter.NewWriter(w)
+	table.Header(headers...)
+
+	for _, row := range cells {
+		line := make([]any, len(row))
+		for i, v := range row {
+			line[i] = fitTableCell(v, widths[i], opts.Wrap)
+		}
+		table.Append(line)
+	}
+
+	table.Render()
+
+	// Print long fields separately
+	for _, f := range set.Columns(fields, true) {
+		if !slices.Contains(set.Long, f) {
+			continue
+		}
+		for _, rec := range rows {
+			if text := recordText(rec, f); text != "" {
+				fmt.Fprintf(w, "\n=== %s ===\n%s\n", recordText(rec, "id"), text)
+			}
+		}
+	}
+}
+
+// formatRecordsCSV outputs rows as CSV or TSV with untruncated values
+func formatRecordsCSV[R Record](w io.Writer, rows []R, set FieldSet, fields map[string]bool, format OutputFormat, opts CSVOptions) error {
+	cw, err := NewCSVWriter(w, format, opts)
+	if err != nil {
+		return err
+	}
+
+	columns := set.Columns(fields, true)
+	cw.Write(columns)
+	for _, rec := range rows {
+		row := make([]string, len(columns))
+		for i, c := range columns {
+			row[i] = fieldText(rec.FieldValue(c), ",")
+		}
+		cw.Write(row)
+	}
+
+	cw.Flush()
+	return cw.Error()
+}
+
+// formatRecordsMarkdown outputs rows as a GitHub-flavored markdown table,
+// followed by one section per row for each long field
+func formatRecordsMarkdown[R Record](w io.Writer, rows []R, set FieldSet, fields map[string]bool) {
+	if headers := set.Columns(fields, false); len(headers) > 0 {
+		writeMarkdownRow(w, headers)
+		sep := make([]string, len(headers))
+		for i := range sep {
+			sep[i] = "---"
+		}
+		writeMarkdownRow(w, sep)
+
+		for _, rec := range rows {
+			row := make([]string, len(headers))
+			for i, f := range headers {
+				row[i] = markdownCell(fieldText(rec.FieldValue(f), ", "))
+			}
+			writeMarkdownRow(w, row)
+		}
+	}
+
+	for _, f := range set.Columns(fields, true) {
+		if !slices.Contains(set.Long, f) {
+			continue
+		}
+		for _, rec := range rows {
+			text := recordText(rec, f)
+			if text == "" {
+				continue
+			}
+			fmt.Fprintf(w, "\n## %s\n\n", markdownHeading(recordText(rec, "subject"), recordText(rec, "id")))
+			writeMarkdownFence(w, text)
+		}
+	}
+}
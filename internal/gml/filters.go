@@ -0,0 +1,187 @@
+package gml
+
+import (
+	"context"
+	"encoding/json"
+	"fmt"
+	"io"
+	"reflect"
+
+	"google.golang.org/api/gmail/v1"
+)
+
+// Filter is a Gmail filter with labels referenced by name so that exported
+// filter sets can be imported into another account
+type Filter struct {
+	ID       string         `json:"id,omitempty"`
+	Criteria FilterCriteria `json:"criteria"`
+	Action   FilterAction   `json:"action"`
+}
+
+// FilterCriteria selects the messages a filter applies to
+type FilterCriteria struct {
+	From           string `json:"from,omitempty"`
+	To             string `json:"to,omitempty"`
+	Subject        string `json:"subject,omitempty"`
+	Query          string `json:"query,omitempty"`
+	NegatedQuery   string `json:"negatedQuery,omitempty"`
+	HasAttachment  bool   `json:"hasAttachment,omitempty"`
+	ExcludeChats   bool   `json:"excludeChats,omitempty"`
+	Size           int64  `json:"size,omitempty"`
+	SizeComparison string `json:"sizeComparison,omitempty"`
+}
+
+// FilterAction describes what a filter does to matching messages
+// Archiving is expressed as removing INBOX, marking read as removing UNREAD
+type FilterAction struct {
+	AddLabels    []string `json:"addLabels,omitempty"`
+	RemoveLabels []string `json:"removeLabels,omitempty"`
+	Forward      string   `json:"forward,omitempty"`
+}
+
+// ListFilters returns all filters with label IDs mapped to names
+func ListFilters(ctx context.Context, svc *Service) ([]Filter, error) {
+	resp, err := svc.Gmail.Users.Settings.Filters.List("me").Context(ctx).Do()
+	if err != nil {
+		return nil, fmt.Errorf("unable to list filters: %w", apiError(err))
+	}
+
+	idx, err := FetchLabelIndex(ctx, svc)
+	if err != nil {
+		return nil, err
+	}
+
+	filters := []Filter{}
+	for _, f := range resp.Filter {
+		filters = append(filters, filterFromAPI(f, idx))
+	}
+	return filters, nil
+}
+
+// CreateFilter creates a filter, creating any user labels it references
+func CreateFilter(ctx context.Context, svc *Service, filter Filter) (*Filter, error) {
+	idx, err := FetchLabelIndex(ctx, svc)
+	if err != nil {
+		return nil, err
+	}
+	return createFilter(ctx, svc, idx, filter)
+}
+
+// DeleteFilter deletes a filter by ID
+func DeleteFilter(ctx context.Context, svc *Service, id string) error {
+	if err := svc.Gmail.Users.Settings.Filters.Delete("me", id).Context(ctx).Do(); err != nil {
+		return fmt.Errorf("unable to delete filter %s: %w", id, apiError(err))
+	}
+	return nil
+}
+
+// FilterImportResult summarizes a filter import
+type FilterImportResult struct {
+	Created int `json:"created"`
+	Skipped int `json:"skipped"`
+}
+
+// ImportFilters creates filters read from JSON (as written by 'gml filter export'),
+// skipping filters identical to existing ones
+func ImportFilters(ctx context.Context, svc *Service, r io.Reader) (*FilterImportResult, error) {
+	var filters []Filter
+	if err := json.NewDecoder(r).Decode(&filters); err != nil {
+		return nil, fmt.Errorf("unable to parse filters: %w", err)
+	}
+
+	existing, err := ListFilters(ctx, svc)
+	if err != nil {
+		return nil, err
+	}
+
+	idx, err := FetchLabelIndex(ctx, svc)
+	if err != nil {
+		return nil, err
+	}
+
+	result := &FilterImportResult{}
+	for _, f := range filters {
+		if containsFilter(existing, f) {
+			result.Skipped++
+			continue
+		}
+		if _, err := createFilter(ctx, svc, idx, f); err != nil {
+			return result, err
+		}
+		result.Created++
+	}
+	return result, nil
+}
+
+func createFilter(ctx context.Context, svc *Service, idx *LabelIndex, filter Filter) (*Filter, error) {
+	addIDs, err := idx.EnsureLabelIDs(ctx, svc, filter.Action.AddLabels)
+	if err != nil {
+		return nil, err
+	}
+	removeIDs, err := idx.EnsureLabelIDs(ctx, svc, filter.Action.RemoveLabels)
+	if err != nil {
+		return nil, err
+	}
+
+	c := filter.Criteria
+	created, err := svc.Gmail.Users.Settings.Filters.Create("me", &gmail.Filter{
+		Criteria: &gmail.FilterCriteria{
+			From:           c.From,
+			To:             c.To,
+			Subject:        c.Subject,
+			Query:          c.Query,
+			NegatedQuery:   c.NegatedQuery,
+			HasAttachment:  c.HasAttachment,
+			ExcludeChats:   c.ExcludeChats,
+			Size:           c.Size,
+			SizeComparison: c.SizeComparison,
+		},
+		Action: &gmail.FilterAction{
+			AddLabelIds:    addIDs,
+			RemoveLabelIds: removeIDs,
+			Forward:        filter.Action.Forward,
+		},
+	}).Context(ctx).Do()
+	if err != nil {
+		return nil, fmt.Errorf("unable to create filter: %w", apiError(err))
+	}
+
+	f := filterFromAPI(created, idx)
+	return &f, nil
+}
+
+// filterFromAPI converts an API filter, mapping label IDs to names
+func filterFromAPI(f *gmail.Filter, idx *LabelIndex) Filter {
+	filter := Filter{ID: f.Id}
+	if c := f.Criteria; c != nil {
+		filter.Criteria = FilterCriteria{
+			From:           c.From,
+			To:             c.To,
+			Subject:        c.Subject,
+			Query:          c.Query,
+			NegatedQuery:   c.NegatedQuery,
+			HasAttachment:  c.HasAttachment,
+			ExcludeChats:   c.ExcludeChats,
+			Size:           c.Size,
+			SizeComparison: c.SizeComparison,
+		}
+	}
+	if a := f.Action; a != nil {
+		filter.Action = FilterAction{
+			AddLabels:    idx.MapLabelIDsToNames(a.AddLabelIds),
+			RemoveLabels: idx.MapLabelIDsToNames(a.RemoveLabelIds),
+			Forward:      a.Forward,
+		}
+	}
+	return filter
+}
+
+// containsFilter reports whether filters has one with the same criteria and action
+func containsFilter(filters []Filter, f Filter) bool {
+	for _, existing := range filters {
+		if reflect.DeepEqual(existing.Criteria, f.Criteria) && reflect.DeepEqual(existing.Action, f.Action) {
+			return true
+		}
+	}
+	return false
+}
@@ -1,11 +1,16 @@
 package gml
 
 import (
+	"bytes"
 	"encoding/json"
 	"fmt"
 	"io"
 	"strings"
+	"sync"
+	"text/template"
+	"time"
 
+	"github.com/mattn/go-runewidth"
 	"github.com/olekukonko/tablewriter"
 )
 
@@ -15,89 +20,68 @@ type OutputFormat string
 const (
 	OutputFormatText OutputFormat = "text"
 	OutputFormatJSON OutputFormat = "json"
+	OutputFormatCSV  OutputFormat = "csv"
+	OutputFormatTSV  OutputFormat = "tsv"
+	// OutputFormatNDJSON writes one JSON object per line
+	OutputFormatNDJSON OutputFormat = "ndjson"
+	// OutputFormatMarkdown writes GitHub-flavored markdown
+	OutputFormatMarkdown OutputFormat = "markdown"
+	// OutputFormatTemplate executes a Go template per item
+	OutputFormatTemplate OutputFormat = "template"
+	// OutputFormatSQLite writes to a database file rather than a stream
+	OutputFormatSQLite OutputFormat = "sqlite"
 )
 
 // FormatMessageList outputs messages in the specified format
 func FormatMessageList(w io.Writer, messages []MessageInfo, fields map[string]bool, format OutputFormat) error {
-	if format == OutputFormatJSON {
-		return formatMessagesJSON(w, messages)
-	}
-	return formatMessagesTable(w, messages, fields)
+	return FormatMessageListCSV(w, messages, fields, format, CSVOptions{})
+}
+
+// FormatMessageListCSV outputs messages like FormatMessageList, applying csvOpts
+// to CSV and TSV output
+func FormatMessageListCSV(w io.Writer, messages []MessageInfo, fields map[string]bool, format OutputFormat, csvOpts CSVOptions) error {
+	return FormatRecords(w, messages, MessageFields, fields, format, RecordOptions{
+		CSV:   csvOpts,
+		Table: TableOptions{Width: TerminalWidth(w)},
+	})
 }
 
 // FormatMessageDetail outputs a message detail in the specified format
 func FormatMessageDetail(w io.Writer, detail *MessageDetail, format OutputFormat) error {
-	if format == OutputFormatJSON {
+	switch format {
+	case OutputFormatJSON:
 		return formatDetailJSON(w, detail)
+	case OutputFormatMarkdown:
+		return formatDetailMarkdown(w, detail)
 	}
 	return formatDetailText(w, detail)
 }
 
-// formatMessagesJSON outputs messages as JSON
-func formatMessagesJSON(w io.Writer, messages []MessageInfo) error {
-	data, err := json.MarshalIndent(messages, "", "  ")
-	if err != nil {
-		return fmt.Errorf("unable to marshal JSON: %w", err)
-	}
-	fmt.Fprintln(w, string(data))
-	return nil
+// NDJSONWriter writes values as newline-delimited JSON
+// It is safe for concurrent use, so per-account goroutines can share one stream
+type NDJSONWriter struct {
+	mu  sync.Mutex
+	enc *json.Encoder
 }
 
-// formatMessagesTable outputs messages as a table
-func formatMessagesTable(w io.Writer, messages []MessageInfo, fields map[string]bool) error {
-	// Build header based on selected fields
-	var headers []any
-	fieldOrder := []string{"id", "threadid", "url", "from", "to", "subject", "date", "labels", "snippet"}
-	for _, f := range fieldOrder {
-		if fields[f] {
-			headers = append(headers, strings.ToUpper(f))
-		}
-	}
-
-	table := tablewriter.NewWriter(w)
-	table.Header(headers...)
-
-	for _, msg := range messages {
-		var row []any
-		for _, f := range fieldOrder {
-			if !fields[f] {
-				continue
-			}
-			switch f {
-			case "id":
-				row = append(row, msg.ID)
-			case "threadid":
-				row = append(row, msg.ThreadID)
-			case "url":
-				row = append(row, msg.URL)
-			case "from":
-				row = append(row, truncate(msg.From, 30))
-			case "to":
-				row = append(row, truncate(msg.To, 30))
-			case "subject":
-				row = append(row, truncate(msg.Subject, 40))
-			case "date":
-				row = append(row, msg.Date)
-			case "labels":
-				row = append(row, strings.Join(msg.Labels, ", "))
-			case "snippet":
-				row = append(row, truncate(msg.Snippet, 50))
-			}
-		}
-		table.Append(row)
-	}
-
-	table.Render()
+// NewNDJSONWriter creates an NDJSON writer
+func NewNDJSONWriter(w io.Writer) *NDJSONWriter {
+	return &NDJSONWriter{enc: json.NewEncoder(w)}
+}
 
-	// Print body separately if requested
-	if fields["body"] {
-		for _, msg := range messages {
-			if msg.Body != "" {
-				fmt.Fprintf(w, "\n=== %s ===\n%s\n", msg.ID, msg.Body)
-			}
-		}
+// Write writes v as one JSON line
+func (n *NDJSONWriter) Write(v any) error {
+	n.mu.Lock()
+	defer n.mu.Unlock()
+	if err := n.enc.Encode(v); err != nil {
+		return fmt.Errorf("unable to write JSON: %w", err)
 	}
+	return nil
+}
 
+// FormatMessageTable outputs messages as a table laid out to opts.Width
+func FormatMessageTable(w io.Writer, messages []MessageInfo, fields map[string]bool, opts TableOptions) error {
+	formatRecordsTable(w, messages, MessageFields, fields, opts)
 	return nil
 }
 
@@ -115,6 +99,9 @@ func formatDetailJSON(w io.Writer, detail *MessageDetail) error {
 func formatDetailText(w io.Writer, detail *MessageDetail) error {
 	fmt.Fprintf(w, "ID: %s\n", detail.ID)
 	fmt.Fprintf(w, "ThreadID: %s\n", detail.ThreadID)
+	if detail.Type != MessageTypeMessage {
+		fmt.Fprintf(w, "Type: %s\n", detail.Type)
+	}
 	fmt.Fprintf(w, "URL: %s\n", detail.URL)
 	fmt.Fprintf(w, "From: %s\n", detail.From)
 	fmt.Fprintf(w, "To: %s\n", detail.To)
@@ -123,15 +110,516 @@ func formatDetailText(w io.Writer, detail *MessageDetail) error {
 	if len(detail.Labels) > 0 {
 		fmt.Fprintf(w, "Labels: %s\n", strings.Join(detail.Labels, ", "))
 	}
+	if detail.Auth != nil {
+		fmt.Fprintf(w, "Auth: %s\n", detail.Auth)
+	}
+	if len(detail.Headers) > 0 {
+		fmt.Fprintln(w, "Headers:")
+		for _, line := range strings.Split(FormatHeaders(detail.Headers), "\n") {
+			fmt.Fprintf(w, "  %s\n", line)
+		}
+	}
 	fmt.Fprintln(w, "---")
 	fmt.Fprintln(w, detail.Body)
 	return nil
 }
 
-// truncate truncates a string to maxLen with ellipsis
+// truncate truncates a string to maxLen terminal cells with ellipsis
 func truncate(s string, maxLen int) string {
-	if len(s) <= maxLen {
-		return s
+	return runewidth.Truncate(s, maxLen, "...")
+}
+
+// FormatOverdueMessages outputs overdue messages in the specified format
+func FormatOverdueMessages(w io.Writer, messages []OverdueMessage, format OutputFormat) error {
+	if format == OutputFormatJSON {
+		data, err := json.MarshalIndent(messages, "", "  ")
+		if err != nil {
+			return fmt.Errorf("unable to marshal JSON: %w", err)
+		}
+		fmt.Fprintln(w, string(data))
+		return nil
+	}
+
+	// Show the account column only when results span accounts
+	withAccount := false
+	for _, msg := range messages {
+		if msg.Account != "" {
+			withAccount = true
+			break
+		}
+	}
+
+	table := tablewriter.NewWriter(w)
+	headers := []any{"ID", "FROM", "SUBJECT", "RECEIVED", "AGE"}
+	if withAccount {
+		headers = append([]any{"ACCOUNT"}, headers...)
+	}
+	table.Header(headers...)
+	for _, msg := range messages {
+		row := []any{
+			msg.ID,
+			truncate(msg.From, 30),
+			truncate(msg.Subject, 40),
+			msg.Received.Local().Format("2006-01-02 15:04"),
+			msg.AgeText,
+		}
+		if withAccount {
+			row = append([]any{msg.Account}, row...)
+		}
+		table.Append(row)
+	}
+	table.Render()
+	return nil
+}
+
+// FormatAccountList outputs account profiles in the specified format
+func FormatAccountList(w io.Writer, accounts []AccountInfo, format OutputFormat) error {
+	if format == OutputFormatJSON {
+		data, err := json.MarshalIndent(accounts, "", "  ")
+		if err != nil {
+			return fmt.Errorf("unable to marshal JSON: %w", err)
+		}
+		fmt.Fprintln(w, string(data))
+		return nil
+	}
+
+	table := tablewriter.NewWriter(w)
+	table.Header("CURRENT", "NAME", "AUTH TYPE", "USER CREDENTIALS")
+	for _, a := range accounts {
+		marker := ""
+		if a.Current {
+			marker = "*"
+		}
+		table.Append(marker, a.Name, string(a.AuthType), a.UserCredentials)
+	}
+	table.Render()
+	return nil
+}
+
+// FormatMarkChanges outputs the messages changed by MarkMessages in the specified format
+func FormatMarkChanges(w io.Writer, changes []MarkChange, format OutputFormat) error {
+	if format == OutputFormatJSON {
+		if changes == nil {
+			changes = []MarkChange{}
+		}
+		return FormatJSON(w, changes)
+	}
+
+	table := tablewriter.NewWriter(w)
+	table.Header("ID", "FROM", "SUBJECT")
+	for _, c := range changes {
+		table.Append(c.ID, truncate(c.From, 30), truncate(c.Subject, 50))
+	}
+	table.Render()
+	return nil
+}
+
+// FormatAssignments outputs message assignments in the specified format
+func FormatAssignments(w io.Writer, assignments []Assignment, format OutputFormat) error {
+	if format == OutputFormatJSON {
+		data, err := json.MarshalIndent(assignments, "", "  ")
+		if err != nil {
+			return fmt.Errorf("unable to marshal JSON: %w", err)
+		}
+		fmt.Fprintln(w, string(data))
+		return nil
+	}
+
+	table := tablewriter.NewWriter(w)
+	table.Header("ID", "LABEL")
+	for _, a := range assignments {
+		table.Append(a.ID, a.Label)
+	}
+	table.Render()
+	return nil
+}
+
+// FormatReport outputs a report in the specified format
+func FormatReport(w io.Writer, report *Report, format OutputFormat) error {
+	return FormatReportCSV(w, report, format, CSVOptions{})
+}
+
+// FormatReportCSV outputs a report like FormatReport, applying csvOpts to CSV
+// and TSV output
+func FormatReportCSV(w io.Writer, report *Report, format OutputFormat, csvOpts CSVOptions) error {
+	switch format {
+	case OutputFormatJSON:
+		data, err := json.MarshalIndent(report, "", "  ")
+		if err != nil {
+			return fmt.Errorf("unable to marshal JSON: %w", err)
+		}
+		fmt.Fprintln(w, string(data))
+		return nil
+	case OutputFormatCSV, OutputFormatTSV:
+		cw, err := NewCSVWriter(w, format, csvOpts)
+		if err != nil {
+			return err
+		}
+		cw.Write([]string{string(report.GroupBy), "count"})
+		for _, g := range report.Groups {
+			cw.Write([]string{g.Key, fmt.Sprint(g.Count)})
+		}
+		cw.Flush()
+		return cw.Error()
+	default:
+		fmt.Fprintf(w, "Report: %s\n", report.Name)
+		fmt.Fprintf(w, "Query: %s\n", report.Query)
+		fmt.Fprintf(w, "Generated: %s\n", report.GeneratedAt.Format("2006-01-02 15:04"))
+		fmt.Fprintf(w, "Total messages: %d\n\n", report.Total)
+
+		table := tablewriter.NewWriter(w)
+		table.Header(strings.ToUpper(string(report.GroupBy)), "COUNT")
+		for _, g := range report.Groups {
+			table.Append(g.Key, g.Count)
+		}
+		table.Render()
+		return nil
+	}
+}
+
+// FormatAuthStatus outputs OAuth credential status in the specified format
+func FormatAuthStatus(w io.Writer, status *AuthStatus, format OutputFormat) error {
+	if format == OutputFormatJSON {
+		data, err := json.MarshalIndent(status, "", "  ")
+		if err != nil {
+			return fmt.Errorf("unable to marshal JSON: %w", err)
+		}
+		fmt.Fprintln(w, string(data))
+		return nil
+	}
+
+	if status.Account != "" {
+		fmt.Fprintf(w, "Account: %s\n", status.Account)
+	}
+	fmt.Fprintf(w, "Email: %s\n", status.Email)
+	fmt.Fprintf(w, "Storage: %s\n", status.Storage)
+	fmt.Fprintf(w, "Valid: %t\n", status.Valid)
+	if status.Error != "" {
+		fmt.Fprintf(w, "Error: %s\n", status.Error)
+	}
+	if !status.Expiry.IsZero() {
+		fmt.Fprintf(w, "Expiry: %s\n", status.Expiry.Local().Format("2006-01-02 15:04:05"))
+	}
+	fmt.Fprintf(w, "Refresh token: %t\n", status.HasRefreshToken)
+	fmt.Fprintln(w, "Scopes:")
+	for _, s := range status.Scopes {
+		fmt.Fprintf(w, "  %s\n", s)
+	}
+	return nil
+}
+
+// FormatDiffSync outputs a two-account comparison in the specified format
+func FormatDiffSync(w io.Writer, result *DiffSyncResult, format OutputFormat) error {
+	if format == OutputFormatJSON {
+		data, err := json.MarshalIndent(result, "", "  ")
+		if err != nil {
+			return fmt.Errorf("unable to marshal JSON: %w", err)
+		}
+		fmt.Fprintln(w, string(data))
+		return nil
+	}
+
+	table := tablewriter.NewWriter(w)
+	table.Header("ONLY IN", "ID", "FROM", "SUBJECT", "DATE", "COPIED")
+	for _, entries := range [][]DiffEntry{result.OnlyInSource, result.OnlyInTarget} {
+		for _, e := range entries {
+			copied := ""
+			if e.Copied {
+				copied = "yes"
+			}
+			table.Append(e.Account, e.ID, truncate(e.From, 30), truncate(e.Subject, 40), e.Date, copied)
+		}
+	}
+	table.Render()
+
+	fmt.Fprintf(w, "Only in %s: %d, only in %s: %d", result.Source, len(result.OnlyInSource), result.Target, len(result.OnlyInTarget))
+	if result.Skipped > 0 {
+		fmt.Fprintf(w, ", skipped without Message-ID: %d", result.Skipped)
+	}
+	fmt.Fprintln(w)
+	return nil
+}
+
+// FormatFilters outputs filters in the specified format
+// The JSON form is the one accepted by ImportFilters
+func FormatFilters(w io.Writer, filters []Filter, format OutputFormat) error {
+	if format == OutputFormatJSON {
+		data, err := json.MarshalIndent(filters, "", "  ")
+		if err != nil {
+			return fmt.Errorf("unable to marshal JSON: %w", err)
+		}
+		fmt.Fprintln(w, string(data))
+		return nil
+	}
+
+	table := tablewriter.NewWriter(w)
+	table.Header("ID", "CRITERIA", "ACTION")
+	for _, f := range filters {
+		table.Append(f.ID, truncate(describeFilterCriteria(f.Criteria), 50), truncate(describeFilterAction(f.Action), 50))
+	}
+	table.Render()
+	return nil
+}
+
+// describeFilterCriteria renders criteria in Gmail search syntax
+func describeFilterCriteria(c FilterCriteria) string {
+	var parts []string
+	if c.From != "" {
+		parts = append(parts, "from:("+c.From+")")
+	}
+	if c.To != "" {
+		parts = append(parts, "to:("+c.To+")")
+	}
+	if c.Subject != "" {
+		parts = append(parts, "subject:("+c.Subject+")")
+	}
+	if c.Query != "" {
+		parts = append(parts, c.Query)
+	}
+	if c.NegatedQuery != "" {
+		parts = append(parts, "-{"+c.NegatedQuery+"}")
+	}
+	if c.HasAttachment {
+		parts = append(parts, "has:attachment")
+	}
+	if c.Size > 0 {
+		parts = append(parts, fmt.Sprintf("size %s %d", c.SizeComparison, c.Size))
+	}
+	return strings.Join(parts, " ")
+}
+
+// describeFilterAction summarizes a filter action
+func describeFilterAction(a FilterAction) string {
+	var parts []string
+	if len(a.AddLabels) > 0 {
+		parts = append(parts, "+"+strings.Join(a.AddLabels, " +"))
+	}
+	if len(a.RemoveLabels) > 0 {
+		parts = append(parts, "-"+strings.Join(a.RemoveLabels, " -"))
+	}
+	if a.Forward != "" {
+		parts = append(parts, "forward to "+a.Forward)
+	}
+	return strings.Join(parts, " ")
+}
+
+// FormatVacation outputs vacation responder settings in the specified format
+func FormatVacation(w io.Writer, vacation *Vacation, format OutputFormat) error {
+	if format == OutputFormatJSON {
+		data, err := json.MarshalIndent(vacation, "", "  ")
+		if err != nil {
+			return fmt.Errorf("unable to marshal JSON: %w", err)
+		}
+		fmt.Fprintln(w, string(data))
+		return nil
+	}
+
+	status := "off"
+	if vacation.Enabled {
+		status = "on"
+	}
+	fmt.Fprintf(w, "Status: %s\n", status)
+	if vacation.Start != nil {
+		fmt.Fprintf(w, "Start: %s\n", vacation.Start.Local().Format("2006-01-02 15:04"))
+	}
+	if vacation.End != nil {
+		fmt.Fprintf(w, "End: %s\n", vacation.End.Local().Format("2006-01-02 15:04"))
+	}
+	if vacation.RestrictToContacts {
+		fmt.Fprintln(w, "Only contacts: yes")
+	}
+	if vacation.RestrictToDomain {
+		fmt.Fprintln(w, "Only domain: yes")
 	}
-	return s[:maxLen-3] + "..."
+	fmt.Fprintf(w, "Subject: %s\n", vacation.Subject)
+	body := vacation.Body
+	if body == "" {
+		body = vacation.BodyHTML
+	}
+	fmt.Fprintf(w, "\n%s\n", body)
+	return nil
+}
+
+// FormatDashboards outputs mailbox overviews in the specified format
+func FormatDashboards(w io.Writer, dashboards []Dashboard, format OutputFormat) error {
+	if format == OutputFormatJSON {
+		data, err := json.MarshalIndent(dashboards, "", "  ")
+		if err != nil {
+			return fmt.Errorf("unable to marshal JSON: %w", err)
+		}
+		fmt.Fprintln(w, string(data))
+		return nil
+	}
+
+	for i, d := range dashboards {
+		if i > 0 {
+			fmt.Fprintln(w)
+		}
+		if d.Account != "" {
+			fmt.Fprintf(w, "[%s]\n", d.Account)
+		}
+
+		width := len("Oldest unread")
+		for _, l := range d.Labels {
+			width = max(width, len(l.Name))
+		}
+		for _, l := range d.Labels {
+			fmt.Fprintf(w, "%-*s  %d unread / %d\n", width, l.Name, l.Unread, l.Total)
+		}
+		fmt.Fprintf(w, "%-*s  %d\n", width, "Today", d.ReceivedToday)
+		if d.OldestUnread != nil {
+			fmt.Fprintf(w, "%-*s  %s (%s)\n", width, "Oldest unread", d.OldestUnreadAge, d.OldestUnread.Local().Format("2006-01-02 15:04"))
+		} else {
+			fmt.Fprintf(w, "%-*s  -\n", width, "Oldest unread")
+		}
+		fmt.Fprintf(w, "%-*s  %d\n", width, "Drafts", d.Drafts)
+	}
+	return nil
+}
+
+// FormatMailSettings outputs account settings in the specified format
+// The JSON form is the one accepted by ReadMailSettings
+func FormatMailSettings(w io.Writer, settings *MailSettings, format OutputFormat) error {
+	if format == OutputFormatJSON {
+		data, err := json.MarshalIndent(settings, "", "  ")
+		if err != nil {
+			return fmt.Errorf("unable to marshal JSON: %w", err)
+		}
+		fmt.Fprintln(w, string(data))
+		return nil
+	}
+
+	if f := settings.Forwarding; f != nil {
+		fmt.Fprintln(w, "Forwarding:")
+		fmt.Fprintf(w, "  Enabled: %t\n", f.Enabled)
+		if f.EmailAddress != "" {
+			fmt.Fprintf(w, "  Address: %s\n", f.EmailAddress)
+			fmt.Fprintf(w, "  Disposition: %s\n", f.Disposition)
+		}
+	}
+	if s := settings.Imap; s != nil {
+		fmt.Fprintln(w, "IMAP:")
+		fmt.Fprintf(w, "  Enabled: %t\n", s.Enabled)
+		fmt.Fprintf(w, "  Auto-expunge: %t\n", s.AutoExpunge)
+		fmt.Fprintf(w, "  Expunge behavior: %s\n", s.ExpungeBehavior)
+		fmt.Fprintf(w, "  Max folder size: %d\n", s.MaxFolderSize)
+	}
+	if s := settings.Pop; s != nil {
+		fmt.Fprintln(w, "POP:")
+		fmt.Fprintf(w, "  Access window: %s\n", s.AccessWindow)
+		fmt.Fprintf(w, "  Disposition: %s\n", s.Disposition)
+	}
+	return nil
+}
+
+// FormatBounces outputs failed deliveries in the specified format
+func FormatBounces(w io.Writer, bounces []Bounce, format OutputFormat) error {
+	switch format {
+	case OutputFormatJSON:
+		if bounces == nil {
+			bounces = []Bounce{}
+		}
+		data, err := json.MarshalIndent(bounces, "", "  ")
+		if err != nil {
+			return fmt.Errorf("unable to marshal JSON: %w", err)
+		}
+		fmt.Fprintln(w, string(data))
+		return nil
+	case OutputFormatNDJSON:
+		enc := NewNDJSONWriter(w)
+		for _, b := range bounces {
+			if err := enc.Write(b); err != nil {
+				return err
+			}
+		}
+		return nil
+	}
+
+	if len(bounces) == 0 {
+		fmt.Fprintln(w, "No bounces found.")
+		return nil
+	}
+
+	// Show the account column only when results span accounts
+	withAccount := false
+	for _, b := range bounces {
+		if b.Account != "" {
+			withAccount = true
+			break
+		}
+	}
+
+	table := tablewriter.NewWriter(w)
+	headers := []any{"DATE", "RECIPIENT", "STATUS", "REASON", "SUBJECT"}
+	if withAccount {
+		headers = append([]any{"ACCOUNT"}, headers...)
+	}
+	table.Header(headers...)
+	for _, b := range bounces {
+		row := []any{
+			FormatMailDate(b.Date, "2006-01-02 15:04", time.Now()),
+			b.Recipient,
+			b.Status,
+			truncate(b.Reason, 50),
+			truncate(b.Subject, 30),
+		}
+		if withAccount {
+			row = append([]any{b.Account}, row...)
+		}
+		table.Append(row)
+	}
+	table.Render()
+	return nil
+}
+
+// templateFuncs are helpers available to --format template
+var templateFuncs = template.FuncMap{
+	"join":     strings.Join,
+	"upper":    strings.ToUpper,
+	"lower":    strings.ToLower,
+	"truncate": func(n int, s string) string { return truncate(s, n) },
+	"json": func(v any) (string, error) {
+		data, err := json.Marshal(v)
+		return string(data), err
+	},
+}
+
+// ParseOutputTemplate parses a Go text/template for --format template
+// Besides the built-in functions, join, upper, lower, truncate and json are available
+func ParseOutputTemplate(text string) (*template.Template, error) {
+	tmpl, err := template.New("output").Funcs(templateFuncs).Option("missingkey=error").Parse(text)
+	if err != nil {
+		return nil, fmt.Errorf("invalid template: %w", err)
+	}
+	return tmpl, nil
+}
+
+// FormatJSON outputs any value as indented JSON, for results without a
+// dedicated formatter
+func FormatJSON(w io.Writer, v any) error {
+	data, err := json.MarshalIndent(v, "", "  ")
+	if err != nil {
+		return fmt.Errorf("unable to marshal JSON: %w", err)
+	}
+	fmt.Fprintln(w, string(data))
+	return nil
+}
+
+// FormatTemplate executes tmpl once per item, ending each output with a
+// newline unless the template already does
+func FormatTemplate[T any](w io.Writer, items []T, tmpl *template.Template) error {
+	var buf bytes.Buffer
+	for _, item := range items {
+		buf.Reset()
+		if err := tmpl.Execute(&buf, item); err != nil {
+			return fmt.Errorf("unable to execute template: %w", err)
+		}
+		if !bytes.HasSuffix(buf.Bytes(), []byte("\n")) {
+			buf.WriteByte('\n')
+		}
+		if _, err := w.Write(buf.Bytes()); err != nil {
+			return err
+		}
+	}
+	return nil
 }
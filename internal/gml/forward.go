@@ -0,0 +1,70 @@
+package gml
+
+import (
+	"bytes"
+	"context"
+	"fmt"
+	"strings"
+
+	"github.com/longkey1/gml/internal/google"
+)
+
+// ForwardOptions controls how PrepareForward builds a forward
+type ForwardOptions struct {
+	// Note is written above the forwarded message
+	Note string
+	// NoAttachments leaves out the attachments of the original message
+	NoAttachments bool
+}
+
+// PrepareForward fetches a message and builds a forward of it, without
+// recipients: a "Fwd: " subject, the note, the original headers and text body
+// below a separator, and the original attachments
+func PrepareForward(ctx context.Context, svc *Service, messageID string, opts ForwardOptions) (*OutgoingMessage, error) {
+	msg, err := svc.API.GetMessage(ctx, messageID, google.GetMessageOptions{Format: "full"})
+	if err != nil {
+		return nil, fmt.Errorf("unable to retrieve message: %w", apiError(err))
+	}
+	p := msg.Payload
+
+	var b strings.Builder
+	if note := strings.TrimRight(opts.Note, "\r\n"); note != "" {
+		b.WriteString(note + "\n\n")
+	}
+	b.WriteString("---------- Forwarded message ---------\n")
+	for _, name := range []string{"From", "Date", "Subject", "To", "Cc"} {
+		if value := headerValue(p, name); value != "" {
+			fmt.Fprintf(&b, "%s: %s\n", name, value)
+		}
+	}
+	b.WriteString("\n" + ExtractBody(p))
+
+	fwd := &OutgoingMessage{
+		Subject: forwardSubject(headerValue(p, "Subject")),
+		Body:    b.String(),
+	}
+	if opts.NoAttachments {
+		return fwd, nil
+	}
+	for _, a := range ListAttachments(p) {
+		var data bytes.Buffer
+		if err := WriteAttachment(ctx, svc, messageID, a, &data); err != nil {
+			return nil, fmt.Errorf("unable to forward %s: %w", a.Filename, err)
+		}
+		fwd.Attachments = append(fwd.Attachments, OutgoingAttachment{
+			Filename: a.Filename,
+			MimeType: a.MimeType,
+			Data:     data.Bytes(),
+		})
+	}
+	return fwd, nil
+}
+
+// forwardSubject prefixes a subject with "Fwd: " unless it already has it
+func forwardSubject(subject string) string {
+	lower := strings.ToLower(subject)
+	if strings.HasPrefix(lower, "fwd:") || strings.HasPrefix(lower, "fw:") {
+		return subject
+	}
+	return "Fwd: " + subject
+}
@@ -0,0 +1,86 @@
+package gml
+
+import (
+	"fmt"
+	"io"
+	"maps"
+	"mime"
+	"net/textproto"
+	"slices"
+	"strings"
+
+	"golang.org/x/text/encoding/htmlindex"
+	"google.golang.org/api/gmail/v1"
+)
+
+// headerDecoder decodes RFC 2047 encoded-words, supporting the charsets known
+// to the WHATWG encoding index (ISO-2022-JP, Shift_JIS, windows-1252, ...)
+var headerDecoder = &mime.WordDecoder{
+	CharsetReader: func(charset string, input io.Reader) (io.Reader, error) {
+		enc, err := htmlindex.Get(charset)
+		if err != nil {
+			return nil, fmt.Errorf("unsupported charset: %s", charset)
+		}
+		return enc.NewDecoder().Reader(input), nil
+	},
+}
+
+// DecodeHeader decodes RFC 2047 encoded-words (=?UTF-8?B?...?=) in a header value
+// Values that can't be decoded are returned unchanged
+func DecodeHeader(value string) string {
+	if !strings.Contains(value, "=?") {
+		return value
+	}
+	decoded, err := headerDecoder.DecodeHeader(value)
+	if err != nil {
+		return value
+	}
+	return decoded
+}
+
+// headerText returns the display value of a header, decoded unless raw is set
+func headerText(value string, raw bool) string {
+	if raw {
+		return value
+	}
+	return DecodeHeader(value)
+}
+
+// MessageHeaders returns the headers of payload keyed by canonical name
+// (Message-Id, List-Unsubscribe), keeping repeated headers such as Received in
+// order. With names, only those headers are kept; values are decoded unless
+// raw is set
+func MessageHeaders(payload *gmail.MessagePart, names []string, raw bool) map[string][]string {
+	if payload == nil {
+		return nil
+	}
+	keep := make(map[string]bool, len(names))
+	for _, name := range names {
+		keep[textproto.CanonicalMIMEHeaderKey(strings.TrimSpace(name))] = true
+	}
+
+	headers := make(map[string][]string)
+	for _, h := range payload.Headers {
+		key := textproto.CanonicalMIMEHeaderKey(h.Name)
+		if len(keep) > 0 && !keep[key] {
+			continue
+		}
+		headers[key] = append(headers[key], headerText(h.Value, raw))
+	}
+	if len(headers) == 0 {
+		return nil
+	}
+	return headers
+}
+
+// FormatHeaders renders headers as "Name: value" lines sorted by name,
+// repeated headers in their original order
+func FormatHeaders(headers map[string][]string) string {
+	var b strings.Builder
+	for _, name := range slices.Sorted(maps.Keys(headers)) {
+		for _, value := range headers[name] {
+			fmt.Fprintf(&b, "%s: %s\n", name, value)
+		}
+	}
+	return strings.TrimSuffix(b.String(), "\n")
+}
@@ -0,0 +1,49 @@
+package gml
+
+import (
+	"bytes"
+	"context"
+	"encoding/json"
+	"fmt"
+	"io"
+	"os"
+	"os/exec"
+	"strings"
+)
+
+// RunMessageHook runs a shell command for a message
+// Message fields are exposed as GML_* environment variables and the message
+// JSON is written to the command's stdin
+func RunMessageHook(ctx context.Context, command string, msg MessageInfo, stdout, stderr io.Writer) error {
+	data, err := json.Marshal(msg)
+	if err != nil {
+		return fmt.Errorf("unable to marshal message: %w", err)
+	}
+
+	c := exec.CommandContext(ctx, "sh", "-c", command)
+	c.Env = append(os.Environ(), MessageEnv(msg)...)
+	c.Stdin = bytes.NewReader(data)
+	c.Stdout = stdout
+	c.Stderr = stderr
+
+	if err := c.Run(); err != nil {
+		return fmt.Errorf("hook failed for message %s: %w", msg.ID, err)
+	}
+	return nil
+}
+
+// MessageEnv returns message fields as GML_* environment variable assignments
+func MessageEnv(msg MessageInfo) []string {
+	return []string{
+		"GML_ACCOUNT=" + msg.Account,
+		"GML_ID=" + msg.ID,
+		"GML_THREAD_ID=" + msg.ThreadID,
+		"GML_URL=" + msg.URL,
+		"GML_FROM=" + msg.From,
+		"GML_TO=" + msg.To,
+		"GML_SUBJECT=" + msg.Subject,
+		"GML_DATE=" + msg.Date,
+		"GML_SNIPPET=" + msg.Snippet,
+		"GML_LABELS=" + strings.Join(msg.Labels, ","),
+	}
+}
@@ -0,0 +1,327 @@
+package gml
+
+import (
+	"fmt"
+	"regexp"
+	"strings"
+
+	"golang.org/x/net/html"
+)
+
+// BodyFormat represents how message bodies are rendered
+type BodyFormat string
+
+const (
+	BodyFormatText     BodyFormat = "text"
+	BodyFormatHTML     BodyFormat = "html"
+	BodyFormatMarkdown BodyFormat = "markdown"
+)
+
+// ParseBodyFormat validates a body format name, defaulting to text
+func ParseBodyFormat(s string) (BodyFormat, error) {
+	switch f := BodyFormat(strings.ToLower(s)); f {
+	case "":
+		return BodyFormatText, nil
+	case BodyFormatText, BodyFormatHTML, BodyFormatMarkdown:
+		return f, nil
+	default:
+		return "", fmt.Errorf("invalid body format: %s (use text, html or markdown)", s)
+	}
+}
+
+// HTMLToText renders HTML as readable plain text: tags are stripped, entities
+// decoded, links shown as "text (url)" and list items prefixed with markers
+func HTMLToText(s string) string {
+	return renderHTML(s, false)
+}
+
+// HTMLToMarkdown renders HTML as Markdown
+func HTMLToMarkdown(s string) string {
+	return renderHTML(s, true)
+}
+
+var excessNewlines = regexp.MustCompile(`\n{3,}`)
+
+func renderHTML(s string, markdown bool) string {
+	doc, err := html.Parse(strings.NewReader(s))
+	if err != nil {
+		return s
+	}
+
+	r := &htmlRenderer{markdown: markdown}
+	r.walk(doc)
+
+	lines := strings.Split(r.buf.String(), "\n")
+	for i, line := range lines {
+		lines[i] = strings.TrimRight(line, " \t")
+	}
+	out := excessNewlines.ReplaceAllString(strings.Join(lines, "\n"), "\n\n")
+	return strings.TrimSpace(out)
+}
+
+// htmlList tracks the state of an open ul/ol element
+type htmlList struct {
+	ordered bool
+	index   int
+}
+
+// htmlRenderer walks a parsed HTML tree and writes text or Markdown
+type htmlRenderer struct {
+	buf      strings.Builder
+	markdown bool
+	lists    []htmlList
+	pre      int
+	// space records a pending collapsed whitespace between words
+	space bool
+}
+
+// skippedElements are never rendered
+var skippedElements = map[string]bool{
+	"head": true, "script": true, "style": true, "title": true, "noscript": true, "template": true,
+}
+
+// blockElements start on a new line
+var blockElements = map[string]bool{
+	"address": true, "article": true, "aside": true, "blockquote": true, "center": true,
+	"div": true, "dl": true, "dt": true, "dd": true, "fieldset": true, "figure": true,
+	"footer": true, "form": true, "header": true, "main": true, "nav": true,
+	"section": true, "table": true, "tbody": true, "thead": true, "tfoot": true, "tr": true,
+}
+
+// paragraphElements are separated from surrounding text by a blank line
+var paragraphElements = map[string]bool{
+	"p": true, "h1": true, "h2": true, "h3": true, "h4": true, "h5": true, "h6": true,
+	"ul": true, "ol": true, "pre": true, "hr": true,
+}
+
+func (r *htmlRenderer) walk(n *html.Node) {
+	switch n.Type {
+	case html.TextNode:
+		r.text(n.Data)
+		return
+	case html.ElementNode:
+		if skippedElements[n.Data] {
+			return
+		}
+		r.element(n)
+		return
+	}
+	r.children(n)
+}
+
+func (r *htmlRenderer) children(n *html.Node) {
+	for c := n.FirstChild; c != nil; c = c.NextSibling {
+		r.walk(c)
+	}
+}
+
+func (r *htmlRenderer) element(n *html.Node) {
+	switch n.Data {
+	case "br":
+		r.newline()
+	case "hr":
+		r.paragraph()
+		if r.markdown {
+			r.write("---")
+		} else {
+			r.write(strings.Repeat("-", 40))
+		}
+		r.paragraph()
+	case "h1", "h2", "h3", "h4", "h5", "h6":
+		r.paragraph()
+		if r.markdown {
+			r.write(strings.Repeat("#", int(n.Data[1]-'0')) + " ")
+		}
+		r.children(n)
+		r.paragraph()
+	case "ul", "ol":
+		if len(r.lists) == 0 {
+			r.paragraph()
+		} else {
+			r.newline()
+		}
+		r.lists = append(r.lists, htmlList{ordered: n.Data == "ol"})
+		r.children(n)
+		r.lists = r.lists[:len(r.lists)-1]
+		if len(r.lists) == 0 {
+			r.paragraph()
+		} else {
+			r.newline()
+		}
+	case "li":
+		r.newline()
+		marker := "- "
+		if len(r.lists) > 0 {
+			l := &r.lists[len(r.lists)-1]
+			l.index++
+			if l.ordered {
+				marker = fmt.Sprintf("%d. ", l.index)
+			}
+			r.write(strings.Repeat("  ", len(r.lists)-1))
+		}
+		r.write(marker)
+		r.children(n)
+		r.newline()
+	case "a":
+		r.link(n)
+	case "img":
+		alt := attr(n, "alt")
+		if r.markdown {
+			r.write(fmt.Sprintf("![%s](%s)", alt, attr(n, "src")))
+		} else if alt != "" {
+			r.text(alt)
+		}
+	case "strong", "b":
+		r.wrap(n, "**")
+	case "em", "i":
+		r.wrap(n, "_")
+	case "code":
+		if r.pre > 0 {
+			r.children(n)
+		} else {
+			r.wrap(n, "`")
+		}
+	case "pre":
+		r.paragraph()
+		if r.markdown {
+			r.write("```\n")
+		}
+		r.pre++
+		r.children(n)
+		r.pre--
+		if r.markdown {
+			r.newline()
+			r.write("```")
+		}
+		r.paragraph()
+	case "blockquote":
+		r.paragraph()
+		start := r.buf.Len()
+		r.children(n)
+		quoted := strings.TrimSpace(r.buf.String()[start:])
+		rest := r.buf.String()[:start]
+		r.buf.Reset()
+		r.buf.WriteString(rest)
+		r.write("> " + strings.ReplaceAll(quoted, "\n", "\n> "))
+		r.paragraph()
+	case "td", "th":
+		if n.PrevSibling != nil {
+			r.write(" | ")
+		}
+		r.children(n)
+	default:
+		switch {
+		case paragraphElements[n.Data]:
+			r.paragraph()
+			r.children(n)
+			r.paragraph()
+		case blockElements[n.Data]:
+			r.newline()
+			r.children(n)
+			r.newline()
+		default:
+			r.children(n)
+		}
+	}
+}
+
+// link renders an anchor as [text](url) in Markdown or "text (url)" in text
+func (r *htmlRenderer) link(n *html.Node) {
+	href := attr(n, "href")
+	if r.markdown && href != "" {
+		r.flushSpace()
+		r.write("[")
+	}
+	start := r.buf.Len()
+	r.children(n)
+	label := strings.TrimSpace(r.buf.String()[start:])
+	if href == "" || strings.HasPrefix(href, "#") {
+		return
+	}
+	if r.markdown {
+		r.write(fmt.Sprintf("](%s)", href))
+		return
+	}
+	if label != href && label != strings.TrimPrefix(href, "mailto:") {
+		r.write(fmt.Sprintf(" (%s)", href))
+	}
+}
+
+// wrap renders children between Markdown emphasis markers
+func (r *htmlRenderer) wrap(n *html.Node, marker string) {
+	if !r.markdown {
+		r.children(n)
+		return
+	}
+	r.flushSpace()
+	r.write(marker)
+	r.children(n)
+	r.write(marker)
+}
+
+// text writes a text node, collapsing whitespace outside pre elements
+func (r *htmlRenderer) text(s string) {
+	if r.pre > 0 {
+		r.write(s)
+		return
+	}
+	if s == "" {
+		return
+	}
+	if isSpace(s[0]) {
+		r.space = true
+	}
+	words := strings.Fields(s)
+	for i, w := range words {
+		if i > 0 {
+			r.space = true
+		}
+		r.flushSpace()
+		r.buf.WriteString(w)
+	}
+	if isSpace(s[len(s)-1]) {
+		r.space = true
+	}
+}
+
+// flushSpace writes a pending space unless at the start of a line
+func (r *htmlRenderer) flushSpace() {
+	if r.space && r.buf.Len() > 0 && !strings.HasSuffix(r.buf.String(), "\n") && !strings.HasSuffix(r.buf.String(), " ") {
+		r.buf.WriteByte(' ')
+	}
+	r.space = false
+}
+
+func (r *htmlRenderer) write(s string) {
+	r.space = false
+	r.buf.WriteString(s)
+}
+
+// newline ends the current line unless already at the start of one
+func (r *htmlRenderer) newline() {
+	r.space = false
+	if r.buf.Len() > 0 && !strings.HasSuffix(r.buf.String(), "\n") {
+		r.buf.WriteByte('\n')
+	}
+}
+
+// paragraph ensures a blank line before the next content
+func (r *htmlRenderer) paragraph() {
+	r.newline()
+	if r.buf.Len() > 0 && !strings.HasSuffix(r.buf.String(), "\n\n") {
+		r.buf.WriteByte('\n')
+	}
+}
+
+func attr(n *html.Node, key string) string {
+	for _, a := range n.Attr {
+		if a.Key == key {
+			return a.Val
+		}
+	}
+	return ""
+}
+
+func isSpace(b byte) bool {
+	return b == ' ' || b == '\t' || b == '\n' || b == '\r' || b == '\f'
+}
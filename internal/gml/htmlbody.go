@@ -0,0 +1,45 @@
+package gml
+
+import (
+	"bytes"
+	"fmt"
+	"strings"
+
+	"github.com/yuin/goldmark"
+	"github.com/yuin/goldmark/extension"
+	goldmarkhtml "github.com/yuin/goldmark/renderer/html"
+)
+
+// markdownRenderer renders GitHub Flavored Markdown. Raw HTML is kept, since
+// the author of the message wrote it, e.g. <img src="cid:logo.png">
+var markdownRenderer = goldmark.New(
+	goldmark.WithExtensions(extension.GFM),
+	goldmark.WithRendererOptions(goldmarkhtml.WithUnsafe()),
+)
+
+// MarkdownToHTML renders a Markdown body as an HTML document for email
+func MarkdownToHTML(s string) (string, error) {
+	var buf bytes.Buffer
+	if err := markdownRenderer.Convert([]byte(s), &buf); err != nil {
+		return "", fmt.Errorf("unable to render markdown: %w", err)
+	}
+	return "<!DOCTYPE html>\n<html>\n<head><meta charset=\"utf-8\"></head>\n<body>\n" + buf.String() + "</body>\n</html>\n", nil
+}
+
+// alternativeBodies returns the plain-text and HTML versions of the body of
+// a message; html is empty for plain-text bodies
+func (m *OutgoingMessage) alternativeBodies() (text, html string, err error) {
+	switch m.BodyFormat {
+	case "", BodyFormatText:
+		return m.Body, "", nil
+	case BodyFormatMarkdown:
+		if html, err = MarkdownToHTML(m.Body); err != nil {
+			return "", "", err
+		}
+	case BodyFormatHTML:
+		html = m.Body
+	default:
+		return "", "", fmt.Errorf("invalid body format: %s (use text, html or markdown)", m.BodyFormat)
+	}
+	return strings.TrimSpace(HTMLToText(html)) + "\n", html, nil
+}
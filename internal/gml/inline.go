@@ -0,0 +1,153 @@
+package gml
+
+import (
+	"bytes"
+	"context"
+	"encoding/base64"
+	"fmt"
+	"log/slog"
+	"mime"
+	"net/url"
+	"os"
+	"path/filepath"
+	"regexp"
+	"slices"
+	"strings"
+
+	"google.golang.org/api/gmail/v1"
+)
+
+// InlineImageOptions selects how cid: references in HTML bodies are resolved
+// The zero value leaves them untouched
+type InlineImageOptions struct {
+	// Embed replaces the references with data: URIs
+	Embed bool
+	// Dir saves the referenced parts into this directory and points the
+	// references at the saved files
+	Dir string
+	// Prefix is prepended to saved file names in the references, e.g. the
+	// directory relative to the HTML file ("message_files/")
+	Prefix string
+}
+
+// enabled reports whether cid: references should be resolved
+func (o InlineImageOptions) enabled() bool {
+	return o.Embed || o.Dir != ""
+}
+
+// cidPattern matches cid: URLs (RFC 2392) in src attributes and CSS url()
+var cidPattern = regexp.MustCompile(`(?i)cid:([^"'\s)>]+)`)
+
+// ResolveInlineImages rewrites the cid: references of an HTML body to the
+// inline parts of msg, either as data: URIs or as files saved into opts.Dir.
+// Each part is downloaded once; references without a matching part are kept
+func ResolveInlineImages(ctx context.Context, svc *Service, msg *gmail.Message, body string, opts InlineImageOptions) (string, error) {
+	if !opts.enabled() || !cidPattern.MatchString(body) {
+		return body, nil
+	}
+
+	parts := contentIDParts(msg.Payload)
+	resolved := map[string]string{}
+	used := map[string]bool{}
+	var firstErr error
+	body = cidPattern.ReplaceAllStringFunc(body, func(ref string) string {
+		if firstErr != nil {
+			return ref
+		}
+		cid := ref[len("cid:"):]
+		if unescaped, err := url.PathUnescape(cid); err == nil {
+			cid = unescaped
+		}
+		if target, ok := resolved[cid]; ok {
+			return target
+		}
+
+		part, ok := parts[cid]
+		if !ok {
+			part, ok = parts[strings.ToLower(cid)]
+		}
+		if !ok {
+			slog.Warn("no inline part for reference", "ref", ref, "id", msg.Id)
+			return ref
+		}
+
+		var buf bytes.Buffer
+		if err := WriteAttachment(ctx, svc, msg.Id, part, &buf); err != nil {
+			firstErr = err
+			return ref
+		}
+
+		var target string
+		if opts.Embed {
+			target = "data:" + part.MimeType + ";base64," + base64.StdEncoding.EncodeToString(buf.Bytes())
+		} else {
+			name, err := saveInlinePart(opts.Dir, part, buf.Bytes(), used)
+			if err != nil {
+				firstErr = err
+				return ref
+			}
+			target = opts.Prefix + url.PathEscape(name)
+		}
+		resolved[cid] = target
+		return target
+	})
+	if firstErr != nil {
+		return "", firstErr
+	}
+	return body, nil
+}
+
+// contentIDParts indexes the parts of payload that carry a Content-ID, by
+// the ID without angle brackets, and also lowercased for lenient lookups
+func contentIDParts(payload *gmail.MessagePart) map[string]AttachmentInfo {
+	parts := map[string]AttachmentInfo{}
+	var walk func(p *gmail.MessagePart)
+	walk = func(p *gmail.MessagePart) {
+		if p == nil {
+			return
+		}
+		if cid := strings.Trim(strings.TrimSpace(headerValue(p, "Content-ID")), "<>"); cid != "" && p.Body != nil {
+			part := AttachmentInfo{
+				PartID:       p.PartId,
+				Filename:     p.Filename,
+				MimeType:     p.MimeType,
+				Size:         p.Body.Size,
+				attachmentID: p.Body.AttachmentId,
+				data:         p.Body.Data,
+			}
+			parts[cid] = part
+			if _, ok := parts[strings.ToLower(cid)]; !ok {
+				parts[strings.ToLower(cid)] = part
+			}
+		}
+		for _, child := range p.Parts {
+			walk(child)
+		}
+	}
+	walk(payload)
+	return parts
+}
+
+// saveInlinePart writes an inline part into dir under its filename (or a name
+// derived from the part ID and MIME type) and returns the name used
+func saveInlinePart(dir string, part AttachmentInfo, data []byte, used map[string]bool) (string, error) {
+	name := part.Filename
+	if name == "" {
+		name = "part-" + part.PartID
+		// The extension list is sorted, which would put .jfif before .jpg
+		if exts, _ := mime.ExtensionsByType(part.MimeType); slices.Contains(exts, ".jpg") {
+			name += ".jpg"
+		} else if len(exts) > 0 {
+			name += exts[0]
+		}
+	}
+	path := uniquePath(filepath.Join(dir, safeFileName(name)), used)
+
+	if err := os.MkdirAll(dir, 0755); err != nil {
+		return "", fmt.Errorf("unable to create image directory: %w", err)
+	}
+	if err := os.WriteFile(path, data, 0644); err != nil {
+		return "", fmt.Errorf("unable to save inline image: %w", err)
+	}
+	return filepath.Base(path), nil
+}
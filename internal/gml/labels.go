@@ -1,50 +1,199 @@
 package gml
 
 import (
+	"context"
 	"fmt"
+	"log/slog"
+	"sort"
 	"strings"
+	"sync"
+	"time"
+
+	"golang.org/x/text/unicode/norm"
+	"google.golang.org/api/gmail/v1"
+	"google.golang.org/api/googleapi"
 )
 
+// labelListFields limits labels.list responses to what the index uses, which
+// keeps the single response small for mailboxes with thousands of labels
+const labelListFields googleapi.Field = "labels(id,name,type,labelListVisibility,messageListVisibility)"
+
+// minRefreshInterval rate-limits refreshes triggered by unknown label IDs
+const minRefreshInterval = time.Minute
+
+// LabelIDPrefix marks a requested label as an exact label ID (id:Label_42),
+// used as is without name matching
+const LabelIDPrefix = "id:"
+
+// dryRunLabelPrefix starts the IDs standing in for labels a dry run didn't create
+const dryRunLabelPrefix = "dry-run:"
+
+// LabelInfo holds the metadata of a label
+type LabelInfo struct {
+	ID   string `json:"id"`
+	Name string `json:"name"`
+	// Type is "system" or "user"
+	Type string `json:"type"`
+	// LabelListVisibility is labelShow, labelShowIfUnread or labelHide
+	LabelListVisibility string `json:"labelListVisibility,omitempty"`
+	// MessageListVisibility is show or hide
+	MessageListVisibility string `json:"messageListVisibility,omitempty"`
+}
+
 // LabelIndex provides fast lookup for label names and IDs
+// It is safe for concurrent use; Refresh swaps in a fresh copy of the labels
 type LabelIndex struct {
-	nameToID map[string]string
-	idToName map[string]string
-	idToID   map[string]string
+	svc *Service
+
+	mu          sync.RWMutex
+	refreshedAt time.Time
+	labels      map[string]LabelInfo
+	nameToID    map[string]string
+	idToName    map[string]string
+	idToID      map[string]string
+	// queryToIDs maps query-style names (see QueryLabelName) to label IDs
+	queryToIDs map[string][]string
 }
 
 // FetchLabelIndex fetches all labels and builds an index for fast lookup
-func FetchLabelIndex(svc *Service) (*LabelIndex, error) {
-	resp, err := svc.Gmail.Users.Labels.List("me").Do()
+func FetchLabelIndex(ctx context.Context, svc *Service) (*LabelIndex, error) {
+	idx := &LabelIndex{svc: svc}
+	if err := idx.Refresh(ctx); err != nil {
+		return nil, err
+	}
+	return idx, nil
+}
+
+// Refresh re-fetches the labels so long-running modes pick up labels created
+// or renamed since the index was built. On error the current labels are kept
+//
+// labels.list has no paging: Gmail returns every label (up to the 10,000 label
+// limit) in a single response, so only the fields the index needs are requested
+func (idx *LabelIndex) Refresh(ctx context.Context) error {
+	labels, err := idx.svc.API.ListLabels(ctx, labelListFields)
 	if err != nil {
-		return nil, fmt.Errorf("unable to list labels: %w", err)
+		return fmt.Errorf("unable to list labels: %w", apiError(err))
+	}
+
+	fresh := &LabelIndex{
+		labels:     make(map[string]LabelInfo, len(labels)),
+		nameToID:   make(map[string]string, len(labels)),
+		idToName:   make(map[string]string, len(labels)),
+		idToID:     make(map[string]string, len(labels)),
+		queryToIDs: make(map[string][]string, len(labels)),
+	}
+	for _, l := range labels {
+		fresh.add(l)
 	}
 
-	nameToID := make(map[string]string)
-	idToName := make(map[string]string)
-	idToID := make(map[string]string)
-	for _, l := range resp.Labels {
-		nameToID[strings.ToLower(l.Name)] = l.Id
-		idToName[strings.ToLower(l.Id)] = l.Name
-		idToID[strings.ToLower(l.Id)] = l.Id
+	idx.mu.Lock()
+	defer idx.mu.Unlock()
+	idx.labels = fresh.labels
+	idx.nameToID = fresh.nameToID
+	idx.idToName = fresh.idToName
+	idx.idToID = fresh.idToID
+	idx.queryToIDs = fresh.queryToIDs
+	idx.refreshedAt = time.Now()
+	slog.Debug("fetched labels", "count", len(labels))
+	return nil
+}
+
+// RefreshIfUnknown refreshes the index when any of the IDs is missing from it,
+// at most once a minute, so labels created while a watcher runs get their names
+func (idx *LabelIndex) RefreshIfUnknown(ctx context.Context, ids []string) error {
+	if idx == nil {
+		return nil
+	}
+
+	idx.mu.RLock()
+	missing := false
+	for _, id := range ids {
+		if _, ok := idx.labels[id]; !ok {
+			missing = true
+			break
+		}
+	}
+	recent := time.Since(idx.refreshedAt) < minRefreshInterval
+	idx.mu.RUnlock()
+
+	if !missing || recent {
+		return nil
+	}
+	return idx.Refresh(ctx)
+}
+
+// add indexes a label; callers must hold the write lock
+func (idx *LabelIndex) add(l *gmail.Label) {
+	idx.labels[l.Id] = LabelInfo{
+		ID:                    l.Id,
+		Name:                  l.Name,
+		Type:                  l.Type,
+		LabelListVisibility:   l.LabelListVisibility,
+		MessageListVisibility: l.MessageListVisibility,
+	}
+	idx.nameToID[labelKey(l.Name)] = l.Id
+	idx.idToName[strings.ToLower(l.Id)] = l.Name
+	idx.idToID[strings.ToLower(l.Id)] = l.Id
+	queryName := QueryLabelName(l.Name)
+	idx.queryToIDs[queryName] = append(idx.queryToIDs[queryName], l.Id)
+}
+
+// Label returns the metadata of a label by ID
+func (idx *LabelIndex) Label(id string) (LabelInfo, bool) {
+	if idx == nil {
+		return LabelInfo{}, false
+	}
+	idx.mu.RLock()
+	defer idx.mu.RUnlock()
+	l, ok := idx.labels[id]
+	return l, ok
+}
+
+// Labels returns the metadata of all labels, system labels first, then by name
+func (idx *LabelIndex) Labels() []LabelInfo {
+	if idx == nil {
+		return nil
+	}
+	idx.mu.RLock()
+	labels := make([]LabelInfo, 0, len(idx.labels))
+	for _, l := range idx.labels {
+		labels = append(labels, l)
 	}
+	idx.mu.RUnlock()
 
-	return &LabelIndex{
-		nameToID: nameToID,
-		idToName: idToName,
-		idToID:   idToID,
-	}, nil
+	sort.Slice(labels, func(i, j int) bool {
+		if labels[i].Type != labels[j].Type {
+			return labels[i].Type == "system"
+		}
+		return labels[i].Name < labels[j].Name
+	})
+	return labels
 }
 
 // ResolveLabelIDs converts label names or IDs to valid label IDs
-// Supports both system labels (INBOX, SENT) and custom labels
+// Supports both system labels (INBOX, SENT) and custom labels; an exact label ID
+// always wins over a name, and names are matched case-insensitively after
+// Unicode normalization. Values with LabelIDPrefix are returned without the
+// prefix and are never matched against names
 func (idx *LabelIndex) ResolveLabelIDs(requested []string) ([]string, error) {
 	if idx == nil {
 		return nil, fmt.Errorf("label index is nil")
 	}
 
+	idx.mu.RLock()
+	defer idx.mu.RUnlock()
+
 	var resolved []string
 	for _, raw := range requested {
-		label := strings.ToLower(strings.TrimSpace(raw))
+		if id, ok := strings.CutPrefix(strings.TrimSpace(raw), LabelIDPrefix); ok && id != "" {
+			resolved = append(resolved, id)
+			continue
+		}
+		if id, ok := idx.idToID[strings.ToLower(strings.TrimSpace(raw))]; ok && id == strings.TrimSpace(raw) {
+			resolved = append(resolved, id)
+			continue
+		}
+		label := labelKey(raw)
 		if id, ok := idx.nameToID[label]; ok {
 			resolved = append(resolved, id)
 			continue
@@ -53,7 +202,52 @@ func (idx *LabelIndex) ResolveLabelIDs(requested []string) ([]string, error) {
 			resolved = append(resolved, id)
 			continue
 		}
-		return nil, fmt.Errorf("label not found: %s", raw)
+		// Accept the form Gmail search uses, e.g. my-project for "My Project"
+		switch ids := idx.queryToIDs[QueryLabelName(label)]; len(ids) {
+		case 1:
+			resolved = append(resolved, ids[0])
+			continue
+		case 0:
+		default:
+			return nil, fmt.Errorf("label %s is ambiguous: matches %s", raw, strings.Join(idx.mapLabelIDsToNames(ids), ", "))
+		}
+		return nil, notFoundError("label not found: %s", raw)
+	}
+
+	return resolved, nil
+}
+
+// EnsureLabelIDs resolves label names or IDs, creating user labels that don't exist yet.
+// Under WithDryRun a label that would be created gets the placeholder ID
+// dryRunLabelPrefix+name, so the requests using it can be reported
+func (idx *LabelIndex) EnsureLabelIDs(ctx context.Context, svc *Service, requested []string) ([]string, error) {
+	if idx == nil {
+		return nil, fmt.Errorf("label index is nil")
+	}
+
+	var resolved []string
+	for _, raw := range requested {
+		name := strings.TrimSpace(raw)
+		if ids, err := idx.ResolveLabelIDs([]string{name}); err == nil {
+			resolved = append(resolved, ids...)
+			continue
+		}
+
+		label, err := svc.API.CreateLabel(ctx, &gmail.Label{
+			Name:                  name,
+			LabelListVisibility:   "labelShow",
+			MessageListVisibility: "show",
+		})
+		if err != nil {
+			return nil, fmt.Errorf("unable to create label %s: %w", name, apiError(err))
+		}
+		if label.Id == "" && IsDryRun(ctx) {
+			label = &gmail.Label{Id: dryRunLabelPrefix + name, Name: name, Type: "user"}
+		}
+		idx.mu.Lock()
+		idx.add(label)
+		idx.mu.Unlock()
+		resolved = append(resolved, label.Id)
 	}
 
 	return resolved, nil
@@ -66,6 +260,13 @@ func (idx *LabelIndex) MapLabelIDsToNames(ids []string) []string {
 		return ids
 	}
 
+	idx.mu.RLock()
+	defer idx.mu.RUnlock()
+	return idx.mapLabelIDsToNames(ids)
+}
+
+// mapLabelIDsToNames is MapLabelIDsToNames for callers holding the lock
+func (idx *LabelIndex) mapLabelIDsToNames(ids []string) []string {
 	var names []string
 	for _, id := range ids {
 		if name, ok := idx.idToName[strings.ToLower(id)]; ok {
@@ -77,17 +278,30 @@ func (idx *LabelIndex) MapLabelIDsToNames(ids []string) []string {
 	return names
 }
 
+// QueryLabelName returns the form of a label name used in Gmail search
+// (label:my-project-q1 for "My Project/Q1"): lowercased, with spaces and
+// slashes replaced by hyphens
+func QueryLabelName(name string) string {
+	return strings.Map(func(r rune) rune {
+		switch r {
+		case ' ', '/', '&':
+			return '-'
+		}
+		return r
+	}, labelKey(name))
+}
+
+// labelKey normalizes a label name for case-insensitive lookup, so composed and
+// decomposed Unicode forms of the same name match
+func labelKey(name string) string {
+	return strings.ToLower(norm.NFC.String(strings.TrimSpace(name)))
+}
+
 // GetUserEmail retrieves the authenticated user's email address
-func GetUserEmail(svc *Service) (string, error) {
-	profile, err := svc.Gmail.Users.GetProfile("me").Do()
+func GetUserEmail(ctx context.Context, svc *Service) (string, error) {
+	profile, err := svc.API.GetProfile(ctx)
 	if err != nil {
-		return "", fmt.Errorf("unable to get user profile: %w", err)
+		return "", fmt.Errorf("unable to get user profile: %w", apiError(err))
 	}
 	return profile.EmailAddress, nil
 }
-
-// BuildMailURL constructs a Gmail web UI URL for a thread
-func BuildMailURL(email, threadID string) string {
-	// Note: url.QueryEscape is not needed here as email addresses don't need escaping
-	return fmt.Sprintf("https://mail.google.com/mail/?authuser=%s#all/%s", email, threadID)
-}
@@ -0,0 +1,142 @@
+package gml
+
+import (
+	"bytes"
+	"context"
+	"errors"
+	"slices"
+	"testing"
+
+	"google.golang.org/api/gmail/v1"
+)
+
+func TestResolveLabelIDs(t *testing.T) {
+	svc, fake := newTestService(t)
+	project := fake.AddLabel("My Project")
+	nested := fake.AddLabel("Clients/Acme")
+	fake.AddLabel("My/Project")
+	unicodeLabel := fake.AddLabel("Équipe/Straße")
+	idx, err := FetchLabelIndex(context.Background(), svc)
+	if err != nil {
+		t.Fatal(err)
+	}
+
+	tests := []struct {
+		name      string
+		requested []string
+		want      []string
+		wantErr   bool
+		notFound  bool
+	}{
+		{name: "system ID", requested: []string{"INBOX"}, want: []string{"INBOX"}},
+		{name: "system name in lowercase", requested: []string{"inbox", "unread"}, want: []string{"INBOX", "UNREAD"}},
+		{name: "user label ID", requested: []string{project}, want: []string{project}},
+		{name: "name ignoring case", requested: []string{" my PROJECT "}, want: []string{project}},
+		{name: "nested name", requested: []string{"clients/acme"}, want: []string{nested}},
+		{name: "query form", requested: []string{"clients-acme"}, want: []string{nested}},
+		{name: "non-ASCII name", requested: []string{"équipe/STRAße"}, want: []string{unicodeLabel}},
+		{name: "decomposed non-ASCII name", requested: []string{"E\u0301quipe/Straße"}, want: []string{unicodeLabel}},
+		{name: "exact ID", requested: []string{"id:" + nested}, want: []string{nested}},
+		{name: "exact ID skips name matching", requested: []string{"id:My Project"}, want: []string{"My Project"}},
+		{name: "ambiguous query form", requested: []string{"my-project"}, wantErr: true},
+		{name: "unknown", requested: []string{"INBOX", "nope"}, wantErr: true, notFound: true},
+		{name: "none", requested: nil, want: nil},
+	}
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			got, err := idx.ResolveLabelIDs(tt.requested)
+			if tt.wantErr {
+				if err == nil || errors.Is(err, ErrNotFound) != tt.notFound {
+					t.Fatalf("ResolveLabelIDs(%q) = %v, %v, want an error (not found: %v)", tt.requested, got, err, tt.notFound)
+				}
+				return
+			}
+			if err != nil {
+				t.Fatal(err)
+			}
+			if !slices.Equal(got, tt.want) {
+				t.Errorf("ResolveLabelIDs(%q) = %v, want %v", tt.requested, got, tt.want)
+			}
+		})
+	}
+}
+
+func TestEnsureLabelIDs(t *testing.T) {
+	svc, fake := newTestService(t)
+	existing := fake.AddLabel("Work")
+	ctx := context.Background()
+	idx, err := FetchLabelIndex(ctx, svc)
+	if err != nil {
+		t.Fatal(err)
+	}
+
+	ids, err := idx.EnsureLabelIDs(ctx, svc, []string{"work", "STARRED", " Receipts/2025 "})
+	if err != nil {
+		t.Fatal(err)
+	}
+	if len(ids) != 3 || ids[0] != existing || ids[1] != "STARRED" || ids[2] == "" {
+		t.Fatalf("EnsureLabelIDs() = %v, want [%s STARRED <new>]", ids, existing)
+	}
+	if label, ok := idx.Label(ids[2]); !ok || label.Name != "Receipts/2025" {
+		t.Errorf("created label = %+v, %v, want Receipts/2025 in the index", label, ok)
+	}
+
+	// The created label is found again, in this index and in a new one
+	again, err := idx.EnsureLabelIDs(ctx, svc, []string{"receipts/2025"})
+	if err != nil || !slices.Equal(again, ids[2:]) {
+		t.Errorf("EnsureLabelIDs() again = %v, %v, want %v", again, err, ids[2:])
+	}
+	fresh, err := FetchLabelIndex(ctx, svc)
+	if err != nil {
+		t.Fatal(err)
+	}
+	if got, err := fresh.ResolveLabelIDs([]string{"Receipts/2025"}); err != nil || !slices.Equal(got, ids[2:]) {
+		t.Errorf("ResolveLabelIDs() after creation = %v, %v, want %v", got, err, ids[2:])
+	}
+
+	fake.Fail = func(method, id string) error {
+		if method == "CreateLabel" {
+			return errors.New("backend error")
+		}
+		return nil
+	}
+	if _, err := idx.EnsureLabelIDs(ctx, svc, []string{"Other"}); err == nil {
+		t.Error("EnsureLabelIDs() with a failing CreateLabel: want an error")
+	}
+}
+
+// dryRunLabelClient answers label creation like the dry-run transport, with
+// an empty label
+type dryRunLabelClient struct {
+	*recordingClient
+	created int
+}
+
+func (c *dryRunLabelClient) CreateLabel(ctx context.Context, label *gmail.Label) (*gmail.Label, error) {
+	c.created++
+	return &gmail.Label{}, nil
+}
+
+func TestEnsureLabelIDsDryRun(t *testing.T) {
+	_, fake := newTestService(t)
+	client := &dryRunLabelClient{recordingClient: fake}
+	svc := NewServiceWithClient(client, "")
+	var out bytes.Buffer
+	ctx := WithDryRun(context.Background(), &out)
+	idx, err := FetchLabelIndex(ctx, svc)
+	if err != nil {
+		t.Fatal(err)
+	}
+
+	ids, err := idx.EnsureLabelIDs(ctx, svc, []string{"INBOX", "New/Label"})
+	if err != nil {
+		t.Fatal(err)
+	}
+	if want := []string{"INBOX", "dry-run:New/Label"}; !slices.Equal(ids, want) {
+		t.Fatalf("EnsureLabelIDs() under a dry run = %q, want %q", ids, want)
+	}
+	again, err := idx.EnsureLabelIDs(ctx, svc, []string{"new/label"})
+	if err != nil || !slices.Equal(again, ids[1:]) || client.created != 1 {
+		t.Errorf("EnsureLabelIDs() again = %q, %v after %d creations, want %q after 1", again, err, client.created, ids[1:])
+	}
+}
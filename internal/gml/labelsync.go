@@ -0,0 +1,244 @@
+package gml
+
+import (
+	"context"
+	"fmt"
+	"sort"
+	"strings"
+
+	"google.golang.org/api/gmail/v1"
+	"gopkg.in/yaml.v3"
+)
+
+// LabelManifest declares the user labels of a mailbox for 'gml label sync'
+type LabelManifest struct {
+	Labels []LabelSpec `yaml:"labels" json:"labels"`
+}
+
+// LabelSpec declares one label. Nested labels are written either as
+// "Parent/Child" names or as children; unset fields are left as they are
+type LabelSpec struct {
+	Name string `yaml:"name" json:"name"`
+	// Color uses Gmail's palette, e.g. {text: "#ffffff", background: "#16a765"}
+	Color *LabelSpecColor `yaml:"color,omitempty" json:"color,omitempty"`
+	// Visibility in the label list: show, showIfUnread or hide
+	LabelListVisibility string `yaml:"labelListVisibility,omitempty" json:"labelListVisibility,omitempty"`
+	// Visibility in the message list: show or hide
+	MessageListVisibility string      `yaml:"messageListVisibility,omitempty" json:"messageListVisibility,omitempty"`
+	Children              []LabelSpec `yaml:"children,omitempty" json:"children,omitempty"`
+}
+
+// LabelSpecColor is a label's text and background color
+type LabelSpecColor struct {
+	Text       string `yaml:"text" json:"text"`
+	Background string `yaml:"background" json:"background"`
+}
+
+// Label sync actions
+const (
+	LabelSyncCreate = "create"
+	LabelSyncUpdate = "update"
+	LabelSyncDelete = "delete"
+)
+
+// LabelSyncChange is one step of a label sync plan
+type LabelSyncChange struct {
+	Action string `json:"action"`
+	Name   string `json:"name"`
+	ID     string `json:"id,omitempty"`
+	// Fields lists what an update changes
+	Fields []string `json:"fields,omitempty"`
+
+	label *gmail.Label
+}
+
+// ParseLabelManifest reads a YAML (or JSON) label manifest
+func ParseLabelManifest(data []byte) (*LabelManifest, error) {
+	var m LabelManifest
+	if err := yaml.Unmarshal(data, &m); err != nil {
+		return nil, fmt.Errorf("unable to parse label manifest: %w", err)
+	}
+	return &m, nil
+}
+
+// flatten returns the manifest's labels by full name, adding parents of
+// nested names that aren't declared themselves
+func (m *LabelManifest) flatten() (map[string]LabelSpec, error) {
+	specs := make(map[string]LabelSpec)
+	var add func(prefix string, list []LabelSpec) error
+	add = func(prefix string, list []LabelSpec) error {
+		for _, s := range list {
+			name := strings.Trim(strings.TrimSpace(s.Name), "/")
+			if name == "" {
+				return fmt.Errorf("label manifest: label without a name")
+			}
+			if prefix != "" {
+				name = prefix + "/" + name
+			}
+			if _, ok := specs[labelKey(name)]; ok {
+				return fmt.Errorf("label manifest: %s is declared twice", name)
+			}
+			s.Name = name
+			specs[labelKey(name)] = s
+			if err := add(name, s.Children); err != nil {
+				return err
+			}
+		}
+		return nil
+	}
+	if err := add("", m.Labels); err != nil {
+		return nil, err
+	}
+
+	for _, s := range specs {
+		parts := strings.Split(s.Name, "/")
+		for i := 1; i < len(parts); i++ {
+			parent := strings.Join(parts[:i], "/")
+			if _, ok := specs[labelKey(parent)]; !ok {
+				specs[labelKey(parent)] = LabelSpec{Name: parent}
+			}
+		}
+	}
+	return specs, nil
+}
+
+// PlanLabelSync compares the manifest with the mailbox's user labels and
+// returns the changes needed to match it: creations (parents first), updates,
+// and with prune, deletions of undeclared labels (children first)
+func PlanLabelSync(ctx context.Context, svc *Service, m *LabelManifest, prune bool) ([]LabelSyncChange, error) {
+	specs, err := m.flatten()
+	if err != nil {
+		return nil, err
+	}
+
+	labels, err := svc.API.ListLabels(ctx)
+	if err != nil {
+		return nil, fmt.Errorf("unable to list labels: %w", apiError(err))
+	}
+	existing := make(map[string]*gmail.Label)
+	for _, l := range labels {
+		if l.Type == "user" {
+			existing[labelKey(l.Name)] = l
+		}
+	}
+
+	var creates, updates, deletes []LabelSyncChange
+	for key, spec := range specs {
+		want, err := spec.label()
+		if err != nil {
+			return nil, err
+		}
+		have, ok := existing[key]
+		if !ok {
+			creates = append(creates, LabelSyncChange{Action: LabelSyncCreate, Name: spec.Name, label: want})
+			continue
+		}
+		if fields := labelDiff(have, want); len(fields) > 0 {
+			want.Id = have.Id
+			updates = append(updates, LabelSyncChange{Action: LabelSyncUpdate, Name: spec.Name, ID: have.Id, Fields: fields, label: want})
+		}
+	}
+	if prune {
+		for key, l := range existing {
+			if _, ok := specs[key]; !ok {
+				deletes = append(deletes, LabelSyncChange{Action: LabelSyncDelete, Name: l.Name, ID: l.Id})
+			}
+		}
+	}
+
+	depth := func(c LabelSyncChange) int { return strings.Count(c.Name, "/") }
+	sort.Slice(creates, func(i, j int) bool {
+		if depth(creates[i]) != depth(creates[j]) {
+			return depth(creates[i]) < depth(creates[j])
+		}
+		return creates[i].Name < creates[j].Name
+	})
+	sort.Slice(updates, func(i, j int) bool { return updates[i].Name < updates[j].Name })
+	sort.Slice(deletes, func(i, j int) bool {
+		if depth(deletes[i]) != depth(deletes[j]) {
+			return depth(deletes[i]) > depth(deletes[j])
+		}
+		return deletes[i].Name < deletes[j].Name
+	})
+
+	return append(append(creates, updates...), deletes...), nil
+}
+
+// ApplyLabelSync carries out a plan from PlanLabelSync in order. Failed
+// changes are recorded in failures (by label name) or stop the sync
+func ApplyLabelSync(ctx context.Context, svc *Service, changes []LabelSyncChange, failures *FailureLog) (int, error) {
+	applied := 0
+	for _, c := range changes {
+		var err error
+		switch c.Action {
+		case LabelSyncCreate:
+			_, err = svc.API.CreateLabel(ctx, c.label)
+		case LabelSyncUpdate:
+			_, err = svc.API.PatchLabel(ctx, c.ID, c.label)
+		case LabelSyncDelete:
+			err = svc.API.DeleteLabel(ctx, c.ID)
+		}
+		if err != nil {
+			if err := failures.Record(fmt.Errorf("unable to %s label %s: %w", c.Action, c.Name, apiError(err)), c.Name); err != nil {
+				return applied, err
+			}
+			continue
+		}
+		applied++
+	}
+	return applied, nil
+}
+
+// label converts a spec to the API representation
+func (s LabelSpec) label() (*gmail.Label, error) {
+	l := &gmail.Label{Name: s.Name}
+	if s.Color != nil {
+		l.Color = &gmail.LabelColor{TextColor: s.Color.Text, BackgroundColor: s.Color.Background}
+	}
+	if s.LabelListVisibility != "" {
+		v, ok := labelListVisibilities[strings.ToLower(s.LabelListVisibility)]
+		if !ok {
+			return nil, fmt.Errorf("label %s: invalid labelListVisibility %q (show, showIfUnread or hide)", s.Name, s.LabelListVisibility)
+		}
+		l.LabelListVisibility = v
+	}
+	if s.MessageListVisibility != "" {
+		v := strings.ToLower(s.MessageListVisibility)
+		if v != "show" && v != "hide" {
+			return nil, fmt.Errorf("label %s: invalid messageListVisibility %q (show or hide)", s.Name, s.MessageListVisibility)
+		}
+		l.MessageListVisibility = v
+	}
+	return l, nil
+}
+
+// labelListVisibilities maps manifest values, short or as in the API, to API values
+var labelListVisibilities = map[string]string{
+	"show":              "labelShow",
+	"showifunread":      "labelShowIfUnread",
+	"hide":              "labelHide",
+	"labelshow":         "labelShow",
+	"labelshowifunread": "labelShowIfUnread",
+	"labelhide":         "labelHide",
+}
+
+// labelDiff lists the fields of want that differ from have; unset fields of
+// want don't count
+func labelDiff(have, want *gmail.Label) []string {
+	var fields []string
+	if have.Name != want.Name {
+		fields = append(fields, "name")
+	}
+	if want.Color != nil && (have.Color == nil ||
+		!strings.EqualFold(have.Color.TextColor, want.Color.TextColor) ||
+		!strings.EqualFold(have.Color.BackgroundColor, want.Color.BackgroundColor)) {
+		fields = append(fields, "color")
+	}
+	if want.LabelListVisibility != "" && want.LabelListVisibility != have.LabelListVisibility {
+		fields = append(fields, "labelListVisibility")
+	}
+	if want.MessageListVisibility != "" && want.MessageListVisibility != have.MessageListVisibility {
+		fields = append(fields, "messageListVisibility")
+	}
+	return fields
+}
@@ -0,0 +1,118 @@
+package gml
+
+import (
+	"context"
+	"fmt"
+	"io"
+	"log/slog"
+	"strings"
+	"sync"
+)
+
+// LogLevel returns the log level for the global --quiet, --verbose and
+// --debug flags: errors only, warnings (the default), info or debug
+func LogLevel(quiet, verbose, debug bool) slog.Level {
+	switch {
+	case debug:
+		return slog.LevelDebug
+	case verbose:
+		return slog.LevelInfo
+	case quiet:
+		return slog.LevelError
+	}
+	return slog.LevelWarn
+}
+
+// logPrefixes label records the way gml has always printed diagnostics
+var logPrefixes = map[slog.Level]string{
+	slog.LevelDebug: "Debug",
+	slog.LevelInfo:  "Info",
+	slog.LevelWarn:  "Warning",
+	slog.LevelError: "Error",
+}
+
+// LogHandler writes records as "Warning: message: err key=value" lines, so
+// log output reads like the rest of the CLI's stderr
+type LogHandler struct {
+	level slog.Leveler
+	attrs []slog.Attr
+	group string
+
+	mu *sync.Mutex
+	w  io.Writer
+}
+
+// NewLogHandler returns a handler writing records at or above level to w
+func NewLogHandler(w io.Writer, level slog.Leveler) *LogHandler {
+	return &LogHandler{level: level, mu: &sync.Mutex{}, w: w}
+}
+
+// Enabled reports whether records at level are written
+func (h *LogHandler) Enabled(_ context.Context, level slog.Level) bool {
+	return level >= h.level.Level()
+}
+
+// Handle writes a record as a single line
+func (h *LogHandler) Handle(_ context.Context, r slog.Record) error {
+	var errText string
+	var attrs strings.Builder
+	write := func(a slog.Attr) {
+		a.Value = a.Value.Resolve()
+		if a.Equal(slog.Attr{}) {
+			return
+		}
+		// An "err" attribute keeps the familiar "message: error" shape
+		if a.Key == "err" && h.group == "" {
+			errText = a.Value.String()
+			return
+		}
+		key := a.Key
+		if h.group != "" {
+			key = h.group + "." + key
+		}
+		value := a.Value.String()
+		if strings.ContainsAny(value, " \t\"=") {
+			value = fmt.Sprintf("%q", value)
+		}
+		fmt.Fprintf(&attrs, " %s=%s", key, value)
+	}
+	for _, a := range h.attrs {
+		write(a)
+	}
+	r.Attrs(func(a slog.Attr) bool {
+		write(a)
+		return true
+	})
+
+	prefix, ok := logPrefixes[r.Level]
+	if !ok {
+		prefix = r.Level.String()
+	}
+	line := prefix + ": " + r.Message
+	if errText != "" {
+		line += ": " + errText
+	}
+	line += attrs.String() + "\n"
+
+	h.mu.Lock()
+	defer h.mu.Unlock()
+	_, err := io.WriteString(h.w, line)
+	return err
+}
+
+// WithAttrs returns a handler that adds attrs to every record
+func (h *LogHandler) WithAttrs(attrs []slog.Attr) slog.Handler {
+	h2 := *h
+	h2.attrs = append(append([]slog.Attr{}, h.attrs...), attrs...)
+	return &h2
+}
+
+// WithGroup returns a handler that qualifies attribute keys with name
+func (h *LogHandler) WithGroup(name string) slog.Handler {
+	h2 := *h
+	if h.group != "" {
+		name = h.group + "." + name
+	}
+	h2.group = name
+	return &h2
+}
@@ -0,0 +1,348 @@
+package gml
+
+import (
+	"bytes"
+	"errors"
+	"fmt"
+	"io"
+	"os"
+	"path/filepath"
+	"sort"
+	"strings"
+	"text/template"
+
+	"github.com/olekukonko/tablewriter"
+)
+
+// templateExt is the file extension of templates in a TemplateStore
+const templateExt = ".tmpl"
+
+// TemplateStore holds the message templates added with 'gml template add', one
+// file per template in a directory next to the config file
+type TemplateStore struct {
+	dir string
+}
+
+// NewTemplateStore returns the store in dir
+func NewTemplateStore(dir string) *TemplateStore {
+	return &TemplateStore{dir: dir}
+}
+
+// TemplateName normalizes a template name; names are case-insensitive and
+// can't contain path separators
+func TemplateName(name string) (string, error) {
+	name = strings.ToLower(strings.TrimSpace(name))
+	if name == "" || name == "." || name == ".." || strings.ContainsAny(name, `/\`) {
+		return "", fmt.Errorf("invalid template name %q", name)
+	}
+	return name, nil
+}
+
+// path returns the file of a template
+func (s *TemplateStore) path(name string) string {
+	return filepath.Join(s.dir, name+templateExt)
+}
+
+// Load returns the text of a stored template
+func (s *TemplateStore) Load(name string) (string, error) {
+	data, err := os.ReadFile(s.path(name))
+	if errors.Is(err, os.ErrNotExist) {
+		return "", notFoundError("template not found: %s", name)
+	}
+	if err != nil {
+		return "", fmt.Errorf("unable to read template: %w", err)
+	}
+	return string(data), nil
+}
+
+// Names returns the names of the stored templates, sorted
+func (s *TemplateStore) Names() ([]string, error) {
+	entries, err := os.ReadDir(s.dir)
+	if errors.Is(err, os.ErrNotExist) {
+		return nil, nil
+	}
+	if err != nil {
+		return nil, fmt.Errorf("unable to read templates: %w", err)
+	}
+	var names []string
+	for _, e := range entries {
+		if name, ok := strings.CutSuffix(e.Name(), templateExt); ok && !e.IsDir() {
+			names = append(names, name)
+		}
+	}
+	sort.Strings(names)
+	return names, nil
+}
+
+// Save stores a template under name, replacing one with the same name. The
+// text must parse with ParseMailTemplate
+func (s *TemplateStore) Save(name, text string) error {
+	if _, err := ParseMailTemplate(text); err != nil {
+		return err
+	}
+	if err := os.MkdirAll(s.dir, 0700); err != nil {
+		return fmt.Errorf("unable to create template directory: %w", err)
+	}
+	if err := os.WriteFile(s.path(name), []byte(text), 0600); err != nil {
+		return fmt.Errorf("unable to write template: %w", err)
+	}
+	return nil
+}
+
+// Delete removes a stored template
+func (s *TemplateStore) Delete(name string) error {
+	err := os.Remove(s.path(name))
+	if errors.Is(err, os.ErrNotExist) {
+		return notFoundError("template not found: %s", name)
+	}
+	if err != nil {
+		return fmt.Errorf("unable to delete template: %w", err)
+	}
+	return nil
+}
+
+// ParseMailTemplate parses a template file: an optional front matter block of
+// "key: value" lines between two --- lines, with subject, to, cc and bcc
+// (comma-separated) and the body format, followed by the body. It checks that
+// every part is a valid Go template
+func ParseMailTemplate(text string) (MailTemplate, error) {
+	text = strings.ReplaceAll(text, "\r\n", "\n")
+	t := MailTemplate{Body: text}
+	var err error
+	if rest, ok := strings.CutPrefix(text, "---\n"); ok {
+		front, body, ok := strings.Cut(rest, "\n---\n")
+		if !ok {
+			if front, ok = strings.CutSuffix(rest, "\n---"); !ok {
+				return MailTemplate{}, fmt.Errorf("front matter is not closed with a --- line")
+			}
+		}
+		t.Body = body
+		for _, line := range strings.Split(front, "\n") {
+			if strings.TrimSpace(line) == "" || strings.HasPrefix(strings.TrimSpace(line), "#") {
+				continue
+			}
+			key, value, ok := strings.Cut(line, ":")
+			if !ok {
+				return MailTemplate{}, fmt.Errorf("invalid front matter line %q (expected key: value)", line)
+			}
+			value = strings.TrimSpace(value)
+			switch strings.ToLower(strings.TrimSpace(key)) {
+			case "subject":
+				t.Subject = value
+			case "to":
+				t.To = SplitAddressList(value)
+			case "cc":
+				t.Cc = SplitAddressList(value)
+			case "bcc":
+				t.Bcc = SplitAddressList(value)
+			case "format":
+				if t.Format, err = ParseBodyFormat(value); err != nil {
+					return MailTemplate{}, err
+				}
+			default:
+				return MailTemplate{}, fmt.Errorf("unknown front matter key %q (use subject, to, cc, bcc and format)", strings.TrimSpace(key))
+			}
+		}
+	}
+	if _, err = t.parse(); err != nil {
+		return MailTemplate{}, err
+	}
+	return t, nil
+}
+
+// FormatMailTemplate renders a template in the file format ParseMailTemplate
+// reads, with front matter for the subject and recipients
+func FormatMailTemplate(t MailTemplate) string {
+	var b strings.Builder
+	if t.Subject != "" || t.Format != "" || len(t.To)+len(t.Cc)+len(t.Bcc) > 0 {
+		b.WriteString("---\n")
+		if t.Format != "" {
+			fmt.Fprintf(&b, "format: %s\n", t.Format)
+		}
+		if t.Subject != "" {
+			fmt.Fprintf(&b, "subject: %s\n", t.Subject)
+		}
+		for _, h := range []struct {
+			key   string
+			addrs []string
+		}{{"to", t.To}, {"cc", t.Cc}, {"bcc", t.Bcc}} {
+			if len(h.addrs) > 0 {
+				fmt.Fprintf(&b, "%s: %s\n", h.key, strings.Join(h.addrs, ", "))
+			}
+		}
+		b.WriteString("---\n")
+	}
+	b.WriteString(t.Body)
+	return b.String()
+}
+
+// mailTemplateParts are the parsed templates of a MailTemplate
+type mailTemplateParts struct {
+	subject, body *template.Template
+	to, cc, bcc   []*template.Template
+}
+
+// parse parses every part of the template
+func (t MailTemplate) parse() (*mailTemplateParts, error) {
+	parse := func(name, text string) (*template.Template, error) {
+		tmpl, err := template.New(name).Option("missingkey=error").Parse(text)
+		if err != nil {
+			return nil, fmt.Errorf("invalid %s template: %w", name, err)
+		}
+		return tmpl, nil
+	}
+	parseAll := func(name string, texts []string) ([]*template.Template, error) {
+		var tmpls []*template.Template
+		for _, text := range texts {
+			tmpl, err := parse(name, text)
+			if err != nil {
+				return nil, err
+			}
+			tmpls = append(tmpls, tmpl)
+		}
+		return tmpls, nil
+	}
+
+	p := &mailTemplateParts{}
+	var err error
+	if p.subject, err = parse("subject", t.Subject); err != nil {
+		return nil, err
+	}
+	if p.body, err = parse("body", t.Body); err != nil {
+		return nil, err
+	}
+	if p.to, err = parseAll("to", t.To); err != nil {
+		return nil, err
+	}
+	if p.cc, err = parseAll("cc", t.Cc); err != nil {
+		return nil, err
+	}
+	if p.bcc, err = parseAll("bcc", t.Bcc); err != nil {
+		return nil, err
+	}
+	return p, nil
+}
+
+// Render renders the template with vars into a message. A variable the
+// template uses but vars doesn't have is an error
+func (t MailTemplate) Render(vars map[string]string) (*OutgoingMessage, error) {
+	p, err := t.parse()
+	if err != nil {
+		return nil, err
+	}
+	render := func(tmpl *template.Template) (string, error) {
+		var buf bytes.Buffer
+		if err := tmpl.Execute(&buf, vars); err != nil {
+			return "", fmt.Errorf("unable to render %s: %w", tmpl.Name(), err)
+		}
+		return buf.String(), nil
+	}
+	renderAll := func(tmpls []*template.Template) ([]string, error) {
+		var values []string
+		for _, tmpl := range tmpls {
+			value, err := render(tmpl)
+			if err != nil {
+				return nil, err
+			}
+			values = append(values, SplitAddressList(value)...)
+		}
+		return values, nil
+	}
+
+	msg := &OutgoingMessage{BodyFormat: t.Format}
+	if msg.Subject, err = render(p.subject); err != nil {
+		return nil, err
+	}
+	if msg.Body, err = render(p.body); err != nil {
+		return nil, err
+	}
+	if msg.To, err = renderAll(p.to); err != nil {
+		return nil, err
+	}
+	if msg.Cc, err = renderAll(p.cc); err != nil {
+		return nil, err
+	}
+	if msg.Bcc, err = renderAll(p.bcc); err != nil {
+		return nil, err
+	}
+	return msg, nil
+}
+
+// ParseTemplateVars parses name=value pairs, as given to --var
+func ParseTemplateVars(pairs []string) (map[string]string, error) {
+	vars := make(map[string]string, len(pairs))
+	for _, pair := range pairs {
+		name, value, ok := strings.Cut(pair, "=")
+		if !ok || strings.TrimSpace(name) == "" {
+			return nil, fmt.Errorf("invalid variable %q (expected name=value)", pair)
+		}
+		vars[strings.TrimSpace(name)] = value
+	}
+	return vars, nil
+}
+
+// Sources of message templates
+const (
+	TemplateSourceConfig = "config"
+	TemplateSourceSaved  = "saved"
+)
+
+// MailTemplateInfo describes a message template for output
+type MailTemplateInfo struct {
+	Name    string `json:"name"`
+	Subject string `json:"subject,omitempty"`
+	// Source is "config" for [templates.<name>] in the config file, "saved"
+	// for templates added with 'gml template add'
+	Source string `json:"source"`
+}
+
+// ListMailTemplates merges the templates of the config file and the store,
+// sorted by name. Stored templates that don't parse are listed without a
+// subject
+func ListMailTemplates(cfg *Config, store *TemplateStore) ([]MailTemplateInfo, error) {
+	names, err := store.Names()
+	if err != nil {
+		return nil, err
+	}
+
+	var templates []MailTemplateInfo
+	for name, t := range cfg.Templates {
+		templates = append(templates, MailTemplateInfo{Name: strings.ToLower(name), Subject: t.Subject, Source: TemplateSourceConfig})
+	}
+	for _, name := range names {
+		if _, ok := cfg.Templates[name]; ok {
+			continue
+		}
+		info := MailTemplateInfo{Name: name, Source: TemplateSourceSaved}
+		if text, err := store.Load(name); err == nil {
+			if t, err := ParseMailTemplate(text); err == nil {
+				info.Subject = t.Subject
+			}
+		}
+		templates = append(templates, info)
+	}
+	sort.Slice(templates, func(i, j int) bool { return templates[i].Name < templates[j].Name })
+	return templates, nil
+}
+
+// FormatMailTemplates outputs message templates in the specified format
+func FormatMailTemplates(w io.Writer, templates []MailTemplateInfo, format OutputFormat) error {
+	if format == OutputFormatJSON {
+		if templates == nil {
+			templates = []MailTemplateInfo{}
+		}
+		return FormatJSON(w, templates)
+	}
+	if len(templates) == 0 {
+		fmt.Fprintln(w, "No templates.")
+		return nil
+	}
+
+	table := tablewriter.NewWriter(w)
+	table.Header("NAME", "SUBJECT", "SOURCE")
+	for _, t := range templates {
+		table.Append(t.Name, t.Subject, t.Source)
+	}
+	table.Render()
+	return nil
+}
@@ -0,0 +1,383 @@
+package gml
+
+import (
+	"context"
+	"errors"
+	"fmt"
+	"io"
+	"os"
+	"slices"
+	"time"
+
+	"github.com/olekukonko/tablewriter"
+)
+
+// MaintainTaskType identifies a built-in maintenance task
+type MaintainTaskType string
+
+const (
+	// MaintainRetention trashes or deletes the messages matching a query
+	MaintainRetention MaintainTaskType = "retention"
+	// MaintainLabelSync applies a label manifest, like 'gml label sync'
+	MaintainLabelSync MaintainTaskType = "label_sync"
+	// MaintainBackup exports new messages as .eml files, like 'gml export tree'
+	MaintainBackup MaintainTaskType = "backup"
+	// MaintainCache fetches uncached metadata into the 'gml stats' cache
+	MaintainCache MaintainTaskType = "cache"
+)
+
+// MaintainTask is one [[maintenance]] task in config
+// Every task picks up where an interrupted run left it: retention finds only
+// the messages still matching, label sync plans the remaining changes, backup
+// skips exported files and the cache keeps what was fetched
+type MaintainTask struct {
+	// Name identifies the task in the checkpoint; defaults to the type
+	Name string           `mapstructure:"name"`
+	Type MaintainTaskType `mapstructure:"type"`
+
+	// retention, backup and cache
+	Query  string   `mapstructure:"query"`
+	Labels []string `mapstructure:"labels"`
+
+	// retention: "trash" (default) or "delete" to delete permanently
+	Action string `mapstructure:"action"`
+
+	// label_sync
+	Manifest string `mapstructure:"manifest"`
+	Prune    bool   `mapstructure:"prune"`
+
+	// backup
+	Dir  string `mapstructure:"dir"`
+	Copy bool   `mapstructure:"copy"`
+}
+
+// MaintainStatus is the outcome of a task in a maintenance run
+type MaintainStatus string
+
+const (
+	// MaintainDone means the task finished
+	MaintainDone MaintainStatus = "done"
+	// MaintainStopped means the budget ran out during the task; the next run resumes it
+	MaintainStopped MaintainStatus = "stopped"
+	// MaintainFailed means the task returned an error; the run went on with the next task
+	MaintainFailed MaintainStatus = "failed"
+	// MaintainPending means the budget ran out before the task started
+	MaintainPending MaintainStatus = "pending"
+)
+
+// MaintainResult is the outcome of one task in a maintenance run
+type MaintainResult struct {
+	Name   string           `json:"name"`
+	Type   MaintainTaskType `json:"type"`
+	Status MaintainStatus   `json:"status"`
+	// Summary describes what the task did, e.g. "trashed 120 messages"
+	Summary  string        `json:"summary,omitempty"`
+	Error    string        `json:"error,omitempty"`
+	Requests int           `json:"requests"`
+	Duration time.Duration `json:"duration"`
+}
+
+// MaintainState is the checkpoint kept between maintenance runs
+type MaintainState struct {
+	// Next is the task the next run starts with; empty starts with the first
+	Next  string                       `json:"next,omitempty"`
+	Tasks map[string]MaintainTaskState `json:"tasks,omitempty"`
+}
+
+// MaintainTaskState records when a task last ran and last finished
+type MaintainTaskState struct {
+	LastRun  time.Time `json:"lastRun,omitzero"`
+	Finished time.Time `json:"finished,omitzero"`
+}
+
+// MaintainOptions contains options for a maintenance run
+type MaintainOptions struct {
+	// StatePath is the checkpoint file, saved after every task; empty runs
+	// without a checkpoint
+	StatePath string
+	// Progress, if set, is called with the task name as a task makes progress
+	Progress func(task string, done, total int)
+}
+
+// MaintainTaskName returns the name of a task, defaulting to its type
+func MaintainTaskName(t MaintainTask) string {
+	if t.Name != "" {
+		return t.Name
+	}
+	return string(t.Type)
+}
+
+// ValidateMaintainTasks checks task types, required settings and that task
+// names are unique
+func ValidateMaintainTasks(tasks []MaintainTask) error {
+	seen := make(map[string]bool)
+	for i, t := range tasks {
+		name := MaintainTaskName(t)
+		if name == "" {
+			return fmt.Errorf("maintenance task %d: type is required", i+1)
+		}
+		if seen[name] {
+			return fmt.Errorf("maintenance task %s: duplicate name (set name to tell them apart)", name)
+		}
+		seen[name] = true
+
+		switch t.Type {
+		case MaintainRetention:
+			if t.Query == "" && len(t.Labels) == 0 {
+				return fmt.Errorf("maintenance task %s: query or labels is required", name)
+			}
+			if t.Action != "" && t.Action != "trash" && t.Action != "delete" {
+				return fmt.Errorf("maintenance task %s: unknown action %q (trash or delete)", name, t.Action)
+			}
+		case MaintainLabelSync:
+			if t.Manifest == "" {
+				return fmt.Errorf("maintenance task %s: manifest is required", name)
+			}
+		case MaintainBackup:
+			if t.Dir == "" {
+				return fmt.Errorf("maintenance task %s: dir is required", name)
+			}
+		case MaintainCache:
+		default:
+			return fmt.Errorf("maintenance task %s: unknown type %q", name, t.Type)
+		}
+	}
+	return nil
+}
+
+// LoadMaintainState reads the checkpoint at path; a missing file is an empty state
+func LoadMaintainState(path string) (*MaintainState, error) {
+	state := &MaintainState{}
+	if path != "" {
+		if _, err := LoadState(path, state); err != nil {
+			return nil, err
+		}
+	}
+	if state.Tasks == nil {
+		state.Tasks = make(map[string]MaintainTaskState)
+	}
+	return state, nil
+}
+
+// Maintain runs the tasks in order, starting with the one the previous run
+// was stopped in, until all are done or the budget of ctx runs out: its
+// deadline, or the request budget of WithRequestBudget. A task cut short by
+// the budget is recorded in the checkpoint and resumed by the next run; a
+// failing task is reported and skipped
+func Maintain(ctx context.Context, svc *Service, tasks []MaintainTask, opts MaintainOptions) ([]MaintainResult, error) {
+	if err := ValidateMaintainTasks(tasks); err != nil {
+		return nil, err
+	}
+	state, err := LoadMaintainState(opts.StatePath)
+	if err != nil {
+		return nil, err
+	}
+
+	start := slices.IndexFunc(tasks, func(t MaintainTask) bool { return MaintainTaskName(t) == state.Next })
+	if start < 0 {
+		start = 0
+	}
+
+	var results []MaintainResult
+	exhausted := false
+	for i, t := range tasks[start:] {
+		name := MaintainTaskName(t)
+		result := MaintainResult{Name: name, Type: t.Type, Status: MaintainPending}
+		if exhausted || budgetExhausted(ctx, nil) {
+			exhausted = true
+			results = append(results, result)
+			continue
+		}
+
+		ts := state.Tasks[name]
+		ts.LastRun = time.Now()
+		before := RequestsUsed(ctx)
+		var progress func(done, total int)
+		if opts.Progress != nil {
+			progress = func(done, total int) { opts.Progress(name, done, total) }
+		}
+
+		summary, err := runMaintainTask(ctx, svc, t, progress)
+		result.Summary = summary
+		result.Requests = RequestsUsed(ctx) - before
+		result.Duration = time.Since(ts.LastRun).Round(time.Millisecond)
+		switch {
+		case err == nil:
+			result.Status = MaintainDone
+			ts.Finished = time.Now()
+		case budgetExhausted(ctx, err):
+			result.Status = MaintainStopped
+			exhausted = true
+			state.Next = name
+		default:
+			result.Status = MaintainFailed
+			result.Error = err.Error()
+		}
+		results = append(results, result)
+
+		state.Tasks[name] = ts
+		if !exhausted {
+			// A run killed during the following task resumes with it
+			state.Next = ""
+			if next := start + i + 1; next < len(tasks) {
+				state.Next = MaintainTaskName(tasks[next])
+			}
+		}
+		if opts.StatePath != "" && !IsDryRun(ctx) {
+			if err := SaveState(opts.StatePath, state); err != nil {
+				return results, err
+			}
+		}
+	}
+	return results, nil
+}
+
+// budgetExhausted reports whether err, or ctx itself, shows that the time or
+// request budget of a maintenance run is used up
+func budgetExhausted(ctx context.Context, err error) bool {
+	if ctx.Err() != nil {
+		return true
+	}
+	return errors.Is(err, ErrRequestBudget) || errors.Is(err, context.DeadlineExceeded)
+}
+
+// runMaintainTask runs one task and summarizes what it did, also when it was
+// interrupted
+func runMaintainTask(ctx context.Context, svc *Service, t MaintainTask, progress func(done, total int)) (string, error) {
+	switch t.Type {
+	case MaintainRetention:
+		return runRetention(ctx, svc, t, progress)
+
+	case MaintainLabelSync:
+		path, err := ExpandPath(t.Manifest)
+		if err != nil {
+			return "", err
+		}
+		data, err := os.ReadFile(path)
+		if err != nil {
+			return "", fmt.Errorf("unable to read manifest: %w", err)
+		}
+		manifest, err := ParseLabelManifest(data)
+		if err != nil {
+			return "", err
+		}
+		changes, err := PlanLabelSync(ctx, svc, manifest, t.Prune)
+		if err != nil {
+			return "", err
+		}
+		applied, err := ApplyLabelSync(ctx, svc, changes, nil)
+		return fmt.Sprintf("applied %d of %d label changes", applied, len(changes)), err
+
+	case MaintainBackup:
+		dir, err := ExpandPath(t.Dir)
+		if err != nil {
+			return "", err
+		}
+		result, err := ExportTree(ctx, svc, TreeExportOptions{
+			Query:    t.Query,
+			LabelIDs: t.Labels,
+			Dir:      dir,
+			Copy:     t.Copy,
+			Progress: progress,
+		})
+		if result == nil {
+			return "", err
+		}
+		return fmt.Sprintf("exported %d messages (%d written, %d linked, %d skipped)",
+			result.Messages, result.Written, result.Linked, result.Skipped), err
+
+	case MaintainCache:
+		path, err := StatePath(StatsCacheName, svc.Account)
+		if err != nil {
+			return "", err
+		}
+		cache, err := LoadMetadataCache(path)
+		if err != nil {
+			return "", err
+		}
+		fetched, err := RefreshMetadataCache(ctx, svc, cache, StatsOptions{Query: t.Query, LabelIDs: t.Labels, Progress: progress})
+		if saveErr := cache.Save(); saveErr != nil {
+			return fmt.Sprintf("cached %d messages", fetched), saveErr
+		}
+		return fmt.Sprintf("cached %d messages", fetched), err
+	}
+	return "", fmt.Errorf("unknown maintenance task type %q", t.Type)
+}
+
+// runRetention trashes the messages matching a retention task one by one,
+// so an interrupted run has trashed a prefix, or deletes them permanently in
+// batches
+func runRetention(ctx context.Context, svc *Service, t MaintainTask, progress func(done, total int)) (string, error) {
+	ids, err := FindPurgeMessages(ctx, svc, PurgeOptions{Query: t.Query, LabelIDs: t.Labels})
+	if err != nil {
+		return "", err
+	}
+
+	if t.Action == "delete" {
+		deleted, err := PurgeMessages(ctx, svc, ids, nil, progress)
+		return fmt.Sprintf("deleted %d of %d messages", deleted, len(ids)), err
+	}
+
+	trashed := 0
+	for _, id := range ids {
+		if err := TrashMessage(ctx, svc, id); err != nil {
+			return fmt.Sprintf("trashed %d of %d messages", trashed, len(ids)), err
+		}
+		trashed++
+		if progress != nil {
+			progress(trashed, len(ids))
+		}
+	}
+	return fmt.Sprintf("trashed %d messages", trashed), nil
+}
+
+// FormatMaintainResults outputs the results of a maintenance run
+func FormatMaintainResults(w io.Writer, results []MaintainResult, format OutputFormat) error {
+	if format == OutputFormatJSON {
+		if results == nil {
+			results = []MaintainResult{}
+		}
+		return FormatJSON(w, results)
+	}
+
+	table := tablewriter.NewWriter(w)
+	table.Header("TASK", "TYPE", "STATUS", "REQUESTS", "DURATION", "SUMMARY")
+	for _, r := range results {
+		summary := r.Summary
+		if r.Error != "" {
+			summary = r.Error
+		}
+		table.Append(r.Name, string(r.Type), string(r.Status), fmt.Sprint(r.Requests), r.Duration.String(), summary)
+	}
+	table.Render()
+	return nil
+}
+
+// FormatMaintainState outputs the configured tasks with their checkpoint
+func FormatMaintainState(w io.Writer, tasks []MaintainTask, state *MaintainState, format OutputFormat) error {
+	if format == OutputFormatJSON {
+		return FormatJSON(w, state)
+	}
+
+	table := tablewriter.NewWriter(w)
+	table.Header("TASK", "TYPE", "LAST RUN", "FINISHED", "NEXT")
+	for i, t := range tasks {
+		name := MaintainTaskName(t)
+		ts := state.Tasks[name]
+		next := ""
+		if name == state.Next || (state.Next == "" && i == 0) {
+			next = "*"
+		}
+		table.Append(name, string(t.Type), formatMaintainTime(ts.LastRun), formatMaintainTime(ts.Finished), next)
+	}
+	table.Render()
+	return nil
+}
+
+// formatMaintainTime formats a checkpoint time, empty when unset
+func formatMaintainTime(t time.Time) string {
+	if t.IsZero() {
+		return ""
+	}
+	return t.Local().Format("2006-01-02 15:04")
+}
@@ -0,0 +1,128 @@
+package gml
+
+import (
+	"context"
+	"fmt"
+	"slices"
+	"strings"
+
+	"github.com/longkey1/gml/internal/google"
+)
+
+// System labels changed by the star, importance and spam commands
+const (
+	LabelInbox     = "INBOX"
+	LabelSpam      = "SPAM"
+	LabelStarred   = "STARRED"
+	LabelImportant = "IMPORTANT"
+)
+
+// MarkOptions selects the messages to add a system label to or remove it from
+type MarkOptions struct {
+	// IDs are the messages to change; when empty, the messages matching Query
+	// and LabelIDs are used
+	IDs      []string
+	Query    string
+	LabelIDs []string
+	// Label is the system label to change, e.g. LabelStarred
+	Label string
+	// Add adds Label when true and removes it otherwise
+	Add    bool
+	DryRun bool
+	// Failures collects messages that couldn't be read or modified; nil stops
+	// at the first failure
+	Failures *FailureLog
+}
+
+// MarkChange is a message whose label was (or with DryRun would be) changed
+type MarkChange struct {
+	ID      string `json:"id"`
+	From    string `json:"from"`
+	Subject string `json:"subject"`
+}
+
+// MarkMessages adds or removes a system label, skipping messages already in the
+// requested state, and returns the messages it changed
+func MarkMessages(ctx context.Context, svc *Service, opts MarkOptions) ([]MarkChange, error) {
+	ids := opts.IDs
+	if len(ids) == 0 {
+		var filterIDs []string
+		if len(opts.LabelIDs) > 0 {
+			idx, err := FetchLabelIndex(ctx, svc)
+			if err != nil {
+				return nil, err
+			}
+			if filterIDs, err = idx.ResolveLabelIDs(opts.LabelIDs); err != nil {
+				return nil, err
+			}
+		}
+
+		// Let the search skip messages that are already in the requested state
+		term := "is:" + strings.ToLower(opts.Label)
+		if opts.Add {
+			term = "-" + term
+		}
+		var err error
+		if ids, err = ListMessageIDs(ctx, svc, strings.TrimSpace(opts.Query+" "+term), filterIDs); err != nil {
+			return nil, err
+		}
+	}
+
+	var changes []MarkChange
+	for _, id := range ids {
+		msg, err := svc.API.GetMessage(ctx, id, google.GetMessageOptions{
+			Format:          "metadata",
+			MetadataHeaders: []string{"From", "Subject"},
+		})
+		if err != nil {
+			if err := opts.Failures.Record(fmt.Errorf("unable to retrieve message: %w", apiError(err)), id); err != nil {
+				return nil, err
+			}
+			continue
+		}
+		if slices.Contains(msg.LabelIds, opts.Label) == opts.Add {
+			continue
+		}
+		info := buildMessageInfo(msg, map[string]bool{"from": true, "subject": true}, MailLinks{}, nil, false)
+		changes = append(changes, MarkChange{ID: msg.Id, From: info.From, Subject: info.Subject})
+	}
+	if opts.DryRun || len(changes) == 0 {
+		return changes, nil
+	}
+
+	changed := make([]string, len(changes))
+	for i, c := range changes {
+		changed[i] = c.ID
+	}
+	var add, remove []string
+	if opts.Add {
+		add = []string{opts.Label}
+	} else {
+		remove = []string{opts.Label}
+	}
+	recorded := 0
+	if opts.Failures != nil {
+		recorded = len(opts.Failures.Items)
+	}
+	if err := batchModify(ctx, svc, changed, add, remove, opts.Failures); err != nil {
+		return nil, err
+	}
+	if opts.Failures != nil && len(opts.Failures.Items) > recorded {
+		// Drop the batches that failed under --ignore-errors
+		failed := make(map[string]bool)
+		for _, f := range opts.Failures.Items[recorded:] {
+			failed[f.ID] = true
+		}
+		changes = slices.DeleteFunc(changes, func(c MarkChange) bool { return failed[c.ID] })
+	}
+	return changes, nil
+}
+
+// ReportSpam moves messages to spam, or with spam false back to the inbox
+func ReportSpam(ctx context.Context, svc *Service, ids []string, spam bool, failures *FailureLog) error {
+	add, remove := []string{LabelSpam}, []string{LabelInbox}
+	if !spam {
+		add, remove = remove, add
+	}
+	return batchModify(ctx, svc, ids, add, remove, failures)
+}
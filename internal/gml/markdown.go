@@ -0,0 +1,79 @@
+package gml
+
+import (
+	"fmt"
+	"io"
+	"strings"
+)
+
+// formatDetailMarkdown outputs a message as a markdown document: the subject
+// as a heading, the headers as a list and the body in a fenced block
+func formatDetailMarkdown(w io.Writer, detail *MessageDetail) error {
+	fmt.Fprintf(w, "# %s\n\n", markdownHeading(detail.Subject, detail.ID))
+	fmt.Fprintf(w, "- **From:** %s\n", markdownInline(detail.From))
+	fmt.Fprintf(w, "- **To:** %s\n", markdownInline(detail.To))
+	fmt.Fprintf(w, "- **Date:** %s\n", markdownInline(detail.Date))
+	if len(detail.Labels) > 0 {
+		fmt.Fprintf(w, "- **Labels:** %s\n", markdownInline(strings.Join(detail.Labels, ", ")))
+	}
+	if detail.Auth != nil {
+		fmt.Fprintf(w, "- **Auth:** %s\n", markdownInline(detail.Auth.String()))
+	}
+	fmt.Fprintf(w, "- **ID:** `%s`\n", detail.ID)
+	if detail.URL != "" {
+		fmt.Fprintf(w, "- **URL:** <%s>\n", detail.URL)
+	}
+	fmt.Fprintln(w)
+	if len(detail.Headers) > 0 {
+		fmt.Fprint(w, "## Headers\n\n")
+		writeMarkdownFence(w, FormatHeaders(detail.Headers))
+		fmt.Fprint(w, "\n## Body\n\n")
+	}
+	writeMarkdownFence(w, detail.Body)
+	return nil
+}
+
+// writeMarkdownRow writes one table row
+func writeMarkdownRow(w io.Writer, cells []string) {
+	fmt.Fprintf(w, "| %s |\n", strings.Join(cells, " | "))
+}
+
+// writeMarkdownFence writes text in a fenced code block whose fence is longer
+// than any backtick run inside the text
+func writeMarkdownFence(w io.Writer, text string) {
+	longest, run := 0, 0
+	for _, r := range text {
+		if r == '`' {
+			run++
+			longest = max(longest, run)
+		} else {
+			run = 0
+		}
+	}
+	fence := strings.Repeat("`", max(3, longest+1))
+	fmt.Fprintf(w, "%s\n%s\n%s\n", fence, strings.TrimRight(text, "\n"), fence)
+}
+
+// markdownCell escapes a value for a table cell, which must stay on one line
+func markdownCell(s string) string {
+	s = strings.Join(strings.Fields(s), " ")
+	return strings.ReplaceAll(markdownInline(s), "|", `\|`)
+}
+
+// markdownHeading returns the subject for a heading, or the ID if it is empty
+func markdownHeading(subject, id string) string {
+	if strings.TrimSpace(subject) == "" {
+		return "(no subject) " + id
+	}
+	return markdownInline(strings.Join(strings.Fields(subject), " "))
+}
+
+// markdownEscaper escapes characters that would otherwise start formatting
+var markdownEscaper = strings.NewReplacer(
+	`\`, `\\`, "`", "\\`", "*", `\*`, "_", `\_`, "[", `\[`, "]", `\]`, "<", "&lt;", ">", "&gt;",
+)
+
+// markdownInline escapes header text (addresses, subjects) for inline markdown
+func markdownInline(s string) string {
+	return markdownEscaper.Replace(s)
+}
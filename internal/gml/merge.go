@@ -0,0 +1,251 @@
+package gml
+
+import (
+	"bytes"
+	"context"
+	"encoding/csv"
+	"fmt"
+	"io"
+	"os"
+	"strings"
+	"text/template"
+	"time"
+)
+
+// MailTemplate is a message template ([templates.<name>] in config, or a
+// file added with 'gml template add'). Subject, body and recipients are Go
+// text/template strings
+type MailTemplate struct {
+	Subject  string   `mapstructure:"subject"`
+	Body     string   `mapstructure:"body"`
+	BodyFile string   `mapstructure:"body_file"`
+	To       []string `mapstructure:"to"`
+	Cc       []string `mapstructure:"cc"`
+	Bcc      []string `mapstructure:"bcc"`
+	// Format is the body format: text (default), markdown or html
+	Format BodyFormat `mapstructure:"format"`
+}
+
+// LoadBody returns the template body, reading body_file if body is empty
+func (t MailTemplate) LoadBody() (string, error) {
+	if t.Body != "" || t.BodyFile == "" {
+		return t.Body, nil
+	}
+	data, err := os.ReadFile(t.BodyFile)
+	if err != nil {
+		return "", fmt.Errorf("unable to read template body: %w", err)
+	}
+	return string(data), nil
+}
+
+// Merge row statuses reported by MailMerge
+const (
+	MergeStatusSent    = "sent"
+	MergeStatusSkipped = "skipped"
+	MergeStatusFailed  = "failed"
+	MergeStatusDryRun  = "dry-run"
+)
+
+// MergeOptions contains options for a mail merge
+type MergeOptions struct {
+	Subject string
+	Body    string
+	// ToColumn is the CSV column holding the recipient address
+	ToColumn string
+	// Rate is the minimum delay between two sends
+	Rate time.Duration
+	// JournalPath records sent recipients so a partial run can be resumed
+	JournalPath string
+	DryRun      bool
+	// BodyFormat and Attachments apply to every message
+	BodyFormat  BodyFormat
+	Attachments []OutgoingAttachment
+	// Check validates each message before sending (nil to skip checks)
+	Check func(*OutgoingMessage) []string
+	// Status is called once per row as it is processed
+	Status func(MergeStatus)
+}
+
+// MergeStatus reports the outcome of one CSV row
+type MergeStatus struct {
+	Row       int              `json:"row"`
+	To        string           `json:"to"`
+	Status    string           `json:"status"`
+	MessageID string           `json:"messageId,omitempty"`
+	Error     string           `json:"error,omitempty"`
+	Message   *OutgoingMessage `json:"-"`
+}
+
+// MergeResult summarizes a mail merge
+type MergeResult struct {
+	Total   int `json:"total"`
+	Sent    int `json:"sent"`
+	Skipped int `json:"skipped"`
+	Failed  int `json:"failed"`
+}
+
+// ReadMergeCSV reads a CSV file whose header row names the template variables
+func ReadMergeCSV(r io.Reader) ([]map[string]string, error) {
+	reader := csv.NewReader(r)
+	reader.TrimLeadingSpace = true
+	records, err := reader.ReadAll()
+	if err != nil {
+		return nil, fmt.Errorf("unable to read CSV: %w", err)
+	}
+	if len(records) == 0 {
+		return nil, fmt.Errorf("CSV has no header row")
+	}
+
+	header := records[0]
+	if len(header) > 0 {
+		header[0] = strings.TrimPrefix(header[0], utf8BOM)
+	}
+	rows := make([]map[string]string, 0, len(records)-1)
+	for _, record := range records[1:] {
+		row := make(map[string]string, len(header))
+		for i, name := range header {
+			if i < len(record) {
+				row[strings.TrimSpace(name)] = record[i]
+			}
+		}
+		rows = append(rows, row)
+	}
+	return rows, nil
+}
+
+// MailMerge sends one templated message per row. Rows whose recipient is in
+// the journal are skipped; a failed row is reported and the merge continues
+func MailMerge(ctx context.Context, svc *Service, rows []map[string]string, opts MergeOptions) (*MergeResult, error) {
+	subjectTmpl, err := template.New("subject").Option("missingkey=error").Parse(opts.Subject)
+	if err != nil {
+		return nil, fmt.Errorf("invalid subject template: %w", err)
+	}
+	bodyTmpl, err := template.New("body").Option("missingkey=error").Parse(opts.Body)
+	if err != nil {
+		return nil, fmt.Errorf("invalid body template: %w", err)
+	}
+
+	toColumn := opts.ToColumn
+	if toColumn == "" {
+		toColumn = "email"
+	}
+	if len(rows) > 0 {
+		if _, ok := rows[0][toColumn]; !ok {
+			return nil, fmt.Errorf("CSV has no %q column", toColumn)
+		}
+	}
+
+	done, err := readJournal(opts.JournalPath)
+	if err != nil {
+		return nil, err
+	}
+
+	// Under WithDryRun sends are only reported, so none is journaled
+	var journal *os.File
+	if !opts.DryRun && !IsDryRun(ctx) {
+		journal, err = openJournal(opts.JournalPath)
+		if err != nil {
+			return nil, err
+		}
+		defer journal.Close()
+	}
+
+	report := func(s MergeStatus) {
+		if opts.Status != nil {
+			opts.Status(s)
+		}
+	}
+
+	result := &MergeResult{Total: len(rows)}
+	var lastSend time.Time
+	for i, row := range rows {
+		// Row 1 is the CSV header
+		status := MergeStatus{Row: i + 2, To: strings.TrimSpace(row[toColumn])}
+		key := strings.ToLower(status.To)
+
+		if done[key] {
+			status.Status = MergeStatusSkipped
+			result.Skipped++
+			report(status)
+			continue
+		}
+
+		msg, err := renderMergeMessage(subjectTmpl, bodyTmpl, status.To, row)
+		if err == nil {
+			msg.BodyFormat, msg.Attachments = opts.BodyFormat, opts.Attachments
+		}
+		if err == nil && opts.Check != nil {
+			if warnings := opts.Check(msg); len(warnings) > 0 {
+				err = fmt.Errorf("checks failed: %s", strings.Join(warnings, "; "))
+			}
+		}
+		if err != nil {
+			status.Status = MergeStatusFailed
+			status.Error = err.Error()
+			result.Failed++
+			report(status)
+			continue
+		}
+		status.Message = msg
+
+		if opts.DryRun {
+			status.Status = MergeStatusDryRun
+			report(status)
+			continue
+		}
+
+		// Space out sends to stay under Gmail's sending rate limits
+		if wait := opts.Rate - time.Since(lastSend); !lastSend.IsZero() && wait > 0 {
+			select {
+			case <-ctx.Done():
+				return result, ctx.Err()
+			case <-time.After(wait):
+			}
+		}
+		lastSend = time.Now()
+
+		sent, err := SendMessage(ctx, svc, msg)
+		if err != nil {
+			if ctx.Err() != nil {
+				return result, ctx.Err()
+			}
+			status.Status = MergeStatusFailed
+			status.Error = err.Error()
+			result.Failed++
+			report(status)
+			continue
+		}
+		if journal != nil {
+			if _, err := fmt.Fprintln(journal, key); err != nil {
+				return result, fmt.Errorf("unable to write journal: %w", err)
+			}
+		}
+		done[key] = true
+
+		status.Status = MergeStatusSent
+		status.MessageID = sent.Id
+		result.Sent++
+		report(status)
+	}
+
+	return result, nil
+}
+
+// renderMergeMessage renders the templates for one row
+func renderMergeMessage(subjectTmpl, bodyTmpl *template.Template, to string, row map[string]string) (*OutgoingMessage, error) {
+	if to == "" {
+		return nil, fmt.Errorf("empty recipient")
+	}
+	var subject, body bytes.Buffer
+	if err := subjectTmpl.Execute(&subject, row); err != nil {
+		return nil, fmt.Errorf("unable to render subject: %w", err)
+	}
+	if err := bodyTmpl.Execute(&body, row); err != nil {
+		return nil, fmt.Errorf("unable to render body: %w", err)
+	}
+	return &OutgoingMessage{
+		To:      []string{to},
+		Subject: subject.String(),
+		Body:    body.String(),
+	}, nil
+}
@@ -4,36 +4,125 @@ import (
 	"context"
 	"encoding/base64"
 	"fmt"
+	"html"
+	"log/slog"
+	"maps"
+	"slices"
 	"strings"
 
+	"github.com/longkey1/gml/internal/google"
+	"github.com/longkey1/gml/internal/telemetry"
+	"go.opentelemetry.io/otel/attribute"
 	"google.golang.org/api/gmail/v1"
 )
 
 // MessageInfo represents a simplified message for output
 type MessageInfo struct {
-	ID       string   `json:"id,omitempty"`
-	ThreadID string   `json:"threadId,omitempty"`
-	URL      string   `json:"url,omitempty"`
-	From     string   `json:"from,omitempty"`
-	To       string   `json:"to,omitempty"`
-	Subject  string   `json:"subject,omitempty"`
-	Date     string   `json:"date,omitempty"`
-	Snippet  string   `json:"snippet,omitempty"`
-	Labels   []string `json:"labels,omitempty"`
-	Body     string   `json:"body,omitempty"`
+	Account  string `json:"account,omitempty"`
+	ID       string `json:"id,omitempty"`
+	ThreadID string `json:"threadId,omitempty"`
+	Type     string `json:"type,omitempty"`
+	URL      string `json:"url,omitempty"`
+	From     string `json:"from,omitempty"`
+	To       string `json:"to,omitempty"`
+	Subject  string `json:"subject,omitempty"`
+	Date     string `json:"date,omitempty"`
+	// Size is the estimated size of the message in bytes
+	Size    int64    `json:"size,omitempty"`
+	Snippet string   `json:"snippet,omitempty"`
+	Labels  []string `json:"labels,omitempty"`
+	// Attachments lists the attachments; requesting them fetches full messages
+	Attachments []AttachmentInfo `json:"attachments,omitempty"`
+	// Headers holds every header (or those requested), keyed by canonical name
+	Headers map[string][]string `json:"headers,omitempty"`
+	// Auth holds the SPF, DKIM and DMARC verdicts
+	Auth *AuthResults `json:"auth,omitempty"`
+	Body string       `json:"body,omitempty"`
+}
+
+// FieldValue returns a field of the message for table and CSV output
+func (m MessageInfo) FieldValue(name string) any {
+	switch name {
+	case "account":
+		return m.Account
+	case "id":
+		return m.ID
+	case "threadid":
+		return m.ThreadID
+	case "type":
+		return m.Type
+	case "url":
+		return m.URL
+	case "from":
+		return m.From
+	case "to":
+		return m.To
+	case "subject":
+		return m.Subject
+	case "date":
+		return m.Date
+	case "size":
+		if m.Size == 0 {
+			return ""
+		}
+		return FormatSize(m.Size)
+	case "labels":
+		return m.Labels
+	case "attachments":
+		names := make([]string, len(m.Attachments))
+		for i, a := range m.Attachments {
+			names[i] = fmt.Sprintf("%s (%s)", a.Filename, FormatSize(a.Size))
+		}
+		return names
+	case "snippet":
+		return m.Snippet
+	case "headers":
+		return FormatHeaders(m.Headers)
+	case "auth":
+		return m.Auth.String()
+	case "body":
+		return m.Body
+	}
+	return nil
 }
 
 // MessageDetail represents a full message with body for output
 type MessageDetail struct {
 	ID       string   `json:"id"`
 	ThreadID string   `json:"threadId"`
+	Type     string   `json:"type"`
 	URL      string   `json:"url"`
 	From     string   `json:"from"`
 	To       string   `json:"to"`
 	Subject  string   `json:"subject"`
 	Date     string   `json:"date"`
 	Labels   []string `json:"labels"`
-	Body     string   `json:"body"`
+	// Headers holds all headers (or the requested ones) when asked for
+	Headers map[string][]string `json:"headers,omitempty"`
+	// Auth holds the SPF, DKIM and DMARC verdicts, if the message has any
+	Auth *AuthResults `json:"auth,omitempty"`
+	Body string       `json:"body"`
+}
+
+// Message types reported in the type field; Gmail returns drafts and Hangouts
+// chat messages through the messages API, often without a payload
+const (
+	MessageTypeMessage = "message"
+	MessageTypeDraft   = "draft"
+	MessageTypeChat    = "chat"
+)
+
+// MessageType classifies a message by its DRAFT and CHAT labels
+func MessageType(msg *gmail.Message) string {
+	for _, l := range msg.LabelIds {
+		switch l {
+		case "DRAFT":
+			return MessageTypeDraft
+		case "CHAT":
+			return MessageTypeChat
+		}
+	}
+	return MessageTypeMessage
 }
 
 // ListMessagesOptions contains options for listing messages
@@ -42,14 +131,68 @@ type ListMessagesOptions struct {
 	MaxResults int64
 	LabelIDs   []string
 	Fields     map[string]bool
+	// BodyFormat controls how the body field is rendered (default text)
+	BodyFormat BodyFormat
+	// RawHeaders keeps RFC 2047 encoded-words in From/To/Subject undecoded
+	RawHeaders bool
+	// Headers limits the headers field to these headers (default all)
+	Headers []string
+	// Each, if set, receives every message as soon as it is fetched instead of
+	// collecting them; ListMessages then returns no messages
+	Each func(MessageInfo) error
+	// Progress, if set, is called as message details are fetched
+	Progress func(done, total int)
+	// URLStyle selects what the url field links to (default auto)
+	URLStyle URLStyle
+	// Pager, if set, is called with the messages of each page that has a next
+	// one and decides whether to fetch more. Messages passed to it are not
+	// returned; ListMessages returns the rest (after PageAll, or the last page)
+	Pager func(page []MessageInfo) (PageAction, error)
+}
+
+// PageAction is a Pager's decision after a page of results
+type PageAction int
+
+const (
+	// PageStop ends the listing
+	PageStop PageAction = iota
+	// PageNext fetches one more page and asks again
+	PageNext
+	// PageAll fetches all remaining pages without asking
+	PageAll
+)
+
+// GetMessageOptions contains options for retrieving a single message
+type GetMessageOptions struct {
+	// BodyFormat controls how the body is rendered (default text)
+	BodyFormat BodyFormat
+	// RawHeaders keeps RFC 2047 encoded-words in From/To/Subject undecoded
+	RawHeaders bool
+	// URLStyle selects what the URL links to (default auto)
+	URLStyle URLStyle
+	// Images resolves cid: references of HTML bodies (BodyFormatHTML only)
+	Images InlineImageOptions
+	// AllHeaders fills Headers with every header of the message
+	AllHeaders bool
+	// Headers fills Headers with only these headers
+	Headers []string
 }
 
 // ListMessages fetches messages with pagination and returns message info
 func ListMessages(ctx context.Context, svc *Service, opts ListMessagesOptions) ([]MessageInfo, error) {
+	ctx, span := telemetry.Start(ctx, "ListMessages", attribute.String("gml.query", opts.Query))
+	messages, err := listMessages(ctx, svc, opts)
+	span.SetAttributes(attribute.Int("gml.messages", len(messages)))
+	telemetry.End(span, err)
+	return messages, err
+}
+
+// listMessages implements ListMessages
+func listMessages(ctx context.Context, svc *Service, opts ListMessagesOptions) ([]MessageInfo, error) {
 	// Fetch user email if URL field is requested
 	var userEmail string
 	if opts.Fields["url"] {
-		email, err := GetUserEmail(svc)
+		email, err := GetUserEmail(ctx, svc)
 		if err != nil {
 			return nil, err
 		}
@@ -59,7 +202,7 @@ func ListMessages(ctx context.Context, svc *Service, opts ListMessagesOptions) (
 	// Fetch label mappings if needed
 	var labelsIndex *LabelIndex
 	if len(opts.LabelIDs) > 0 || opts.Fields["labels"] {
-		idx, err := FetchLabelIndex(svc)
+		idx, err := FetchLabelIndex(ctx, svc)
 		if err != nil {
 			return nil, err
 		}
@@ -79,22 +222,19 @@ func ListMessages(ctx context.Context, svc *Service, opts ListMessagesOptions) (
 	// List messages with pagination
 	var allMessages []*gmail.Message
 	pageToken := ""
+	pageAll := false
 
+	listCtx, listSpan := telemetry.Start(ctx, "list message IDs")
 	for {
-		call := svc.Gmail.Users.Messages.List("me").MaxResults(opts.MaxResults).Context(ctx)
-		if opts.Query != "" {
-			call = call.Q(opts.Query)
-		}
-		if len(resolvedLabels) > 0 {
-			call = call.LabelIds(resolvedLabels...)
-		}
-		if pageToken != "" {
-			call = call.PageToken(pageToken)
-		}
-
-		result, err := call.Do()
+		result, err := svc.API.ListMessages(listCtx, google.ListMessagesOptions{
+			Query:      opts.Query,
+			LabelIDs:   resolvedLabels,
+			MaxResults: opts.MaxResults,
+			PageToken:  pageToken,
+		})
 		if err != nil {
-			return nil, fmt.Errorf("unable to retrieve messages: %w", err)
+			telemetry.End(listSpan, err)
+			return nil, fmt.Errorf("unable to retrieve messages: %w", apiError(err))
 		}
 
 		allMessages = append(allMessages, result.Messages...)
@@ -103,88 +243,250 @@ func ListMessages(ctx context.Context, svc *Service, opts ListMessagesOptions) (
 			break
 		}
 		pageToken = result.NextPageToken
+
+		if opts.Pager != nil && !pageAll {
+			// Show what we have before deciding whether to go on
+			page, err := fetchMessageDetails(ctx, svc, allMessages, opts, userEmail, labelsIndex)
+			if err != nil {
+				telemetry.End(listSpan, err)
+				return nil, err
+			}
+			allMessages = nil
+			action, err := opts.Pager(page)
+			if err != nil {
+				telemetry.End(listSpan, err)
+				return nil, err
+			}
+			if action == PageStop {
+				break
+			}
+			pageAll = action == PageAll
+		}
 	}
+	listSpan.SetAttributes(attribute.Int("gml.messages", len(allMessages)))
+	telemetry.End(listSpan, nil)
 
 	if len(allMessages) == 0 {
 		return nil, nil
 	}
+	return fetchMessageDetails(ctx, svc, allMessages, opts, userEmail, labelsIndex)
+}
 
-	// Determine if we need full format (for body)
-	needsBody := opts.Fields["body"]
+// fetchMessageDetails gets the requested fields of listed messages
+func fetchMessageDetails(ctx context.Context, svc *Service, listed []*gmail.Message, opts ListMessagesOptions, userEmail string, labelsIndex *LabelIndex) ([]MessageInfo, error) {
+	// Determine if we need full format (for body and attachments)
+	needsFull := needsFullFormat(opts.Fields)
 
 	// Get message details
+	ctx, fetchSpan := telemetry.Start(ctx, "fetch messages")
+	defer fetchSpan.End()
+
 	var messages []MessageInfo
-	for _, m := range allMessages {
+	var skipped int
+	missing := make(map[string]int)
+	for i, m := range listed {
+		if opts.Progress != nil {
+			opts.Progress(i, len(listed))
+		}
+
 		var msg *gmail.Message
 		var err error
 
-		if needsBody {
-			msg, err = svc.Gmail.Users.Messages.Get("me", m.Id).Format("full").Context(ctx).Do()
+		if needsFull {
+			msg, err = svc.API.GetMessage(ctx, m.Id, google.GetMessageOptions{Format: "full"})
 		} else {
-			msg, err = svc.Gmail.Users.Messages.Get("me", m.Id).Format("metadata").
-				MetadataHeaders("From", "To", "Subject", "Date").Context(ctx).Do()
+			getOpts := google.GetMessageOptions{Format: "metadata"}
+			// Without MetadataHeaders, metadata includes every header
+			if !opts.Fields["headers"] || len(opts.Headers) > 0 {
+				getOpts.MetadataHeaders = metadataHeaders(opts.Fields, opts.Headers)
+			}
+			msg, err = svc.API.GetMessage(ctx, m.Id, getOpts)
 		}
 		if err != nil {
 			// Skip messages we can't retrieve instead of failing completely
+			slog.Debug("skipping message", "id", m.Id, "err", err)
+			skipped++
 			continue
 		}
+		if msg.Payload == nil {
+			slog.Debug("message has no payload", "id", m.Id, "type", MessageType(msg))
+			missing[MessageType(msg)]++
+		}
 
-		info := buildMessageInfo(msg, opts.Fields, userEmail, labelsIndex)
+		info := buildMessageInfo(msg, opts.Fields, MailLinks{Email: userEmail, Style: opts.URLStyle}, labelsIndex, opts.RawHeaders)
 
-		if needsBody {
-			info.Body = ExtractBody(msg.Payload)
+		if opts.Fields["headers"] {
+			info.Headers = MessageHeaders(msg.Payload, opts.Headers, opts.RawHeaders)
+		}
+		if opts.Fields["body"] {
+			info.Body = ExtractBodyAs(msg.Payload, opts.BodyFormat)
 		}
 
+		if opts.Each != nil {
+			if err := opts.Each(info); err != nil {
+				return nil, err
+			}
+			continue
+		}
 		messages = append(messages, info)
 	}
+	if opts.Progress != nil {
+		opts.Progress(len(listed), len(listed))
+	}
+	if skipped > 0 {
+		slog.Warn("skipped messages that could not be retrieved (see --debug)", "count", skipped)
+	}
+	for _, t := range slices.Sorted(maps.Keys(missing)) {
+		slog.Warn("messages without a payload have empty headers and body", "type", t, "count", missing[t])
+	}
 
 	return messages, nil
 }
 
+// FindMessageID returns the ID of the single message matching query. It fails
+// when none or several match, unless latest is set, which picks the newest
+func FindMessageID(ctx context.Context, svc *Service, query string, latest bool) (string, error) {
+	limit := int64(2)
+	if latest {
+		limit = 1
+	}
+	// Gmail lists newest first, so the first result is the latest match
+	resp, err := svc.API.ListMessages(ctx, google.ListMessagesOptions{Query: query, MaxResults: limit})
+	if err != nil {
+		return "", fmt.Errorf("unable to retrieve messages: %w", apiError(err))
+	}
+	switch {
+	case len(resp.Messages) == 0:
+		return "", notFoundError("no message matches %q", query)
+	case len(resp.Messages) > 1:
+		return "", fmt.Errorf("more than one message matches %q; narrow the query or use --latest", query)
+	}
+	return resp.Messages[0].Id, nil
+}
+
+// ListMessageIDs returns the IDs of all messages matching the query and label IDs,
+// following pagination
+func ListMessageIDs(ctx context.Context, svc *Service, query string, labelIDs []string) ([]string, error) {
+	var ids []string
+	pageToken := ""
+
+	for {
+		result, err := svc.API.ListMessages(ctx, google.ListMessagesOptions{
+			Query:      query,
+			LabelIDs:   labelIDs,
+			MaxResults: 500,
+			PageToken:  pageToken,
+		})
+		if err != nil {
+			return nil, fmt.Errorf("unable to retrieve messages: %w", apiError(err))
+		}
+
+		for _, m := range result.Messages {
+			ids = append(ids, m.Id)
+		}
+
+		if result.NextPageToken == "" {
+			break
+		}
+		pageToken = result.NextPageToken
+	}
+
+	return ids, nil
+}
+
+// ModifyMessage adds and removes label IDs on a single message
+func ModifyMessage(ctx context.Context, svc *Service, messageID string, addLabelIDs, removeLabelIDs []string) error {
+	req := &gmail.ModifyMessageRequest{
+		AddLabelIds:    addLabelIDs,
+		RemoveLabelIds: removeLabelIDs,
+	}
+	if err := svc.API.ModifyMessage(ctx, messageID, req); err != nil {
+		return fmt.Errorf("unable to modify message: %w", apiError(err))
+	}
+	return nil
+}
+
+// TrashMessage moves a single message to the trash
+func TrashMessage(ctx context.Context, svc *Service, messageID string) error {
+	if err := svc.API.TrashMessage(ctx, messageID); err != nil {
+		return fmt.Errorf("unable to trash message: %w", apiError(err))
+	}
+	return nil
+}
+
 // GetMessage retrieves a single message by ID with full details
-func GetMessage(ctx context.Context, svc *Service, messageID string) (*MessageDetail, error) {
-	userEmail, err := GetUserEmail(svc)
+func GetMessage(ctx context.Context, svc *Service, messageID string, opts GetMessageOptions) (*MessageDetail, error) {
+	userEmail, err := GetUserEmail(ctx, svc)
 	if err != nil {
 		return nil, err
 	}
 
-	labelsIndex, err := FetchLabelIndex(svc)
+	labelsIndex, err := FetchLabelIndex(ctx, svc)
 	if err != nil {
 		return nil, err
 	}
 
-	msg, err := svc.Gmail.Users.Messages.Get("me", messageID).Format("full").Context(ctx).Do()
+	msg, err := svc.API.GetMessage(ctx, messageID, google.GetMessageOptions{Format: "full"})
 	if err != nil {
-		return nil, fmt.Errorf("unable to retrieve message: %w", err)
+		return nil, fmt.Errorf("unable to retrieve message: %w", apiError(err))
 	}
 
 	detail := &MessageDetail{
 		ID:       msg.Id,
 		ThreadID: msg.ThreadId,
-		URL:      BuildMailURL(userEmail, msg.ThreadId),
+		Type:     MessageType(msg),
+		URL:      MailLinks{Email: userEmail, Style: opts.URLStyle}.Message(msg),
 		Labels:   labelsIndex.MapLabelIDsToNames(msg.LabelIds),
 	}
+	if msg.Payload == nil {
+		slog.Warn("message has no payload; headers and body are empty", "id", msg.Id, "type", detail.Type)
+		return detail, nil
+	}
 
 	for _, header := range msg.Payload.Headers {
 		switch header.Name {
 		case "From":
-			detail.From = header.Value
+			detail.From = headerText(header.Value, opts.RawHeaders)
 		case "To":
-			detail.To = header.Value
+			detail.To = headerText(header.Value, opts.RawHeaders)
 		case "Subject":
-			detail.Subject = header.Value
+			detail.Subject = headerText(header.Value, opts.RawHeaders)
 		case "Date":
 			detail.Date = header.Value
 		}
 	}
 
-	detail.Body = ExtractBody(msg.Payload)
+	if opts.AllHeaders || len(opts.Headers) > 0 {
+		detail.Headers = MessageHeaders(msg.Payload, opts.Headers, opts.RawHeaders)
+	}
+	detail.Auth = ParseAuthResults(msg.Payload)
+
+	detail.Body = ExtractBodyAs(msg.Payload, opts.BodyFormat)
+	if opts.BodyFormat == BodyFormatHTML {
+		if detail.Body, err = ResolveInlineImages(ctx, svc, msg, detail.Body, opts.Images); err != nil {
+			return nil, err
+		}
+	}
 
 	return detail, nil
 }
 
+// GetRawMessage retrieves a message in RFC 822 format
+func GetRawMessage(ctx context.Context, svc *Service, messageID string) ([]byte, *gmail.Message, error) {
+	msg, err := svc.API.GetMessage(ctx, messageID, google.GetMessageOptions{Format: "raw"})
+	if err != nil {
+		return nil, nil, fmt.Errorf("unable to retrieve message: %w", apiError(err))
+	}
+
+	raw, err := base64.URLEncoding.DecodeString(msg.Raw)
+	if err != nil {
+		return nil, nil, fmt.Errorf("unable to decode message: %w", err)
+	}
+	return raw, msg, nil
+}
+
 // buildMessageInfo constructs a MessageInfo from a Gmail message
-func buildMessageInfo(msg *gmail.Message, fields map[string]bool, userEmail string, labelsIndex *LabelIndex) MessageInfo {
+func buildMessageInfo(msg *gmail.Message, fields map[string]bool, links MailLinks, labelsIndex *LabelIndex, rawHeaders bool) MessageInfo {
 	info := MessageInfo{}
 
 	if fields["id"] {
@@ -193,30 +495,46 @@ func buildMessageInfo(msg *gmail.Message, fields map[string]bool, userEmail stri
 	if fields["threadid"] {
 		info.ThreadID = msg.ThreadId
 	}
+	if fields["type"] {
+		info.Type = MessageType(msg)
+	}
 	if fields["url"] {
-		info.URL = BuildMailURL(userEmail, msg.ThreadId)
+		info.URL = links.Message(msg)
 	}
 	if fields["labels"] && labelsIndex != nil {
 		info.Labels = labelsIndex.MapLabelIDsToNames(msg.LabelIds)
 	}
+	if fields["size"] {
+		info.Size = msg.SizeEstimate
+	}
 	if fields["snippet"] {
 		info.Snippet = msg.Snippet
 	}
+	if fields["auth"] {
+		info.Auth = ParseAuthResults(msg.Payload)
+	}
+	if fields["attachments"] {
+		info.Attachments = ListAttachments(msg.Payload)
+		// Drop inline data; listings only show names and sizes
+		for i := range info.Attachments {
+			info.Attachments[i].data = ""
+		}
+	}
 
 	if msg.Payload != nil {
 		for _, header := range msg.Payload.Headers {
 			switch header.Name {
 			case "From":
 				if fields["from"] {
-					info.From = header.Value
+					info.From = headerText(header.Value, rawHeaders)
 				}
 			case "To":
 				if fields["to"] {
-					info.To = header.Value
+					info.To = headerText(header.Value, rawHeaders)
 				}
 			case "Subject":
 				if fields["subject"] {
-					info.Subject = header.Value
+					info.Subject = headerText(header.Value, rawHeaders)
 				}
 			case "Date":
 				if fields["date"] {
@@ -229,33 +547,68 @@ func buildMessageInfo(msg *gmail.Message, fields map[string]bool, userEmail stri
 	return info
 }
 
-// ExtractBody extracts the message body from payload
+// metadataHeaders returns the headers to request in metadata format for
+// fields, plus extra ones
+func metadataHeaders(fields map[string]bool, extra []string) []string {
+	names := append([]string{"From", "To", "Subject", "Date"}, extra...)
+	if fields["auth"] {
+		names = append(names, authHeaders...)
+	}
+	return names
+}
+
+// needsFullFormat reports whether fields need messages in full format rather
+// than metadata
+func needsFullFormat(fields map[string]bool) bool {
+	return fields["body"] || fields["attachments"]
+}
+
+// ExtractBody extracts the message body from payload as plain text
 func ExtractBody(payload *gmail.MessagePart) string {
+	return ExtractBodyAs(payload, BodyFormatText)
+}
+
+// ExtractBodyAs extracts the message body from payload in the given format
+// HTML-only messages are converted for text and markdown; plain-text-only
+// messages are escaped into a <pre> block for html
+func ExtractBodyAs(payload *gmail.MessagePart, format BodyFormat) string {
 	if payload == nil {
 		return ""
 	}
 
-	// Try to get plain text body first
-	body := findBodyPart(payload, "text/plain")
-	if body != "" {
-		return body
-	}
+	plain := findBodyPart(payload, "text/plain")
+	htmlBody := findBodyPart(payload, "text/html")
 
-	// Fall back to HTML body
-	body = findBodyPart(payload, "text/html")
-	if body != "" {
-		return body
-	}
-
-	// If no parts, try the main body
-	if payload.Body != nil && payload.Body.Data != "" {
+	// If no parts, use the main body
+	if plain == "" && htmlBody == "" && payload.Body != nil && payload.Body.Data != "" {
 		decoded, err := base64.URLEncoding.DecodeString(payload.Body.Data)
 		if err != nil {
 			return ""
 		}
-		return string(decoded)
+		plain = string(decoded)
 	}
 
+	switch format {
+	case BodyFormatHTML:
+		if htmlBody != "" {
+			return htmlBody
+		}
+		if plain != "" {
+			return "<pre>" + html.EscapeString(plain) + "</pre>"
+		}
+	case BodyFormatMarkdown:
+		if htmlBody != "" {
+			return HTMLToMarkdown(htmlBody)
+		}
+		return plain
+	default:
+		if plain != "" {
+			return plain
+		}
+		if htmlBody != "" {
+			return HTMLToText(htmlBody)
+		}
+	}
 	return ""
 }
 
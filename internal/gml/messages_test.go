@@ -0,0 +1,207 @@
+package gml
+
+import (
+	"context"
+	"errors"
+	"slices"
+	"strings"
+	"testing"
+
+	"github.com/longkey1/gml/internal/fakegmail"
+)
+
+func TestListMessagesPaging(t *testing.T) {
+	svc, fake := newTestService(t)
+	ids := addMessages(fake, 25, fakegmail.Message{From: "bob@example.com", LabelIDs: []string{"INBOX"}})
+	ctx := context.Background()
+
+	messages, err := ListMessages(ctx, svc, ListMessagesOptions{MaxResults: 10, Fields: ParseFields("id,subject")})
+	if err != nil {
+		t.Fatal(err)
+	}
+	if len(messages) != 25 {
+		t.Fatalf("ListMessages() returned %d messages, want 25", len(messages))
+	}
+	if messages[0].ID != ids[24] || messages[24].ID != ids[0] {
+		t.Errorf("ListMessages() order = %s..%s, want newest first", messages[0].ID, messages[24].ID)
+	}
+	var tokens []string
+	for _, l := range fake.lists {
+		if l.MaxResults != 10 {
+			t.Errorf("page size = %d, want 10", l.MaxResults)
+		}
+		tokens = append(tokens, l.PageToken)
+	}
+	if want := []string{"", "10", "20"}; !slices.Equal(tokens, want) {
+		t.Errorf("page tokens = %q, want %q", tokens, want)
+	}
+}
+
+func TestListMessagesPager(t *testing.T) {
+	tests := []struct {
+		action    PageAction
+		wantPages int
+		wantRest  int
+	}{
+		{action: PageStop, wantPages: 1, wantRest: 0},
+		{action: PageNext, wantPages: 2, wantRest: 5},
+		{action: PageAll, wantPages: 1, wantRest: 15},
+	}
+	for _, tt := range tests {
+		svc, fake := newTestService(t)
+		addMessages(fake, 25, fakegmail.Message{LabelIDs: []string{"INBOX"}})
+
+		var pages []int
+		rest, err := ListMessages(context.Background(), svc, ListMessagesOptions{
+			MaxResults: 10,
+			Fields:     ParseFields("id"),
+			Pager: func(page []MessageInfo) (PageAction, error) {
+				pages = append(pages, len(page))
+				return tt.action, nil
+			},
+		})
+		if err != nil {
+			t.Fatal(err)
+		}
+		if len(pages) != tt.wantPages || pages[0] != 10 || len(rest) != tt.wantRest {
+			t.Errorf("action %d: pages %v, rest %d; want %d pages of 10, rest %d", tt.action, pages, len(rest), tt.wantPages, tt.wantRest)
+		}
+	}
+}
+
+func TestListMessagesLabels(t *testing.T) {
+	svc, fake := newTestService(t)
+	work := fake.AddLabel("My Project")
+	addMessages(fake, 3, fakegmail.Message{LabelIDs: []string{"INBOX"}})
+	tagged := addMessages(fake, 2, fakegmail.Message{LabelIDs: []string{"INBOX", work, "UNREAD"}})
+	ctx := context.Background()
+
+	tests := []struct {
+		name   string
+		labels []string
+		query  string
+		want   int
+	}{
+		{name: "system label", labels: []string{"INBOX"}, want: 5},
+		{name: "name", labels: []string{"my project"}, want: 2},
+		{name: "query form", labels: []string{"my-project"}, want: 2},
+		{name: "ID", labels: []string{work}, want: 2},
+		{name: "several labels", labels: []string{"INBOX", "UNREAD"}, want: 2},
+		{name: "label query", query: "label:my-project", want: 2},
+		{name: "negated query", labels: []string{"INBOX"}, query: "-is:unread", want: 3},
+	}
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			messages, err := ListMessages(ctx, svc, ListMessagesOptions{Query: tt.query, LabelIDs: tt.labels, Fields: ParseFields("id,labels")})
+			if err != nil {
+				t.Fatal(err)
+			}
+			if len(messages) != tt.want {
+				t.Errorf("ListMessages() returned %d messages, want %d", len(messages), tt.want)
+			}
+		})
+	}
+
+	messages, err := ListMessages(ctx, svc, ListMessagesOptions{LabelIDs: []string{work}, Fields: ParseFields("id,labels")})
+	if err != nil {
+		t.Fatal(err)
+	}
+	if messages[0].ID != tagged[1] || !slices.Contains(messages[0].Labels, "My Project") {
+		t.Errorf("ListMessages() = %+v, want %s labeled My Project", messages[0], tagged[1])
+	}
+
+	if _, err := ListMessages(ctx, svc, ListMessagesOptions{LabelIDs: []string{"nope"}}); !errors.Is(err, ErrNotFound) {
+		t.Errorf("ListMessages() with an unknown label: error = %v, want ErrNotFound", err)
+	}
+}
+
+func TestGetMessage(t *testing.T) {
+	svc, fake := newTestService(t)
+	work := fake.AddLabel("Work")
+	id := fake.AddMessage(fakegmail.Message{
+		From:     "Bob <bob@example.com>",
+		To:       testEmail,
+		Subject:  "Quarterly report",
+		Body:     "See attached.",
+		Date:     testDate,
+		LabelIDs: []string{"INBOX", work},
+	})
+	ctx := context.Background()
+
+	msg, err := GetMessage(ctx, svc, id, GetMessageOptions{})
+	if err != nil {
+		t.Fatal(err)
+	}
+	if msg.ID != id || msg.From != "Bob <bob@example.com>" || msg.Subject != "Quarterly report" || msg.Body != "See attached." {
+		t.Errorf("GetMessage() = %+v", msg)
+	}
+	if !slices.Equal(msg.Labels, []string{"INBOX", "Work"}) {
+		t.Errorf("GetMessage() labels = %v, want [INBOX Work]", msg.Labels)
+	}
+
+	raw, full, err := GetRawMessage(ctx, svc, id)
+	if err != nil {
+		t.Fatal(err)
+	}
+	if full.Id != id || !strings.Contains(string(raw), "Subject: Quarterly report\r\n") || !strings.HasSuffix(string(raw), "\r\n\r\nSee attached.") {
+		t.Errorf("GetRawMessage() = %q", raw)
+	}
+
+	if _, err := GetMessage(ctx, svc, "missing", GetMessageOptions{}); !errors.Is(err, ErrNotFound) {
+		t.Errorf("GetMessage() of a missing message: error = %v, want ErrNotFound", err)
+	}
+	if _, _, err := GetRawMessage(ctx, svc, "missing"); !errors.Is(err, ErrNotFound) {
+		t.Errorf("GetRawMessage() of a missing message: error = %v, want ErrNotFound", err)
+	}
+}
+
+func TestModifyAndTrashMessage(t *testing.T) {
+	svc, fake := newTestService(t)
+	ids := addMessages(fake, 2, fakegmail.Message{LabelIDs: []string{"INBOX", "UNREAD"}})
+	ctx := context.Background()
+
+	if err := ModifyMessage(ctx, svc, ids[0], []string{"STARRED"}, []string{"UNREAD"}); err != nil {
+		t.Fatal(err)
+	}
+	if got := fake.Message(ids[0]).LabelIds; !slices.Equal(got, []string{"INBOX", "STARRED"}) {
+		t.Errorf("labels after ModifyMessage() = %v, want [INBOX STARRED]", got)
+	}
+
+	if err := TrashMessage(ctx, svc, ids[1]); err != nil {
+		t.Fatal(err)
+	}
+	if got := fake.Message(ids[1]).LabelIds; !slices.Equal(got, []string{"UNREAD", "TRASH"}) {
+		t.Errorf("labels after TrashMessage() = %v, want [UNREAD TRASH]", got)
+	}
+	// Trash is left out of searches, like in Gmail
+	listed, err := ListMessageIDs(ctx, svc, "", nil)
+	if err != nil {
+		t.Fatal(err)
+	}
+	if !slices.Equal(listed, ids[:1]) {
+		t.Errorf("ListMessageIDs() = %v, want %v", listed, ids[:1])
+	}
+
+	if err := ModifyMessage(ctx, svc, "missing", nil, []string{"INBOX"}); !errors.Is(err, ErrNotFound) {
+		t.Errorf("ModifyMessage() of a missing message: error = %v, want ErrNotFound", err)
+	}
+	if err := TrashMessage(ctx, svc, "missing"); !errors.Is(err, ErrNotFound) {
+		t.Errorf("TrashMessage() of a missing message: error = %v, want ErrNotFound", err)
+	}
+}
+
+func TestFindMessageID(t *testing.T) {
+	svc, fake := newTestService(t)
+	ids := addMessages(fake, 2, fakegmail.Message{From: "bob@example.com", Subject: "invoice"})
+	ctx := context.Background()
+
+	if _, err := FindMessageID(ctx, svc, "from:bob", false); err == nil {
+		t.Error("FindMessageID() with two matches: want an error")
+	}
+	if id, err := FindMessageID(ctx, svc, "from:bob", true); err != nil || id != ids[1] {
+		t.Errorf("FindMessageID(latest) = %q, %v, want %q", id, err, ids[1])
+	}
+	if _, err := FindMessageID(ctx, svc, "from:carol", true); !errors.Is(err, ErrNotFound) {
+		t.Errorf("FindMessageID() without matches: error = %v, want ErrNotFound", err)
+	}
+}
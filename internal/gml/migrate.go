@@ -0,0 +1,169 @@
+package gml
+
+import (
+	"bufio"
+	"context"
+	"errors"
+	"fmt"
+	"os"
+	"path/filepath"
+	"strings"
+)
+
+// unmigratableLabels are system labels that can't be applied on import
+var unmigratableLabels = map[string]bool{
+	"DRAFT": true,
+	"CHAT":  true,
+}
+
+// MigrateOptions contains options for copying messages between accounts
+type MigrateOptions struct {
+	Query  string
+	Labels []string
+	// JournalPath records migrated source message IDs so an interrupted
+	// migration can resume without duplicating messages
+	JournalPath string
+	DryRun      bool
+	// Failures collects messages that could not be migrated; nil stops the
+	// migration at the first failure
+	Failures *FailureLog
+	// Progress, if set, is called after each message
+	Progress func(done, total int)
+}
+
+// MigrateResult summarizes a migration
+type MigrateResult struct {
+	Total    int `json:"total"`
+	Migrated int `json:"migrated"`
+	Skipped  int `json:"skipped"`
+}
+
+// MigrateMessages streams raw messages matching opts from src and imports them
+// into dst, applying the same labels (created in dst as needed)
+// The internal date is taken from each message's Date header
+func MigrateMessages(ctx context.Context, src, dst *Service, opts MigrateOptions) (*MigrateResult, error) {
+	srcIdx, err := FetchLabelIndex(ctx, src)
+	if err != nil {
+		return nil, err
+	}
+	labelIDs, err := srcIdx.ResolveLabelIDs(opts.Labels)
+	if err != nil {
+		return nil, err
+	}
+
+	ids, err := ListMessageIDs(ctx, src, opts.Query, labelIDs)
+	if err != nil {
+		return nil, err
+	}
+
+	done, err := readJournal(opts.JournalPath)
+	if err != nil {
+		return nil, err
+	}
+
+	result := &MigrateResult{Total: len(ids)}
+	var pending []string
+	for _, id := range ids {
+		if done[id] {
+			result.Skipped++
+			continue
+		}
+		pending = append(pending, id)
+	}
+	if opts.DryRun || len(pending) == 0 {
+		return result, nil
+	}
+
+	dstIdx, err := FetchLabelIndex(ctx, dst)
+	if err != nil {
+		return nil, err
+	}
+
+	journal, err := openJournal(opts.JournalPath)
+	if err != nil {
+		return nil, err
+	}
+	defer journal.Close()
+
+	for i, id := range pending {
+		if opts.Progress != nil {
+			opts.Progress(i, len(pending))
+		}
+		if err := migrateMessage(ctx, src, dst, srcIdx, dstIdx, id); err != nil {
+			// Failed messages stay out of the journal, so a re-run retries them
+			if err := opts.Failures.Record(err, id); err != nil {
+				return result, err
+			}
+			continue
+		}
+		if _, err := fmt.Fprintln(journal, id); err != nil {
+			return result, fmt.Errorf("unable to write migration journal: %w", err)
+		}
+		result.Migrated++
+	}
+	if opts.Progress != nil {
+		opts.Progress(len(pending), len(pending))
+	}
+
+	return result, nil
+}
+
+// migrateMessage copies one raw message with its labels from src to dst
+func migrateMessage(ctx context.Context, src, dst *Service, srcIdx, dstIdx *LabelIndex, id string) error {
+	raw, msg, err := GetRawMessage(ctx, src, id)
+	if err != nil {
+		return err
+	}
+
+	var names []string
+	for _, name := range srcIdx.MapLabelIDsToNames(msg.LabelIds) {
+		if !unmigratableLabels[name] {
+			names = append(names, name)
+		}
+	}
+	dstLabelIDs, err := dstIdx.EnsureLabelIDs(ctx, dst, names)
+	if err != nil {
+		return err
+	}
+
+	if _, err := ImportMessage(ctx, dst, raw, dstLabelIDs); err != nil {
+		return fmt.Errorf("message %s: %w", id, err)
+	}
+	return nil
+}
+
+// readJournal returns the keys recorded in a resume journal
+func readJournal(path string) (map[string]bool, error) {
+	done := make(map[string]bool)
+	f, err := os.Open(path)
+	if errors.Is(err, os.ErrNotExist) {
+		return done, nil
+	}
+	if err != nil {
+		return nil, fmt.Errorf("unable to read journal: %w", err)
+	}
+	defer f.Close()
+
+	scanner := bufio.NewScanner(f)
+	for scanner.Scan() {
+		if id := strings.TrimSpace(scanner.Text()); id != "" {
+			done[id] = true
+		}
+	}
+	if err := scanner.Err(); err != nil {
+		return nil, fmt.Errorf("unable to read journal: %w", err)
+	}
+	return done, nil
+}
+
+// openJournal opens a resume journal for appending
+func openJournal(path string) (*os.File, error) {
+	if err := os.MkdirAll(filepath.Dir(path), 0700); err != nil {
+		return nil, fmt.Errorf("unable to create state directory: %w", err)
+	}
+	f, err := os.OpenFile(path, os.O_CREATE|os.O_APPEND|os.O_WRONLY, 0600)
+	if err != nil {
+		return nil, fmt.Errorf("unable to open journal: %w", err)
+	}
+	return f, nil
+}
@@ -0,0 +1,181 @@
+package gml
+
+import (
+	"context"
+	"errors"
+	"fmt"
+	"io"
+	"net/url"
+	"os"
+	"path/filepath"
+	"strings"
+
+	"cloud.google.com/go/storage"
+	awsconfig "github.com/aws/aws-sdk-go-v2/config"
+	"github.com/aws/aws-sdk-go-v2/feature/s3/manager"
+	"github.com/aws/aws-sdk-go-v2/service/s3"
+)
+
+// errOutputAborted is reported to object storage uploads cancelled by Abort
+var errOutputAborted = errors.New("output aborted")
+
+// Output is a destination for exported data: a local file, or an s3:// or gs://
+// object streamed to object storage as it is written
+// Close commits the output; Abort discards it and is a no-op after Close
+type Output struct {
+	io.Writer
+	commit func() error
+	abort  func()
+	done   bool
+}
+
+// Close finishes writing and commits the output
+func (o *Output) Close() error {
+	if o.done {
+		return nil
+	}
+	o.done = true
+	return o.commit()
+}
+
+// Abort discards a partially written output
+func (o *Output) Abort() {
+	if o.done {
+		return
+	}
+	o.done = true
+	o.abort()
+}
+
+// IsRemoteOutput reports whether dest is an object storage URL
+func IsRemoteOutput(dest string) bool {
+	return strings.HasPrefix(dest, "s3://") || strings.HasPrefix(dest, "gs://")
+}
+
+// CreateOutput opens an output for a local path or an s3://bucket/key or
+// gs://bucket/object URL. S3 uses the default AWS credential chain and
+// multipart uploads; GCS uses Application Default Credentials and resumable uploads.
+func CreateOutput(ctx context.Context, dest string) (*Output, error) {
+	if !IsRemoteOutput(dest) {
+		return createFileOutput(dest)
+	}
+
+	u, err := url.Parse(dest)
+	if err != nil {
+		return nil, fmt.Errorf("invalid output URL: %w", err)
+	}
+	bucket, key := u.Host, strings.TrimPrefix(u.Path, "/")
+	if bucket == "" || key == "" {
+		return nil, fmt.Errorf("output URL must include bucket and object name: %s", dest)
+	}
+
+	switch u.Scheme {
+	case "s3":
+		return createS3Output(ctx, bucket, key)
+	default:
+		return createGCSOutput(ctx, bucket, key)
+	}
+}
+
+// createFileOutput writes to a temporary file next to path and renames it
+// over path on Close, so an aborted export leaves the previous file in place.
+// Paths that exist but aren't regular files, e.g. /dev/stdout, are written
+// directly
+func createFileOutput(path string) (*Output, error) {
+	if info, err := os.Stat(path); err == nil && !info.Mode().IsRegular() {
+		f, err := os.Create(path)
+		if err != nil {
+			return nil, fmt.Errorf("unable to create output file: %w", err)
+		}
+		return &Output{Writer: f, commit: f.Close, abort: func() { f.Close() }}, nil
+	}
+
+	// CreateTemp uses mode 0600
+	tmp, err := os.CreateTemp(filepath.Dir(path), ".gml-*")
+	if err != nil {
+		return nil, fmt.Errorf("unable to create output file: %w", err)
+	}
+	return &Output{
+		Writer: tmp,
+		commit: func() error {
+			err := tmp.Close()
+			if err == nil {
+				err = os.Rename(tmp.Name(), path)
+			}
+			if err != nil {
+				os.Remove(tmp.Name())
+				return fmt.Errorf("unable to write output file: %w", err)
+			}
+			return nil
+		},
+		abort: func() {
+			tmp.Close()
+			os.Remove(tmp.Name())
+		},
+	}, nil
+}
+
+// createS3Output streams writes to an S3 multipart upload through a pipe
+func createS3Output(ctx context.Context, bucket, key string) (*Output, error) {
+	cfg, err := awsconfig.LoadDefaultConfig(ctx)
+	if err != nil {
+		return nil, fmt.Errorf("unable to load AWS config: %w", err)
+	}
+	uploader := manager.NewUploader(s3.NewFromConfig(cfg))
+
+	pr, pw := io.Pipe()
+	done := make(chan error, 1)
+	go func() {
+		_, err := uploader.Upload(ctx, &s3.PutObjectInput{
+			Bucket: &bucket,
+			Key:    &key,
+			Body:   pr,
+		})
+		pr.CloseWithError(err)
+		done <- err
+	}()
+
+	return &Output{
+		Writer: pw,
+		commit: func() error {
+			pw.Close()
+			if err := <-done; err != nil {
+				return fmt.Errorf("unable to upload to s3://%s/%s: %w", bucket, key, err)
+			}
+			return nil
+		},
+		abort: func() {
+			pw.CloseWithError(errOutputAborted)
+			<-done
+		},
+	}, nil
+}
+
+// createGCSOutput streams writes to a GCS resumable upload
+func createGCSOutput(ctx context.Context, bucket, object string) (*Output, error) {
+	client, err := storage.NewClient(ctx)
+	if err != nil {
+		return nil, fmt.Errorf("unable to create GCS client: %w", err)
+	}
+
+	// Cancelling the writer's context aborts the upload
+	ctx, cancel := context.WithCancel(ctx)
+	w := client.Bucket(bucket).Object(object).NewWriter(ctx)
+
+	return &Output{
+		Writer: w,
+		commit: func() error {
+			defer cancel()
+			defer client.Close()
+			if err := w.Close(); err != nil {
+				return fmt.Errorf("unable to upload to gs://%s/%s: %w", bucket, object, err)
+			}
+			return nil
+		},
+		abort: func() {
+			cancel()
+			w.Close()
+			client.Close()
+		},
+	}, nil
+}
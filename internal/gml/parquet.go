@@ -0,0 +1,61 @@
+package gml
+
+import (
+	"fmt"
+	"io"
+
+	"github.com/parquet-go/parquet-go"
+	"github.com/parquet-go/parquet-go/compress/zstd"
+)
+
+// parquetMessage defines the columns written by ExportMessagesParquet
+type parquetMessage struct {
+	Account  string   `parquet:"account"`
+	ID       string   `parquet:"id"`
+	ThreadID string   `parquet:"thread_id"`
+	URL      string   `parquet:"url"`
+	From     string   `parquet:"sender"`
+	To       string   `parquet:"recipient"`
+	Subject  string   `parquet:"subject"`
+	Date     string   `parquet:"date"`
+	Snippet  string   `parquet:"snippet"`
+	Labels   []string `parquet:"labels,list"`
+	Body     *string  `parquet:"body,optional"`
+}
+
+// MetadataFields lists every message field except body
+var MetadataFields = []string{"id", "threadid", "url", "from", "to", "subject", "date", "labels", "snippet"}
+
+// ExportMessagesParquet writes messages as a zstd-compressed Parquet file
+func ExportMessagesParquet(w io.Writer, messages []MessageInfo) error {
+	writer := parquet.NewGenericWriter[parquetMessage](w, parquet.Compression(&zstd.Codec{}))
+
+	rows := make([]parquetMessage, 0, len(messages))
+	for _, msg := range messages {
+		row := parquetMessage{
+			Account:  msg.Account,
+			ID:       msg.ID,
+			ThreadID: msg.ThreadID,
+			URL:      msg.URL,
+			From:     msg.From,
+			To:       msg.To,
+			Subject:  msg.Subject,
+			Date:     msg.Date,
+			Snippet:  msg.Snippet,
+			Labels:   msg.Labels,
+		}
+		if msg.Body != "" {
+			body := msg.Body
+			row.Body = &body
+		}
+		rows = append(rows, row)
+	}
+
+	if _, err := writer.Write(rows); err != nil {
+		return fmt.Errorf("unable to write parquet rows: %w", err)
+	}
+	if err := writer.Close(); err != nil {
+		return fmt.Errorf("unable to finish parquet file: %w", err)
+	}
+	return nil
+}
@@ -0,0 +1,362 @@
+package gml
+
+import (
+	"bytes"
+	"context"
+	"encoding/json"
+	"fmt"
+	"io"
+	"regexp"
+	"strings"
+	"text/template"
+	"time"
+)
+
+// PipelineStepType identifies a built-in pipeline step
+type PipelineStepType string
+
+const (
+	// StepSearch replaces the current message set with a query's results
+	StepSearch PipelineStepType = "search"
+	// StepFilter keeps messages whose field contains or matches a pattern
+	StepFilter PipelineStepType = "filter"
+	// StepAction adds or removes labels on the current messages
+	StepAction PipelineStepType = "action"
+	// StepNotify runs a command per message or emails a summary
+	StepNotify PipelineStepType = "notify"
+	// StepPrint writes the current messages as JSON lines
+	StepPrint PipelineStepType = "print"
+)
+
+// pipelineFields are the message fields available to pipeline steps
+const pipelineFields = "id,threadid,url,from,to,subject,date,labels,snippet"
+
+// PipelineConfig holds the definition of a named pipeline in config
+type PipelineConfig struct {
+	Description string            `mapstructure:"description"`
+	Params      map[string]string `mapstructure:"params"`
+	Steps       []PipelineStep    `mapstructure:"steps"`
+}
+
+// PipelineStep is one step of a pipeline
+// String fields are Go templates rendered with .Params, .Messages, .Count and .Now
+// (and .Message for per-message notify commands)
+type PipelineStep struct {
+	Type PipelineStepType `mapstructure:"type"`
+
+	// search
+	Query  string   `mapstructure:"query"`
+	Labels []string `mapstructure:"labels"`
+	Limit  int      `mapstructure:"limit"`
+
+	// filter
+	Field    string `mapstructure:"field"`
+	Contains string `mapstructure:"contains"`
+	Matches  string `mapstructure:"matches"`
+	Exclude  bool   `mapstructure:"exclude"`
+
+	// action
+	AddLabels    []string `mapstructure:"add_labels"`
+	RemoveLabels []string `mapstructure:"remove_labels"`
+
+	// notify
+	Exec    string   `mapstructure:"exec"`
+	Email   []string `mapstructure:"email"`
+	Subject string   `mapstructure:"subject"`
+	Body    string   `mapstructure:"body"`
+}
+
+// PipelineOptions contains options for running a pipeline
+type PipelineOptions struct {
+	// Params override the pipeline's default parameters
+	Params map[string]string
+	// DryRun skips action and notify steps
+	DryRun bool
+	// Failures collects messages whose action or notify command failed;
+	// nil stops the pipeline at the first failure
+	Failures *FailureLog
+	Stdout   io.Writer
+	Stderr   io.Writer
+}
+
+// PipelineData is the template data available to pipeline steps
+type PipelineData struct {
+	Params   map[string]string
+	Messages []MessageInfo
+	Count    int
+	Now      time.Time
+	Message  MessageInfo
+}
+
+// defaultNotifyBody is used for notify emails without a body template
+const defaultNotifyBody = `{{.Count}} message(s):
+{{range .Messages}}
+- {{.From}}: {{.Subject}}
+  {{.URL}}{{end}}
+`
+
+// RunPipeline executes the pipeline steps in order, passing the current message
+// set from step to step; progress is reported on opts.Stderr
+func RunPipeline(ctx context.Context, svc *Service, pc PipelineConfig, opts PipelineOptions) ([]MessageInfo, error) {
+	params := make(map[string]string)
+	for k, v := range pc.Params {
+		params[k] = v
+	}
+	for k, v := range opts.Params {
+		params[k] = v
+	}
+
+	data := PipelineData{Params: params, Now: time.Now()}
+	var idx *LabelIndex
+	for i, step := range pc.Steps {
+		data.Count = len(data.Messages)
+		prefix := fmt.Sprintf("step %d (%s)", i+1, step.Type)
+
+		switch step.Type {
+		case StepSearch:
+			query, err := renderTemplate(step.Query, data)
+			if err != nil {
+				return nil, fmt.Errorf("%s: %w", prefix, err)
+			}
+			labels, err := renderTemplates(step.Labels, data)
+			if err != nil {
+				return nil, fmt.Errorf("%s: %w", prefix, err)
+			}
+			messages, err := ListMessages(ctx, svc, ListMessagesOptions{
+				Query:      query,
+				MaxResults: 500,
+				LabelIDs:   labels,
+				Fields:     ParseFields(pipelineFields),
+			})
+			if err != nil {
+				return nil, fmt.Errorf("%s: %w", prefix, err)
+			}
+			if step.Limit > 0 && len(messages) > step.Limit {
+				messages = messages[:step.Limit]
+			}
+			data.Messages = messages
+			fmt.Fprintf(opts.Stderr, "%s: %d messages\n", prefix, len(messages))
+
+		case StepFilter:
+			kept, err := filterMessages(data.Messages, step, data)
+			if err != nil {
+				return nil, fmt.Errorf("%s: %w", prefix, err)
+			}
+			data.Messages = kept
+			fmt.Fprintf(opts.Stderr, "%s: %d messages\n", prefix, len(kept))
+
+		case StepAction:
+			if len(data.Messages) == 0 {
+				continue
+			}
+			if opts.DryRun {
+				fmt.Fprintf(opts.Stderr, "%s: would modify %d messages\n", prefix, len(data.Messages))
+				continue
+			}
+			if idx == nil {
+				var err error
+				if idx, err = FetchLabelIndex(ctx, svc); err != nil {
+					return nil, fmt.Errorf("%s: %w", prefix, err)
+				}
+			}
+			if err := pipelineAction(ctx, svc, idx, step, data, opts.Failures); err != nil {
+				return nil, fmt.Errorf("%s: %w", prefix, err)
+			}
+			fmt.Fprintf(opts.Stderr, "%s: modified %d messages\n", prefix, len(data.Messages))
+
+		case StepNotify:
+			if len(data.Messages) == 0 {
+				continue
+			}
+			if opts.DryRun {
+				fmt.Fprintf(opts.Stderr, "%s: would notify for %d messages\n", prefix, len(data.Messages))
+				continue
+			}
+			if err := pipelineNotify(ctx, svc, step, data, opts); err != nil {
+				return nil, fmt.Errorf("%s: %w", prefix, err)
+			}
+			fmt.Fprintf(opts.Stderr, "%s: notified for %d messages\n", prefix, len(data.Messages))
+
+		case StepPrint:
+			enc := json.NewEncoder(opts.Stdout)
+			for _, msg := range data.Messages {
+				if err := enc.Encode(msg); err != nil {
+					return nil, fmt.Errorf("%s: unable to write message: %w", prefix, err)
+				}
+			}
+
+		default:
+			return nil, fmt.Errorf("%s: unknown step type", prefix)
+		}
+	}
+
+	return data.Messages, nil
+}
+
+// filterMessages keeps messages whose field contains (case-insensitively) or
+// matches the step's pattern, or the opposite with Exclude
+func filterMessages(messages []MessageInfo, step PipelineStep, data PipelineData) ([]MessageInfo, error) {
+	contains, err := renderTemplate(step.Contains, data)
+	if err != nil {
+		return nil, err
+	}
+	var re *regexp.Regexp
+	if step.Matches != "" {
+		pattern, err := renderTemplate(step.Matches, data)
+		if err != nil {
+			return nil, err
+		}
+		if re, err = regexp.Compile(pattern); err != nil {
+			return nil, fmt.Errorf("invalid pattern: %w", err)
+		}
+	}
+	if contains == "" && re == nil {
+		return nil, fmt.Errorf("filter requires contains or matches")
+	}
+
+	kept := []MessageInfo{}
+	for _, msg := range messages {
+		value, err := messageField(msg, step.Field)
+		if err != nil {
+			return nil, err
+		}
+		ok := true
+		if contains != "" {
+			ok = strings.Contains(strings.ToLower(value), strings.ToLower(contains))
+		}
+		if ok && re != nil {
+			ok = re.MatchString(value)
+		}
+		if ok != step.Exclude {
+			kept = append(kept, msg)
+		}
+	}
+	return kept, nil
+}
+
+// messageField returns a message field as text for filtering
+func messageField(msg MessageInfo, field string) (string, error) {
+	switch strings.ToLower(field) {
+	case "from":
+		return msg.From, nil
+	case "to":
+		return msg.To, nil
+	case "subject", "":
+		return msg.Subject, nil
+	case "snippet":
+		return msg.Snippet, nil
+	case "labels":
+		return strings.Join(msg.Labels, ","), nil
+	default:
+		return "", fmt.Errorf("unsupported filter field: %s (use from, to, subject, snippet or labels)", field)
+	}
+}
+
+// pipelineAction applies label changes to the current messages
+func pipelineAction(ctx context.Context, svc *Service, idx *LabelIndex, step PipelineStep, data PipelineData, failures *FailureLog) error {
+	addNames, err := renderTemplates(step.AddLabels, data)
+	if err != nil {
+		return err
+	}
+	removeNames, err := renderTemplates(step.RemoveLabels, data)
+	if err != nil {
+		return err
+	}
+	if len(addNames) == 0 && len(removeNames) == 0 {
+		return fmt.Errorf("action requires add_labels or remove_labels")
+	}
+
+	addIDs, err := idx.EnsureLabelIDs(ctx, svc, addNames)
+	if err != nil {
+		return err
+	}
+	removeIDs, err := idx.ResolveLabelIDs(removeNames)
+	if err != nil {
+		return err
+	}
+
+	ids := make([]string, len(data.Messages))
+	for i, msg := range data.Messages {
+		ids[i] = msg.ID
+	}
+	return batchModify(ctx, svc, ids, addIDs, removeIDs, failures)
+}
+
+// pipelineNotify runs the exec command per message and/or emails a summary
+func pipelineNotify(ctx context.Context, svc *Service, step PipelineStep, data PipelineData, opts PipelineOptions) error {
+	if step.Exec == "" && len(step.Email) == 0 {
+		return fmt.Errorf("notify requires exec or email")
+	}
+
+	if step.Exec != "" {
+		for _, msg := range data.Messages {
+			data.Message = msg
+			command, err := renderTemplate(step.Exec, data)
+			if err != nil {
+				return err
+			}
+			if err := RunMessageHook(ctx, command, msg, opts.Stdout, opts.Stderr); err != nil {
+				if err := opts.Failures.Record(err, msg.ID); err != nil {
+					return err
+				}
+			}
+		}
+		data.Message = MessageInfo{}
+	}
+
+	if len(step.Email) > 0 {
+		to, err := renderTemplates(step.Email, data)
+		if err != nil {
+			return err
+		}
+		subjectTmpl := step.Subject
+		if subjectTmpl == "" {
+			subjectTmpl = "gml: {{.Count}} message(s)"
+		}
+		subject, err := renderTemplate(subjectTmpl, data)
+		if err != nil {
+			return err
+		}
+		bodyTmpl := step.Body
+		if bodyTmpl == "" {
+			bodyTmpl = defaultNotifyBody
+		}
+		body, err := renderTemplate(bodyTmpl, data)
+		if err != nil {
+			return err
+		}
+		if _, err := SendMessage(ctx, svc, &OutgoingMessage{To: to, Subject: subject, Body: body}); err != nil {
+			return err
+		}
+	}
+	return nil
+}
+
+// renderTemplate renders a Go template string with pipeline data
+func renderTemplate(text string, data PipelineData) (string, error) {
+	if !strings.Contains(text, "{{") {
+		return text, nil
+	}
+	tmpl, err := template.New("step").Option("missingkey=error").Parse(text)
+	if err != nil {
+		return "", fmt.Errorf("invalid template %q: %w", text, err)
+	}
+	var buf bytes.Buffer
+	if err := tmpl.Execute(&buf, data); err != nil {
+		return "", fmt.Errorf("unable to render template %q: %w", text, err)
+	}
+	return buf.String(), nil
+}
+
+// renderTemplates renders each template string in a slice
+func renderTemplates(texts []string, data PipelineData) ([]string, error) {
+	var rendered []string
+	for _, text := range texts {
+		s, err := renderTemplate(text, data)
+		if err != nil {
+			return nil, err
+		}
+		rendered = append(rendered, s)
+	}
+	return rendered, nil
+}
@@ -0,0 +1,57 @@
+package gml
+
+import (
+	"context"
+	"fmt"
+	"io"
+)
+
+// Profile holds the Gmail profile of the authenticated user
+type Profile struct {
+	Account       string `json:"account,omitempty"`
+	EmailAddress  string `json:"emailAddress"`
+	MessagesTotal int64  `json:"messagesTotal"`
+	ThreadsTotal  int64  `json:"threadsTotal"`
+	// HistoryID is the mailbox's current history record, the starting point for
+	// 'gml watch' and history-based sync
+	HistoryID uint64 `json:"historyId"`
+}
+
+// GetProfile retrieves the profile of the authenticated user
+func GetProfile(ctx context.Context, svc *Service) (*Profile, error) {
+	p, err := svc.API.GetProfile(ctx)
+	if err != nil {
+		return nil, fmt.Errorf("unable to get user profile: %w", apiError(err))
+	}
+	return &Profile{
+		EmailAddress:  p.EmailAddress,
+		MessagesTotal: p.MessagesTotal,
+		ThreadsTotal:  p.ThreadsTotal,
+		HistoryID:     p.HistoryId,
+	}, nil
+}
+
+// FormatProfiles outputs profiles in the specified format. JSON is a single
+// object for one untagged profile and an array across accounts
+func FormatProfiles(w io.Writer, profiles []Profile, format OutputFormat) error {
+	if format == OutputFormatJSON {
+		if len(profiles) == 1 && profiles[0].Account == "" {
+			return FormatJSON(w, profiles[0])
+		}
+		return FormatJSON(w, profiles)
+	}
+
+	for i, p := range profiles {
+		if i > 0 {
+			fmt.Fprintln(w)
+		}
+		if p.Account != "" {
+			fmt.Fprintf(w, "Account: %s\n", p.Account)
+		}
+		fmt.Fprintf(w, "Email: %s\n", p.EmailAddress)
+		fmt.Fprintf(w, "Messages: %d\n", p.MessagesTotal)
+		fmt.Fprintf(w, "Threads: %d\n", p.ThreadsTotal)
+		fmt.Fprintf(w, "History ID: %d\n", p.HistoryID)
+	}
+	return nil
+}
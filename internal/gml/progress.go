@@ -0,0 +1,117 @@
+package gml
+
+import (
+	"fmt"
+	"io"
+	"strings"
+	"sync"
+	"time"
+)
+
+// Progress reports how far a long operation has come
+// Update may be called often; implementations throttle their output
+type Progress interface {
+	// Update records that done of total items are processed (total 0 if unknown)
+	Update(done, total int)
+	// Done finishes the report, e.g. clears the bar
+	Done()
+}
+
+// ProgressMode selects how progress is shown
+type ProgressMode int
+
+const (
+	// ProgressNone shows nothing
+	ProgressNone ProgressMode = iota
+	// ProgressBar redraws a bar in place, for terminals
+	ProgressBar
+	// ProgressLog writes a line now and then, for logs
+	ProgressLog
+)
+
+const (
+	// progressBarInterval is the minimum time between bar redraws
+	progressBarInterval = 100 * time.Millisecond
+	// progressLogInterval is the minimum time between log lines
+	progressLogInterval = 5 * time.Second
+	// progressBarWidth is the number of cells of the bar itself
+	progressBarWidth = 30
+)
+
+// NewProgress returns a Progress writing to w in the given mode; title
+// names the operation, e.g. "Fetching messages"
+func NewProgress(w io.Writer, title string, mode ProgressMode) Progress {
+	switch mode {
+	case ProgressBar:
+		return &progressReporter{w: w, title: title, bar: true, interval: progressBarInterval}
+	case ProgressLog:
+		return &progressReporter{w: w, title: title, interval: progressLogInterval}
+	}
+	return noProgress{}
+}
+
+// noProgress discards progress
+type noProgress struct{}
+
+func (noProgress) Update(done, total int) {}
+func (noProgress) Done()                  {}
+
+// progressReporter renders a bar or log lines, throttled to interval
+type progressReporter struct {
+	w        io.Writer
+	title    string
+	bar      bool
+	interval time.Duration
+
+	mu          sync.Mutex
+	done, total int
+	last        time.Time
+	drawn       bool
+}
+
+func (p *progressReporter) Update(done, total int) {
+	p.mu.Lock()
+	defer p.mu.Unlock()
+	p.done, p.total = done, total
+
+	// Always show completion; otherwise throttle
+	now := time.Now()
+	if done != total && now.Sub(p.last) < p.interval {
+		return
+	}
+	p.last = now
+	p.render()
+}
+
+func (p *progressReporter) Done() {
+	p.mu.Lock()
+	defer p.mu.Unlock()
+	if p.bar && p.drawn {
+		// Clear the bar so following output starts on a clean line
+		fmt.Fprint(p.w, "\r\033[K")
+	}
+	p.drawn = false
+}
+
+// render writes the current state; callers hold the lock
+func (p *progressReporter) render() {
+	count := fmt.Sprintf("%d", p.done)
+	if p.total > 0 {
+		count = fmt.Sprintf("%d/%d", p.done, p.total)
+	}
+
+	if !p.bar {
+		fmt.Fprintf(p.w, "%s: %s\n", p.title, count)
+		return
+	}
+
+	line := p.title + " " + count
+	if p.total > 0 {
+		filled := min(progressBarWidth, p.done*progressBarWidth/p.total)
+		line = fmt.Sprintf("%s [%s%s] %3d%% %s", p.title,
+			strings.Repeat("=", filled), strings.Repeat(" ", progressBarWidth-filled),
+			min(100, p.done*100/p.total), count)
+	}
+	fmt.Fprint(p.w, "\r\033[K"+line)
+	p.drawn = true
+}
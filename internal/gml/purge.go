@@ -0,0 +1,90 @@
+package gml
+
+import (
+	"context"
+	"fmt"
+
+	"google.golang.org/api/gmail/v1"
+)
+
+// batchDeleteLimit is the maximum number of IDs accepted by Users.Messages.BatchDelete
+const batchDeleteLimit = 1000
+
+// PurgeOptions selects the messages to delete permanently
+type PurgeOptions struct {
+	// Query and LabelIDs select messages like 'gml list'; an empty selection
+	// with neither Trash nor Spam matches nothing
+	Query    string
+	LabelIDs []string
+	// Trash and Spam add every message in the trash or spam
+	Trash bool
+	Spam  bool
+}
+
+// FindPurgeMessages returns the IDs of the messages selected by opts
+func FindPurgeMessages(ctx context.Context, svc *Service, opts PurgeOptions) ([]string, error) {
+	seen := make(map[string]bool)
+	var ids []string
+	collect := func(query string, labelIDs []string) error {
+		found, err := ListMessageIDs(ctx, svc, query, labelIDs)
+		if err != nil {
+			return err
+		}
+		for _, id := range found {
+			if !seen[id] {
+				seen[id] = true
+				ids = append(ids, id)
+			}
+		}
+		return nil
+	}
+
+	if opts.Query != "" || len(opts.LabelIDs) > 0 {
+		var filterIDs []string
+		if len(opts.LabelIDs) > 0 {
+			idx, err := FetchLabelIndex(ctx, svc)
+			if err != nil {
+				return nil, err
+			}
+			if filterIDs, err = idx.ResolveLabelIDs(opts.LabelIDs); err != nil {
+				return nil, err
+			}
+		}
+		if err := collect(opts.Query, filterIDs); err != nil {
+			return nil, err
+		}
+	}
+	if opts.Trash {
+		if err := collect("", []string{"TRASH"}); err != nil {
+			return nil, err
+		}
+	}
+	if opts.Spam {
+		if err := collect("", []string{LabelSpam}); err != nil {
+			return nil, err
+		}
+	}
+	return ids, nil
+}
+
+// PurgeMessages permanently deletes messages with BatchDelete, bypassing the
+// trash, and returns the number deleted. Progress, if set, is called after
+// each batch
+func PurgeMessages(ctx context.Context, svc *Service, ids []string, failures *FailureLog, progress func(done, total int)) (int, error) {
+	deleted := 0
+	for start := 0; start < len(ids); start += batchDeleteLimit {
+		end := min(start+batchDeleteLimit, len(ids))
+		req := &gmail.BatchDeleteMessagesRequest{Ids: ids[start:end]}
+		if err := svc.Gmail.Users.Messages.BatchDelete("me", req).Context(ctx).Do(); err != nil {
+			if err := failures.Record(fmt.Errorf("unable to delete messages: %w", apiError(err)), ids[start:end]...); err != nil {
+				return deleted, err
+			}
+		} else {
+			deleted += end - start
+		}
+		if progress != nil {
+			progress(end, len(ids))
+		}
+	}
+	return deleted, nil
+}
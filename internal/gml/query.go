@@ -0,0 +1,259 @@
+package gml
+
+import (
+	"fmt"
+	"strings"
+	"time"
+	"unicode"
+	"unicode/utf8"
+)
+
+// AgeQuery appends before:/after: terms for the given age bounds to a Gmail query
+// Ages are converted to epoch seconds so every ParseAge unit behaves the same;
+// a zero duration leaves that bound unset
+func AgeQuery(query string, olderThan, newerThan time.Duration, now time.Time) string {
+	return NewQueryBuilder(query).OlderThan(olderThan, now).NewerThan(newerThan, now).String()
+}
+
+// QueryBuilder composes a Gmail search query from a raw query and structured
+// terms. Values are quoted when needed so they match literally: spaces,
+// operators (OR, -, parentheses) and colons can't change the query's meaning
+type QueryBuilder struct {
+	terms []string
+}
+
+// NewQueryBuilder starts a query from raw Gmail search syntax, used verbatim
+func NewQueryBuilder(query string) *QueryBuilder {
+	b := &QueryBuilder{}
+	if q := strings.TrimSpace(query); q != "" {
+		b.terms = append(b.terms, q)
+	}
+	return b
+}
+
+// term adds operator:value, skipping empty values
+func (b *QueryBuilder) term(operator, value string) *QueryBuilder {
+	if value = strings.TrimSpace(value); value != "" {
+		b.terms = append(b.terms, operator+":"+QuoteQueryValue(value))
+	}
+	return b
+}
+
+// From matches the sender
+func (b *QueryBuilder) From(value string) *QueryBuilder { return b.term("from", value) }
+
+// To matches a recipient
+func (b *QueryBuilder) To(value string) *QueryBuilder { return b.term("to", value) }
+
+// Subject matches words or a phrase in the subject
+func (b *QueryBuilder) Subject(value string) *QueryBuilder { return b.term("subject", value) }
+
+// In restricts the search to a location such as inbox, sent or anywhere
+func (b *QueryBuilder) In(value string) *QueryBuilder { return b.term("in", value) }
+
+// Label matches messages with the label, by name. Names with anything but
+// ASCII letters, digits, -, _ and . are quoted, so nested labels, spaces and
+// non-ASCII names match the label exactly
+func (b *QueryBuilder) Label(name string) *QueryBuilder {
+	if name = strings.TrimSpace(name); name == "" {
+		return b
+	}
+	value := QuoteQueryValue(name)
+	plain := func(r rune) bool {
+		return r == '-' || r == '_' || r == '.' || r < utf8.RuneSelf && (unicode.IsLetter(r) || unicode.IsDigit(r))
+	}
+	if !strings.HasPrefix(value, `"`) && strings.IndexFunc(value, func(r rune) bool { return !plain(r) }) >= 0 {
+		value = `"` + value + `"`
+	}
+	b.terms = append(b.terms, "label:"+value)
+	return b
+}
+
+// After matches messages received after t (epoch seconds, so the local day
+// boundary is kept)
+func (b *QueryBuilder) After(t time.Time) *QueryBuilder {
+	if !t.IsZero() {
+		b.terms = append(b.terms, fmt.Sprintf("after:%d", t.Unix()))
+	}
+	return b
+}
+
+// Before matches messages received before t
+func (b *QueryBuilder) Before(t time.Time) *QueryBuilder {
+	if !t.IsZero() {
+		b.terms = append(b.terms, fmt.Sprintf("before:%d", t.Unix()))
+	}
+	return b
+}
+
+// NewerThan matches messages younger than age; zero leaves the bound unset
+func (b *QueryBuilder) NewerThan(age time.Duration, now time.Time) *QueryBuilder {
+	if age > 0 {
+		b.After(now.Add(-age))
+	}
+	return b
+}
+
+// OlderThan matches messages older than age; zero leaves the bound unset
+func (b *QueryBuilder) OlderThan(age time.Duration, now time.Time) *QueryBuilder {
+	if age > 0 {
+		b.Before(now.Add(-age))
+	}
+	return b
+}
+
+// Larger matches messages bigger than size bytes; zero leaves the bound unset
+func (b *QueryBuilder) Larger(size int64) *QueryBuilder {
+	if size > 0 {
+		b.terms = append(b.terms, fmt.Sprintf("larger:%d", size))
+	}
+	return b
+}
+
+// Smaller matches messages smaller than size bytes; zero leaves the bound unset
+func (b *QueryBuilder) Smaller(size int64) *QueryBuilder {
+	if size > 0 {
+		b.terms = append(b.terms, fmt.Sprintf("smaller:%d", size))
+	}
+	return b
+}
+
+// HasAttachment matches messages with attachments when set
+func (b *QueryBuilder) HasAttachment(set bool) *QueryBuilder {
+	if set {
+		b.terms = append(b.terms, "has:attachment")
+	}
+	return b
+}
+
+// Unread matches unread messages only when set
+func (b *QueryBuilder) Unread(set bool) *QueryBuilder {
+	if set {
+		b.terms = append(b.terms, "is:unread")
+	}
+	return b
+}
+
+// String returns the query, terms separated by spaces (an implicit AND)
+func (b *QueryBuilder) String() string {
+	return strings.Join(b.terms, " ")
+}
+
+// QuoteQueryValue returns value as a single Gmail search term: values with
+// spaces or characters Gmail treats as syntax are wrapped in double quotes.
+// Gmail has no escape for a double quote inside a phrase, so those become spaces
+func QuoteQueryValue(value string) string {
+	value = strings.Join(strings.Fields(strings.ReplaceAll(value, `"`, " ")), " ")
+	if value == "" {
+		return `""`
+	}
+	if strings.ContainsAny(value, ` (){}:"`) || strings.HasPrefix(value, "-") || strings.HasPrefix(value, "+") ||
+		value == "OR" || value == "AND" || value == "AROUND" {
+		return `"` + value + `"`
+	}
+	return value
+}
+
+// ParseQueryDate parses a date for after:/before: bounds: YYYY-MM-DD or
+// YYYY/MM/DD (midnight local time) or RFC 3339
+func ParseQueryDate(s string) (time.Time, error) {
+	for _, layout := range []string{"2006-01-02", "2006/01/02"} {
+		if t, err := time.ParseInLocation(layout, s, time.Local); err == nil {
+			return t, nil
+		}
+	}
+	if t, err := time.Parse(time.RFC3339, s); err == nil {
+		return t, nil
+	}
+	return time.Time{}, fmt.Errorf("invalid date %q (use YYYY-MM-DD)", s)
+}
+
+// inLabels maps in: query locations to the system labels they select
+var inLabels = map[string]string{
+	"inbox":     "INBOX",
+	"sent":      "SENT",
+	"draft":     "DRAFT",
+	"drafts":    "DRAFT",
+	"spam":      "SPAM",
+	"trash":     "TRASH",
+	"starred":   "STARRED",
+	"important": "IMPORTANT",
+	"chats":     "CHAT",
+}
+
+// ExtractLabelTerms moves top-level label: and in: terms out of a Gmail query,
+// returning the remaining query and the label names (or system label IDs)
+// Listing label IDs is exact for labels with spaces and cheaper than searching;
+// queries using OR or {} groups are returned unchanged since moving terms
+// would change their meaning
+func ExtractLabelTerms(query string) (string, []string) {
+	tokens := splitQuery(query)
+	for _, t := range tokens {
+		if t == "OR" || strings.HasPrefix(t, "{") {
+			return query, nil
+		}
+	}
+
+	var rest, labels []string
+	for _, t := range tokens {
+		key, value, ok := strings.Cut(t, ":")
+		if !ok || value == "" {
+			rest = append(rest, t)
+			continue
+		}
+		value = unquote(value)
+		switch strings.ToLower(key) {
+		case "label":
+			labels = append(labels, value)
+		case "in":
+			id, ok := inLabels[strings.ToLower(value)]
+			if !ok {
+				rest = append(rest, t)
+				continue
+			}
+			labels = append(labels, id)
+		default:
+			rest = append(rest, t)
+		}
+	}
+	return strings.Join(rest, " "), labels
+}
+
+// splitQuery splits a Gmail query into whitespace-separated terms, keeping
+// quoted strings and (), {} groups intact
+func splitQuery(query string) []string {
+	var tokens []string
+	var current strings.Builder
+	depth := 0
+	inQuote := false
+	for _, r := range query {
+		switch {
+		case r == '"':
+			inQuote = !inQuote
+		case inQuote:
+		case r == '(' || r == '{':
+			depth++
+		case (r == ')' || r == '}') && depth > 0:
+			depth--
+		case (r == ' ' || r == '\t' || r == '\n') && depth == 0:
+			if current.Len() > 0 {
+				tokens = append(tokens, current.String())
+				current.Reset()
+			}
+			continue
+		}
+		current.WriteRune(r)
+	}
+	if current.Len() > 0 {
+		tokens = append(tokens, current.String())
+	}
+	return tokens
+}
+
+// unquote strips surrounding double quotes from a query value
+func unquote(s string) string {
+	if len(s) >= 2 && s[0] == '"' && s[len(s)-1] == '"' {
+		return s[1 : len(s)-1]
+	}
+	return s
+}
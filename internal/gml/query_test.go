@@ -0,0 +1,214 @@
+package gml
+
+import (
+	"context"
+	"slices"
+	"testing"
+	"time"
+
+	"github.com/longkey1/gml/internal/fakegmail"
+)
+
+func TestQuoteQueryValue(t *testing.T) {
+	tests := []struct {
+		name  string
+		value string
+		want  string
+	}{
+		{"plain", "alice@example.com", "alice@example.com"},
+		{"spaces", "weekly report", `"weekly report"`},
+		{"repeated spaces", "  weekly   report ", `"weekly report"`},
+		{"embedded quote", `say "hi"`, `"say hi"`},
+		{"only quotes", `""`, `""`},
+		{"leading minus", "-spam", `"-spam"`},
+		{"inner minus", "re-send", "re-send"},
+		{"leading plus", "+exact", `"+exact"`},
+		{"parentheses", "(draft)", `"(draft)"`},
+		{"braces", "{a b}", `"{a b}"`},
+		{"colon", "subject:hi", `"subject:hi"`},
+		{"OR", "OR", `"OR"`},
+		{"AND", "AND", `"AND"`},
+		{"AROUND", "AROUND", `"AROUND"`},
+		{"lowercase or", "or", "or"},
+		{"empty", "", `""`},
+		{"whitespace only", " \t ", `""`},
+	}
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			if got := QuoteQueryValue(tt.value); got != tt.want {
+				t.Errorf("QuoteQueryValue(%q) = %s, want %s", tt.value, got, tt.want)
+			}
+		})
+	}
+}
+
+func TestParseQueryDate(t *testing.T) {
+	midnight := time.Date(2025, 3, 14, 0, 0, 0, 0, time.Local)
+	tests := []struct {
+		value   string
+		want    time.Time
+		wantErr bool
+	}{
+		{value: "2025-03-14", want: midnight},
+		{value: "2025/03/14", want: midnight},
+		{value: "2025-03-14T09:30:00Z", want: time.Date(2025, 3, 14, 9, 30, 0, 0, time.UTC)},
+		{value: "2025-03-14T09:30:00+09:00", want: time.Date(2025, 3, 14, 0, 30, 0, 0, time.UTC)},
+		{value: "", wantErr: true},
+		{value: "14/03/2025", wantErr: true},
+		{value: "2025-13-01", wantErr: true},
+		{value: "yesterday", wantErr: true},
+	}
+	for _, tt := range tests {
+		t.Run(tt.value, func(t *testing.T) {
+			got, err := ParseQueryDate(tt.value)
+			if tt.wantErr {
+				if err == nil {
+					t.Fatalf("ParseQueryDate(%q) = %v, want an error", tt.value, got)
+				}
+				return
+			}
+			if err != nil {
+				t.Fatalf("ParseQueryDate(%q): %v", tt.value, err)
+			}
+			if !got.Equal(tt.want) {
+				t.Errorf("ParseQueryDate(%q) = %v, want %v", tt.value, got, tt.want)
+			}
+		})
+	}
+}
+
+func TestQueryBuilderString(t *testing.T) {
+	now := time.Unix(1_700_000_000, 0)
+	after := time.Unix(1_690_000_000, 0)
+	tests := []struct {
+		name  string
+		build func() *QueryBuilder
+		want  string
+	}{
+		{
+			name:  "empty",
+			build: func() *QueryBuilder { return NewQueryBuilder("") },
+			want:  "",
+		},
+		{
+			name:  "raw query only",
+			build: func() *QueryBuilder { return NewQueryBuilder("  from:bob OR from:carol ") },
+			want:  "from:bob OR from:carol",
+		},
+		{
+			name:  "whitespace-only values are skipped",
+			build: func() *QueryBuilder { return NewQueryBuilder(" ").From(" ").Subject("").In("\t") },
+			want:  "",
+		},
+		{
+			name: "quoted values",
+			build: func() *QueryBuilder {
+				return NewQueryBuilder("").From("Alice Smith").To("-team").Subject(`say "hi" (now)`).In("inbox")
+			},
+			want: `from:"Alice Smith" to:"-team" subject:"say hi (now)" in:inbox`,
+		},
+		{
+			name:  "operator words as values",
+			build: func() *QueryBuilder { return NewQueryBuilder("").Subject("OR").From("AROUND") },
+			want:  `subject:"OR" from:"AROUND"`,
+		},
+		{
+			name: "raw query with older-than",
+			build: func() *QueryBuilder {
+				return NewQueryBuilder("is:starred").OlderThan(24*time.Hour, now)
+			},
+			want: "is:starred before:1699913600",
+		},
+		{
+			name: "raw query with after and newer-than",
+			build: func() *QueryBuilder {
+				return NewQueryBuilder("label:work -in:chats").After(after).NewerThan(time.Hour, now)
+			},
+			want: "label:work -in:chats after:1690000000 after:1699996400",
+		},
+		{
+			name: "zero bounds are unset",
+			build: func() *QueryBuilder {
+				return NewQueryBuilder("x").After(time.Time{}).Before(time.Time{}).OlderThan(0, now).Larger(0).Smaller(0)
+			},
+			want: "x",
+		},
+		{
+			name: "flags",
+			build: func() *QueryBuilder {
+				return NewQueryBuilder("").Larger(1024).Smaller(2048).HasAttachment(true).Unread(true).HasAttachment(false)
+			},
+			want: "larger:1024 smaller:2048 has:attachment is:unread",
+		},
+	}
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			if got := tt.build().String(); got != tt.want {
+				t.Errorf("String() = %q, want %q", got, tt.want)
+			}
+		})
+	}
+}
+
+func TestAgeQuery(t *testing.T) {
+	now := time.Unix(1_700_000_000, 0)
+	after := NewQueryBuilder("from:bob -label:done").After(time.Unix(1_690_000_000, 0)).String()
+	tests := []struct {
+		name                 string
+		query                string
+		olderThan, newerThan time.Duration
+		want                 string
+	}{
+		{"no bounds", "from:bob", 0, 0, "from:bob"},
+		{"older-than", "from:bob", 48 * time.Hour, 0, "from:bob before:1699827200"},
+		{"both bounds", "", 48 * time.Hour, 72 * time.Hour, "before:1699827200 after:1699740800"},
+		{"raw query with after", after, 24 * time.Hour, 0, "from:bob -label:done after:1690000000 before:1699913600"},
+	}
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			if got := AgeQuery(tt.query, tt.olderThan, tt.newerThan, now); got != tt.want {
+				t.Errorf("AgeQuery() = %q, want %q", got, tt.want)
+			}
+		})
+	}
+}
+
+func TestQueryBuilderLabel(t *testing.T) {
+	tests := []struct {
+		name string
+		want string
+	}{
+		{name: "Work", want: "label:Work"},
+		{name: "work-items_2025", want: "label:work-items_2025"},
+		{name: "My Label", want: `label:"My Label"`},
+		{name: "My Label/Sub", want: `label:"My Label/Sub"`},
+		{name: "Clients/Acme", want: `label:"Clients/Acme"`},
+		{name: "Équipe/Straße", want: `label:"Équipe/Straße"`},
+		{name: "日本語", want: `label:"日本語"`},
+		{name: "-minus", want: `label:"-minus"`},
+	}
+	svc, fake := newTestService(t)
+	ctx := context.Background()
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			query := NewQueryBuilder("").Label(tt.name).String()
+			if query != tt.want {
+				t.Errorf("Label(%q) = %s, want %s", tt.name, query, tt.want)
+			}
+
+			// The term finds the label's messages and no others
+			id := fake.AddMessage(fakegmail.Message{Date: testDate, LabelIDs: []string{fake.AddLabel(tt.name)}})
+			got, err := ListMessageIDs(ctx, svc, query, nil)
+			if err != nil {
+				t.Fatal(err)
+			}
+			if !slices.Equal(got, []string{id}) {
+				t.Errorf("ListMessageIDs(%s) = %v, want [%s]", query, got, id)
+			}
+		})
+	}
+
+	if got := NewQueryBuilder("from:bob").Label(" ").Label("A B").String(); got != `from:bob label:"A B"` {
+		t.Errorf("Label() after a raw query = %s", got)
+	}
+}
@@ -0,0 +1,114 @@
+package gml
+
+import (
+	"bytes"
+	"context"
+	"fmt"
+	"net/mail"
+	"regexp"
+	"strings"
+)
+
+// RendererConfig maps a sender to a compact view of its messages
+// ([[renderers]] in config); exactly one of Builtin and Command is set
+type RendererConfig struct {
+	// From is a sender address, or a domain matching every address at it and
+	// its subdomains (github.com matches notifications@github.com)
+	From string `mapstructure:"from"`
+	// Builtin names a built-in renderer (see builtinRenderers)
+	Builtin string `mapstructure:"builtin"`
+	// Command is a shell command reading the message as JSON on stdin (with
+	// GML_* variables set, like hooks) and writing the rendered body to stdout
+	Command string `mapstructure:"command"`
+}
+
+// builtinRenderers are the renderers available as builtin = "<name>"
+var builtinRenderers = map[string]func(body string) string{
+	"github": renderGitHub,
+}
+
+// matches reports whether the renderer applies to a From header
+func (r RendererConfig) matches(from string) bool {
+	addr := from
+	if a, err := mail.ParseAddress(from); err == nil {
+		addr = a.Address
+	}
+	addr = strings.ToLower(strings.TrimSpace(addr))
+	pattern := strings.ToLower(strings.TrimSpace(r.From))
+	if pattern == "" {
+		return false
+	}
+	if strings.Contains(pattern, "@") {
+		return addr == pattern
+	}
+	_, domain, ok := strings.Cut(addr, "@")
+	return ok && (domain == pattern || strings.HasSuffix(domain, "."+pattern))
+}
+
+// RenderMessage returns the body of msg as shown by the first renderer that
+// matches its sender, or the body unchanged when none does
+func RenderMessage(ctx context.Context, renderers []RendererConfig, msg MessageInfo) (string, error) {
+	for _, r := range renderers {
+		if !r.matches(msg.From) {
+			continue
+		}
+		if r.Command != "" {
+			var stdout, stderr bytes.Buffer
+			if err := RunMessageHook(ctx, r.Command, msg, &stdout, &stderr); err != nil {
+				return "", fmt.Errorf("renderer for %s: %w: %s", r.From, err, strings.TrimSpace(stderr.String()))
+			}
+			return stdout.String(), nil
+		}
+		render, ok := builtinRenderers[r.Builtin]
+		if !ok {
+			return "", fmt.Errorf("renderer for %s: unknown builtin %q", r.From, r.Builtin)
+		}
+		return render(msg.Body), nil
+	}
+	return msg.Body, nil
+}
+
+// RenderMessageDetail applies RenderMessage to the body of a fetched message
+func RenderMessageDetail(ctx context.Context, renderers []RendererConfig, detail *MessageDetail) error {
+	body, err := RenderMessage(ctx, renderers, MessageInfo{
+		ID:       detail.ID,
+		ThreadID: detail.ThreadID,
+		URL:      detail.URL,
+		From:     detail.From,
+		To:       detail.To,
+		Subject:  detail.Subject,
+		Date:     detail.Date,
+		Labels:   detail.Labels,
+		Body:     detail.Body,
+	})
+	if err != nil {
+		return err
+	}
+	detail.Body = body
+	return nil
+}
+
+// githubFooter starts the "Reply to this email directly" footer of GitHub
+// notifications, after a "—" or "-- " separator line
+var githubFooter = regexp.MustCompile(`(?m)^(—|-- ?)\s*\n\s*Reply to this email directly`)
+
+// githubURL finds the link to the notification's thread in the footer
+var githubURL = regexp.MustCompile(`https://github\.com/[^\s<>)]+`)
+
+// blankLines matches runs of blank lines to collapse
+var blankLines = regexp.MustCompile(`\n[ \t]*(\n[ \t]*)+\n`)
+
+// renderGitHub trims a GitHub notification to the comment and the thread link
+func renderGitHub(body string) string {
+	body = strings.ReplaceAll(body, "\r\n", "\n")
+	link := ""
+	if loc := githubFooter.FindStringIndex(body); loc != nil {
+		link = githubURL.FindString(body[loc[0]:])
+		body = body[:loc[0]]
+	}
+	body = strings.TrimSpace(blankLines.ReplaceAllString(body, "\n\n"))
+	if link != "" {
+		body += "\n\n" + link
+	}
+	return body + "\n"
+}
@@ -0,0 +1,192 @@
+package gml
+
+import (
+	"bytes"
+	"context"
+	"fmt"
+	"io"
+	"net/mail"
+	"os"
+	"os/exec"
+	"strings"
+
+	"github.com/longkey1/gml/internal/google"
+	"google.golang.org/api/googleapi"
+)
+
+// ReplyContext is a message being replied to, with the thread leading up to it
+type ReplyContext struct {
+	// Thread holds the messages up to and including the replied one, oldest
+	// first, with plain-text bodies
+	Thread []MessageDetail
+	// Reply is addressed and threaded, with an empty body
+	Reply *OutgoingMessage
+}
+
+// Message returns the message being replied to
+func (rc *ReplyContext) Message() MessageDetail {
+	return rc.Thread[len(rc.Thread)-1]
+}
+
+// PrepareReply fetches the thread of a message and addresses a reply to its
+// sender (Reply-To if set), or to its recipients when the message was sent by
+// the user. With all, the other recipients are copied as well
+func PrepareReply(ctx context.Context, svc *Service, messageID string, all bool) (*ReplyContext, error) {
+	self, err := GetUserEmail(ctx, svc)
+	if err != nil {
+		return nil, err
+	}
+	self = strings.ToLower(self)
+
+	meta, err := svc.API.GetMessage(ctx, messageID, google.GetMessageOptions{Format: "minimal", Fields: []googleapi.Field{"threadId"}})
+	if err != nil {
+		return nil, fmt.Errorf("unable to retrieve message: %w", apiError(err))
+	}
+	thread, err := svc.API.GetThread(ctx, meta.ThreadId, google.GetMessageOptions{Format: "full"})
+	if err != nil {
+		return nil, fmt.Errorf("unable to get thread %s: %w", meta.ThreadId, apiError(err))
+	}
+
+	rc := &ReplyContext{}
+	for _, msg := range thread.Messages {
+		rc.Thread = append(rc.Thread, MessageDetail{
+			ID:       msg.Id,
+			ThreadID: msg.ThreadId,
+			Type:     MessageType(msg),
+			From:     headerValue(msg.Payload, "From"),
+			To:       headerValue(msg.Payload, "To"),
+			Subject:  headerValue(msg.Payload, "Subject"),
+			Date:     headerValue(msg.Payload, "Date"),
+			Body:     ExtractBody(msg.Payload),
+		})
+		if msg.Id != messageID {
+			continue
+		}
+
+		p := msg.Payload
+		sender := headerValue(p, "Reply-To")
+		if sender == "" {
+			sender = headerValue(p, "From")
+		}
+		to := addressList(sender)
+		if senderAddress(headerValue(p, "From")) == self {
+			// Replying to our own message continues the conversation with its recipients
+			to = addressList(headerValue(p, "To"))
+		}
+
+		seen := map[string]bool{self: true}
+		reply := &OutgoingMessage{
+			To:         uniqueAddresses(to, seen),
+			Subject:    replySubject(headerValue(p, "Subject")),
+			InReplyTo:  headerValue(p, "Message-ID"),
+			References: strings.TrimSpace(headerValue(p, "References") + " " + headerValue(p, "Message-ID")),
+			ThreadID:   msg.ThreadId,
+		}
+		if all {
+			reply.Cc = uniqueAddresses(append(addressList(headerValue(p, "To")), addressList(headerValue(p, "Cc"))...), seen)
+		}
+		if len(reply.To) == 0 {
+			reply.To, reply.Cc = reply.Cc, nil
+		}
+		rc.Reply = reply
+		return rc, nil
+	}
+	return nil, notFoundError("message %s not found in thread %s", messageID, meta.ThreadId)
+}
+
+// addressList parses an address list header, keeping RFC 2047 encoding of
+// display names for the outgoing headers
+func addressList(value string) []*mail.Address {
+	if strings.TrimSpace(value) == "" {
+		return nil
+	}
+	list, err := mail.ParseAddressList(value)
+	if err != nil {
+		return []*mail.Address{{Address: senderAddress(value)}}
+	}
+	return list
+}
+
+// uniqueAddresses formats the addresses not yet in seen, marking them seen
+func uniqueAddresses(addrs []*mail.Address, seen map[string]bool) []string {
+	var out []string
+	for _, a := range addrs {
+		key := strings.ToLower(a.Address)
+		if seen[key] {
+			continue
+		}
+		seen[key] = true
+		out = append(out, a.String())
+	}
+	return out
+}
+
+// replySubject prefixes a subject with "Re: " unless it already has it
+func replySubject(subject string) string {
+	if strings.HasPrefix(strings.ToLower(subject), "re:") {
+		return subject
+	}
+	return "Re: " + subject
+}
+
+// QuoteReply returns the body of a message quoted for a reply
+func QuoteReply(msg MessageDetail) string {
+	var b strings.Builder
+	fmt.Fprintf(&b, "On %s, %s wrote:\n", msg.Date, msg.From)
+	for _, line := range strings.Split(strings.TrimRight(msg.Body, "\r\n"), "\n") {
+		line = strings.TrimRight(line, "\r")
+		if line == "" || strings.HasPrefix(line, ">") {
+			b.WriteString(">" + line + "\n")
+		} else {
+			b.WriteString("> " + line + "\n")
+		}
+	}
+	return b.String()
+}
+
+// WriteReplyTranscript writes the thread as plain text, oldest message first,
+// for a suggestion command to read
+func WriteReplyTranscript(w io.Writer, rc *ReplyContext) error {
+	for i, msg := range rc.Thread {
+		if i > 0 {
+			if _, err := io.WriteString(w, "\n-----\n\n"); err != nil {
+				return err
+			}
+		}
+		if _, err := fmt.Fprintf(w, "From: %s\nTo: %s\nDate: %s\nSubject: %s\n\n%s\n",
+			msg.From, msg.To, msg.Date, msg.Subject, strings.TrimRight(msg.Body, "\r\n")); err != nil {
+			return err
+		}
+	}
+	return nil
+}
+
+// SuggestReply runs a shell command with the thread transcript on stdin and
+// returns what it prints as the suggested reply body. The replied message is
+// exposed as GML_* environment variables, as for message hooks
+func SuggestReply(ctx context.Context, command string, rc *ReplyContext, stderr io.Writer) (string, error) {
+	var transcript bytes.Buffer
+	if err := WriteReplyTranscript(&transcript, rc); err != nil {
+		return "", err
+	}
+
+	msg := rc.Message()
+	var stdout bytes.Buffer
+	c := exec.CommandContext(ctx, "sh", "-c", command)
+	c.Env = append(os.Environ(), MessageEnv(MessageInfo{
+		ID:       msg.ID,
+		ThreadID: msg.ThreadID,
+		From:     msg.From,
+		To:       msg.To,
+		Subject:  msg.Subject,
+		Date:     msg.Date,
+	})...)
+	c.Stdin = &transcript
+	c.Stdout = &stdout
+	c.Stderr = stderr
+
+	if err := c.Run(); err != nil {
+		return "", fmt.Errorf("suggest command failed: %w", err)
+	}
+	return strings.TrimRight(stdout.String(), "\n") + "\n", nil
+}
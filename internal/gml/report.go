@@ -0,0 +1,169 @@
+package gml
+
+import (
+	"context"
+	"fmt"
+	"net/mail"
+	"sort"
+	"strings"
+	"time"
+)
+
+// GroupBy represents the key messages are grouped by
+type GroupBy string
+
+const (
+	GroupBySender GroupBy = "sender"
+	GroupByDomain GroupBy = "domain"
+	GroupByLabel  GroupBy = "label"
+	GroupByDay    GroupBy = "day"
+	GroupByMonth  GroupBy = "month"
+)
+
+// GroupCount represents the number of messages sharing a group key
+type GroupCount struct {
+	Key   string `json:"key"`
+	Count int    `json:"count"`
+}
+
+// ReportConfig holds the definition of a named report in config
+type ReportConfig struct {
+	Query   string       `mapstructure:"query"`
+	Labels  []string     `mapstructure:"labels"`
+	GroupBy GroupBy      `mapstructure:"group_by"`
+	Limit   int          `mapstructure:"limit"`
+	Format  OutputFormat `mapstructure:"format"`
+	Output  string       `mapstructure:"output"`
+	Email   []string     `mapstructure:"email"`
+	Subject string       `mapstructure:"subject"`
+
+	// CSV options for csv/tsv reports
+	Delimiter string `mapstructure:"delimiter"`
+	CRLF      bool   `mapstructure:"crlf"`
+	BOM       bool   `mapstructure:"bom"`
+}
+
+// CSVOptions returns the report's CSV options
+func (rc ReportConfig) CSVOptions() (CSVOptions, error) {
+	d, err := ParseDelimiter(rc.Delimiter)
+	if err != nil {
+		return CSVOptions{}, err
+	}
+	return CSVOptions{Delimiter: d, CRLF: rc.CRLF, BOM: rc.BOM}, nil
+}
+
+// Report represents the result of running a report
+type Report struct {
+	Name        string       `json:"name"`
+	Query       string       `json:"query"`
+	GroupBy     GroupBy      `json:"groupBy"`
+	GeneratedAt time.Time    `json:"generatedAt"`
+	Total       int          `json:"total"`
+	Groups      []GroupCount `json:"groups"`
+}
+
+// RunReport fetches messages for the report definition and groups them
+func RunReport(ctx context.Context, svc *Service, name string, rc ReportConfig) (*Report, error) {
+	groupBy := rc.GroupBy
+	if groupBy == "" {
+		groupBy = GroupBySender
+	}
+
+	messages, err := ListMessages(ctx, svc, ListMessagesOptions{
+		Query:      rc.Query,
+		MaxResults: 500,
+		LabelIDs:   rc.Labels,
+		Fields:     ParseFields("id,from,date,labels"),
+	})
+	if err != nil {
+		return nil, err
+	}
+
+	groups, err := GroupMessages(messages, groupBy)
+	if err != nil {
+		return nil, err
+	}
+	if rc.Limit > 0 && len(groups) > rc.Limit {
+		groups = groups[:rc.Limit]
+	}
+
+	return &Report{
+		Name:        name,
+		Query:       rc.Query,
+		GroupBy:     groupBy,
+		GeneratedAt: time.Now(),
+		Total:       len(messages),
+		Groups:      groups,
+	}, nil
+}
+
+// GroupMessages counts messages per group key, sorted by count descending
+// Messages are counted once per label when grouping by label
+func GroupMessages(messages []MessageInfo, by GroupBy) ([]GroupCount, error) {
+	counts := make(map[string]int)
+	for _, msg := range messages {
+		keys, err := groupKeys(msg, by)
+		if err != nil {
+			return nil, err
+		}
+		for _, k := range keys {
+			counts[k]++
+		}
+	}
+
+	groups := make([]GroupCount, 0, len(counts))
+	for k, n := range counts {
+		groups = append(groups, GroupCount{Key: k, Count: n})
+	}
+	sort.Slice(groups, func(i, j int) bool {
+		if groups[i].Count != groups[j].Count {
+			return groups[i].Count > groups[j].Count
+		}
+		return groups[i].Key < groups[j].Key
+	})
+	return groups, nil
+}
+
+// groupKeys returns the group keys a message belongs to
+func groupKeys(msg MessageInfo, by GroupBy) ([]string, error) {
+	switch by {
+	case GroupBySender:
+		return []string{senderAddress(msg.From)}, nil
+	case GroupByDomain:
+		addr := senderAddress(msg.From)
+		if i := strings.LastIndex(addr, "@"); i >= 0 {
+			return []string{addr[i+1:]}, nil
+		}
+		return []string{addr}, nil
+	case GroupByLabel:
+		if len(msg.Labels) == 0 {
+			return []string{"(none)"}, nil
+		}
+		return msg.Labels, nil
+	case GroupByDay, GroupByMonth:
+		t, err := ParseMailDate(msg.Date)
+		if err != nil {
+			return []string{"(unknown)"}, nil
+		}
+		if by == GroupByDay {
+			return []string{t.Local().Format("2006-01-02")}, nil
+		}
+		return []string{t.Local().Format("2006-01")}, nil
+	default:
+		return nil, fmt.Errorf("unknown group-by: %s", by)
+	}
+}
+
+// senderAddress returns the lowercased email address of a From header
+func senderAddress(from string) string {
+	addr, err := mail.ParseAddress(from)
+	if err != nil {
+		return strings.ToLower(strings.TrimSpace(from))
+	}
+	return strings.ToLower(addr.Address)
+}
+
+// ReportOutputPath expands the {date} placeholder in a report output path
+func ReportOutputPath(path string, t time.Time) string {
+	return strings.ReplaceAll(path, "{date}", t.Format("2006-01-02"))
+}
@@ -0,0 +1,174 @@
+package gml
+
+import (
+	"errors"
+	"fmt"
+	"io"
+	"os"
+	"path/filepath"
+	"sort"
+	"strings"
+
+	"github.com/olekukonko/tablewriter"
+	"github.com/pelletier/go-toml/v2"
+)
+
+// SavedSearch is a named query for 'gml list --saved'
+type SavedSearch struct {
+	Query  string   `mapstructure:"query" toml:"query,omitempty" json:"query,omitempty"`
+	Labels []string `mapstructure:"labels" toml:"labels,omitempty" json:"labels,omitempty"`
+}
+
+// SavedSearchInfo describes a saved search for output
+type SavedSearchInfo struct {
+	Name string `json:"name"`
+	SavedSearch
+	// Source is "config" for [searches.<name>] in the config file, "saved" for
+	// searches stored with 'gml search save'
+	Source string `json:"source"`
+}
+
+// Sources of saved searches
+const (
+	SearchSourceConfig = "config"
+	SearchSourceSaved  = "saved"
+)
+
+// SearchStore holds the searches managed with 'gml search save/delete', kept
+// in searches.toml next to the config file so the config file itself is never
+// rewritten (and keeps its comments)
+type SearchStore struct {
+	path string
+}
+
+// NewSearchStore returns the store at path
+func NewSearchStore(path string) *SearchStore {
+	return &SearchStore{path: path}
+}
+
+// Load reads the stored searches; a missing file has none
+func (s *SearchStore) Load() (map[string]SavedSearch, error) {
+	searches := make(map[string]SavedSearch)
+	data, err := os.ReadFile(s.path)
+	if errors.Is(err, os.ErrNotExist) {
+		return searches, nil
+	}
+	if err != nil {
+		return nil, fmt.Errorf("unable to read saved searches: %w", err)
+	}
+	if err := toml.Unmarshal(data, &searches); err != nil {
+		return nil, fmt.Errorf("unable to parse %s: %w", s.path, err)
+	}
+	return searches, nil
+}
+
+// Save stores a search under name, replacing one with the same name
+func (s *SearchStore) Save(name string, search SavedSearch) error {
+	searches, err := s.Load()
+	if err != nil {
+		return err
+	}
+	searches[name] = search
+	return s.write(searches)
+}
+
+// Delete removes a stored search
+func (s *SearchStore) Delete(name string) error {
+	searches, err := s.Load()
+	if err != nil {
+		return err
+	}
+	if _, ok := searches[name]; !ok {
+		return notFoundError("saved search not found: %s", name)
+	}
+	delete(searches, name)
+	return s.write(searches)
+}
+
+// write replaces the store file
+func (s *SearchStore) write(searches map[string]SavedSearch) error {
+	data, err := toml.Marshal(searches)
+	if err != nil {
+		return fmt.Errorf("unable to encode saved searches: %w", err)
+	}
+	if err := os.MkdirAll(filepath.Dir(s.path), 0700); err != nil {
+		return fmt.Errorf("unable to create config directory: %w", err)
+	}
+	if err := os.WriteFile(s.path, data, 0600); err != nil {
+		return fmt.Errorf("unable to write saved searches: %w", err)
+	}
+	return nil
+}
+
+// SearchName normalizes a saved search name; names are case-insensitive
+func SearchName(name string) string {
+	return strings.ToLower(strings.TrimSpace(name))
+}
+
+// ListSavedSearches merges the searches of the config file and the store,
+// sorted by name
+func ListSavedSearches(cfg *Config, store *SearchStore) ([]SavedSearchInfo, error) {
+	stored, err := store.Load()
+	if err != nil {
+		return nil, err
+	}
+
+	var searches []SavedSearchInfo
+	for name, s := range cfg.Searches {
+		searches = append(searches, SavedSearchInfo{Name: SearchName(name), SavedSearch: s, Source: SearchSourceConfig})
+	}
+	for name, s := range stored {
+		if _, ok := cfg.Searches[name]; ok {
+			continue
+		}
+		searches = append(searches, SavedSearchInfo{Name: name, SavedSearch: s, Source: SearchSourceSaved})
+	}
+	sort.Slice(searches, func(i, j int) bool { return searches[i].Name < searches[j].Name })
+	return searches, nil
+}
+
+// FindSavedSearch returns a search by name, from the config file first
+func FindSavedSearch(cfg *Config, store *SearchStore, name string) (SavedSearchInfo, error) {
+	searches, err := ListSavedSearches(cfg, store)
+	if err != nil {
+		return SavedSearchInfo{}, err
+	}
+	for _, s := range searches {
+		if s.Name == SearchName(name) {
+			return s, nil
+		}
+	}
+	return SavedSearchInfo{}, notFoundError("saved search not found: %s", name)
+}
+
+// CombineQuery adds extra terms to a saved query, grouping the saved query so
+// an OR inside it can't absorb the extra terms
+func CombineQuery(saved, extra string) string {
+	saved, extra = strings.TrimSpace(saved), strings.TrimSpace(extra)
+	switch {
+	case saved == "":
+		return extra
+	case extra == "":
+		return saved
+	}
+	return "(" + saved + ") " + extra
+}
+
+// FormatSavedSearches outputs saved searches in the specified format
+func FormatSavedSearches(w io.Writer, searches []SavedSearchInfo, format OutputFormat) error {
+	if format == OutputFormatJSON {
+		return FormatJSON(w, searches)
+	}
+	if len(searches) == 0 {
+		fmt.Fprintln(w, "No saved searches.")
+		return nil
+	}
+
+	table := tablewriter.NewWriter(w)
+	table.Header("NAME", "QUERY", "LABELS", "SOURCE")
+	for _, s := range searches {
+		table.Append(s.Name, s.Query, strings.Join(s.Labels, ", "), s.Source)
+	}
+	table.Render()
+	return nil
+}
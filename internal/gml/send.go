@@ -0,0 +1,312 @@
+package gml
+
+import (
+	"bytes"
+	"context"
+	"encoding/base64"
+	"fmt"
+	"maps"
+	"mime"
+	"mime/multipart"
+	"mime/quotedprintable"
+	"net/http"
+	"net/mail"
+	"net/textproto"
+	"os"
+	"path/filepath"
+	"slices"
+	"strings"
+
+	"google.golang.org/api/gmail/v1"
+	"google.golang.org/api/googleapi"
+)
+
+// MaxAttachmentSize is Gmail's limit on the total size of attachments
+const MaxAttachmentSize = 25 << 20
+
+// maxRawSize is the message size above which messages are uploaded as media
+// instead of in the request body
+const maxRawSize = 4 << 20
+
+// OutgoingMessage represents a message to send
+type OutgoingMessage struct {
+	To      []string `json:"to,omitempty"`
+	Cc      []string `json:"cc,omitempty"`
+	Bcc     []string `json:"bcc,omitempty"`
+	Subject string   `json:"subject"`
+	Body    string   `json:"body"`
+	// BodyFormat is how Body is written: text (default), markdown or html.
+	// Markdown and HTML bodies are sent as multipart/alternative with a
+	// plain-text version
+	BodyFormat BodyFormat `json:"bodyFormat,omitempty"`
+	// InReplyTo and References thread a reply (RFC 5322 section 3.6.4)
+	InReplyTo  string `json:"inReplyTo,omitempty"`
+	References string `json:"references,omitempty"`
+	// ThreadID adds the message to an existing Gmail thread
+	ThreadID string `json:"threadId,omitempty"`
+	// Attachments are added as multipart/mixed parts, inline ones together
+	// with the body in a multipart/related part
+	Attachments []OutgoingAttachment `json:"attachments,omitempty"`
+}
+
+// OutgoingAttachment is a file attached to an outgoing message
+type OutgoingAttachment struct {
+	Filename string `json:"filename"`
+	MimeType string `json:"mimeType"`
+	Data     []byte `json:"data"`
+	// Inline attachments are shown in the body and referenced from HTML as
+	// cid:<ContentID>
+	Inline    bool   `json:"inline,omitempty"`
+	ContentID string `json:"contentId,omitempty"`
+}
+
+// ReadAttachment reads a file to attach, detecting its content type from the
+// extension or, failing that, the content. Inline attachments get the file
+// name as their content ID
+func ReadAttachment(path string, inline bool) (OutgoingAttachment, error) {
+	data, err := os.ReadFile(path)
+	if err != nil {
+		return OutgoingAttachment{}, fmt.Errorf("unable to read attachment: %w", err)
+	}
+	a := OutgoingAttachment{
+		Filename: filepath.Base(path),
+		MimeType: mime.TypeByExtension(filepath.Ext(path)),
+		Data:     data,
+		Inline:   inline,
+	}
+	if a.MimeType == "" {
+		a.MimeType = http.DetectContentType(data)
+	}
+	if inline {
+		a.ContentID = a.Filename
+	}
+	return a, nil
+}
+
+// Raw builds the RFC 5322 representation of the message
+func (m *OutgoingMessage) Raw() ([]byte, error) {
+	if len(m.To)+len(m.Cc)+len(m.Bcc) == 0 {
+		return nil, fmt.Errorf("at least one recipient is required")
+	}
+	return m.build()
+}
+
+// build builds the RFC 5322 representation of the message, which may have no
+// recipients yet
+func (m *OutgoingMessage) build() ([]byte, error) {
+	var size int
+	for _, a := range m.Attachments {
+		size += len(a.Data)
+	}
+	if size > MaxAttachmentSize {
+		return nil, fmt.Errorf("attachments total %s, more than Gmail's limit of %s", FormatSize(int64(size)), FormatSize(MaxAttachmentSize))
+	}
+
+	body, err := m.bodyPart()
+	if err != nil {
+		return nil, err
+	}
+
+	var buf bytes.Buffer
+	for _, h := range []struct {
+		name  string
+		addrs []string
+	}{{"To", m.To}, {"Cc", m.Cc}, {"Bcc", m.Bcc}} {
+		if err := writeAddressHeader(&buf, h.name, h.addrs); err != nil {
+			return nil, err
+		}
+	}
+	fmt.Fprintf(&buf, "Subject: %s\r\n", mime.QEncoding.Encode("utf-8", m.Subject))
+	if m.InReplyTo != "" {
+		fmt.Fprintf(&buf, "In-Reply-To: %s\r\n", m.InReplyTo)
+	}
+	if m.References != "" {
+		fmt.Fprintf(&buf, "References: %s\r\n", m.References)
+	}
+	buf.WriteString("MIME-Version: 1.0\r\n")
+	for _, key := range slices.Sorted(maps.Keys(body.header)) {
+		fmt.Fprintf(&buf, "%s: %s\r\n", key, body.header.Get(key))
+	}
+	buf.WriteString("\r\n")
+	buf.Write(body.body)
+
+	return buf.Bytes(), nil
+}
+
+// mimePart is a MIME entity: its Content-* headers and encoded body
+type mimePart struct {
+	header textproto.MIMEHeader
+	body   []byte
+}
+
+// bodyPart builds the MIME structure of the message: the text alone, or
+// multipart/alternative with text and HTML, in multipart/related with the
+// inline attachments, in multipart/mixed with the other attachments
+func (m *OutgoingMessage) bodyPart() (mimePart, error) {
+	plain, html, err := m.alternativeBodies()
+	if err != nil {
+		return mimePart{}, err
+	}
+	text, err := textPart("plain", plain)
+	if err != nil {
+		return mimePart{}, err
+	}
+	if html != "" {
+		htmlPart, err := textPart("html", html)
+		if err != nil {
+			return mimePart{}, err
+		}
+		if text, err = multipartPart("alternative", []mimePart{text, htmlPart}); err != nil {
+			return mimePart{}, err
+		}
+	}
+
+	related := []mimePart{text}
+	var mixed []mimePart
+	used := make(map[string]bool)
+	for _, a := range m.Attachments {
+		if a.Inline {
+			// Content IDs must be unique within the message
+			base := a.ContentID
+			if base == "" {
+				base = a.Filename
+			}
+			cid := base
+			for i := 2; used[cid]; i++ {
+				cid = fmt.Sprintf("%d-%s", i, base)
+			}
+			used[cid] = true
+			related = append(related, attachmentPart(a, cid))
+		} else {
+			mixed = append(mixed, attachmentPart(a, ""))
+		}
+	}
+
+	body := text
+	if len(related) > 1 {
+		if body, err = multipartPart("related", related); err != nil {
+			return mimePart{}, err
+		}
+	}
+	if len(mixed) > 0 {
+		if body, err = multipartPart("mixed", append([]mimePart{body}, mixed...)); err != nil {
+			return mimePart{}, err
+		}
+	}
+	return body, nil
+}
+
+// textPart encodes a text/<subtype> body as quoted-printable UTF-8
+func textPart(subtype, text string) (mimePart, error) {
+	var buf bytes.Buffer
+	qp := quotedprintable.NewWriter(&buf)
+	if _, err := qp.Write([]byte(text)); err != nil {
+		return mimePart{}, fmt.Errorf("unable to encode body: %w", err)
+	}
+	if err := qp.Close(); err != nil {
+		return mimePart{}, fmt.Errorf("unable to encode body: %w", err)
+	}
+	header := textproto.MIMEHeader{}
+	header.Set("Content-Type", `text/`+subtype+`; charset="UTF-8"`)
+	header.Set("Content-Transfer-Encoding", "quoted-printable")
+	return mimePart{header: header, body: buf.Bytes()}, nil
+}
+
+// attachmentPart encodes an attachment as base64 in lines of 76 characters;
+// a content ID makes it inline
+func attachmentPart(a OutgoingAttachment, contentID string) mimePart {
+	mimeType, params, err := mime.ParseMediaType(a.MimeType)
+	if err != nil {
+		mimeType, params = "application/octet-stream", nil
+	}
+	if params == nil {
+		params = make(map[string]string)
+	}
+	params["name"] = a.Filename
+	disposition := "attachment"
+	if contentID != "" {
+		disposition = "inline"
+	}
+
+	header := textproto.MIMEHeader{}
+	header.Set("Content-Type", mime.FormatMediaType(mimeType, params))
+	header.Set("Content-Disposition", mime.FormatMediaType(disposition, map[string]string{"filename": a.Filename}))
+	header.Set("Content-Transfer-Encoding", "base64")
+	if contentID != "" {
+		header.Set("Content-ID", "<"+contentID+">")
+	}
+
+	encoded := base64.StdEncoding.EncodeToString(a.Data)
+	var buf bytes.Buffer
+	for len(encoded) > 76 {
+		buf.WriteString(encoded[:76] + "\r\n")
+		encoded = encoded[76:]
+	}
+	buf.WriteString(encoded + "\r\n")
+	return mimePart{header: header, body: buf.Bytes()}
+}
+
+// multipartPart combines parts into a multipart entity of the given subtype
+func multipartPart(subtype string, parts []mimePart) (mimePart, error) {
+	var buf bytes.Buffer
+	w := multipart.NewWriter(&buf)
+	for _, p := range parts {
+		pw, err := w.CreatePart(p.header)
+		if err != nil {
+			return mimePart{}, fmt.Errorf("unable to build message: %w", err)
+		}
+		if _, err := pw.Write(p.body); err != nil {
+			return mimePart{}, fmt.Errorf("unable to build message: %w", err)
+		}
+	}
+	if err := w.Close(); err != nil {
+		return mimePart{}, fmt.Errorf("unable to build message: %w", err)
+	}
+	header := textproto.MIMEHeader{}
+	header.Set("Content-Type", mime.FormatMediaType("multipart/"+subtype, map[string]string{"boundary": w.Boundary()}))
+	return mimePart{header: header, body: buf.Bytes()}, nil
+}
+
+// writeAddressHeader writes an address list header if any addresses are
+// given. Each address is parsed and written back in canonical form, so input
+// such as a CSV field with a line break can't add headers of its own
+func writeAddressHeader(buf *bytes.Buffer, name string, addrs []string) error {
+	var formatted []string
+	for _, raw := range addrs {
+		list, err := mail.ParseAddressList(raw)
+		if err != nil {
+			return fmt.Errorf("invalid %s address %q: %w", name, raw, err)
+		}
+		for _, addr := range list {
+			formatted = append(formatted, addr.String())
+		}
+	}
+	if len(formatted) == 0 {
+		return nil
+	}
+	fmt.Fprintf(buf, "%s: %s\r\n", name, strings.Join(formatted, ", "))
+	return nil
+}
+
+// SendMessage sends the message from the authenticated user. Large messages,
+// e.g. with attachments, are uploaded as media
+func SendMessage(ctx context.Context, svc *Service, msg *OutgoingMessage) (*gmail.Message, error) {
+	raw, err := msg.Raw()
+	if err != nil {
+		return nil, err
+	}
+
+	message := &gmail.Message{ThreadId: msg.ThreadID}
+	if len(raw) <= maxRawSize {
+		message.Raw = base64.URLEncoding.EncodeToString(raw)
+	}
+	call := svc.Gmail.Users.Messages.Send("me", message)
+	if len(raw) > maxRawSize {
+		call = call.Media(bytes.NewReader(raw), googleapi.ContentType("message/rfc822"))
+	}
+	sent, err := call.Context(ctx).Do()
+	if err != nil {
+		return nil, fmt.Errorf("unable to send message: %w", apiError(err))
+	}
+	return sent, nil
+}
@@ -0,0 +1,30 @@
+package gml
+
+import (
+	"strings"
+	"testing"
+)
+
+func TestRawAddressHeaders(t *testing.T) {
+	msg := &OutgoingMessage{
+		To:      []string{"Bob Smith <bob@example.com>", "carol@example.com, dave@example.com"},
+		Subject: "Hello",
+		Body:    "Hi",
+	}
+	raw, err := msg.Raw()
+	if err != nil {
+		t.Fatal(err)
+	}
+	want := "To: \"Bob Smith\" <bob@example.com>, <carol@example.com>, <dave@example.com>\r\n"
+	if !strings.HasPrefix(string(raw), want) {
+		t.Errorf("Raw() starts with %q, want %q", strings.SplitAfter(string(raw), "\r\n")[0], want)
+	}
+
+	// A line break, e.g. from a quoted CSV field, must not add a header
+	for _, to := range []string{"a@example.com\nBcc: someone@example.com", "a@example.com\r\nBcc: someone@example.com"} {
+		msg := &OutgoingMessage{To: []string{to}, Subject: "Hello", Body: "Hi"}
+		if raw, err := msg.Raw(); err == nil {
+			t.Errorf("Raw() with To %q = %q, want error", to, raw)
+		}
+	}
+}
@@ -0,0 +1,256 @@
+package gml
+
+import (
+	"cmp"
+	"context"
+	"fmt"
+	"io"
+	"net/mail"
+	"slices"
+	"strings"
+	"time"
+
+	"github.com/olekukonko/tablewriter"
+)
+
+// SenderSummary is the mail of one sender in a senders report
+type SenderSummary struct {
+	Sender string    `json:"sender"`
+	Name   string    `json:"name,omitempty"`
+	Count  int       `json:"count"`
+	Size   int64     `json:"size"`
+	Latest time.Time `json:"latest"`
+}
+
+// SendersReport lists the senders of the messages matching a query
+type SendersReport struct {
+	Query     string          `json:"query,omitempty"`
+	Total     int             `json:"total"`
+	TotalSize int64           `json:"totalSize"`
+	Cached    int             `json:"cached"`
+	Senders   []SenderSummary `json:"senders"`
+}
+
+// SendersOptions contains options for TopSenders
+type SendersOptions struct {
+	Query    string
+	LabelIDs []string
+	// Limit keeps the top senders (0 for all)
+	Limit int
+	// SortBySize ranks senders by total size instead of message count
+	SortBySize bool
+	// MaxMessages stops the crawl after this many messages (0 for all)
+	MaxMessages int
+	// Concurrency is the number of parallel requests (default 8)
+	Concurrency int
+	// Cache, if set, keeps sender, date and size of messages between runs
+	Cache MessageCache
+	// Progress, if set, is called as message metadata is fetched
+	Progress func(done, total int)
+}
+
+// TopSenders crawls the messages matching a query, like MailboxStats, and
+// ranks their senders by message count or total size
+func TopSenders(ctx context.Context, svc *Service, opts SendersOptions) (*SendersReport, error) {
+	var labelIDs []string
+	if len(opts.LabelIDs) > 0 {
+		idx, err := FetchLabelIndex(ctx, svc)
+		if err != nil {
+			return nil, err
+		}
+		if labelIDs, err = idx.ResolveLabelIDs(opts.LabelIDs); err != nil {
+			return nil, err
+		}
+	}
+
+	ids, metas, cached, err := crawlMetadata(ctx, svc, labelIDs, StatsOptions{
+		Query:       opts.Query,
+		MaxMessages: opts.MaxMessages,
+		Concurrency: opts.Concurrency,
+		Cache:       opts.Cache,
+		Progress:    opts.Progress,
+	}, true, true)
+	if err != nil {
+		return nil, err
+	}
+
+	report := &SendersReport{Query: opts.Query, Total: len(ids), Cached: cached}
+	bySender := make(map[string]*SenderSummary)
+	for _, m := range metas {
+		addr := senderAddress(m.From)
+		s, ok := bySender[addr]
+		if !ok {
+			s = &SenderSummary{Sender: addr}
+			if a, err := mail.ParseAddress(m.From); err == nil {
+				s.Name = a.Name
+			}
+			bySender[addr] = s
+		}
+		s.Count++
+		s.Size += m.Size
+		if t := time.UnixMilli(m.Date); t.After(s.Latest) {
+			s.Latest = t
+		}
+		report.TotalSize += m.Size
+	}
+
+	for _, s := range bySender {
+		report.Senders = append(report.Senders, *s)
+	}
+	slices.SortFunc(report.Senders, func(a, b SenderSummary) int {
+		c := cmp.Compare(b.Count, a.Count)
+		if opts.SortBySize {
+			c = cmp.Compare(b.Size, a.Size)
+		}
+		if c != 0 {
+			return c
+		}
+		return strings.Compare(a.Sender, b.Sender)
+	})
+	if opts.Limit > 0 && len(report.Senders) > opts.Limit {
+		report.Senders = report.Senders[:opts.Limit]
+	}
+	return report, nil
+}
+
+// SenderAction is what CleanupSenders does with the messages of a sender
+type SenderAction string
+
+const (
+	// SenderArchive removes the messages from the inbox
+	SenderArchive SenderAction = "archive"
+	// SenderTrash moves the messages to the trash
+	SenderTrash SenderAction = "trash"
+)
+
+// SenderCleanupOptions selects senders and what to do with their mail
+type SenderCleanupOptions struct {
+	// Query and LabelIDs limit the messages acted on, e.g. to the query
+	// the report was made for
+	Query    string
+	LabelIDs []string
+	Senders  []string
+	Action   SenderAction
+	// Filter also creates a filter per sender applying the action to new mail
+	Filter bool
+	// Failures collects batches that couldn't be modified; nil stops at the
+	// first failure
+	Failures *FailureLog
+	// Progress, if set, is called after each sender
+	Progress func(done, total int)
+}
+
+// SenderCleanupResult is what CleanupSenders did for one sender
+type SenderCleanupResult struct {
+	Sender   string `json:"sender"`
+	Modified int    `json:"modified"`
+	FilterID string `json:"filterId,omitempty"`
+}
+
+// senderActionLabels returns the label changes that carry out an action
+func senderActionLabels(action SenderAction) (add, remove []string, err error) {
+	switch action {
+	case SenderArchive:
+		return nil, []string{"INBOX"}, nil
+	case SenderTrash:
+		return []string{"TRASH"}, nil, nil
+	}
+	return nil, nil, fmt.Errorf("unknown sender action %q (archive or trash)", action)
+}
+
+// CleanupSenders archives or trashes the messages of each sender matching
+// the query, with BulkModify, and optionally creates filters doing the same
+// to their future mail
+func CleanupSenders(ctx context.Context, svc *Service, opts SenderCleanupOptions) ([]SenderCleanupResult, error) {
+	add, remove, err := senderActionLabels(opts.Action)
+	if err != nil {
+		return nil, err
+	}
+
+	var results []SenderCleanupResult
+	for i, sender := range opts.Senders {
+		if opts.Progress != nil {
+			opts.Progress(i, len(opts.Senders))
+		}
+		modified, err := BulkModify(ctx, svc, BulkModifyOptions{
+			Query:        strings.TrimSpace(opts.Query + " from:" + sender),
+			LabelIDs:     opts.LabelIDs,
+			AddLabels:    add,
+			RemoveLabels: remove,
+			Failures:     opts.Failures,
+		})
+		if err != nil {
+			return results, fmt.Errorf("unable to %s messages from %s: %w", opts.Action, sender, err)
+		}
+		result := SenderCleanupResult{Sender: sender, Modified: modified.Modified}
+
+		if opts.Filter {
+			filter, err := CreateFilter(ctx, svc, Filter{
+				Criteria: FilterCriteria{From: sender},
+				Action:   FilterAction{AddLabels: add, RemoveLabels: remove},
+			})
+			if err != nil {
+				return results, err
+			}
+			result.FilterID = filter.ID
+		}
+		results = append(results, result)
+	}
+	if opts.Progress != nil {
+		opts.Progress(len(opts.Senders), len(opts.Senders))
+	}
+	return results, nil
+}
+
+// FormatSenders outputs a senders report in the specified format
+func FormatSenders(w io.Writer, report *SendersReport, format OutputFormat, csvOpts CSVOptions) error {
+	switch format {
+	case OutputFormatJSON:
+		if report.Senders == nil {
+			report.Senders = []SenderSummary{}
+		}
+		return FormatJSON(w, report)
+	case OutputFormatCSV, OutputFormatTSV:
+		cw, err := NewCSVWriter(w, format, csvOpts)
+		if err != nil {
+			return err
+		}
+		cw.Write([]string{"sender", "name", "count", "size", "latest"})
+		for _, s := range report.Senders {
+			cw.Write([]string{s.Sender, s.Name, fmt.Sprint(s.Count), fmt.Sprint(s.Size), s.Latest.Format(time.RFC3339)})
+		}
+		cw.Flush()
+		return cw.Error()
+	default:
+		table := tablewriter.NewWriter(w)
+		table.Header("SENDER", "NAME", "COUNT", "SIZE", "LATEST")
+		for _, s := range report.Senders {
+			table.Append(s.Sender, s.Name, s.Count, FormatSize(s.Size), s.Latest.Local().Format("2006-01-02"))
+		}
+		table.Render()
+		fmt.Fprintf(w, "Total messages: %d (%s)\n", report.Total, FormatSize(report.TotalSize))
+		return nil
+	}
+}
+
+// FormatSenderCleanup outputs the results of CleanupSenders
+func FormatSenderCleanup(w io.Writer, results []SenderCleanupResult, action SenderAction, format OutputFormat) error {
+	if format == OutputFormatJSON {
+		if results == nil {
+			results = []SenderCleanupResult{}
+		}
+		return FormatJSON(w, results)
+	}
+	verb := "Archived"
+	if action == SenderTrash {
+		verb = "Trashed"
+	}
+	for _, r := range results {
+		fmt.Fprintf(w, "%s %d messages from %s", verb, r.Modified, r.Sender)
+		if r.FilterID != "" {
+			fmt.Fprintf(w, " (filter %s)", r.FilterID)
+		}
+		fmt.Fprintln(w)
+	}
+	return nil
+}
@@ -0,0 +1,245 @@
+package gml
+
+import (
+	"context"
+	"crypto/rand"
+	"encoding/hex"
+	"errors"
+	"fmt"
+	"io"
+	"net/http"
+	"os"
+	"path/filepath"
+	"slices"
+	"strings"
+	"time"
+
+	"github.com/olekukonko/tablewriter"
+	"google.golang.org/api/googleapi"
+)
+
+// SendQueueName is the state file name of the scheduled send queue
+const SendQueueName = "send-queue"
+
+// maxSendAttempts is how often SendDue tries a queued message before giving up
+const maxSendAttempts = 10
+
+// QueuedMessage is a message waiting in the send queue for its send time
+type QueuedMessage struct {
+	ID      string           `json:"id"`
+	At      time.Time        `json:"at"`
+	Queued  time.Time        `json:"queued"`
+	Message *OutgoingMessage `json:"message"`
+	// Attempts and Error record failed sends; the message is retried on the
+	// next run until Failed is set, after maxSendAttempts or an error that
+	// retrying won't fix. Editing or rescheduling the message clears them
+	Attempts int    `json:"attempts,omitempty"`
+	Error    string `json:"error,omitempty"`
+	Failed   bool   `json:"failed,omitempty"`
+	// SentID is the Gmail ID of the sent message, set by SendDue
+	SentID string `json:"sentId,omitempty"`
+}
+
+// SendQueue is the local queue of scheduled messages, soonest first
+type SendQueue struct {
+	Messages []QueuedMessage `json:"messages"`
+}
+
+// LoadSendQueue reads the queue at path; a missing file is empty
+func LoadSendQueue(path string) (*SendQueue, error) {
+	q := &SendQueue{}
+	if _, err := LoadState(path, q); err != nil {
+		return nil, err
+	}
+	return q, nil
+}
+
+// FindQueued returns the queued message with the given ID
+func (q *SendQueue) FindQueued(id string) (QueuedMessage, error) {
+	i := slices.IndexFunc(q.Messages, func(m QueuedMessage) bool { return m.ID == id })
+	if i < 0 {
+		return QueuedMessage{}, notFoundError("queued message not found: %s", id)
+	}
+	return q.Messages[i], nil
+}
+
+// lockSendQueue takes the lock of the queue at path, creating its directory
+// if needed
+func lockSendQueue(path string) (func(), error) {
+	if err := os.MkdirAll(filepath.Dir(path), 0700); err != nil {
+		return nil, fmt.Errorf("unable to create state directory: %w", err)
+	}
+	unlock, err := LockFile(path + ".lock")
+	if err != nil {
+		return nil, fmt.Errorf("unable to lock send queue: %w", err)
+	}
+	return unlock, nil
+}
+
+// updateSendQueue applies fn to the queue at path under its lock and saves it
+// unless fn fails. Under WithDryRun nothing is saved
+func updateSendQueue(ctx context.Context, path string, fn func(q *SendQueue) error) error {
+	unlock, err := lockSendQueue(path)
+	if err != nil {
+		return err
+	}
+	defer unlock()
+
+	q, err := LoadSendQueue(path)
+	if err != nil {
+		return err
+	}
+	if err := fn(q); err != nil {
+		return err
+	}
+	return saveSendQueue(ctx, path, q)
+}
+
+// saveSendQueue sorts and saves the queue, except under WithDryRun
+func saveSendQueue(ctx context.Context, path string, q *SendQueue) error {
+	slices.SortStableFunc(q.Messages, func(a, b QueuedMessage) int { return a.At.Compare(b.At) })
+	if IsDryRun(ctx) {
+		return nil
+	}
+	return SaveState(path, q)
+}
+
+// EnqueueMessage adds a message to the queue at path, to be sent by SendDue
+// at the given time
+func EnqueueMessage(ctx context.Context, path string, msg *OutgoingMessage, at time.Time) (QueuedMessage, error) {
+	// Fail now rather than at send time
+	if _, err := msg.Raw(); err != nil {
+		return QueuedMessage{}, err
+	}
+	id := make([]byte, 4)
+	if _, err := rand.Read(id); err != nil {
+		return QueuedMessage{}, fmt.Errorf("unable to generate queue ID: %w", err)
+	}
+
+	queued := QueuedMessage{ID: hex.EncodeToString(id), At: at, Queued: time.Now(), Message: msg}
+	err := updateSendQueue(ctx, path, func(q *SendQueue) error {
+		q.Messages = append(q.Messages, queued)
+		return nil
+	})
+	return queued, err
+}
+
+// UpdateQueued changes a queued message with fn, e.g. to edit or reschedule it
+func UpdateQueued(ctx context.Context, path, id string, fn func(m *QueuedMessage) error) (QueuedMessage, error) {
+	var updated QueuedMessage
+	err := updateSendQueue(ctx, path, func(q *SendQueue) error {
+		i := slices.IndexFunc(q.Messages, func(m QueuedMessage) bool { return m.ID == id })
+		if i < 0 {
+			return notFoundError("queued message not found: %s", id)
+		}
+		if err := fn(&q.Messages[i]); err != nil {
+			return err
+		}
+		if _, err := q.Messages[i].Message.Raw(); err != nil {
+			return err
+		}
+		q.Messages[i].Attempts, q.Messages[i].Error, q.Messages[i].Failed = 0, "", false
+		updated = q.Messages[i]
+		return nil
+	})
+	return updated, err
+}
+
+// CancelQueued removes messages from the queue at path without sending them
+func CancelQueued(ctx context.Context, path string, ids []string) ([]QueuedMessage, error) {
+	var canceled []QueuedMessage
+	err := updateSendQueue(ctx, path, func(q *SendQueue) error {
+		for _, id := range ids {
+			m, err := q.FindQueued(id)
+			if err != nil {
+				return err
+			}
+			canceled = append(canceled, m)
+			q.Messages = slices.DeleteFunc(q.Messages, func(m QueuedMessage) bool { return m.ID == id })
+		}
+		return nil
+	})
+	if err != nil {
+		return nil, err
+	}
+	return canceled, nil
+}
+
+// SendDue sends the messages of the queue at path that are due at now and
+// removes them from the queue, which is saved after every message so none is
+// sent twice. The queue stays locked meanwhile, so overlapping runs (cron and
+// a daemon) wait for each other. Failed messages stay queued with their error
+// and are returned joined; messages marked Failed are skipped
+func SendDue(ctx context.Context, svc *Service, path string, now time.Time) ([]QueuedMessage, error) {
+	unlock, err := lockSendQueue(path)
+	if err != nil {
+		return nil, err
+	}
+	defer unlock()
+
+	q, err := LoadSendQueue(path)
+	if err != nil {
+		return nil, err
+	}
+
+	var sent []QueuedMessage
+	var errs []error
+	for _, m := range slices.Clone(q.Messages) {
+		if m.Failed || m.At.After(now) {
+			continue
+		}
+		msg, err := SendMessage(ctx, svc, m.Message)
+		if err != nil {
+			if ctx.Err() != nil {
+				return sent, err
+			}
+			errs = append(errs, fmt.Errorf("unable to send queued message %s: %w", m.ID, err))
+			i := slices.IndexFunc(q.Messages, func(q QueuedMessage) bool { return q.ID == m.ID })
+			q.Messages[i].Attempts++
+			q.Messages[i].Error = err.Error()
+			q.Messages[i].Failed = q.Messages[i].Attempts >= maxSendAttempts || permanentSendError(err)
+		} else {
+			m.SentID = msg.Id
+			sent = append(sent, m)
+			q.Messages = slices.DeleteFunc(q.Messages, func(q QueuedMessage) bool { return q.ID == m.ID })
+		}
+		if err := saveSendQueue(ctx, path, q); err != nil {
+			return sent, err
+		}
+	}
+	return sent, errors.Join(errs...)
+}
+
+// permanentSendError reports whether sending failed for a reason retrying
+// won't fix, such as a message Gmail rejects or an invalid recipient. Auth,
+// scope and quota errors can be resolved meanwhile and are retried
+func permanentSendError(err error) bool {
+	var apiErr *googleapi.Error
+	if !errors.As(err, &apiErr) || ErrorKind(err) != nil {
+		return false
+	}
+	return apiErr.Code >= 400 && apiErr.Code < 500 && apiErr.Code != http.StatusRequestTimeout
+}
+
+// FormatSendQueue outputs queued messages in the specified format
+func FormatSendQueue(w io.Writer, messages []QueuedMessage, format OutputFormat) error {
+	if format == OutputFormatJSON {
+		if messages == nil {
+			messages = []QueuedMessage{}
+		}
+		return FormatJSON(w, messages)
+	}
+
+	table := tablewriter.NewWriter(w)
+	table.Header("ID", "AT", "TO", "SUBJECT", "ERROR")
+	for _, m := range messages {
+		to := append(append(slices.Clone(m.Message.To), m.Message.Cc...), m.Message.Bcc...)
+		errText := m.Error
+		if m.Failed {
+			errText = "failed, not retried: " + errText
+		}
+		table.Append(m.ID, m.At.Local().Format("2006-01-02 15:04"), strings.Join(to, ", "), m.Message.Subject, errText)
+	}
+	table.Render()
+	return nil
+}
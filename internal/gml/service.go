@@ -2,6 +2,10 @@ package gml
 
 import (
 	"context"
+	"errors"
+	"fmt"
+	"io"
+	"net/http"
 
 	"github.com/longkey1/gml/internal/google"
 )
@@ -9,32 +13,116 @@ import (
 // Service represents the gml application service
 type Service struct {
 	Gmail *google.GmailService
+	// API is the Gmail API of the core message and label functions, Gmail
+	// unless the service was made with NewServiceWithClient
+	API google.GmailClient
+	// People looks up contacts; its requests need the "contacts" or
+	// "contacts.other" scope
+	People *google.PeopleService
+	// Account is the name of the account profile (empty for top-level credentials)
+	Account string
 }
 
 // NewService creates a new gml service based on the configuration
 func NewService(ctx context.Context, config *Config) (*Service, error) {
-	auth := newAuthenticator(config)
+	auth, err := newAuthenticator(config)
+	if err != nil {
+		return nil, err
+	}
 
-	gmailSvc, err := google.NewGmailService(ctx, auth)
+	// Both APIs share the client, and so the token source
+	client, err := google.NewClient(ctx, auth, config.HTTPHeader())
+	if err != nil {
+		return nil, err
+	}
+	gmailSvc, err := google.NewGmailService(ctx, client)
+	if err != nil {
+		return nil, err
+	}
+	peopleSvc, err := google.NewPeopleService(ctx, client)
 	if err != nil {
 		return nil, err
 	}
 
 	return &Service{
-		Gmail: gmailSvc,
+		Gmail:   gmailSvc,
+		API:     gmailSvc,
+		People:  peopleSvc,
+		Account: config.Account,
 	}, nil
 }
 
-func newAuthenticator(config *Config) google.Authenticator {
+// ErrUnsupportedClient is returned by the functions that need more of the
+// Gmail API than GmailClient when the service was made by NewServiceWithClient
+var ErrUnsupportedClient = errors.New("not supported by this Gmail client")
+
+// NewServiceWithClient creates a service on top of client, e.g. a fake for
+// tests. Only the functions using Service.API work with it: reading,
+// listing, counting, labeling and trashing messages and threads, attachments,
+// labels, the profile and the reports built on them. The others, i.e.
+// drafts, sending, importing, purging, filters, settings, watch and history,
+// raw API calls and contacts, fail with an error wrapping ErrUnsupportedClient
+func NewServiceWithClient(client google.GmailClient, account string) *Service {
+	// Services that send nothing, so the other functions fail cleanly
+	unsupported := &http.Client{Transport: unsupportedTransport{}}
+	gmailSvc, _ := google.NewGmailService(context.Background(), unsupported)
+	peopleSvc, _ := google.NewPeopleService(context.Background(), unsupported)
+	return &Service{Gmail: gmailSvc, API: client, People: peopleSvc, Account: account}
+}
+
+// unsupportedTransport fails every request with ErrUnsupportedClient
+type unsupportedTransport struct{}
+
+func (unsupportedTransport) RoundTrip(req *http.Request) (*http.Response, error) {
+	if req.Body != nil {
+		req.Body.Close()
+	}
+	return nil, fmt.Errorf("%s %s: %w", req.Method, req.URL.Path, ErrUnsupportedClient)
+}
+
+// WithDryRun returns a context in which services report API requests that
+// change state as JSON lines on w instead of sending them
+func WithDryRun(ctx context.Context, w io.Writer) context.Context {
+	return google.WithDryRun(ctx, w)
+}
+
+// IsDryRun reports whether ctx was created by WithDryRun, for changes made
+// outside the Gmail API
+func IsDryRun(ctx context.Context) bool {
+	return google.IsDryRun(ctx)
+}
+
+// ErrRequestBudget is returned by API calls made after the budget of
+// WithRequestBudget was used up
+var ErrRequestBudget = google.ErrRequestBudget
+
+// WithRequestBudget returns a context in which services send at most limit
+// API requests (0 for no limit), counted by RequestsUsed
+func WithRequestBudget(ctx context.Context, limit int) context.Context {
+	return google.WithRequestBudget(ctx, limit)
+}
+
+// RequestsUsed returns the number of API requests sent with a context
+// created by WithRequestBudget
+func RequestsUsed(ctx context.Context) int {
+	return google.RequestsUsed(ctx)
+}
+
+func newAuthenticator(config *Config) (google.Authenticator, error) {
 	switch config.AuthType {
 	case AuthTypeServiceAccount:
-		return google.NewServiceAccountAuthenticator(config.GoogleApplicationCredentials)
+		return google.NewServiceAccountAuthenticator(config.GoogleApplicationCredentials), nil
 	case AuthTypeOAuth:
 		fallthrough
 	default:
-		return google.NewOAuthAuthenticator(
-			config.GoogleApplicationCredentials,
-			config.GoogleUserCredentials,
-		)
+		cfg := *config
+		cfg.AuthType = AuthTypeOAuth
+		return newOAuthAuthenticator(&cfg)
 	}
 }
+
+// LockFile takes an exclusive advisory lock on path, creating it if needed,
+// and returns a function that releases it; it's a no-op without flock
+func LockFile(path string) (func(), error) {
+	return google.LockFile(path)
+}
@@ -0,0 +1,82 @@
+package gml
+
+import (
+	"context"
+	"errors"
+	"fmt"
+	"sync"
+	"testing"
+	"time"
+
+	"github.com/longkey1/gml/internal/fakegmail"
+	"github.com/longkey1/gml/internal/google"
+	"google.golang.org/api/gmail/v1"
+)
+
+// testEmail is the address of the fake mailboxes
+const testEmail = "me@example.com"
+
+// testDate is the date of the oldest message added by addMessages
+var testDate = time.Date(2025, 3, 14, 9, 0, 0, 0, time.UTC)
+
+// recordingClient is a fake mailbox that records the requests it gets
+type recordingClient struct {
+	*fakegmail.Client
+
+	mu      sync.Mutex
+	lists   []google.ListMessagesOptions
+	batches []int
+}
+
+func (c *recordingClient) ListMessages(ctx context.Context, opts google.ListMessagesOptions) (*gmail.ListMessagesResponse, error) {
+	c.mu.Lock()
+	c.lists = append(c.lists, opts)
+	c.mu.Unlock()
+	return c.Client.ListMessages(ctx, opts)
+}
+
+func (c *recordingClient) BatchModifyMessages(ctx context.Context, req *gmail.BatchModifyMessagesRequest) error {
+	c.mu.Lock()
+	c.batches = append(c.batches, len(req.Ids))
+	c.mu.Unlock()
+	return c.Client.BatchModifyMessages(ctx, req)
+}
+
+// newTestService returns a service over an empty fake mailbox
+func newTestService(t *testing.T) (*Service, *recordingClient) {
+	t.Helper()
+	fake := &recordingClient{Client: fakegmail.New(testEmail)}
+	return NewServiceWithClient(fake, ""), fake
+}
+
+// addMessages adds n messages based on m, an hour apart, and returns their IDs
+// oldest first. Subjects are numbered when m has none
+func addMessages(fake *recordingClient, n int, m fakegmail.Message) []string {
+	ids := make([]string, n)
+	for i := range n {
+		msg := m
+		msg.Date = testDate.Add(time.Duration(i) * time.Hour)
+		if msg.Subject == "" {
+			msg.Subject = fmt.Sprintf("message %d", i)
+		}
+		ids[i] = fake.AddMessage(msg)
+	}
+	return ids
+}
+
+func TestNewServiceWithClientUnsupported(t *testing.T) {
+	svc, _ := newTestService(t)
+	ctx := context.Background()
+
+	if _, err := ListFilters(ctx, svc); !errors.Is(err, ErrUnsupportedClient) {
+		t.Errorf("ListFilters() error = %v, want ErrUnsupportedClient", err)
+	}
+	if _, err := SearchContacts(ctx, svc, "bob", 0); !errors.Is(err, ErrUnsupportedClient) {
+		t.Errorf("SearchContacts() error = %v, want ErrUnsupportedClient", err)
+	}
+
+	// Functions on the GmailClient work
+	if email, err := GetUserEmail(ctx, svc); err != nil || email != testEmail {
+		t.Errorf("GetUserEmail() = %q, %v, want %q", email, err, testEmail)
+	}
+}
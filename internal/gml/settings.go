@@ -0,0 +1,185 @@
+package gml
+
+import (
+	"context"
+	"encoding/json"
+	"fmt"
+	"io"
+
+	"google.golang.org/api/gmail/v1"
+)
+
+// MailSettings holds account settings that can be exported and applied to
+// other accounts; nil sections are left unchanged by ApplyMailSettings
+type MailSettings struct {
+	Forwarding *ForwardingSettings `json:"forwarding,omitempty"`
+	Imap       *ImapSettings       `json:"imap,omitempty"`
+	Pop        *PopSettings        `json:"pop,omitempty"`
+}
+
+// ForwardingSettings holds auto-forwarding settings
+type ForwardingSettings struct {
+	Enabled      bool   `json:"enabled"`
+	EmailAddress string `json:"emailAddress,omitempty"`
+	// Disposition is what happens to forwarded messages:
+	// leaveInInbox, archive, trash or markRead
+	Disposition string `json:"disposition,omitempty"`
+}
+
+// ImapSettings holds IMAP access settings
+type ImapSettings struct {
+	Enabled     bool `json:"enabled"`
+	AutoExpunge bool `json:"autoExpunge"`
+	// ExpungeBehavior is archive, trash or deleteForever
+	ExpungeBehavior string `json:"expungeBehavior,omitempty"`
+	MaxFolderSize   int64  `json:"maxFolderSize"`
+}
+
+// PopSettings holds POP access settings
+type PopSettings struct {
+	// AccessWindow is disabled, fromNowOn or allMail
+	AccessWindow string `json:"accessWindow"`
+	// Disposition is leaveInInbox, archive, trash or markRead
+	Disposition string `json:"disposition,omitempty"`
+}
+
+// GetMailSettings returns forwarding, IMAP and POP settings
+func GetMailSettings(ctx context.Context, svc *Service) (*MailSettings, error) {
+	forwarding, err := GetForwarding(ctx, svc)
+	if err != nil {
+		return nil, err
+	}
+	imap, err := GetImap(ctx, svc)
+	if err != nil {
+		return nil, err
+	}
+	pop, err := GetPop(ctx, svc)
+	if err != nil {
+		return nil, err
+	}
+	return &MailSettings{Forwarding: forwarding, Imap: imap, Pop: pop}, nil
+}
+
+// ApplyMailSettings updates every section present in settings
+func ApplyMailSettings(ctx context.Context, svc *Service, settings *MailSettings) (*MailSettings, error) {
+	applied := &MailSettings{}
+	var err error
+	if settings.Forwarding != nil {
+		if applied.Forwarding, err = SetForwarding(ctx, svc, settings.Forwarding); err != nil {
+			return nil, err
+		}
+	}
+	if settings.Imap != nil {
+		if applied.Imap, err = SetImap(ctx, svc, settings.Imap); err != nil {
+			return nil, err
+		}
+	}
+	if settings.Pop != nil {
+		if applied.Pop, err = SetPop(ctx, svc, settings.Pop); err != nil {
+			return nil, err
+		}
+	}
+	return applied, nil
+}
+
+// ReadMailSettings parses settings JSON as written by FormatMailSettings
+func ReadMailSettings(r io.Reader) (*MailSettings, error) {
+	var settings MailSettings
+	if err := json.NewDecoder(r).Decode(&settings); err != nil {
+		return nil, fmt.Errorf("unable to parse settings: %w", err)
+	}
+	return &settings, nil
+}
+
+// GetForwarding returns the auto-forwarding settings
+func GetForwarding(ctx context.Context, svc *Service) (*ForwardingSettings, error) {
+	f, err := svc.Gmail.Users.Settings.GetAutoForwarding("me").Context(ctx).Do()
+	if err != nil {
+		return nil, fmt.Errorf("unable to get forwarding settings: %w", apiError(err))
+	}
+	return &ForwardingSettings{
+		Enabled:      f.Enabled,
+		EmailAddress: f.EmailAddress,
+		Disposition:  f.Disposition,
+	}, nil
+}
+
+// SetForwarding updates the auto-forwarding settings
+// The address must already be a verified forwarding address
+func SetForwarding(ctx context.Context, svc *Service, settings *ForwardingSettings) (*ForwardingSettings, error) {
+	f, err := svc.Gmail.Users.Settings.UpdateAutoForwarding("me", &gmail.AutoForwarding{
+		Enabled:         settings.Enabled,
+		EmailAddress:    settings.EmailAddress,
+		Disposition:     settings.Disposition,
+		ForceSendFields: []string{"Enabled"},
+	}).Context(ctx).Do()
+	if err != nil {
+		return nil, fmt.Errorf("unable to update forwarding settings: %w", apiError(err))
+	}
+	return &ForwardingSettings{
+		Enabled:      f.Enabled,
+		EmailAddress: f.EmailAddress,
+		Disposition:  f.Disposition,
+	}, nil
+}
+
+// GetImap returns the IMAP settings
+func GetImap(ctx context.Context, svc *Service) (*ImapSettings, error) {
+	s, err := svc.Gmail.Users.Settings.GetImap("me").Context(ctx).Do()
+	if err != nil {
+		return nil, fmt.Errorf("unable to get IMAP settings: %w", apiError(err))
+	}
+	return &ImapSettings{
+		Enabled:         s.Enabled,
+		AutoExpunge:     s.AutoExpunge,
+		ExpungeBehavior: s.ExpungeBehavior,
+		MaxFolderSize:   s.MaxFolderSize,
+	}, nil
+}
+
+// SetImap updates the IMAP settings
+func SetImap(ctx context.Context, svc *Service, settings *ImapSettings) (*ImapSettings, error) {
+	s, err := svc.Gmail.Users.Settings.UpdateImap("me", &gmail.ImapSettings{
+		Enabled:         settings.Enabled,
+		AutoExpunge:     settings.AutoExpunge,
+		ExpungeBehavior: settings.ExpungeBehavior,
+		MaxFolderSize:   settings.MaxFolderSize,
+		ForceSendFields: []string{"Enabled", "AutoExpunge", "MaxFolderSize"},
+	}).Context(ctx).Do()
+	if err != nil {
+		return nil, fmt.Errorf("unable to update IMAP settings: %w", apiError(err))
+	}
+	return &ImapSettings{
+		Enabled:         s.Enabled,
+		AutoExpunge:     s.AutoExpunge,
+		ExpungeBehavior: s.ExpungeBehavior,
+		MaxFolderSize:   s.MaxFolderSize,
+	}, nil
+}
+
+// GetPop returns the POP settings
+func GetPop(ctx context.Context, svc *Service) (*PopSettings, error) {
+	s, err := svc.Gmail.Users.Settings.GetPop("me").Context(ctx).Do()
+	if err != nil {
+		return nil, fmt.Errorf("unable to get POP settings: %w", apiError(err))
+	}
+	return &PopSettings{
+		AccessWindow: s.AccessWindow,
+		Disposition:  s.Disposition,
+	}, nil
+}
+
+// SetPop updates the POP settings
+func SetPop(ctx context.Context, svc *Service, settings *PopSettings) (*PopSettings, error) {
+	s, err := svc.Gmail.Users.Settings.UpdatePop("me", &gmail.PopSettings{
+		AccessWindow: settings.AccessWindow,
+		Disposition:  settings.Disposition,
+	}).Context(ctx).Do()
+	if err != nil {
+		return nil, fmt.Errorf("unable to update POP settings: %w", apiError(err))
+	}
+	return &PopSettings{
+		AccessWindow: s.AccessWindow,
+		Disposition:  s.Disposition,
+	}, nil
+}
@@ -0,0 +1,158 @@
+package gml
+
+import (
+	"context"
+	"errors"
+	"fmt"
+	"strconv"
+	"strings"
+	"time"
+
+	"github.com/longkey1/gml/internal/google"
+	"google.golang.org/api/gmail/v1"
+)
+
+// OverdueMessage represents a message that has exceeded an age threshold
+type OverdueMessage struct {
+	Account  string        `json:"account,omitempty"`
+	ID       string        `json:"id"`
+	ThreadID string        `json:"threadId"`
+	From     string        `json:"from"`
+	Subject  string        `json:"subject"`
+	Received time.Time     `json:"received"`
+	Age      time.Duration `json:"-"`
+	AgeText  string        `json:"age"`
+}
+
+// SLAOptions contains options for checking message age
+type SLAOptions struct {
+	Query     string
+	LabelIDs  []string
+	OlderThan time.Duration
+	Now       time.Time
+}
+
+// FindOverdueMessages returns messages matching the query that are older than the threshold.
+// A message that can't be read fails the check rather than being left out, so
+// an error never passes for "nothing overdue"; only messages deleted since the
+// listing are skipped
+func FindOverdueMessages(ctx context.Context, svc *Service, opts SLAOptions) ([]OverdueMessage, error) {
+	now := opts.Now
+	if now.IsZero() {
+		now = time.Now()
+	}
+	cutoff := now.Add(-opts.OlderThan)
+
+	// Gmail accepts epoch seconds for before:, which narrows the search server-side
+	query := strings.TrimSpace(fmt.Sprintf("%s before:%d", opts.Query, cutoff.Unix()))
+
+	var labelIDs []string
+	if len(opts.LabelIDs) > 0 {
+		idx, err := FetchLabelIndex(ctx, svc)
+		if err != nil {
+			return nil, err
+		}
+		labelIDs, err = idx.ResolveLabelIDs(opts.LabelIDs)
+		if err != nil {
+			return nil, err
+		}
+	}
+
+	ids, err := ListMessageIDs(ctx, svc, query, labelIDs)
+	if err != nil {
+		return nil, err
+	}
+
+	var overdue []OverdueMessage
+	for _, id := range ids {
+		msg, err := svc.API.GetMessage(ctx, id, google.GetMessageOptions{
+			Format:          "metadata",
+			MetadataHeaders: []string{"From", "Subject"},
+		})
+		if errors.Is(apiError(err), ErrNotFound) {
+			// Deleted since it was listed
+			continue
+		}
+		if err != nil {
+			return nil, fmt.Errorf("unable to retrieve message %s: %w", id, apiError(err))
+		}
+
+		received := time.UnixMilli(msg.InternalDate)
+		if received.After(cutoff) {
+			continue
+		}
+		age := now.Sub(received)
+		overdue = append(overdue, OverdueMessage{
+			ID:       msg.Id,
+			ThreadID: msg.ThreadId,
+			From:     headerValue(msg.Payload, "From"),
+			Subject:  headerValue(msg.Payload, "Subject"),
+			Received: received,
+			Age:      age,
+			AgeText:  FormatAge(age),
+		})
+	}
+
+	return overdue, nil
+}
+
+// headerValue returns the value of the named header, or empty string if absent
+func headerValue(payload *gmail.MessagePart, name string) string {
+	if payload == nil {
+		return ""
+	}
+	for _, header := range payload.Headers {
+		if strings.EqualFold(header.Name, name) {
+			return DecodeHeader(header.Value)
+		}
+	}
+	return ""
+}
+
+// ParseAge parses an age threshold such as "30m", "4h", "2d", "1w" or "2y"
+// In addition to time.ParseDuration units, d (days), w (weeks) and y (365 days) are supported
+func ParseAge(s string) (time.Duration, error) {
+	s = strings.TrimSpace(s)
+	if s == "" {
+		return 0, fmt.Errorf("age is empty")
+	}
+
+	unit := s[len(s)-1]
+	if unit == 'd' || unit == 'w' || unit == 'y' {
+		n, err := strconv.Atoi(s[:len(s)-1])
+		if err != nil || n < 0 {
+			return 0, fmt.Errorf("invalid age: %s", s)
+		}
+		days := n
+		switch unit {
+		case 'w':
+			days = n * 7
+		case 'y':
+			days = n * 365
+		}
+		return time.Duration(days) * 24 * time.Hour, nil
+	}
+
+	d, err := time.ParseDuration(s)
+	if err != nil || d < 0 {
+		return 0, fmt.Errorf("invalid age: %s", s)
+	}
+	return d, nil
+}
+
+// FormatAge formats a duration as a compact age like "3d4h" or "45m"
+func FormatAge(d time.Duration) string {
+	d = d.Truncate(time.Minute)
+	days := int(d / (24 * time.Hour))
+	hours := int(d % (24 * time.Hour) / time.Hour)
+	minutes := int(d % time.Hour / time.Minute)
+
+	switch {
+	case days > 0:
+		return fmt.Sprintf("%dd%dh", days, hours)
+	case hours > 0:
+		return fmt.Sprintf("%dh%dm", hours, minutes)
+	default:
+		return fmt.Sprintf("%dm", minutes)
+	}
+}
@@ -0,0 +1,82 @@
+package gml
+
+import (
+	"context"
+	"errors"
+	"net/http"
+	"testing"
+	"time"
+
+	"github.com/longkey1/gml/internal/fakegmail"
+	"google.golang.org/api/googleapi"
+)
+
+func TestFindOverdueMessages(t *testing.T) {
+	// addMessages dates messages an hour apart from testDate, so the six
+	// before testDate+6h are more than a day old
+	now := testDate.Add(30 * time.Hour)
+	overdueOpts := SLAOptions{LabelIDs: []string{"inbox"}, OlderThan: 24 * time.Hour, Now: now}
+
+	tests := []struct {
+		name string
+		// fail is the error of GetMessage for the oldest message
+		fail     error
+		want     int
+		wantKind error
+		wantErr  bool
+	}{
+		{name: "all readable", want: 6},
+		{name: "deleted since listed", fail: &googleapi.Error{Code: http.StatusNotFound}, want: 5},
+		{name: "server error", fail: &googleapi.Error{Code: http.StatusInternalServerError}, wantErr: true},
+		{name: "quota", fail: &googleapi.Error{Code: http.StatusTooManyRequests}, wantErr: true, wantKind: ErrQuotaExceeded},
+		{name: "expired token", fail: &googleapi.Error{Code: http.StatusUnauthorized}, wantErr: true, wantKind: ErrAuthExpired},
+	}
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			svc, fake := newTestService(t)
+			ids := addMessages(fake, 10, fakegmail.Message{From: "customer@example.com", LabelIDs: []string{"INBOX"}})
+			fake.Fail = func(method, id string) error {
+				if method == "GetMessage" && id == ids[0] {
+					return tt.fail
+				}
+				return nil
+			}
+
+			overdue, err := FindOverdueMessages(context.Background(), svc, overdueOpts)
+			if tt.wantErr {
+				// A message that can't be read must not pass the check
+				if err == nil {
+					t.Fatalf("FindOverdueMessages() = %d messages, want an error", len(overdue))
+				}
+				if ErrorKind(err) != tt.wantKind {
+					t.Errorf("ErrorKind() = %v, want %v", ErrorKind(err), tt.wantKind)
+				}
+				return
+			}
+			if err != nil {
+				t.Fatal(err)
+			}
+			if len(overdue) != tt.want {
+				t.Fatalf("FindOverdueMessages() = %d messages, want %d", len(overdue), tt.want)
+			}
+			newest := overdue[0]
+			if newest.ID != ids[5] || newest.AgeText != "1d1h" || newest.From != "customer@example.com" {
+				t.Errorf("FindOverdueMessages() newest = %+v, want %s aged 1d1h", newest, ids[5])
+			}
+		})
+	}
+}
+
+func TestFindOverdueMessagesListFails(t *testing.T) {
+	svc, fake := newTestService(t)
+	addMessages(fake, 3, fakegmail.Message{LabelIDs: []string{"INBOX"}})
+	fake.Fail = func(method, id string) error {
+		if method == "ListMessages" {
+			return errors.New("connection reset")
+		}
+		return nil
+	}
+	if _, err := FindOverdueMessages(context.Background(), svc, SLAOptions{OlderThan: time.Hour}); err == nil {
+		t.Error("FindOverdueMessages() with a failing listing: want an error")
+	}
+}
@@ -0,0 +1,206 @@
+package gml
+
+import (
+	"context"
+	"errors"
+	"fmt"
+	"io"
+	"log/slog"
+	"os"
+	"path/filepath"
+	"slices"
+	"time"
+
+	"github.com/longkey1/gml/internal/google"
+	"github.com/olekukonko/tablewriter"
+	"google.golang.org/api/gmail/v1"
+)
+
+// SnoozeStoreName is the state file name of the snooze schedule
+const SnoozeStoreName = "snooze"
+
+// SnoozedMessage is a message taken out of the inbox until a given time
+type SnoozedMessage struct {
+	ID      string    `json:"id"`
+	From    string    `json:"from,omitempty"`
+	Subject string    `json:"subject,omitempty"`
+	Until   time.Time `json:"until"`
+	Snoozed time.Time `json:"snoozed"`
+}
+
+// SnoozeSchedule is the local store of snoozed messages, soonest first
+type SnoozeSchedule struct {
+	Messages []SnoozedMessage `json:"messages"`
+}
+
+// LoadSnoozeSchedule reads the schedule at path; a missing file is empty
+func LoadSnoozeSchedule(path string) (*SnoozeSchedule, error) {
+	s := &SnoozeSchedule{}
+	if _, err := LoadState(path, s); err != nil {
+		return nil, err
+	}
+	return s, nil
+}
+
+// updateSnoozeSchedule applies fn to the schedule at path and saves it,
+// re-reading the file under its lock so entries added by another gml in the
+// meantime are kept. Under WithDryRun nothing is saved
+func updateSnoozeSchedule(ctx context.Context, path string, fn func(s *SnoozeSchedule)) error {
+	if err := os.MkdirAll(filepath.Dir(path), 0700); err != nil {
+		return fmt.Errorf("unable to create state directory: %w", err)
+	}
+	unlock, err := LockFile(path + ".lock")
+	if err != nil {
+		return fmt.Errorf("unable to lock snooze schedule: %w", err)
+	}
+	defer unlock()
+
+	s, err := LoadSnoozeSchedule(path)
+	if err != nil {
+		return err
+	}
+	fn(s)
+	slices.SortStableFunc(s.Messages, func(a, b SnoozedMessage) int { return a.Until.Compare(b.Until) })
+	if IsDryRun(ctx) {
+		return nil
+	}
+	return SaveState(path, s)
+}
+
+// SnoozeMessages removes messages from the inbox and records them in the
+// schedule at path until the given time; snoozing a snoozed message moves its
+// wake-up time. Messages that couldn't be snoozed are recorded in failures
+func SnoozeMessages(ctx context.Context, svc *Service, path string, ids []string, until time.Time, failures *FailureLog) ([]SnoozedMessage, error) {
+	now := time.Now()
+	var snoozed []SnoozedMessage
+	var err error
+	for _, id := range ids {
+		var msg *gmail.Message
+		msg, err = svc.API.GetMessage(ctx, id, google.GetMessageOptions{
+			Format:          "metadata",
+			MetadataHeaders: []string{"From", "Subject"},
+		})
+		if err == nil {
+			err = ModifyMessage(ctx, svc, id, nil, []string{LabelInbox})
+		} else {
+			err = fmt.Errorf("unable to retrieve message: %w", apiError(err))
+		}
+		if err != nil {
+			if err = failures.Record(fmt.Errorf("unable to snooze %s: %w", id, err), id); err != nil {
+				break
+			}
+			continue
+		}
+		snoozed = append(snoozed, SnoozedMessage{
+			ID:      msg.Id,
+			From:    headerValue(msg.Payload, "From"),
+			Subject: headerValue(msg.Payload, "Subject"),
+			Until:   until,
+			Snoozed: now,
+		})
+	}
+
+	// Record what was archived, also when a later message failed
+	if len(snoozed) > 0 {
+		saveErr := updateSnoozeSchedule(ctx, path, func(s *SnoozeSchedule) {
+			s.Messages = slices.DeleteFunc(s.Messages, func(m SnoozedMessage) bool {
+				return slices.ContainsFunc(snoozed, func(n SnoozedMessage) bool { return n.ID == m.ID })
+			})
+			s.Messages = append(s.Messages, snoozed...)
+		})
+		if saveErr != nil {
+			return snoozed, saveErr
+		}
+	}
+	return snoozed, err
+}
+
+// WakeSnoozed returns the messages of the schedule at path that are due at now
+// to the inbox, marked unread, and drops them from the schedule. Messages that
+// no longer exist are dropped too; other failures are kept for the next run
+// and returned joined
+func WakeSnoozed(ctx context.Context, svc *Service, path string, now time.Time) ([]SnoozedMessage, error) {
+	s, err := LoadSnoozeSchedule(path)
+	if err != nil {
+		return nil, err
+	}
+
+	var woken []SnoozedMessage
+	done := make(map[string]time.Time)
+	var errs []error
+	for _, m := range s.Messages {
+		if m.Until.After(now) {
+			continue
+		}
+		if err := ModifyMessage(ctx, svc, m.ID, []string{LabelInbox, "UNREAD"}, nil); err != nil {
+			if errors.Is(err, ErrNotFound) {
+				slog.Warn("dropping snoozed message that no longer exists", "id", m.ID)
+				done[m.ID] = m.Until
+				continue
+			}
+			errs = append(errs, fmt.Errorf("unable to wake %s: %w", m.ID, err))
+			continue
+		}
+		woken = append(woken, m)
+		done[m.ID] = m.Until
+	}
+	if len(done) == 0 {
+		return nil, errors.Join(errs...)
+	}
+
+	err = updateSnoozeSchedule(ctx, path, func(s *SnoozeSchedule) {
+		// Keep entries snoozed again since they were loaded
+		s.Messages = slices.DeleteFunc(s.Messages, func(m SnoozedMessage) bool {
+			until, ok := done[m.ID]
+			return ok && m.Until.Equal(until)
+		})
+	})
+	return woken, errors.Join(append(errs, err)...)
+}
+
+// CancelSnooze drops messages from the schedule at path and, with restore,
+// returns them to the inbox right away. IDs that aren't snoozed are an error
+func CancelSnooze(ctx context.Context, svc *Service, path string, ids []string, restore bool) ([]SnoozedMessage, error) {
+	s, err := LoadSnoozeSchedule(path)
+	if err != nil {
+		return nil, err
+	}
+
+	var canceled []SnoozedMessage
+	for _, id := range ids {
+		i := slices.IndexFunc(s.Messages, func(m SnoozedMessage) bool { return m.ID == id })
+		if i < 0 {
+			return canceled, notFoundError("message %s is not snoozed", id)
+		}
+		if restore {
+			if err := ModifyMessage(ctx, svc, id, []string{LabelInbox}, nil); err != nil && !errors.Is(err, ErrNotFound) {
+				return canceled, err
+			}
+		}
+		canceled = append(canceled, s.Messages[i])
+		if err := updateSnoozeSchedule(ctx, path, func(s *SnoozeSchedule) {
+			s.Messages = slices.DeleteFunc(s.Messages, func(m SnoozedMessage) bool { return m.ID == id })
+		}); err != nil {
+			return canceled, err
+		}
+	}
+	return canceled, nil
+}
+
+// FormatSnoozed outputs snoozed messages in the specified format
+func FormatSnoozed(w io.Writer, messages []SnoozedMessage, format OutputFormat) error {
+	if format == OutputFormatJSON {
+		if messages == nil {
+			messages = []SnoozedMessage{}
+		}
+		return FormatJSON(w, messages)
+	}
+
+	table := tablewriter.NewWriter(w)
+	table.Header("ID", "FROM", "SUBJECT", "UNTIL")
+	for _, m := range messages {
+		table.Append(m.ID, m.From, m.Subject, m.Until.Local().Format("2006-01-02 15:04"))
+	}
+	table.Render()
+	return nil
+}
@@ -0,0 +1,82 @@
+package gml
+
+import (
+	"cmp"
+	"fmt"
+	"slices"
+	"strings"
+	"time"
+)
+
+// Sort keys accepted by 'gml list --sort'
+const (
+	SortByDate    = "date"
+	SortByFrom    = "from"
+	SortBySubject = "subject"
+	SortBySize    = "size"
+)
+
+// ParseSortKey validates a sort key; an empty key keeps the API order
+func ParseSortKey(key string) (string, error) {
+	switch key = strings.ToLower(strings.TrimSpace(key)); key {
+	case "", SortByDate, SortByFrom, SortBySubject, SortBySize:
+		return key, nil
+	default:
+		return "", fmt.Errorf("unknown sort key: %s (use date, from, subject or size)", key)
+	}
+}
+
+// SortMessages sorts messages in place: dates newest first, sizes largest
+// first, from and subject alphabetically ignoring case. reverse flips the order; ties keep API order.
+// Dates must still be raw headers; unparseable dates sort as oldest
+func SortMessages(messages []MessageInfo, key string, reverse bool) {
+	if key == "" {
+		if reverse {
+			slices.Reverse(messages)
+		}
+		return
+	}
+
+	type sortable struct {
+		msg  MessageInfo
+		date time.Time
+		text string
+	}
+	items := make([]sortable, len(messages))
+	for i, m := range messages {
+		items[i].msg = m
+		switch key {
+		case SortByDate:
+			items[i].date, _ = ParseMailDate(m.Date)
+		case SortByFrom:
+			items[i].text = sortText(m.From)
+		case SortBySubject:
+			items[i].text = sortText(m.Subject)
+		}
+	}
+
+	slices.SortStableFunc(items, func(a, b sortable) int {
+		var c int
+		switch key {
+		case SortByDate:
+			c = b.date.Compare(a.date)
+		case SortBySize:
+			c = cmp.Compare(b.msg.Size, a.msg.Size)
+		default:
+			c = cmp.Compare(a.text, b.text)
+		}
+		if reverse {
+			return -c
+		}
+		return c
+	})
+
+	for i := range items {
+		messages[i] = items[i].msg
+	}
+}
+
+// sortText normalizes a header for alphabetical sorting
+func sortText(s string) string {
+	return strings.ToLower(strings.TrimLeft(s, `"' `))
+}
@@ -0,0 +1,138 @@
+package gml
+
+import (
+	"context"
+	"database/sql"
+	"fmt"
+	"io"
+	"os"
+
+	_ "modernc.org/sqlite"
+)
+
+// sqliteSchema defines the tables written by ExportMessagesSQLite
+// Re-exporting into the same database replaces rows with the same account and ID
+const sqliteSchema = `
+CREATE TABLE IF NOT EXISTS messages (
+	account   TEXT NOT NULL DEFAULT '',
+	id        TEXT NOT NULL,
+	thread_id TEXT,
+	url       TEXT,
+	sender    TEXT,
+	recipient TEXT,
+	subject   TEXT,
+	date      TEXT,
+	snippet   TEXT,
+	body      TEXT,
+	PRIMARY KEY (account, id)
+);
+CREATE TABLE IF NOT EXISTS message_labels (
+	account    TEXT NOT NULL DEFAULT '',
+	message_id TEXT NOT NULL,
+	label      TEXT NOT NULL,
+	PRIMARY KEY (account, message_id, label)
+);
+CREATE INDEX IF NOT EXISTS message_labels_label ON message_labels (label);
+`
+
+// ExportMessagesSQLite writes messages into a SQLite database at dest,
+// creating the schema if needed. For s3:// and gs:// destinations the database
+// is built in a temporary file and then uploaded, replacing any existing object.
+func ExportMessagesSQLite(ctx context.Context, dest string, messages []MessageInfo) error {
+	if !IsRemoteOutput(dest) {
+		return writeMessagesSQLite(ctx, dest, messages)
+	}
+
+	tmp, err := os.CreateTemp("", "gml-*.db")
+	if err != nil {
+		return fmt.Errorf("unable to create temporary database: %w", err)
+	}
+	tmp.Close()
+	defer os.Remove(tmp.Name())
+
+	if err := writeMessagesSQLite(ctx, tmp.Name(), messages); err != nil {
+		return err
+	}
+
+	f, err := os.Open(tmp.Name())
+	if err != nil {
+		return fmt.Errorf("unable to open temporary database: %w", err)
+	}
+	defer f.Close()
+
+	out, err := CreateOutput(ctx, dest)
+	if err != nil {
+		return err
+	}
+	defer out.Abort()
+
+	if _, err := io.Copy(out, f); err != nil {
+		return fmt.Errorf("unable to upload database: %w", err)
+	}
+	return out.Close()
+}
+
+// writeMessagesSQLite writes messages into a local SQLite database file
+func writeMessagesSQLite(ctx context.Context, path string, messages []MessageInfo) error {
+	db, err := sql.Open("sqlite", path)
+	if err != nil {
+		return fmt.Errorf("unable to open database: %w", err)
+	}
+	defer db.Close()
+
+	if _, err := db.ExecContext(ctx, sqliteSchema); err != nil {
+		return fmt.Errorf("unable to create schema: %w", err)
+	}
+
+	tx, err := db.BeginTx(ctx, nil)
+	if err != nil {
+		return fmt.Errorf("unable to begin transaction: %w", err)
+	}
+	defer tx.Rollback()
+
+	insertMessage, err := tx.PrepareContext(ctx, `INSERT OR REPLACE INTO messages
+		(account, id, thread_id, url, sender, recipient, subject, date, snippet, body)
+		VALUES (?, ?, ?, ?, ?, ?, ?, ?, ?, ?)`)
+	if err != nil {
+		return fmt.Errorf("unable to prepare statement: %w", err)
+	}
+	defer insertMessage.Close()
+
+	deleteLabels, err := tx.PrepareContext(ctx, `DELETE FROM message_labels WHERE account = ? AND message_id = ?`)
+	if err != nil {
+		return fmt.Errorf("unable to prepare statement: %w", err)
+	}
+	defer deleteLabels.Close()
+
+	insertLabel, err := tx.PrepareContext(ctx, `INSERT OR IGNORE INTO message_labels (account, message_id, label) VALUES (?, ?, ?)`)
+	if err != nil {
+		return fmt.Errorf("unable to prepare statement: %w", err)
+	}
+	defer insertLabel.Close()
+
+	for _, msg := range messages {
+		if msg.ID == "" {
+			return fmt.Errorf("the id field is required for sqlite output")
+		}
+		if _, err := insertMessage.ExecContext(ctx,
+			msg.Account, msg.ID, msg.ThreadID, msg.URL, msg.From, msg.To,
+			msg.Subject, msg.Date, msg.Snippet, msg.Body,
+		); err != nil {
+			return fmt.Errorf("unable to insert message %s: %w", msg.ID, err)
+		}
+
+		if _, err := deleteLabels.ExecContext(ctx, msg.Account, msg.ID); err != nil {
+			return fmt.Errorf("unable to update labels for %s: %w", msg.ID, err)
+		}
+		for _, label := range msg.Labels {
+			if _, err := insertLabel.ExecContext(ctx, msg.Account, msg.ID, label); err != nil {
+				return fmt.Errorf("unable to insert label for %s: %w", msg.ID, err)
+			}
+		}
+	}
+
+	if err := tx.Commit(); err != nil {
+		return fmt.Errorf("unable to commit transaction: %w", err)
+	}
+	return nil
+}
@@ -0,0 +1,70 @@
+package gml
+
+import (
+	"encoding/json"
+	"errors"
+	"fmt"
+	"os"
+	"path/filepath"
+)
+
+// StateDir returns the directory for persistent runtime state
+// ($XDG_STATE_HOME/gml, defaulting to ~/.local/state/gml)
+func StateDir() (string, error) {
+	if dir := os.Getenv("XDG_STATE_HOME"); dir != "" {
+		return filepath.Join(dir, "gml"), nil
+	}
+	home, err := os.UserHomeDir()
+	if err != nil {
+		return "", fmt.Errorf("unable to determine home directory: %w", err)
+	}
+	return filepath.Join(home, ".local", "state", "gml"), nil
+}
+
+// StatePath returns the path of a named state file, scoped to an account
+func StatePath(name, account string) (string, error) {
+	dir, err := StateDir()
+	if err != nil {
+		return "", err
+	}
+	if account == "" {
+		account = "default"
+	}
+	return filepath.Join(dir, fmt.Sprintf("%s-%s.json", name, account)), nil
+}
+
+// LoadState reads a JSON state file into v
+// It reports false without error if the file does not exist
+func LoadState(path string, v any) (bool, error) {
+	data, err := os.ReadFile(path)
+	if errors.Is(err, os.ErrNotExist) {
+		return false, nil
+	}
+	if err != nil {
+		return false, fmt.Errorf("unable to read state: %w", err)
+	}
+	if err := json.Unmarshal(data, v); err != nil {
+		return false, fmt.Errorf("unable to parse state %s: %w", path, err)
+	}
+	return true, nil
+}
+
+// SaveState atomically writes v as a JSON state file
+func SaveState(path string, v any) error {
+	data, err := json.MarshalIndent(v, "", "  ")
+	if err != nil {
+		return fmt.Errorf("unable to marshal state: %w", err)
+	}
+	if err := os.MkdirAll(filepath.Dir(path), 0700); err != nil {
+		return fmt.Errorf("unable to create state directory: %w", err)
+	}
+
+	tmp := path + ".tmp"
+	if err := os.WriteFile(tmp, data, 0600); err != nil {
+		return fmt.Errorf("unable to write state: %w", err)
+	}
+	if err := os.Rename(tmp, path); err != nil {
+		return fmt.Errorf("unable to write state: %w", err)
+	}
+	return nil
+}
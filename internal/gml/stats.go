@@ -0,0 +1,398 @@
+package gml
+
+import (
+	"context"
+	"fmt"
+	"io"
+	"log/slog"
+	"maps"
+	"slices"
+	"strings"
+	"sync"
+	"time"
+
+	"github.com/longkey1/gml/internal/google"
+	"github.com/olekukonko/tablewriter"
+	"google.golang.org/api/googleapi"
+)
+
+// GroupBys lists the keys messages can be grouped by
+var GroupBys = []GroupBy{GroupBySender, GroupByDomain, GroupByLabel, GroupByDay, GroupByMonth}
+
+// ParseGroupBy parses a --by value
+func ParseGroupBy(s string) (GroupBy, error) {
+	if by := GroupBy(s); slices.Contains(GroupBys, by) {
+		return by, nil
+	}
+	return "", fmt.Errorf("unknown group-by: %s (use sender, domain, label, day or month)", s)
+}
+
+// defaultStatsConcurrency is the number of parallel metadata requests; at 5
+// quota units each it stays well below the per-user rate limit
+const defaultStatsConcurrency = 8
+
+// StatsOptions contains options for MailboxStats
+type StatsOptions struct {
+	Query    string
+	LabelIDs []string
+	By       GroupBy
+	// Limit keeps the largest groups (0 for all)
+	Limit int
+	// MaxMessages stops the crawl after this many messages (0 for all)
+	MaxMessages int
+	// Concurrency is the number of parallel requests (default 8)
+	Concurrency int
+	// Cache, if set, keeps sender and date of messages between runs
+	Cache MessageCache
+	// Progress, if set, is called as message metadata is fetched
+	Progress func(done, total int)
+}
+
+// Stats is the result of MailboxStats
+type Stats struct {
+	Query   string       `json:"query,omitempty"`
+	GroupBy GroupBy      `json:"groupBy"`
+	Total   int          `json:"total"`
+	Cached  int          `json:"cached"`
+	Groups  []GroupCount `json:"groups"`
+}
+
+// MailboxStats counts the messages matching a query per sender, domain,
+// label, day or month. It lists all matching IDs, then fetches the metadata
+// of the messages not in the cache concurrently. Labels change, so grouping
+// by label always fetches
+func MailboxStats(ctx context.Context, svc *Service, opts StatsOptions) (*Stats, error) {
+	idx, err := FetchLabelIndex(ctx, svc)
+	if err != nil {
+		return nil, err
+	}
+	labelIDs, err := idx.ResolveLabelIDs(opts.LabelIDs)
+	if err != nil {
+		return nil, err
+	}
+
+	ids, metas, cached, err := crawlMetadata(ctx, svc, labelIDs, opts, opts.By != GroupByLabel, false)
+	if err != nil {
+		return nil, err
+	}
+
+	messages := make([]MessageInfo, len(metas))
+	for i, m := range metas {
+		messages[i] = MessageInfo{
+			From:   m.From,
+			Date:   time.UnixMilli(m.Date).Format(time.RFC1123Z),
+			Labels: idx.MapLabelIDsToNames(m.Labels),
+		}
+	}
+	groups, err := GroupMessages(messages, opts.By)
+	if err != nil {
+		return nil, err
+	}
+	if opts.Limit > 0 && len(groups) > opts.Limit {
+		groups = groups[:opts.Limit]
+	}
+
+	return &Stats{Query: opts.Query, GroupBy: opts.By, Total: len(ids), Cached: cached, Groups: groups}, nil
+}
+
+// crawlMetadata lists the messages matching the query and labels of opts and
+// returns their metadata, taken from the cache when useCache is set, and how
+// many came from the cache. With needSize, cached entries from before sizes
+// were cached are fetched again
+func crawlMetadata(ctx context.Context, svc *Service, labelIDs []string, opts StatsOptions, useCache, needSize bool) ([]string, []messageMeta, int, error) {
+	ids, err := ListMessageIDs(ctx, svc, opts.Query, labelIDs)
+	if err != nil {
+		return nil, nil, 0, err
+	}
+	if opts.MaxMessages > 0 && len(ids) > opts.MaxMessages {
+		ids = ids[:opts.MaxMessages]
+	}
+
+	var hits map[string]CachedMessage
+	if useCache && opts.Cache != nil {
+		// A failing cache only costs requests
+		if hits, err = opts.Cache.GetMessages(ctx, ids); err != nil {
+			slog.Warn("unable to read message cache", "err", err)
+		}
+	}
+	metas := make([]messageMeta, len(ids))
+	var missing []int
+	cached := 0
+	for i, id := range ids {
+		if m, ok := hits[id]; ok && (!needSize || m.Size > 0) {
+			metas[i] = messageMeta{CachedMessage: m}
+			cached++
+			continue
+		}
+		missing = append(missing, i)
+	}
+
+	if err := fetchMetadata(ctx, svc, ids, missing, metas, opts); err != nil {
+		return nil, nil, 0, err
+	}
+	if opts.Cache != nil && len(missing) > 0 {
+		fetched := make(map[string]CachedMessage, len(missing))
+		for _, i := range missing {
+			fetched[ids[i]] = metas[i].CachedMessage
+		}
+		if err := opts.Cache.PutMessages(ctx, fetched); err != nil {
+			slog.Warn("unable to update message cache", "err", err)
+		}
+	}
+	return ids, metas, cached, nil
+}
+
+// RefreshMetadataCache fetches the sender and date of the messages matching
+// the query that are not cached yet and puts them in the cache (a
+// MetadataCache still has to be saved). Messages fetched before an error are
+// kept, so an interrupted refresh resumes where it stopped. It returns the
+// number of messages fetched
+func RefreshMetadataCache(ctx context.Context, svc *Service, cache MessageCache, opts StatsOptions) (int, error) {
+	var labelIDs []string
+	if len(opts.LabelIDs) > 0 {
+		idx, err := FetchLabelIndex(ctx, svc)
+		if err != nil {
+			return 0, err
+		}
+		if labelIDs, err = idx.ResolveLabelIDs(opts.LabelIDs); err != nil {
+			return 0, err
+		}
+	}
+
+	ids, err := ListMessageIDs(ctx, svc, opts.Query, labelIDs)
+	if err != nil {
+		return 0, err
+	}
+	hits, err := cache.GetMessages(ctx, ids)
+	if err != nil {
+		return 0, err
+	}
+	var missing []int
+	for i, id := range ids {
+		if _, ok := hits[id]; !ok {
+			missing = append(missing, i)
+		}
+	}
+
+	metas := make([]messageMeta, len(ids))
+	fetchErr := fetchMetadata(ctx, svc, ids, missing, metas, opts)
+	fetched := make(map[string]CachedMessage)
+	for _, i := range missing {
+		// Messages not reached before an error have no date
+		if metas[i].Date != 0 {
+			fetched[ids[i]] = metas[i].CachedMessage
+		}
+	}
+	if len(fetched) > 0 {
+		if err := cache.PutMessages(ctx, fetched); err != nil {
+			return 0, err
+		}
+	}
+	return len(fetched), fetchErr
+}
+
+// fetchMetadata fills metas[i] for each index in missing with a pool of workers;
+// the first error cancels the remaining requests
+func fetchMetadata(ctx context.Context, svc *Service, ids []string, missing []int, metas []messageMeta, opts StatsOptions) error {
+	ctx, cancel := context.WithCancel(ctx)
+	defer cancel()
+
+	workers := opts.Concurrency
+	if workers <= 0 {
+		workers = defaultStatsConcurrency
+	}
+
+	jobs := make(chan int)
+	var (
+		wg       sync.WaitGroup
+		mu       sync.Mutex
+		done     int
+		firstErr error
+	)
+	for range min(workers, len(missing)) {
+		wg.Add(1)
+		go func() {
+			defer wg.Done()
+			for i := range jobs {
+				msg, err := svc.API.GetMessage(ctx, ids[i], google.GetMessageOptions{
+					Format:          "metadata",
+					MetadataHeaders: []string{"From"},
+					Fields:          []googleapi.Field{"labelIds", "internalDate", "sizeEstimate", "payload/headers"},
+				})
+
+				mu.Lock()
+				if err != nil {
+					if firstErr == nil {
+						firstErr = fmt.Errorf("unable to retrieve message %s: %w", ids[i], apiError(err))
+						cancel()
+					}
+					mu.Unlock()
+					continue
+				}
+				metas[i] = messageMeta{
+					CachedMessage: CachedMessage{
+						From: headerValue(msg.Payload, "From"),
+						Date: msg.InternalDate,
+						Size: msg.SizeEstimate,
+					},
+					Labels: msg.LabelIds,
+				}
+				done++
+				if opts.Progress != nil {
+					opts.Progress(done, len(missing))
+				}
+				mu.Unlock()
+			}
+		}()
+	}
+
+feed:
+	for _, i := range missing {
+		select {
+		case jobs <- i:
+		case <-ctx.Done():
+			break feed
+		}
+	}
+	close(jobs)
+	wg.Wait()
+
+	if firstErr != nil {
+		return firstErr
+	}
+	return ctx.Err()
+}
+
+// CachedMessage is the metadata of a message kept in a MessageCache; sender,
+// date and size never change
+type CachedMessage struct {
+	From string `json:"f"`
+	// Date is the internal date in Unix milliseconds
+	Date int64 `json:"d"`
+	// Size is the size estimate in bytes; 0 in caches written before sizes were kept
+	Size int64 `json:"s,omitempty"`
+}
+
+// messageMeta is what stats need of a message: the cached metadata and the
+// labels, which change and are never cached
+type messageMeta struct {
+	CachedMessage
+	Labels []string
+}
+
+// MessageCache keeps the metadata of messages between runs, so repeated
+// stats over the same mail only fetch new messages. MetadataCache keeps it
+// in a state file; other stores, e.g. in a database, can be plugged in
+// through the Go API. It must be safe for concurrent use
+type MessageCache interface {
+	// GetMessages returns the cached metadata of those of ids that are cached
+	GetMessages(ctx context.Context, ids []string) (map[string]CachedMessage, error)
+	// PutMessages stores the metadata of messages, replacing cached entries
+	PutMessages(ctx context.Context, messages map[string]CachedMessage) error
+}
+
+// StatsCacheName is the state file caching message senders and dates for
+// stats (see StatePath)
+const StatsCacheName = "stats-cache"
+
+// MetadataCache is the MessageCache of the gml command: the metadata is read
+// from a state file and written back by Save. A nil *MetadataCache caches
+// nothing
+type MetadataCache struct {
+	path string
+
+	mu       sync.Mutex
+	messages map[string]CachedMessage
+	dirty    bool
+}
+
+// NewMetadataCache returns an empty cache that is only kept in memory
+func NewMetadataCache() *MetadataCache {
+	return &MetadataCache{messages: make(map[string]CachedMessage)}
+}
+
+// LoadMetadataCache reads the cache at path; a missing file is an empty cache
+func LoadMetadataCache(path string) (*MetadataCache, error) {
+	c := &MetadataCache{path: path, messages: make(map[string]CachedMessage)}
+	if _, err := LoadState(path, &c.messages); err != nil {
+		return nil, err
+	}
+	return c, nil
+}
+
+// GetMessages returns the cached metadata of messages
+func (c *MetadataCache) GetMessages(ctx context.Context, ids []string) (map[string]CachedMessage, error) {
+	if c == nil {
+		return nil, nil
+	}
+	c.mu.Lock()
+	defer c.mu.Unlock()
+	hits := make(map[string]CachedMessage)
+	for _, id := range ids {
+		if m, ok := c.messages[id]; ok {
+			hits[id] = m
+		}
+	}
+	return hits, nil
+}
+
+// PutMessages stores the metadata of messages until Save
+func (c *MetadataCache) PutMessages(ctx context.Context, messages map[string]CachedMessage) error {
+	if c == nil {
+		return nil
+	}
+	c.mu.Lock()
+	defer c.mu.Unlock()
+	maps.Copy(c.messages, messages)
+	c.dirty = true
+	return nil
+}
+
+// Save writes the cache if it changed; a cache without a file is kept in
+// memory only
+func (c *MetadataCache) Save() error {
+	if c == nil {
+		return nil
+	}
+	c.mu.Lock()
+	defer c.mu.Unlock()
+	if !c.dirty || c.path == "" {
+		return nil
+	}
+	if err := SaveState(c.path, c.messages); err != nil {
+		return err
+	}
+	c.dirty = false
+	return nil
+}
+
+// FormatStats outputs mailbox statistics in the specified format
+func FormatStats(w io.Writer, stats *Stats, format OutputFormat, csvOpts CSVOptions) error {
+	switch format {
+	case OutputFormatJSON:
+		if stats.Groups == nil {
+			stats.Groups = []GroupCount{}
+		}
+		return FormatJSON(w, stats)
+	case OutputFormatCSV, OutputFormatTSV:
+		cw, err := NewCSVWriter(w, format, csvOpts)
+		if err != nil {
+			return err
+		}
+		cw.Write([]string{string(stats.GroupBy), "count"})
+		for _, g := range stats.Groups {
+			cw.Write([]string{g.Key, fmt.Sprint(g.Count)})
+		}
+		cw.Flush()
+		return cw.Error()
+	default:
+		table := tablewriter.NewWriter(w)
+		table.Header(strings.ToUpper(string(stats.GroupBy)), "COUNT", "SHARE")
+		for _, g := range stats.Groups {
+			table.Append(g.Key, g.Count, fmt.Sprintf("%.1f%%", 100*float64(g.Count)/float64(max(stats.Total, 1))))
+		}
+		table.Render()
+		fmt.Fprintf(w, "Total messages: %d\n", stats.Total)
+		return nil
+	}
+}
@@ -0,0 +1,162 @@
+package gml
+
+import (
+	"io"
+	"os"
+	"strconv"
+	"strings"
+
+	"github.com/mattn/go-runewidth"
+	"golang.org/x/term"
+)
+
+// TableOptions controls the layout of text tables
+type TableOptions struct {
+	// Width is the total width to fit, usually the terminal width
+	// (0 keeps the fixed per-column limits)
+	Width int
+	// Wrap wraps long cells onto several lines instead of truncating them
+	Wrap bool
+}
+
+// tableColumnLimits are the fixed widths of flexible columns when the terminal
+// width is unknown, and their starting widths when it is
+var tableColumnLimits = map[string]int{
+	"from":    30,
+	"to":      30,
+	"subject": 40,
+	"snippet": 50,
+}
+
+// tableSlackOrder lists the columns that receive spare width, in order
+var tableSlackOrder = []string{"subject", "snippet", "from", "to", "labels", "attachments"}
+
+// minTableColumnWidth is the narrowest a flexible column is squeezed to
+const minTableColumnWidth = 10
+
+// TerminalWidth returns the width of the terminal w writes to, or 0 if w is
+// not a terminal. A numeric $COLUMNS overrides detection
+func TerminalWidth(w io.Writer) int {
+	if n, err := strconv.Atoi(os.Getenv("COLUMNS")); err == nil && n > 0 {
+		return n
+	}
+	f, ok := w.(*os.File)
+	if !ok || !term.IsTerminal(int(f.Fd())) {
+		return 0
+	}
+	width, _, err := term.GetSize(int(f.Fd()))
+	if err != nil {
+		return 0
+	}
+	return width
+}
+
+// tableColumnWidths distributes the available width over the columns: fixed
+// columns (IDs, dates, URLs) keep their natural width, other flexible columns
+// shrink before the subject when space is short, and the subject gets any
+// slack first
+func tableColumnWidths(columns []string, natural []int, opts TableOptions) []int {
+	widths := make([]int, len(columns))
+	for i, c := range columns {
+		widths[i] = natural[i]
+		if limit, ok := tableColumnLimits[c]; ok {
+			widths[i] = min(natural[i], limit)
+		}
+	}
+	if opts.Width <= 0 {
+		return widths
+	}
+
+	flexible := func(c string) bool {
+		_, ok := tableColumnLimits[c]
+		return ok || c == "labels" || c == "attachments"
+	}
+
+	// Borders and padding take three cells per column plus one
+	avail := opts.Width - 3*len(columns) - 1
+	total := 0
+	for _, w := range widths {
+		total += w
+	}
+
+	// Squeeze the widest flexible column until the table fits, keeping the
+	// subject as wide as possible
+	for total > avail {
+		widest := -1
+		for _, subject := range []bool{false, true} {
+			for i, c := range columns {
+				if flexible(c) && (c == "subject") == subject && widths[i] > minTableColumnWidth && (widest < 0 || widths[i] > widths[widest]) {
+					widest = i
+				}
+			}
+			if widest >= 0 {
+				break
+			}
+		}
+		if widest < 0 {
+			break
+		}
+		widths[widest]--
+		total--
+	}
+
+	// Hand out spare width, subject first
+	for _, name := range tableSlackOrder {
+		for i, c := range columns {
+			if c != name || total >= avail {
+				continue
+			}
+			grow := min(natural[i]-widths[i], avail-total)
+			if grow > 0 {
+				widths[i] += grow
+				total += grow
+			}
+		}
+	}
+	return widths
+}
+
+// fitTableCell truncates s to width cells, or wraps it onto several lines
+func fitTableCell(s string, width int, wrap bool) string {
+	if width <= 0 || runewidth.StringWidth(s) <= width {
+		return s
+	}
+	if !wrap {
+		return truncate(s, width)
+	}
+	return strings.Join(wrapCell(s, width), "\n")
+}
+
+// wrapCell word-wraps s to width cells, breaking words longer than a line
+func wrapCell(s string, width int) []string {
+	var lines []string
+	line := ""
+	for _, word := range strings.Fields(s) {
+		for runewidth.StringWidth(word) > width {
+			if line != "" {
+				lines = append(lines, line)
+				line = ""
+			}
+			head := runewidth.Truncate(word, width, "")
+			if head == "" {
+				break
+			}
+			lines = append(lines, head)
+			word = word[len(head):]
+		}
+		switch {
+		case word == "":
+		case line == "":
+			line = word
+		case runewidth.StringWidth(line)+1+runewidth.StringWidth(word) <= width:
+			line += " " + word
+		default:
+			lines = append(lines, line)
+			line = word
+		}
+	}
+	if line != "" {
+		lines = append(lines, line)
+	}
+	return lines
+}
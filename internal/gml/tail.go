@@ -0,0 +1,192 @@
+package gml
+
+import (
+	"context"
+	"errors"
+	"fmt"
+	"net/http"
+	"os"
+	"slices"
+	"sync"
+	"time"
+
+	"github.com/longkey1/gml/internal/google"
+	"google.golang.org/api/googleapi"
+)
+
+// LatestMessages returns the n most recent messages with a label (any label
+// when empty), oldest first
+func LatestMessages(ctx context.Context, svc *Service, label string, n int64, fields map[string]bool) ([]MessageInfo, error) {
+	if n <= 0 {
+		return nil, nil
+	}
+
+	var userEmail string
+	if fields["url"] {
+		email, err := GetUserEmail(ctx, svc)
+		if err != nil {
+			return nil, err
+		}
+		userEmail = email
+	}
+
+	var idx *LabelIndex
+	listOpts := google.ListMessagesOptions{MaxResults: n}
+	if label != "" || fields["labels"] {
+		var err error
+		if idx, err = FetchLabelIndex(ctx, svc); err != nil {
+			return nil, err
+		}
+	}
+	if label != "" {
+		ids, err := idx.ResolveLabelIDs([]string{label})
+		if err != nil {
+			return nil, err
+		}
+		listOpts.LabelIDs = ids
+	}
+
+	resp, err := svc.API.ListMessages(ctx, listOpts)
+	if err != nil {
+		return nil, fmt.Errorf("unable to retrieve messages: %w", apiError(err))
+	}
+
+	var messages []MessageInfo
+	for _, m := range resp.Messages {
+		info, err := GetMessageInfo(ctx, svc, m.Id, fields, userEmail, idx)
+		if errors.Is(err, ErrNotFound) {
+			// Messages may be deleted while we iterate
+			continue
+		}
+		if err != nil {
+			return nil, err
+		}
+		messages = append(messages, info)
+	}
+	slices.Reverse(messages)
+	return messages, nil
+}
+
+// AppendWriter appends to a file for the life of a long-running process.
+// Before each write it checks that the path still refers to the open file and
+// reopens it otherwise, so logrotate-style rename or removal is picked up
+type AppendWriter struct {
+	path string
+
+	mu   sync.Mutex
+	file *os.File
+}
+
+// OpenAppendWriter opens path for appending, creating it if needed
+func OpenAppendWriter(path string) (*AppendWriter, error) {
+	w := &AppendWriter{path: path}
+	if err := w.reopen(); err != nil {
+		return nil, err
+	}
+	return w, nil
+}
+
+// reopen closes the current file, if any, and opens the path again
+func (w *AppendWriter) reopen() error {
+	if w.file != nil {
+		w.file.Close()
+	}
+	f, err := os.OpenFile(w.path, os.O_WRONLY|os.O_APPEND|os.O_CREATE, 0600)
+	if err != nil {
+		w.file = nil
+		return fmt.Errorf("unable to open output file: %w", err)
+	}
+	w.file = f
+	return nil
+}
+
+// Write appends p, reopening the file first if it was rotated
+func (w *AppendWriter) Write(p []byte) (int, error) {
+	w.mu.Lock()
+	defer w.mu.Unlock()
+
+	if w.file == nil || w.rotated() {
+		if err := w.reopen(); err != nil {
+			return 0, err
+		}
+	}
+	return w.file.Write(p)
+}
+
+// rotated reports whether the path no longer refers to the open file
+func (w *AppendWriter) rotated() bool {
+	pathInfo, err := os.Stat(w.path)
+	if err != nil {
+		return true
+	}
+	fileInfo, err := w.file.Stat()
+	if err != nil {
+		return true
+	}
+	return !os.SameFile(pathInfo, fileInfo)
+}
+
+// Close closes the file
+func (w *AppendWriter) Close() error {
+	w.mu.Lock()
+	defer w.mu.Unlock()
+	if w.file == nil {
+		return nil
+	}
+	err := w.file.Close()
+	w.file = nil
+	return err
+}
+
+// Backoff spaces out retries of a long-running poller: after each failure the
+// delay doubles from Interval up to Max, and a success resets it
+type Backoff struct {
+	Interval time.Duration
+	Max      time.Duration
+	failures int
+}
+
+// Next returns the delay before the next attempt given the last attempt's error
+func (b *Backoff) Next(err error) time.Duration {
+	if err == nil {
+		b.failures = 0
+		return b.Interval
+	}
+	b.failures++
+	delay := b.Interval
+	for i := 1; i < b.failures && delay < b.Max; i++ {
+		delay *= 2
+	}
+	if IsRateLimitError(err) {
+		// Quota windows are per minute; don't retry sooner than that
+		delay = max(delay, time.Minute)
+	}
+	return min(delay, b.Max)
+}
+
+// IsRateLimitError reports whether err is a Gmail quota, rate limit or
+// transient server error worth retrying later
+func IsRateLimitError(err error) bool {
+	var apiErr *googleapi.Error
+	if !errors.As(err, &apiErr) {
+		return false
+	}
+	switch {
+	case apiErr.Code == http.StatusTooManyRequests, apiErr.Code >= 500:
+		return true
+	case apiErr.Code == http.StatusForbidden:
+		for _, e := range apiErr.Errors {
+			if e.Reason == "rateLimitExceeded" || e.Reason == "userRateLimitExceeded" {
+				return true
+			}
+		}
+	}
+	return false
+}
+
+// IsHistoryExpired reports whether err means a stored history ID is no longer
+// available (Gmail keeps roughly a week of history)
+func IsHistoryExpired(err error) bool {
+	var apiErr *googleapi.Error
+	return errors.As(err, &apiErr) && apiErr.Code == http.StatusNotFound
+}
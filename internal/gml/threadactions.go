@@ -0,0 +1,151 @@
+package gml
+
+import (
+	"context"
+	"fmt"
+
+	"github.com/longkey1/gml/internal/google"
+	"google.golang.org/api/gmail/v1"
+	"google.golang.org/api/googleapi"
+)
+
+// ThreadAction is a change applied to whole conversations
+type ThreadAction string
+
+const (
+	// ThreadArchive removes the threads from the inbox
+	ThreadArchive ThreadAction = "archive"
+	// ThreadTrash moves the threads to the trash
+	ThreadTrash ThreadAction = "trash"
+	// ThreadRead marks every message of the threads as read
+	ThreadRead ThreadAction = "read"
+	// ThreadUnread marks every message of the threads as unread
+	ThreadUnread ThreadAction = "unread"
+	// ThreadLabel adds and removes the labels of ThreadModifyOptions
+	ThreadLabel ThreadAction = "label"
+)
+
+// ThreadModifyOptions selects threads and the change to apply to them
+type ThreadModifyOptions struct {
+	// IDs are the threads to change; when empty, every thread matching Query
+	// and LabelIDs is used
+	IDs      []string
+	Query    string
+	LabelIDs []string
+	Action   ThreadAction
+	// AddLabels and RemoveLabels are label names or IDs for ThreadLabel; added
+	// labels are created if they don't exist
+	AddLabels    []string
+	RemoveLabels []string
+	// DryRun resolves the threads without modifying them
+	DryRun bool
+	// Failures collects threads that couldn't be modified; nil stops at the
+	// first failure
+	Failures *FailureLog
+	// Progress, if set, is called after each thread
+	Progress func(done, total int)
+}
+
+// threadActionLabels returns the label changes of an action other than trash
+func threadActionLabels(opts ThreadModifyOptions) (add, remove []string, err error) {
+	switch opts.Action {
+	case ThreadArchive:
+		return nil, []string{LabelInbox}, nil
+	case ThreadRead:
+		return nil, []string{"UNREAD"}, nil
+	case ThreadUnread:
+		return []string{"UNREAD"}, nil, nil
+	case ThreadLabel:
+		if len(opts.AddLabels) == 0 && len(opts.RemoveLabels) == 0 {
+			return nil, nil, fmt.Errorf("at least one label to add or remove is required")
+		}
+		return opts.AddLabels, opts.RemoveLabels, nil
+	}
+	return nil, nil, fmt.Errorf("unknown thread action %q", opts.Action)
+}
+
+// ModifyThreads applies an action to every message of the selected threads
+// with Threads.Modify or Threads.Trash, one request per thread. Matched and
+// Modified of the result count threads
+func ModifyThreads(ctx context.Context, svc *Service, opts ThreadModifyOptions) (*BulkModifyResult, error) {
+	var add, remove []string
+	var err error
+	if opts.Action != ThreadTrash {
+		if add, remove, err = threadActionLabels(opts); err != nil {
+			return nil, err
+		}
+	}
+
+	var idx *LabelIndex
+	var addIDs, removeIDs, filterIDs []string
+	if opts.Action != ThreadTrash || len(opts.LabelIDs) > 0 {
+		if idx, err = FetchLabelIndex(ctx, svc); err != nil {
+			return nil, err
+		}
+		if removeIDs, err = idx.ResolveLabelIDs(remove); err != nil {
+			return nil, err
+		}
+		if filterIDs, err = idx.ResolveLabelIDs(opts.LabelIDs); err != nil {
+			return nil, err
+		}
+	}
+	// A dry run doesn't create the labels to add
+	if len(add) > 0 && !opts.DryRun {
+		if addIDs, err = idx.EnsureLabelIDs(ctx, svc, add); err != nil {
+			return nil, err
+		}
+	}
+
+	ids := opts.IDs
+	if len(ids) == 0 {
+		if ids, err = listThreadIDs(ctx, svc, opts.Query, filterIDs); err != nil {
+			return nil, err
+		}
+	}
+
+	result := &BulkModifyResult{Matched: len(ids)}
+	if opts.DryRun {
+		return result, nil
+	}
+
+	for i, id := range ids {
+		var err error
+		if opts.Action == ThreadTrash {
+			err = svc.API.TrashThread(ctx, id)
+		} else {
+			req := &gmail.ModifyThreadRequest{AddLabelIds: addIDs, RemoveLabelIds: removeIDs}
+			err = svc.API.ModifyThread(ctx, id, req)
+		}
+		if err != nil {
+			if err := opts.Failures.Record(fmt.Errorf("unable to %s thread %s: %w", opts.Action, id, apiError(err)), id); err != nil {
+				return result, err
+			}
+		} else {
+			result.Modified++
+		}
+		if opts.Progress != nil {
+			opts.Progress(i+1, len(ids))
+		}
+	}
+	return result, nil
+}
+
+// ThreadIDsOf returns the threads of messages, in order and without duplicates
+func ThreadIDsOf(ctx context.Context, svc *Service, messageIDs []string) ([]string, error) {
+	var ids []string
+	seen := make(map[string]bool)
+	for _, id := range messageIDs {
+		msg, err := svc.API.GetMessage(ctx, id, google.GetMessageOptions{
+			Format: "minimal",
+			Fields: []googleapi.Field{"threadId"},
+		})
+		if err != nil {
+			return nil, fmt.Errorf("unable to retrieve message %s: %w", id, apiError(err))
+		}
+		if !seen[msg.ThreadId] {
+			seen[msg.ThreadId] = true
+			ids = append(ids, msg.ThreadId)
+		}
+	}
+	return ids, nil
+}
@@ -0,0 +1,231 @@
+package gml
+
+import (
+	"context"
+	"fmt"
+	"net/mail"
+
+	"github.com/longkey1/gml/internal/google"
+	"google.golang.org/api/gmail/v1"
+)
+
+// ThreadInfo represents a conversation for output
+type ThreadInfo struct {
+	ID  string `json:"id,omitempty"`
+	URL string `json:"url,omitempty"`
+	// From and Subject are taken from the first message
+	From string `json:"from,omitempty"`
+	// Participants are the distinct senders, in the order they first wrote
+	Participants []string `json:"participants,omitempty"`
+	Subject      string   `json:"subject,omitempty"`
+	// Date is the date of the latest message
+	Date     string   `json:"date,omitempty"`
+	Messages int      `json:"messages,omitempty"`
+	Labels   []string `json:"labels,omitempty"`
+	Snippet  string   `json:"snippet,omitempty"`
+}
+
+// ThreadFields are the fields of thread listings
+var ThreadFields = FieldSet{
+	Names: []string{"id", "url", "from", "participants", "subject", "date", "messages", "labels", "snippet"},
+}
+
+// FieldValue returns a field of the thread for table and CSV output
+func (t ThreadInfo) FieldValue(name string) any {
+	switch name {
+	case "id":
+		return t.ID
+	case "url":
+		return t.URL
+	case "from":
+		return t.From
+	case "participants":
+		return t.Participants
+	case "subject":
+		return t.Subject
+	case "date":
+		return t.Date
+	case "messages":
+		return t.Messages
+	case "labels":
+		return t.Labels
+	case "snippet":
+		return t.Snippet
+	}
+	return nil
+}
+
+// ListThreadsOptions contains options for listing threads
+type ListThreadsOptions struct {
+	Query      string
+	MaxResults int64
+	LabelIDs   []string
+	Fields     map[string]bool
+	// Progress, if set, is called as thread details are fetched
+	Progress func(done, total int)
+	// URLStyle selects what the url field links to (default auto)
+	URLStyle URLStyle
+}
+
+// ListThreads returns up to MaxResults threads matching the query and labels,
+// newest first. Thread details are only fetched for fields beyond id and snippet
+func ListThreads(ctx context.Context, svc *Service, opts ListThreadsOptions) ([]ThreadInfo, error) {
+	var userEmail string
+	if opts.Fields["url"] {
+		email, err := GetUserEmail(ctx, svc)
+		if err != nil {
+			return nil, err
+		}
+		userEmail = email
+	}
+
+	var labelsIndex *LabelIndex
+	labelIDs := opts.LabelIDs
+	if len(opts.LabelIDs) > 0 || opts.Fields["labels"] {
+		idx, err := FetchLabelIndex(ctx, svc)
+		if err != nil {
+			return nil, err
+		}
+		if len(opts.LabelIDs) > 0 {
+			if labelIDs, err = idx.ResolveLabelIDs(opts.LabelIDs); err != nil {
+				return nil, err
+			}
+		}
+		labelsIndex = idx
+	}
+
+	// List threads with pagination up to MaxResults
+	var listed []*gmail.Thread
+	pageToken := ""
+	for int64(len(listed)) < opts.MaxResults {
+		result, err := svc.API.ListThreads(ctx, google.ListMessagesOptions{
+			Query:      opts.Query,
+			LabelIDs:   labelIDs,
+			MaxResults: min(opts.MaxResults-int64(len(listed)), 500),
+			PageToken:  pageToken,
+		})
+		if err != nil {
+			return nil, fmt.Errorf("unable to list threads: %w", apiError(err))
+		}
+		listed = append(listed, result.Threads...)
+
+		if result.NextPageToken == "" {
+			break
+		}
+		pageToken = result.NextPageToken
+	}
+
+	// threads.list only returns IDs and snippets
+	needsDetails := false
+	for f, ok := range opts.Fields {
+		if ok && f != "id" && f != "snippet" {
+			needsDetails = true
+		}
+	}
+
+	threads := make([]ThreadInfo, 0, len(listed))
+	for i, t := range listed {
+		if opts.Progress != nil {
+			opts.Progress(i, len(listed))
+		}
+		info := ThreadInfo{ID: t.Id, Snippet: t.Snippet}
+		if needsDetails {
+			thread, err := svc.API.GetThread(ctx, t.Id, google.GetMessageOptions{
+				Format:          "metadata",
+				MetadataHeaders: []string{"From", "Subject", "Date"},
+			})
+			if err != nil {
+				return nil, fmt.Errorf("unable to get thread %s: %w", t.Id, apiError(err))
+			}
+			info = buildThreadInfo(thread, MailLinks{Email: userEmail, Style: opts.URLStyle}, labelsIndex)
+		}
+		threads = append(threads, selectThreadFields(info, opts.Fields))
+	}
+	if opts.Progress != nil {
+		opts.Progress(len(listed), len(listed))
+	}
+	return threads, nil
+}
+
+// buildThreadInfo summarizes a thread fetched in metadata format
+func buildThreadInfo(thread *gmail.Thread, links MailLinks, labelsIndex *LabelIndex) ThreadInfo {
+	info := ThreadInfo{
+		ID:       thread.Id,
+		URL:      links.Thread(thread),
+		Messages: len(thread.Messages),
+		Snippet:  thread.Snippet,
+	}
+
+	seen := make(map[string]bool)
+	senders := make(map[string]bool)
+	var labelIDs []string
+	for i, msg := range thread.Messages {
+		from := headerValue(msg.Payload, "From")
+		if i == 0 {
+			info.From = from
+			info.Subject = headerValue(msg.Payload, "Subject")
+		}
+		if addr := senderAddress(from); addr != "" && !senders[addr] {
+			senders[addr] = true
+			info.Participants = append(info.Participants, participantName(from))
+		}
+		if date := headerValue(msg.Payload, "Date"); date != "" {
+			info.Date = date
+		}
+		if info.Snippet == "" {
+			info.Snippet = msg.Snippet
+		}
+		for _, id := range msg.LabelIds {
+			if !seen[id] {
+				seen[id] = true
+				labelIDs = append(labelIDs, id)
+			}
+		}
+	}
+	info.Labels = labelsIndex.MapLabelIDsToNames(labelIDs)
+	return info
+}
+
+// participantName returns the display name of a From header, or its address
+// when it has none
+func participantName(from string) string {
+	if a, err := mail.ParseAddress(from); err == nil {
+		if a.Name != "" {
+			return a.Name
+		}
+		return a.Address
+	}
+	return from
+}
+
+// selectThreadFields clears the fields that weren't requested, so JSON output
+// matches the table
+func selectThreadFields(t ThreadInfo, fields map[string]bool) ThreadInfo {
+	var out ThreadInfo
+	for _, f := range ThreadFields.Names {
+		if !fields[f] {
+			continue
+		}
+		switch f {
+		case "id":
+			out.ID = t.ID
+		case "url":
+			out.URL = t.URL
+		case "from":
+			out.From = t.From
+		case "participants":
+			out.Participants = t.Participants
+		case "subject":
+			out.Subject = t.Subject
+		case "date":
+			out.Date = t.Date
+		case "messages":
+			out.Messages = t.Messages
+		case "labels":
+			out.Labels = t.Labels
+		case "snippet":
+			out.Snippet = t.Snippet
+		}
+	}
+	return out
+}
@@ -0,0 +1,136 @@
+package gml
+
+import (
+	"context"
+	"slices"
+	"testing"
+	"time"
+
+	"github.com/longkey1/gml/internal/fakegmail"
+	"github.com/longkey1/gml/internal/google"
+)
+
+// addThread adds a conversation of messages from the senders, an hour apart
+// starting at testDate, and returns the thread ID
+func addThread(fake *recordingClient, subject string, labels []string, senders ...string) string {
+	var thread string
+	for i, from := range senders {
+		id := fake.AddMessage(fakegmail.Message{
+			ThreadID: thread,
+			From:     from,
+			Subject:  subject,
+			Date:     testDate.Add(time.Duration(i) * time.Hour),
+			LabelIDs: labels,
+		})
+		if thread == "" {
+			thread = id
+		}
+	}
+	return thread
+}
+
+func TestListThreads(t *testing.T) {
+	svc, fake := newTestService(t)
+	first := addThread(fake, "Lunch", []string{"INBOX"}, "Alice <alice@example.com>", "Bob <bob@example.com>", "alice@example.com")
+	second := addThread(fake, "Report", []string{"INBOX", "UNREAD"}, "carol@example.com")
+	ctx := context.Background()
+
+	threads, err := ListThreads(ctx, svc, ListThreadsOptions{MaxResults: 10, Fields: ParseFields("id,from,participants,subject,messages")})
+	if err != nil {
+		t.Fatal(err)
+	}
+	if len(threads) != 2 || threads[0].ID != first || threads[1].ID != second {
+		t.Fatalf("ListThreads() = %+v, want %s then %s", threads, first, second)
+	}
+	lunch := threads[0]
+	if lunch.Messages != 3 || lunch.From != "Alice <alice@example.com>" || lunch.Subject != "Lunch" ||
+		!slices.Equal(lunch.Participants, []string{"Alice", "Bob"}) {
+		t.Errorf("ListThreads() first thread = %+v", lunch)
+	}
+
+	unread, err := ListThreads(ctx, svc, ListThreadsOptions{MaxResults: 10, LabelIDs: []string{"unread"}, Fields: ParseFields("id")})
+	if err != nil {
+		t.Fatal(err)
+	}
+	if len(unread) != 1 || unread[0].ID != second {
+		t.Errorf("ListThreads(unread) = %+v, want %s", unread, second)
+	}
+}
+
+func TestModifyThreads(t *testing.T) {
+	svc, fake := newTestService(t)
+	archived := addThread(fake, "Lunch", []string{"INBOX", "UNREAD"}, "alice@example.com", "bob@example.com")
+	trashed := addThread(fake, "Spam-ish", []string{"INBOX"}, "carol@example.com")
+	ctx := context.Background()
+
+	result, err := ModifyThreads(ctx, svc, ThreadModifyOptions{Query: "subject:lunch", Action: ThreadArchive})
+	if err != nil {
+		t.Fatal(err)
+	}
+	if result.Matched != 1 || result.Modified != 1 {
+		t.Errorf("ModifyThreads(archive) = %+v, want 1 matched and modified", result)
+	}
+	if _, err := ModifyThreads(ctx, svc, ThreadModifyOptions{IDs: []string{trashed}, Action: ThreadTrash}); err != nil {
+		t.Fatal(err)
+	}
+
+	thread, err := svc.API.GetThread(ctx, archived, google.GetMessageOptions{Format: "minimal"})
+	if err != nil {
+		t.Fatal(err)
+	}
+	for _, msg := range thread.Messages {
+		if !slices.Equal(msg.LabelIds, []string{"UNREAD"}) {
+			t.Errorf("labels of %s after archive = %v, want [UNREAD]", msg.Id, msg.LabelIds)
+		}
+	}
+	if got := fake.Message(trashed).LabelIds; !slices.Equal(got, []string{"TRASH"}) {
+		t.Errorf("labels after trash = %v, want [TRASH]", got)
+	}
+
+	failures := &FailureLog{Ignore: true}
+	result, err = ModifyThreads(ctx, svc, ThreadModifyOptions{IDs: []string{"missing", archived}, Action: ThreadRead, Failures: failures})
+	if err != nil {
+		t.Fatal(err)
+	}
+	if result.Modified != 1 || len(failures.Items) != 1 || failures.Items[0].ID != "missing" {
+		t.Errorf("ModifyThreads() with a missing thread = %+v, failures %+v", result, failures.Items)
+	}
+}
+
+func TestCountMessages(t *testing.T) {
+	svc, fake := newTestService(t)
+	addMessages(fake, 620, fakegmail.Message{LabelIDs: []string{"INBOX"}})
+	addMessages(fake, 5, fakegmail.Message{LabelIDs: []string{"INBOX", "UNREAD"}})
+	ctx := context.Background()
+
+	tests := []struct {
+		name   string
+		opts   CountOptions
+		want   int64
+		exact  bool
+		tokens []string
+	}{
+		{name: "estimate", opts: CountOptions{LabelIDs: []string{"inbox"}}, want: 625, tokens: []string{""}},
+		{name: "exact", opts: CountOptions{LabelIDs: []string{"inbox"}, Exact: true}, want: 625, exact: true, tokens: []string{"", "500"}},
+		{name: "query", opts: CountOptions{Query: "is:unread", Exact: true}, want: 5, exact: true, tokens: []string{""}},
+	}
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			fake.lists = nil
+			got, err := CountMessages(ctx, svc, tt.opts)
+			if err != nil {
+				t.Fatal(err)
+			}
+			if got.Count != tt.want || got.Exact != tt.exact {
+				t.Errorf("CountMessages() = %+v, want %d (exact %v)", got, tt.want, tt.exact)
+			}
+			var tokens []string
+			for _, l := range fake.lists {
+				tokens = append(tokens, l.PageToken)
+			}
+			if !slices.Equal(tokens, tt.tokens) {
+				t.Errorf("page tokens = %q, want %q", tokens, tt.tokens)
+			}
+		})
+	}
+}
@@ -0,0 +1,217 @@
+package gml
+
+import (
+	"context"
+	"errors"
+	"fmt"
+	"io"
+	"os"
+	"path/filepath"
+	"strings"
+
+	"github.com/longkey1/gml/internal/google"
+)
+
+// unlabeledDir holds messages that have no labels
+const unlabeledDir = "_unlabeled"
+
+// TreeExportOptions contains options for exporting messages as a label tree
+type TreeExportOptions struct {
+	Query    string
+	LabelIDs []string
+	Dir      string
+	// Copy writes a separate file per label instead of hard-linking
+	Copy bool
+	// Failures collects messages that could not be downloaded or written;
+	// nil stops the export at the first failure
+	Failures *FailureLog
+	// Progress, if set, is called after each message
+	Progress func(done, total int)
+}
+
+// TreeExportResult summarizes a tree export
+type TreeExportResult struct {
+	Messages int `json:"messages"`
+	Written  int `json:"written"`
+	Linked   int `json:"linked"`
+	Skipped  int `json:"skipped"`
+}
+
+// ExportTree writes each matching message as <dir>/<label path>/<id>.eml under
+// every label it carries. Nested labels (Parent/Child) become nested directories.
+// Existing files are skipped, so repeated runs only fetch new messages.
+func ExportTree(ctx context.Context, svc *Service, opts TreeExportOptions) (*TreeExportResult, error) {
+	idx, err := FetchLabelIndex(ctx, svc)
+	if err != nil {
+		return nil, err
+	}
+
+	filterIDs, err := idx.ResolveLabelIDs(opts.LabelIDs)
+	if err != nil {
+		return nil, err
+	}
+
+	ids, err := ListMessageIDs(ctx, svc, opts.Query, filterIDs)
+	if err != nil {
+		return nil, err
+	}
+
+	// Label IDs per message are needed before fetching the raw content, so
+	// already-exported messages can be skipped without downloading them
+	result := &TreeExportResult{}
+	for i, id := range ids {
+		if err := exportTreeMessage(ctx, svc, idx, opts, id, result); err != nil {
+			if err := opts.Failures.Record(err, id); err != nil {
+				return nil, err
+			}
+		}
+		if opts.Progress != nil {
+			opts.Progress(i+1, len(ids))
+		}
+	}
+
+	return result, nil
+}
+
+// exportTreeMessage writes one message under each of its label directories
+func exportTreeMessage(ctx context.Context, svc *Service, idx *LabelIndex, opts TreeExportOptions, id string, result *TreeExportResult) error {
+	meta, err := svc.API.GetMessage(ctx, id, google.GetMessageOptions{Format: "minimal"})
+	if err != nil {
+		return fmt.Errorf("unable to retrieve message %s: %w", id, apiError(err))
+	}
+	result.Messages++
+
+	var paths []string
+	for _, name := range idx.MapLabelIDsToNames(meta.LabelIds) {
+		paths = append(paths, filepath.Join(opts.Dir, labelPath(name), id+".eml"))
+	}
+	if len(paths) == 0 {
+		paths = []string{filepath.Join(opts.Dir, unlabeledDir, id+".eml")}
+	}
+
+	var missing []string
+	var existing string
+	for _, p := range paths {
+		if _, err := os.Stat(p); err == nil {
+			existing = p
+			result.Skipped++
+			continue
+		}
+		missing = append(missing, p)
+	}
+	if len(missing) == 0 {
+		return nil
+	}
+
+	source := existing
+	if source == "" {
+		raw, _, err := GetRawMessage(ctx, svc, id)
+		if err != nil {
+			return err
+		}
+		source = missing[0]
+		if err := writeFileAll(source, raw); err != nil {
+			return err
+		}
+		result.Written++
+		missing = missing[1:]
+	}
+
+	for _, p := range missing {
+		linked, err := linkOrCopy(source, p, opts.Copy)
+		if err != nil {
+			return err
+		}
+		if linked {
+			result.Linked++
+		} else {
+			result.Written++
+		}
+	}
+	return nil
+}
+
+// labelPath converts a label name to a relative directory path, turning
+// nested labels into nested directories and sanitizing each component
+func labelPath(name string) string {
+	var parts []string
+	for _, part := range strings.Split(name, "/") {
+		part = strings.TrimSpace(part)
+		part = strings.Map(func(r rune) rune {
+			switch r {
+			case '\\', ':', '*', '?', '"', '<', '>', '|', 0:
+				return '_'
+			}
+			return r
+		}, part)
+		if part == "" || part == "." || part == ".." {
+			part = "_"
+		}
+		parts = append(parts, part)
+	}
+	return filepath.Join(parts...)
+}
+
+// writeFileAll writes data to path, creating parent directories. The data
+// goes to a temporary file first, so an interrupted export never leaves a
+// truncated message that later runs would skip.
+func writeFileAll(path string, data []byte) error {
+	return writeFileAtomic(path, func(w io.Writer) error {
+		_, err := w.Write(data)
+		return err
+	})
+}
+
+// linkOrCopy hard-links src to dst, falling back to a copy when linking is
+// unsupported or copy is requested. It reports whether a link was created.
+func linkOrCopy(src, dst string, copyOnly bool) (bool, error) {
+	if err := os.MkdirAll(filepath.Dir(dst), 0700); err != nil {
+		return false, fmt.Errorf("unable to create directory: %w", err)
+	}
+
+	if !copyOnly {
+		if err := os.Link(src, dst); err == nil {
+			return true, nil
+		} else if errors.Is(err, os.ErrExist) {
+			return true, nil
+		}
+	}
+
+	in, err := os.Open(src)
+	if err != nil {
+		return false, err
+	}
+	defer in.Close()
+
+	return false, writeFileAtomic(dst, func(w io.Writer) error {
+		_, err := io.Copy(w, in)
+		return err
+	})
+}
+
+// writeFileAtomic creates path readable only by the owner with the content
+// written by write, renaming a temporary file from the same directory into
+// place once it is complete
+func writeFileAtomic(path string, write func(io.Writer) error) error {
+	if err := os.MkdirAll(filepath.Dir(path), 0700); err != nil {
+		return fmt.Errorf("unable to create directory: %w", err)
+	}
+	// CreateTemp uses mode 0600
+	tmp, err := os.CreateTemp(filepath.Dir(path), ".gml-*")
+	if err != nil {
+		return fmt.Errorf("unable to write %s: %w", path, err)
+	}
+	defer os.Remove(tmp.Name())
+
+	err = write(tmp)
+	if cerr := tmp.Close(); err == nil {
+		err = cerr
+	}
+	if err == nil {
+		err = os.Rename(tmp.Name(), path)
+	}
+	if err != nil {
+		return fmt.Errorf("unable to write %s: %w", path, err)
+	}
+	return nil
+}
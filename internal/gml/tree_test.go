@@ -0,0 +1,82 @@
+package gml
+
+import (
+	"context"
+	"os"
+	"path/filepath"
+	"strings"
+	"testing"
+
+	"github.com/longkey1/gml/internal/fakegmail"
+)
+
+func TestExportTree(t *testing.T) {
+	svc, fake := newTestService(t)
+	acme := fake.AddLabel("Clients/Acme")
+	both := fake.AddMessage(fakegmail.Message{Subject: "Contract", Body: "Signed", Date: testDate, LabelIDs: []string{"INBOX", acme}})
+	loose := fake.AddMessage(fakegmail.Message{Subject: "Note", Date: testDate, LabelIDs: []string{}})
+	ctx := context.Background()
+
+	for _, copyOnly := range []bool{false, true} {
+		dir := t.TempDir()
+		inbox := filepath.Join(dir, "INBOX", both+".eml")
+		// A temporary file left by an interrupted run doesn't count as exported
+		if err := os.MkdirAll(filepath.Dir(inbox), 0700); err != nil {
+			t.Fatal(err)
+		}
+		if err := os.WriteFile(filepath.Join(filepath.Dir(inbox), ".gml-123"), []byte("Subject: Con"), 0600); err != nil {
+			t.Fatal(err)
+		}
+
+		opts := TreeExportOptions{Dir: dir, Copy: copyOnly}
+		result, err := ExportTree(ctx, svc, opts)
+		if err != nil {
+			t.Fatal(err)
+		}
+		want := TreeExportResult{Messages: 2, Written: 2, Linked: 1}
+		if copyOnly {
+			want = TreeExportResult{Messages: 2, Written: 3}
+		}
+		if *result != want {
+			t.Errorf("ExportTree(copy %v) = %+v, want %+v", copyOnly, *result, want)
+		}
+
+		for _, path := range []string{inbox, filepath.Join(dir, "Clients", "Acme", both+".eml"), filepath.Join(dir, unlabeledDir, loose+".eml")} {
+			info, err := os.Stat(path)
+			if err != nil {
+				t.Fatal(err)
+			}
+			if info.Mode().Perm() != 0600 {
+				t.Errorf("mode of %s = %v, want 0600", path, info.Mode().Perm())
+			}
+			dirInfo, err := os.Stat(filepath.Dir(path))
+			if err != nil {
+				t.Fatal(err)
+			}
+			if dirInfo.Mode().Perm() != 0700 {
+				t.Errorf("mode of %s = %v, want 0700", filepath.Dir(path), dirInfo.Mode().Perm())
+			}
+			entries, err := os.ReadDir(filepath.Dir(path))
+			if err != nil {
+				t.Fatal(err)
+			}
+			for _, e := range entries {
+				if strings.HasPrefix(e.Name(), ".gml-") && e.Name() != ".gml-123" {
+					t.Errorf("temporary file %s left in %s", e.Name(), filepath.Dir(path))
+				}
+			}
+		}
+		if data, err := os.ReadFile(inbox); err != nil || !strings.HasSuffix(string(data), "\r\n\r\nSigned") {
+			t.Errorf("exported message = %q, %v", data, err)
+		}
+
+		// Repeated runs skip what is already there
+		result, err = ExportTree(ctx, svc, opts)
+		if err != nil {
+			t.Fatal(err)
+		}
+		if want := (TreeExportResult{Messages: 2, Skipped: 3}); *result != want {
+			t.Errorf("second ExportTree(copy %v) = %+v, want %+v", copyOnly, *result, want)
+		}
+	}
+}
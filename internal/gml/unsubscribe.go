@@ -0,0 +1,317 @@
+package gml
+
+import (
+	"context"
+	"fmt"
+	"io"
+	"log/slog"
+	"net/http"
+	"net/url"
+	"strings"
+	"time"
+
+	"github.com/longkey1/gml/internal/google"
+	"github.com/olekukonko/tablewriter"
+)
+
+// unsubscribeTimeout bounds a one-click unsubscribe request
+const unsubscribeTimeout = 30 * time.Second
+
+// UnsubscribeStatus is the outcome of an unsubscribe attempt
+type UnsubscribeStatus string
+
+const (
+	// UnsubscribeDone means the one-click request (RFC 8058) was accepted
+	UnsubscribeDone UnsubscribeStatus = "unsubscribed"
+	// UnsubscribeSent means the unsubscribe email was sent
+	UnsubscribeSent UnsubscribeStatus = "sent"
+	// UnsubscribeManual means the URL or address has to be visited by hand
+	UnsubscribeManual UnsubscribeStatus = "manual"
+	// UnsubscribeSkipped means a dry run left the request unsent
+	UnsubscribeSkipped UnsubscribeStatus = "skipped"
+	// UnsubscribeFailed means the request was refused or could not be sent
+	UnsubscribeFailed UnsubscribeStatus = "failed"
+)
+
+// UnsubscribeTarget is a mailing list to leave, from the List-Unsubscribe
+// headers (RFC 2369) of its newest message
+type UnsubscribeTarget struct {
+	MessageID string `json:"messageId"`
+	From      string `json:"from"`
+	Subject   string `json:"subject"`
+	ListID    string `json:"listId,omitempty"`
+	// URL is the HTTP(S) unsubscribe link and Mailto the unsubscribe address
+	URL    string `json:"url,omitempty"`
+	Mailto string `json:"mailto,omitempty"`
+	// OneClick means URL accepts a one-click POST (List-Unsubscribe-Post)
+	OneClick bool `json:"oneClick"`
+}
+
+// UnsubscribeResult is the outcome for one target
+type UnsubscribeResult struct {
+	UnsubscribeTarget
+	Status UnsubscribeStatus `json:"status"`
+	Detail string            `json:"detail,omitempty"`
+}
+
+// UnsubscribeSearchOptions selects the messages to unsubscribe from
+type UnsubscribeSearchOptions struct {
+	// IDs are messages to use; when empty, the messages matching Query and
+	// LabelIDs are used
+	IDs      []string
+	Query    string
+	LabelIDs []string
+	// MaxMessages stops after this many matching messages, newest first (0
+	// for all)
+	MaxMessages int
+	// Failures, if set, records messages that couldn't be read instead of
+	// failing
+	Failures *FailureLog
+	// Progress, if set, is called as messages are fetched
+	Progress func(done, total int)
+}
+
+// unsubscribeHeaders are the headers FindUnsubscribeTargets reads
+var unsubscribeHeaders = []string{"From", "Subject", "List-Id", "List-Unsubscribe", "List-Unsubscribe-Post"}
+
+// FindUnsubscribeTargets reads the List-Unsubscribe headers of the selected
+// messages, one target per list (List-Id, or sender without one) taken from
+// its newest message. It also returns how many messages had no such header.
+// Messages that can't be read fail the search unless opts.Failures ignores
+// errors
+func FindUnsubscribeTargets(ctx context.Context, svc *Service, opts UnsubscribeSearchOptions) ([]UnsubscribeTarget, int, error) {
+	ids := opts.IDs
+	if len(ids) == 0 {
+		var labelIDs []string
+		if len(opts.LabelIDs) > 0 {
+			idx, err := FetchLabelIndex(ctx, svc)
+			if err != nil {
+				return nil, 0, err
+			}
+			if labelIDs, err = idx.ResolveLabelIDs(opts.LabelIDs); err != nil {
+				return nil, 0, err
+			}
+		}
+		var err error
+		if ids, err = ListMessageIDs(ctx, svc, opts.Query, labelIDs); err != nil {
+			return nil, 0, err
+		}
+		if opts.MaxMessages > 0 && len(ids) > opts.MaxMessages {
+			ids = ids[:opts.MaxMessages]
+		}
+	}
+
+	var targets []UnsubscribeTarget
+	seen := make(map[string]bool)
+	missing := 0
+	for i, id := range ids {
+		if opts.Progress != nil {
+			opts.Progress(i, len(ids))
+		}
+		msg, err := svc.API.GetMessage(ctx, id, google.GetMessageOptions{Format: "metadata", MetadataHeaders: unsubscribeHeaders})
+		if err != nil {
+			if err := opts.Failures.Record(fmt.Errorf("unable to retrieve message %s: %w", id, apiError(err)), id); err != nil {
+				return nil, 0, err
+			}
+			continue
+		}
+
+		link, mailto := ParseListUnsubscribe(headerValue(msg.Payload, "List-Unsubscribe"))
+		if link == "" && mailto == "" {
+			slog.Debug("no List-Unsubscribe header", "id", id)
+			missing++
+			continue
+		}
+
+		from := headerValue(msg.Payload, "From")
+		listID := strings.TrimSpace(headerValue(msg.Payload, "List-Id"))
+		key := strings.ToLower(listID)
+		if key == "" {
+			key = senderAddress(from)
+		}
+		// Messages are listed newest first, so the first one of a list wins
+		if seen[key] {
+			continue
+		}
+		seen[key] = true
+
+		targets = append(targets, UnsubscribeTarget{
+			MessageID: id,
+			From:      from,
+			Subject:   headerValue(msg.Payload, "Subject"),
+			ListID:    listID,
+			URL:       link,
+			Mailto:    mailto,
+			OneClick: strings.HasPrefix(link, "https://") &&
+				strings.EqualFold(strings.TrimSpace(headerValue(msg.Payload, "List-Unsubscribe-Post")), "List-Unsubscribe=One-Click"),
+		})
+	}
+	if opts.Progress != nil {
+		opts.Progress(len(ids), len(ids))
+	}
+	return targets, missing, nil
+}
+
+// ParseListUnsubscribe returns the first HTTP(S) URL and the first mailto:
+// URL of a List-Unsubscribe header, a list of <URL>s
+func ParseListUnsubscribe(value string) (link, mailto string) {
+	for {
+		start := strings.Index(value, "<")
+		if start < 0 {
+			return link, mailto
+		}
+		end := strings.Index(value[start:], ">")
+		if end < 0 {
+			return link, mailto
+		}
+		uri := strings.Join(strings.Fields(value[start+1:start+end]), "")
+		value = value[start+end+1:]
+
+		lower := strings.ToLower(uri)
+		switch {
+		case link == "" && (strings.HasPrefix(lower, "https://") || strings.HasPrefix(lower, "http://")):
+			link = uri
+		case mailto == "" && strings.HasPrefix(lower, "mailto:"):
+			mailto = uri
+		}
+	}
+}
+
+// UnsubscribeOptions controls how Unsubscribe leaves a list
+type UnsubscribeOptions struct {
+	// Mailto sends the unsubscribe email for lists without one-click links
+	Mailto bool
+	// Client sends one-click requests; nil uses a client with a timeout
+	Client *http.Client
+}
+
+// Unsubscribe leaves a list: by the one-click POST of RFC 8058 when offered,
+// otherwise by sending the mailto: request when opts.Mailto is set. Anything
+// else is left for the user, with status manual. Under WithDryRun the
+// one-click request is skipped. Failures are reported in the result
+func Unsubscribe(ctx context.Context, svc *Service, t UnsubscribeTarget, opts UnsubscribeOptions) UnsubscribeResult {
+	result := UnsubscribeResult{UnsubscribeTarget: t, Status: UnsubscribeManual}
+	switch {
+	case t.OneClick:
+		if IsDryRun(ctx) {
+			result.Status = UnsubscribeSkipped
+			result.Detail = "dry run: POST " + t.URL
+			return result
+		}
+		if err := postOneClick(ctx, opts.Client, t.URL); err != nil {
+			result.Status = UnsubscribeFailed
+			result.Detail = err.Error()
+			return result
+		}
+		result.Status = UnsubscribeDone
+
+	case t.Mailto != "" && opts.Mailto:
+		msg, err := mailtoMessage(t.Mailto)
+		if err == nil {
+			_, err = SendMessage(ctx, svc, msg)
+		}
+		if err != nil {
+			result.Status = UnsubscribeFailed
+			result.Detail = err.Error()
+			return result
+		}
+		result.Status = UnsubscribeSent
+		result.Detail = "to " + strings.Join(msg.To, ", ")
+
+	case t.URL != "":
+		result.Detail = t.URL
+	default:
+		result.Detail = t.Mailto
+	}
+	return result
+}
+
+// postOneClick sends the one-click unsubscribe request (RFC 8058), without
+// cookies or credentials
+func postOneClick(ctx context.Context, client *http.Client, link string) error {
+	if client == nil {
+		client = &http.Client{Timeout: unsubscribeTimeout}
+	}
+	req, err := http.NewRequestWithContext(ctx, http.MethodPost, link, strings.NewReader("List-Unsubscribe=One-Click"))
+	if err != nil {
+		return fmt.Errorf("invalid unsubscribe URL: %w", err)
+	}
+	req.Header.Set("Content-Type", "application/x-www-form-urlencoded")
+
+	resp, err := client.Do(req)
+	if err != nil {
+		return fmt.Errorf("unsubscribe request failed: %w", err)
+	}
+	defer resp.Body.Close()
+	io.Copy(io.Discard, io.LimitReader(resp.Body, 1<<16))
+	if resp.StatusCode < 200 || resp.StatusCode >= 300 {
+		return fmt.Errorf("unsubscribe request failed: %s", resp.Status)
+	}
+	return nil
+}
+
+// mailtoMessage builds the message a mailto: URL (RFC 6068) describes; the
+// subject defaults to "unsubscribe"
+func mailtoMessage(uri string) (*OutgoingMessage, error) {
+	u, err := url.Parse(uri)
+	if err != nil || u.Opaque == "" {
+		return nil, fmt.Errorf("invalid mailto URL %q", uri)
+	}
+	to, err := url.PathUnescape(u.Opaque)
+	if err != nil {
+		return nil, fmt.Errorf("invalid mailto URL %q: %w", uri, err)
+	}
+
+	msg := &OutgoingMessage{To: strings.Split(to, ","), Subject: "unsubscribe", Body: "unsubscribe"}
+	q := u.Query()
+	if s := q.Get("subject"); s != "" {
+		msg.Subject = s
+	}
+	if b := q.Get("body"); b != "" {
+		msg.Body = b
+	}
+	return msg, nil
+}
+
+// FormatUnsubscribeTargets outputs the lists found, without acting on them
+func FormatUnsubscribeTargets(w io.Writer, targets []UnsubscribeTarget, format OutputFormat) error {
+	if format == OutputFormatJSON {
+		if targets == nil {
+			targets = []UnsubscribeTarget{}
+		}
+		return FormatJSON(w, targets)
+	}
+
+	table := tablewriter.NewWriter(w)
+	table.Header("FROM", "METHOD", "TARGET")
+	for _, t := range targets {
+		method, target := "url", t.URL
+		switch {
+		case t.OneClick:
+			method = "one-click"
+		case t.URL == "":
+			method, target = "mailto", t.Mailto
+		}
+		table.Append(t.From, method, target)
+	}
+	table.Render()
+	return nil
+}
+
+// FormatUnsubscribeResults outputs the outcome of unsubscribing
+func FormatUnsubscribeResults(w io.Writer, results []UnsubscribeResult, format OutputFormat) error {
+	if format == OutputFormatJSON {
+		if results == nil {
+			results = []UnsubscribeResult{}
+		}
+		return FormatJSON(w, results)
+	}
+
+	table := tablewriter.NewWriter(w)
+	table.Header("FROM", "STATUS", "DETAIL")
+	for _, r := range results {
+		table.Append(r.From, string(r.Status), r.Detail)
+	}
+	table.Render()
+	return nil
+}
@@ -0,0 +1,117 @@
+package gml
+
+import (
+	"context"
+	"errors"
+	"fmt"
+	"net/http"
+	"slices"
+
+	"github.com/longkey1/gml/internal/google"
+	"google.golang.org/api/gmail/v1"
+	"google.golang.org/api/googleapi"
+)
+
+// URLStyle selects what Gmail web UI links point at
+type URLStyle string
+
+const (
+	// URLStyleAuto links messages to themselves and threads to the thread
+	URLStyleAuto URLStyle = "auto"
+	// URLStyleThread always links to the conversation
+	URLStyleThread URLStyle = "thread"
+	// URLStyleMessage always links to a message; for threads the newest one
+	URLStyleMessage URLStyle = "message"
+)
+
+// URLStyles lists the accepted --url-style values
+var URLStyles = []URLStyle{URLStyleAuto, URLStyleThread, URLStyleMessage}
+
+// ParseURLStyle parses a --url-style value; empty means auto
+func ParseURLStyle(s string) (URLStyle, error) {
+	if s == "" {
+		return URLStyleAuto, nil
+	}
+	if style := URLStyle(s); slices.Contains(URLStyles, style) {
+		return style, nil
+	}
+	return "", fmt.Errorf("invalid URL style: %s (use auto, thread or message)", s)
+}
+
+// Gmail web UI views a link can open in
+const (
+	mailViewInbox = "inbox"
+	mailViewAll   = "all"
+	mailViewSpam  = "spam"
+	mailViewTrash = "trash"
+)
+
+// MailView returns the Gmail web UI view that shows mail with the given label
+// IDs. #all/ doesn't include spam and trash, and #inbox/ keeps the inbox
+// navigation for mail that is still there
+func MailView(labelIDs []string) string {
+	switch {
+	case slices.Contains(labelIDs, "SPAM"):
+		return mailViewSpam
+	case slices.Contains(labelIDs, "TRASH"):
+		return mailViewTrash
+	case slices.Contains(labelIDs, "INBOX"):
+		return mailViewInbox
+	}
+	return mailViewAll
+}
+
+// BuildMailURL constructs a Gmail web UI URL opening a thread or message ID in a view
+func BuildMailURL(email, view, id string) string {
+	// Note: url.QueryEscape is not needed here as email addresses don't need escaping
+	return fmt.Sprintf("https://mail.google.com/mail/?authuser=%s#%s/%s", email, view, id)
+}
+
+// MailLinks builds Gmail web UI links for one account
+type MailLinks struct {
+	Email string
+	Style URLStyle
+}
+
+// Message returns the link of a message: the message itself, or its
+// conversation with URLStyleThread
+func (l MailLinks) Message(msg *gmail.Message) string {
+	id := msg.Id
+	if l.Style == URLStyleThread {
+		id = msg.ThreadId
+	}
+	return BuildMailURL(l.Email, MailView(msg.LabelIds), id)
+}
+
+// Thread returns the link of a thread: the conversation, or its newest message
+// with URLStyleMessage. The view is taken from the labels of all its messages
+func (l MailLinks) Thread(thread *gmail.Thread) string {
+	var labelIDs []string
+	for _, msg := range thread.Messages {
+		labelIDs = append(labelIDs, msg.LabelIds...)
+	}
+	id := thread.Id
+	if l.Style == URLStyleMessage && len(thread.Messages) > 0 {
+		id = thread.Messages[len(thread.Messages)-1].Id
+	}
+	return BuildMailURL(l.Email, MailView(labelIDs), id)
+}
+
+// ResolveMailURL returns the Gmail web UI link for a message or thread ID
+func ResolveMailURL(ctx context.Context, svc *Service, links MailLinks, id string) (string, error) {
+	msg, err := svc.API.GetMessage(ctx, id, google.GetMessageOptions{Format: "minimal", Fields: []googleapi.Field{"id", "threadId", "labelIds"}})
+	if err == nil {
+		return links.Message(msg), nil
+	}
+	// Thread IDs are rejected as not found or, for some, as invalid message IDs
+	var apiErr *googleapi.Error
+	if !errors.As(err, &apiErr) || (apiErr.Code != http.StatusNotFound && apiErr.Code != http.StatusBadRequest) {
+		return "", fmt.Errorf("unable to retrieve message: %w", apiError(err))
+	}
+
+	thread, err := svc.API.GetThread(ctx, id, google.GetMessageOptions{Format: "minimal", Fields: []googleapi.Field{"id", "messages(id,labelIds)"}})
+	if err != nil {
+		return "", fmt.Errorf("no message or thread found with ID %s: %w", id, apiError(err))
+	}
+	return links.Thread(thread), nil
+}
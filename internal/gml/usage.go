@@ -0,0 +1,153 @@
+package gml
+
+import (
+	"fmt"
+	"io"
+	"maps"
+	"os"
+	"path/filepath"
+	"slices"
+	"strings"
+	"time"
+
+	"github.com/longkey1/gml/internal/google"
+)
+
+// usageFileName is the state file holding local usage statistics
+const usageFileName = "usage.json"
+
+// UsageStats are local, never-transmitted statistics of gml invocations,
+// collected only when usage_stats is enabled in the config
+type UsageStats struct {
+	Since       time.Time `json:"since"`
+	Updated     time.Time `json:"updated"`
+	Invocations int       `json:"invocations"`
+	// Commands counts invocations by command path, e.g. "gml list"
+	Commands map[string]int `json:"commands"`
+	// Requests counts Gmail API requests by method and endpoint
+	Requests       map[string]int `json:"requests"`
+	RequestErrors  int            `json:"requestErrors"`
+	TokenHits      int            `json:"tokenHits"`
+	TokenRefreshes int            `json:"tokenRefreshes"`
+}
+
+// UsagePath returns the path of the usage statistics file
+func UsagePath() (string, error) {
+	dir, err := StateDir()
+	if err != nil {
+		return "", err
+	}
+	return filepath.Join(dir, usageFileName), nil
+}
+
+// LoadUsage reads the usage statistics; a missing file yields empty stats
+func LoadUsage() (*UsageStats, error) {
+	path, err := UsagePath()
+	if err != nil {
+		return nil, err
+	}
+	stats := &UsageStats{}
+	if _, err := LoadState(path, stats); err != nil {
+		return nil, err
+	}
+	if stats.Commands == nil {
+		stats.Commands = make(map[string]int)
+	}
+	if stats.Requests == nil {
+		stats.Requests = make(map[string]int)
+	}
+	return stats, nil
+}
+
+// RecordUsage adds one invocation of command, and the API usage of this
+// process, to the usage statistics
+func RecordUsage(command string) error {
+	stats, err := LoadUsage()
+	if err != nil {
+		return err
+	}
+
+	now := time.Now()
+	if stats.Since.IsZero() {
+		stats.Since = now
+	}
+	stats.Updated = now
+	stats.Invocations++
+	stats.Commands[command]++
+
+	u := google.TakeUsage()
+	for endpoint, n := range u.Requests {
+		stats.Requests[endpoint] += n
+	}
+	stats.RequestErrors += u.Errors
+	stats.TokenHits += u.TokenHits
+	stats.TokenRefreshes += u.TokenRefreshes
+
+	path, err := UsagePath()
+	if err != nil {
+		return err
+	}
+	return SaveState(path, stats)
+}
+
+// ResetUsage deletes the usage statistics
+func ResetUsage() error {
+	path, err := UsagePath()
+	if err != nil {
+		return err
+	}
+	if err := os.Remove(path); err != nil && !os.IsNotExist(err) {
+		return fmt.Errorf("unable to reset usage statistics: %w", err)
+	}
+	return nil
+}
+
+// TotalRequests returns the number of API requests recorded
+func (s *UsageStats) TotalRequests() int {
+	total := 0
+	for _, n := range s.Requests {
+		total += n
+	}
+	return total
+}
+
+// FormatUsage writes usage statistics as a human-readable report, busiest
+// commands and endpoints first
+func FormatUsage(w io.Writer, s *UsageStats) error {
+	if s.Invocations == 0 {
+		_, err := fmt.Fprintln(w, "No usage recorded yet.")
+		return err
+	}
+
+	var b strings.Builder
+	fmt.Fprintf(&b, "Since:        %s\n", s.Since.Format(time.RFC3339))
+	fmt.Fprintf(&b, "Invocations:  %d\n", s.Invocations)
+	fmt.Fprintf(&b, "API requests: %d (%d failed)\n", s.TotalRequests(), s.RequestErrors)
+	if lookups := s.TokenHits + s.TokenRefreshes; lookups > 0 {
+		fmt.Fprintf(&b, "Token cache:  %.0f%% hit rate (%d hits, %d refreshes)\n",
+			float64(s.TokenHits)*100/float64(lookups), s.TokenHits, s.TokenRefreshes)
+	}
+
+	writeCounts(&b, "Commands", s.Commands)
+	writeCounts(&b, "API requests by endpoint", s.Requests)
+	_, err := io.WriteString(w, b.String())
+	return err
+}
+
+// writeCounts writes a titled list of counts, largest first
+func writeCounts(b *strings.Builder, title string, counts map[string]int) {
+	if len(counts) == 0 {
+		return
+	}
+	keys := slices.Sorted(maps.Keys(counts))
+	slices.SortStableFunc(keys, func(a, b string) int { return counts[b] - counts[a] })
+	width := 0
+	for _, k := range keys {
+		width = max(width, len(k))
+	}
+
+	fmt.Fprintf(b, "\n%s:\n", title)
+	for _, k := range keys {
+		fmt.Fprintf(b, "  %-*s  %d\n", width, k, counts[k])
+	}
+}
@@ -0,0 +1,98 @@
+package gml
+
+import (
+	"context"
+	"fmt"
+	"time"
+
+	"google.golang.org/api/gmail/v1"
+)
+
+// Vacation holds the vacation responder (auto-reply) settings
+type Vacation struct {
+	Enabled            bool       `json:"enabled"`
+	Subject            string     `json:"subject,omitempty"`
+	Body               string     `json:"body,omitempty"`
+	BodyHTML           string     `json:"bodyHtml,omitempty"`
+	RestrictToContacts bool       `json:"restrictToContacts"`
+	RestrictToDomain   bool       `json:"restrictToDomain"`
+	Start              *time.Time `json:"start,omitempty"`
+	End                *time.Time `json:"end,omitempty"`
+}
+
+// GetVacation returns the current vacation responder settings
+func GetVacation(ctx context.Context, svc *Service) (*Vacation, error) {
+	v, err := svc.Gmail.Users.Settings.GetVacation("me").Context(ctx).Do()
+	if err != nil {
+		return nil, fmt.Errorf("unable to get vacation settings: %w", apiError(err))
+	}
+
+	vacation := &Vacation{
+		Enabled:            v.EnableAutoReply,
+		Subject:            v.ResponseSubject,
+		Body:               v.ResponseBodyPlainText,
+		BodyHTML:           v.ResponseBodyHtml,
+		RestrictToContacts: v.RestrictToContacts,
+		RestrictToDomain:   v.RestrictToDomain,
+	}
+	if v.StartTime > 0 {
+		t := time.UnixMilli(v.StartTime)
+		vacation.Start = &t
+	}
+	if v.EndTime > 0 {
+		t := time.UnixMilli(v.EndTime)
+		vacation.End = &t
+	}
+	return vacation, nil
+}
+
+// SetVacation replaces the vacation responder settings
+func SetVacation(ctx context.Context, svc *Service, vacation *Vacation) (*Vacation, error) {
+	settings := &gmail.VacationSettings{
+		EnableAutoReply:       vacation.Enabled,
+		ResponseSubject:       vacation.Subject,
+		ResponseBodyPlainText: vacation.Body,
+		ResponseBodyHtml:      vacation.BodyHTML,
+		RestrictToContacts:    vacation.RestrictToContacts,
+		RestrictToDomain:      vacation.RestrictToDomain,
+		// Explicit false values must be sent so that flags can be turned off
+		ForceSendFields: []string{"EnableAutoReply", "RestrictToContacts", "RestrictToDomain"},
+	}
+	if vacation.Start != nil {
+		settings.StartTime = vacation.Start.UnixMilli()
+	}
+	if vacation.End != nil {
+		settings.EndTime = vacation.End.UnixMilli()
+	}
+
+	if _, err := svc.Gmail.Users.Settings.UpdateVacation("me", settings).Context(ctx).Do(); err != nil {
+		return nil, fmt.Errorf("unable to update vacation settings: %w", apiError(err))
+	}
+	return GetVacation(ctx, svc)
+}
+
+// DisableVacation turns the vacation responder off, keeping its message
+func DisableVacation(ctx context.Context, svc *Service) (*Vacation, error) {
+	vacation, err := GetVacation(ctx, svc)
+	if err != nil {
+		return nil, err
+	}
+	vacation.Enabled = false
+	return SetVacation(ctx, svc, vacation)
+}
+
+// ParseVacationTime parses a date (YYYY-MM-DD, local time) or RFC 3339 timestamp
+// With endOfDay, a plain date refers to the end of that day
+func ParseVacationTime(s string, endOfDay bool) (time.Time, error) {
+	if t, err := time.Parse(time.RFC3339, s); err == nil {
+		return t, nil
+	}
+	t, err := time.ParseInLocation(time.DateOnly, s, time.Local)
+	if err != nil {
+		return time.Time{}, fmt.Errorf("invalid date %q (use YYYY-MM-DD or RFC 3339)", s)
+	}
+	if endOfDay {
+		t = t.AddDate(0, 0, 1)
+	}
+	return t, nil
+}
@@ -0,0 +1,342 @@
+package gml
+
+import (
+	"context"
+	"encoding/base64"
+	"encoding/json"
+	"errors"
+	"fmt"
+	"net/http"
+	"sync"
+	"time"
+
+	"github.com/longkey1/gml/internal/google"
+	"google.golang.org/api/gmail/v1"
+	"google.golang.org/api/googleapi"
+)
+
+// WatchState records the last processed history ID for an account
+type WatchState struct {
+	HistoryID  uint64    `json:"historyId"`
+	Expiration time.Time `json:"expiration,omitempty"`
+	Topic      string    `json:"topic,omitempty"`
+	LabelIDs   []string  `json:"labelIds,omitempty"`
+}
+
+// WatchOptions contains options for registering a Gmail watch
+type WatchOptions struct {
+	Topic    string
+	LabelIDs []string
+}
+
+// StartWatch registers (or renews) a Gmail push watch on a Pub/Sub topic
+// Gmail requires renewal at least every 7 days; renewing daily is recommended
+func StartWatch(ctx context.Context, svc *Service, opts WatchOptions) (*WatchState, error) {
+	var labelIDs []string
+	if len(opts.LabelIDs) > 0 {
+		idx, err := FetchLabelIndex(ctx, svc)
+		if err != nil {
+			return nil, err
+		}
+		labelIDs, err = idx.ResolveLabelIDs(opts.LabelIDs)
+		if err != nil {
+			return nil, err
+		}
+	}
+
+	req := &gmail.WatchRequest{
+		TopicName: opts.Topic,
+		LabelIds:  labelIDs,
+	}
+	if len(labelIDs) > 0 {
+		req.LabelFilterBehavior = "include"
+	}
+
+	resp, err := svc.Gmail.Users.Watch("me", req).Context(ctx).Do()
+	if err != nil {
+		return nil, fmt.Errorf("unable to start watch: %w", apiError(err))
+	}
+
+	return &WatchState{
+		HistoryID:  resp.HistoryId,
+		Expiration: time.UnixMilli(resp.Expiration),
+		Topic:      opts.Topic,
+		LabelIDs:   labelIDs,
+	}, nil
+}
+
+// StopWatch stops push notifications for the mailbox
+func StopWatch(ctx context.Context, svc *Service) error {
+	if err := svc.Gmail.Users.Stop("me").Context(ctx).Do(); err != nil {
+		return fmt.Errorf("unable to stop watch: %w", apiError(err))
+	}
+	return nil
+}
+
+// CurrentHistoryID returns the mailbox's latest history ID
+func CurrentHistoryID(ctx context.Context, svc *Service) (uint64, error) {
+	profile, err := svc.API.GetProfile(ctx)
+	if err != nil {
+		return 0, fmt.Errorf("unable to get user profile: %w", apiError(err))
+	}
+	return profile.HistoryId, nil
+}
+
+// FetchNewMessageIDs returns IDs of messages added since startHistoryID,
+// optionally limited to a label, and the latest history ID seen
+func FetchNewMessageIDs(ctx context.Context, svc *Service, startHistoryID uint64, labelID string) ([]string, uint64, error) {
+	var ids []string
+	seen := make(map[string]bool)
+	latest := startHistoryID
+	pageToken := ""
+
+	for {
+		call := svc.Gmail.Users.History.List("me").StartHistoryId(startHistoryID).
+			HistoryTypes("messageAdded").MaxResults(500).Context(ctx)
+		if labelID != "" {
+			call = call.LabelId(labelID)
+		}
+		if pageToken != "" {
+			call = call.PageToken(pageToken)
+		}
+
+		resp, err := call.Do()
+		if err != nil {
+			if apiErr, ok := err.(*googleapi.Error); ok && apiErr.Code == http.StatusNotFound {
+				return nil, 0, fmt.Errorf("history ID %d is too old; restart the watch: %w", startHistoryID, apiError(err))
+			}
+			return nil, 0, fmt.Errorf("unable to list history: %w", err)
+		}
+
+		for _, h := range resp.History {
+			for _, added := range h.MessagesAdded {
+				if added.Message == nil || seen[added.Message.Id] {
+					continue
+				}
+				seen[added.Message.Id] = true
+				ids = append(ids, added.Message.Id)
+			}
+		}
+		if resp.HistoryId > latest {
+			latest = resp.HistoryId
+		}
+
+		if resp.NextPageToken == "" {
+			break
+		}
+		pageToken = resp.NextPageToken
+	}
+
+	return ids, latest, nil
+}
+
+// GetMessageInfo retrieves metadata for a single message as MessageInfo
+func GetMessageInfo(ctx context.Context, svc *Service, messageID string, fields map[string]bool, userEmail string, labelsIndex *LabelIndex) (MessageInfo, error) {
+	var opts google.GetMessageOptions
+	switch {
+	case needsFullFormat(fields):
+		opts.Format = "full"
+	case fields["headers"]:
+		// Without MetadataHeaders, metadata includes every header
+		opts.Format = "metadata"
+	default:
+		opts = google.GetMessageOptions{Format: "metadata", MetadataHeaders: metadataHeaders(fields, nil)}
+	}
+
+	msg, err := svc.API.GetMessage(ctx, messageID, opts)
+	if err != nil {
+		return MessageInfo{}, fmt.Errorf("unable to retrieve message: %w", apiError(err))
+	}
+
+	// Pick up labels created since the watcher started; stale names fall back to IDs
+	if fields["labels"] {
+		_ = labelsIndex.RefreshIfUnknown(ctx, msg.LabelIds)
+	}
+
+	info := buildMessageInfo(msg, fields, MailLinks{Email: userEmail}, labelsIndex, false)
+	if fields["headers"] {
+		info.Headers = MessageHeaders(msg.Payload, nil, false)
+	}
+	if fields["body"] {
+		info.Body = ExtractBody(msg.Payload)
+	}
+	return info, nil
+}
+
+// WatchConfig holds the [watch] config section; command-line flags take precedence
+type WatchConfig struct {
+	Label  string `mapstructure:"label"`
+	Fields string `mapstructure:"fields"`
+	Exec   string `mapstructure:"exec"`
+}
+
+// PushNotification is the payload Gmail publishes to Pub/Sub
+type PushNotification struct {
+	EmailAddress string `json:"emailAddress"`
+	HistoryID    uint64 `json:"historyId"`
+}
+
+// ParsePushRequest decodes a Pub/Sub push subscription request body
+func ParsePushRequest(r *http.Request) (*PushNotification, error) {
+	var envelope struct {
+		Message struct {
+			Data string `json:"data"`
+		} `json:"message"`
+	}
+	if err := json.NewDecoder(r.Body).Decode(&envelope); err != nil {
+		return nil, fmt.Errorf("invalid push request: %w", err)
+	}
+
+	data, err := base64.StdEncoding.DecodeString(envelope.Message.Data)
+	if err != nil {
+		return nil, fmt.Errorf("invalid push message data: %w", err)
+	}
+
+	var n PushNotification
+	if err := json.Unmarshal(data, &n); err != nil {
+		return nil, fmt.Errorf("invalid push notification: %w", err)
+	}
+	return &n, nil
+}
+
+// MessageNotifier emits newly arrived messages since a persisted history ID
+// It is safe for concurrent use; calls are serialized
+type MessageNotifier struct {
+	svc       *Service
+	statePath string
+
+	mu          sync.Mutex
+	state       WatchState
+	labelID     string
+	fields      map[string]bool
+	emit        func(MessageInfo) error
+	userEmail   string
+	labelsIndex *LabelIndex
+}
+
+// NewMessageNotifier creates a notifier that calls emit for each new message
+// If no history ID has been recorded yet, it starts from the current mailbox state
+func NewMessageNotifier(ctx context.Context, svc *Service, statePath, label string, fields map[string]bool, emit func(MessageInfo) error) (*MessageNotifier, error) {
+	n := &MessageNotifier{
+		svc:       svc,
+		statePath: statePath,
+	}
+
+	if _, err := LoadState(statePath, &n.state); err != nil {
+		return nil, err
+	}
+	if n.state.HistoryID == 0 {
+		id, err := CurrentHistoryID(ctx, svc)
+		if err != nil {
+			return nil, err
+		}
+		n.state.HistoryID = id
+		if err := n.save(ctx); err != nil {
+			return nil, err
+		}
+	}
+
+	if err := n.Reconfigure(ctx, label, fields, emit); err != nil {
+		return nil, err
+	}
+	return n, nil
+}
+
+// Reconfigure replaces the label filter, fields and emit callback used by
+// subsequent checks. On error the previous settings are kept
+func (n *MessageNotifier) Reconfigure(ctx context.Context, label string, fields map[string]bool, emit func(MessageInfo) error) error {
+	var userEmail string
+	if fields["url"] {
+		email, err := GetUserEmail(ctx, n.svc)
+		if err != nil {
+			return err
+		}
+		userEmail = email
+	}
+
+	var labelsIndex *LabelIndex
+	var labelID string
+	if fields["labels"] || label != "" {
+		idx, err := FetchLabelIndex(ctx, n.svc)
+		if err != nil {
+			return err
+		}
+		labelsIndex = idx
+		if label != "" {
+			ids, err := idx.ResolveLabelIDs([]string{label})
+			if err != nil {
+				return err
+			}
+			labelID = ids[0]
+		}
+	}
+
+	n.mu.Lock()
+	defer n.mu.Unlock()
+	n.labelID = labelID
+	n.fields = fields
+	n.emit = emit
+	n.userEmail = userEmail
+	n.labelsIndex = labelsIndex
+	return nil
+}
+
+// Resync moves the persisted history ID to the current mailbox state, e.g.
+// after the stored one expired; messages in between are not emitted
+func (n *MessageNotifier) Resync(ctx context.Context) error {
+	id, err := CurrentHistoryID(ctx, n.svc)
+	if err != nil {
+		return err
+	}
+
+	n.mu.Lock()
+	defer n.mu.Unlock()
+	n.state.HistoryID = id
+	return n.save(ctx)
+}
+
+// save persists the history ID, except under WithDryRun: whatever was done
+// with the emitted messages was only reported, so they must come again
+func (n *MessageNotifier) save(ctx context.Context) error {
+	if IsDryRun(ctx) {
+		return nil
+	}
+	return SaveState(n.statePath, n.state)
+}
+
+// Check emits messages added since the last processed history ID and
+// advances the persisted history ID
+func (n *MessageNotifier) Check(ctx context.Context) (int, error) {
+	n.mu.Lock()
+	defer n.mu.Unlock()
+
+	ids, latest, err := FetchNewMessageIDs(ctx, n.svc, n.state.HistoryID, n.labelID)
+	if err != nil {
+		return 0, err
+	}
+
+	count := 0
+	for _, id := range ids {
+		info, err := GetMessageInfo(ctx, n.svc, id, n.fields, n.userEmail, n.labelsIndex)
+		if errors.Is(err, ErrNotFound) {
+			// Messages may be deleted between notification and fetch
+			continue
+		}
+		if err != nil {
+			// Keep the history ID so the next check retries from here
+			return count, err
+		}
+		if err := n.emit(info); err != nil {
+			return count, err
+		}
+		count++
+	}
+
+	if latest > n.state.HistoryID {
+		n.state.HistoryID = latest
+		if err := n.save(ctx); err != nil {
+			return count, err
+		}
+	}
+	return count, nil
+}
@@ -0,0 +1,136 @@
+package gml
+
+import (
+	"fmt"
+	"strconv"
+	"strings"
+	"time"
+)
+
+// whenDefaultHour is the time of day used when a time names only a day, like
+// Gmail's "tomorrow" for snooze and scheduled send (8:00)
+const whenDefaultHour = 8
+
+// ParseWhen parses a future time for snoozing or scheduled sending, relative
+// to now:
+//   - a duration such as "3h", "2d" or "1w", optionally after "in"
+//   - "today", "tomorrow", "tonight" or a weekday ("monday", "next fri"),
+//     optionally followed by a time of day; a day alone means 8:00
+//   - a time of day alone ("9am", "17:30"): today, or tomorrow if it has passed
+//   - a date ("2025-07-01", optionally followed by a time of day) or RFC 3339
+//
+// The result must be in the future
+func ParseWhen(s string, now time.Time) (time.Time, error) {
+	text := strings.ToLower(strings.Join(strings.Fields(s), " "))
+	if text == "" {
+		return time.Time{}, fmt.Errorf("time is empty")
+	}
+	t, err := time.Parse(time.RFC3339, strings.TrimSpace(s))
+	if err != nil {
+		if t, err = parseWhen(text, now); err != nil {
+			return time.Time{}, fmt.Errorf("invalid time %q (e.g. 3h, tomorrow 9am, monday, 2025-07-01 14:00)", s)
+		}
+	}
+	if !t.After(now) {
+		return time.Time{}, fmt.Errorf("time %s is in the past", t.Format("2006-01-02 15:04"))
+	}
+	return t, nil
+}
+
+// parseWhen parses a normalized (lower case, single-spaced) time
+func parseWhen(text string, now time.Time) (time.Time, error) {
+	if d, err := ParseAge(strings.TrimPrefix(text, "in ")); err == nil {
+		return now.Add(d), nil
+	}
+
+	day, rest, _ := strings.Cut(text, " ")
+	var date time.Time
+	switch day {
+	case "today":
+		date = now
+	case "tonight":
+		date = now
+		if rest == "" {
+			rest = "19:00"
+		}
+	case "tomorrow":
+		date = now.AddDate(0, 0, 1)
+	case "next":
+		// "next monday" is the coming monday, like "monday"
+		day, rest, _ = strings.Cut(rest, " ")
+		fallthrough
+	default:
+		if wd, ok := parseWeekday(day); ok {
+			days := (int(wd) - int(now.Weekday()) + 7) % 7
+			if days == 0 {
+				days = 7
+			}
+			date = now.AddDate(0, 0, days)
+		} else if d, err := ParseQueryDate(day); err == nil {
+			date = d
+		} else if hour, minute, err := parseTimeOfDay(text); err == nil {
+			t := time.Date(now.Year(), now.Month(), now.Day(), hour, minute, 0, 0, now.Location())
+			if !t.After(now) {
+				t = t.AddDate(0, 0, 1)
+			}
+			return t, nil
+		} else {
+			return time.Time{}, err
+		}
+	}
+
+	hour, minute := whenDefaultHour, 0
+	if rest != "" {
+		var err error
+		if hour, minute, err = parseTimeOfDay(strings.TrimPrefix(rest, "at ")); err != nil {
+			return time.Time{}, err
+		}
+	}
+	return time.Date(date.Year(), date.Month(), date.Day(), hour, minute, 0, 0, now.Location()), nil
+}
+
+// parseWeekday parses a weekday name or its three-letter abbreviation
+func parseWeekday(s string) (time.Weekday, bool) {
+	if len(s) < 3 {
+		return 0, false
+	}
+	for d := time.Sunday; d <= time.Saturday; d++ {
+		if strings.HasPrefix(strings.ToLower(d.String()), s) {
+			return d, true
+		}
+	}
+	return 0, false
+}
+
+// parseTimeOfDay parses "9", "9am", "9:30pm", "17:30" or "noon"
+func parseTimeOfDay(s string) (hour, minute int, err error) {
+	s = strings.ReplaceAll(s, " ", "")
+	if s == "noon" {
+		return 12, 0, nil
+	}
+	suffix := ""
+	if strings.HasSuffix(s, "am") || strings.HasSuffix(s, "pm") {
+		s, suffix = s[:len(s)-2], s[len(s)-2:]
+	}
+	h, m, hasMin := strings.Cut(s, ":")
+	if hour, err = strconv.Atoi(h); err != nil {
+		return 0, 0, fmt.Errorf("invalid time of day %q", s)
+	}
+	if hasMin {
+		if minute, err = strconv.Atoi(m); err != nil || len(m) != 2 {
+			return 0, 0, fmt.Errorf("invalid time of day %q", s)
+		}
+	}
+	switch {
+	case suffix != "" && (hour < 1 || hour > 12):
+		return 0, 0, fmt.Errorf("invalid time of day %q", s)
+	case suffix == "am" && hour == 12:
+		hour = 0
+	case suffix == "pm" && hour < 12:
+		hour += 12
+	}
+	if hour > 23 || minute > 59 {
+		return 0, 0, fmt.Errorf("invalid time of day %q", s)
+	}
+	return hour, minute, nil
+}
@@ -1,14 +1,19 @@
 package google
 
 import (
+	"bufio"
 	"context"
-	"encoding/json"
+	"crypto/rand"
+	"encoding/hex"
 	"fmt"
+	"io"
 	"net"
 	"net/http"
+	"net/url"
 	"os"
 	"os/exec"
 	"runtime"
+	"strings"
 
 	"golang.org/x/oauth2"
 	"golang.org/x/oauth2/google"
@@ -23,69 +28,78 @@ type Authenticator interface {
 // OAuthAuthenticator implements Authenticator using OAuth2
 type OAuthAuthenticator struct {
 	credentialsFile string
-	tokenFile       string
+	tokenStore      TokenStore
+	scopes          []string
 }
 
 // NewOAuthAuthenticator creates a new OAuthAuthenticator
-func NewOAuthAuthenticator(credentialsFile, tokenFile string) *OAuthAuthenticator {
+// If no scopes are given, read-only Gmail access is requested
+func NewOAuthAuthenticator(credentialsFile string, tokenStore TokenStore, scopes ...string) *OAuthAuthenticator {
+	if len(scopes) == 0 {
+		scopes = []string{gmail.GmailReadonlyScope}
+	}
 	return &OAuthAuthenticator{
 		credentialsFile: credentialsFile,
-		tokenFile:       tokenFile,
+		tokenStore:      tokenStore,
+		scopes:          scopes,
 	}
 }
 
 // GetClient returns an authenticated HTTP client using OAuth2
 func (a *OAuthAuthenticator) GetClient(ctx context.Context) (*http.Client, error) {
-	b, err := os.ReadFile(a.credentialsFile)
+	config, err := a.oauthConfig()
 	if err != nil {
-		return nil, fmt.Errorf("unable to read client secret file: %v", err)
-	}
-
-	config, err := google.ConfigFromJSON(b, gmail.GmailReadonlyScope)
-	if err != nil {
-		return nil, fmt.Errorf("unable to parse client secret file to config: %v", err)
+		return nil, err
 	}
 
-	token, err := a.tokenFromFile()
+	token, err := a.tokenStore.Load()
 	if err != nil {
 		return nil, fmt.Errorf("token not found, please run 'gml auth' first: %v", err)
 	}
 
-	return config.Client(ctx, token), nil
+	// Refreshed tokens are written back to the token store
+	ts := newPersistingTokenSource(ctx, config, a.tokenStore, token)
+	return oauth2.NewClient(ctx, ts), nil
 }
 
-func (a *OAuthAuthenticator) tokenFromFile() (*oauth2.Token, error) {
-	f, err := os.Open(a.tokenFile)
+func (a *OAuthAuthenticator) saveToken(token *oauth2.Token) error {
+	fmt.Printf("Saving credential to: %s\n", a.tokenStore)
+	unlock, err := a.tokenStore.Lock()
 	if err != nil {
-		return nil, err
+		return fmt.Errorf("unable to lock token store: %v", err)
 	}
-	defer f.Close()
-
-	token := &oauth2.Token{}
-	err = json.NewDecoder(f).Decode(token)
-	return token, err
-}
+	defer unlock()
 
-func (a *OAuthAuthenticator) saveToken(token *oauth2.Token) error {
-	fmt.Printf("Saving credential file to: %s\n", a.tokenFile)
-	f, err := os.Create(a.tokenFile)
-	if err != nil {
+	if err := a.tokenStore.Save(token); err != nil {
 		return fmt.Errorf("unable to cache oauth token: %v", err)
 	}
-	defer f.Close()
-	return json.NewEncoder(f).Encode(token)
+	return nil
 }
 
-// Authenticate runs the OAuth flow with local server callback and saves the token
-func (a *OAuthAuthenticator) Authenticate() error {
+// oauthConfig loads the OAuth client configuration from the credentials file,
+// or uses the built-in client when no file is configured
+func (a *OAuthAuthenticator) oauthConfig() (*oauth2.Config, error) {
+	if a.credentialsFile == "" {
+		return builtinConfig(a.scopes)
+	}
+
 	b, err := os.ReadFile(a.credentialsFile)
 	if err != nil {
-		return fmt.Errorf("unable to read client secret file: %v", err)
+		return nil, fmt.Errorf("unable to read client secret file: %v", err)
+	}
+
+	config, err := google.ConfigFromJSON(b, a.scopes...)
+	if err != nil {
+		return nil, fmt.Errorf("unable to parse client secret file to config: %v", err)
 	}
+	return config, nil
+}
 
-	config, err := google.ConfigFromJSON(b, gmail.GmailReadonlyScope)
+// Authenticate runs the OAuth flow with local server callback and saves the token
+func (a *OAuthAuthenticator) Authenticate() error {
+	config, err := a.oauthConfig()
 	if err != nil {
-		return fmt.Errorf("unable to parse client secret file to config: %v", err)
+		return err
 	}
 
 	// Find available port
@@ -124,8 +138,9 @@ func (a *OAuthAuthenticator) Authenticate() error {
 		}
 	}()
 
-	// Generate auth URL
-	authURL := config.AuthCodeURL("state-token", oauth2.AccessTypeOffline)
+	// Generate auth URL; the PKCE verifier binds the code to this process
+	verifier := oauth2.GenerateVerifier()
+	authURL := config.AuthCodeURL("state-token", oauth2.AccessTypeOffline, oauth2.S256ChallengeOption(verifier))
 
 	fmt.Printf("Opening browser for authentication...\n")
 	fmt.Printf("If browser doesn't open, visit this URL:\n%s\n", authURL)
@@ -146,7 +161,49 @@ func (a *OAuthAuthenticator) Authenticate() error {
 	server.Close()
 
 	// Exchange code for token
-	token, err := config.Exchange(context.Background(), code)
+	token, err := config.Exchange(context.Background(), code, oauth2.VerifierOption(verifier))
+	if err != nil {
+		return fmt.Errorf("unable to retrieve token: %v", err)
+	}
+
+	return a.saveToken(token)
+}
+
+// AuthenticateManual runs the OAuth flow without a browser or local server
+// The user opens the printed URL on any machine and pastes back the URL the
+// browser was redirected to (or just the code), which suits SSH-only hosts
+func (a *OAuthAuthenticator) AuthenticateManual(in io.Reader, out io.Writer) error {
+	config, err := a.oauthConfig()
+	if err != nil {
+		return err
+	}
+
+	// Loopback redirect without a listener: the page fails to load, but the
+	// address bar still carries the authorization code
+	config.RedirectURL = "http://localhost"
+
+	state, err := randomState()
+	if err != nil {
+		return err
+	}
+	verifier := oauth2.GenerateVerifier()
+	authURL := config.AuthCodeURL(state, oauth2.AccessTypeOffline, oauth2.S256ChallengeOption(verifier))
+
+	fmt.Fprintf(out, "Visit this URL in a browser on any machine:\n%s\n\n", authURL)
+	fmt.Fprintln(out, "After approving access, the browser is redirected to a localhost page that will not load.")
+	fmt.Fprint(out, "Paste the full URL from the address bar (or just the code): ")
+
+	line, err := bufio.NewReader(in).ReadString('\n')
+	if err != nil && line == "" {
+		return fmt.Errorf("unable to read authorization response: %v", err)
+	}
+
+	code, err := parseAuthResponse(strings.TrimSpace(line), state)
+	if err != nil {
+		return err
+	}
+
+	token, err := config.Exchange(context.Background(), code, oauth2.VerifierOption(verifier))
 	if err != nil {
 		return fmt.Errorf("unable to retrieve token: %v", err)
 	}
@@ -154,18 +211,59 @@ func (a *OAuthAuthenticator) Authenticate() error {
 	return a.saveToken(token)
 }
 
+// parseAuthResponse extracts the authorization code from a pasted redirect URL or bare code
+func parseAuthResponse(input, state string) (string, error) {
+	if input == "" {
+		return "", fmt.Errorf("no authorization code entered")
+	}
+	if !strings.Contains(input, "code=") {
+		return input, nil
+	}
+
+	u, err := url.Parse(input)
+	if err != nil {
+		return "", fmt.Errorf("unable to parse redirect URL: %v", err)
+	}
+	q := u.Query()
+	if e := q.Get("error"); e != "" {
+		return "", fmt.Errorf("authorization denied: %s", e)
+	}
+	if s := q.Get("state"); s != "" && s != state {
+		return "", fmt.Errorf("state mismatch in redirect URL")
+	}
+	code := q.Get("code")
+	if code == "" {
+		return "", fmt.Errorf("no code in redirect URL")
+	}
+	return code, nil
+}
+
+// randomState returns a random OAuth state value
+func randomState() (string, error) {
+	b := make([]byte, 16)
+	if _, err := rand.Read(b); err != nil {
+		return "", fmt.Errorf("unable to generate state: %v", err)
+	}
+	return hex.EncodeToString(b), nil
+}
+
 func openBrowser(url string) {
-	var err error
+	if err := OpenBrowser(url); err != nil {
+		fmt.Printf("Failed to open browser: %v\n", err)
+	}
+}
+
+// OpenBrowser opens a URL in the default browser
+func OpenBrowser(url string) error {
 	switch runtime.GOOS {
 	case "darwin":
-		err = exec.Command("open", url).Start()
+		return exec.Command("open", url).Start()
 	case "linux":
-		err = exec.Command("xdg-open", url).Start()
+		return exec.Command("xdg-open", url).Start()
 	case "windows":
-		err = exec.Command("rundll32", "url.dll,FileProtocolHandler", url).Start()
-	}
-	if err != nil {
-		fmt.Printf("Failed to open browser: %v\n", err)
+		return exec.Command("rundll32", "url.dll,FileProtocolHandler", url).Start()
+	default:
+		return fmt.Errorf("opening a browser is not supported on %s", runtime.GOOS)
 	}
 }
 
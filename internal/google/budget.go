@@ -0,0 +1,63 @@
+package google
+
+import (
+	"context"
+	"errors"
+	"net/http"
+	"sync"
+)
+
+// ErrRequestBudget is returned for requests made after the budget of
+// WithRequestBudget was used up
+var ErrRequestBudget = errors.New("API request budget exhausted")
+
+// budgetKey is the context key of the request budget
+type budgetKey struct{}
+
+// requestBudget counts the requests made with a context against a limit
+type requestBudget struct {
+	mu    sync.Mutex
+	limit int
+	used  int
+}
+
+// WithRequestBudget returns a context in which at most limit API requests
+// are sent; later ones fail with ErrRequestBudget without being sent. A
+// limit of 0 only counts the requests. Requests of services using
+// Application Default Credentials are not counted
+func WithRequestBudget(ctx context.Context, limit int) context.Context {
+	return context.WithValue(ctx, budgetKey{}, &requestBudget{limit: limit})
+}
+
+// RequestsUsed returns the number of requests sent with a context created by
+// WithRequestBudget, or 0 for other contexts
+func RequestsUsed(ctx context.Context) int {
+	b, ok := ctx.Value(budgetKey{}).(*requestBudget)
+	if !ok {
+		return 0
+	}
+	b.mu.Lock()
+	defer b.mu.Unlock()
+	return b.used
+}
+
+// hasRequestLimit reports whether ctx limits the number of requests
+func hasRequestLimit(ctx context.Context) bool {
+	b, ok := ctx.Value(budgetKey{}).(*requestBudget)
+	return ok && b.limit > 0
+}
+
+// takeBudget counts a request against the budget of its context
+func takeBudget(r *http.Request) error {
+	b, ok := r.Context().Value(budgetKey{}).(*requestBudget)
+	if !ok {
+		return nil
+	}
+	b.mu.Lock()
+	defer b.mu.Unlock()
+	if b.limit > 0 && b.used >= b.limit {
+		return ErrRequestBudget
+	}
+	b.used++
+	return nil
+}
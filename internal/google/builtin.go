@@ -0,0 +1,50 @@
+package google
+
+import (
+	"fmt"
+	"slices"
+
+	"golang.org/x/oauth2"
+	"golang.org/x/oauth2/google"
+	"google.golang.org/api/gmail/v1"
+)
+
+// The built-in OAuth client lets gml authenticate without a Google Cloud
+// project of the user's own. Release builds set it with
+//
+//	-ldflags "-X github.com/longkey1/gml/internal/google.BuiltinClientID=..."
+//
+// Google treats the secret of a desktop client as public; PKCE protects the
+// authorization code exchange instead
+var (
+	BuiltinClientID     = ""
+	BuiltinClientSecret = ""
+)
+
+// builtinScopes are the only scopes the built-in client may request
+var builtinScopes = []string{gmail.GmailReadonlyScope}
+
+// HasBuiltinClient reports whether this build includes a built-in OAuth client
+func HasBuiltinClient() bool {
+	return BuiltinClientID != ""
+}
+
+// builtinConfig returns the OAuth configuration of the built-in client
+func builtinConfig(scopes []string) (*oauth2.Config, error) {
+	if !HasBuiltinClient() {
+		return nil, fmt.Errorf("application_credentials is not set and this build has no built-in OAuth client; " +
+			"create an OAuth client in Google Cloud and set application_credentials in the config file")
+	}
+	for _, s := range scopes {
+		if !slices.Contains(builtinScopes, s) {
+			return nil, fmt.Errorf("the built-in OAuth client only grants read-only access (requested %s); "+
+				"set application_credentials to use your own OAuth client", s)
+		}
+	}
+	return &oauth2.Config{
+		ClientID:     BuiltinClientID,
+		ClientSecret: BuiltinClientSecret,
+		Endpoint:     google.Endpoint,
+		Scopes:       scopes,
+	}, nil
+}
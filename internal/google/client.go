@@ -0,0 +1,219 @@
+package google
+
+import (
+	"context"
+
+	"google.golang.org/api/gmail/v1"
+	"google.golang.org/api/googleapi"
+)
+
+// GmailClient is the narrow part of the Gmail API the core mail functions
+// use: messages, threads, labels and the profile of the authenticated user. GmailService
+// implements it with the API; fakes implement it for tests (see
+// internal/fakegmail). Errors are those of the API, e.g. *googleapi.Error
+type GmailClient interface {
+	// GetProfile returns the email address and totals of the mailbox
+	GetProfile(ctx context.Context) (*gmail.Profile, error)
+	// ListMessages returns one page of the IDs of matching messages, newest first
+	ListMessages(ctx context.Context, opts ListMessagesOptions) (*gmail.ListMessagesResponse, error)
+	// GetMessage returns a message in the format of opts
+	GetMessage(ctx context.Context, id string, opts GetMessageOptions) (*gmail.Message, error)
+	// ModifyMessage adds and removes labels of a message
+	ModifyMessage(ctx context.Context, id string, req *gmail.ModifyMessageRequest) error
+	// BatchModifyMessages adds and removes labels of up to 1,000 messages
+	BatchModifyMessages(ctx context.Context, req *gmail.BatchModifyMessagesRequest) error
+	// TrashMessage moves a message to the trash
+	TrashMessage(ctx context.Context, id string) error
+	// GetAttachment returns the data of an attachment of a message
+	GetAttachment(ctx context.Context, messageID, id string) (*gmail.MessagePartBody, error)
+	// ListThreads returns one page of the IDs and snippets of threads with a
+	// matching message, newest first
+	ListThreads(ctx context.Context, opts ListMessagesOptions) (*gmail.ListThreadsResponse, error)
+	// GetThread returns the messages of a thread, oldest first, in the format
+	// of opts
+	GetThread(ctx context.Context, id string, opts GetMessageOptions) (*gmail.Thread, error)
+	// ModifyThread adds and removes labels of every message of a thread
+	ModifyThread(ctx context.Context, id string, req *gmail.ModifyThreadRequest) error
+	// TrashThread moves every message of a thread to the trash
+	TrashThread(ctx context.Context, id string) error
+	// ListLabels returns every label, with only the given fields if any.
+	// Message and thread counts are only set by GetLabel
+	ListLabels(ctx context.Context, fields ...googleapi.Field) ([]*gmail.Label, error)
+	// GetLabel returns a label with its message and thread counts
+	GetLabel(ctx context.Context, id string) (*gmail.Label, error)
+	// CreateLabel creates a user label
+	CreateLabel(ctx context.Context, label *gmail.Label) (*gmail.Label, error)
+	// PatchLabel changes the non-empty fields of a user label
+	PatchLabel(ctx context.Context, id string, label *gmail.Label) (*gmail.Label, error)
+	// DeleteLabel deletes a user label and removes it from its messages
+	DeleteLabel(ctx context.Context, id string) error
+}
+
+// ListMessagesOptions selects the messages of GmailClient.ListMessages, or the
+// threads of GmailClient.ListThreads
+type ListMessagesOptions struct {
+	// Query is a Gmail search query
+	Query string
+	// LabelIDs limits the results to messages with all these labels
+	LabelIDs []string
+	// MaxResults is the page size (0 for the API default)
+	MaxResults int64
+	// PageToken is the NextPageToken of the previous page
+	PageToken string
+	// Fields limits the response to these fields
+	Fields []googleapi.Field
+}
+
+// GetMessageOptions controls what GmailClient.GetMessage and GetThread return
+type GetMessageOptions struct {
+	// Format is minimal, metadata, full (default) or raw
+	Format string
+	// MetadataHeaders limits the headers of the metadata format
+	MetadataHeaders []string
+	// Fields limits the response to these fields
+	Fields []googleapi.Field
+}
+
+// GetProfile returns the profile of the authenticated user
+func (s *GmailService) GetProfile(ctx context.Context) (*gmail.Profile, error) {
+	return s.Users.GetProfile("me").Context(ctx).Do()
+}
+
+// ListMessages lists one page of messages
+func (s *GmailService) ListMessages(ctx context.Context, opts ListMessagesOptions) (*gmail.ListMessagesResponse, error) {
+	call := s.Users.Messages.List("me").Context(ctx)
+	if opts.Query != "" {
+		call = call.Q(opts.Query)
+	}
+	if len(opts.LabelIDs) > 0 {
+		call = call.LabelIds(opts.LabelIDs...)
+	}
+	if opts.MaxResults > 0 {
+		call = call.MaxResults(opts.MaxResults)
+	}
+	if opts.PageToken != "" {
+		call = call.PageToken(opts.PageToken)
+	}
+	if len(opts.Fields) > 0 {
+		call = call.Fields(opts.Fields...)
+	}
+	return call.Do()
+}
+
+// GetMessage gets a message
+func (s *GmailService) GetMessage(ctx context.Context, id string, opts GetMessageOptions) (*gmail.Message, error) {
+	call := s.Users.Messages.Get("me", id).Context(ctx)
+	if opts.Format != "" {
+		call = call.Format(opts.Format)
+	}
+	if len(opts.MetadataHeaders) > 0 {
+		call = call.MetadataHeaders(opts.MetadataHeaders...)
+	}
+	if len(opts.Fields) > 0 {
+		call = call.Fields(opts.Fields...)
+	}
+	return call.Do()
+}
+
+// ModifyMessage modifies the labels of a message
+func (s *GmailService) ModifyMessage(ctx context.Context, id string, req *gmail.ModifyMessageRequest) error {
+	_, err := s.Users.Messages.Modify("me", id, req).Context(ctx).Do()
+	return err
+}
+
+// BatchModifyMessages modifies the labels of messages
+func (s *GmailService) BatchModifyMessages(ctx context.Context, req *gmail.BatchModifyMessagesRequest) error {
+	return s.Users.Messages.BatchModify("me", req).Context(ctx).Do()
+}
+
+// TrashMessage trashes a message
+func (s *GmailService) TrashMessage(ctx context.Context, id string) error {
+	_, err := s.Users.Messages.Trash("me", id).Context(ctx).Do()
+	return err
+}
+
+// GetAttachment gets the data of an attachment
+func (s *GmailService) GetAttachment(ctx context.Context, messageID, id string) (*gmail.MessagePartBody, error) {
+	return s.Users.Messages.Attachments.Get("me", messageID, id).Context(ctx).Do()
+}
+
+// ListThreads lists one page of threads
+func (s *GmailService) ListThreads(ctx context.Context, opts ListMessagesOptions) (*gmail.ListThreadsResponse, error) {
+	call := s.Users.Threads.List("me").Context(ctx)
+	if opts.Query != "" {
+		call = call.Q(opts.Query)
+	}
+	if len(opts.LabelIDs) > 0 {
+		call = call.LabelIds(opts.LabelIDs...)
+	}
+	if opts.MaxResults > 0 {
+		call = call.MaxResults(opts.MaxResults)
+	}
+	if opts.PageToken != "" {
+		call = call.PageToken(opts.PageToken)
+	}
+	if len(opts.Fields) > 0 {
+		call = call.Fields(opts.Fields...)
+	}
+	return call.Do()
+}
+
+// GetThread gets a thread
+func (s *GmailService) GetThread(ctx context.Context, id string, opts GetMessageOptions) (*gmail.Thread, error) {
+	call := s.Users.Threads.Get("me", id).Context(ctx)
+	if opts.Format != "" {
+		call = call.Format(opts.Format)
+	}
+	if len(opts.MetadataHeaders) > 0 {
+		call = call.MetadataHeaders(opts.MetadataHeaders...)
+	}
+	if len(opts.Fields) > 0 {
+		call = call.Fields(opts.Fields...)
+	}
+	return call.Do()
+}
+
+// ModifyThread modifies the labels of a thread
+func (s *GmailService) ModifyThread(ctx context.Context, id string, req *gmail.ModifyThreadRequest) error {
+	_, err := s.Users.Threads.Modify("me", id, req).Context(ctx).Do()
+	return err
+}
+
+// TrashThread trashes a thread
+func (s *GmailService) TrashThread(ctx context.Context, id string) error {
+	_, err := s.Users.Threads.Trash("me", id).Context(ctx).Do()
+	return err
+}
+
+// ListLabels lists the labels; labels.list has no paging
+func (s *GmailService) ListLabels(ctx context.Context, fields ...googleapi.Field) ([]*gmail.Label, error) {
+	call := s.Users.Labels.List("me").Context(ctx)
+	if len(fields) > 0 {
+		call = call.Fields(fields...)
+	}
+	resp, err := call.Do()
+	if err != nil {
+		return nil, err
+	}
+	return resp.Labels, nil
+}
+
+// CreateLabel creates a label
+func (s *GmailService) CreateLabel(ctx context.Context, label *gmail.Label) (*gmail.Label, error) {
+	return s.Users.Labels.Create("me", label).Context(ctx).Do()
+}
+
+// GetLabel gets a label
+func (s *GmailService) GetLabel(ctx context.Context, id string) (*gmail.Label, error) {
+	return s.Users.Labels.Get("me", id).Context(ctx).Do()
+}
+
+// PatchLabel patches a label
+func (s *GmailService) PatchLabel(ctx context.Context, id string, label *gmail.Label) (*gmail.Label, error) {
+	return s.Users.Labels.Patch("me", id, label).Context(ctx).Do()
+}
+
+// DeleteLabel deletes a label
+func (s *GmailService) DeleteLabel(ctx context.Context, id string) error {
+	return s.Users.Labels.Delete("me", id).Context(ctx).Do()
+}
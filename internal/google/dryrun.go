@@ -0,0 +1,122 @@
+package google
+
+import (
+	"bytes"
+	"context"
+	"encoding/json"
+	"fmt"
+	"io"
+	"net/http"
+	"strings"
+	"sync"
+)
+
+// dryRunKey is the context key of the dry-run writer
+type dryRunKey struct{}
+
+// dryRun holds where intercepted operations are written
+type dryRun struct {
+	mu sync.Mutex
+	w  io.Writer
+}
+
+// DryRunOperation is an API request that was not sent because of --dry-run
+type DryRunOperation struct {
+	DryRun   bool   `json:"dryRun"`
+	Method   string `json:"method"`
+	Endpoint string `json:"endpoint"`
+	URL      string `json:"url"`
+	// Body is the JSON request body; other bodies are summarized by
+	// ContentType and Size
+	Body        json.RawMessage `json:"body,omitempty"`
+	ContentType string          `json:"contentType,omitempty"`
+	Size        int             `json:"size,omitempty"`
+}
+
+// WithDryRun returns a context in which API requests that change state are
+// written to w as JSON lines instead of being sent. Reads still go through,
+// so commands can find what they would act on
+func WithDryRun(ctx context.Context, w io.Writer) context.Context {
+	return context.WithValue(ctx, dryRunKey{}, &dryRun{w: w})
+}
+
+// IsDryRun reports whether ctx was created by WithDryRun
+func IsDryRun(ctx context.Context) bool {
+	_, ok := ctx.Value(dryRunKey{}).(*dryRun)
+	return ok
+}
+
+// interceptDryRun writes a state-changing request to the dry-run writer and
+// answers it with an empty success response. It reports false for requests
+// that should be sent
+func interceptDryRun(r *http.Request) (*http.Response, bool, error) {
+	d, ok := r.Context().Value(dryRunKey{}).(*dryRun)
+	if !ok || r.Method == http.MethodGet || r.Method == http.MethodHead {
+		return nil, false, nil
+	}
+
+	op := DryRunOperation{
+		DryRun:   true,
+		Method:   r.Method,
+		Endpoint: endpointName(r.URL.Path),
+		URL:      r.URL.String(),
+	}
+	if r.Body != nil {
+		body, err := io.ReadAll(r.Body)
+		r.Body.Close()
+		if err != nil {
+			return nil, true, fmt.Errorf("unable to read request body: %v", err)
+		}
+		contentType := r.Header.Get("Content-Type")
+		if strings.HasPrefix(contentType, "application/json") && json.Valid(body) {
+			op.Body = body
+		} else if len(body) > 0 {
+			op.ContentType = contentType
+			op.Size = len(body)
+		}
+	}
+
+	if err := d.write(op); err != nil {
+		return nil, true, err
+	}
+
+	status, body := http.StatusOK, "{}"
+	if r.Method == http.MethodDelete {
+		status, body = http.StatusNoContent, ""
+	}
+	return &http.Response{
+		Status:        http.StatusText(status),
+		StatusCode:    status,
+		Proto:         "HTTP/1.1",
+		ProtoMajor:    1,
+		ProtoMinor:    1,
+		Header:        http.Header{"Content-Type": []string{"application/json"}},
+		Body:          io.NopCloser(bytes.NewBufferString(body)),
+		ContentLength: int64(len(body)),
+		Request:       r,
+	}, true, nil
+}
+
+// ReportDryRun writes op to the dry-run writer of ctx, for requests that
+// don't go through the Gmail transport. It reports false outside a dry run,
+// when the request should be sent
+func ReportDryRun(ctx context.Context, op DryRunOperation) (bool, error) {
+	d, ok := ctx.Value(dryRunKey{}).(*dryRun)
+	if !ok {
+		return false, nil
+	}
+	op.DryRun = true
+	return true, d.write(op)
+}
+
+// write encodes op as a JSON line
+func (d *dryRun) write(op DryRunOperation) error {
+	d.mu.Lock()
+	defer d.mu.Unlock()
+	enc := json.NewEncoder(d.w)
+	enc.SetEscapeHTML(false)
+	if err := enc.Encode(op); err != nil {
+		return fmt.Errorf("unable to write dry-run operation: %v", err)
+	}
+	return nil
+}
@@ -3,7 +3,13 @@ package google
 import (
 	"context"
 	"fmt"
+	"io"
+	"net/http"
+	"net/url"
+	"strings"
 
+	"github.com/longkey1/gml/internal/telemetry"
+	oauth2google "golang.org/x/oauth2/google"
 	"google.golang.org/api/gmail/v1"
 	"google.golang.org/api/option"
 )
@@ -11,16 +17,49 @@ import (
 // GmailService wraps the Google Gmail API service
 type GmailService struct {
 	*gmail.Service
+	// client sends raw requests (see Do); nil with Application Default Credentials
+	client *http.Client
 }
 
-// NewGmailService creates a new Gmail service with the given authenticator
-func NewGmailService(ctx context.Context, auth Authenticator) (*GmailService, error) {
+// NewClient returns the HTTP client API services send their requests with,
+// authenticated by auth. header, if not empty, is added to every API request.
+// It returns nil with Application Default Credentials, which each service
+// sets up itself
+func NewClient(ctx context.Context, auth Authenticator, header http.Header) (*http.Client, error) {
 	client, err := auth.GetClient(ctx)
 	if err != nil {
 		return nil, fmt.Errorf("failed to get authenticated client: %v", err)
 	}
 
+	if client == nil {
+		// The ADC transport can't be wrapped, so writes couldn't be intercepted
+		if IsDryRun(ctx) {
+			return nil, fmt.Errorf("--dry-run is not supported with service account authentication")
+		}
+		if hasRequestLimit(ctx) {
+			return nil, fmt.Errorf("request budgets are not supported with service account authentication")
+		}
+		if len(header) > 0 {
+			return nil, fmt.Errorf("custom headers are not supported with service account authentication")
+		}
+		return nil, nil
+	}
+
+	// Trace, count and log API requests; the ADC transport is instrumented by the client library
+	c := *client
+	c.Transport = client.Transport
+	if len(header) > 0 {
+		c.Transport = headerTransport{base: c.Transport, header: header}
+	}
+	c.Transport = telemetry.Transport(apiTransport{base: c.Transport})
+	return &c, nil
+}
+
+// NewGmailService creates a new Gmail service sending requests with client
+// (see NewClient), or with Application Default Credentials when it's nil
+func NewGmailService(ctx context.Context, client *http.Client) (*GmailService, error) {
 	var srv *gmail.Service
+	var err error
 	if client != nil {
 		srv, err = gmail.NewService(ctx, option.WithHTTPClient(client))
 	} else {
@@ -31,5 +70,41 @@ func NewGmailService(ctx context.Context, auth Authenticator) (*GmailService, er
 		return nil, fmt.Errorf("failed to create gmail service: %v", err)
 	}
 
-	return &GmailService{srv}, nil
+	return &GmailService{Service: srv, client: client}, nil
+}
+
+// Do sends a request to the Gmail REST API signed with the service's
+// credentials. path is relative to gmail/v1/, e.g. users/me/profile
+func (s *GmailService) Do(ctx context.Context, method, path string, body io.Reader) (*http.Response, error) {
+	client := s.client
+	if client == nil {
+		c, err := oauth2google.DefaultClient(ctx, gmail.MailGoogleComScope)
+		if err != nil {
+			return nil, fmt.Errorf("unable to get default credentials: %w", err)
+		}
+		c.Transport = telemetry.Transport(apiTransport{base: c.Transport})
+		client = c
+	}
+
+	base, err := url.Parse(s.BasePath + "gmail/v1/")
+	if err != nil {
+		return nil, fmt.Errorf("invalid API base path: %w", err)
+	}
+	ref, err := url.Parse(strings.TrimPrefix(strings.TrimPrefix(path, "/"), "gmail/v1/"))
+	if err != nil {
+		return nil, fmt.Errorf("invalid API path: %w", err)
+	}
+	// Never send the credentials anywhere but the Gmail API
+	if ref.Scheme != "" || ref.Host != "" {
+		return nil, fmt.Errorf("API path must be relative, e.g. users/me/profile: %s", path)
+	}
+
+	req, err := http.NewRequestWithContext(ctx, method, base.ResolveReference(ref).String(), body)
+	if err != nil {
+		return nil, fmt.Errorf("unable to create request: %w", err)
+	}
+	if body != nil {
+		req.Header.Set("Content-Type", "application/json")
+	}
+	return client.Do(req)
 }
@@ -0,0 +1,24 @@
+package google
+
+import "net/http"
+
+// headerTransport adds fixed headers to every request, e.g. for an API
+// gateway between gml and Google. It runs before the OAuth transport, which
+// sets Authorization last
+type headerTransport struct {
+	base   http.RoundTripper
+	header http.Header
+}
+
+func (t headerTransport) RoundTrip(r *http.Request) (*http.Response, error) {
+	// RoundTrippers must not modify the caller's request
+	r = r.Clone(r.Context())
+	for name, values := range t.header {
+		r.Header[name] = values
+	}
+	base := t.base
+	if base == nil {
+		base = http.DefaultTransport
+	}
+	return base.RoundTrip(r)
+}
@@ -0,0 +1,82 @@
+package google
+
+import (
+	"encoding/json"
+	"fmt"
+	"os"
+	"path/filepath"
+
+	"github.com/zalando/go-keyring"
+	"golang.org/x/oauth2"
+)
+
+// keyringService is the service name tokens are stored under in the OS keyring
+const keyringService = "gml"
+
+// KeyringTokenStore stores the token in the OS keyring
+// (macOS Keychain, Linux Secret Service, Windows Credential Manager)
+type KeyringTokenStore struct {
+	key string
+}
+
+// NewKeyringTokenStore creates a new KeyringTokenStore for the given key
+func NewKeyringTokenStore(key string) *KeyringTokenStore {
+	return &KeyringTokenStore{key: key}
+}
+
+// Load reads the token from the keyring
+func (s *KeyringTokenStore) Load() (*oauth2.Token, error) {
+	data, err := keyring.Get(keyringService, s.key)
+	if err != nil {
+		if err == keyring.ErrNotFound {
+			return nil, os.ErrNotExist
+		}
+		return nil, fmt.Errorf("unable to read token from keyring: %v", err)
+	}
+
+	token := &oauth2.Token{}
+	if err := json.Unmarshal([]byte(data), token); err != nil {
+		return nil, fmt.Errorf("unable to parse token from keyring: %v", err)
+	}
+	return token, nil
+}
+
+// Save writes the token to the keyring
+func (s *KeyringTokenStore) Save(token *oauth2.Token) error {
+	data, err := json.Marshal(token)
+	if err != nil {
+		return err
+	}
+	if err := keyring.Set(keyringService, s.key, string(data)); err != nil {
+		return fmt.Errorf("unable to write token to keyring: %v", err)
+	}
+	return nil
+}
+
+// Delete removes the token from the keyring
+func (s *KeyringTokenStore) Delete() error {
+	if err := keyring.Delete(keyringService, s.key); err != nil {
+		if err == keyring.ErrNotFound {
+			return os.ErrNotExist
+		}
+		return fmt.Errorf("unable to delete token from keyring: %v", err)
+	}
+	return nil
+}
+
+// Lock takes an exclusive lock on a file in the user cache directory
+func (s *KeyringTokenStore) Lock() (func(), error) {
+	dir, err := os.UserCacheDir()
+	if err != nil {
+		dir = os.TempDir()
+	}
+	dir = filepath.Join(dir, "gml")
+	if err := os.MkdirAll(dir, 0700); err != nil {
+		return nil, err
+	}
+	return LockFile(filepath.Join(dir, "keyring-"+s.key+".lock"))
+}
+
+func (s *KeyringTokenStore) String() string {
+	return fmt.Sprintf("keyring (service %q, key %q)", keyringService, s.key)
+}
@@ -0,0 +1,8 @@
+//go:build !unix
+
+package google
+
+// LockFile is a no-op on platforms without flock support
+func LockFile(path string) (func(), error) {
+	return func() {}, nil
+}
@@ -0,0 +1,25 @@
+//go:build unix
+
+package google
+
+import (
+	"os"
+	"syscall"
+)
+
+// LockFile takes an exclusive advisory lock on path, creating it if needed,
+// and returns a function that releases it
+func LockFile(path string) (func(), error) {
+	f, err := os.OpenFile(path, os.O_CREATE|os.O_RDWR, 0600)
+	if err != nil {
+		return nil, err
+	}
+	if err := syscall.Flock(int(f.Fd()), syscall.LOCK_EX); err != nil {
+		f.Close()
+		return nil, err
+	}
+	return func() {
+		syscall.Flock(int(f.Fd()), syscall.LOCK_UN)
+		f.Close()
+	}, nil
+}
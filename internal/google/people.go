@@ -0,0 +1,31 @@
+package google
+
+import (
+	"context"
+	"fmt"
+	"net/http"
+
+	"google.golang.org/api/option"
+	"google.golang.org/api/people/v1"
+)
+
+// PeopleService wraps the Google People API service, used to look up contacts
+type PeopleService struct {
+	*people.Service
+}
+
+// NewPeopleService creates a new People service sending requests with client
+// (see NewClient), or with Application Default Credentials when it's nil
+func NewPeopleService(ctx context.Context, client *http.Client) (*PeopleService, error) {
+	var srv *people.Service
+	var err error
+	if client != nil {
+		srv, err = people.NewService(ctx, option.WithHTTPClient(client))
+	} else {
+		srv, err = people.NewService(ctx)
+	}
+	if err != nil {
+		return nil, fmt.Errorf("failed to create people service: %v", err)
+	}
+	return &PeopleService{Service: srv}, nil
+}
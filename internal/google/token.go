@@ -0,0 +1,217 @@
+package google
+
+import (
+	"context"
+	"encoding/json"
+	"fmt"
+	"log/slog"
+	"os"
+	"path/filepath"
+	"sync"
+
+	"golang.org/x/oauth2"
+)
+
+// TokenStore persists OAuth tokens between invocations
+type TokenStore interface {
+	// Load returns the stored token
+	Load() (*oauth2.Token, error)
+	// Save stores the token, replacing any existing one
+	Save(token *oauth2.Token) error
+	// Delete removes the stored token
+	Delete() error
+	// Lock serializes access across processes and returns a function that releases it
+	Lock() (func(), error)
+	// String describes where the token is stored
+	String() string
+}
+
+// FileTokenStore stores the token as a JSON file
+type FileTokenStore struct {
+	path string
+}
+
+// NewFileTokenStore creates a new FileTokenStore
+func NewFileTokenStore(path string) *FileTokenStore {
+	return &FileTokenStore{path: path}
+}
+
+// Load reads the token from the file
+func (s *FileTokenStore) Load() (*oauth2.Token, error) {
+	return readTokenFile(s.path)
+}
+
+// Save writes the token to the file
+func (s *FileTokenStore) Save(token *oauth2.Token) error {
+	return writeTokenFile(s.path, token)
+}
+
+// Delete removes the token file
+func (s *FileTokenStore) Delete() error {
+	return os.Remove(s.path)
+}
+
+// Lock takes an exclusive lock on a sibling .lock file
+func (s *FileTokenStore) Lock() (func(), error) {
+	return LockFile(s.path + ".lock")
+}
+
+func (s *FileTokenStore) String() string {
+	return s.path
+}
+
+// MemoryTokenStore keeps the token in memory only, e.g. for tests or a token
+// obtained elsewhere. Lock serializes access within the process
+type MemoryTokenStore struct {
+	lock sync.Mutex
+
+	mu    sync.Mutex
+	token *oauth2.Token
+}
+
+// NewMemoryTokenStore creates a new MemoryTokenStore holding token, which may
+// be nil
+func NewMemoryTokenStore(token *oauth2.Token) *MemoryTokenStore {
+	return &MemoryTokenStore{token: token}
+}
+
+// Load returns the token, or os.ErrNotExist if there is none
+func (s *MemoryTokenStore) Load() (*oauth2.Token, error) {
+	s.mu.Lock()
+	defer s.mu.Unlock()
+	if s.token == nil {
+		return nil, os.ErrNotExist
+	}
+	token := *s.token
+	return &token, nil
+}
+
+// Save replaces the token
+func (s *MemoryTokenStore) Save(token *oauth2.Token) error {
+	s.mu.Lock()
+	defer s.mu.Unlock()
+	t := *token
+	s.token = &t
+	return nil
+}
+
+// Delete drops the token
+func (s *MemoryTokenStore) Delete() error {
+	s.mu.Lock()
+	defer s.mu.Unlock()
+	if s.token == nil {
+		return os.ErrNotExist
+	}
+	s.token = nil
+	return nil
+}
+
+// Lock takes the store's mutex
+func (s *MemoryTokenStore) Lock() (func(), error) {
+	s.lock.Lock()
+	return s.lock.Unlock, nil
+}
+
+func (s *MemoryTokenStore) String() string {
+	return "memory"
+}
+
+// persistingTokenSource is an oauth2.TokenSource that writes refreshed tokens
+// back to the token store so later invocations reuse them
+type persistingTokenSource struct {
+	ctx    context.Context
+	config *oauth2.Config
+	store  TokenStore
+
+	mu    sync.Mutex
+	token *oauth2.Token
+}
+
+func newPersistingTokenSource(ctx context.Context, config *oauth2.Config, store TokenStore, token *oauth2.Token) *persistingTokenSource {
+	return &persistingTokenSource{
+		ctx:    ctx,
+		config: config,
+		store:  store,
+		token:  token,
+	}
+}
+
+// Token returns a valid token, refreshing and saving it when expired
+func (s *persistingTokenSource) Token() (*oauth2.Token, error) {
+	s.mu.Lock()
+	defer s.mu.Unlock()
+
+	if s.token.Valid() {
+		countTokenLookup(true)
+		return s.token, nil
+	}
+
+	// Hold the lock across read-refresh-write so concurrent gml processes
+	// don't refresh the same token twice
+	unlock, err := s.store.Lock()
+	if err != nil {
+		return nil, fmt.Errorf("unable to lock token store: %v", err)
+	}
+	defer unlock()
+
+	// Another process may have refreshed the token while we were waiting
+	if token, err := s.store.Load(); err == nil && token.Valid() {
+		s.token = token
+		countTokenLookup(true)
+		return token, nil
+	}
+
+	token, err := s.config.TokenSource(s.ctx, s.token).Token()
+	if err != nil {
+		return nil, err
+	}
+	countTokenLookup(false)
+	slog.Debug("refreshed access token", "expiry", token.Expiry)
+
+	if token.AccessToken != s.token.AccessToken {
+		if err := s.store.Save(token); err != nil {
+			return nil, fmt.Errorf("unable to save refreshed token: %v", err)
+		}
+	}
+
+	s.token = token
+	return token, nil
+}
+
+// readTokenFile reads an OAuth token from a JSON file
+func readTokenFile(path string) (*oauth2.Token, error) {
+	f, err := os.Open(path)
+	if err != nil {
+		return nil, err
+	}
+	defer f.Close()
+
+	token := &oauth2.Token{}
+	err = json.NewDecoder(f).Decode(token)
+	return token, err
+}
+
+// writeTokenFile atomically writes an OAuth token as JSON readable only by the owner
+func writeTokenFile(path string, token *oauth2.Token) error {
+	if err := os.MkdirAll(filepath.Dir(path), 0700); err != nil {
+		return err
+	}
+	tmp, err := os.CreateTemp(filepath.Dir(path), filepath.Base(path)+".tmp*")
+	if err != nil {
+		return err
+	}
+	defer os.Remove(tmp.Name())
+
+	if err := json.NewEncoder(tmp).Encode(token); err != nil {
+		tmp.Close()
+		return err
+	}
+	if err := tmp.Chmod(0600); err != nil {
+		tmp.Close()
+		return err
+	}
+	if err := tmp.Close(); err != nil {
+		return err
+	}
+	return os.Rename(tmp.Name(), path)
+}
@@ -0,0 +1,118 @@
+package google
+
+import (
+	"context"
+	"encoding/json"
+	"fmt"
+	"net/http"
+	"net/url"
+	"strconv"
+	"strings"
+	"time"
+)
+
+const (
+	tokenInfoURL = "https://oauth2.googleapis.com/tokeninfo"
+	revokeURL    = "https://oauth2.googleapis.com/revoke"
+)
+
+// TokenInfo describes the stored OAuth token as reported by Google
+type TokenInfo struct {
+	Storage         string
+	Scopes          []string
+	Expiry          time.Time
+	HasRefreshToken bool
+}
+
+// TokenInfo returns details about the stored token, refreshing it first if expired
+func (a *OAuthAuthenticator) TokenInfo(ctx context.Context) (*TokenInfo, error) {
+	config, err := a.oauthConfig()
+	if err != nil {
+		return nil, err
+	}
+
+	stored, err := a.tokenStore.Load()
+	if err != nil {
+		return nil, fmt.Errorf("token not found, please run 'gml auth' first: %v", err)
+	}
+
+	token, err := newPersistingTokenSource(ctx, config, a.tokenStore, stored).Token()
+	if err != nil {
+		return nil, fmt.Errorf("unable to refresh token: %v", err)
+	}
+
+	req, err := http.NewRequestWithContext(ctx, http.MethodGet,
+		tokenInfoURL+"?access_token="+url.QueryEscape(token.AccessToken), nil)
+	if err != nil {
+		return nil, err
+	}
+	resp, err := http.DefaultClient.Do(req)
+	if err != nil {
+		return nil, fmt.Errorf("unable to query token info: %v", err)
+	}
+	defer resp.Body.Close()
+
+	if resp.StatusCode != http.StatusOK {
+		return nil, fmt.Errorf("token info request failed: %s", resp.Status)
+	}
+
+	var body struct {
+		Scope     string `json:"scope"`
+		ExpiresIn string `json:"expires_in"`
+	}
+	if err := json.NewDecoder(resp.Body).Decode(&body); err != nil {
+		return nil, fmt.Errorf("unable to parse token info: %v", err)
+	}
+
+	info := &TokenInfo{
+		Storage:         a.tokenStore.String(),
+		Scopes:          strings.Fields(body.Scope),
+		Expiry:          token.Expiry,
+		HasRefreshToken: token.RefreshToken != "",
+	}
+	if secs, err := strconv.Atoi(body.ExpiresIn); err == nil {
+		info.Expiry = time.Now().Add(time.Duration(secs) * time.Second)
+	}
+	return info, nil
+}
+
+// Revoke revokes the stored token with Google and deletes it locally
+// Revoking the refresh token also invalidates its access tokens. Under
+// WithDryRun the request is reported, without the token, and nothing changes
+func (a *OAuthAuthenticator) Revoke(ctx context.Context) error {
+	token, err := a.tokenStore.Load()
+	if err != nil {
+		return fmt.Errorf("token not found: %v", err)
+	}
+	if ok, err := ReportDryRun(ctx, DryRunOperation{Method: http.MethodPost, Endpoint: "revoke", URL: revokeURL}); ok {
+		return err
+	}
+
+	value := token.RefreshToken
+	if value == "" {
+		value = token.AccessToken
+	}
+
+	req, err := http.NewRequestWithContext(ctx, http.MethodPost, revokeURL,
+		strings.NewReader(url.Values{"token": {value}}.Encode()))
+	if err != nil {
+		return err
+	}
+	req.Header.Set("Content-Type", "application/x-www-form-urlencoded")
+
+	resp, err := http.DefaultClient.Do(req)
+	if err != nil {
+		return fmt.Errorf("unable to revoke token: %v", err)
+	}
+	defer resp.Body.Close()
+
+	// 400 means the token is already invalid, which is fine to clean up locally
+	if resp.StatusCode != http.StatusOK && resp.StatusCode != http.StatusBadRequest {
+		return fmt.Errorf("token revocation failed: %s", resp.Status)
+	}
+
+	if err := a.tokenStore.Delete(); err != nil {
+		return fmt.Errorf("token revoked but unable to delete local copy: %v", err)
+	}
+	return nil
+}
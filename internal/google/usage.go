@@ -0,0 +1,182 @@
+package google
+
+import (
+	"log/slog"
+	"net/http"
+	"strings"
+	"sync"
+	"time"
+)
+
+// Usage counts the API requests and token cache lookups made by this process
+type Usage struct {
+	// Requests counts requests by method and endpoint, e.g. "GET messages.get"
+	Requests map[string]int
+	// Errors counts requests that failed or returned a non-2xx status
+	Errors int
+	// TokenHits counts access token lookups served without a refresh
+	TokenHits int
+	// TokenRefreshes counts access token refreshes against the OAuth server
+	TokenRefreshes int
+}
+
+var (
+	usageMu sync.Mutex
+	usage   = Usage{Requests: make(map[string]int)}
+)
+
+// TakeUsage returns the usage counted since the last call and resets it
+func TakeUsage() Usage {
+	usageMu.Lock()
+	defer usageMu.Unlock()
+	u := usage
+	usage = Usage{Requests: make(map[string]int)}
+	return u
+}
+
+// countTokenLookup records whether an access token came from the cache
+func countTokenLookup(hit bool) {
+	usageMu.Lock()
+	defer usageMu.Unlock()
+	if hit {
+		usage.TokenHits++
+	} else {
+		usage.TokenRefreshes++
+	}
+}
+
+// apiTransport counts requests by endpoint and, at debug level, logs each
+// one with its latency and estimated quota cost. Under WithDryRun, requests
+// that change state are reported instead of sent; under WithRequestBudget,
+// requests beyond the budget fail
+type apiTransport struct {
+	base http.RoundTripper
+}
+
+func (t apiTransport) RoundTrip(r *http.Request) (*http.Response, error) {
+	if resp, ok, err := interceptDryRun(r); ok {
+		return resp, err
+	}
+	if err := takeBudget(r); err != nil {
+		return nil, err
+	}
+
+	base := t.base
+	if base == nil {
+		base = http.DefaultTransport
+	}
+	start := time.Now()
+	resp, err := base.RoundTrip(r)
+	latency := time.Since(start)
+
+	endpoint := endpointName(r.URL.Path)
+	failed := err != nil || resp.StatusCode >= 300
+	if slog.Default().Enabled(r.Context(), slog.LevelDebug) {
+		status := 0
+		if resp != nil {
+			status = resp.StatusCode
+		}
+		args := []any{"method", r.Method, "url", r.URL.String(), "status", status,
+			"latency", latency.Round(time.Millisecond), "quota", quotaCost(r.Method, endpoint)}
+		if err != nil {
+			args = append(args, "err", err)
+		}
+		slog.Debug("api call", args...)
+	}
+
+	usageMu.Lock()
+	defer usageMu.Unlock()
+	usage.Requests[r.Method+" "+endpoint]++
+	if failed {
+		usage.Errors++
+	}
+	return resp, err
+}
+
+// endpointName turns a Gmail API path into a stable name without IDs, e.g.
+// /gmail/v1/users/me/messages/18abc/modify becomes messages/{id}/modify.
+// People API paths keep their method, e.g. people:searchContacts
+func endpointName(path string) string {
+	if strings.HasPrefix(path, "/batch/") {
+		return "batch"
+	}
+	if rest, ok := strings.CutPrefix(path, "/v1/"); ok {
+		return rest
+	}
+	segments := strings.Split(strings.Trim(path, "/"), "/")
+	// Drop the [upload/]gmail/v1/users/{userId} prefix
+	for i, s := range segments {
+		if s == "users" {
+			segments = segments[min(i+2, len(segments)):]
+			break
+		}
+	}
+	if len(segments) == 0 {
+		return "profile"
+	}
+
+	for i := 1; i < len(segments); i++ {
+		if collections[segments[i-1]] && !actions[segments[i]] {
+			segments[i] = "{id}"
+		}
+	}
+	return strings.Join(segments, "/")
+}
+
+// collections are path segments followed by a resource ID
+var collections = map[string]bool{
+	"messages": true, "threads": true, "labels": true, "drafts": true, "attachments": true,
+	"filters": true, "forwardingAddresses": true, "sendAs": true, "delegates": true, "smimeInfo": true,
+}
+
+// actions are path segments that name a method on a collection, not an ID
+var actions = map[string]bool{
+	"send": true, "import": true, "batchModify": true, "batchDelete": true,
+}
+
+// quotaCost estimates the Gmail API quota units of a request, per the
+// published per-method costs; unknown methods count as 5
+func quotaCost(method, endpoint string) int {
+	switch endpoint {
+	case "batch":
+		// Depends on the calls inside the batch
+		return 0
+	case "people:searchContacts", "otherContacts:search":
+		// People API requests don't use the Gmail quota
+		return 0
+	case "profile", "labels", "labels/{id}":
+		if method == http.MethodGet {
+			return 1
+		}
+	case "history":
+		return 2
+	case "messages/send", "drafts/send":
+		return 100
+	case "watch":
+		return 100
+	case "stop", "messages/batchModify", "messages/batchDelete":
+		return 50
+	case "messages/import", "messages":
+		if method == http.MethodPost {
+			return 25
+		}
+	case "threads", "threads/{id}", "threads/{id}/modify", "threads/{id}/trash", "threads/{id}/untrash":
+		if method == http.MethodDelete {
+			return 20
+		}
+		return 10
+	case "drafts/{id}", "drafts":
+		if method == http.MethodGet {
+			return 5
+		}
+		return 10
+	case "messages/{id}":
+		if method == http.MethodDelete {
+			return 10
+		}
+	}
+	if strings.HasPrefix(endpoint, "settings") && method == http.MethodGet {
+		return 1
+	}
+	return 5
+}
@@ -0,0 +1,118 @@
+// Package telemetry provides optional OpenTelemetry tracing configured through
+// the standard OTEL_* environment variables
+package telemetry
+
+import (
+	"context"
+	"fmt"
+	"net/http"
+	"os"
+	"strings"
+
+	"go.opentelemetry.io/contrib/instrumentation/net/http/otelhttp"
+	"go.opentelemetry.io/otel"
+	"go.opentelemetry.io/otel/attribute"
+	"go.opentelemetry.io/otel/codes"
+	"go.opentelemetry.io/otel/exporters/otlp/otlptrace/otlptracegrpc"
+	"go.opentelemetry.io/otel/exporters/otlp/otlptrace/otlptracehttp"
+	"go.opentelemetry.io/otel/exporters/stdout/stdouttrace"
+	"go.opentelemetry.io/otel/propagation"
+	"go.opentelemetry.io/otel/sdk/resource"
+	sdktrace "go.opentelemetry.io/otel/sdk/trace"
+	semconv "go.opentelemetry.io/otel/semconv/v1.26.0"
+	"go.opentelemetry.io/otel/trace"
+)
+
+// instrumentationName identifies gml's spans
+const instrumentationName = "github.com/longkey1/gml"
+
+// Setup installs a global tracer provider when tracing is configured:
+//
+//   - OTEL_TRACES_EXPORTER=otlp (implied by OTEL_EXPORTER_OTLP_[TRACES_]ENDPOINT)
+//     exports over OTLP, using OTEL_EXPORTER_OTLP_[TRACES_]PROTOCOL
+//     (http/protobuf by default, or grpc)
+//   - OTEL_TRACES_EXPORTER=console writes spans to stderr
+//   - unset, "none" or OTEL_SDK_DISABLED=true leaves tracing off
+//
+// The returned context carries the parent span from $TRACEPARENT (W3C trace
+// context), so gml joins the trace of the automation that runs it. The
+// shutdown function flushes pending spans and must be called before exit
+func Setup(ctx context.Context, version string) (context.Context, func(context.Context) error, error) {
+	noop := func(context.Context) error { return nil }
+
+	exporter := strings.ToLower(strings.TrimSpace(os.Getenv("OTEL_TRACES_EXPORTER")))
+	if exporter == "" && (os.Getenv("OTEL_EXPORTER_OTLP_ENDPOINT") != "" || os.Getenv("OTEL_EXPORTER_OTLP_TRACES_ENDPOINT") != "") {
+		exporter = "otlp"
+	}
+	if exporter == "" || exporter == "none" || strings.EqualFold(os.Getenv("OTEL_SDK_DISABLED"), "true") {
+		return ctx, noop, nil
+	}
+
+	var exp sdktrace.SpanExporter
+	var err error
+	switch exporter {
+	case "otlp":
+		protocol := os.Getenv("OTEL_EXPORTER_OTLP_TRACES_PROTOCOL")
+		if protocol == "" {
+			protocol = os.Getenv("OTEL_EXPORTER_OTLP_PROTOCOL")
+		}
+		switch protocol {
+		case "", "http/protobuf":
+			exp, err = otlptracehttp.New(ctx)
+		case "grpc":
+			exp, err = otlptracegrpc.New(ctx)
+		default:
+			return ctx, noop, fmt.Errorf("unsupported OTLP protocol: %s (use http/protobuf or grpc)", protocol)
+		}
+	case "console":
+		exp, err = stdouttrace.New(stdouttrace.WithWriter(os.Stderr), stdouttrace.WithPrettyPrint())
+	default:
+		return ctx, noop, fmt.Errorf("unsupported traces exporter: %s (use otlp, console or none)", exporter)
+	}
+	if err != nil {
+		return ctx, noop, fmt.Errorf("unable to create trace exporter: %w", err)
+	}
+
+	// OTEL_SERVICE_NAME and OTEL_RESOURCE_ATTRIBUTES override the defaults
+	res, err := resource.Merge(
+		resource.NewSchemaless(semconv.ServiceName("gml"), semconv.ServiceVersion(version)),
+		resource.Environment(),
+	)
+	if err != nil {
+		return ctx, noop, fmt.Errorf("unable to build trace resource: %w", err)
+	}
+
+	tp := sdktrace.NewTracerProvider(
+		sdktrace.WithBatcher(exp),
+		sdktrace.WithResource(res),
+	)
+	otel.SetTracerProvider(tp)
+	otel.SetTextMapPropagator(propagation.NewCompositeTextMapPropagator(propagation.TraceContext{}, propagation.Baggage{}))
+
+	ctx = otel.GetTextMapPropagator().Extract(ctx, propagation.MapCarrier{
+		"traceparent": os.Getenv("TRACEPARENT"),
+		"tracestate":  os.Getenv("TRACESTATE"),
+	})
+	return ctx, tp.Shutdown, nil
+}
+
+// Start starts a span; it is a no-op unless Setup enabled tracing
+func Start(ctx context.Context, name string, attrs ...attribute.KeyValue) (context.Context, trace.Span) {
+	return otel.Tracer(instrumentationName).Start(ctx, name, trace.WithAttributes(attrs...))
+}
+
+// End records err on the span, if any, and ends it
+func End(span trace.Span, err error) {
+	if err != nil {
+		span.RecordError(err)
+		span.SetStatus(codes.Error, err.Error())
+	}
+	span.End()
+}
+
+// Transport wraps an HTTP transport so each API request gets a client span
+func Transport(base http.RoundTripper) http.RoundTripper {
+	return otelhttp.NewTransport(base, otelhttp.WithSpanNameFormatter(func(_ string, r *http.Request) string {
+		return r.Method + " " + r.URL.Host
+	}))
+}
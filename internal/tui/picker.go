@@ -0,0 +1,245 @@
+package tui
+
+import (
+	"errors"
+	"fmt"
+	"os"
+	"slices"
+	"sort"
+	"strings"
+	"time"
+	"unicode"
+
+	"github.com/charmbracelet/bubbles/textinput"
+	tea "github.com/charmbracelet/bubbletea"
+	"github.com/longkey1/gml/internal/gml"
+)
+
+// ErrPickCanceled is returned by Pick when the user leaves without choosing
+var ErrPickCanceled = errors.New("selection canceled")
+
+// Pick shows messages in a fuzzy finder on the terminal and returns the IDs
+// of the chosen messages. It reads keys from the terminal and draws on stderr,
+// so stdout stays free for the result, e.g. $(gml list --pick)
+func Pick(messages []gml.MessageInfo) ([]string, error) {
+	input := textinput.New()
+	input.Prompt = "> "
+	input.Focus()
+
+	now := time.Now()
+	items := make([]pickItem, len(messages))
+	for i, m := range messages {
+		items[i] = pickItem{
+			id:   m.ID,
+			text: strings.Join(strings.Fields(gml.FormatMailDate(m.Date, gml.DateFormatRelative, now)+"  "+m.From+"  "+m.Subject), " "),
+		}
+	}
+
+	p := &picker{items: items, input: input, selected: make(map[string]bool)}
+	p.filter()
+
+	result, err := tea.NewProgram(p, tea.WithOutput(os.Stderr), tea.WithInputTTY()).Run()
+	if err != nil {
+		return nil, fmt.Errorf("unable to run picker: %w", err)
+	}
+	p = result.(*picker)
+	if p.canceled || len(p.chosen) == 0 {
+		return nil, ErrPickCanceled
+	}
+	return p.chosen, nil
+}
+
+// pickItem is one line of the picker
+type pickItem struct {
+	id   string
+	text string
+}
+
+// picker is the bubbletea model of the fuzzy finder
+type picker struct {
+	items    []pickItem
+	input    textinput.Model
+	matches  []int
+	cursor   int
+	offset   int
+	selected map[string]bool
+
+	chosen   []string
+	canceled bool
+
+	width, height int
+}
+
+// Init starts the cursor blinking
+func (p *picker) Init() tea.Cmd {
+	return textinput.Blink
+}
+
+// Update handles keys: typing filters, arrows move, tab marks several
+// messages, enter chooses
+func (p *picker) Update(msg tea.Msg) (tea.Model, tea.Cmd) {
+	switch msg := msg.(type) {
+	case tea.WindowSizeMsg:
+		p.width, p.height = msg.Width, msg.Height
+		return p, nil
+
+	case tea.KeyMsg:
+		switch msg.String() {
+		case "ctrl+c", "esc":
+			p.canceled = true
+			return p, tea.Quit
+		case "enter":
+			for _, item := range p.items {
+				if p.selected[item.id] {
+					p.chosen = append(p.chosen, item.id)
+				}
+			}
+			if len(p.chosen) == 0 && len(p.matches) > 0 {
+				p.chosen = []string{p.items[p.matches[p.cursor]].id}
+			}
+			return p, tea.Quit
+		case "up", "ctrl+p", "ctrl+k":
+			p.move(-1)
+			return p, nil
+		case "down", "ctrl+n", "ctrl+j":
+			p.move(1)
+			return p, nil
+		case "tab":
+			if len(p.matches) > 0 {
+				id := p.items[p.matches[p.cursor]].id
+				p.selected[id] = !p.selected[id]
+				p.move(1)
+			}
+			return p, nil
+		}
+	}
+
+	prev := p.input.Value()
+	var cmd tea.Cmd
+	p.input, cmd = p.input.Update(msg)
+	if p.input.Value() != prev {
+		p.filter()
+	}
+	return p, cmd
+}
+
+// move moves the cursor, keeping it on screen
+func (p *picker) move(delta int) {
+	if len(p.matches) == 0 {
+		return
+	}
+	p.cursor = min(max(p.cursor+delta, 0), len(p.matches)-1)
+	rows := p.rows()
+	if p.cursor < p.offset {
+		p.offset = p.cursor
+	} else if p.cursor >= p.offset+rows {
+		p.offset = p.cursor - rows + 1
+	}
+}
+
+// rows returns the number of list rows that fit above the prompt
+func (p *picker) rows() int {
+	return max(1, p.height-2)
+}
+
+// filter ranks the items against the query, best match first
+func (p *picker) filter() {
+	query := p.input.Value()
+	type scored struct{ index, score int }
+	var hits []scored
+	for i, item := range p.items {
+		if score, ok := fuzzyScore(item.text, query); ok {
+			hits = append(hits, scored{i, score})
+		}
+	}
+	sort.SliceStable(hits, func(a, b int) bool { return hits[a].score > hits[b].score })
+
+	p.matches = p.matches[:0]
+	for _, h := range hits {
+		p.matches = append(p.matches, h.index)
+	}
+	p.cursor, p.offset = 0, 0
+}
+
+// View draws the matches above the prompt, like fzf
+func (p *picker) View() string {
+	if p.width == 0 {
+		return ""
+	}
+
+	rows := p.rows()
+	lines := make([]string, 0, rows)
+	for i := p.offset; i < len(p.matches) && len(lines) < rows; i++ {
+		item := p.items[p.matches[i]]
+		mark := "  "
+		if p.selected[item.id] {
+			mark = " *"
+		}
+		line := fit(mark+" "+item.text, p.width)
+		if i == p.cursor {
+			line = selectedStyle.Render(line)
+		}
+		lines = append(lines, line)
+	}
+	// Draw from the bottom up so the best match sits next to the prompt
+	slices.Reverse(lines)
+	for len(lines) < rows {
+		lines = append([]string{""}, lines...)
+	}
+
+	count := fmt.Sprintf("  %d/%d", len(p.matches), len(p.items))
+	if n := countTrue(p.selected); n > 0 {
+		count += fmt.Sprintf(" (%d selected)", n)
+	}
+	lines = append(lines, faintStyle.Render(fit(count, p.width)), p.input.View())
+	return strings.Join(lines, "\n")
+}
+
+// countTrue counts the marked messages
+func countTrue(m map[string]bool) int {
+	n := 0
+	for _, v := range m {
+		if v {
+			n++
+		}
+	}
+	return n
+}
+
+// fuzzyScore matches every space-separated term of query as a case-insensitive
+// subsequence of text. Consecutive characters and matches at word starts score
+// higher, gaps lower
+func fuzzyScore(text, query string) (int, bool) {
+	runes := []rune(strings.ToLower(text))
+	total := 0
+	for _, term := range strings.Fields(strings.ToLower(query)) {
+		score, pos, prev := 0, 0, -2
+		for _, r := range term {
+			found := false
+			for ; pos < len(runes); pos++ {
+				if runes[pos] != r {
+					continue
+				}
+				score++
+				if pos == prev+1 {
+					score += 4
+				}
+				if pos == 0 || !unicode.IsLetter(runes[pos-1]) && !unicode.IsDigit(runes[pos-1]) {
+					score += 3
+				}
+				if prev >= 0 {
+					score -= min(pos-prev-1, 3)
+				}
+				prev = pos
+				pos++
+				found = true
+				break
+			}
+			if !found {
+				return 0, false
+			}
+		}
+		total += score
+	}
+	return total, true
+}
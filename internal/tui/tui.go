@@ -0,0 +1,748 @@
+// Package tui implements the interactive terminal mail reader (gml tui)
+package tui
+
+import (
+	"context"
+	"fmt"
+	"slices"
+	"strings"
+	"time"
+
+	"github.com/charmbracelet/bubbles/textinput"
+	tea "github.com/charmbracelet/bubbletea"
+	"github.com/charmbracelet/lipgloss"
+	"github.com/longkey1/gml/internal/gml"
+	"github.com/mattn/go-runewidth"
+)
+
+// DefaultPageSize is the number of messages loaded per label or search
+const DefaultPageSize = 50
+
+// Options configures the TUI
+type Options struct {
+	// Label is the label opened at startup (default INBOX)
+	Label string
+	// Query is an initial Gmail search
+	Query string
+	// PageSize is the number of messages loaded at once (0 for the default)
+	PageSize int64
+	// Renderers give messages from matching senders a compact preview
+	Renderers []gml.RendererConfig
+}
+
+// sidebarLabels are the system labels shown above user labels, in this order
+var sidebarLabels = []string{"INBOX", "STARRED", "IMPORTANT", "SENT", "DRAFT", "SPAM", "TRASH"}
+
+// listFields are the fields fetched for the message list
+var listFields = gml.ParseFields("id,threadid,from,to,subject,date,labels,snippet")
+
+// previewFields are the fields fetched for the preview; renderers see them all
+var previewFields = gml.ParseFields("id,threadid,from,to,subject,date,body")
+
+// Run starts the TUI and blocks until the user quits
+func Run(ctx context.Context, svc *gml.Service, opts Options) error {
+	idx, err := gml.FetchLabelIndex(ctx, svc)
+	if err != nil {
+		return err
+	}
+	if opts.PageSize <= 0 {
+		opts.PageSize = DefaultPageSize
+	}
+
+	m := newModel(ctx, svc, idx, opts)
+	if opts.Label != "" {
+		ids, err := idx.ResolveLabelIDs([]string{opts.Label})
+		if err != nil {
+			return err
+		}
+		m.selectLabel(ids[0])
+	}
+
+	_, err = tea.NewProgram(m, tea.WithAltScreen(), tea.WithContext(ctx)).Run()
+	if err != nil && ctx.Err() == nil {
+		return fmt.Errorf("unable to run terminal UI: %w", err)
+	}
+	return nil
+}
+
+// focus identifies the pane receiving navigation keys
+type focus int
+
+const (
+	focusList focus = iota
+	focusSidebar
+	focusPreview
+)
+
+// model is the bubbletea model of the TUI
+type model struct {
+	ctx       context.Context
+	svc       *gml.Service
+	idx       *gml.LabelIndex
+	pageSize  int64
+	renderers []gml.RendererConfig
+
+	// Sidebar; an empty ID means all mail
+	labels      []gml.LabelInfo
+	labelCursor int
+	labelID     string
+	query       string
+
+	// Message list; visible holds indexes into messages matching the filter
+	messages []gml.MessageInfo
+	visible  []int
+	cursor   int
+	offset   int
+
+	// Preview bodies by message ID, and the preview scroll position
+	bodies        map[string]string
+	previewOffset int
+
+	focus     focus
+	search    textinput.Model
+	searching bool
+	loading   bool
+	status    string
+
+	width, height int
+}
+
+// messagesLoadedMsg carries the result of loading a label or search
+type messagesLoadedMsg struct {
+	messages []gml.MessageInfo
+	err      error
+}
+
+// bodyLoadedMsg carries a message body for the preview pane
+type bodyLoadedMsg struct {
+	id   string
+	body string
+	err  error
+}
+
+// modifiedMsg reports the result of an action on a message
+type modifiedMsg struct {
+	id     string
+	action string
+	add    []string
+	remove []string
+	err    error
+}
+
+func newModel(ctx context.Context, svc *gml.Service, idx *gml.LabelIndex, opts Options) *model {
+	search := textinput.New()
+	search.Prompt = "/"
+	search.Placeholder = "filter, enter to search Gmail"
+
+	m := &model{
+		ctx:       ctx,
+		svc:       svc,
+		idx:       idx,
+		pageSize:  opts.PageSize,
+		renderers: opts.Renderers,
+		query:     opts.Query,
+		labelID:   "INBOX",
+		bodies:    make(map[string]string),
+		search:    search,
+		loading:   true,
+	}
+	m.labels = sidebarEntries(idx)
+	m.selectLabel("INBOX")
+	return m
+}
+
+// sidebarEntries lists the system labels, visible user labels by name, and all mail
+func sidebarEntries(idx *gml.LabelIndex) []gml.LabelInfo {
+	var entries []gml.LabelInfo
+	for _, id := range sidebarLabels {
+		if l, ok := idx.Label(id); ok {
+			entries = append(entries, l)
+		}
+	}
+	for _, l := range idx.Labels() {
+		if l.Type == "user" && l.LabelListVisibility != "labelHide" {
+			entries = append(entries, l)
+		}
+	}
+	return append(entries, gml.LabelInfo{Name: "All Mail"})
+}
+
+// selectLabel moves the sidebar cursor to a label ID
+func (m *model) selectLabel(id string) {
+	m.labelID = id
+	for i, l := range m.labels {
+		if l.ID == id {
+			m.labelCursor = i
+		}
+	}
+}
+
+// Init loads the initial message list
+func (m *model) Init() tea.Cmd {
+	return m.loadMessages()
+}
+
+// loadMessages fetches the first page of the current label and query
+func (m *model) loadMessages() tea.Cmd {
+	ctx, svc, labelID, query, pageSize := m.ctx, m.svc, m.labelID, m.query, m.pageSize
+	idx := m.idx
+	return func() tea.Msg {
+		call := svc.Gmail.Users.Messages.List("me").MaxResults(pageSize).Context(ctx)
+		if labelID != "" {
+			call = call.LabelIds(labelID)
+		}
+		if query != "" {
+			call = call.Q(query)
+		}
+		resp, err := call.Do()
+		if err != nil {
+			return messagesLoadedMsg{err: fmt.Errorf("unable to retrieve messages: %w", err)}
+		}
+
+		messages := make([]gml.MessageInfo, 0, len(resp.Messages))
+		for _, msg := range resp.Messages {
+			info, err := gml.GetMessageInfo(ctx, svc, msg.Id, listFields, "", idx)
+			if err != nil {
+				// Messages may be deleted while we iterate
+				continue
+			}
+			messages = append(messages, info)
+		}
+		return messagesLoadedMsg{messages: messages}
+	}
+}
+
+// loadBody fetches the body of a message for the preview pane
+func (m *model) loadBody(id string) tea.Cmd {
+	if _, ok := m.bodies[id]; ok || id == "" {
+		return nil
+	}
+	ctx, svc, renderers := m.ctx, m.svc, m.renderers
+	return func() tea.Msg {
+		info, err := gml.GetMessageInfo(ctx, svc, id, previewFields, "", nil)
+		if err != nil {
+			return bodyLoadedMsg{id: id, err: err}
+		}
+		body, err := gml.RenderMessage(ctx, renderers, info)
+		return bodyLoadedMsg{id: id, body: body, err: err}
+	}
+}
+
+// modify applies an action (archive, trash, read, unread, star, unstar) to a message
+func (m *model) modify(msg gml.MessageInfo, action string) tea.Cmd {
+	ctx, svc := m.ctx, m.svc
+	var add, remove []string
+	switch action {
+	case "archive":
+		remove = []string{"INBOX"}
+	case "read":
+		remove = []string{"UNREAD"}
+	case "unread":
+		add = []string{"UNREAD"}
+	case "star":
+		add = []string{"STARRED"}
+	case "unstar":
+		remove = []string{"STARRED"}
+	}
+	return func() tea.Msg {
+		var err error
+		if action == "trash" {
+			err = gml.TrashMessage(ctx, svc, msg.ID)
+		} else {
+			err = gml.ModifyMessage(ctx, svc, msg.ID, add, remove)
+		}
+		return modifiedMsg{id: msg.ID, action: action, add: add, remove: remove, err: err}
+	}
+}
+
+// Update handles input and async results
+func (m *model) Update(msg tea.Msg) (tea.Model, tea.Cmd) {
+	switch msg := msg.(type) {
+	case tea.WindowSizeMsg:
+		m.width, m.height = msg.Width, msg.Height
+		m.search.Width = max(10, msg.Width-2)
+		return m, nil
+
+	case messagesLoadedMsg:
+		m.loading = false
+		if msg.err != nil {
+			m.status = msg.err.Error()
+			return m, nil
+		}
+		m.messages = msg.messages
+		m.cursor, m.offset, m.previewOffset = 0, 0, 0
+		m.applyFilter()
+		m.status = fmt.Sprintf("%d messages", len(m.messages))
+		return m, m.loadBody(m.currentID())
+
+	case bodyLoadedMsg:
+		if msg.err != nil {
+			m.status = msg.err.Error()
+			return m, nil
+		}
+		m.bodies[msg.id] = msg.body
+		return m, nil
+
+	case modifiedMsg:
+		if msg.err != nil {
+			m.status = msg.err.Error()
+			return m, nil
+		}
+		m.applyModification(msg)
+		return m, m.loadBody(m.currentID())
+
+	case tea.KeyMsg:
+		if m.searching {
+			return m.updateSearch(msg)
+		}
+		return m.updateKeys(msg)
+	}
+	return m, nil
+}
+
+// updateSearch handles keys while the search input is active: typing filters
+// the loaded messages, enter runs the text as a Gmail search
+func (m *model) updateSearch(msg tea.KeyMsg) (tea.Model, tea.Cmd) {
+	switch msg.Type {
+	case tea.KeyEsc:
+		m.searching = false
+		m.search.Blur()
+		m.search.SetValue("")
+		m.applyFilter()
+		return m, nil
+	case tea.KeyEnter:
+		m.searching = false
+		m.search.Blur()
+		m.query = strings.TrimSpace(m.search.Value())
+		m.search.SetValue("")
+		m.loading = true
+		m.status = "Searching..."
+		return m, m.loadMessages()
+	}
+
+	var cmd tea.Cmd
+	m.search, cmd = m.search.Update(msg)
+	m.cursor, m.offset = 0, 0
+	m.applyFilter()
+	return m, tea.Batch(cmd, m.loadBody(m.currentID()))
+}
+
+// updateKeys handles navigation and actions
+func (m *model) updateKeys(msg tea.KeyMsg) (tea.Model, tea.Cmd) {
+	switch msg.String() {
+	case "q", "ctrl+c":
+		return m, tea.Quit
+	case "tab":
+		m.focus = (m.focus + 1) % 3
+		return m, nil
+	case "shift+tab":
+		m.focus = (m.focus + 2) % 3
+		return m, nil
+	case "/":
+		m.searching = true
+		m.focus = focusList
+		return m, m.search.Focus()
+	case "esc":
+		if m.query != "" {
+			m.query = ""
+			m.loading = true
+			return m, m.loadMessages()
+		}
+		return m, nil
+	case "r":
+		m.loading = true
+		m.status = "Refreshing..."
+		return m, m.loadMessages()
+	}
+
+	switch m.focus {
+	case focusSidebar:
+		return m.updateSidebar(msg)
+	case focusPreview:
+		return m.updatePreview(msg)
+	}
+	return m.updateList(msg)
+}
+
+// updateSidebar moves through labels; enter opens the selected label
+func (m *model) updateSidebar(msg tea.KeyMsg) (tea.Model, tea.Cmd) {
+	switch msg.String() {
+	case "j", "down":
+		m.labelCursor = min(m.labelCursor+1, len(m.labels)-1)
+	case "k", "up":
+		m.labelCursor = max(m.labelCursor-1, 0)
+	case "enter", "l", "right":
+		m.labelID = m.labels[m.labelCursor].ID
+		m.query = ""
+		m.focus = focusList
+		m.loading = true
+		m.status = "Loading..."
+		return m, m.loadMessages()
+	}
+	return m, nil
+}
+
+// updatePreview scrolls the preview pane
+func (m *model) updatePreview(msg tea.KeyMsg) (tea.Model, tea.Cmd) {
+	switch msg.String() {
+	case "j", "down":
+		m.previewOffset++
+	case "k", "up":
+		m.previewOffset = max(m.previewOffset-1, 0)
+	case " ", "pgdown", "ctrl+d":
+		m.previewOffset += max(1, m.previewHeight()-2)
+	case "pgup", "ctrl+u":
+		m.previewOffset = max(m.previewOffset-max(1, m.previewHeight()-2), 0)
+	case "h", "left":
+		m.focus = focusList
+	}
+	return m, nil
+}
+
+// updateList moves through messages and applies actions to the selected one
+func (m *model) updateList(msg tea.KeyMsg) (tea.Model, tea.Cmd) {
+	switch msg.String() {
+	case "j", "down":
+		m.moveCursor(1)
+	case "k", "up":
+		m.moveCursor(-1)
+	case "g", "home":
+		m.moveCursor(-len(m.visible))
+	case "G", "end":
+		m.moveCursor(len(m.visible))
+	case "pgdown", "ctrl+d":
+		m.moveCursor(m.listHeight())
+	case "pgup", "ctrl+u":
+		m.moveCursor(-m.listHeight())
+	case "enter", "l", "right":
+		m.focus = focusPreview
+		return m, m.loadBody(m.currentID())
+	case "h", "left":
+		m.focus = focusSidebar
+	case " ":
+		m.previewOffset += max(1, m.previewHeight()-2)
+	}
+
+	cur, ok := m.current()
+	if !ok {
+		return m, nil
+	}
+	switch msg.String() {
+	case "e":
+		return m, m.modify(cur, "archive")
+	case "#", "d":
+		return m, m.modify(cur, "trash")
+	case "u":
+		if slices.Contains(cur.Labels, "UNREAD") {
+			return m, m.modify(cur, "read")
+		}
+		return m, m.modify(cur, "unread")
+	case "s":
+		if slices.Contains(cur.Labels, "STARRED") {
+			return m, m.modify(cur, "unstar")
+		}
+		return m, m.modify(cur, "star")
+	}
+	return m, m.loadBody(cur.ID)
+}
+
+// moveCursor moves the list cursor, keeping it on screen
+func (m *model) moveCursor(delta int) {
+	if len(m.visible) == 0 {
+		return
+	}
+	prev := m.cursor
+	m.cursor = min(max(m.cursor+delta, 0), len(m.visible)-1)
+	if m.cursor != prev {
+		m.previewOffset = 0
+	}
+	height := m.listHeight()
+	if m.cursor < m.offset {
+		m.offset = m.cursor
+	} else if m.cursor >= m.offset+height {
+		m.offset = m.cursor - height + 1
+	}
+}
+
+// applyFilter recomputes the visible messages from the search input
+func (m *model) applyFilter() {
+	filter := strings.ToLower(strings.TrimSpace(m.search.Value()))
+	m.visible = m.visible[:0]
+	for i, msg := range m.messages {
+		if filter == "" || strings.Contains(strings.ToLower(msg.From+"\x00"+msg.Subject+"\x00"+msg.Snippet), filter) {
+			m.visible = append(m.visible, i)
+		}
+	}
+	m.cursor = min(m.cursor, max(len(m.visible)-1, 0))
+}
+
+// applyModification updates the local copy of a message after an action
+func (m *model) applyModification(msg modifiedMsg) {
+	i := slices.IndexFunc(m.messages, func(info gml.MessageInfo) bool { return info.ID == msg.id })
+	if i < 0 {
+		return
+	}
+
+	// Drop messages that no longer belong to the open label
+	if msg.action == "trash" || (msg.action == "archive" && m.labelID == "INBOX") ||
+		(msg.action == "unstar" && m.labelID == "STARRED") {
+		m.messages = slices.Delete(m.messages, i, i+1)
+		m.applyFilter()
+		m.moveCursor(0)
+		m.status = fmt.Sprintf("Message %s: %s", msg.id, actionPastTense[msg.action])
+		return
+	}
+
+	info := &m.messages[i]
+	info.Labels = slices.DeleteFunc(info.Labels, func(l string) bool { return slices.Contains(msg.remove, l) })
+	for _, l := range msg.add {
+		if !slices.Contains(info.Labels, l) {
+			info.Labels = append(info.Labels, l)
+		}
+	}
+	m.status = fmt.Sprintf("Message %s: %s", msg.id, actionPastTense[msg.action])
+}
+
+// actionPastTense describes completed actions in the status line
+var actionPastTense = map[string]string{
+	"archive": "archived",
+	"trash":   "moved to trash",
+	"read":    "marked as read",
+	"unread":  "marked as unread",
+	"star":    "starred",
+	"unstar":  "unstarred",
+}
+
+// current returns the message under the cursor
+func (m *model) current() (gml.MessageInfo, bool) {
+	if m.cursor < 0 || m.cursor >= len(m.visible) {
+		return gml.MessageInfo{}, false
+	}
+	return m.messages[m.visible[m.cursor]], true
+}
+
+// currentID returns the ID of the message under the cursor, or ""
+func (m *model) currentID() string {
+	cur, _ := m.current()
+	return cur.ID
+}
+
+var (
+	titleStyle    = lipgloss.NewStyle().Bold(true).Reverse(true)
+	selectedStyle = lipgloss.NewStyle().Reverse(true)
+	unreadStyle   = lipgloss.NewStyle().Bold(true)
+	faintStyle    = lipgloss.NewStyle().Faint(true)
+	headerStyle   = lipgloss.NewStyle().Bold(true)
+)
+
+// sidebarWidth returns the width of the label sidebar
+func (m *model) sidebarWidth() int {
+	return min(24, max(12, m.width/5))
+}
+
+// listHeight returns the number of message rows shown
+func (m *model) listHeight() int {
+	// Title and status lines take two rows; the list gets 40% of the rest
+	return max(3, (m.height-2)*2/5)
+}
+
+// previewHeight returns the number of preview rows shown, including the separator
+func (m *model) previewHeight() int {
+	return max(1, m.height-2-m.listHeight())
+}
+
+// View renders the screen
+func (m *model) View() string {
+	if m.width == 0 {
+		return "Loading..."
+	}
+
+	sideWidth := m.sidebarWidth()
+	mainWidth := max(10, m.width-sideWidth-1)
+	bodyHeight := m.height - 2
+
+	side := m.renderSidebar(sideWidth, bodyHeight)
+	main := append(m.renderList(mainWidth), m.renderPreview(mainWidth, m.previewHeight())...)
+
+	var b strings.Builder
+	b.WriteString(titleStyle.Render(fit(m.title(), m.width)))
+	b.WriteString("\n")
+	for i := range bodyHeight {
+		b.WriteString(lineAt(side, i, sideWidth))
+		b.WriteString(faintStyle.Render("│"))
+		b.WriteString(lineAt(main, i, mainWidth))
+		b.WriteString("\n")
+	}
+	if m.searching {
+		b.WriteString(m.search.View())
+	} else {
+		b.WriteString(faintStyle.Render(fit(m.statusLine(), m.width)))
+	}
+	return b.String()
+}
+
+// title describes the open label and search
+func (m *model) title() string {
+	name := "All Mail"
+	if l, ok := m.idx.Label(m.labelID); ok {
+		name = l.Name
+	}
+	t := " gml  " + name
+	if m.query != "" {
+		t += "  search: " + m.query
+	}
+	if m.loading {
+		t += "  (loading)"
+	}
+	return t
+}
+
+// statusLine shows the last status message and key help
+func (m *model) statusLine() string {
+	help := "j/k move  tab pane  enter open  e archive  # trash  u read  s star  / search  r refresh  q quit"
+	if m.status == "" {
+		return help
+	}
+	return m.status + "  |  " + help
+}
+
+// renderSidebar renders the label list
+func (m *model) renderSidebar(width, height int) []string {
+	lines := make([]string, 0, len(m.labels))
+	start := max(0, m.labelCursor-height+1)
+	for i := start; i < len(m.labels) && len(lines) < height; i++ {
+		l := m.labels[i]
+		marker := "  "
+		if l.ID == m.labelID {
+			marker = "▸ "
+		}
+		line := fit(marker+l.Name, width)
+		if i == m.labelCursor && m.focus == focusSidebar {
+			line = selectedStyle.Render(line)
+		}
+		lines = append(lines, line)
+	}
+	return lines
+}
+
+// renderList renders the visible slice of the message list
+func (m *model) renderList(width int) []string {
+	height := m.listHeight()
+	lines := make([]string, 0, height)
+	if len(m.visible) == 0 && !m.loading {
+		lines = append(lines, fit("  No messages found.", width))
+	}
+
+	now := time.Now()
+	dateWidth := 10
+	fromWidth := min(24, max(10, width/4))
+	for i := m.offset; i < len(m.visible) && len(lines) < height; i++ {
+		msg := m.messages[m.visible[i]]
+		marker := " "
+		if i == m.cursor && m.focus != focusList {
+			marker = "›"
+		}
+		flag := " "
+		if slices.Contains(msg.Labels, "STARRED") {
+			flag = "*"
+		}
+		date := gml.FormatMailDate(msg.Date, gml.DateFormatRelative, now)
+		subjectWidth := max(1, width-5-dateWidth-fromWidth)
+		line := marker + flag + " " + fit(date, dateWidth) + " " + fit(msg.From, fromWidth) + " " + fit(msg.Subject, subjectWidth)
+
+		switch {
+		case i == m.cursor && m.focus == focusList:
+			line = selectedStyle.Render(line)
+		case slices.Contains(msg.Labels, "UNREAD"):
+			line = unreadStyle.Render(line)
+		}
+		lines = append(lines, line)
+	}
+	for len(lines) < height {
+		lines = append(lines, "")
+	}
+	return lines
+}
+
+// renderPreview renders the headers and wrapped body of the selected message
+func (m *model) renderPreview(width, height int) []string {
+	lines := []string{faintStyle.Render(strings.Repeat("─", width))}
+	cur, ok := m.current()
+	if !ok {
+		return lines
+	}
+
+	var content []string
+	content = append(content,
+		headerStyle.Render("From: ")+fit(cur.From, width-6),
+		headerStyle.Render("To: ")+fit(cur.To, width-4),
+		headerStyle.Render("Date: ")+fit(cur.Date, width-6),
+		headerStyle.Render("Subject: ")+fit(cur.Subject, width-9),
+		headerStyle.Render("Labels: ")+fit(strings.Join(cur.Labels, ", "), width-8),
+		"",
+	)
+	body, loaded := m.bodies[cur.ID]
+	if !loaded {
+		body = "Loading..."
+	}
+	content = append(content, wrap(body, width)...)
+
+	offset := min(m.previewOffset, max(0, len(content)-1))
+	for _, line := range content[offset:] {
+		if len(lines) >= height {
+			break
+		}
+		lines = append(lines, line)
+	}
+	return lines
+}
+
+// lineAt returns line i padded to width, or blank padding past the end
+func lineAt(lines []string, i, width int) string {
+	if i >= len(lines) {
+		return strings.Repeat(" ", width)
+	}
+	if pad := width - lipgloss.Width(lines[i]); pad > 0 {
+		return lines[i] + strings.Repeat(" ", pad)
+	}
+	return lines[i]
+}
+
+// fit truncates or pads s to exactly width terminal cells
+func fit(s string, width int) string {
+	if width <= 0 {
+		return ""
+	}
+	s = strings.Map(func(r rune) rune {
+		if r == '\n' || r == '\r' || r == '\t' {
+			return ' '
+		}
+		return r
+	}, s)
+	return runewidth.FillRight(runewidth.Truncate(s, width, "…"), width)
+}
+
+// wrap hard-wraps text to width terminal cells
+func wrap(text string, width int) []string {
+	var lines []string
+	for _, line := range strings.Split(strings.ReplaceAll(text, "\r\n", "\n"), "\n") {
+		line = strings.ReplaceAll(line, "\t", "    ")
+		// Drop control characters so bodies can't emit terminal escapes
+		line = strings.Map(func(r rune) rune {
+			if r < 0x20 || r == 0x7f {
+				return -1
+			}
+			return r
+		}, line)
+		for runewidth.StringWidth(line) > width {
+			head := runewidth.Truncate(line, width, "")
+			if head == "" {
+				break
+			}
+			lines = append(lines, head)
+			line = line[len(head):]
+		}
+		lines = append(lines, line)
+	}
+	return lines
+}
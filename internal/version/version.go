@@ -13,6 +13,19 @@ var (
 	GoVersion = runtime.Version()
 )
 
+// BuildInfo holds the version information for machine-readable output
+type BuildInfo struct {
+	Version   string `json:"version"`
+	Commit    string `json:"commit"`
+	BuildTime string `json:"buildTime"`
+	GoVersion string `json:"goVersion"`
+}
+
+// Build returns the version information as a BuildInfo
+func Build() BuildInfo {
+	return BuildInfo{Version: Version, Commit: CommitSHA, BuildTime: BuildTime, GoVersion: GoVersion}
+}
+
 // Info returns version information as a string
 func Info() string {
 	return fmt.Sprintf("Version: %s\nCommit: %s\nBuild Time: %s\nGo Version: %s",
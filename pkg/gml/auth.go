@@ -0,0 +1,65 @@
+package gml
+
+import (
+	"context"
+	"fmt"
+	"io"
+
+	core "github.com/longkey1/gml/internal/gml"
+	"github.com/longkey1/gml/internal/google"
+)
+
+// AuthStatus describes the stored OAuth token of an account
+type AuthStatus = core.AuthStatus
+
+// oauthAuthenticator returns the OAuth authenticator of the options
+func oauthAuthenticator(opts Options) (*google.OAuthAuthenticator, error) {
+	cfg, err := opts.config()
+	if err != nil {
+		return nil, err
+	}
+	if cfg.AuthType != core.AuthTypeOAuth {
+		return nil, fmt.Errorf("only available for OAuth authentication (current: %s)", cfg.AuthType)
+	}
+	scopes, err := cfg.OAuthScopes()
+	if err != nil {
+		return nil, err
+	}
+	store, err := cfg.NewTokenStore()
+	if err != nil {
+		return nil, err
+	}
+	return google.NewOAuthAuthenticator(cfg.OAuthClientCredentials(), store, scopes...), nil
+}
+
+// Authenticate authorizes the account the options select in the browser,
+// like 'gml auth', and saves the token for New
+func Authenticate(opts Options) error {
+	auth, err := oauthAuthenticator(opts)
+	if err != nil {
+		return err
+	}
+	return auth.Authenticate()
+}
+
+// AuthenticateManual is Authenticate for machines without a browser: the
+// authorization URL is written to out and the redirected URL is read from in,
+// like 'gml auth --no-browser'
+func AuthenticateManual(opts Options, in io.Reader, out io.Writer) error {
+	auth, err := oauthAuthenticator(opts)
+	if err != nil {
+		return err
+	}
+	return auth.AuthenticateManual(in, out)
+}
+
+// GetAuthStatus reports the validity, scopes, expiry and email of the stored
+// token of the account the options select. A token Gmail doesn't accept is
+// reported with Valid false and the reason in Error
+func GetAuthStatus(ctx context.Context, opts Options) (*AuthStatus, error) {
+	cfg, err := opts.config()
+	if err != nil {
+		return nil, err
+	}
+	return core.GetAuthStatus(ctx, cfg)
+}
@@ -0,0 +1,144 @@
+// Package gml is the Go API of gml: it reads and changes a Gmail mailbox
+// with the same configuration, credentials and logic as the gml command.
+//
+// A Client is created from Options, which default to the gml config file
+// and the token saved by 'gml auth':
+//
+//	client, err := gml.New(ctx, gml.Options{Account: "work"})
+//	if err != nil {
+//		return err
+//	}
+//	messages, err := client.ListMessages(ctx, gml.ListOptions{Query: "is:unread", MaxResults: 20})
+//
+// Errors can be tested for their kind with errors.Is, e.g. ErrNotFound.
+// Only what this package exports is kept stable between releases
+package gml
+
+import (
+	"context"
+	"fmt"
+	"sync"
+
+	core "github.com/longkey1/gml/internal/gml"
+)
+
+// Options selects the configuration and credentials of a Client
+type Options struct {
+	// ConfigFile is the gml config file to read; empty reads the default
+	// config.toml when it exists, like the gml command
+	ConfigFile string
+	// Account selects an account profile of the config file; empty uses
+	// default_account, or the top-level credentials
+	Account string
+	// Scopes overrides the OAuth scopes of the config, e.g. "modify"
+	Scopes []string
+	// CredentialsFile overrides the OAuth client credentials
+	// (application_credentials)
+	CredentialsFile string
+	// TokenFile overrides the file the OAuth token is stored in
+	// (user_credentials)
+	TokenFile string
+	// TokenStore, if set, keeps the OAuth token instead of the file or
+	// keyring of the config
+	TokenStore TokenStore
+	// MessageCache, if set, keeps message metadata for Stats and TopSenders
+	// instead of the state file of the gml command
+	MessageCache MessageCache
+}
+
+// config returns the gml configuration the options select
+func (o Options) config() (*core.Config, error) {
+	cfg, _, err := core.ReadConfig(o.ConfigFile)
+	if err != nil {
+		return nil, err
+	}
+	if cfg == nil {
+		cfg = core.DefaultConfig()
+	}
+
+	account := o.Account
+	if account == "" {
+		account = cfg.DefaultAccount
+	}
+	if account != "" {
+		if cfg, err = cfg.ForAccount(account); err != nil {
+			return nil, err
+		}
+	}
+
+	if len(o.Scopes) > 0 {
+		cfg.Scopes = o.Scopes
+	}
+	if o.CredentialsFile != "" {
+		if cfg.GoogleApplicationCredentials, err = core.ExpandPath(o.CredentialsFile); err != nil {
+			return nil, err
+		}
+	}
+	if o.TokenFile != "" {
+		if cfg.GoogleUserCredentials, err = core.ExpandPath(o.TokenFile); err != nil {
+			return nil, err
+		}
+		cfg.TokenStorage = core.TokenStorageFile
+	}
+	if o.TokenStore != nil {
+		cfg.Tokens = o.TokenStore
+	}
+	if err := cfg.Validate(); err != nil {
+		return nil, fmt.Errorf("invalid configuration: %w", err)
+	}
+	return cfg, nil
+}
+
+// Client accesses one Gmail account. It is safe for concurrent use
+type Client struct {
+	svc *core.Service
+
+	mu     sync.Mutex
+	labels *core.LabelIndex
+	cache  core.MessageCache
+	// fileCache is cache when it's the state file, which has to be saved
+	fileCache *core.MetadataCache
+}
+
+// New creates a Client for the account the options select. The account has
+// to be authorized first, with Authenticate or 'gml auth'
+func New(ctx context.Context, opts Options) (*Client, error) {
+	cfg, err := opts.config()
+	if err != nil {
+		return nil, err
+	}
+	svc, err := core.NewService(ctx, cfg)
+	if err != nil {
+		return nil, fmt.Errorf("unable to create service: %w", err)
+	}
+	c := &Client{svc: svc}
+	if opts.MessageCache != nil {
+		c.cache = coreMessageCache{opts.MessageCache}
+	}
+	return c, nil
+}
+
+// Account returns the name of the account profile, empty for the top-level
+// credentials
+func (c *Client) Account() string {
+	return c.svc.Account
+}
+
+// Email returns the email address of the account
+func (c *Client) Email(ctx context.Context) (string, error) {
+	return core.GetUserEmail(ctx, c.svc)
+}
+
+// labelIndex returns the labels of the account, fetched on first use
+func (c *Client) labelIndex(ctx context.Context) (*core.LabelIndex, error) {
+	c.mu.Lock()
+	defer c.mu.Unlock()
+	if c.labels == nil {
+		idx, err := core.FetchLabelIndex(ctx, c.svc)
+		if err != nil {
+			return nil, err
+		}
+		c.labels = idx
+	}
+	return c.labels, nil
+}
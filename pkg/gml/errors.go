@@ -0,0 +1,18 @@
+package gml
+
+import (
+	core "github.com/longkey1/gml/internal/gml"
+)
+
+// Kinds of errors returned by Client methods; test for them with errors.Is
+var (
+	// ErrNotFound means a message, label or other named item doesn't exist
+	ErrNotFound = core.ErrNotFound
+	// ErrAuthExpired means the stored token was revoked or expired and the
+	// account has to be authorized again
+	ErrAuthExpired = core.ErrAuthExpired
+	// ErrQuotaExceeded means a Gmail quota or rate limit was hit; retry later
+	ErrQuotaExceeded = core.ErrQuotaExceeded
+	// ErrScopeMissing means the token lacks the OAuth scope the request needs
+	ErrScopeMissing = core.ErrScopeMissing
+)
@@ -0,0 +1,32 @@
+package gml
+
+import (
+	"context"
+
+	core "github.com/longkey1/gml/internal/gml"
+)
+
+// Label is a system or user label of the account
+type Label = core.LabelInfo
+
+// Labels returns the labels of the account, fetched again on every call
+func (c *Client) Labels(ctx context.Context) ([]Label, error) {
+	idx, err := core.FetchLabelIndex(ctx, c.svc)
+	if err != nil {
+		return nil, err
+	}
+	c.mu.Lock()
+	c.labels = idx
+	c.mu.Unlock()
+	return idx.Labels(), nil
+}
+
+// ResolveLabels returns the IDs of labels given by name or ID; an unknown
+// label is ErrNotFound
+func (c *Client) ResolveLabels(ctx context.Context, labels []string) ([]string, error) {
+	idx, err := c.labelIndex(ctx)
+	if err != nil {
+		return nil, err
+	}
+	return idx.ResolveLabelIDs(labels)
+}
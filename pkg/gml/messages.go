@@ -0,0 +1,136 @@
+package gml
+
+import (
+	"context"
+
+	core "github.com/longkey1/gml/internal/gml"
+)
+
+// Message is a message of a listing, with the fields that were requested
+type Message = core.MessageInfo
+
+// MessageDetail is a single message with its body
+type MessageDetail = core.MessageDetail
+
+// BodyFormat is how message bodies are rendered or written
+type BodyFormat = core.BodyFormat
+
+// Body formats
+const (
+	BodyFormatText     = core.BodyFormatText
+	BodyFormatHTML     = core.BodyFormatHTML
+	BodyFormatMarkdown = core.BodyFormatMarkdown
+)
+
+// DefaultFields are the message fields listed when ListOptions.Fields is empty
+var DefaultFields = []string{"id", "from", "subject", "date", "labels", "snippet"}
+
+// ListOptions selects the messages ListMessages returns
+type ListOptions struct {
+	// Query is a Gmail search query, e.g. "is:unread from:bob@example.com"
+	Query string
+	// Labels limits the listing to messages with all these labels, by name
+	// or ID
+	Labels []string
+	// MaxResults caps the number of messages, newest first (0 for all)
+	MaxResults int
+	// Fields are the fields of Message to fill, e.g. "to" or "body" (default
+	// DefaultFields); "id" is always filled
+	Fields []string
+	// BodyFormat is how the body field is rendered (default text)
+	BodyFormat BodyFormat
+}
+
+// GetOptions controls how GetMessage returns a message
+type GetOptions struct {
+	// BodyFormat is how the body is rendered (default text)
+	BodyFormat BodyFormat
+	// AllHeaders fills MessageDetail.Headers with every header of the message
+	AllHeaders bool
+	// Headers fills MessageDetail.Headers with only these headers
+	Headers []string
+}
+
+// ListMessages returns the messages matching the options, newest first
+func (c *Client) ListMessages(ctx context.Context, opts ListOptions) ([]Message, error) {
+	names := opts.Fields
+	if len(names) == 0 {
+		names = DefaultFields
+	}
+	fields := map[string]bool{"id": true}
+	for _, name := range names {
+		fields[name] = true
+	}
+
+	listOpts := core.ListMessagesOptions{
+		Query:      opts.Query,
+		LabelIDs:   opts.Labels,
+		Fields:     fields,
+		BodyFormat: opts.BodyFormat,
+	}
+	// With a limit, a single page of that size is fetched
+	var first []Message
+	if opts.MaxResults > 0 {
+		listOpts.MaxResults = int64(opts.MaxResults)
+		listOpts.Pager = func(page []Message) (core.PageAction, error) {
+			first = page
+			return core.PageStop, nil
+		}
+	}
+	messages, err := core.ListMessages(ctx, c.svc, listOpts)
+	if err != nil {
+		return nil, err
+	}
+	return append(first, messages...), nil
+}
+
+// GetMessage returns a message by ID; a missing message is ErrNotFound
+func (c *Client) GetMessage(ctx context.Context, id string, opts GetOptions) (*MessageDetail, error) {
+	return core.GetMessage(ctx, c.svc, id, core.GetMessageOptions{
+		BodyFormat: opts.BodyFormat,
+		AllHeaders: opts.AllHeaders,
+		Headers:    opts.Headers,
+	})
+}
+
+// GetRawMessage returns a message in RFC 822 format
+func (c *Client) GetRawMessage(ctx context.Context, id string) ([]byte, error) {
+	raw, _, err := core.GetRawMessage(ctx, c.svc, id)
+	return raw, err
+}
+
+// ModifyLabels adds and removes labels of a message, by name or ID. Labels
+// to add are created if they don't exist
+func (c *Client) ModifyLabels(ctx context.Context, id string, add, remove []string) error {
+	idx, err := c.labelIndex(ctx)
+	if err != nil {
+		return err
+	}
+	addIDs, err := idx.EnsureLabelIDs(ctx, c.svc, add)
+	if err != nil {
+		return err
+	}
+	removeIDs, err := idx.ResolveLabelIDs(remove)
+	if err != nil {
+		return err
+	}
+	return core.ModifyMessage(ctx, c.svc, id, addIDs, removeIDs)
+}
+
+// Archive removes a message from the inbox
+func (c *Client) Archive(ctx context.Context, id string) error {
+	return core.ModifyMessage(ctx, c.svc, id, nil, []string{core.LabelInbox})
+}
+
+// MarkRead marks a message as read, or as unread when read is false
+func (c *Client) MarkRead(ctx context.Context, id string, read bool) error {
+	if read {
+		return core.ModifyMessage(ctx, c.svc, id, nil, []string{"UNREAD"})
+	}
+	return core.ModifyMessage(ctx, c.svc, id, []string{"UNREAD"}, nil)
+}
+
+// Trash moves a message to the trash
+func (c *Client) Trash(ctx context.Context, id string) error {
+	return core.TrashMessage(ctx, c.svc, id)
+}
@@ -0,0 +1,38 @@
+package gml
+
+import (
+	"context"
+
+	core "github.com/longkey1/gml/internal/gml"
+)
+
+// OutgoingMessage is a message to send or save as a draft
+type OutgoingMessage = core.OutgoingMessage
+
+// OutgoingAttachment is a file attached to an OutgoingMessage
+type OutgoingAttachment = core.OutgoingAttachment
+
+// ReadAttachment reads a file to attach, inline (e.g. an image referenced
+// from an HTML body as cid:<file name>) or as a regular attachment
+func ReadAttachment(path string, inline bool) (OutgoingAttachment, error) {
+	return core.ReadAttachment(path, inline)
+}
+
+// Send sends a message and returns its ID. It needs the send, compose or
+// modify scope
+func (c *Client) Send(ctx context.Context, msg *OutgoingMessage) (string, error) {
+	sent, err := core.SendMessage(ctx, c.svc, msg)
+	if err != nil {
+		return "", err
+	}
+	return sent.Id, nil
+}
+
+// CreateDraft saves a message as a draft and returns the draft ID
+func (c *Client) CreateDraft(ctx context.Context, msg *OutgoingMessage) (string, error) {
+	draft, err := core.CreateDraft(ctx, c.svc, msg)
+	if err != nil {
+		return "", err
+	}
+	return draft.Id, nil
+}
@@ -0,0 +1,127 @@
+package gml
+
+import (
+	"context"
+
+	core "github.com/longkey1/gml/internal/gml"
+)
+
+// Stats is the result of Client.Stats
+type Stats = core.Stats
+
+// GroupCount is the number of messages of one group of Stats
+type GroupCount = core.GroupCount
+
+// GroupBy is what Stats groups messages by
+type GroupBy = core.GroupBy
+
+// Stats groupings
+const (
+	GroupBySender = core.GroupBySender
+	GroupByDomain = core.GroupByDomain
+	GroupByLabel  = core.GroupByLabel
+	GroupByDay    = core.GroupByDay
+	GroupByMonth  = core.GroupByMonth
+)
+
+// SendersReport is the result of Client.TopSenders
+type SendersReport = core.SendersReport
+
+// SenderSummary is the mail of one sender in a SendersReport
+type SenderSummary = core.SenderSummary
+
+// StatsOptions selects the messages Stats counts and how
+type StatsOptions struct {
+	Query  string
+	Labels []string
+	// By is the grouping (default GroupBySender)
+	By GroupBy
+	// Limit keeps the largest groups (0 for all)
+	Limit int
+	// MaxMessages stops after this many messages, newest first (0 for all)
+	MaxMessages int
+}
+
+// SendersOptions selects the messages TopSenders ranks the senders of
+type SendersOptions struct {
+	Query  string
+	Labels []string
+	// Limit keeps the top senders (0 for all)
+	Limit int
+	// SortBySize ranks senders by total size instead of message count
+	SortBySize bool
+	// MaxMessages stops after this many messages, newest first (0 for all)
+	MaxMessages int
+}
+
+// Stats counts the messages matching the options per sender, domain, label,
+// day or month. Message metadata is kept in the MessageCache
+func (c *Client) Stats(ctx context.Context, opts StatsOptions) (*Stats, error) {
+	by := opts.By
+	if by == "" {
+		by = GroupBySender
+	}
+	cache, err := c.messageCache()
+	if err != nil {
+		return nil, err
+	}
+	stats, err := core.MailboxStats(ctx, c.svc, core.StatsOptions{
+		Query:       opts.Query,
+		LabelIDs:    opts.Labels,
+		By:          by,
+		Limit:       opts.Limit,
+		MaxMessages: opts.MaxMessages,
+		Cache:       cache,
+	})
+	if err != nil {
+		return nil, err
+	}
+	return stats, c.saveMessageCache()
+}
+
+// TopSenders ranks the senders of the messages matching the options by
+// message count or total size. Message metadata is kept in the MessageCache
+func (c *Client) TopSenders(ctx context.Context, opts SendersOptions) (*SendersReport, error) {
+	cache, err := c.messageCache()
+	if err != nil {
+		return nil, err
+	}
+	report, err := core.TopSenders(ctx, c.svc, core.SendersOptions{
+		Query:       opts.Query,
+		LabelIDs:    opts.Labels,
+		Limit:       opts.Limit,
+		SortBySize:  opts.SortBySize,
+		MaxMessages: opts.MaxMessages,
+		Cache:       cache,
+	})
+	if err != nil {
+		return nil, err
+	}
+	return report, c.saveMessageCache()
+}
+
+// messageCache returns Options.MessageCache, or else the state file cache of
+// the gml command, loaded on first use
+func (c *Client) messageCache() (core.MessageCache, error) {
+	c.mu.Lock()
+	defer c.mu.Unlock()
+	if c.cache == nil {
+		path, err := core.StatePath(core.StatsCacheName, c.svc.Account)
+		if err != nil {
+			return nil, err
+		}
+		cache, err := core.LoadMetadataCache(path)
+		if err != nil {
+			return nil, err
+		}
+		c.cache, c.fileCache = cache, cache
+	}
+	return c.cache, nil
+}
+
+// saveMessageCache writes the state file cache, if it is used
+func (c *Client) saveMessageCache() error {
+	c.mu.Lock()
+	defer c.mu.Unlock()
+	return c.fileCache.Save()
+}
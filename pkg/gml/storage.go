@@ -0,0 +1,120 @@
+package gml
+
+import (
+	"context"
+	"maps"
+	"sync"
+	"time"
+
+	core "github.com/longkey1/gml/internal/gml"
+	"github.com/longkey1/gml/internal/google"
+	"golang.org/x/oauth2"
+)
+
+// TokenStore persists the OAuth token of an account. Set Options.TokenStore
+// to keep tokens elsewhere than the gml command does, e.g. in Redis or a
+// database. Refreshed tokens are saved back to it
+type TokenStore interface {
+	// Load returns the stored token, or an error wrapping os.ErrNotExist if
+	// there is none
+	Load() (*oauth2.Token, error)
+	// Save stores the token, replacing any existing one
+	Save(token *oauth2.Token) error
+	// Delete removes the stored token
+	Delete() error
+	// Lock serializes refreshes of the token, across processes if the store
+	// is shared, and returns a function that releases it
+	Lock() (func(), error)
+	// String describes where the token is stored
+	String() string
+}
+
+// NewFileTokenStore returns a TokenStore keeping the token in a JSON file,
+// like the gml command does by default
+func NewFileTokenStore(path string) TokenStore {
+	return google.NewFileTokenStore(path)
+}
+
+// NewMemoryTokenStore returns a TokenStore holding the token in memory only,
+// e.g. for tests or a token obtained elsewhere; token may be nil
+func NewMemoryTokenStore(token *oauth2.Token) TokenStore {
+	return google.NewMemoryTokenStore(token)
+}
+
+// MessageCache keeps the sender, date and size of messages between calls, so
+// Stats and TopSenders only fetch new messages. Set Options.MessageCache to
+// keep it elsewhere than the state file of the gml command. It must be safe
+// for concurrent use
+type MessageCache interface {
+	// GetMessages returns the cached metadata of those of ids that are cached
+	GetMessages(ctx context.Context, ids []string) (map[string]CachedMessage, error)
+	// PutMessages stores the metadata of messages, replacing cached entries
+	PutMessages(ctx context.Context, messages map[string]CachedMessage) error
+}
+
+// CachedMessage is the metadata of a message kept in a MessageCache
+type CachedMessage struct {
+	From string    `json:"from"`
+	Date time.Time `json:"date"`
+	// Size is the size estimate in bytes
+	Size int64 `json:"size"`
+}
+
+// NewMemoryMessageCache returns a MessageCache kept in memory only
+func NewMemoryMessageCache() MessageCache {
+	return &memoryMessageCache{messages: make(map[string]CachedMessage)}
+}
+
+// memoryMessageCache is the MessageCache of NewMemoryMessageCache
+type memoryMessageCache struct {
+	mu       sync.Mutex
+	messages map[string]CachedMessage
+}
+
+// GetMessages returns the cached metadata of messages
+func (c *memoryMessageCache) GetMessages(ctx context.Context, ids []string) (map[string]CachedMessage, error) {
+	c.mu.Lock()
+	defer c.mu.Unlock()
+	hits := make(map[string]CachedMessage)
+	for _, id := range ids {
+		if m, ok := c.messages[id]; ok {
+			hits[id] = m
+		}
+	}
+	return hits, nil
+}
+
+// PutMessages stores the metadata of messages
+func (c *memoryMessageCache) PutMessages(ctx context.Context, messages map[string]CachedMessage) error {
+	c.mu.Lock()
+	defer c.mu.Unlock()
+	maps.Copy(c.messages, messages)
+	return nil
+}
+
+// coreMessageCache adapts a MessageCache to the cache of the internal stats
+type coreMessageCache struct {
+	cache MessageCache
+}
+
+// GetMessages returns the cached metadata of messages
+func (c coreMessageCache) GetMessages(ctx context.Context, ids []string) (map[string]core.CachedMessage, error) {
+	hits, err := c.cache.GetMessages(ctx, ids)
+	if err != nil {
+		return nil, err
+	}
+	converted := make(map[string]core.CachedMessage, len(hits))
+	for id, m := range hits {
+		converted[id] = core.CachedMessage{From: m.From, Date: m.Date.UnixMilli(), Size: m.Size}
+	}
+	return converted, nil
+}
+
+// PutMessages stores the metadata of messages
+func (c coreMessageCache) PutMessages(ctx context.Context, messages map[string]core.CachedMessage) error {
+	converted := make(map[string]CachedMessage, len(messages))
+	for id, m := range messages {
+		converted[id] = CachedMessage{From: m.From, Date: time.UnixMilli(m.Date), Size: m.Size}
+	}
+	return c.cache.PutMessages(ctx, converted)
+}
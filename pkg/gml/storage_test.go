@@ -0,0 +1,40 @@
+package gml
+
+import (
+	"context"
+	"testing"
+	"time"
+
+	core "github.com/longkey1/gml/internal/gml"
+)
+
+func TestCoreMessageCache(t *testing.T) {
+	ctx := context.Background()
+	cache := NewMemoryMessageCache()
+	adapter := coreMessageCache{cache}
+
+	date := time.Date(2025, 3, 14, 9, 30, 0, 0, time.UTC)
+	err := adapter.PutMessages(ctx, map[string]core.CachedMessage{
+		"a": {From: "alice@example.com", Date: date.UnixMilli(), Size: 1024},
+	})
+	if err != nil {
+		t.Fatal(err)
+	}
+
+	public, err := cache.GetMessages(ctx, []string{"a", "missing"})
+	if err != nil {
+		t.Fatal(err)
+	}
+	want := CachedMessage{From: "alice@example.com", Date: date, Size: 1024}
+	if len(public) != 1 || !public["a"].Date.Equal(want.Date) || public["a"].From != want.From || public["a"].Size != want.Size {
+		t.Errorf("GetMessages() = %+v, want a: %+v", public, want)
+	}
+
+	internal, err := adapter.GetMessages(ctx, []string{"a"})
+	if err != nil {
+		t.Fatal(err)
+	}
+	if got := internal["a"]; got != (core.CachedMessage{From: "alice@example.com", Date: date.UnixMilli(), Size: 1024}) {
+		t.Errorf("adapter GetMessages() = %+v", got)
+	}
+}